@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+// errAdminPolicy the adapters without the admin policy interfaces
+var errAdminPolicy = bluez.ErrNotSupported.WithMessage("Admin policy not available, it requires bluez 5.60 or later with the admin plugin")
+
+// adminPolicyPath return the path of an adapter exposing the admin policy
+func adminPolicyPath(adapterID string) (string, error) {
+
+	manager, err := GetManager()
+	if err != nil {
+		return "", err
+	}
+
+	path := dbus.ObjectPath("/org/bluez/" + adapterID)
+	cache := manager.GetCache()
+	if cache.Get(path, bluez.Adapter1Interface) == nil {
+		return "", errors.New("Adapter " + adapterID + " not found")
+	}
+	if cache.Get(path, profile.AdminPolicySet1Interface) == nil {
+		return "", errAdminPolicy
+	}
+	return string(path), nil
+}
+
+//SetServiceAllowList restrict the services the devices may use on an
+// adapter, the UUIDs can be in their 16, 32 or 128 bit form. The connected
+// devices using other services are disconnected by bluez. An empty list
+// allows all the services
+func SetServiceAllowList(adapterID string, uuids []string) error {
+
+	list := make([]string, 0, len(uuids))
+	for _, uuid := range uuids {
+		u, err := bluetooth.ParseUUID(uuid)
+		if err != nil {
+			return err
+		}
+		list = append(list, u.String())
+	}
+
+	path, err := adminPolicyPath(adapterID)
+	if err != nil {
+		return err
+	}
+	return profile.NewAdminPolicySet1(path).SetServiceAllowList(list)
+}
+
+//GetServiceAllowList return the 128 bit UUIDs of the services allowed on an
+// adapter, empty when all the services are allowed
+func GetServiceAllowList(adapterID string) ([]string, error) {
+
+	path, err := adminPolicyPath(adapterID)
+	if err != nil {
+		return nil, err
+	}
+	props, err := profile.NewAdminPolicyStatus1(path).GetProperties()
+	if err != nil {
+		return nil, err
+	}
+	return props.ServiceAllowList, nil
+}
+
+//IsAffectedByPolicy return true if some services of the device are blocked
+// by the service allow list of its adapter
+func (d *Device) IsAffectedByPolicy() (bool, error) {
+
+	manager, err := GetManager()
+	if err != nil {
+		return false, err
+	}
+	affected, ok := manager.GetCache().Property(dbus.ObjectPath(d.Path), profile.AdminPolicyStatus1Interface, "IsAffectedByPolicy")
+	if !ok {
+		return false, errAdminPolicy
+	}
+	b, _ := affected.Value().(bool)
+	return b, nil
+}
@@ -0,0 +1,530 @@
+package api
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/linux/mgmt"
+)
+
+//AgentDefaultPath the object path used when none is specified
+const AgentDefaultPath = "/org/bluez/agent/gobluetooth"
+
+//AgentEventsBuffer how many pairing events are queued before requests get rejected
+const AgentEventsBuffer = 10
+
+//AgentPairingTimeout how long the outcome of a pairing initiated by a remote
+// device is awaited after the last request, see PairingCompleteEvent
+const AgentPairingTimeout = 60 * time.Second
+
+var errAgentRejected = bluez.ErrRejected.DBusError()
+var errAgentCanceled = bluez.ErrCanceled.DBusError()
+
+//ErrRemotePairingDisconnected the remote device disconnected before the end
+// of the pairing it initiated
+var ErrRemotePairingDisconnected = bluez.ErrAuthenticationFailed.WithMessage("Disconnected before pairing")
+
+//ErrRemotePairingTimeout the pairing initiated by a remote device did not
+// complete within AgentPairingTimeout
+var ErrRemotePairingTimeout = bluez.ErrAuthenticationTimeout.WithMessage("Pairing timed out")
+
+// NewAgent create a new pairing agent, call Register to make it available to bluez
+func NewAgent(path string, capability string) (*Agent, error) {
+
+	if path == "" {
+		path = AgentDefaultPath
+	}
+	if capability == "" {
+		capability = profile.AgentCapabilityDisplayYesNo
+	}
+
+	conn, err := bluez.GetConnection(bluez.SystemBus)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Agent{
+		path:       dbus.ObjectPath(path),
+		capability: capability,
+		conn:       conn,
+		events:     make(chan PairingEvent, AgentEventsBuffer),
+		cancels:    make(map[chan bool]bool),
+		pairing:    make(map[dbus.ObjectPath]bool),
+		watched:    make(map[dbus.ObjectPath]chan bool),
+	}
+
+	return a, nil
+}
+
+type agentReply struct {
+	accept  bool
+	pincode string
+	passkey uint32
+}
+
+// Agent implements org.bluez.Agent1 forwarding every request on a channel
+// of PairingEvent, so that a UI can present it and reply asynchronously.
+// The outcome of the pairings, started with Pair or by the remote devices, is
+// reported with PairingCompleteEvent and PairingFailedEvent
+type Agent struct {
+	path       dbus.ObjectPath
+	capability string
+	conn       *dbus.Conn
+	events     chan PairingEvent
+
+	mutex   sync.Mutex
+	manager *profile.AgentManager1
+	// cancels the channels of the pending requests, closed by Cancel
+	cancels map[chan bool]bool
+	// pairing the devices being paired by Pair
+	pairing map[dbus.ObjectPath]bool
+	// watched the devices pairing from remote, with the channel ending the
+	// watch, see watchPairing
+	watched map[dbus.ObjectPath]chan bool
+}
+
+//Path return the agent object path
+func (a *Agent) Path() dbus.ObjectPath {
+	return a.path
+}
+
+//Events return the stream of pairing events
+func (a *Agent) Events() <-chan PairingEvent {
+	return a.events
+}
+
+//Register expose the agent on DBus and register it with bluez
+func (a *Agent) Register(isDefault bool) error {
+
+	err := a.conn.Export(a, a.path, bluez.Agent1Interface)
+	if err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			//Introspect
+			introspect.IntrospectData,
+			//Agent1
+			{
+				Name:    bluez.Agent1Interface,
+				Methods: introspect.Methods(a),
+			},
+		},
+	}
+
+	err = a.conn.Export(
+		introspect.NewIntrospectable(node),
+		a.path,
+		"org.freedesktop.DBus.Introspectable")
+	if err != nil {
+		return err
+	}
+
+	// the calls go on the connection the agent is exported on, the
+	// manager must not close it
+	manager := profile.NewAgentManager1()
+	manager.SetConnection(a.conn)
+	a.mutex.Lock()
+	a.manager = manager
+	a.mutex.Unlock()
+
+	err = a.registerAgent(isDefault)
+	if err != nil {
+		return err
+	}
+
+	recovery, err := bluez.GetRecovery(a.conn)
+	if err != nil {
+		bluez.GetLogger().Warnf("Agent: cannot watch for bluetoothd restarts: %s", err.Error())
+		return nil
+	}
+	recovery.Add(a.recoveryName(), func() error {
+		return a.registerAgent(isDefault)
+	})
+
+	return nil
+}
+
+func (a *Agent) registerAgent(isDefault bool) error {
+	a.mutex.Lock()
+	manager := a.manager
+	a.mutex.Unlock()
+	if manager == nil {
+		return errors.New("Agent not registered")
+	}
+	err := manager.RegisterAgent(a.path, a.capability)
+	if err != nil {
+		return err
+	}
+	if isDefault {
+		return manager.RequestDefaultAgent(a.path)
+	}
+	return nil
+}
+
+func (a *Agent) recoveryName() string {
+	return "api.agent:" + string(a.path)
+}
+
+//Unregister remove the agent from bluez and DBus
+func (a *Agent) Unregister() error {
+	a.mutex.Lock()
+	manager := a.manager
+	a.manager = nil
+	for device, stop := range a.watched {
+		close(stop)
+		delete(a.watched, device)
+	}
+	a.mutex.Unlock()
+	if manager == nil {
+		return nil
+	}
+
+	if recovery, err := bluez.GetRecovery(a.conn); err == nil {
+		recovery.Remove(a.recoveryName())
+	}
+
+	// bluez does not call Release on UnregisterAgent
+	err := manager.UnregisterAgent(a.path)
+
+	a.conn.Export(nil, a.path, bluez.Agent1Interface)
+	a.conn.Export(nil, a.path, "org.freedesktop.DBus.Introspectable")
+
+	return err
+}
+
+//Pair start pairing with a device, reporting the outcome on the event stream
+func (a *Agent) Pair(dev *Device) error {
+
+	if dev == nil {
+		return errors.New("Empty device pointer")
+	}
+
+	c, err := dev.GetClient()
+	if err != nil {
+		return err
+	}
+
+	// the outcome is reported here, not by watchPairing
+	path := dbus.ObjectPath(dev.Path)
+	a.mutex.Lock()
+	a.pairing[path] = true
+	a.mutex.Unlock()
+	defer func() {
+		a.mutex.Lock()
+		delete(a.pairing, path)
+		a.mutex.Unlock()
+	}()
+
+	err = c.Pair()
+	if err != nil {
+		a.emit(PairingFailedEvent{dev, err})
+		return err
+	}
+
+	a.emit(PairingCompleteEvent{dev})
+	return nil
+}
+
+//PairWithOOB store the OOB data received from the device (eg. over NFC) on the
+// adapter, then start pairing with it. addressType is one of mgmt.Address*.
+// The management socket requires CAP_NET_ADMIN
+func (a *Agent) PairWithOOB(adapterID string, dev *Device, addressType uint8, data *mgmt.OOBData) error {
+
+	if dev == nil {
+		return errors.New("Empty device pointer")
+	}
+
+	err := addRemoteOOBData(adapterID, dev.Properties.Address, addressType, data)
+	if err != nil {
+		a.emit(PairingFailedEvent{dev, err})
+		return err
+	}
+
+	return a.Pair(dev)
+}
+
+// addRemoteOOBData store the OOB data of a device through the management socket
+func addRemoteOOBData(adapterID string, address string, addressType uint8, data *mgmt.OOBData) error {
+
+	index, err := mgmt.Index(adapterID)
+	if err != nil {
+		return err
+	}
+
+	s, err := mgmt.Open()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return s.AddRemoteOOBData(index, address, addressType, data)
+}
+
+func (a *Agent) emit(ev PairingEvent) bool {
+	select {
+	case a.events <- ev:
+		return true
+	default:
+		bluez.GetLogger().Warnf("Agent: event stream is full, dropping %T", ev)
+		return false
+	}
+}
+
+// request emit an event and wait for the user reply or a Cancel from bluez.
+// For the pairing requests the outcome of the pairing is reported, see
+// watchPairing
+func (a *Agent) request(device dbus.ObjectPath, pairing bool, build func(reply func(agentReply)) PairingEvent) (agentReply, *dbus.Error) {
+
+	replies := make(chan agentReply, 1)
+	var once sync.Once
+	ev := build(func(r agentReply) {
+		once.Do(func() {
+			replies <- r
+		})
+	})
+
+	cancel := make(chan bool)
+	a.mutex.Lock()
+	a.cancels[cancel] = true
+	a.mutex.Unlock()
+
+	defer func() {
+		a.mutex.Lock()
+		delete(a.cancels, cancel)
+		a.mutex.Unlock()
+	}()
+
+	if !a.emit(ev) {
+		if pairing {
+			a.pairingFailed(device, bluez.ErrRejected)
+		}
+		return agentReply{}, errAgentRejected
+	}
+
+	select {
+	case r := <-replies:
+		if !r.accept {
+			if pairing {
+				a.pairingFailed(device, bluez.ErrRejected)
+			}
+			return r, errAgentRejected
+		}
+		if pairing {
+			a.watchPairing(device)
+		}
+		return r, nil
+	case <-cancel:
+		if pairing {
+			a.pairingFailed(device, bluez.ErrCanceled)
+		}
+		return agentReply{}, errAgentCanceled
+	}
+}
+
+// pairingFailed report the failure of a pairing initiated by a remote device
+func (a *Agent) pairingFailed(device dbus.ObjectPath, err error) {
+	a.mutex.Lock()
+	local := a.pairing[device]
+	if stop, ok := a.watched[device]; ok {
+		close(stop)
+		delete(a.watched, device)
+	}
+	a.mutex.Unlock()
+	if !local {
+		a.emit(PairingFailedEvent{NewDevice(string(device)), err})
+	}
+}
+
+// watchPairing follow a pairing initiated by a remote device, accepted by
+// the agent: PairingCompleteEvent is emitted when bluez sets Paired,
+// PairingFailedEvent when the device disconnects first or no outcome comes
+// within AgentPairingTimeout. The signals are subscribed before the reply to
+// bluez, the outcome cannot be missed
+func (a *Agent) watchPairing(device dbus.ObjectPath) {
+
+	a.mutex.Lock()
+	if a.pairing[device] || a.manager == nil {
+		a.mutex.Unlock()
+		return
+	}
+	if stop, ok := a.watched[device]; ok {
+		// a request following the first one, restart the timeout
+		close(stop)
+	}
+	stop := make(chan bool)
+	a.watched[device] = stop
+	a.mutex.Unlock()
+
+	dispatcher := bluez.GetSignalDispatcher(a.conn)
+	signals, err := dispatcher.Subscribe(bluez.SignalFilter{
+		Path:      device,
+		Interface: bluez.PropertiesInterface,
+		Member:    "PropertiesChanged",
+	})
+	if err != nil {
+		bluez.GetLogger().Warnf("Agent: cannot watch the pairing of %s: %s", device, err.Error())
+		a.mutex.Lock()
+		if a.watched[device] == stop {
+			delete(a.watched, device)
+		}
+		a.mutex.Unlock()
+		return
+	}
+
+	go func() {
+		defer dispatcher.Unsubscribe(signals)
+		timeout := time.NewTimer(AgentPairingTimeout)
+		defer timeout.Stop()
+
+		done := func(ev PairingEvent) {
+			a.mutex.Lock()
+			if a.watched[device] != stop {
+				// stopped or restarted meanwhile
+				a.mutex.Unlock()
+				return
+			}
+			delete(a.watched, device)
+			a.mutex.Unlock()
+			a.emit(ev)
+		}
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-timeout.C:
+				done(PairingFailedEvent{NewDevice(string(device)), ErrRemotePairingTimeout})
+				return
+			case sig := <-signals:
+				if sig == nil || len(sig.Body) < 2 {
+					continue
+				}
+				if iface, _ := sig.Body[0].(string); iface != bluez.Device1Interface {
+					continue
+				}
+				changed, _ := sig.Body[1].(map[string]dbus.Variant)
+				if paired, ok := changed["Paired"].Value().(bool); ok && paired {
+					done(PairingCompleteEvent{NewDevice(string(device))})
+					return
+				}
+				if connected, ok := changed["Connected"].Value().(bool); ok && !connected {
+					done(PairingFailedEvent{NewDevice(string(device)), ErrRemotePairingDisconnected})
+					return
+				}
+			}
+		}
+	}()
+}
+
+//Release called when bluez unregisters the agent
+func (a *Agent) Release() *dbus.Error {
+	bluez.GetLogger().Debugf("Agent.Release")
+	a.mutex.Lock()
+	a.manager = nil
+	a.mutex.Unlock()
+	return nil
+}
+
+//RequestPinCode ask for the PIN code of a legacy device
+func (a *Agent) RequestPinCode(device dbus.ObjectPath) (string, *dbus.Error) {
+	bluez.GetLogger().Debugf("Agent.RequestPinCode %s", device)
+	r, err := a.request(device, true, func(reply func(agentReply)) PairingEvent {
+		return PinCodeRequestEvent{
+			Device: NewDevice(string(device)),
+			Reply: func(pincode string, accept bool) {
+				reply(agentReply{accept: accept, pincode: pincode})
+			},
+		}
+	})
+	return r.pincode, err
+}
+
+//DisplayPinCode show the PIN code the remote has to enter
+func (a *Agent) DisplayPinCode(device dbus.ObjectPath, pincode string) *dbus.Error {
+	bluez.GetLogger().Debugf("Agent.DisplayPinCode %s", device)
+	a.watchPairing(device)
+	a.emit(PinCodeDisplayEvent{NewDevice(string(device)), pincode})
+	return nil
+}
+
+//RequestPasskey ask for the passkey shown by the remote
+func (a *Agent) RequestPasskey(device dbus.ObjectPath) (uint32, *dbus.Error) {
+	bluez.GetLogger().Debugf("Agent.RequestPasskey %s", device)
+	r, err := a.request(device, true, func(reply func(agentReply)) PairingEvent {
+		return PasskeyRequestEvent{
+			Device: NewDevice(string(device)),
+			Reply: func(passkey uint32, accept bool) {
+				reply(agentReply{accept: accept, passkey: passkey})
+			},
+		}
+	})
+	return r.passkey, err
+}
+
+//DisplayPasskey show the passkey the remote has to enter
+func (a *Agent) DisplayPasskey(device dbus.ObjectPath, passkey uint32, entered uint16) *dbus.Error {
+	bluez.GetLogger().Debugf("Agent.DisplayPasskey %s", device)
+	a.watchPairing(device)
+	a.emit(PasskeyDisplayEvent{NewDevice(string(device)), passkey, entered})
+	return nil
+}
+
+//RequestConfirmation ask to confirm the passkey shown by the remote
+func (a *Agent) RequestConfirmation(device dbus.ObjectPath, passkey uint32) *dbus.Error {
+	bluez.GetLogger().Debugf("Agent.RequestConfirmation %s", device)
+	_, err := a.request(device, true, func(reply func(agentReply)) PairingEvent {
+		return ConfirmationRequestEvent{
+			Device:  NewDevice(string(device)),
+			Passkey: passkey,
+			Accept: func(accept bool) {
+				reply(agentReply{accept: accept})
+			},
+		}
+	})
+	return err
+}
+
+//RequestAuthorization ask to authorize an incoming pairing
+func (a *Agent) RequestAuthorization(device dbus.ObjectPath) *dbus.Error {
+	bluez.GetLogger().Debugf("Agent.RequestAuthorization %s", device)
+	return a.authorize(device, "", true)
+}
+
+//AuthorizeService ask to authorize a connection to a service
+func (a *Agent) AuthorizeService(device dbus.ObjectPath, uuid string) *dbus.Error {
+	bluez.GetLogger().Debugf("Agent.AuthorizeService %s %s", device, uuid)
+	return a.authorize(device, uuid, false)
+}
+
+func (a *Agent) authorize(device dbus.ObjectPath, uuid string, pairing bool) *dbus.Error {
+	_, err := a.request(device, pairing, func(reply func(agentReply)) PairingEvent {
+		return AuthorizationRequestEvent{
+			Device: NewDevice(string(device)),
+			UUID:   uuid,
+			Accept: func(accept bool) {
+				reply(agentReply{accept: accept})
+			},
+		}
+	})
+	return err
+}
+
+//Cancel called when bluez drops the pending request. The call does not name
+// the request, all the pending ones are canceled
+func (a *Agent) Cancel() *dbus.Error {
+	bluez.GetLogger().Debugf("Agent.Cancel")
+	a.mutex.Lock()
+	for cancel := range a.cancels {
+		close(cancel)
+		delete(a.cancels, cancel)
+	}
+	a.mutex.Unlock()
+	return nil
+}
@@ -1,12 +1,16 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
 	"github.com/muka/go-bluetooth/bluez"
 	"github.com/muka/go-bluetooth/bluez/profile"
 	"github.com/muka/go-bluetooth/emitter"
@@ -15,9 +19,16 @@ import (
 
 var deviceRegistry = make(map[string]*Device)
 
+// deviceRegistryMutex guard deviceRegistry, NewDevice is called from the
+// concurrent agent requests
+var deviceRegistryMutex sync.Mutex
+
 // NewDevice creates a new Device
 func NewDevice(path string) *Device {
 
+	deviceRegistryMutex.Lock()
+	defer deviceRegistryMutex.Unlock()
+
 	if _, ok := deviceRegistry[path]; ok {
 		return deviceRegistry[path]
 	}
@@ -57,9 +68,9 @@ func ClearDevice(d *Device) error {
 
 	c.Close()
 
-	if _, ok := deviceRegistry[d.Path]; ok {
-		delete(deviceRegistry, d.Path)
-	}
+	deviceRegistryMutex.Lock()
+	delete(deviceRegistry, d.Path)
+	deviceRegistryMutex.Unlock()
 
 	return nil
 }
@@ -209,6 +220,16 @@ func (d *Device) GetProperties() (*profile.Device1Properties, error) {
 	return d.Properties, err
 }
 
+//GetClass return the Class of Device of a classic device, zero if bluez does
+// not know it, eg. for an LE device
+func (d *Device) GetClass() (bluetooth.Class, error) {
+	props, err := d.GetProperties()
+	if err != nil {
+		return 0, err
+	}
+	return bluetooth.Class(props.Class), nil
+}
+
 //GetProperty return a property value
 func (d *Device) GetProperty(name string) (data interface{}, err error) {
 	c, err := d.GetClient()
@@ -306,11 +327,10 @@ func (d *Device) GetAllServicesAndUUID() ([]string, error) {
 	return deviceFound, nil
 }
 
-//GetCharByUUID return a GattService by its uuid, return nil if not found
+//GetCharByUUID return a GattService by its uuid, in its 16bit or 128bit
+// form, return nil if not found
 func (d *Device) GetCharByUUID(uuid string) (*profile.GattCharacteristic1, error) {
 
-	uuid = strings.ToUpper(uuid)
-
 	list, err := d.GetCharsList()
 	if err != nil {
 		return nil, err
@@ -331,9 +351,8 @@ func (d *Device) GetCharByUUID(uuid string) (*profile.GattCharacteristic1, error
 		}
 
 		props := d.chars[path].Properties
-		cuuid := strings.ToUpper(props.UUID)
 
-		if cuuid == uuid {
+		if bluetooth.EqualUUID(props.UUID, uuid) {
 			deviceFound = d.chars[path]
 		}
 	}
@@ -345,6 +364,46 @@ func (d *Device) GetCharByUUID(uuid string) (*profile.GattCharacteristic1, error
 	return deviceFound, nil
 }
 
+//FindChar return a characteristic by the UUIDs of its service and its own,
+// in any of their forms. An empty service UUID matches any service, an error
+// is returned if the characteristic is found in several services
+func (d *Device) FindChar(serviceUUID string, charUUID string) (*profile.GattCharacteristic1, error) {
+
+	manager, err := GetManager()
+	if err != nil {
+		return nil, err
+	}
+	cache := manager.GetCache()
+
+	paths, err := d.GetCharsList()
+	if err != nil {
+		return nil, err
+	}
+	var found dbus.ObjectPath
+	for _, path := range paths {
+		uuid, _ := cache.Property(path, bluez.GattCharacteristic1Interface, "UUID")
+		if s, _ := uuid.Value().(string); !bluetooth.EqualUUID(s, charUUID) {
+			continue
+		}
+		if serviceUUID != "" {
+			service, _ := cache.Property(path, bluez.GattCharacteristic1Interface, "Service")
+			servicePath, _ := service.Value().(dbus.ObjectPath)
+			uuid, _ := cache.Property(servicePath, bluez.GattService1Interface, "UUID")
+			if s, _ := uuid.Value().(string); !bluetooth.EqualUUID(s, serviceUUID) {
+				continue
+			}
+		}
+		if found != "" {
+			return nil, fmt.Errorf("Characteristic %s found in several services", charUUID)
+		}
+		found = path
+	}
+	if found == "" {
+		return nil, errors.New("characteristic not found")
+	}
+	return profile.NewGattCharacteristic1(string(found))
+}
+
 //GetCharsList return a device characteristics
 func (d *Device) GetCharsList() ([]dbus.ObjectPath, error) {
 
@@ -402,6 +461,143 @@ func (d *Device) Connect() error {
 	return nil
 }
 
+//ConnectContext connect to the device, giving up when the context is done
+func (d *Device) ConnectContext(ctx context.Context) error {
+	c, err := d.GetClient()
+	if err != nil {
+		return err
+	}
+	return c.ConnectContext(ctx)
+}
+
+//ResolveServices connect to the device if needed and wait for bluez to
+// resolve its services, giving up when the context is done
+func (d *Device) ResolveServices(ctx context.Context) error {
+
+	if !d.IsConnected() {
+		if err := d.ConnectContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	for {
+		props, err := d.GetProperties()
+		if err != nil {
+			return err
+		}
+		if props.ServicesResolved {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+//ReadRetries how many times ReadAll tries a read failing with a transient
+// error, eg. InProgress when another client is using the device
+const ReadRetries = 3
+
+//ReadRetryInterval the delay between the tries of a read
+const ReadRetryInterval = 500 * time.Millisecond
+
+//ReadAllTimeout bound the connection, the resolution of the services and the
+// reads of ReadAll
+const ReadAllTimeout = 30 * time.Second
+
+//ReadError a characteristic of ReadAll not found or failing to be read
+type ReadError struct {
+	// UUID the characteristic as passed to ReadAll
+	UUID string
+	// Err the cause, eg. a *bluez.Error, see bluez.IsError
+	Err error
+}
+
+func (e *ReadError) Error() string {
+	return e.UUID + ": " + e.Err.Error()
+}
+
+//Unwrap return the cause
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+//ReadAll read characteristics by UUID, see ReadAllContext, within
+// ReadAllTimeout
+func (d *Device) ReadAll(uuids ...string) (map[string][]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ReadAllTimeout)
+	defer cancel()
+	return d.ReadAllContext(ctx, uuids...)
+}
+
+//ReadAllContext connect to the device if needed, wait once for its services
+// to be resolved, then read the characteristics one after the other. The
+// UUIDs can be in any form and must identify a single characteristic, the
+// values are returned by the UUIDs as passed. Each read is retried on its
+// own, the first one failing for good stops the reads with a *ReadError
+func (d *Device) ReadAllContext(ctx context.Context, uuids ...string) (map[string][]byte, error) {
+
+	if err := d.ResolveServices(ctx); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(uuids))
+	for _, uuid := range uuids {
+		if _, ok := values[uuid]; ok {
+			continue
+		}
+		char, err := d.FindChar("", uuid)
+		if err != nil {
+			return nil, &ReadError{uuid, err}
+		}
+		value, err := d.readRetry(ctx, char)
+		if err != nil {
+			return nil, &ReadError{uuid, err}
+		}
+		values[uuid] = value
+	}
+	return values, nil
+}
+
+// readRetry read a characteristic, retrying the transient errors. A device
+// disconnected meanwhile is connected again, bluez reports it with
+// NotConnected or with Failed "Not connected"
+func (d *Device) readRetry(ctx context.Context, char *profile.GattCharacteristic1) (value []byte, err error) {
+	for i := 0; i < ReadRetries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(ReadRetryInterval):
+			}
+		}
+		value, err = char.ReadValueContext(ctx, nil)
+		switch {
+		case err == nil:
+			return value, nil
+		case bluez.IsError(err, bluez.ErrNotConnected), isNotConnected(err):
+			if err := d.ResolveServices(ctx); err != nil {
+				return nil, err
+			}
+		case bluez.IsError(err, bluez.ErrInProgress),
+			bluez.IsError(err, bluez.ErrNotReady),
+			err == bluez.ErrQueueFull:
+		default:
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// isNotConnected return true for the Failed error of the GATT calls on a
+// device disconnected
+func isNotConnected(err error) bool {
+	e, ok := err.(*bluez.Error)
+	return ok && e.Is(bluez.ErrFailed) && e.Message == "Not connected"
+}
+
 //Disconnect from a device
 func (d *Device) Disconnect() error {
 	c, err := d.GetClient()
@@ -424,3 +620,12 @@ func (d *Device) Pair() error {
 	c.Pair()
 	return nil
 }
+
+//PairContext pair a device, canceling the pairing when the context is done
+func (d *Device) PairContext(ctx context.Context) error {
+	c, err := d.GetClient()
+	if err != nil {
+		return err
+	}
+	return c.PairContext(ctx)
+}
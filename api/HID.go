@@ -0,0 +1,64 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//IsHID return true if the device is a classic HID device, eg. a keyboard or a mouse
+func (d *Device) IsHID() bool {
+
+	props, err := d.GetProperties()
+	if err != nil || props == nil {
+		return false
+	}
+
+	for _, uuid := range props.UUIDs {
+		if strings.ToLower(uuid) == profile.HumanInterfaceDeviceUUID {
+			return true
+		}
+	}
+
+	return bluetooth.Class(props.Class).Major() == bluetooth.MajorPeripheral
+}
+
+//ConnectHID connect the HID profile of a paired device. The device is marked
+// as trusted, so that the input plugin accepts its reconnections
+func (d *Device) ConnectHID() error {
+
+	c, err := d.GetClient()
+	if err != nil {
+		return err
+	}
+
+	if !d.Properties.Trusted {
+		err = c.SetProperty("Trusted", dbus.MakeVariant(true))
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.ConnectProfile(profile.HumanInterfaceDeviceUUID)
+}
+
+//DisconnectHID disconnect the HID profile, leaving other profiles connected
+func (d *Device) DisconnectHID() error {
+	c, err := d.GetClient()
+	if err != nil {
+		return err
+	}
+	return c.DisconnectProfile(profile.HumanInterfaceDeviceUUID)
+}
+
+//GetHIDReconnectMode return which side reconnects the HID profile, see profile.InputReconnect*
+func (d *Device) GetHIDReconnectMode() (string, error) {
+	input := profile.NewInput1(d.Path)
+	props, err := input.GetProperties()
+	if err != nil {
+		return "", err
+	}
+	return props.ReconnectMode, nil
+}
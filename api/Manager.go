@@ -30,35 +30,73 @@ func GetManager() (*Manager, error) {
 func NewManager() (*Manager, error) {
 	m := new(Manager)
 	m.objectManager = profile.NewObjectManager("org.bluez", "/")
-	m.objects = make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
+
+	// Load initial object cache, kept current by the signals
+	cache, err := profile.NewManagedObjects("org.bluez", "/")
+	if err != nil {
+		return nil, err
+	}
+	m.cache = cache
 
 	// watch for signaling from ObjectManager
 	m.watchChanges()
 
-	// Load initial object cache and emit events
-	err := m.LoadObjects()
+	m.watchRecovery()
+
+	return m, nil
+}
+
+// watchRecovery reload the objects when bluetoothd restarts and emit
+// "recovered" events
+func (m *Manager) watchRecovery() {
+
+	conn, err := bluez.GetConnection(bluez.SystemBus)
 	if err != nil {
-		return nil, err
+		return
+	}
+	recovery, err := bluez.GetRecovery(conn)
+	if err != nil {
+		bluez.GetLogger().Warnf("Cannot watch for bluetoothd restarts: %s", err.Error())
+		return
 	}
 
-	return m, nil
+	events, cancel := recovery.Events()
+	m.stopRecovery = cancel
+
+	go func() {
+		for ev := range events {
+			if ev.Status == bluez.RecoveryLost {
+				emitter.Emit("recovered", RecoveredEvent{Status: StatusRemoved})
+				continue
+			}
+			err := m.LoadObjects()
+			if err != nil {
+				bluez.GetLogger().Warnf("Failed to reload the objects: %s", err.Error())
+			}
+			emitter.Emit("recovered", RecoveredEvent{Status: StatusAdded, Errors: ev.Errors})
+		}
+	}()
 }
 
 // Manager track changes in the bluez dbus tree reflecting protocol updates
 type Manager struct {
 	objectManager       *profile.ObjectManager
 	watchChangesEnabled bool
-	objects             map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	cache               *profile.ManagedObjects
 	channel             chan *dbus.Signal
+	stopRecovery        func()
 }
 
 // unwatchChanges register for signals from the ObjectManager
 func (m *Manager) unwatchChanges() error {
-	if m.channel != nil {
-		close(m.channel)
-	}
 	m.watchChangesEnabled = false
-	return m.objectManager.Unregister(m.channel)
+	if m.channel == nil {
+		return nil
+	}
+	err := m.objectManager.Unregister(m.channel)
+	close(m.channel)
+	m.channel = nil
+	return err
 }
 
 // watchChanges regitster for signals from the ObjectManager
@@ -89,7 +127,7 @@ func (m *Manager) watchChanges() error {
 				return
 			}
 
-			// log.Debugf("ObjectManager event: %++v", v)
+			// bluez.GetLogger().Debugf("ObjectManager event: %++v", v)
 
 			switch v.Name {
 			case bluez.InterfacesAdded:
@@ -98,7 +136,7 @@ func (m *Manager) watchChanges() error {
 					props := v.Body[1].(map[string]map[string]dbus.Variant)
 
 					// keep cache up to date
-					m.objects[path] = props
+					m.cache.Update(v)
 
 					emitChanges(path, props)
 				}
@@ -109,9 +147,7 @@ func (m *Manager) watchChanges() error {
 					ifaces := v.Body[1].([]string)
 
 					// keep cache up to date
-					if _, ok := m.objects[path]; ok {
-						delete(m.objects, path)
-					}
+					m.cache.Update(v)
 
 					for _, iF := range ifaces {
 						// device removed
@@ -212,17 +248,18 @@ func emitChanges(path dbus.ObjectPath, props map[string]map[string]dbus.Variant)
 
 //LoadObjects force reloading of cache objects list
 func (m *Manager) LoadObjects() error {
-	objs, err := m.objectManager.GetManagedObjects()
-	if err != nil {
-		return err
-	}
-	m.objects = objs
-	return nil
+	return m.cache.Refresh()
 }
 
-//GetObjects return the cached list of objects from the ObjectManager
+//GetObjects return a snapshot of the cached objects from the ObjectManager
 func (m *Manager) GetObjects() *map[dbus.ObjectPath]map[string]map[string]dbus.Variant {
-	return &m.objects
+	objs := map[dbus.ObjectPath]map[string]map[string]dbus.Variant(m.cache.Objects())
+	return &objs
+}
+
+//GetCache return the cache of the bluez objects, with query helpers
+func (m *Manager) GetCache() *profile.ManagedObjects {
+	return m.cache
 }
 
 //RefreshState emit local manager objects and interfaces
@@ -243,7 +280,11 @@ func (m *Manager) RefreshState() error {
 
 //Close Close the Manager and free underlying resources
 func (m *Manager) Close() {
+	if m.stopRecovery != nil {
+		m.stopRecovery()
+	}
 	m.objectManager.Unregister(m.channel)
 	m.objectManager.Close()
 	m.objectManager = nil
+	m.cache.Close()
 }
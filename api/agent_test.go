@@ -0,0 +1,214 @@
+package api_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+func startBluez(t *testing.T) (*bluetest.Bluez, *bluetest.Device) {
+	b, err := bluetest.Start()
+	if err != nil {
+		t.Skipf("Cannot start the fake bluez: %s", err.Error())
+	}
+	if _, err = b.AddAdapter("hci0", "00:11:22:33:44:55"); err != nil {
+		b.Close()
+		t.Fatal(err)
+	}
+	dev, err := b.AddDevice("hci0", "AA:BB:CC:DD:EE:FF", "Remote")
+	if err != nil {
+		b.Close()
+		t.Fatal(err)
+	}
+	return b, dev
+}
+
+func registerAgent(t *testing.T) *api.Agent {
+	agent, err := api.NewAgent("", profile.AgentCapabilityDisplayYesNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = agent.Register(true); err != nil {
+		t.Fatal(err)
+	}
+	return agent
+}
+
+// nextEvent return the next event of the agent, failing after a second
+func nextEvent(t *testing.T, agent *api.Agent) api.PairingEvent {
+	select {
+	case ev := <-agent.Events():
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("No pairing event")
+		return nil
+	}
+}
+
+func TestAgentUnregisterKeepsConnection(t *testing.T) {
+
+	b, _ := startBluez(t)
+	defer b.Close()
+
+	agent := registerAgent(t)
+	if _, ok := b.Agents()[agent.Path()]; !ok {
+		t.Fatalf("Agent not registered: %v", b.Agents())
+	}
+	if err := agent.Unregister(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Agents()[agent.Path()]; ok {
+		t.Fatal("Agent still registered")
+	}
+
+	// the shared connection is still usable
+	if _, err := profile.NewAdapter1("hci0").GetProperties(); err != nil {
+		t.Fatalf("Connection closed by Unregister: %s", err)
+	}
+	if err := agent.Register(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := agent.Unregister(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentRelease(t *testing.T) {
+
+	b, _ := startBluez(t)
+	defer b.Close()
+
+	agent := registerAgent(t)
+	if err := agent.Release(); err != nil {
+		t.Fatal(err)
+	}
+	// released by bluez, nothing left to unregister
+	if err := agent.Unregister(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentRemotePairing(t *testing.T) {
+
+	b, dev := startBluez(t)
+	defer b.Close()
+
+	agent := registerAgent(t)
+	defer agent.Unregister()
+
+	errs := make(chan error, 1)
+	go func() { errs <- dev.PairFromRemote() }()
+
+	ev, ok := nextEvent(t, agent).(api.AuthorizationRequestEvent)
+	if !ok {
+		t.Fatalf("Expected an authorization request, got %T", ev)
+	}
+	ev.Accept(true)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	complete, ok := nextEvent(t, agent).(api.PairingCompleteEvent)
+	if !ok || complete.Device.Path != string(dev.Path) {
+		t.Fatalf("Expected the pairing of %s to complete, got %#v", dev.Path, complete)
+	}
+}
+
+func TestAgentRemotePairingRejected(t *testing.T) {
+
+	b, dev := startBluez(t)
+	defer b.Close()
+
+	agent := registerAgent(t)
+	defer agent.Unregister()
+
+	errs := make(chan error, 1)
+	go func() { errs <- dev.PairFromRemote() }()
+
+	ev, ok := nextEvent(t, agent).(api.AuthorizationRequestEvent)
+	if !ok {
+		t.Fatalf("Expected an authorization request, got %T", ev)
+	}
+	ev.Accept(false)
+	if err := <-errs; !bluez.IsError(err, bluez.ErrRejected) {
+		t.Fatalf("Expected Rejected, got %v", err)
+	}
+
+	failed, ok := nextEvent(t, agent).(api.PairingFailedEvent)
+	if !ok || !bluez.IsError(failed.Err, bluez.ErrRejected) {
+		t.Fatalf("Expected the pairing to fail, got %#v", failed)
+	}
+}
+
+func TestAgentRemotePairingDisconnected(t *testing.T) {
+
+	b, dev := startBluez(t)
+	defer b.Close()
+	dev.SetProperty("Connected", true)
+
+	agent := registerAgent(t)
+	defer agent.Unregister()
+
+	errs := make(chan *dbus.Error, 1)
+	go func() { errs <- agent.RequestConfirmation(dev.Path, 123456) }()
+
+	ev, ok := nextEvent(t, agent).(api.ConfirmationRequestEvent)
+	if !ok {
+		t.Fatalf("Expected a confirmation request, got %T", ev)
+	}
+	ev.Accept(true)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	dev.SetProperty("Connected", false)
+
+	failed, ok := nextEvent(t, agent).(api.PairingFailedEvent)
+	if !ok || failed.Err != api.ErrRemotePairingDisconnected {
+		t.Fatalf("Expected the pairing to fail on disconnection, got %#v", failed)
+	}
+}
+
+func TestAgentCancelConcurrent(t *testing.T) {
+
+	b, dev := startBluez(t)
+	defer b.Close()
+	other, err := b.AddDevice("hci0", "AA:BB:CC:DD:EE:00", "Other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent := registerAgent(t)
+	defer agent.Unregister()
+
+	errs := make(chan *dbus.Error, 2)
+	for _, path := range []dbus.ObjectPath{dev.Path, other.Path} {
+		go func(path dbus.ObjectPath) { errs <- agent.RequestConfirmation(path, 0) }(path)
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := nextEvent(t, agent).(api.ConfirmationRequestEvent); !ok {
+			t.Fatal("Expected a confirmation request")
+		}
+	}
+
+	agent.Cancel()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil || err.Name != bluez.ErrCanceled.Name {
+				t.Fatalf("Expected Canceled, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("A request has not been canceled")
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := nextEvent(t, agent).(api.PairingFailedEvent); !ok {
+			t.Fatal("Expected the pairings to fail")
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"errors"
 
 	"github.com/godbus/dbus"
@@ -10,27 +11,29 @@ import (
 	"github.com/muka/go-bluetooth/emitter"
 )
 
-//Exit performs a clean exit
+//Exit performs a clean exit. The manager and the devices are created again
+// on the next use, eg. on a new connection to the system bus
 func Exit() {
-	manager, err := GetManager()
-	if err == nil {
+	if manager != nil {
 		manager.Close()
+		manager = nil
 	}
+	deviceRegistryMutex.Lock()
+	deviceRegistry = make(map[string]*Device)
+	deviceRegistryMutex.Unlock()
 }
 
 //GetDeviceByAddress return a Device object based on its address
 func GetDeviceByAddress(address string) (*Device, error) {
-	list, err := GetDeviceList()
+	manager, err := GetManager()
 	if err != nil {
 		return nil, err
 	}
-	for _, path := range list {
-		dev := NewDevice(string(path))
-		if dev.Properties.Address == address {
-			return dev, nil
-		}
+	list := manager.GetCache().ByAddress(address)
+	if len(list) == 0 {
+		return nil, nil
 	}
-	return nil, nil
+	return NewDevice(string(list[0])), nil
 }
 
 //GetDevices returns a list of bluetooth discovered Devices
@@ -69,20 +72,7 @@ func GetDeviceList() ([]dbus.ObjectPath, error) {
 		return nil, err
 	}
 
-	objects := manager.GetObjects()
-	var devices []dbus.ObjectPath
-	for path, ifaces := range *objects {
-		for iface := range ifaces {
-			switch iface {
-			case bluez.Device1Interface:
-				{
-					devices = append(devices, path)
-				}
-			}
-		}
-	}
-
-	return devices, nil
+	return manager.GetCache().ByInterface(bluez.Device1Interface), nil
 }
 
 //AdapterExists checks if an adapter is available
@@ -155,6 +145,16 @@ func StartDiscoveryOn(adapterID string) error {
 	return nil
 }
 
+//StartDiscoveryContext start discovery on the specified adapter, stopping it
+// when the context is done
+func StartDiscoveryContext(ctx context.Context, adapterID string) error {
+	adapter, err := GetAdapter(adapterID)
+	if err != nil {
+		return err
+	}
+	return adapter.StartDiscoveryContext(ctx)
+}
+
 // StopDiscoveryOn start discovery on specified adapter
 func StopDiscoveryOn(adapterID string) error {
 	adapter, err := GetAdapter(adapterID)
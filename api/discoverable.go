@@ -0,0 +1,166 @@
+package api
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/emitter"
+)
+
+//DiscoverableWindow a period during which an adapter is discoverable and
+// pairable, see OpenDiscoverableWindow
+type DiscoverableWindow struct {
+	AdapterID string
+
+	adapter *profile.Adapter1
+	// previous the state restored when the window closes
+	previous profile.Adapter1Properties
+
+	mutex  sync.Mutex
+	timer  *time.Timer
+	closed bool
+	err    error
+	done   chan struct{}
+}
+
+var (
+	windowsMutex sync.Mutex
+	windows      = make(map[string]*DiscoverableWindow)
+)
+
+// timeoutSeconds convert a duration to the seconds of the bluez timeouts,
+// rounded up
+func timeoutSeconds(d time.Duration) uint32 {
+	return uint32(math.Ceil(d.Seconds()))
+}
+
+//OpenDiscoverableWindow make an adapter discoverable and pairable for a
+// duration, eg. when a pairing button is pressed, then restore its previous
+// state. The bluez timeouts are set to the duration too, so that the adapter
+// is not left discoverable if the program exits.
+//
+// A DiscoverableEvent is emitted on "discoverable" when the window opens and
+// when it closes. Opening a window on an adapter with an open window extends
+// it and returns it
+func OpenDiscoverableWindow(adapterID string, duration time.Duration) (*DiscoverableWindow, error) {
+
+	windowsMutex.Lock()
+	defer windowsMutex.Unlock()
+
+	if w, ok := windows[adapterID]; ok {
+		extended, err := w.extend(duration)
+		if err != nil {
+			return nil, err
+		}
+		if extended {
+			return w, nil
+		}
+		// the window is ending, restore the state before opening a new one
+		delete(windows, adapterID)
+		w.close()
+	}
+
+	adapter := profile.NewAdapter1(adapterID)
+	props, err := adapter.GetProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &DiscoverableWindow{
+		AdapterID: adapterID,
+		adapter:   adapter,
+		previous:  *props,
+		done:      make(chan struct{}),
+	}
+	if err = w.set(true, timeoutSeconds(duration)); err != nil {
+		w.restore()
+		return nil, err
+	}
+	w.timer = time.AfterFunc(duration, func() {
+		w.Close()
+	})
+	windows[adapterID] = w
+
+	emitter.Emit("discoverable", DiscoverableEvent{AdapterID: adapterID, Status: StatusAdded})
+	return w, nil
+}
+
+// set the timeouts then the discoverable and pairable state
+func (w *DiscoverableWindow) set(on bool, timeout uint32) error {
+	if err := w.adapter.SetProperty("DiscoverableTimeout", timeout); err != nil {
+		return err
+	}
+	if err := w.adapter.SetProperty("PairableTimeout", timeout); err != nil {
+		return err
+	}
+	if err := w.adapter.SetProperty("Pairable", on); err != nil {
+		return err
+	}
+	return w.adapter.SetProperty("Discoverable", on)
+}
+
+// extend restart the window for a duration, it returns false if the window
+// is closing
+func (w *DiscoverableWindow) extend(duration time.Duration) (bool, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed || !w.timer.Stop() {
+		return false, nil
+	}
+	w.timer.Reset(duration)
+	return true, w.set(true, timeoutSeconds(duration))
+}
+
+// restore the state of the adapter before the window
+func (w *DiscoverableWindow) restore() error {
+	var first error
+	for _, p := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"Discoverable", w.previous.Discoverable},
+		{"Pairable", w.previous.Pairable},
+		{"DiscoverableTimeout", w.previous.DiscoverableTimeout},
+		{"PairableTimeout", w.previous.PairableTimeout},
+	} {
+		if err := w.adapter.SetProperty(p.name, p.value); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+//Close the window before its end, restoring the previous state of the
+// adapter. It returns the error restoring the state, also when the window
+// was already closed
+func (w *DiscoverableWindow) Close() error {
+
+	windowsMutex.Lock()
+	if windows[w.AdapterID] == w {
+		delete(windows, w.AdapterID)
+	}
+	windowsMutex.Unlock()
+	return w.close()
+}
+
+func (w *DiscoverableWindow) close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	w.timer.Stop()
+	w.err = w.restore()
+	close(w.done)
+
+	emitter.Emit("discoverable", DiscoverableEvent{AdapterID: w.AdapterID, Status: StatusRemoved})
+	return w.err
+}
+
+//Done is closed when the window closes
+func (w *DiscoverableWindow) Done() <-chan struct{} {
+	return w.done
+}
@@ -0,0 +1,50 @@
+package api_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+)
+
+func TestDiscoverableWindow(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	a := b.Adapter("hci0")
+	w, err := api.OpenDiscoverableWindow("hci0", 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Property("Discoverable").(bool) || a.Property("DiscoverableTimeout").(uint32) != 1 {
+		t.Fatalf("Expected discoverable for 1s, got %v %v", a.Property("Discoverable"), a.Property("DiscoverableTimeout"))
+	}
+
+	// opening again extends the window
+	time.Sleep(200 * time.Millisecond)
+	if again, err := api.OpenDiscoverableWindow("hci0", 300*time.Millisecond); err != nil || again != w {
+		t.Fatalf("Expected the window extended: %v", err)
+	}
+	select {
+	case <-w.Done():
+		t.Fatal("Window closed before its extension")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	select {
+	case <-w.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Window not closed")
+	}
+	if a.Property("Discoverable").(bool) || a.Property("DiscoverableTimeout").(uint32) != 180 {
+		t.Fatalf("Expected the state restored, got %v %v", a.Property("Discoverable"), a.Property("DiscoverableTimeout"))
+	}
+	if !a.Property("Pairable").(bool) || a.Property("PairableTimeout").(uint32) != 0 {
+		t.Fatalf("Expected pairable restored, got %v %v", a.Property("Pairable"), a.Property("PairableTimeout"))
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -69,6 +69,21 @@ type GattDescriptorEvent struct {
 	Status     EventStatus
 }
 
+// RecoveredEvent triggered when bluetoothd left the bus (Status StatusRemoved)
+// and once it is back and the registrations have been renewed (StatusAdded)
+type RecoveredEvent struct {
+	Status EventStatus
+	// Errors the registrations that could not be renewed
+	Errors map[string]error
+}
+
+// DiscoverableEvent triggered when a DiscoverableWindow opens (Status
+// StatusAdded) and when it closes (StatusRemoved)
+type DiscoverableEvent struct {
+	AdapterID string
+	Status    EventStatus
+}
+
 // DataEvent triggered when a new data value is available
 type DataEvent struct {
 	Device *Device
@@ -76,3 +91,83 @@ type DataEvent struct {
 	Value  interface{}
 	Unit   string
 }
+
+// PairingEvent is sent on the Agent event stream during a pairing process
+type PairingEvent interface {
+	GetDevice() *Device
+}
+
+// PasskeyDisplayEvent the passkey to be shown while the remote enters it
+type PasskeyDisplayEvent struct {
+	Device  *Device
+	Passkey uint32
+	Entered uint16
+}
+
+// PinCodeDisplayEvent the PIN code to be shown while the remote enters it
+type PinCodeDisplayEvent struct {
+	Device  *Device
+	PinCode string
+}
+
+// ConfirmationRequestEvent ask the user to confirm the passkey shown by the remote
+type ConfirmationRequestEvent struct {
+	Device  *Device
+	Passkey uint32
+	Accept  func(accept bool)
+}
+
+// AuthorizationRequestEvent ask the user to authorize an incoming pairing
+// or, when UUID is set, a connection to a service
+type AuthorizationRequestEvent struct {
+	Device *Device
+	UUID   string
+	Accept func(accept bool)
+}
+
+// PinCodeRequestEvent ask the user for the PIN code of a legacy device
+type PinCodeRequestEvent struct {
+	Device *Device
+	Reply  func(pincode string, accept bool)
+}
+
+// PasskeyRequestEvent ask the user for the passkey shown by the remote
+type PasskeyRequestEvent struct {
+	Device *Device
+	Reply  func(passkey uint32, accept bool)
+}
+
+// PairingCompleteEvent the device has been paired successfully
+type PairingCompleteEvent struct {
+	Device *Device
+}
+
+// PairingFailedEvent the pairing with the device failed
+type PairingFailedEvent struct {
+	Device *Device
+	Err    error
+}
+
+//GetDevice return the device being paired
+func (e PasskeyDisplayEvent) GetDevice() *Device { return e.Device }
+
+//GetDevice return the device being paired
+func (e PinCodeDisplayEvent) GetDevice() *Device { return e.Device }
+
+//GetDevice return the device being paired
+func (e ConfirmationRequestEvent) GetDevice() *Device { return e.Device }
+
+//GetDevice return the device being paired
+func (e AuthorizationRequestEvent) GetDevice() *Device { return e.Device }
+
+//GetDevice return the device being paired
+func (e PinCodeRequestEvent) GetDevice() *Device { return e.Device }
+
+//GetDevice return the device being paired
+func (e PasskeyRequestEvent) GetDevice() *Device { return e.Device }
+
+//GetDevice return the device being paired
+func (e PairingCompleteEvent) GetDevice() *Device { return e.Device }
+
+//GetDevice return the device being paired
+func (e PairingFailedEvent) GetDevice() *Device { return e.Device }
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/emitter"
+)
+
+//Filter select the devices found by FindDevice, the empty fields match any
+// device
+type Filter struct {
+	// AdapterID the adapter discovering the devices, defaults to hci0
+	AdapterID string
+	// NamePrefix the beginning of the name, case insensitive
+	NamePrefix string
+	// NamePattern a regular expression matching the name
+	NamePattern *regexp.Regexp
+	// Address the address of the device, case insensitive
+	Address string
+	// ServiceUUID a service advertised by the device, in any form
+	ServiceUUID string
+}
+
+//Match return true if the properties of a device match the filter
+func (f *Filter) Match(props *profile.Device1Properties) bool {
+	if f.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(props.Name), strings.ToLower(f.NamePrefix)) {
+		return false
+	}
+	if f.NamePattern != nil && !f.NamePattern.MatchString(props.Name) {
+		return false
+	}
+	if f.Address != "" && !strings.EqualFold(props.Address, f.Address) {
+		return false
+	}
+	if f.ServiceUUID != "" {
+		for _, uuid := range props.UUIDs {
+			if bluetooth.EqualUUID(uuid, f.ServiceUUID) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+//FindDevice return the first device of the adapter matching the filter: a
+// device already known to bluez, else the first one found by a discovery,
+// stopped on return. The devices are checked again when their properties
+// change, eg. a name or services received after the device was found. Bound
+// the search with the context, eg. context.WithTimeout
+func FindDevice(ctx context.Context, filter Filter) (*Device, error) {
+
+	if filter.ServiceUUID != "" {
+		if _, err := bluetooth.ParseUUID(filter.ServiceUUID); err != nil {
+			return nil, err
+		}
+	}
+	adapterID := filter.AdapterID
+	if adapterID == "" {
+		adapterID = "hci0"
+	}
+	adapterPath := dbus.ObjectPath("/org/bluez/" + adapterID)
+
+	found := make(chan *Device, 1)
+	check := func(dev *Device) {
+		props, err := dev.GetProperties()
+		if err != nil || props.Adapter != adapterPath || !filter.Match(props) {
+			return
+		}
+		select {
+		case found <- dev:
+		default:
+		}
+	}
+
+	cb := emitter.NewCallback(func(ev emitter.Event) {
+		discovery := ev.GetData().(DiscoveredDeviceEvent)
+		if discovery.Status == DeviceAdded {
+			check(discovery.Device)
+		}
+	})
+	if err := On("discovery", cb); err != nil {
+		return nil, err
+	}
+	defer Off("discovery", cb)
+
+	conn, err := bluez.GetConnection(bluez.SystemBus)
+	if err != nil {
+		return nil, err
+	}
+	dispatcher := bluez.GetSignalDispatcher(conn)
+	signals, err := dispatcher.Subscribe(bluez.SignalFilter{
+		Path:          adapterPath,
+		PathNamespace: true,
+		Interface:     bluez.PropertiesInterface,
+		Member:        "PropertiesChanged",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer dispatcher.Unsubscribe(signals)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				if deviceChanged(sig) {
+					check(NewDevice(string(sig.Path)))
+				}
+			}
+		}
+	}()
+
+	devices, err := GetDevices()
+	if err != nil {
+		return nil, err
+	}
+	for i := range devices {
+		check(&devices[i])
+	}
+	select {
+	case dev := <-found:
+		return dev, nil
+	default:
+	}
+
+	adapter, err := GetAdapter(adapterID)
+	if err != nil {
+		return nil, err
+	}
+	err = adapter.StartDiscovery()
+	if err == nil {
+		defer adapter.StopDiscovery()
+	} else if !bluez.IsError(err, bluez.ErrInProgress) {
+		// a discovery in progress is shared
+		return nil, err
+	}
+
+	select {
+	case dev := <-found:
+		return dev, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// deviceChanged return true if a PropertiesChanged signal changes a property
+// of a device matched by a Filter
+func deviceChanged(sig *dbus.Signal) bool {
+	if sig == nil || len(sig.Body) < 2 {
+		return false
+	}
+	if iface, _ := sig.Body[0].(string); iface != bluez.Device1Interface {
+		return false
+	}
+	changed, _ := sig.Body[1].(map[string]dbus.Variant)
+	for _, name := range []string{"Name", "Address", "UUIDs"} {
+		if _, ok := changed[name]; ok {
+			return true
+		}
+	}
+	return false
+}
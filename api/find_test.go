@@ -0,0 +1,93 @@
+package api_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+func TestFilterMatch(t *testing.T) {
+
+	props := &profile.Device1Properties{
+		Name:    "Thermometer 42",
+		Address: "AA:BB:CC:DD:EE:FF",
+		UUIDs:   []string{"0000180a-0000-1000-8000-00805f9b34fb", "00001809-0000-1000-8000-00805f9b34fb"},
+	}
+
+	tests := []struct {
+		name   string
+		filter api.Filter
+		match  bool
+	}{
+		{"empty", api.Filter{}, true},
+		{"prefix", api.Filter{NamePrefix: "thermo"}, true},
+		{"other prefix", api.Filter{NamePrefix: "hygro"}, false},
+		{"pattern", api.Filter{NamePattern: regexp.MustCompile(`\d+$`)}, true},
+		{"other pattern", api.Filter{NamePattern: regexp.MustCompile(`^\d`)}, false},
+		{"address", api.Filter{Address: "aa:bb:cc:dd:ee:ff"}, true},
+		{"other address", api.Filter{Address: "AA:BB:CC:DD:EE:00"}, false},
+		{"short service", api.Filter{ServiceUUID: "1809"}, true},
+		{"long service", api.Filter{ServiceUUID: "0000180A-0000-1000-8000-00805F9B34FB"}, true},
+		{"other service", api.Filter{ServiceUUID: "180f"}, false},
+		{"all", api.Filter{NamePrefix: "Thermo", Address: "AA:BB:CC:DD:EE:FF", ServiceUUID: "1809"}, true},
+		{"all but one", api.Filter{NamePrefix: "Thermo", Address: "AA:BB:CC:DD:EE:FF", ServiceUUID: "180f"}, false},
+	}
+	for _, test := range tests {
+		if match := test.filter.Match(props); match != test.match {
+			t.Errorf("%s: expected %v, got %v", test.name, test.match, match)
+		}
+	}
+
+	// a device without name nor services
+	if (&api.Filter{NamePrefix: "a"}).Match(&profile.Device1Properties{}) {
+		t.Error("Expected no match without name")
+	}
+	if (&api.Filter{ServiceUUID: "1809"}).Match(&profile.Device1Properties{}) {
+		t.Error("Expected no match without services")
+	}
+}
+
+func TestFindDevice(t *testing.T) {
+
+	b, _ := startBluez(t)
+	defer b.Close()
+	if _, err := b.AddAdapter("hci1", "00:11:22:33:44:66"); err != nil {
+		t.Fatal(err)
+	}
+
+	// known to the other adapter only
+	if _, err := b.AddDevice("hci1", "00:00:00:00:00:01", "Sensor"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if dev, err := api.FindDevice(ctx, api.Filter{NamePrefix: "sensor"}); err == nil {
+		t.Fatalf("Expected no device on hci0, found %s", dev.Path)
+	}
+
+	// found nameless, named later
+	dev, err := b.AddDevice("hci0", "00:00:00:00:00:02", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		dev.SetProperty("Name", "Sensor 2")
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	found, err := api.FindDevice(ctx, api.Filter{NamePrefix: "sensor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Path != string(dev.Path) {
+		t.Fatalf("Expected %s, found %s", dev.Path, found.Path)
+	}
+	if b.Adapter("hci0").Property("Discovering").(bool) {
+		t.Fatal("The discovery is still running")
+	}
+}
@@ -0,0 +1,396 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/util"
+)
+
+//GattDatabase the description of the GATT database of a remote device, see
+// Device.ExportGATT
+type GattDatabase struct {
+	Address  string            `json:"address,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Services []GattServiceInfo `json:"services"`
+}
+
+//GattServiceInfo a service of a GattDatabase
+type GattServiceInfo struct {
+	UUID            string                   `json:"uuid"`
+	Name            string                   `json:"name,omitempty"`
+	Handle          uint16                   `json:"handle"`
+	Primary         bool                     `json:"primary"`
+	Characteristics []GattCharacteristicInfo `json:"characteristics"`
+}
+
+//GattCharacteristicInfo a characteristic of a GattDatabase
+type GattCharacteristicInfo struct {
+	UUID        string               `json:"uuid"`
+	Name        string               `json:"name,omitempty"`
+	Handle      uint16               `json:"handle"`
+	Flags       []string             `json:"flags"`
+	Value       HexValue             `json:"value,omitempty"`
+	Descriptors []GattDescriptorInfo `json:"descriptors,omitempty"`
+}
+
+//GattDescriptorInfo a descriptor of a GattDatabase
+type GattDescriptorInfo struct {
+	UUID   string   `json:"uuid"`
+	Name   string   `json:"name,omitempty"`
+	Handle uint16   `json:"handle"`
+	Value  HexValue `json:"value,omitempty"`
+}
+
+//HexValue a value encoded in hex in the JSON description
+type HexValue []byte
+
+//MarshalText encode the value in hex
+func (v HexValue) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(v)), nil
+}
+
+//UnmarshalText decode the hex value
+func (v *HexValue) UnmarshalText(b []byte) error {
+	d, err := hex.DecodeString(string(b))
+	if err != nil {
+		return fmt.Errorf("Invalid value %q: %s", b, err)
+	}
+	*v = d
+	return nil
+}
+
+//ExportGATTOptions the options of Device.ExportGATT
+type ExportGATTOptions struct {
+	// Values read the readable characteristics and descriptors. When a read
+	// fails, eg. on an encrypted characteristic, the value cached by bluez is
+	// used, if any
+	Values bool
+}
+
+//ExportGATT describe the services, characteristics and descriptors resolved
+// on the device, ordered by handle. The device must be connected and its
+// services resolved
+func (d *Device) ExportGATT(options ExportGATTOptions) (*GattDatabase, error) {
+
+	manager, err := GetManager()
+	if err != nil {
+		return nil, err
+	}
+
+	db := &GattDatabase{}
+	if props, err := d.GetProperties(); err == nil {
+		db.Address = props.Address
+		db.Name = props.Name
+	}
+
+	var read func(path dbus.ObjectPath, iface string) ([]byte, error)
+	if options.Values {
+		read = readGattValue
+	}
+
+	db.Services, err = exportServices(*manager.GetObjects(), dbus.ObjectPath(d.Path), read)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+//ExportGATTJSON return the indented JSON of ExportGATT
+func (d *Device) ExportGATTJSON(options ExportGATTOptions) ([]byte, error) {
+	db, err := d.ExportGATT(options)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(db, "", "  ")
+}
+
+//ImportGATT decode the JSON description of a GATT database
+func ImportGATT(b []byte) (*GattDatabase, error) {
+	db := new(GattDatabase)
+	if err := json.Unmarshal(b, db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func readGattValue(path dbus.ObjectPath, iface string) ([]byte, error) {
+	if iface == bluez.GattDescriptor1Interface {
+		return profile.NewGattDescriptor1(string(path)).ReadValue(nil)
+	}
+	char, err := profile.NewGattCharacteristic1(string(path))
+	if err != nil {
+		return nil, err
+	}
+	return char.ReadValue(nil)
+}
+
+func exportServices(objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant,
+	device dbus.ObjectPath, read func(path dbus.ObjectPath, iface string) ([]byte, error)) ([]GattServiceInfo, error) {
+
+	prefix := string(device) + "/"
+	paths := []dbus.ObjectPath{}
+	for path := range objects {
+		if strings.HasPrefix(string(path), prefix) {
+			paths = append(paths, path)
+		}
+	}
+	// the handles are in the path, the services come before their
+	// characteristics and descriptors
+	sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
+
+	services := []GattServiceInfo{}
+	serviceIndex := map[dbus.ObjectPath]int{}
+	charIndex := map[dbus.ObjectPath][2]int{}
+
+	for _, path := range paths {
+		ifaces := objects[path]
+
+		if props, ok := ifaces[bluez.GattService1Interface]; ok {
+			p := new(profile.GattService1Properties)
+			if err := util.MapToStruct(p, props); err != nil {
+				return nil, err
+			}
+			serviceIndex[path] = len(services)
+			services = append(services, GattServiceInfo{
+				UUID:            strings.ToLower(p.UUID),
+				Name:            uuidName(p.UUID),
+				Handle:          pathHandle(path),
+				Primary:         p.Primary,
+				Characteristics: []GattCharacteristicInfo{},
+			})
+			continue
+		}
+
+		if props, ok := ifaces[bluez.GattCharacteristic1Interface]; ok {
+			p := new(profile.GattCharacteristic1Properties)
+			if err := util.MapToStruct(p, props); err != nil {
+				return nil, err
+			}
+			i, ok := serviceIndex[p.Service]
+			if !ok {
+				continue
+			}
+			c := GattCharacteristicInfo{
+				UUID:   strings.ToLower(p.UUID),
+				Name:   uuidName(p.UUID),
+				Handle: pathHandle(path),
+				Flags:  p.Flags,
+			}
+			if read != nil {
+				c.Value = readValue(read, path, bluez.GattCharacteristic1Interface, p.Flags, p.Value)
+			}
+			charIndex[path] = [2]int{i, len(services[i].Characteristics)}
+			services[i].Characteristics = append(services[i].Characteristics, c)
+			continue
+		}
+
+		if props, ok := ifaces[bluez.GattDescriptor1Interface]; ok {
+			p := new(profile.GattDescriptor1Properties)
+			if err := util.MapToStruct(p, props); err != nil {
+				return nil, err
+			}
+			index, ok := charIndex[p.Characteristic]
+			if !ok {
+				continue
+			}
+			desc := GattDescriptorInfo{
+				UUID:   strings.ToLower(p.UUID),
+				Name:   uuidName(p.UUID),
+				Handle: pathHandle(path),
+			}
+			if read != nil {
+				desc.Value = readValue(read, path, bluez.GattDescriptor1Interface, p.Flags, p.Value)
+			}
+			c := &services[index[0]].Characteristics[index[1]]
+			c.Descriptors = append(c.Descriptors, desc)
+		}
+	}
+
+	return services, nil
+}
+
+// readValue read the value of a readable attribute, fallback to the cached
+// one
+func readValue(read func(path dbus.ObjectPath, iface string) ([]byte, error),
+	path dbus.ObjectPath, iface string, flags []string, cached []byte) HexValue {
+
+	readable := len(flags) == 0
+	for _, flag := range flags {
+		if strings.Contains(flag, "read") {
+			readable = true
+		}
+	}
+	if readable {
+		if v, err := read(path, iface); err == nil {
+			return v
+		}
+	}
+	return cached
+}
+
+// pathHandle return the handle of an attribute from the last element of its
+// path, eg. char000b
+func pathHandle(path dbus.ObjectPath) uint16 {
+	s := string(path)
+	if len(s) < 4 {
+		return 0
+	}
+	v, err := strconv.ParseUint(s[len(s)-4:], 16, 16)
+	if err != nil {
+		return 0
+	}
+	return uint16(v)
+}
+
+func uuidName(s string) string {
+	u, err := bluetooth.ParseUUID(s)
+	if err != nil {
+		return ""
+	}
+	return u.Name()
+}
+
+//GattDifference a difference between two GATT databases, see DiffGATT
+type GattDifference struct {
+	// Path the UUIDs of the service, characteristic and descriptor, eg.
+	// 180f/2a19
+	Path string
+	// Change one of added, removed, flags, value
+	Change string
+	From   string
+	To     string
+}
+
+func (d GattDifference) String() string {
+	switch d.Change {
+	case "added", "removed":
+		return fmt.Sprintf("%s %s", d.Change, d.Path)
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", d.Change, d.Path, d.From, d.To)
+}
+
+//DiffGATT compare two GATT databases, eg. a reference export and the one of
+// a device of the fleet. The attributes are matched by UUID, in order when a
+// UUID is repeated; the handles are not compared. The values are compared
+// only when both are exported
+func DiffGATT(a, b *GattDatabase) []GattDifference {
+
+	diff := []GattDifference{}
+
+	aServices := map[string]GattServiceInfo{}
+	bServices := map[string]GattServiceInfo{}
+	aKeys := serviceKeys(a.Services, aServices)
+	bKeys := serviceKeys(b.Services, bServices)
+
+	for _, key := range aKeys {
+		bs, ok := bServices[key]
+		if !ok {
+			diff = append(diff, GattDifference{Path: key, Change: "removed"})
+			continue
+		}
+		diff = append(diff, diffCharacteristics(key, aServices[key].Characteristics, bs.Characteristics)...)
+	}
+	for _, key := range bKeys {
+		if _, ok := aServices[key]; !ok {
+			diff = append(diff, GattDifference{Path: key, Change: "added"})
+		}
+	}
+	return diff
+}
+
+func diffCharacteristics(prefix string, a, b []GattCharacteristicInfo) []GattDifference {
+
+	diff := []GattDifference{}
+
+	aChars := map[string]GattCharacteristicInfo{}
+	bChars := map[string]GattCharacteristicInfo{}
+	aKeys := uniqueKeys(len(a), func(i int) string { return a[i].UUID }, func(i int, key string) { aChars[key] = a[i] })
+	bKeys := uniqueKeys(len(b), func(i int) string { return b[i].UUID }, func(i int, key string) { bChars[key] = b[i] })
+
+	for _, key := range aKeys {
+		path := prefix + "/" + key
+		ac := aChars[key]
+		bc, ok := bChars[key]
+		if !ok {
+			diff = append(diff, GattDifference{Path: path, Change: "removed"})
+			continue
+		}
+		if from, to := strings.Join(ac.Flags, ","), strings.Join(bc.Flags, ","); from != to {
+			diff = append(diff, GattDifference{Path: path, Change: "flags", From: from, To: to})
+		}
+		if ac.Value != nil && bc.Value != nil && hex.EncodeToString(ac.Value) != hex.EncodeToString(bc.Value) {
+			diff = append(diff, GattDifference{Path: path, Change: "value",
+				From: hex.EncodeToString(ac.Value), To: hex.EncodeToString(bc.Value)})
+		}
+		diff = append(diff, diffDescriptors(path, ac.Descriptors, bc.Descriptors)...)
+	}
+	for _, key := range bKeys {
+		if _, ok := aChars[key]; !ok {
+			diff = append(diff, GattDifference{Path: prefix + "/" + key, Change: "added"})
+		}
+	}
+	return diff
+}
+
+func diffDescriptors(prefix string, a, b []GattDescriptorInfo) []GattDifference {
+
+	diff := []GattDifference{}
+
+	aDescs := map[string]GattDescriptorInfo{}
+	bDescs := map[string]GattDescriptorInfo{}
+	aKeys := uniqueKeys(len(a), func(i int) string { return a[i].UUID }, func(i int, key string) { aDescs[key] = a[i] })
+	bKeys := uniqueKeys(len(b), func(i int) string { return b[i].UUID }, func(i int, key string) { bDescs[key] = b[i] })
+
+	for _, key := range aKeys {
+		path := prefix + "/" + key
+		bd, ok := bDescs[key]
+		if !ok {
+			diff = append(diff, GattDifference{Path: path, Change: "removed"})
+			continue
+		}
+		ad := aDescs[key]
+		if ad.Value != nil && bd.Value != nil && hex.EncodeToString(ad.Value) != hex.EncodeToString(bd.Value) {
+			diff = append(diff, GattDifference{Path: path, Change: "value",
+				From: hex.EncodeToString(ad.Value), To: hex.EncodeToString(bd.Value)})
+		}
+	}
+	for _, key := range bKeys {
+		if _, ok := aDescs[key]; !ok {
+			diff = append(diff, GattDifference{Path: prefix + "/" + key, Change: "added"})
+		}
+	}
+	return diff
+}
+
+func serviceKeys(list []GattServiceInfo, m map[string]GattServiceInfo) []string {
+	return uniqueKeys(len(list), func(i int) string { return list[i].UUID }, func(i int, key string) { m[key] = list[i] })
+}
+
+// uniqueKeys return the short UUIDs of a list as keys, suffixed with #n
+// when repeated, eg. two instances of a service
+func uniqueKeys(n int, uuid func(i int) string, set func(i int, key string)) []string {
+	keys := make([]string, n)
+	seen := map[string]int{}
+	for i := 0; i < n; i++ {
+		key := uuid(i)
+		if u, err := bluetooth.ParseUUID(key); err == nil {
+			key = strings.ToLower(u.ShortString())
+		}
+		seen[key]++
+		if seen[key] > 1 {
+			key = fmt.Sprintf("%s#%d", key, seen[key])
+		}
+		keys[i] = key
+		set(i, key)
+	}
+	return keys
+}
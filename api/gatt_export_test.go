@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testDatabase() *GattDatabase {
+	return &GattDatabase{
+		Address: "AA:BB:CC:DD:EE:FF",
+		Name:    "Remote",
+		Services: []GattServiceInfo{
+			{
+				UUID:    "0000180f-0000-1000-8000-00805f9b34fb",
+				Name:    "Battery Service",
+				Handle:  0x000a,
+				Primary: true,
+				Characteristics: []GattCharacteristicInfo{
+					{
+						UUID:   "00002a19-0000-1000-8000-00805f9b34fb",
+						Handle: 0x000b,
+						Flags:  []string{"read", "notify"},
+						Value:  HexValue{0x64},
+						Descriptors: []GattDescriptorInfo{
+							{UUID: "00002902-0000-1000-8000-00805f9b34fb", Handle: 0x000d, Value: HexValue{0x00, 0x00}},
+						},
+					},
+				},
+			},
+			{
+				UUID:    "0000180a-0000-1000-8000-00805f9b34fb",
+				Handle:  0x0010,
+				Primary: true,
+				Characteristics: []GattCharacteristicInfo{
+					{UUID: "00002a24-0000-1000-8000-00805f9b34fb", Handle: 0x0011, Flags: []string{"read"}, Value: HexValue("model")},
+				},
+			},
+		},
+	}
+}
+
+func TestDiffGATT(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		change func(db *GattDatabase)
+		diff   []GattDifference
+	}{
+		{"same", func(db *GattDatabase) {}, []GattDifference{}},
+		{"handles", func(db *GattDatabase) {
+			db.Services[0].Handle = 0x0020
+			db.Services[0].Characteristics[0].Handle = 0x0021
+		}, []GattDifference{}},
+		{"service removed", func(db *GattDatabase) {
+			db.Services = db.Services[:1]
+		}, []GattDifference{{Path: "180a", Change: "removed"}}},
+		{"service added", func(db *GattDatabase) {
+			db.Services = append(db.Services, GattServiceInfo{UUID: "00001809-0000-1000-8000-00805f9b34fb"})
+		}, []GattDifference{{Path: "1809", Change: "added"}}},
+		{"service repeated", func(db *GattDatabase) {
+			db.Services = append(db.Services, GattServiceInfo{UUID: "0000180F-0000-1000-8000-00805F9B34FB"})
+		}, []GattDifference{{Path: "180f#2", Change: "added"}}},
+		{"characteristic removed", func(db *GattDatabase) {
+			db.Services[1].Characteristics = nil
+		}, []GattDifference{{Path: "180a/2a24", Change: "removed"}}},
+		{"characteristic added", func(db *GattDatabase) {
+			db.Services[1].Characteristics = append(db.Services[1].Characteristics,
+				GattCharacteristicInfo{UUID: "00002a29-0000-1000-8000-00805f9b34fb", Flags: []string{"read"}})
+		}, []GattDifference{{Path: "180a/2a29", Change: "added"}}},
+		{"flags", func(db *GattDatabase) {
+			db.Services[0].Characteristics[0].Flags = []string{"read"}
+		}, []GattDifference{{Path: "180f/2a19", Change: "flags", From: "read,notify", To: "read"}}},
+		{"value", func(db *GattDatabase) {
+			db.Services[0].Characteristics[0].Value = HexValue{0x32}
+		}, []GattDifference{{Path: "180f/2a19", Change: "value", From: "64", To: "32"}}},
+		{"value not exported", func(db *GattDatabase) {
+			db.Services[0].Characteristics[0].Value = nil
+		}, []GattDifference{}},
+		{"descriptor removed", func(db *GattDatabase) {
+			db.Services[0].Characteristics[0].Descriptors = nil
+		}, []GattDifference{{Path: "180f/2a19/2902", Change: "removed"}}},
+		{"descriptor added", func(db *GattDatabase) {
+			db.Services[1].Characteristics[0].Descriptors = []GattDescriptorInfo{{UUID: "00002901-0000-1000-8000-00805f9b34fb"}}
+		}, []GattDifference{{Path: "180a/2a24/2901", Change: "added"}}},
+		{"descriptor value", func(db *GattDatabase) {
+			db.Services[0].Characteristics[0].Descriptors[0].Value = HexValue{0x01, 0x00}
+		}, []GattDifference{{Path: "180f/2a19/2902", Change: "value", From: "0000", To: "0100"}}},
+		{"several", func(db *GattDatabase) {
+			db.Services[0].Characteristics[0].Flags = []string{"read"}
+			db.Services[0].Characteristics[0].Value = HexValue{0x32}
+			db.Services = db.Services[:1]
+		}, []GattDifference{
+			{Path: "180f/2a19", Change: "flags", From: "read,notify", To: "read"},
+			{Path: "180f/2a19", Change: "value", From: "64", To: "32"},
+			{Path: "180a", Change: "removed"},
+		}},
+	}
+	for _, test := range tests {
+		b := testDatabase()
+		test.change(b)
+		if diff := DiffGATT(testDatabase(), b); !reflect.DeepEqual(diff, test.diff) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.diff, diff)
+		}
+	}
+}
+
+func TestDiffCharacteristics(t *testing.T) {
+
+	a := []GattCharacteristicInfo{
+		{UUID: "2a19", Flags: []string{"read"}},
+		{UUID: "2a19", Flags: []string{"read"}, Value: HexValue{0x01}},
+	}
+
+	tests := []struct {
+		name string
+		b    []GattCharacteristicInfo
+		diff []GattDifference
+	}{
+		{"same", a, []GattDifference{}},
+		{"none", nil, []GattDifference{
+			{Path: "180f/2a19", Change: "removed"},
+			{Path: "180f/2a19#2", Change: "removed"},
+		}},
+		{"repeated in order", []GattCharacteristicInfo{
+			{UUID: "00002A19-0000-1000-8000-00805F9B34FB", Flags: []string{"read"}},
+			{UUID: "2a19", Flags: []string{"read", "write"}, Value: HexValue{0x02}},
+		}, []GattDifference{
+			{Path: "180f/2a19#2", Change: "flags", From: "read", To: "read,write"},
+			{Path: "180f/2a19#2", Change: "value", From: "01", To: "02"},
+		}},
+		{"added", append(append([]GattCharacteristicInfo{}, a...), GattCharacteristicInfo{UUID: "2a19"}), []GattDifference{
+			{Path: "180f/2a19#3", Change: "added"},
+		}},
+	}
+	for _, test := range tests {
+		if diff := diffCharacteristics("180f", a, test.b); !reflect.DeepEqual(diff, test.diff) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.diff, diff)
+		}
+	}
+}
+
+func TestDiffDescriptors(t *testing.T) {
+
+	a := []GattDescriptorInfo{
+		{UUID: "2902", Value: HexValue{0x00, 0x00}},
+		{UUID: "2901", Value: HexValue("level")},
+	}
+
+	tests := []struct {
+		name string
+		b    []GattDescriptorInfo
+		diff []GattDifference
+	}{
+		{"same", a, []GattDifference{}},
+		{"order", []GattDescriptorInfo{a[1], a[0]}, []GattDifference{}},
+		{"removed", a[:1], []GattDifference{{Path: "180f/2a19/2901", Change: "removed"}}},
+		{"added", append(append([]GattDescriptorInfo{}, a...), GattDescriptorInfo{UUID: "2904"}), []GattDifference{
+			{Path: "180f/2a19/2904", Change: "added"},
+		}},
+		{"value", []GattDescriptorInfo{{UUID: "2902", Value: HexValue{0x01, 0x00}}, a[1]}, []GattDifference{
+			{Path: "180f/2a19/2902", Change: "value", From: "0000", To: "0100"},
+		}},
+		{"value not exported", []GattDescriptorInfo{{UUID: "2902"}, {UUID: "2901"}}, []GattDifference{}},
+	}
+	for _, test := range tests {
+		if diff := diffDescriptors("180f/2a19", a, test.b); !reflect.DeepEqual(diff, test.diff) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.diff, diff)
+		}
+	}
+}
+
+func TestGattDatabaseJSON(t *testing.T) {
+
+	db := testDatabase()
+	b, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ImportGATT(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported, db) {
+		t.Fatalf("Expected %+v, got %+v", db, imported)
+	}
+	if diff := DiffGATT(db, imported); len(diff) != 0 {
+		t.Fatalf("Expected no difference, got %v", diff)
+	}
+
+	// the values are in hex
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		t.Fatal(err)
+	}
+	char := raw["services"].([]interface{})[0].(map[string]interface{})["characteristics"].([]interface{})[0].(map[string]interface{})
+	if char["value"] != "64" {
+		t.Fatalf("Expected the value in hex, got %v", char["value"])
+	}
+
+	if _, err := ImportGATT([]byte(`{"services":[{"uuid":"180f","characteristics":[{"uuid":"2a19","value":"zz"}]}]}`)); err == nil {
+		t.Fatal("Expected an error on an invalid value")
+	}
+}
@@ -0,0 +1,98 @@
+package api_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+)
+
+// startGatt start a fake device with a battery level and a model number
+func startGatt(t *testing.T) (*bluetest.Bluez, *bluetest.Device, *bluetest.Characteristic, *bluetest.Characteristic) {
+	b, dev := startBluez(t)
+	read := []string{bluez.FlagCharacteristicRead}
+	level, err := dev.AddCharacteristic("180f", "2a19", read, []byte{0x64})
+	if err != nil {
+		b.Close()
+		t.Fatal(err)
+	}
+	model, err := dev.AddCharacteristic("180a", "2a24", read, []byte("model"))
+	if err != nil {
+		b.Close()
+		t.Fatal(err)
+	}
+	return b, dev, level, model
+}
+
+func TestReadAll(t *testing.T) {
+
+	b, dev, level, _ := startGatt(t)
+	defer b.Close()
+
+	// a transient error, then a disconnection reported as Failed
+	level.FailNext(bluez.ErrInProgress, bluez.ErrFailed.WithMessage("Not connected"))
+
+	values, err := api.NewDevice(string(dev.Path)).ReadAll("2a19", "00002A24-0000-1000-8000-00805F9B34FB", "2a19")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || !bytes.Equal(values["2a19"], []byte{0x64}) ||
+		string(values["00002A24-0000-1000-8000-00805F9B34FB"]) != "model" {
+		t.Fatalf("Unexpected values %v", values)
+	}
+	if !dev.Property("Connected").(bool) {
+		t.Fatal("Expected the device connected")
+	}
+}
+
+func TestReadAllReconnect(t *testing.T) {
+
+	b, dev, _, model := startGatt(t)
+	defer b.Close()
+
+	device := api.NewDevice(string(dev.Path))
+	if _, err := device.ReadAll("2a19"); err != nil {
+		t.Fatal(err)
+	}
+
+	// disconnected meanwhile
+	dev.SetProperty("Connected", false)
+	model.FailNext(bluez.ErrNotConnected)
+	values, err := device.ReadAll("2a24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(values["2a24"]) != "model" {
+		t.Fatalf("Unexpected values %v", values)
+	}
+}
+
+func TestReadAllErrors(t *testing.T) {
+
+	b, dev, level, _ := startGatt(t)
+	defer b.Close()
+	device := api.NewDevice(string(dev.Path))
+
+	level.FailNext(bluez.ErrNotPermitted)
+	_, err := device.ReadAll("2a24", "2a19")
+	readErr, ok := err.(*api.ReadError)
+	if !ok || readErr.UUID != "2a19" {
+		t.Fatalf("Expected a read error of 2a19, got %v", err)
+	}
+	if !bluez.IsError(err, bluez.ErrNotPermitted) {
+		t.Fatalf("Expected the cause to be NotPermitted, got %v", readErr.Err)
+	}
+
+	// the retries give up
+	level.FailNext(bluez.ErrInProgress, bluez.ErrInProgress, bluez.ErrInProgress)
+	if _, err = device.ReadAll("2a19"); !bluez.IsError(err, bluez.ErrInProgress) {
+		t.Fatalf("Expected InProgress, got %v", err)
+	}
+
+	_, err = device.ReadAll("2a00")
+	if readErr, ok := err.(*api.ReadError); !ok || readErr.UUID != "2a00" {
+		t.Fatalf("Expected a read error of 2a00, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package bluetooth
+
+import "fmt"
+
+// The tables of assigned_gen.go are generated from a checkout of the public
+// repository of the Bluetooth SIG, https://bitbucket.org/bluetooth-SIG/public
+//go:generate go run gen/main.go -out assigned_gen.go $BLUETOOTH_SIG_PUBLIC
+
+//ServiceName return the name of a service of the assigned numbers, or of the
+// owner of a 16bit UUID assigned to a member, empty if unknown
+func ServiceName(u UUID) string {
+	v, ok := u.Uint16()
+	if !ok {
+		return ""
+	}
+	if name, ok := serviceNames[v]; ok {
+		return name
+	}
+	return memberNames[v]
+}
+
+//CharacteristicName return the name of a characteristic of the assigned
+// numbers, empty if unknown
+func CharacteristicName(u UUID) string {
+	return lookup(characteristicNames, u)
+}
+
+//DescriptorName return the name of a descriptor of the assigned numbers,
+// empty if unknown
+func DescriptorName(u UUID) string {
+	return lookup(descriptorNames, u)
+}
+
+//CompanyName return the name of a company identifier, eg. the first two bytes
+// of the manufacturer data, empty if unknown
+func CompanyName(id uint16) string {
+	return companyNames[id]
+}
+
+//DescribeCompany return the identifier followed by the name, if known, eg.
+// "0x004C (Apple, Inc.)", for the logs
+func DescribeCompany(id uint16) string {
+	if name := CompanyName(id); name != "" {
+		return fmt.Sprintf("0x%04X (%s)", id, name)
+	}
+	return fmt.Sprintf("0x%04X", id)
+}
+
+//DescribeUUID return the description of a UUID string, see UUID.Describe, or
+// the string unchanged if it is not a valid UUID
+func DescribeUUID(s string) string {
+	u, err := ParseUUID(s)
+	if err != nil {
+		return s
+	}
+	return u.Describe()
+}
+
+// assignedName return the name of any of the tables, their 16bit ranges do
+// not overlap
+func assignedName(u UUID) string {
+	if name := lookup(declarationNames, u); name != "" {
+		return name
+	}
+	if name := ServiceName(u); name != "" {
+		return name
+	}
+	if name := CharacteristicName(u); name != "" {
+		return name
+	}
+	return DescriptorName(u)
+}
+
+func lookup(table map[uint16]string, u UUID) string {
+	v, ok := u.Uint16()
+	if !ok {
+		return ""
+	}
+	return table[v]
+}
@@ -0,0 +1,40 @@
+package bluetooth
+
+import "testing"
+
+func TestAssignedNames(t *testing.T) {
+	if name := ServiceName(UUID16(0x180F)); name != "Battery Service" {
+		t.Fatalf("Unexpected service name %q", name)
+	}
+	if name := ServiceName(UUID16(0xFE59)); name != "Nordic Semiconductor ASA" {
+		t.Fatalf("Unexpected member name %q", name)
+	}
+	if name := CharacteristicName(UUID16(0x2A19)); name != "Battery Level" {
+		t.Fatalf("Unexpected characteristic name %q", name)
+	}
+	if name := CharacteristicName(UUID16(0x180F)); name != "" {
+		t.Fatalf("Unexpected characteristic name %q", name)
+	}
+	if name := UUID16(0x2803).Name(); name != "Characteristic" {
+		t.Fatalf("Unexpected declaration name %q", name)
+	}
+	if d := DescribeUUID("2a37"); d != "2A37 (Heart Rate Measurement)" {
+		t.Fatalf("Unexpected description %s", d)
+	}
+	if d := DescribeUUID("invalid"); d != "invalid" {
+		t.Fatalf("Unexpected description %s", d)
+	}
+	if d := DescribeCompany(0x004C); d != "0x004C (Apple, Inc.)" {
+		t.Fatalf("Unexpected description %s", d)
+	}
+	if d := DescribeCompany(0xFFFE); d != "0xFFFE" {
+		t.Fatalf("Unexpected description %s", d)
+	}
+
+	u := UUID16(0x2A6E)
+	RegisterUUIDName(u, "Probe Temperature")
+	defer RegisterUUIDName(u, CharacteristicName(u))
+	if name := u.Name(); name != "Probe Temperature" {
+		t.Fatalf("Unexpected registered name %q", name)
+	}
+}
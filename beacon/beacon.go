@@ -0,0 +1,215 @@
+//Package beacon decode the iBeacon and Eddystone beacons from the advertising
+// data, eg. of a passive scan with hci.Socket.Scan
+package beacon
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/linux/hci"
+)
+
+//Type the format of a beacon
+type Type string
+
+// Types of beacons
+const (
+	IBeacon      Type = "ibeacon"
+	EddystoneUID Type = "eddystone-uid"
+	EddystoneURL Type = "eddystone-url"
+	EddystoneTLM Type = "eddystone-tlm"
+	EddystoneEID Type = "eddystone-eid"
+)
+
+//AppleCompanyID the company identifier of the iBeacon manufacturer data
+const AppleCompanyID = 0x004C
+
+//EddystoneService the service UUID of the Eddystone service data
+var EddystoneService = bluetooth.UUID16(0xFEAA)
+
+// Eddystone frame types
+const (
+	frameUID = 0x00
+	frameURL = 0x10
+	frameTLM = 0x20
+	frameEID = 0x30
+)
+
+//Beacon a decoded beacon frame
+type Beacon struct {
+	Type Type `json:"type"`
+	// ID identify the beacon: UUID/major/minor for an iBeacon, namespace and
+	// instance for Eddystone UID, the URL or the EID. Empty for the telemetry
+	// frames, identified by the address of the sender only
+	ID string `json:"id,omitempty"`
+
+	// iBeacon
+	UUID  *bluetooth.UUID `json:"uuid,omitempty"`
+	Major uint16          `json:"major,omitempty"`
+	Minor uint16          `json:"minor,omitempty"`
+
+	// Eddystone UID
+	Namespace string `json:"namespace,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	// Eddystone URL
+	URL string `json:"url,omitempty"`
+	// Eddystone EID, the ephemeral identifier in hex
+	EID string `json:"eid,omitempty"`
+	// Eddystone TLM
+	Telemetry *Telemetry `json:"telemetry,omitempty"`
+
+	// MeasuredPower the RSSI at 1m, in dBm, see Distance. Zero for the
+	// telemetry frames
+	MeasuredPower int8 `json:"measured_power,omitempty"`
+}
+
+//Telemetry the unencrypted Eddystone TLM frame
+type Telemetry struct {
+	// Battery in mV, zero if not supported
+	Battery uint16 `json:"battery"`
+	// Temperature in Celsius, -128 if not supported
+	Temperature float64 `json:"temperature"`
+	// Advertisements sent since the power up
+	Advertisements uint32 `json:"advertisements"`
+	// Uptime in 0.1s since the power up
+	Uptime uint32 `json:"uptime"`
+}
+
+//Parse decode the beacon of a scan record, nil if the record has none
+func Parse(r *hci.ScanRecord) (*Beacon, error) {
+	if b, ok := r.ManufacturerData[AppleCompanyID]; ok && len(b) >= 2 && b[0] == 0x02 {
+		return ParseIBeacon(b)
+	}
+	if b, ok := r.ServiceData[EddystoneService]; ok {
+		return ParseEddystone(b)
+	}
+	return nil, nil
+}
+
+//ParseIBeacon decode the Apple manufacturer data of an iBeacon, following the
+// company identifier
+func ParseIBeacon(b []byte) (*Beacon, error) {
+	if len(b) != 23 || b[0] != 0x02 || b[1] != 0x15 {
+		return nil, fmt.Errorf("Invalid iBeacon data %x", b)
+	}
+	var u bluetooth.UUID
+	copy(u[:], b[2:18])
+	major := binary.BigEndian.Uint16(b[18:])
+	minor := binary.BigEndian.Uint16(b[20:])
+	return &Beacon{
+		Type:          IBeacon,
+		ID:            fmt.Sprintf("%s/%d/%d", u, major, minor),
+		UUID:          &u,
+		Major:         major,
+		Minor:         minor,
+		MeasuredPower: int8(b[22]),
+	}, nil
+}
+
+//ParseEddystone decode the Eddystone service data
+func ParseEddystone(b []byte) (*Beacon, error) {
+
+	if len(b) < 2 {
+		return nil, fmt.Errorf("Invalid Eddystone data %x", b)
+	}
+	// the power at 0m, the signal loses 41dB at 1m
+	power := int8(math.Max(float64(int8(b[1]))-41, math.MinInt8))
+
+	switch b[0] {
+	case frameUID:
+		if len(b) < 18 {
+			return nil, fmt.Errorf("Invalid Eddystone UID frame %x", b)
+		}
+		namespace := hex.EncodeToString(b[2:12])
+		instance := hex.EncodeToString(b[12:18])
+		return &Beacon{
+			Type:          EddystoneUID,
+			ID:            namespace + "/" + instance,
+			Namespace:     namespace,
+			Instance:      instance,
+			MeasuredPower: power,
+		}, nil
+
+	case frameURL:
+		if len(b) < 4 {
+			return nil, fmt.Errorf("Invalid Eddystone URL frame %x", b)
+		}
+		url, err := DecodeURL(b[2:])
+		if err != nil {
+			return nil, err
+		}
+		return &Beacon{
+			Type:          EddystoneURL,
+			ID:            url,
+			URL:           url,
+			MeasuredPower: power,
+		}, nil
+
+	case frameEID:
+		if len(b) != 10 {
+			return nil, fmt.Errorf("Invalid Eddystone EID frame %x", b)
+		}
+		eid := hex.EncodeToString(b[2:])
+		return &Beacon{
+			Type:          EddystoneEID,
+			ID:            eid,
+			EID:           eid,
+			MeasuredPower: power,
+		}, nil
+
+	case frameTLM:
+		// version 0x01 is the encrypted telemetry, not supported
+		if len(b) != 14 || b[1] != 0x00 {
+			return nil, fmt.Errorf("Invalid Eddystone TLM frame %x", b)
+		}
+		return &Beacon{
+			Type: EddystoneTLM,
+			Telemetry: &Telemetry{
+				Battery:        binary.BigEndian.Uint16(b[2:]),
+				Temperature:    float64(int16(binary.BigEndian.Uint16(b[4:]))) / 256,
+				Advertisements: binary.BigEndian.Uint32(b[6:]),
+				Uptime:         binary.BigEndian.Uint32(b[10:]),
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("Unknown Eddystone frame 0x%02x", b[0])
+}
+
+var urlSchemes = []string{"http://www.", "https://www.", "http://", "https://"}
+
+var urlExpansions = []string{".com/", ".org/", ".edu/", ".net/", ".info/", ".biz/", ".gov/",
+	".com", ".org", ".edu", ".net", ".info", ".biz", ".gov"}
+
+//DecodeURL decode the compressed URL of an Eddystone URL frame: the scheme
+// prefix followed by the encoded URL
+func DecodeURL(b []byte) (string, error) {
+	if len(b) == 0 || int(b[0]) >= len(urlSchemes) {
+		return "", fmt.Errorf("Invalid Eddystone URL %x", b)
+	}
+	url := urlSchemes[b[0]]
+	for _, c := range b[1:] {
+		switch {
+		case int(c) < len(urlExpansions):
+			url += urlExpansions[c]
+		case c > 0x20 && c < 0x7f:
+			url += string(rune(c))
+		default:
+			return "", fmt.Errorf("Invalid Eddystone URL %x", b)
+		}
+	}
+	return url, nil
+}
+
+//Distance estimate the distance in meters from the RSSI and the measured
+// power at 1m, with the log-distance path loss model of the free space. The
+// estimate is rough, indoors the signal loses more, see Smoother to reduce
+// the noise of the RSSI. Return -1 if the measured power is not known
+func Distance(rssi float64, measuredPower int8) float64 {
+	if measuredPower == 0 {
+		return -1
+	}
+	return math.Pow(10, (float64(measuredPower)-rssi)/20)
+}
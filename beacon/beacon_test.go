@@ -0,0 +1,130 @@
+package beacon
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth/linux/hci"
+)
+
+func parseHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseIBeacon(t *testing.T) {
+
+	// flags, then the Apple manufacturer data
+	record, err := hci.ParseScanRecord(parseHex(t,
+		"020106"+"1aff4c000215"+"f7826da64fa24e988024bc5b71e0893e"+"0001"+"0102"+"c5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b == nil || b.Type != IBeacon {
+		t.Fatalf("Expected an iBeacon, got %v", b)
+	}
+	if b.UUID.String() != "F7826DA6-4FA2-4E98-8024-BC5B71E0893E" || b.Major != 1 || b.Minor != 258 {
+		t.Fatalf("Unexpected beacon %+v", b)
+	}
+	if b.ID != "F7826DA6-4FA2-4E98-8024-BC5B71E0893E/1/258" || b.MeasuredPower != -59 {
+		t.Fatalf("Unexpected beacon %+v", b)
+	}
+
+	if _, err = ParseIBeacon(parseHex(t, "0215f782")); err == nil {
+		t.Fatal("Truncated iBeacon accepted")
+	}
+}
+
+func TestParseEddystone(t *testing.T) {
+
+	// the complete list of 16bit services, then the service data
+	record, err := hci.ParseScanRecord(parseHex(t,
+		"0303aafe"+"1516aafe"+"00e7"+"00112233445566778899"+"aabbccddeeff"+"0000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Type != EddystoneUID || b.ID != "00112233445566778899/aabbccddeeff" {
+		t.Fatalf("Unexpected beacon %+v", b)
+	}
+	// -25dBm at 0m
+	if b.MeasuredPower != -66 {
+		t.Fatalf("Expected -66dBm at 1m, got %d", b.MeasuredPower)
+	}
+
+	b, err = ParseEddystone(parseHex(t, "10eb"+"01"+"676f6f676c65"+"07"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Type != EddystoneURL || b.URL != "https://www.google.com" {
+		t.Fatalf("Unexpected beacon %+v", b)
+	}
+
+	b, err = ParseEddystone(parseHex(t, "2000"+"0bb8"+"1680"+"00000064"+"00000e10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlm := b.Telemetry
+	if b.Type != EddystoneTLM || tlm == nil {
+		t.Fatalf("Unexpected beacon %+v", b)
+	}
+	if tlm.Battery != 3000 || tlm.Temperature != 22.5 || tlm.Advertisements != 100 || tlm.Uptime != 3600 {
+		t.Fatalf("Unexpected telemetry %+v", tlm)
+	}
+
+	if _, err = ParseEddystone(parseHex(t, "50eb")); err == nil {
+		t.Fatal("Unknown frame accepted")
+	}
+	if _, err = DecodeURL(parseHex(t, "0501")); err == nil {
+		t.Fatal("Invalid scheme accepted")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if d := Distance(-59, -59); d != 1 {
+		t.Fatalf("Expected 1m, got %f", d)
+	}
+	if d := Distance(-79, -59); math.Abs(d-10) > 0.001 {
+		t.Fatalf("Expected 10m, got %f", d)
+	}
+	if d := Distance(-79, 0); d != -1 {
+		t.Fatalf("Expected an unknown distance, got %f", d)
+	}
+}
+
+func TestSmoother(t *testing.T) {
+
+	s := NewSmoother(0.5, time.Minute)
+	now := time.Now()
+	if v := s.add("a", -60, now); v != -60 {
+		t.Fatalf("Expected -60, got %f", v)
+	}
+	if v := s.add("a", -70, now); v != -65 {
+		t.Fatalf("Expected -65, got %f", v)
+	}
+	if v := s.add("b", -80, now); v != -80 {
+		t.Fatalf("Expected -80, got %f", v)
+	}
+
+	// restarted after MaxAge
+	later := now.Add(2 * time.Minute)
+	if v := s.add("a", -50, later); v != -50 {
+		t.Fatalf("Expected -50, got %f", v)
+	}
+	expired := s.expire(later)
+	if len(expired) != 1 || expired[0] != "b" {
+		t.Fatalf("Expected b to expire, got %v", expired)
+	}
+}
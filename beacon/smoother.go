@@ -0,0 +1,82 @@
+package beacon
+
+import (
+	"sync"
+	"time"
+)
+
+//DefaultSmoothing the weight of a new RSSI in the average of a Smoother
+const DefaultSmoothing = 0.3
+
+//Smoother average the RSSI of each beacon with an exponential moving average,
+// the RSSI of a beacon varying by several dB between two advertisements
+type Smoother struct {
+	// Alpha the weight of a new RSSI, from 0 exclusive to 1 (no smoothing)
+	Alpha float64
+	// MaxAge forget a beacon not seen for longer, restarting its average.
+	// Zero keeps the beacons
+	MaxAge time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*smoothed
+}
+
+type smoothed struct {
+	rssi float64
+	seen time.Time
+}
+
+//NewSmoother create a Smoother, DefaultSmoothing if alpha is not in (0, 1]
+func NewSmoother(alpha float64, maxAge time.Duration) *Smoother {
+	if alpha <= 0 || alpha > 1 {
+		alpha = DefaultSmoothing
+	}
+	return &Smoother{
+		Alpha:   alpha,
+		MaxAge:  maxAge,
+		entries: make(map[string]*smoothed),
+	}
+}
+
+//Add a RSSI received from the beacon and return its average
+func (s *Smoother) Add(key string, rssi int8) float64 {
+	return s.add(key, rssi, time.Now())
+}
+
+func (s *Smoother) add(key string, rssi int8, now time.Time) float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || (s.MaxAge > 0 && now.Sub(e.seen) > s.MaxAge) {
+		e = &smoothed{rssi: float64(rssi)}
+		s.entries[key] = e
+	} else {
+		e.rssi = s.Alpha*float64(rssi) + (1-s.Alpha)*e.rssi
+	}
+	e.seen = now
+	return e.rssi
+}
+
+//Expire forget the beacons not seen for longer than MaxAge, return their
+// keys
+func (s *Smoother) Expire() []string {
+	return s.expire(time.Now())
+}
+
+func (s *Smoother) expire(now time.Time) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	expired := []string{}
+	if s.MaxAge == 0 {
+		return expired
+	}
+	for key, e := range s.entries {
+		if now.Sub(e.seen) > s.MaxAge {
+			delete(s.entries, key)
+			expired = append(expired, key)
+		}
+	}
+	return expired
+}
@@ -1,6 +1,9 @@
 package bluez
 
 import (
+	"context"
+	"time"
+
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/util"
 )
@@ -19,24 +22,45 @@ type Client struct {
 	Config     *Config
 }
 
+func (c *Client) logger() Logger {
+	if c.Config.Logger != nil {
+		return c.Config.Logger
+	}
+	return GetLogger()
+}
+
 func (c *Client) isConnected() bool {
 	return c.conn != nil
 }
 
-//Disconnect from DBus
+//Disconnect from DBus, connections set with Config.Conn are left open
 func (c *Client) Disconnect() {
 	if c.isConnected() {
-		c.conn.Close()
+		if c.Config.Conn == nil {
+			c.conn.Close()
+		}
 		c.conn = nil
 		c.dbusObject = nil
 	}
 }
 
+//SetConnection use conn for the following calls, eg. to register objects
+// exported on a private connection
+func (c *Client) SetConnection(conn *dbus.Conn) {
+	c.Config.Conn = conn
+	c.conn = nil
+	c.dbusObject = nil
+}
+
 // Connect connects to DBus
 func (c *Client) Connect() error {
-	dbusConn, err := GetConnection(c.Config.Bus)
-	if err != nil {
-		return err
+	dbusConn := c.Config.Conn
+	if dbusConn == nil {
+		var err error
+		dbusConn, err = GetConnection(c.Config.Bus)
+		if err != nil {
+			return err
+		}
 	}
 	c.conn = dbusConn
 	c.dbusObject = c.conn.Object(c.Config.Name, dbus.ObjectPath(c.Config.Path))
@@ -56,8 +80,71 @@ func (c *Client) Call(method string, flags dbus.Flags, args ...interface{}) *dbu
 	}
 
 	methodPath := c.Config.Iface + "." + method
+	if CallTimeout(methodPath) > 0 {
+		return c.CallWithContext(context.Background(), method, flags, args...)
+	}
+	c.logger().Debugf("bluez: call %s %s %v", c.Config.Path, methodPath, args)
 
-	return c.dbusObject.Call(methodPath, flags, args...)
+	start := time.Now()
+	call := c.dbusObject.Call(methodPath, flags, args...)
+	if call.Err != nil {
+		call.Err = ParseError(call.Err)
+		c.logger().Debugf("bluez: %s %s: %s", c.Config.Path, methodPath, call.Err.Error())
+	}
+	observeCall(methodPath, start, call.Err)
+	recordCall(c.Config.Path, methodPath, args, start, call.Err)
+	return call
+}
+
+//CallWithContext call a DBus method, returning as soon as the context is done
+// or the timeout of the method expires, see SetMethodTimeout. The method keeps
+// running on the remote side, cancel it explicitly when bluez provides a way
+// to do so (eg. Device1.Disconnect for Connect)
+func (c *Client) CallWithContext(ctx context.Context, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+
+	if err := ctx.Err(); err != nil {
+		return &dbus.Call{
+			Err: err,
+		}
+	}
+
+	if !c.isConnected() {
+		err := c.Connect()
+		if err != nil {
+			return &dbus.Call{
+				Err: err,
+			}
+		}
+	}
+
+	methodPath := c.Config.Iface + "." + method
+	c.logger().Debugf("bluez: call %s %s %v", c.Config.Path, methodPath, args)
+
+	ctx, cancel := withCallTimeout(ctx, methodPath)
+	defer cancel()
+
+	start := time.Now()
+	call := c.dbusObject.Go(methodPath, flags, make(chan *dbus.Call, 1), args...)
+
+	select {
+	case call = <-call.Done:
+		if call.Err != nil {
+			call.Err = ParseError(call.Err)
+			c.logger().Debugf("bluez: %s %s: %s", c.Config.Path, methodPath, call.Err.Error())
+		}
+		observeCall(methodPath, start, call.Err)
+		recordCall(c.Config.Path, methodPath, args, start, call.Err)
+		return call
+	case <-ctx.Done():
+		c.logger().Debugf("bluez: %s %s: %s", c.Config.Path, methodPath, ctx.Err().Error())
+		observeCall(methodPath, start, ctx.Err())
+		recordCall(c.Config.Path, methodPath, args, start, ctx.Err())
+		return &dbus.Call{
+			Method: methodPath,
+			Args:   args,
+			Err:    ctx.Err(),
+		}
+	}
 }
 
 //GetProperty return a property value
@@ -68,7 +155,8 @@ func (c *Client) GetProperty(p string) (dbus.Variant, error) {
 			return dbus.Variant{}, err
 		}
 	}
-	return c.dbusObject.GetProperty(c.Config.Iface + "." + p)
+	v, err := c.dbusObject.GetProperty(c.Config.Iface + "." + p)
+	return v, ParseError(err)
 }
 
 //SetProperty set a property value
@@ -79,7 +167,9 @@ func (c *Client) SetProperty(p string, v interface{}) error {
 			return err
 		}
 	}
-	return c.dbusObject.Call("org.freedesktop.DBus.Properties.Set", 0, c.Config.Iface, p, v).Store()
+	c.logger().Debugf("bluez: set %s %s.%s %v", c.Config.Path, c.Config.Iface, p, v)
+	err := c.dbusObject.Call("org.freedesktop.DBus.Properties.Set", 0, c.Config.Iface, p, v).Store()
+	return ParseError(err)
 }
 
 //GetProperties load all the properties for an interface
@@ -95,17 +185,14 @@ func (c *Client) GetProperties(props interface{}) error {
 	result := make(map[string]dbus.Variant)
 	err := c.dbusObject.Call("org.freedesktop.DBus.Properties.GetAll", 0, c.Config.Iface).Store(&result)
 	if err != nil {
-		return err
+		return ParseError(err)
 	}
 
 	return util.MapToStruct(props, result)
 }
 
-func getMatchString(path string, iface string) string {
-	return "type='signal',interface='" + iface + "',path='" + path + "'"
-}
-
-//Register for signals
+//Register for signals. The subscriptions share the match rules of the
+// connection, see SignalDispatcher
 func (c *Client) Register(path string, iface string) (chan *dbus.Signal, error) {
 
 	if !c.isConnected() {
@@ -115,13 +202,10 @@ func (c *Client) Register(path string, iface string) (chan *dbus.Signal, error)
 		}
 	}
 
-	matchstr := getMatchString(path, iface)
-	c.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchstr)
-
-	channel := make(chan *dbus.Signal, 1)
-	c.conn.Signal(channel)
-
-	return channel, nil
+	return GetSignalDispatcher(c.conn).Subscribe(SignalFilter{
+		Path:      dbus.ObjectPath(path),
+		Interface: iface,
+	})
 }
 
 //Unregister for signals
@@ -132,11 +216,8 @@ func (c *Client) Unregister(path string, iface string, signal chan *dbus.Signal)
 			return err
 		}
 	}
-	matchstr := getMatchString(path, iface)
-	c.conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, matchstr)
-	if signal != nil {
-		c.conn.RemoveSignal(signal)
+	if signal == nil {
+		return nil
 	}
-
-	return nil
+	return GetSignalDispatcher(c.conn).Unsubscribe(signal)
 }
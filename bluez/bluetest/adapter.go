@@ -0,0 +1,421 @@
+package bluetest
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//Adapter a fake adapter, implementing Adapter1, GattManager1,
+// LEAdvertisingManager1, BatteryProviderManager1, the players of Media1 and
+// the admin policy
+type Adapter struct {
+	ID   string
+	Path dbus.ObjectPath
+
+	b   *Bluez
+	obj *object
+
+	mutex           sync.Mutex
+	discoveryFilter map[string]dbus.Variant
+	applications    map[registrationKey]*Application
+	advertisements  map[registrationKey]*Advertisement
+	providers       map[registrationKey]*BatteryProvider
+	players         map[registrationKey]*Player
+}
+
+// registrationKey objects registered by clients are identified by the
+// sender unique name and their path
+type registrationKey struct {
+	sender string
+	path   dbus.ObjectPath
+}
+
+//AddAdapter add an adapter, eg. hci0, powered on
+func (b *Bluez) AddAdapter(id string, address string) (*Adapter, error) {
+
+	path := dbus.ObjectPath("/org/bluez/" + id)
+	a := &Adapter{
+		ID:              id,
+		Path:            path,
+		b:               b,
+		discoveryFilter: make(map[string]dbus.Variant),
+		applications:    make(map[registrationKey]*Application),
+		advertisements:  make(map[registrationKey]*Advertisement),
+		providers:       make(map[registrationKey]*BatteryProvider),
+		players:         make(map[registrationKey]*Player),
+	}
+
+	a.obj = b.addObject(path, map[string]map[string]*prop.Prop{
+		bluez.Adapter1Interface: {
+			"Address":             property(address, false),
+			"Name":                property("bluetest", false),
+			"Alias":               property("bluetest", true),
+			"Class":               property(uint32(0), false),
+			"Powered":             property(true, true),
+			"Discoverable":        property(false, true),
+			"DiscoverableTimeout": property(uint32(180), true),
+			"Pairable":            property(true, true),
+			"PairableTimeout":     property(uint32(0), true),
+			"Discovering":         property(false, false),
+			"UUIDs":               property([]string{}, false),
+			"Modalias":            property("", false),
+		},
+		bluez.GattManager1Interface: {},
+		bluez.LEAdvertisingManager1Interface: {
+			"ActiveInstances":    property(byte(0), false),
+			"SupportedInstances": property(byte(5), false),
+			"SupportedIncludes":  property([]string{"tx-power", "appearance", "local-name"}, false),
+		},
+		profile.BatteryProviderManager1Interface: {},
+		profile.AdminPolicySet1Interface:         {},
+		bluez.Media1Interface:                    {},
+		profile.AdminPolicyStatus1Interface: {
+			"ServiceAllowList": property([]string{}, false),
+		},
+	})
+
+	err := b.export(path, a.obj, map[string]interface{}{
+		bluez.Adapter1Interface:                  &adapter1{a},
+		bluez.GattManager1Interface:              &gattManager1{a},
+		bluez.LEAdvertisingManager1Interface:     &advertisingManager1{a},
+		profile.BatteryProviderManager1Interface: &batteryProviderManager1{a},
+		profile.AdminPolicySet1Interface:         &adminPolicySet1{a},
+		bluez.Media1Interface:                    &media1{a},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b.mutex.Lock()
+	b.adapters[id] = a
+	b.mutex.Unlock()
+
+	return a, nil
+}
+
+//Adapter return an adapter by id, nil if not found
+func (b *Bluez) Adapter(id string) *Adapter {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.adapters[id]
+}
+
+//Property return the current value of an Adapter1 property
+func (a *Adapter) Property(name string) interface{} {
+	return a.obj.props.GetMust(bluez.Adapter1Interface, name)
+}
+
+//SetProperty change an Adapter1 property, emitting PropertiesChanged
+func (a *Adapter) SetProperty(name string, value interface{}) {
+	a.obj.props.SetMust(bluez.Adapter1Interface, name, value)
+}
+
+//DiscoveryFilter return the filter set with SetDiscoveryFilter
+func (a *Adapter) DiscoveryFilter() map[string]dbus.Variant {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.discoveryFilter
+}
+
+//Applications return the registered GATT applications
+func (a *Adapter) Applications() []*Application {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	list := make([]*Application, 0, len(a.applications))
+	for _, app := range a.applications {
+		list = append(list, app)
+	}
+	return list
+}
+
+//Advertisements return the registered advertisements
+func (a *Adapter) Advertisements() []*Advertisement {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	list := make([]*Advertisement, 0, len(a.advertisements))
+	for _, adv := range a.advertisements {
+		list = append(list, adv)
+	}
+	return list
+}
+
+//BatteryProviders return the registered battery providers
+func (a *Adapter) BatteryProviders() []*BatteryProvider {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	list := make([]*BatteryProvider, 0, len(a.providers))
+	for _, p := range a.providers {
+		list = append(list, p)
+	}
+	return list
+}
+
+//ReleaseAdvertisements drop the advertisements and call their Release, as
+// bluetoothd does when the controller removes them, eg. once a central is
+// connected
+func (a *Adapter) ReleaseAdvertisements() error {
+	a.mutex.Lock()
+	advertisements := a.advertisements
+	a.advertisements = make(map[registrationKey]*Advertisement)
+	a.mutex.Unlock()
+	a.obj.props.SetMust(bluez.LEAdvertisingManager1Interface, "ActiveInstances", byte(0))
+
+	for _, adv := range advertisements {
+		err := adv.conn.Object(adv.Sender, adv.Path).
+			Call(bluez.LEAdvertisement1Interface+".Release", 0).Store()
+		if err != nil {
+			return bluez.ParseError(err)
+		}
+	}
+	return nil
+}
+
+//ServiceAllowList return the list set with SetServiceAllowList
+func (a *Adapter) ServiceAllowList() []string {
+	return a.obj.props.GetMust(profile.AdminPolicyStatus1Interface, "ServiceAllowList").([]string)
+}
+
+// allowed return true if a service is in the allow list, or the list is
+// empty
+func (a *Adapter) allowed(uuid string) bool {
+	list := a.ServiceAllowList()
+	if len(list) == 0 {
+		return true
+	}
+	for _, allowed := range list {
+		if bluetooth.EqualUUID(allowed, uuid) {
+			return true
+		}
+	}
+	return false
+}
+
+// reset drop the registrations, as on a restart
+func (a *Adapter) reset() {
+	a.mutex.Lock()
+	a.applications = make(map[registrationKey]*Application)
+	a.advertisements = make(map[registrationKey]*Advertisement)
+	a.providers = make(map[registrationKey]*BatteryProvider)
+	a.players = make(map[registrationKey]*Player)
+	a.mutex.Unlock()
+	a.obj.props.SetMust(bluez.LEAdvertisingManager1Interface, "ActiveInstances", byte(0))
+	a.obj.props.SetMust(bluez.Adapter1Interface, "Powered", false)
+}
+
+// adapter1 the org.bluez.Adapter1 methods
+type adapter1 struct {
+	a *Adapter
+}
+
+//StartDiscovery set Discovering
+func (m *adapter1) StartDiscovery() *dbus.Error {
+	if m.a.Property("Discovering").(bool) {
+		return bluez.ErrInProgress.DBusError()
+	}
+	m.a.SetProperty("Discovering", true)
+	return nil
+}
+
+//StopDiscovery clear Discovering
+func (m *adapter1) StopDiscovery() *dbus.Error {
+	if !m.a.Property("Discovering").(bool) {
+		return bluez.ErrFailed.WithMessage("No discovery started").DBusError()
+	}
+	m.a.SetProperty("Discovering", false)
+	return nil
+}
+
+//SetDiscoveryFilter store the filter
+func (m *adapter1) SetDiscoveryFilter(filter map[string]dbus.Variant) *dbus.Error {
+	m.a.mutex.Lock()
+	defer m.a.mutex.Unlock()
+	m.a.discoveryFilter = filter
+	return nil
+}
+
+//GetDiscoveryFilters return the supported filters
+func (m *adapter1) GetDiscoveryFilters() ([]string, *dbus.Error) {
+	return []string{"UUIDs", "RSSI", "Pathloss", "Transport", "DuplicateData"}, nil
+}
+
+//RemoveDevice drop a device
+func (m *adapter1) RemoveDevice(device dbus.ObjectPath) *dbus.Error {
+	if m.a.b.Device(device) == nil {
+		return bluez.ErrDoesNotExist.DBusError()
+	}
+	m.a.b.RemoveDevice(device)
+	return nil
+}
+
+// gattManager1 the org.bluez.GattManager1 methods
+type gattManager1 struct {
+	a *Adapter
+}
+
+//RegisterApplication load the objects of the application
+func (m *gattManager1) RegisterApplication(sender dbus.Sender, path dbus.ObjectPath, options map[string]dbus.Variant) *dbus.Error {
+
+	key := registrationKey{string(sender), path}
+	m.a.mutex.Lock()
+	_, exists := m.a.applications[key]
+	m.a.mutex.Unlock()
+	if exists {
+		return bluez.ErrAlreadyExists.DBusError()
+	}
+
+	app := &Application{
+		Sender:  string(sender),
+		Path:    path,
+		Options: options,
+		conn:    m.a.b.conn,
+	}
+	err := app.Refresh()
+	if err != nil {
+		return bluez.ErrFailed.WithMessage(err.Error()).DBusError()
+	}
+
+	m.a.mutex.Lock()
+	m.a.applications[key] = app
+	m.a.mutex.Unlock()
+	return nil
+}
+
+//UnregisterApplication drop the application
+func (m *gattManager1) UnregisterApplication(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+	key := registrationKey{string(sender), path}
+	m.a.mutex.Lock()
+	defer m.a.mutex.Unlock()
+	if _, ok := m.a.applications[key]; !ok {
+		return bluez.ErrDoesNotExist.DBusError()
+	}
+	delete(m.a.applications, key)
+	return nil
+}
+
+// advertisingManager1 the org.bluez.LEAdvertisingManager1 methods
+type advertisingManager1 struct {
+	a *Adapter
+}
+
+//RegisterAdvertisement load the advertisement properties
+func (m *advertisingManager1) RegisterAdvertisement(sender dbus.Sender, path dbus.ObjectPath, options map[string]dbus.Variant) *dbus.Error {
+
+	key := registrationKey{string(sender), path}
+	m.a.mutex.Lock()
+	_, exists := m.a.advertisements[key]
+	count := len(m.a.advertisements)
+	m.a.mutex.Unlock()
+	if exists {
+		return bluez.ErrAlreadyExists.DBusError()
+	}
+	if count >= 5 {
+		return bluez.ErrNotPermitted.WithMessage("Maximum advertisements reached").DBusError()
+	}
+
+	adv := &Advertisement{
+		Sender: string(sender),
+		Path:   path,
+		conn:   m.a.b.conn,
+	}
+	err := adv.Refresh()
+	if err != nil {
+		return bluez.ErrInvalidArguments.WithMessage(err.Error()).DBusError()
+	}
+
+	m.a.mutex.Lock()
+	m.a.advertisements[key] = adv
+	count = len(m.a.advertisements)
+	m.a.mutex.Unlock()
+	m.a.obj.props.SetMust(bluez.LEAdvertisingManager1Interface, "ActiveInstances", byte(count))
+	return nil
+}
+
+//UnregisterAdvertisement drop the advertisement
+func (m *advertisingManager1) UnregisterAdvertisement(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+	key := registrationKey{string(sender), path}
+	m.a.mutex.Lock()
+	if _, ok := m.a.advertisements[key]; !ok {
+		m.a.mutex.Unlock()
+		return bluez.ErrDoesNotExist.DBusError()
+	}
+	delete(m.a.advertisements, key)
+	count := len(m.a.advertisements)
+	m.a.mutex.Unlock()
+	m.a.obj.props.SetMust(bluez.LEAdvertisingManager1Interface, "ActiveInstances", byte(count))
+	return nil
+}
+
+// batteryProviderManager1 the org.bluez.BatteryProviderManager1 methods
+type batteryProviderManager1 struct {
+	a *Adapter
+}
+
+//RegisterBatteryProvider load the batteries of the provider
+func (m *batteryProviderManager1) RegisterBatteryProvider(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+
+	key := registrationKey{string(sender), path}
+	m.a.mutex.Lock()
+	_, exists := m.a.providers[key]
+	m.a.mutex.Unlock()
+	if exists {
+		return bluez.ErrAlreadyExists.DBusError()
+	}
+
+	p := &BatteryProvider{
+		Sender: string(sender),
+		Path:   path,
+		conn:   m.a.b.conn,
+	}
+	err := p.Refresh()
+	if err != nil {
+		return bluez.ErrFailed.WithMessage(err.Error()).DBusError()
+	}
+
+	m.a.mutex.Lock()
+	m.a.providers[key] = p
+	m.a.mutex.Unlock()
+	return nil
+}
+
+//UnregisterBatteryProvider drop the provider
+func (m *batteryProviderManager1) UnregisterBatteryProvider(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+	key := registrationKey{string(sender), path}
+	m.a.mutex.Lock()
+	defer m.a.mutex.Unlock()
+	if _, ok := m.a.providers[key]; !ok {
+		return bluez.ErrDoesNotExist.DBusError()
+	}
+	delete(m.a.providers, key)
+	return nil
+}
+
+// adminPolicySet1 the org.bluez.AdminPolicySet1 methods
+type adminPolicySet1 struct {
+	a *Adapter
+}
+
+//SetServiceAllowList store the UUIDs in their 128 bit form and update the
+// devices affected by the policy
+func (m *adminPolicySet1) SetServiceAllowList(uuids []string) *dbus.Error {
+
+	list := make([]string, 0, len(uuids))
+	for _, uuid := range uuids {
+		u, err := bluetooth.ParseUUID(uuid)
+		if err != nil {
+			return bluez.ErrInvalidArguments.DBusError()
+		}
+		list = append(list, strings.ToLower(u.String()))
+	}
+	m.a.obj.props.SetMust(profile.AdminPolicyStatus1Interface, "ServiceAllowList", list)
+
+	for _, d := range m.a.b.adapterDevices(m.a) {
+		d.updatePolicy()
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package bluetest
+
+import (
+	"errors"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// agentManagerPath the object exposing AgentManager1 and ProfileManager1
+const agentManagerPath = dbus.ObjectPath("/org/bluez")
+
+// agentManager1 the org.bluez.AgentManager1 methods
+type agentManager1 struct {
+	b *Bluez
+}
+
+func (b *Bluez) exportManagers() error {
+
+	m := &agentManager1{b}
+	err := b.conn.Export(m, agentManagerPath, bluez.AgentManager1Interface)
+	if err != nil {
+		return err
+	}
+	pm := &profileManager1{b}
+	err = b.conn.Export(pm, agentManagerPath, bluez.ProfileManager1Interface)
+	if err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name:    bluez.AgentManager1Interface,
+				Methods: introspect.Methods(m),
+			},
+			{
+				Name:    bluez.ProfileManager1Interface,
+				Methods: introspect.Methods(pm),
+			},
+		},
+	}
+	return b.conn.Export(introspect.NewIntrospectable(node), agentManagerPath, "org.freedesktop.DBus.Introspectable")
+}
+
+//RegisterAgent store the agent and its capability
+func (m *agentManager1) RegisterAgent(sender dbus.Sender, path dbus.ObjectPath, capability string) *dbus.Error {
+	key := registrationKey{string(sender), path}
+	m.b.mutex.Lock()
+	defer m.b.mutex.Unlock()
+	if _, ok := m.b.agents[key]; ok {
+		return bluez.ErrAlreadyExists.DBusError()
+	}
+	m.b.agents[key] = capability
+	return nil
+}
+
+//UnregisterAgent drop the agent
+func (m *agentManager1) UnregisterAgent(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+	key := registrationKey{string(sender), path}
+	m.b.mutex.Lock()
+	defer m.b.mutex.Unlock()
+	if _, ok := m.b.agents[key]; !ok {
+		return bluez.ErrDoesNotExist.DBusError()
+	}
+	delete(m.b.agents, key)
+	if m.b.defaultAgent == key {
+		m.b.defaultAgent = registrationKey{}
+	}
+	return nil
+}
+
+//RequestDefaultAgent make a registered agent the default one
+func (m *agentManager1) RequestDefaultAgent(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+	key := registrationKey{string(sender), path}
+	m.b.mutex.Lock()
+	defer m.b.mutex.Unlock()
+	if _, ok := m.b.agents[key]; !ok {
+		return bluez.ErrDoesNotExist.DBusError()
+	}
+	m.b.defaultAgent = key
+	return nil
+}
+
+//Agents return the capabilities of the registered agents by path
+func (b *Bluez) Agents() map[dbus.ObjectPath]string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	agents := make(map[dbus.ObjectPath]string, len(b.agents))
+	for key, capability := range b.agents {
+		agents[key.path] = capability
+	}
+	return agents
+}
+
+//PairFromRemote simulate a pairing initiated by the device: the default
+// agent is asked for the authorization and Paired is set if it accepts
+func (d *Device) PairFromRemote() error {
+
+	b := d.Adapter.b
+	b.mutex.Lock()
+	agent := b.defaultAgent
+	b.mutex.Unlock()
+	if agent.path == "" {
+		return errors.New("No default agent")
+	}
+
+	err := b.conn.Object(agent.sender, agent.path).
+		Call(bluez.Agent1Interface+".RequestAuthorization", 0, d.Path).Store()
+	if err != nil {
+		return bluez.ParseError(err)
+	}
+	d.SetProperty("Paired", true)
+	return nil
+}
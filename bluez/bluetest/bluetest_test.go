@@ -0,0 +1,81 @@
+package bluetest
+
+import (
+	"testing"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+func TestAdapterDiscovery(t *testing.T) {
+
+	b := StartTest(t)
+	defer b.Close()
+
+	adapter := profile.NewAdapter1("hci0")
+	props, err := adapter.GetProperties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props.Address != "00:11:22:33:44:55" || !props.Powered {
+		t.Fatalf("Unexpected adapter properties: %+v", props)
+	}
+
+	err = adapter.StartDiscovery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Adapter("hci0").Property("Discovering").(bool) {
+		t.Fatal("Adapter should be discovering")
+	}
+
+	err = adapter.StartDiscovery()
+	if !bluez.IsError(err, bluez.ErrInProgress) {
+		t.Fatalf("Expected %s, got %v", bluez.ErrInProgress, err)
+	}
+
+	err = adapter.StopDiscovery()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDevice(t *testing.T) {
+
+	b := StartTest(t)
+	defer b.Close()
+
+	d, err := b.AddDevice("hci0", "aa:bb:cc:dd:ee:ff", "sensor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Path != "/org/bluez/hci0/dev_AA_BB_CC_DD_EE_FF" {
+		t.Fatalf("Unexpected path %s", d.Path)
+	}
+
+	dev := profile.NewDevice1(string(d.Path))
+	err = dev.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	props, err := dev.GetProperties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !props.Connected || props.Name != "sensor" {
+		t.Fatalf("Unexpected device properties: %+v", props)
+	}
+
+	err = dev.Connect()
+	if !bluez.IsError(err, bluez.ErrAlreadyConnected) {
+		t.Fatalf("Expected %s, got %v", bluez.ErrAlreadyConnected, err)
+	}
+
+	err = profile.NewAdapter1("hci0").RemoveDevice(string(d.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Device(d.Path) != nil {
+		t.Fatal("Device should be removed")
+	}
+}
@@ -0,0 +1,284 @@
+//Package bluetest provides a fake org.bluez service running on a private
+// dbus-daemon, to test applications built on this library without
+// bluetooth hardware. Adapters and devices are simulated, GATT applications
+// and advertisements registered by the code under test can be inspected.
+package bluetest
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// object a fake object exported by the service
+type object struct {
+	path  dbus.ObjectPath
+	props *prop.Properties
+	// ifaces the interfaces with properties, exported by the ObjectManager
+	ifaces []string
+}
+
+//Bluez a fake bluetoothd
+type Bluez struct {
+	conn   *dbus.Conn
+	daemon *Daemon
+	client *dbus.Conn
+
+	mutex    sync.Mutex
+	objects  map[dbus.ObjectPath]*object
+	adapters map[string]*Adapter
+	devices  map[dbus.ObjectPath]*Device
+	// agents the capability of the registered pairing agents
+	agents       map[registrationKey]string
+	defaultAgent registrationKey
+	// profiles the UUID of the registered profiles
+	profiles map[registrationKey]string
+}
+
+//Start run a private dbus-daemon with a fake bluez, and make the clients of
+// the library use it in place of the system bus. Close restores the system bus
+func Start() (*Bluez, error) {
+
+	daemon, err := StartDaemon()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := New(daemon.Address)
+	if err != nil {
+		daemon.Close()
+		return nil, err
+	}
+	b.daemon = daemon
+
+	// a separate connection for the code under test, messages to org.bluez
+	// go through the daemon as they would on the system bus
+	client, err := bluez.DialBus(daemon.Address)
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	b.client = client
+	bluez.SetConnection(bluez.SystemBus, client)
+
+	return b, nil
+}
+
+//New expose a fake bluez on the bus at address
+func New(address string) (*Bluez, error) {
+
+	conn, err := dbus.Dial(address)
+	if err != nil {
+		return nil, err
+	}
+	if err = conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err = conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	b := &Bluez{
+		conn:     conn,
+		objects:  make(map[dbus.ObjectPath]*object),
+		adapters: make(map[string]*Adapter),
+		devices:  make(map[dbus.ObjectPath]*Device),
+		agents:   make(map[registrationKey]string),
+		profiles: make(map[registrationKey]string),
+	}
+
+	_, err = conn.RequestName(bluez.OrgBluez, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	err = b.exportRoot()
+	if err == nil {
+		err = b.exportManagers()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+//Conn return the connection of the fake service
+func (b *Bluez) Conn() *dbus.Conn {
+	return b.conn
+}
+
+//ClientConn return the connection installed as system bus by Start, nil if
+// the service has been created with New
+func (b *Bluez) ClientConn() *dbus.Conn {
+	return b.client
+}
+
+//Close stop the fake service and the daemon started with it
+func (b *Bluez) Close() {
+	if b.client != nil {
+		// the api objects are bound to the connection closed below
+		api.Exit()
+		bluez.SetConnection(bluez.SystemBus, nil)
+		b.client.Close()
+	}
+	b.conn.Close()
+	if b.daemon != nil {
+		b.daemon.Close()
+	}
+}
+
+//Restart simulate a bluetoothd restart: org.bluez leaves the bus, the
+// registered applications, advertisements, agents and profiles are dropped,
+// the adapters are
+// powered off and the name is acquired again
+func (b *Bluez) Restart() error {
+
+	_, err := b.conn.ReleaseName(bluez.OrgBluez)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	for _, a := range b.adapters {
+		a.reset()
+	}
+	b.agents = make(map[registrationKey]string)
+	b.defaultAgent = registrationKey{}
+	b.profiles = make(map[registrationKey]string)
+	b.mutex.Unlock()
+
+	_, err = b.conn.RequestName(bluez.OrgBluez, dbus.NameFlagDoNotQueue)
+	return err
+}
+
+func (b *Bluez) exportRoot() error {
+
+	err := b.conn.Export(&objectManager{b}, "/", bluez.ObjectManagerInterface)
+	if err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			bluez.ObjectManagerIntrospectData,
+		},
+	}
+	return b.conn.Export(introspect.NewIntrospectable(node), "/", "org.freedesktop.DBus.Introspectable")
+}
+
+// addObject export the properties of a new object, announced by export
+// once its methods are exported too
+func (b *Bluez) addObject(path dbus.ObjectPath, props map[string]map[string]*prop.Prop) *object {
+
+	o := &object{
+		path:  path,
+		props: prop.New(b.conn, path, props),
+	}
+	for iface := range props {
+		o.ifaces = append(o.ifaces, iface)
+	}
+	sort.Strings(o.ifaces)
+	return o
+}
+
+// removeObject drop an object and announce it
+func (b *Bluez) removeObject(path dbus.ObjectPath, ifaces ...string) {
+
+	b.mutex.Lock()
+	o, ok := b.objects[path]
+	delete(b.objects, path)
+	b.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	for _, iface := range append(ifaces, bluez.PropertiesInterface, "org.freedesktop.DBus.Introspectable") {
+		b.conn.Export(nil, path, iface)
+	}
+	b.conn.Emit("/", bluez.InterfacesRemoved, path, o.ifaces)
+}
+
+func (o *object) managedProperties() map[string]map[string]dbus.Variant {
+	m := make(map[string]map[string]dbus.Variant)
+	for _, iface := range o.ifaces {
+		m[iface], _ = o.props.GetAll(iface)
+	}
+	return m
+}
+
+// export an object with its methods and introspection data, and announce it
+func (b *Bluez) export(path dbus.ObjectPath, o *object, methods map[string]interface{}) error {
+
+	ifaces := []introspect.Interface{
+		introspect.IntrospectData,
+		prop.IntrospectData,
+	}
+
+	names := make([]string, 0, len(methods))
+	for iface := range methods {
+		names = append(names, iface)
+	}
+	sort.Strings(names)
+
+	for _, iface := range names {
+		err := b.conn.Export(methods[iface], path, iface)
+		if err != nil {
+			return err
+		}
+		ifaces = append(ifaces, introspect.Interface{
+			Name:       iface,
+			Methods:    introspect.Methods(methods[iface]),
+			Properties: o.props.Introspection(iface),
+		})
+	}
+
+	node := &introspect.Node{Interfaces: ifaces}
+	err := b.conn.Export(introspect.NewIntrospectable(node), path, "org.freedesktop.DBus.Introspectable")
+	if err != nil {
+		return err
+	}
+
+	// the clients learn about the object when it is ready to be called
+	b.mutex.Lock()
+	b.objects[path] = o
+	b.mutex.Unlock()
+	b.conn.Emit("/", bluez.InterfacesAdded, path, o.managedProperties())
+	return nil
+}
+
+// objectManager the org.freedesktop.DBus.ObjectManager of the service root
+type objectManager struct {
+	b *Bluez
+}
+
+//GetManagedObjects return the adapters and devices
+func (om *objectManager) GetManagedObjects() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, *dbus.Error) {
+	om.b.mutex.Lock()
+	defer om.b.mutex.Unlock()
+
+	objects := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
+	for path, o := range om.b.objects {
+		objects[path] = o.managedProperties()
+	}
+	return objects, nil
+}
+
+// property build a property emitting changes
+func property(value interface{}, writable bool) *prop.Prop {
+	return &prop.Prop{
+		Value:    value,
+		Writable: writable,
+		Emit:     prop.EmitTrue,
+	}
+}
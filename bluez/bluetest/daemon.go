@@ -0,0 +1,92 @@
+package bluetest
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const daemonConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=SOCKET</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+//Daemon a private dbus-daemon, isolated from the system and session buses
+type Daemon struct {
+	// Address the bus address, eg. unix:path=/tmp/bluetest123/bus
+	Address string
+
+	cmd *exec.Cmd
+	dir string
+}
+
+//StartDaemon run a private dbus-daemon, dbus-daemon has to be in PATH
+func StartDaemon() (*Daemon, error) {
+
+	bin, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "bluetest")
+	if err != nil {
+		return nil, err
+	}
+
+	socket := filepath.Join(dir, "bus")
+	config := filepath.Join(dir, "bus.conf")
+	err = ioutil.WriteFile(config, []byte(strings.Replace(daemonConfig, "SOCKET", socket, 1)), 0600)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	cmd := exec.Command(bin, "--nofork", "--nopidfile", "--config-file="+config)
+	err = cmd.Start()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	d := &Daemon{
+		Address: "unix:path=" + socket,
+		cmd:     cmd,
+		dir:     dir,
+	}
+
+	// wait for the daemon to accept the connections, the socket is created
+	// before it listens
+	for i := 0; i < 100; i++ {
+		if c, err := net.Dial("unix", socket); err == nil {
+			c.Close()
+			return d, nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	d.Close()
+	return nil, errors.New("Timeout waiting for dbus-daemon")
+}
+
+//Close stop the daemon and remove its socket
+func (d *Daemon) Close() error {
+	if d.cmd.Process != nil {
+		d.cmd.Process.Kill()
+		d.cmd.Wait()
+	}
+	return os.RemoveAll(d.dir)
+}
@@ -0,0 +1,222 @@
+package bluetest
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//Device a fake remote device, implementing Device1 and the admin policy
+// status
+type Device struct {
+	Address string
+	Path    dbus.ObjectPath
+	Adapter *Adapter
+
+	// OnConnect called on Connect, an error fails the call
+	OnConnect func() error
+	// OnPair called on Pair, an error fails the call
+	OnPair func() error
+	// OnConnectProfile called on ConnectProfile, an error fails the call
+	OnConnectProfile func(uuid string) error
+	// OnProfileConnection receive the device end of the sockets handed over
+	// to the registered profiles, they are closed if not set
+	OnProfileConnection func(uuid string, conn net.Conn)
+
+	obj *object
+}
+
+//AddDevice add a discovered device to an adapter
+func (b *Bluez) AddDevice(adapterID string, address string, name string) (*Device, error) {
+
+	a := b.Adapter(adapterID)
+	if a == nil {
+		return nil, errors.New("Adapter not found: " + adapterID)
+	}
+
+	path := dbus.ObjectPath(string(a.Path) + "/dev_" + strings.Replace(strings.ToUpper(address), ":", "_", -1))
+	if b.Device(path) != nil {
+		return nil, errors.New("Device already exists: " + address)
+	}
+
+	d := &Device{
+		Address: address,
+		Path:    path,
+		Adapter: a,
+	}
+
+	d.obj = b.addObject(path, map[string]map[string]*prop.Prop{
+		bluez.Device1Interface: {
+			"Address":          property(address, false),
+			"AddressType":      property("public", false),
+			"Name":             property(name, false),
+			"Alias":            property(name, true),
+			"Class":            property(uint32(0), false),
+			"Appearance":       property(uint16(0), false),
+			"Icon":             property("", false),
+			"Paired":           property(false, false),
+			"Trusted":          property(false, true),
+			"Blocked":          property(false, true),
+			"LegacyPairing":    property(false, false),
+			"RSSI":             property(int16(-60), false),
+			"TxPower":          property(int16(0), false),
+			"Connected":        property(false, false),
+			"UUIDs":            property([]string{}, false),
+			"Modalias":         property("", false),
+			"Adapter":          property(a.Path, false),
+			"ServicesResolved": property(false, false),
+			"WakeAllowed":      property(false, true),
+			"PreferredBearer":  property(string(profile.BearerLastUsed), true),
+		},
+		profile.AdminPolicyStatus1Interface: {
+			"IsAffectedByPolicy": property(false, false),
+		},
+	})
+
+	err := b.export(path, d.obj, map[string]interface{}{
+		bluez.Device1Interface: &device1{d},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b.mutex.Lock()
+	b.devices[path] = d
+	b.mutex.Unlock()
+
+	return d, nil
+}
+
+//Device return a device by path, nil if not found
+func (b *Bluez) Device(path dbus.ObjectPath) *Device {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.devices[path]
+}
+
+// adapterDevices return the devices of an adapter
+func (b *Bluez) adapterDevices(a *Adapter) []*Device {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	list := make([]*Device, 0)
+	for _, d := range b.devices {
+		if d.Adapter == a {
+			list = append(list, d)
+		}
+	}
+	return list
+}
+
+//RemoveDevice drop a device, emitting InterfacesRemoved
+func (b *Bluez) RemoveDevice(path dbus.ObjectPath) {
+	b.mutex.Lock()
+	delete(b.devices, path)
+	b.mutex.Unlock()
+	b.removeObject(path, bluez.Device1Interface)
+}
+
+//Property return the current value of a Device1 property
+func (d *Device) Property(name string) interface{} {
+	return d.obj.props.GetMust(bluez.Device1Interface, name)
+}
+
+//SetProperty change a Device1 property, emitting PropertiesChanged
+func (d *Device) SetProperty(name string, value interface{}) {
+	d.obj.props.SetMust(bluez.Device1Interface, name, value)
+	if name == "UUIDs" {
+		d.updatePolicy()
+	}
+}
+
+// updatePolicy set IsAffectedByPolicy if some services of the device are not
+// in the allow list of the adapter
+func (d *Device) updatePolicy() {
+	affected := false
+	for _, uuid := range d.Property("UUIDs").([]string) {
+		if !d.Adapter.allowed(uuid) {
+			affected = true
+			break
+		}
+	}
+	if d.obj.props.GetMust(profile.AdminPolicyStatus1Interface, "IsAffectedByPolicy").(bool) != affected {
+		d.obj.props.SetMust(profile.AdminPolicyStatus1Interface, "IsAffectedByPolicy", affected)
+	}
+}
+
+// device1 the org.bluez.Device1 methods
+type device1 struct {
+	d *Device
+}
+
+//Connect set Connected and ServicesResolved
+func (m *device1) Connect() *dbus.Error {
+	if m.d.Property("Connected").(bool) {
+		return bluez.ErrAlreadyConnected.DBusError()
+	}
+	if m.d.OnConnect != nil {
+		if err := m.d.OnConnect(); err != nil {
+			return bluez.ErrFailed.WithMessage(err.Error()).DBusError()
+		}
+	}
+	m.d.SetProperty("Connected", true)
+	m.d.SetProperty("ServicesResolved", true)
+	return nil
+}
+
+//Disconnect clear Connected and ServicesResolved
+func (m *device1) Disconnect() *dbus.Error {
+	if !m.d.Property("Connected").(bool) {
+		return bluez.ErrNotConnected.DBusError()
+	}
+	m.d.SetProperty("ServicesResolved", false)
+	m.d.SetProperty("Connected", false)
+	return nil
+}
+
+//ConnectProfile connect the device and hand over a socket to the profile
+// registered for uuid, if any
+func (m *device1) ConnectProfile(uuid string) *dbus.Error {
+	if m.d.OnConnectProfile != nil {
+		if err := m.d.OnConnectProfile(uuid); err != nil {
+			return bluez.ErrFailed.WithMessage(err.Error()).DBusError()
+		}
+	}
+	if !m.d.Property("Connected").(bool) {
+		if err := m.Connect(); err != nil {
+			return err
+		}
+	}
+	return m.d.connectProfile(uuid)
+}
+
+//DisconnectProfile the profile is ignored
+func (m *device1) DisconnectProfile(uuid string) *dbus.Error {
+	if !m.d.Property("Connected").(bool) {
+		return bluez.ErrNotConnected.DBusError()
+	}
+	return nil
+}
+
+//Pair set Paired
+func (m *device1) Pair() *dbus.Error {
+	if m.d.Property("Paired").(bool) {
+		return bluez.ErrAlreadyExists.DBusError()
+	}
+	if m.d.OnPair != nil {
+		if err := m.d.OnPair(); err != nil {
+			return bluez.ErrAuthenticationFailed.WithMessage(err.Error()).DBusError()
+		}
+	}
+	m.d.SetProperty("Paired", true)
+	return nil
+}
+
+//CancelPairing pairing completes immediately, nothing to cancel
+func (m *device1) CancelPairing() *dbus.Error {
+	return bluez.ErrDoesNotExist.DBusError()
+}
@@ -0,0 +1,210 @@
+package bluetest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//Characteristic a characteristic of the GATT database of a fake remote
+// device, implementing GattCharacteristic1
+type Characteristic struct {
+	UUID    string
+	Path    dbus.ObjectPath
+	Service dbus.ObjectPath
+	Device  *Device
+
+	// OnWrite called with the values written, an error fails the call
+	OnWrite func(value []byte) error
+
+	obj   *object
+	flags []string
+
+	mutex sync.Mutex
+	// fail the errors returned by the next calls, see FailNext
+	fail []*bluez.Error
+}
+
+//AddCharacteristic add a characteristic to the GATT database of the device,
+// creating its primary service on the first use. The flags are the bluez
+// ones, eg. bluez.FlagCharacteristicRead, the calls they do not allow fail
+// with NotPermitted and, as with bluez, every call fails with Failed "Not
+// connected" while the device is not connected
+func (d *Device) AddCharacteristic(serviceUUID, charUUID string, flags []string, value []byte) (*Characteristic, error) {
+
+	serviceID, err := bluetooth.ParseUUID(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+	charID, err := bluetooth.ParseUUID(charUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	b := d.Adapter.b
+	servicePath, err := d.service(strings.ToLower(serviceID.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	b.mutex.Lock()
+	path := dbus.ObjectPath(fmt.Sprintf("%s/char%04x", servicePath, len(b.objects)))
+	b.mutex.Unlock()
+
+	c := &Characteristic{
+		UUID:    strings.ToLower(charID.String()),
+		Path:    path,
+		Service: servicePath,
+		Device:  d,
+		flags:   flags,
+	}
+	if value == nil {
+		value = []byte{}
+	}
+
+	c.obj = b.addObject(path, map[string]map[string]*prop.Prop{
+		bluez.GattCharacteristic1Interface: {
+			"UUID":      property(c.UUID, false),
+			"Service":   property(servicePath, false),
+			"Value":     property(value, false),
+			"Notifying": property(false, false),
+			"Flags":     property(flags, false),
+		},
+	})
+
+	err = b.export(path, c.obj, map[string]interface{}{
+		bluez.GattCharacteristic1Interface: &gattCharacteristic1{c},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// service return the path of a service of the device, added if missing
+func (d *Device) service(uuid string) (dbus.ObjectPath, error) {
+
+	b := d.Adapter.b
+	b.mutex.Lock()
+	for path, o := range b.objects {
+		if !strings.HasPrefix(string(path), string(d.Path)+"/service") {
+			continue
+		}
+		if v, err := o.props.Get(bluez.GattService1Interface, "UUID"); err == nil && v.Value() == uuid {
+			b.mutex.Unlock()
+			return path, nil
+		}
+	}
+	path := dbus.ObjectPath(fmt.Sprintf("%s/service%04x", d.Path, len(b.objects)))
+	b.mutex.Unlock()
+
+	o := b.addObject(path, map[string]map[string]*prop.Prop{
+		bluez.GattService1Interface: {
+			"UUID":    property(uuid, false),
+			"Primary": property(true, false),
+			"Device":  property(d.Path, false),
+		},
+	})
+	err := b.export(path, o, map[string]interface{}{})
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+//Value return the current value of the characteristic
+func (c *Characteristic) Value() []byte {
+	return c.obj.props.GetMust(bluez.GattCharacteristic1Interface, "Value").([]byte)
+}
+
+//SetValue change the value, notified to the subscribed clients
+func (c *Characteristic) SetValue(value []byte) {
+	c.obj.props.SetMust(bluez.GattCharacteristic1Interface, "Value", value)
+}
+
+//FailNext make the next calls fail with errs, one error per call, eg.
+// bluez.ErrInProgress to test the retries of a client
+func (c *Characteristic) FailNext(errs ...*bluez.Error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.fail = append(c.fail, errs...)
+}
+
+// check return the error of a call requiring one of the flags
+func (c *Characteristic) check(flags ...string) *dbus.Error {
+
+	c.mutex.Lock()
+	if len(c.fail) > 0 {
+		err := c.fail[0]
+		c.fail = c.fail[1:]
+		c.mutex.Unlock()
+		return err.DBusError()
+	}
+	c.mutex.Unlock()
+
+	if !c.Device.Property("Connected").(bool) {
+		return bluez.ErrFailed.WithMessage("Not connected").DBusError()
+	}
+	for _, flag := range c.flags {
+		for _, f := range flags {
+			if flag == f {
+				return nil
+			}
+		}
+	}
+	return bluez.ErrNotPermitted.DBusError()
+}
+
+// gattCharacteristic1 the org.bluez.GattCharacteristic1 methods
+type gattCharacteristic1 struct {
+	c *Characteristic
+}
+
+//ReadValue return the value
+func (m *gattCharacteristic1) ReadValue(options map[string]dbus.Variant) ([]byte, *dbus.Error) {
+	if err := m.c.check(bluez.FlagCharacteristicRead); err != nil {
+		return nil, err
+	}
+	return m.c.Value(), nil
+}
+
+//WriteValue store the value, with or without response
+func (m *gattCharacteristic1) WriteValue(value []byte, options map[string]dbus.Variant) *dbus.Error {
+	flag := bluez.FlagCharacteristicWrite
+	if t, ok := options["type"]; ok && t.Value() == "command" {
+		flag = bluez.FlagCharacteristicWriteWithoutResponse
+	}
+	if err := m.c.check(flag); err != nil {
+		return err
+	}
+	if m.c.OnWrite != nil {
+		if err := m.c.OnWrite(value); err != nil {
+			return bluez.ErrFailed.WithMessage(err.Error()).DBusError()
+		}
+	}
+	m.c.SetValue(value)
+	return nil
+}
+
+//StartNotify set Notifying
+func (m *gattCharacteristic1) StartNotify() *dbus.Error {
+	if err := m.c.check(bluez.FlagCharacteristicNotify, bluez.FlagCharacteristicIndicate); err != nil {
+		return err
+	}
+	m.c.obj.props.SetMust(bluez.GattCharacteristic1Interface, "Notifying", true)
+	return nil
+}
+
+//StopNotify clear Notifying
+func (m *gattCharacteristic1) StopNotify() *dbus.Error {
+	if !m.c.obj.props.GetMust(bluez.GattCharacteristic1Interface, "Notifying").(bool) {
+		return bluez.ErrFailed.WithMessage("No notify session started").DBusError()
+	}
+	m.c.obj.props.SetMust(bluez.GattCharacteristic1Interface, "Notifying", false)
+	return nil
+}
@@ -0,0 +1,182 @@
+package bluetest
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+//DefaultHarnessTimeout the time allowed to discover and connect the peripheral
+const DefaultHarnessTimeout = 30 * time.Second
+
+//Harness an end to end environment: a peripheral Application served on one
+// virtual controller and a central client on the other
+type Harness struct {
+	// Peripheral the adapter serving the application
+	Peripheral string
+	// Central the adapter connecting to the peripheral
+	Central string
+	// Timeout used by Connect, defaults to DefaultHarnessTimeout
+	Timeout time.Duration
+
+	virtual *Virtual
+	app     *service.Application
+}
+
+//NewHarness create two virtual controllers, the test is skipped if VirtualEnv
+// is not set or the controllers cannot be created
+func NewHarness(t testing.TB) *Harness {
+
+	if os.Getenv(VirtualEnv) == "" {
+		t.Skipf("Set %s to run the tests on virtual controllers", VirtualEnv)
+	}
+
+	v, err := StartVirtual(2)
+	if err != nil {
+		t.Skipf("Cannot create the virtual controllers: %s", err.Error())
+	}
+
+	return &Harness{
+		Peripheral: v.Adapters[0],
+		Central:    v.Adapters[1],
+		Timeout:    DefaultHarnessTimeout,
+		virtual:    v,
+	}
+}
+
+//StartTest start the fake bluez with the adapter hci0 at 00:11:22:33:44:55,
+// the test is skipped if the bus cannot be started. Close it once done
+func StartTest(t testing.TB) *Bluez {
+	b, err := Start()
+	if err != nil {
+		t.Skipf("Cannot start the fake bluez: %s", err.Error())
+	}
+	if _, err = b.AddAdapter("hci0", "00:11:22:33:44:55"); err != nil {
+		b.Close()
+		t.Fatal(err)
+	}
+	return b
+}
+
+//Serve register the application on the peripheral adapter and advertise it.
+// The application has to be running, with its services added
+func (h *Harness) Serve(app *service.Application) error {
+
+	err := app.Register(h.Peripheral)
+	if err != nil {
+		return err
+	}
+
+	h.app = app
+	return app.StartAdvertising(h.Peripheral)
+}
+
+// wait poll fn until it returns true or the timeout expires
+func (h *Harness) wait(fn func() bool) bool {
+	deadline := time.Now().Add(h.Timeout)
+	for time.Now().Before(deadline) {
+		if fn() {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+//Connect discover the peripheral from the central adapter and connect to it,
+// waiting for the services to be resolved
+func (h *Harness) Connect() (*api.Device, error) {
+
+	props, err := profile.NewAdapter1(h.Peripheral).GetProperties()
+	if err != nil {
+		return nil, err
+	}
+	path := "/org/bluez/" + h.Central + "/dev_" + strings.Replace(props.Address, ":", "_", -1)
+
+	central := profile.NewAdapter1(h.Central)
+	err = central.StartDiscovery()
+	if err != nil && !bluez.IsError(err, bluez.ErrInProgress) {
+		return nil, err
+	}
+
+	found := h.wait(func() bool {
+		_, err := profile.NewDevice1(path).GetProperties()
+		return err == nil
+	})
+	central.StopDiscovery()
+	if !found {
+		return nil, errors.New("Peripheral not discovered")
+	}
+
+	dev := api.NewDevice(path)
+	err = dev.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := h.wait(func() bool {
+		props, err := dev.GetProperties()
+		return err == nil && props.ServicesResolved
+	})
+	if !resolved {
+		return nil, errors.New("Peripheral services not resolved")
+	}
+
+	return dev, nil
+}
+
+//Notifications start the notifications of a characteristic, the values are
+// sent on the returned channel. Call the returned function to stop
+func Notifications(char *profile.GattCharacteristic1) (<-chan []byte, func(), error) {
+
+	signals, err := char.Register()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = char.StartNotify()
+	if err != nil {
+		char.Unregister(signals)
+		return nil, nil, err
+	}
+
+	values := make(chan []byte, 16)
+	go func() {
+		defer close(values)
+		for sig := range signals {
+			if sig == nil || len(sig.Body) < 2 {
+				continue
+			}
+			changed, ok := sig.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
+			if value, ok := changed["Value"].Value().([]byte); ok {
+				values <- value
+			}
+		}
+	}()
+
+	stop := func() {
+		char.StopNotify()
+		char.Unregister(signals)
+	}
+	return values, stop, nil
+}
+
+//Close stop advertising and remove the virtual controllers
+func (h *Harness) Close() {
+	if h.app != nil {
+		h.app.StopAdvertising()
+		h.app.Unregister()
+	}
+	h.virtual.Close()
+}
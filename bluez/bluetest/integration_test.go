@@ -0,0 +1,124 @@
+package bluetest
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+//TestIntegration read, write and notify a characteristic end to end, run with
+// BLUETEST_VIRTUAL=1 as root
+func TestIntegration(t *testing.T) {
+
+	h := NewHarness(t)
+	defer h.Close()
+
+	var mutex sync.Mutex
+	value := []byte{1}
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "AAAA",
+		ObjectName: "org.bluez.bluetest.integration",
+		ObjectPath: "/bluetest/integration",
+		LocalName:  "bluetest",
+		ReadFunc: func(app *service.Application, srvUUID string, charUUID string) ([]byte, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return value, nil
+		},
+		WriteFunc: func(app *service.Application, srvUUID string, charUUID string, v []byte) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			value = v
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: true,
+		UUID:    app.GenerateUUID("0001"),
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.AddService(srv); err != nil {
+		t.Fatal(err)
+	}
+	char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID: app.GenerateUUID("0002"),
+		Flags: []string{
+			bluez.FlagCharacteristicRead,
+			bluez.FlagCharacteristicWrite,
+			bluez.FlagCharacteristicNotify,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.AddCharacteristic(char); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = h.Serve(app); err != nil {
+		t.Fatal(err)
+	}
+
+	dev, err := h.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Disconnect()
+
+	remote, err := dev.GetCharByUUID(app.GenerateUUID("0002"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := remote.ReadValue(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(read, []byte{1}) {
+		t.Fatalf("Unexpected value %v", read)
+	}
+
+	err = remote.WriteValue([]byte{2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err = remote.ReadValue(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(read, []byte{2}) {
+		t.Fatalf("Unexpected value after write %v", read)
+	}
+
+	values, stop, err := Notifications(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	char.UpdateValue([]byte{3})
+	select {
+	case v := <-values:
+		if !bytes.Equal(v, []byte{3}) {
+			t.Fatalf("Unexpected notification %v", v)
+		}
+	case <-time.After(h.Timeout):
+		t.Fatal("Timeout waiting for the notification")
+	}
+}
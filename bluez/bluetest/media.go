@@ -0,0 +1,77 @@
+package bluetest
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//Player a media player registered with Media1, exposed to the AVRCP
+// controllers
+type Player struct {
+	Sender string
+	Path   dbus.ObjectPath
+	// Properties the player properties passed to RegisterPlayer
+	Properties map[string]dbus.Variant
+
+	conn *dbus.Conn
+}
+
+//Command send an org.mpris.MediaPlayer2.Player method call to the player, as
+// bluez does for the commands of a controller, eg. Seek
+func (p *Player) Command(method string, args ...interface{}) error {
+	return p.conn.Object(p.Sender, p.Path).Call(bluez.MprisPlayerInterface+"."+method, 0, args...).Store()
+}
+
+//Property read a current property of the player
+func (p *Player) Property(name string) (dbus.Variant, error) {
+	var v dbus.Variant
+	err := p.conn.Object(p.Sender, p.Path).
+		Call(bluez.PropertiesInterface+".Get", 0, bluez.MprisPlayerInterface, name).
+		Store(&v)
+	return v, err
+}
+
+//Players return the registered media players
+func (a *Adapter) Players() []*Player {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	list := make([]*Player, 0, len(a.players))
+	for _, p := range a.players {
+		list = append(list, p)
+	}
+	return list
+}
+
+// media1 the org.bluez.Media1 methods, endpoints are not supported
+type media1 struct {
+	a *Adapter
+}
+
+//RegisterPlayer store the player
+func (m *media1) RegisterPlayer(sender dbus.Sender, path dbus.ObjectPath, properties map[string]dbus.Variant) *dbus.Error {
+	key := registrationKey{string(sender), path}
+	m.a.mutex.Lock()
+	defer m.a.mutex.Unlock()
+	if _, ok := m.a.players[key]; ok {
+		return bluez.ErrAlreadyExists.DBusError()
+	}
+	m.a.players[key] = &Player{
+		Sender:     string(sender),
+		Path:       path,
+		Properties: properties,
+		conn:       m.a.b.conn,
+	}
+	return nil
+}
+
+//UnregisterPlayer drop the player
+func (m *media1) UnregisterPlayer(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+	key := registrationKey{string(sender), path}
+	m.a.mutex.Lock()
+	defer m.a.mutex.Unlock()
+	if _, ok := m.a.players[key]; !ok {
+		return bluez.ErrDoesNotExist.DBusError()
+	}
+	delete(m.a.players, key)
+	return nil
+}
@@ -0,0 +1,97 @@
+package bluetest
+
+import (
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// profileManager1 the org.bluez.ProfileManager1 methods
+type profileManager1 struct {
+	b *Bluez
+}
+
+//RegisterProfile store the profile and its UUID, the options are ignored
+func (m *profileManager1) RegisterProfile(sender dbus.Sender, path dbus.ObjectPath, uuid string, options map[string]dbus.Variant) *dbus.Error {
+	key := registrationKey{string(sender), path}
+	m.b.mutex.Lock()
+	defer m.b.mutex.Unlock()
+	if _, ok := m.b.profiles[key]; ok {
+		return bluez.ErrAlreadyExists.DBusError()
+	}
+	m.b.profiles[key] = uuid
+	return nil
+}
+
+//UnregisterProfile drop the profile
+func (m *profileManager1) UnregisterProfile(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+	key := registrationKey{string(sender), path}
+	m.b.mutex.Lock()
+	defer m.b.mutex.Unlock()
+	if _, ok := m.b.profiles[key]; !ok {
+		return bluez.ErrDoesNotExist.DBusError()
+	}
+	delete(m.b.profiles, key)
+	return nil
+}
+
+//Profiles return the UUIDs of the registered profiles by path
+func (b *Bluez) Profiles() map[dbus.ObjectPath]string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	profiles := make(map[dbus.ObjectPath]string, len(b.profiles))
+	for key, uuid := range b.profiles {
+		profiles[key.path] = uuid
+	}
+	return profiles
+}
+
+// connectProfile hand over a socket to the profile registered for uuid, the
+// other end is passed to OnProfileConnection. Nothing is done if no profile
+// is registered
+func (d *Device) connectProfile(uuid string) *dbus.Error {
+
+	b := d.Adapter.b
+	var profile registrationKey
+	b.mutex.Lock()
+	for key, registered := range b.profiles {
+		if strings.EqualFold(registered, uuid) {
+			profile = key
+		}
+	}
+	b.mutex.Unlock()
+	if profile.path == "" {
+		return nil
+	}
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return bluez.ErrFailed.WithMessage(err.Error()).DBusError()
+	}
+	file := os.NewFile(uintptr(fds[1]), string(d.Path))
+	defer file.Close()
+
+	// the descriptor is duplicated to the profile
+	err = b.conn.Object(profile.sender, profile.path).
+		Call(bluez.Profile1Interface+".NewConnection", 0, d.Path, dbus.UnixFD(fds[0]), map[string]dbus.Variant{}).
+		Store()
+	syscall.Close(fds[0])
+	if err != nil {
+		return bluez.ErrFailed.WithMessage(err.Error()).DBusError()
+	}
+
+	conn, err := net.FileConn(file)
+	if err != nil {
+		return bluez.ErrFailed.WithMessage(err.Error()).DBusError()
+	}
+	if d.OnProfileConnection == nil {
+		conn.Close()
+		return nil
+	}
+	go d.OnProfileConnection(uuid, conn)
+	return nil
+}
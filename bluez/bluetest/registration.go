@@ -0,0 +1,250 @@
+package bluetest
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//Application a GATT application registered with GattManager1
+type Application struct {
+	// Sender the unique bus name of the application
+	Sender  string
+	Path    dbus.ObjectPath
+	Options map[string]dbus.Variant
+
+	conn    *dbus.Conn
+	mutex   sync.Mutex
+	objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+}
+
+//Refresh reload the objects of the application
+func (app *Application) Refresh() error {
+	objects := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
+	err := app.conn.Object(app.Sender, app.Path).
+		Call(bluez.ObjectManagerInterface+".GetManagedObjects", 0).
+		Store(&objects)
+	if err != nil {
+		return err
+	}
+	app.mutex.Lock()
+	app.objects = objects
+	app.mutex.Unlock()
+	return nil
+}
+
+//Objects return the objects exposed by the application, as returned by
+// GetManagedObjects
+func (app *Application) Objects() map[dbus.ObjectPath]map[string]map[string]dbus.Variant {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	return app.objects
+}
+
+// find return the path of the first object of iface with the given UUID
+func (app *Application) find(iface string, uuid string) (dbus.ObjectPath, error) {
+	for path, ifaces := range app.Objects() {
+		props, ok := ifaces[iface]
+		if !ok {
+			continue
+		}
+		if v, ok := props["UUID"].Value().(string); ok && strings.EqualFold(v, uuid) {
+			return path, nil
+		}
+	}
+	return "", errors.New("No " + iface + " with UUID " + uuid)
+}
+
+//Services return the UUIDs of the exposed services
+func (app *Application) Services() []string {
+	uuids := []string{}
+	for _, ifaces := range app.Objects() {
+		if props, ok := ifaces[bluez.GattService1Interface]; ok {
+			if v, ok := props["UUID"].Value().(string); ok {
+				uuids = append(uuids, v)
+			}
+		}
+	}
+	return uuids
+}
+
+func (app *Application) call(path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	return app.conn.Object(app.Sender, path).Call(method, 0, args...)
+}
+
+//ReadValue read a characteristic as a remote device would
+func (app *Application) ReadValue(uuid string, options map[string]dbus.Variant) ([]byte, error) {
+	path, err := app.find(bluez.GattCharacteristic1Interface, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = map[string]dbus.Variant{}
+	}
+	var value []byte
+	err = app.call(path, bluez.GattCharacteristic1Interface+".ReadValue", options).Store(&value)
+	return value, bluez.ParseError(err)
+}
+
+//WriteValue write a characteristic as a remote device would
+func (app *Application) WriteValue(uuid string, value []byte, options map[string]dbus.Variant) error {
+	path, err := app.find(bluez.GattCharacteristic1Interface, uuid)
+	if err != nil {
+		return err
+	}
+	if options == nil {
+		options = map[string]dbus.Variant{}
+	}
+	err = app.call(path, bluez.GattCharacteristic1Interface+".WriteValue", value, options).Store()
+	return bluez.ParseError(err)
+}
+
+//ReadDescriptor read the first descriptor with the given UUID, as a remote
+// device would
+func (app *Application) ReadDescriptor(uuid string, options map[string]dbus.Variant) ([]byte, error) {
+	path, err := app.find(bluez.GattDescriptor1Interface, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = map[string]dbus.Variant{}
+	}
+	var value []byte
+	err = app.call(path, bluez.GattDescriptor1Interface+".ReadValue", options).Store(&value)
+	return value, bluez.ParseError(err)
+}
+
+//WriteDescriptor write the first descriptor with the given UUID, as a remote
+// device would
+func (app *Application) WriteDescriptor(uuid string, value []byte, options map[string]dbus.Variant) error {
+	path, err := app.find(bluez.GattDescriptor1Interface, uuid)
+	if err != nil {
+		return err
+	}
+	if options == nil {
+		options = map[string]dbus.Variant{}
+	}
+	err = app.call(path, bluez.GattDescriptor1Interface+".WriteValue", value, options).Store()
+	return bluez.ParseError(err)
+}
+
+//StartNotify subscribe to a characteristic
+func (app *Application) StartNotify(uuid string) error {
+	path, err := app.find(bluez.GattCharacteristic1Interface, uuid)
+	if err != nil {
+		return err
+	}
+	return bluez.ParseError(app.call(path, bluez.GattCharacteristic1Interface+".StartNotify").Store())
+}
+
+//StopNotify unsubscribe from a characteristic
+func (app *Application) StopNotify(uuid string) error {
+	path, err := app.find(bluez.GattCharacteristic1Interface, uuid)
+	if err != nil {
+		return err
+	}
+	return bluez.ParseError(app.call(path, bluez.GattCharacteristic1Interface+".StopNotify").Store())
+}
+
+//Confirm acknowledge an indication of a characteristic, as bluez does when
+// the remote device confirms it
+func (app *Application) Confirm(uuid string) error {
+	path, err := app.find(bluez.GattCharacteristic1Interface, uuid)
+	if err != nil {
+		return err
+	}
+	return bluez.ParseError(app.call(path, bluez.GattCharacteristic1Interface+".Confirm").Store())
+}
+
+//Advertisement an advertisement registered with LEAdvertisingManager1
+type Advertisement struct {
+	Sender string
+	Path   dbus.ObjectPath
+
+	conn       *dbus.Conn
+	mutex      sync.Mutex
+	properties map[string]dbus.Variant
+}
+
+//Refresh reload the advertisement properties
+func (adv *Advertisement) Refresh() error {
+	props := make(map[string]dbus.Variant)
+	err := adv.conn.Object(adv.Sender, adv.Path).
+		Call(bluez.PropertiesInterface+".GetAll", 0, bluez.LEAdvertisement1Interface).
+		Store(&props)
+	if err != nil {
+		return err
+	}
+	if _, ok := props["Type"]; !ok {
+		return errors.New("Advertisement Type is missing")
+	}
+	adv.mutex.Lock()
+	adv.properties = props
+	adv.mutex.Unlock()
+	return nil
+}
+
+//Properties return the LEAdvertisement1 properties
+func (adv *Advertisement) Properties() map[string]dbus.Variant {
+	adv.mutex.Lock()
+	defer adv.mutex.Unlock()
+	return adv.properties
+}
+
+//LocalName return the advertised name, if any
+func (adv *Advertisement) LocalName() string {
+	name, _ := adv.Properties()["LocalName"].Value().(string)
+	return name
+}
+
+//ServiceUUIDs return the advertised service UUIDs
+func (adv *Advertisement) ServiceUUIDs() []string {
+	uuids, _ := adv.Properties()["ServiceUUIDs"].Value().([]string)
+	return uuids
+}
+
+//BatteryProvider a battery provider registered with BatteryProviderManager1
+type BatteryProvider struct {
+	Sender string
+	Path   dbus.ObjectPath
+
+	conn    *dbus.Conn
+	mutex   sync.Mutex
+	objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+}
+
+//Refresh reload the batteries of the provider
+func (p *BatteryProvider) Refresh() error {
+	objects := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
+	err := p.conn.Object(p.Sender, p.Path).
+		Call(bluez.ObjectManagerInterface+".GetManagedObjects", 0).
+		Store(&objects)
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	p.objects = objects
+	p.mutex.Unlock()
+	return nil
+}
+
+//Batteries return the percentage of the batteries by device, as loaded
+// by Refresh
+func (p *BatteryProvider) Batteries() map[dbus.ObjectPath]byte {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	batteries := make(map[dbus.ObjectPath]byte)
+	for _, ifaces := range p.objects {
+		props, ok := ifaces[profile.BatteryProvider1Interface]
+		if !ok {
+			continue
+		}
+		device, _ := props["Device"].Value().(dbus.ObjectPath)
+		batteries[device], _ = props["Percentage"].Value().(byte)
+	}
+	return batteries
+}
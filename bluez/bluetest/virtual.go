@@ -0,0 +1,117 @@
+package bluetest
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//VirtualEnv set this environment variable to run the tests on virtual
+// controllers, they require root, the hci_vhci module, btvirt and a running
+// bluetoothd
+const VirtualEnv = "BLUETEST_VIRTUAL"
+
+const sysClassBluetooth = "/sys/class/bluetooth"
+
+//Virtual LE controllers emulated by btvirt, managed by the system bluetoothd
+type Virtual struct {
+	// Adapters the ids of the created controllers, eg. hci1
+	Adapters []string
+
+	cmd *exec.Cmd
+}
+
+// controllers list the hci devices known by the kernel
+func controllers() map[string]bool {
+	list := make(map[string]bool)
+	files, err := ioutil.ReadDir(sysClassBluetooth)
+	if err != nil {
+		return list
+	}
+	for _, f := range files {
+		// skip connections, eg. hci0:1
+		if strings.HasPrefix(f.Name(), "hci") && !strings.Contains(f.Name(), ":") {
+			list[f.Name()] = true
+		}
+	}
+	return list
+}
+
+//StartVirtual create count LE controllers with btvirt and wait for bluetoothd
+// to power them on
+func StartVirtual(count int) (*Virtual, error) {
+
+	bin, err := exec.LookPath("btvirt")
+	if err != nil {
+		return nil, err
+	}
+
+	before := controllers()
+
+	cmd := exec.Command(bin, "-L", fmt.Sprintf("-l%d", count))
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Virtual{cmd: cmd}
+
+	for i := 0; i < 100 && len(v.Adapters) < count; i++ {
+		time.Sleep(50 * time.Millisecond)
+		v.Adapters = v.Adapters[:0]
+		for id := range controllers() {
+			if !before[id] {
+				v.Adapters = append(v.Adapters, id)
+			}
+		}
+	}
+	if len(v.Adapters) < count {
+		v.Close()
+		return nil, errors.New("Timeout waiting for the virtual controllers")
+	}
+
+	for _, id := range v.Adapters {
+		err = powerOn(id)
+		if err != nil {
+			v.Close()
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+// powerOn wait for bluetoothd to expose the adapter and power it
+func powerOn(id string) error {
+
+	adapter := profile.NewAdapter1(id)
+
+	var err error
+	for i := 0; i < 100; i++ {
+		if _, err = adapter.GetProperties(); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		return fmt.Errorf("Adapter %s not found: %s", id, err.Error())
+	}
+
+	return adapter.SetProperty("Powered", dbus.MakeVariant(true))
+}
+
+//Close stop btvirt, the controllers are removed
+func (v *Virtual) Close() error {
+	if v.cmd.Process == nil {
+		return nil
+	}
+	v.cmd.Process.Kill()
+	v.cmd.Wait()
+	return nil
+}
@@ -4,6 +4,8 @@ import "github.com/godbus/dbus/introspect"
 
 const (
 
+	//OrgBluez the bus name of bluetoothd
+	OrgBluez = "org.bluez"
 	//Device1Interface the bluez interface for Device1
 	Device1Interface = "org.bluez.Device1"
 	//Adapter1Interface the bluez interface for Adapter1
@@ -16,6 +18,53 @@ const (
 	GattDescriptor1Interface = "org.bluez.GattDescriptor1"
 	//LEAdvertisement1Interface the bluez interface for LEAdvertisement1
 	LEAdvertisement1Interface = "org.bluez.LEAdvertisement1"
+	//GattManager1Interface the bluez interface for GattManager1
+	GattManager1Interface = "org.bluez.GattManager1"
+	//LEAdvertisingManager1Interface the bluez interface for LEAdvertisingManager1
+	LEAdvertisingManager1Interface = "org.bluez.LEAdvertisingManager1"
+	//Agent1Interface the bluez interface for Agent1
+	Agent1Interface = "org.bluez.Agent1"
+	//AgentManager1Interface the bluez interface for AgentManager1
+	AgentManager1Interface = "org.bluez.AgentManager1"
+	//Profile1Interface the bluez interface for Profile1
+	Profile1Interface = "org.bluez.Profile1"
+	//ProfileManager1Interface the bluez interface for ProfileManager1
+	ProfileManager1Interface = "org.bluez.ProfileManager1"
+	//Media1Interface the bluez interface for Media1
+	Media1Interface = "org.bluez.Media1"
+	//MediaEndpoint1Interface the bluez interface for MediaEndpoint1
+	MediaEndpoint1Interface = "org.bluez.MediaEndpoint1"
+	//MediaTransport1Interface the bluez interface for MediaTransport1
+	MediaTransport1Interface = "org.bluez.MediaTransport1"
+	//MediaControl1Interface the bluez interface for MediaControl1
+	MediaControl1Interface = "org.bluez.MediaControl1"
+	//MediaPlayer1Interface the bluez interface for MediaPlayer1
+	MediaPlayer1Interface = "org.bluez.MediaPlayer1"
+	//Network1Interface the bluez interface for Network1
+	Network1Interface = "org.bluez.Network1"
+	//NetworkServer1Interface the bluez interface for NetworkServer1
+	NetworkServer1Interface = "org.bluez.NetworkServer1"
+	//Input1Interface the bluez interface for Input1
+	Input1Interface = "org.bluez.Input1"
+	//MprisPlayerInterface the MPRIS interface implemented by players registered with Media1
+	MprisPlayerInterface = "org.mpris.MediaPlayer2.Player"
+
+	//MeshService the bus name of bluetooth-meshd
+	MeshService = "org.bluez.mesh"
+	//MeshNetwork1Interface the bluetooth-meshd interface for Network1
+	MeshNetwork1Interface = "org.bluez.mesh.Network1"
+	//MeshNode1Interface the bluetooth-meshd interface for Node1
+	MeshNode1Interface = "org.bluez.mesh.Node1"
+	//MeshManagement1Interface the bluetooth-meshd interface for Management1
+	MeshManagement1Interface = "org.bluez.mesh.Management1"
+	//MeshApplication1Interface the interface of applications attached to bluetooth-meshd
+	MeshApplication1Interface = "org.bluez.mesh.Application1"
+	//MeshElement1Interface the interface of the elements of an application
+	MeshElement1Interface = "org.bluez.mesh.Element1"
+	//MeshProvisioner1Interface the interface of provisioner applications
+	MeshProvisioner1Interface = "org.bluez.mesh.Provisioner1"
+	//MeshProvisionAgent1Interface the interface of the provisioning agent
+	MeshProvisionAgent1Interface = "org.bluez.mesh.ProvisionAgent1"
 
 	//ObjectManagerInterface the dbus object manager interface
 	ObjectManagerInterface = "org.freedesktop.DBus.ObjectManager"
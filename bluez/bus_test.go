@@ -0,0 +1,144 @@
+package bluez_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+func TestCallTimeout(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	d, err := b.AddDevice("hci0", "aa:bb:cc:dd:ee:ff", "sensor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.OnConnect = func() error {
+		time.Sleep(time.Second)
+		return nil
+	}
+
+	bluez.SetMethodTimeout(bluez.Device1Interface+".Connect", 100*time.Millisecond)
+	defer bluez.SetMethodTimeout(bluez.Device1Interface+".Connect", 0)
+
+	begin := time.Now()
+	err = profile.NewDevice1(string(d.Path)).Connect()
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected %s, got %v", context.DeadlineExceeded, err)
+	}
+	if time.Since(begin) > 500*time.Millisecond {
+		t.Fatalf("Connect returned after %s", time.Since(begin))
+	}
+}
+
+func TestSignalDispatcher(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	d1, err := b.AddDevice("hci0", "00:00:00:00:00:01", "one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2, err := b.AddDevice("hci0", "00:00:00:00:00:02", "two")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev1 := profile.NewDevice1(string(d1.Path))
+	ch1, err := dev1.Register()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev2 := profile.NewDevice1(string(d2.Path))
+	ch2, err := dev2.Register()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := bluez.GetSignalDispatcher(b.ClientConn())
+	if dispatcher.Matches() != 1 {
+		t.Fatalf("Expected a single match, got %d", dispatcher.Matches())
+	}
+
+	d2.SetProperty("RSSI", int16(-40))
+	select {
+	case sig := <-ch2:
+		if sig.Path != d2.Path {
+			t.Fatalf("Unexpected signal from %s", sig.Path)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the signal")
+	}
+	select {
+	case sig := <-ch1:
+		t.Fatalf("Unexpected signal from %s", sig.Path)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	dev1.Unregister(ch1)
+	dev2.Unregister(ch2)
+	if dispatcher.Matches() != 0 {
+		t.Fatalf("Expected no match, got %d", dispatcher.Matches())
+	}
+}
+
+func TestSignalDispatcherSlowSubscriber(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	d, err := b.AddDevice("hci0", "00:00:00:00:00:01", "one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher := bluez.GetSignalDispatcher(b.ClientConn())
+	filter := bluez.SignalFilter{Path: d.Path, Interface: bluez.PropertiesInterface, Member: "PropertiesChanged"}
+	slow, err := dispatcher.Subscribe(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, err := dispatcher.Subscribe(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dispatcher.Unsubscribe(fast)
+
+	// a call to the bus from a subscriber does not block the dispatcher
+	d.SetProperty("RSSI", int16(-1))
+	select {
+	case <-fast:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the signal")
+	}
+	other, err := dispatcher.Subscribe(bluez.SignalFilter{Interface: bluez.ObjectManagerInterface})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.Unsubscribe(other)
+
+	// more signals than the slow subscriber can queue
+	for i := 2; i <= bluez.SignalQueueSize+50; i++ {
+		d.SetProperty("RSSI", int16(-i))
+		select {
+		case <-fast:
+		case <-time.After(time.Second):
+			t.Fatalf("Signal %d not received", i)
+		}
+	}
+
+	if len(slow) == 0 {
+		t.Fatal("Expected the first signals queued for the slow subscriber")
+	}
+	if err = dispatcher.Unsubscribe(slow); err != nil {
+		t.Fatal(err)
+	}
+	// nothing is sent once unsubscribed
+	close(slow)
+}
@@ -0,0 +1,35 @@
+package bluez
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+//PanicError a user callback panicked, see SafeCall
+type PanicError struct {
+	// Callback the name of the callback, eg. ReadFunc
+	Callback string
+	// Value the value passed to panic
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.Callback, e.Value)
+}
+
+//SafeCall run a user callback, a panic is returned as a *PanicError and
+// logged with its stack trace on logger, GetLogger() if nil. Use it for the
+// callbacks run by the exported objects, a panic would stop the whole process
+func SafeCall(logger Logger, name string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if logger == nil {
+				logger = GetLogger()
+			}
+			err = &PanicError{Callback: name, Value: r}
+			RecordEvent(Event{Type: EventPanic, Name: name, Error: err.Error()})
+			logger.Errorf("%s\n%s", err.Error(), debug.Stack())
+		}
+	}()
+	return fn()
+}
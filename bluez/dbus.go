@@ -2,6 +2,7 @@ package bluez
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/godbus/dbus"
 )
@@ -30,16 +31,25 @@ const (
 
 var conns = make([]*dbus.Conn, 2)
 
+// connsLock guard the shared connections, set and resolved from any goroutine
+var connsLock sync.Mutex
+
 // Config pass configuration to a DBUS client
 type Config struct {
 	Name  string
 	Iface string
 	Path  string
 	Bus   BusType
+	// Logger log the calls of the client, defaults to GetLogger()
+	Logger Logger
+	// Conn the connection to use instead of the shared Bus connection
+	Conn *dbus.Conn
 }
 
 //GetConnection get a DBus connection
 func GetConnection(connType BusType) (*dbus.Conn, error) {
+	connsLock.Lock()
+	defer connsLock.Unlock()
 	switch connType {
 	case SystemBus:
 		if conns[SystemBus] == nil {
@@ -65,3 +75,57 @@ func GetConnection(connType BusType) (*dbus.Conn, error) {
 		return nil, errors.New("Unmanged DBus type code")
 	}
 }
+
+var dialed = struct {
+	sync.Mutex
+	conns map[string]*dbus.Conn
+}{conns: make(map[string]*dbus.Conn)}
+
+//SetConnection replace the shared connection of a bus, eg. with a private
+// connection in tests. The clients connecting afterwards use it
+func SetConnection(connType BusType, conn *dbus.Conn) error {
+	if connType != SystemBus && connType != SessionBus {
+		return errors.New("Unmanged DBus type code")
+	}
+	connsLock.Lock()
+	defer connsLock.Unlock()
+	conns[connType] = conn
+	return nil
+}
+
+//DialBus connect to the bus at address, eg. a system bus proxied in a
+// container. Connections are shared by address
+func DialBus(address string) (*dbus.Conn, error) {
+
+	dialed.Lock()
+	defer dialed.Unlock()
+
+	if conn, ok := dialed.conns[address]; ok {
+		return conn, nil
+	}
+
+	conn, err := dbus.Dial(address)
+	if err != nil {
+		return nil, err
+	}
+	if err = conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err = conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	dialed.conns[address] = conn
+	return conn, nil
+}
+
+//ResolveConnection return the connection to the bus at address, or the
+// system bus connection when address is empty
+func ResolveConnection(address string) (*dbus.Conn, error) {
+	if address == "" {
+		return GetConnection(SystemBus)
+	}
+	return DialBus(address)
+}
@@ -0,0 +1,46 @@
+package bluez
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func TestSetConnection(t *testing.T) {
+
+	previous := conns[SessionBus]
+	defer func() { conns[SessionBus] = previous }()
+
+	first, second := &dbus.Conn{}, &dbus.Conn{}
+	if err := SetConnection(SessionBus, first); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			SetConnection(SessionBus, second)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			conn, err := GetConnection(SessionBus)
+			if err != nil || (conn != first && conn != second) {
+				t.Errorf("Unexpected connection %p: %v", conn, err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	if conn, _ := GetConnection(SessionBus); conn != second {
+		t.Fatal("Expected the last connection set")
+	}
+	if err := SetConnection(BusType(5), first); err == nil {
+		t.Fatal("Expected an error for an unknown bus")
+	}
+}
@@ -0,0 +1,117 @@
+package bluez
+
+import (
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+//ErrorPrefix the prefix of the errors returned by bluetoothd
+const ErrorPrefix = "org.bluez.Error."
+
+//Error an error returned by bluez, identified by its D-Bus name. Errors
+// returned by the clients can be compared with the Err* values using
+// IsError, or errors.Is with go1.13 and later
+type Error struct {
+	// Name the D-Bus error name, eg. org.bluez.Error.InProgress
+	Name string
+	// Message the optional description sent along the error
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+	return e.Name + ": " + e.Message
+}
+
+//Is match errors with the same name, regardless of the message
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Name == e.Name
+}
+
+//DBusError return the error for the reply of an exported method
+func (e *Error) DBusError() *dbus.Error {
+	if e.Message == "" {
+		return dbus.NewError(e.Name, nil)
+	}
+	return dbus.NewError(e.Name, []interface{}{e.Message})
+}
+
+//WithMessage return a copy of the error with a description
+func (e *Error) WithMessage(message string) *Error {
+	return &Error{Name: e.Name, Message: message}
+}
+
+// Errors returned by bluetoothd
+var (
+	ErrFailed                  = &Error{Name: ErrorPrefix + "Failed"}
+	ErrInvalidArguments        = &Error{Name: ErrorPrefix + "InvalidArguments"}
+	ErrInProgress              = &Error{Name: ErrorPrefix + "InProgress"}
+	ErrAlreadyExists           = &Error{Name: ErrorPrefix + "AlreadyExists"}
+	ErrDoesNotExist            = &Error{Name: ErrorPrefix + "DoesNotExist"}
+	ErrNotReady                = &Error{Name: ErrorPrefix + "NotReady"}
+	ErrNotPermitted            = &Error{Name: ErrorPrefix + "NotPermitted"}
+	ErrNotAuthorized           = &Error{Name: ErrorPrefix + "NotAuthorized"}
+	ErrNotAvailable            = &Error{Name: ErrorPrefix + "NotAvailable"}
+	ErrNotSupported            = &Error{Name: ErrorPrefix + "NotSupported"}
+	ErrNotConnected            = &Error{Name: ErrorPrefix + "NotConnected"}
+	ErrAlreadyConnected        = &Error{Name: ErrorPrefix + "AlreadyConnected"}
+	ErrNotAcquired             = &Error{Name: ErrorPrefix + "NotAcquired"}
+	ErrConnectionAttemptFailed = &Error{Name: ErrorPrefix + "ConnectionAttemptFailed"}
+	ErrAuthenticationFailed    = &Error{Name: ErrorPrefix + "AuthenticationFailed"}
+	ErrAuthenticationCanceled  = &Error{Name: ErrorPrefix + "AuthenticationCanceled"}
+	ErrAuthenticationRejected  = &Error{Name: ErrorPrefix + "AuthenticationRejected"}
+	ErrAuthenticationTimeout   = &Error{Name: ErrorPrefix + "AuthenticationTimeout"}
+	ErrInvalidOffset           = &Error{Name: ErrorPrefix + "InvalidOffset"}
+	ErrInvalidValueLength      = &Error{Name: ErrorPrefix + "InvalidValueLength"}
+	ErrRejected                = &Error{Name: ErrorPrefix + "Rejected"}
+	ErrCanceled                = &Error{Name: ErrorPrefix + "Canceled"}
+)
+
+//ParseError convert the D-Bus errors returned by bluez to *Error, other
+// errors are returned unchanged
+func ParseError(err error) error {
+
+	var name string
+	var body []interface{}
+	switch e := err.(type) {
+	case dbus.Error:
+		name, body = e.Name, e.Body
+	case *dbus.Error:
+		name, body = e.Name, e.Body
+	default:
+		return err
+	}
+
+	if !strings.HasPrefix(name, "org.bluez.") {
+		return err
+	}
+
+	bzErr := &Error{Name: name}
+	if len(body) > 0 {
+		if msg, ok := body[0].(string); ok {
+			bzErr.Message = msg
+		}
+	}
+	return bzErr
+}
+
+//IsError return true if err is a bluez error with the name of target, eg.
+// IsError(err, bluez.ErrInProgress). The errors wrapping a cause, with an
+// Unwrap method, are unwrapped
+func IsError(err error, target *Error) bool {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			return e.Is(target)
+		}
+		wrapper, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = wrapper.Unwrap()
+	}
+	return false
+}
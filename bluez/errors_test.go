@@ -0,0 +1,58 @@
+package bluez
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func TestParseError(t *testing.T) {
+
+	err := ParseError(dbus.Error{
+		Name: "org.bluez.Error.InProgress",
+		Body: []interface{}{"Operation already in progress"},
+	})
+
+	if !IsError(err, ErrInProgress) {
+		t.Fatalf("Expected InProgress, got %v", err)
+	}
+	if IsError(err, ErrNotReady) {
+		t.Fatal("Unexpected NotReady match")
+	}
+	if err.Error() != "org.bluez.Error.InProgress: Operation already in progress" {
+		t.Fatalf("Unexpected message %s", err.Error())
+	}
+
+	other := dbus.Error{Name: "org.freedesktop.DBus.Error.UnknownObject"}
+	if _, ok := ParseError(other).(dbus.Error); !ok {
+		t.Fatal("Expected non bluez errors to be unchanged")
+	}
+
+	plain := errors.New("plain")
+	if ParseError(plain) != plain || ParseError(nil) != nil {
+		t.Fatal("Expected errors to be unchanged")
+	}
+}
+
+// wrapped an error with a cause
+type wrapped struct {
+	err error
+}
+
+func (w wrapped) Error() string { return "wrapped: " + w.err.Error() }
+func (w wrapped) Unwrap() error { return w.err }
+
+func TestIsErrorWrapped(t *testing.T) {
+
+	err := wrapped{wrapped{ErrNotConnected.WithMessage("Not connected")}}
+	if !IsError(err, ErrNotConnected) {
+		t.Fatalf("Expected NotConnected, got %v", err)
+	}
+	if IsError(err, ErrFailed) {
+		t.Fatal("Unexpected Failed match")
+	}
+	if IsError(wrapped{errors.New("plain")}, ErrFailed) || IsError(nil, ErrFailed) {
+		t.Fatal("Unexpected match of a non bluez error")
+	}
+}
@@ -0,0 +1,237 @@
+package bluez
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// Types of the events recorded in the EventLog
+const (
+	EventCall    = "call"
+	EventConnect = "connect"
+	EventRead    = "read"
+	EventWrite   = "write"
+	EventSignal  = "signal"
+	EventError   = "error"
+	EventPanic   = "panic"
+)
+
+//DefaultEventLogSize how many events the default EventLog keeps
+const DefaultEventLogSize = 256
+
+//EventDataSize the length the data of an event is truncated to, eg. the value
+// of a write
+const EventDataSize = 64
+
+//Event an entry of the EventLog
+type Event struct {
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	// Path the object involved, eg. the device or the characteristic
+	Path string `json:"path,omitempty"`
+	// Name the method, signal or UUID, eg. org.bluez.Device1.Connect
+	Name string `json:"name,omitempty"`
+	// Data the arguments or the value, truncated to EventDataSize
+	Data string `json:"data,omitempty"`
+	// Duration of the call, if any
+	Duration time.Duration `json:"duration,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+//EventLog keep the last events in memory, to report what led up to a failure
+// without verbose logging. It is cheap enough to stay always on
+type EventLog struct {
+	mutex  sync.Mutex
+	events []Event
+	next   int
+	full   bool
+}
+
+//NewEventLog create a log keeping the last size events
+func NewEventLog(size int) *EventLog {
+	if size <= 0 {
+		size = DefaultEventLogSize
+	}
+	return &EventLog{
+		events: make([]Event, size),
+	}
+}
+
+//Record add an event, replacing the oldest one when full. Time is set if zero
+// and Data is truncated to EventDataSize
+func (l *EventLog) Record(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Data = truncateData(e.Data)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.events[l.next] = e
+	l.next++
+	if l.next == len(l.events) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+//Events return a copy of the events, the oldest first
+func (l *EventLog) Events() []Event {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if !l.full {
+		list := make([]Event, l.next)
+		copy(list, l.events[:l.next])
+		return list
+	}
+	list := make([]Event, 0, len(l.events))
+	list = append(list, l.events[l.next:]...)
+	return append(list, l.events[:l.next]...)
+}
+
+//Reset drop all the events
+func (l *EventLog) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for i := range l.events {
+		l.events[i] = Event{}
+	}
+	l.next = 0
+	l.full = false
+}
+
+//Dump write the events as JSON, one per line
+func (l *EventLog) Dump(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, e := range l.Events() {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var eventLog = struct {
+	sync.RWMutex
+	l *EventLog
+}{l: NewEventLog(DefaultEventLogSize)}
+
+//SetEventLog replace the event log, eg. to keep more events. nil disables
+// the recording
+func SetEventLog(l *EventLog) {
+	eventLog.Lock()
+	defer eventLog.Unlock()
+	eventLog.l = l
+}
+
+//GetEventLog return the event log, nil if disabled
+func GetEventLog() *EventLog {
+	eventLog.RLock()
+	defer eventLog.RUnlock()
+	return eventLog.l
+}
+
+//RecordEvent add an event to the event log, if enabled
+func RecordEvent(e Event) {
+	if l := GetEventLog(); l != nil {
+		l.Record(e)
+	}
+}
+
+//DumpEventsOnPanic write the event log to stderr when the calling goroutine
+// panics, then panic again. Defer it at the top of main or of a goroutine:
+//
+//	defer bluez.DumpEventsOnPanic()
+func DumpEventsOnPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if l := GetEventLog(); l != nil {
+		fmt.Fprintf(os.Stderr, "bluez: last events before panic: %v\n", r)
+		l.Dump(os.Stderr)
+	}
+	panic(r)
+}
+
+// recordCall record a call made by a Client, once the reply is received
+func recordCall(path string, method string, args []interface{}, start time.Time, err error) {
+	if GetEventLog() == nil {
+		return
+	}
+	t := EventCall
+	if op, ok := metricMethods[method]; ok {
+		switch op {
+		case MetricRead:
+			t = EventRead
+		case MetricWrite:
+			t = EventWrite
+		case MetricConnect:
+			t = EventConnect
+		}
+	}
+	e := Event{
+		Time:     start,
+		Type:     t,
+		Path:     path,
+		Name:     method,
+		Data:     EventData(args...),
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	RecordEvent(e)
+}
+
+// recordSignal record a signal received by a SignalDispatcher
+func recordSignal(sig *dbus.Signal) {
+	if GetEventLog() == nil {
+		return
+	}
+	RecordEvent(Event{
+		Type: EventSignal,
+		Path: string(sig.Path),
+		Name: sig.Name,
+		Data: EventData(sig.Body...),
+	})
+}
+
+//EventData format values for an Event, bytes as hex
+func EventData(values ...interface{}) string {
+	s := ""
+	for i, v := range values {
+		if i > 0 {
+			s += " "
+		}
+		switch val := v.(type) {
+		case []byte:
+			s += hex.EncodeToString(val)
+		case dbus.Variant:
+			s += EventData(val.Value())
+		default:
+			s += fmt.Sprintf("%v", v)
+		}
+		if len(s) > EventDataSize {
+			break
+		}
+	}
+	return truncateData(s)
+}
+
+func truncateData(s string) string {
+	if len(s) <= EventDataSize {
+		return s
+	}
+	return s[:EventDataSize] + "..."
+}
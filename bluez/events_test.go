@@ -0,0 +1,81 @@
+package bluez
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventLog(t *testing.T) {
+
+	l := NewEventLog(3)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		l.Record(Event{Type: EventCall, Name: name})
+	}
+
+	events := l.Events()
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	for i, name := range []string{"b", "c", "d"} {
+		if events[i].Name != name {
+			t.Fatalf("Expected %s at %d, got %s", name, i, events[i].Name)
+		}
+		if events[i].Time.IsZero() {
+			t.Fatal("Expected the time to be set")
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := l.Dump(buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Fatalf("Expected 3 lines, got %d", lines)
+	}
+
+	l.Reset()
+	if len(l.Events()) != 0 {
+		t.Fatal("Expected no events after Reset")
+	}
+}
+
+func TestEventData(t *testing.T) {
+	if d := EventData([]byte{0x01, 0xff}, "x"); d != "01ff x" {
+		t.Fatalf("Unexpected data %s", d)
+	}
+	d := EventData(make([]byte, 100))
+	if len(d) != EventDataSize+3 || !strings.HasSuffix(d, "...") {
+		t.Fatalf("Expected truncated data, got %s", d)
+	}
+}
+
+func TestRecordCall(t *testing.T) {
+
+	l := NewEventLog(10)
+	SetEventLog(l)
+	defer SetEventLog(NewEventLog(DefaultEventLogSize))
+
+	recordCall("/org/bluez/hci0/dev_00", Device1Interface+".Connect", nil, time.Now(), errors.New("failed"))
+	recordCall("/org/bluez/hci0/dev_00/service0001/char0002", GattCharacteristic1Interface+".WriteValue",
+		[]interface{}{[]byte{0x2a}}, time.Now(), nil)
+
+	events := l.Events()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].Type != EventConnect || events[0].Error != "failed" {
+		t.Fatalf("Unexpected event %v", events[0])
+	}
+	if events[1].Type != EventWrite || events[1].Data != "2a" {
+		t.Fatalf("Unexpected event %v", events[1])
+	}
+
+	SetEventLog(nil)
+	RecordEvent(Event{Type: EventCall})
+	if len(l.Events()) != 2 {
+		t.Fatal("Expected no recording when disabled")
+	}
+}
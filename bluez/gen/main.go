@@ -0,0 +1,399 @@
+//Command gen generate the typed clients of the bluez D-Bus interfaces from
+// their introspection XML, eg. as returned by
+//
+//	busctl introspect --xml-interface org.bluez /org/bluez/hci0
+//
+// Each interface is written to <Name>_gen.go, see bluez/profile/generate.go
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/godbus/dbus/introspect"
+)
+
+func main() {
+
+	pkg := flag.String("pkg", "profile", "the package of the generated files")
+	out := flag.String("out", ".", "the directory of the generated files")
+	prefix := flag.String("prefix", "org.bluez.", "generate the interfaces with this prefix only")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: gen [-pkg profile] [-out dir] file.xml|dir...")
+		os.Exit(2)
+	}
+
+	// the directories are replaced by the XML files they contain
+	files := []string{}
+	for _, arg := range flag.Args() {
+		info, err := os.Stat(arg)
+		if err != nil {
+			fail(err)
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		list, err := filepath.Glob(filepath.Join(arg, "*.xml"))
+		if err != nil {
+			fail(err)
+		}
+		files = append(files, list...)
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			fail(err)
+		}
+		node := introspect.Node{}
+		if err = xml.Unmarshal(data, &node); err != nil {
+			fail(fmt.Errorf("%s: %s", file, err))
+		}
+		for _, iface := range node.Interfaces {
+			if !strings.HasPrefix(iface.Name, *prefix) {
+				continue
+			}
+			src, err := Generate(*pkg, iface)
+			if err != nil {
+				fail(fmt.Errorf("%s: %s", iface.Name, err))
+			}
+			name := filepath.Join(*out, TypeName(iface.Name)+"_gen.go")
+			if err = ioutil.WriteFile(name, src, 0644); err != nil {
+				fail(err)
+			}
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "gen:", err)
+	os.Exit(1)
+}
+
+//TypeName return the Go type of an interface, eg. AdminPolicySet1 for
+// org.bluez.AdminPolicySet1 and MeshNode1 for org.bluez.mesh.Node1
+func TypeName(iface string) string {
+	name := strings.TrimPrefix(iface, "org.bluez.")
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = exported(p)
+	}
+	return strings.Join(parts, "")
+}
+
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+var keywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+	// the receiver and the locals of the generated methods
+	"a": true, "err": true,
+}
+
+// argName return a Go identifier for an argument, i is used for unnamed ones
+func argName(name string, i int) string {
+	if name == "" {
+		return fmt.Sprintf("arg%d", i)
+	}
+	// camel case, eg. element_path to elementPath
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
+	for j := 1; j < len(parts); j++ {
+		parts[j] = exported(parts[j])
+	}
+	name = strings.Join(parts, "")
+	// lower the leading capitals, eg. UUIDs to uuids
+	i = 0
+	for i < len(name) && name[i] >= 'A' && name[i] <= 'Z' {
+		i++
+	}
+	name = strings.ToLower(name[:i]) + name[i:]
+	if keywords[name] {
+		name += "Value"
+	}
+	return name
+}
+
+var basicTypes = map[byte]string{
+	'y': "byte",
+	'b': "bool",
+	'n': "int16",
+	'q': "uint16",
+	'i': "int32",
+	'u': "uint32",
+	'x': "int64",
+	't': "uint64",
+	'd': "float64",
+	's': "string",
+	'o': "dbus.ObjectPath",
+	'g': "dbus.Signature",
+	'h': "dbus.UnixFD",
+	'v': "dbus.Variant",
+}
+
+//GoType return the Go type of a D-Bus signature, structs are decoded as
+// []interface{}
+func GoType(sig string) (string, error) {
+	t, rest, err := goType(sig)
+	if err != nil {
+		return "", err
+	}
+	if rest != "" {
+		return "", fmt.Errorf("invalid signature %s", sig)
+	}
+	return t, nil
+}
+
+func goType(sig string) (string, string, error) {
+	if sig == "" {
+		return "", "", fmt.Errorf("empty signature")
+	}
+	if t, ok := basicTypes[sig[0]]; ok {
+		return t, sig[1:], nil
+	}
+	switch sig[0] {
+	case 'a':
+		if strings.HasPrefix(sig, "a{") {
+			key, rest, err := goType(sig[2:])
+			if err != nil {
+				return "", "", err
+			}
+			value, rest, err := goType(rest)
+			if err != nil {
+				return "", "", err
+			}
+			if !strings.HasPrefix(rest, "}") {
+				return "", "", fmt.Errorf("invalid dict in %s", sig)
+			}
+			return "map[" + key + "]" + value, rest[1:], nil
+		}
+		elem, rest, err := goType(sig[1:])
+		if err != nil {
+			return "", "", err
+		}
+		return "[]" + elem, rest, nil
+	case '(':
+		rest := sig[1:]
+		for !strings.HasPrefix(rest, ")") {
+			var err error
+			_, rest, err = goType(rest)
+			if err != nil {
+				return "", "", err
+			}
+		}
+		return "[]interface{}", rest[1:], nil
+	}
+	return "", "", fmt.Errorf("unsupported type %c in %s", sig[0], sig)
+}
+
+type arg struct {
+	Name string
+	Type string
+}
+
+type method struct {
+	Name string
+	In   []arg
+	Out  []arg
+}
+
+type property struct {
+	Name     string
+	Type     string
+	Writable bool
+}
+
+type generated struct {
+	Package    string
+	Interface  string
+	Type       string
+	Methods    []method
+	Properties []property
+	Signals    bool
+}
+
+//Generate the source of the client of an interface
+func Generate(pkg string, iface introspect.Interface) ([]byte, error) {
+
+	g := generated{
+		Package:   pkg,
+		Interface: iface.Name,
+		Type:      TypeName(iface.Name),
+		Signals:   len(iface.Signals) > 0,
+	}
+
+	for _, m := range iface.Methods {
+		gm := method{Name: exported(m.Name)}
+		for i, a := range m.Args {
+			t, err := GoType(a.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", m.Name, err)
+			}
+			if a.Direction == "out" {
+				gm.Out = append(gm.Out, arg{Name: fmt.Sprintf("r%d", len(gm.Out)), Type: t})
+			} else {
+				gm.In = append(gm.In, arg{Name: argName(a.Name, i), Type: t})
+			}
+		}
+		g.Methods = append(g.Methods, gm)
+	}
+	sort.Slice(g.Methods, func(i, j int) bool { return g.Methods[i].Name < g.Methods[j].Name })
+
+	for _, p := range iface.Properties {
+		t, err := GoType(p.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", p.Name, err)
+		}
+		g.Properties = append(g.Properties, property{
+			Name:     exported(p.Name),
+			Type:     t,
+			Writable: strings.Contains(p.Access, "write"),
+		})
+	}
+
+	buf := new(bytes.Buffer)
+	if err := clientTemplate.Execute(buf, g); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+var clientTemplate = template.Must(template.New("client").Funcs(template.FuncMap{
+	"args": func(list []arg) string {
+		s := make([]string, len(list))
+		for i, a := range list {
+			s[i] = a.Name + " " + a.Type
+		}
+		return strings.Join(s, ", ")
+	},
+	"names": func(list []arg) string {
+		s := make([]string, len(list))
+		for i, a := range list {
+			s[i] = a.Name
+		}
+		return strings.Join(s, ", ")
+	},
+	"refs": func(list []arg) string {
+		s := make([]string, len(list))
+		for i, a := range list {
+			s[i] = "&" + a.Name
+		}
+		return strings.Join(s, ", ")
+	},
+}).Parse(`// Code generated by bluez/gen from the introspection of {{.Interface}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//{{.Type}}Interface the bluez interface for {{.Type}}
+const {{.Type}}Interface = "{{.Interface}}"
+
+//New{{.Type}} create a new {{.Type}} client
+func New{{.Type}}(path string) *{{.Type}} {
+	a := new({{.Type}})
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: {{.Type}}Interface,
+			Path:  path,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new({{.Type}}Properties)
+	return a
+}
+
+//{{.Type}} client
+type {{.Type}} struct {
+	client     *bluez.Client
+	Properties *{{.Type}}Properties
+}
+
+//{{.Type}}Properties exposed properties of a {{.Type}}
+type {{.Type}}Properties struct {
+{{- range .Properties}}
+	{{.Name}} {{.Type}}
+{{- end}}
+}
+
+//SetConnection use conn for the calls, eg. to register objects exported on
+// a private connection
+func (a *{{.Type}}) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+//Close the connection
+func (a *{{.Type}}) Close() {
+	a.client.Disconnect()
+}
+
+//GetProperties load all available properties
+func (a *{{.Type}}) GetProperties() (*{{.Type}}Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+//GetProperty get a property
+func (a *{{.Type}}) GetProperty(name string) (dbus.Variant, error) {
+	return a.client.GetProperty(name)
+}
+{{- range .Properties}}{{if .Writable}}
+
+//Set{{.Name}} set the {{.Name}} property
+func (a *{{$.Type}}) Set{{.Name}}(value {{.Type}}) error {
+	return a.client.SetProperty("{{.Name}}", dbus.MakeVariant(value))
+}
+{{- end}}{{end}}
+
+//Register for changes signalling
+func (a *{{.Type}}) Register() (chan *dbus.Signal, error) {
+	return a.client.Register(a.client.Config.Path, bluez.PropertiesInterface)
+}
+
+//Unregister for changes signalling
+func (a *{{.Type}}) Unregister(signal chan *dbus.Signal) error {
+	return a.client.Unregister(a.client.Config.Path, bluez.PropertiesInterface, signal)
+}
+{{- if .Signals}}
+
+//RegisterSignals receive the signals of {{.Interface}}
+func (a *{{.Type}}) RegisterSignals() (chan *dbus.Signal, error) {
+	return a.client.Register(a.client.Config.Path, {{.Type}}Interface)
+}
+{{- end}}
+{{- range .Methods}}
+
+//{{.Name}} call {{$.Interface}}.{{.Name}}
+func (a *{{$.Type}}) {{.Name}}({{args .In}}) {{if .Out}}({{args .Out}}, err error){{else}}error{{end}} {
+{{- if .Out}}
+	err = a.client.Call("{{.Name}}", 0{{if .In}}, {{names .In}}{{end}}).Store({{refs .Out}})
+	return
+{{- else}}
+	return a.client.Call("{{.Name}}", 0{{if .In}}, {{names .In}}{{end}}).Store()
+{{- end}}
+}
+{{- end}}
+`))
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/introspect"
+)
+
+func TestGoType(t *testing.T) {
+	tests := map[string]string{
+		"s":      "string",
+		"ao":     "[]dbus.ObjectPath",
+		"ay":     "[]byte",
+		"a{sv}":  "map[string]dbus.Variant",
+		"a{qay}": "map[uint16][]byte",
+		"a(sy)":  "[][]interface{}",
+	}
+	for sig, expected := range tests {
+		got, err := GoType(sig)
+		if err != nil {
+			t.Fatalf("%s: %s", sig, err)
+		}
+		if got != expected {
+			t.Errorf("%s: expected %s, got %s", sig, expected, got)
+		}
+	}
+	for _, sig := range []string{"", "a{sv", "ss", "z"} {
+		if _, err := GoType(sig); err == nil {
+			t.Errorf("%s: expected an error", sig)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+
+	iface := introspect.Interface{
+		Name: "org.bluez.mesh.Node1",
+		Methods: []introspect.Method{
+			{Name: "Send", Args: []introspect.Arg{
+				{Name: "element_path", Type: "o", Direction: "in"},
+				{Name: "type", Type: "q", Direction: "in"},
+				{Name: "data", Type: "ay", Direction: "in"},
+			}},
+			{Name: "Attach", Args: []introspect.Arg{
+				{Name: "token", Type: "t", Direction: "in"},
+				{Name: "node", Type: "o", Direction: "out"},
+				{Name: "configuration", Type: "a(ya(qa{sv}))", Direction: "out"},
+			}},
+		},
+		Properties: []introspect.Property{
+			{Name: "Beacon", Type: "b", Access: "read"},
+			{Name: "Features", Type: "a{sv}", Access: "readwrite"},
+		},
+	}
+
+	src, err := Generate("profile", iface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(src)
+	for _, expected := range []string{
+		"type MeshNode1 struct",
+		"Beacon   bool",
+		"func (a *MeshNode1) SetFeatures(value map[string]dbus.Variant) error",
+		"func (a *MeshNode1) Send(elementPath dbus.ObjectPath, typeValue uint16, data []byte) error",
+		"func (a *MeshNode1) Attach(token uint64) (r0 dbus.ObjectPath, r1 [][]interface{}, err error)",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Missing %q in\n%s", expected, code)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package bluez
+
+import (
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//Logger the logging interface used by the library. It is satisfied by the
+// logrus Logger and Entry, so a logrus.WithField(...) entry can be used to
+// tag the messages of a component
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+var logger = struct {
+	sync.RWMutex
+	l Logger
+}{l: log.StandardLogger()}
+
+//SetLogger set the default logger, nil restores the logrus standard logger
+func SetLogger(l Logger) {
+	if l == nil {
+		l = log.StandardLogger()
+	}
+	logger.Lock()
+	defer logger.Unlock()
+	logger.l = l
+}
+
+//GetLogger return the default logger
+func GetLogger() Logger {
+	logger.RLock()
+	defer logger.RUnlock()
+	return logger.l
+}
+
+//NopLogger discard all the messages
+type NopLogger struct{}
+
+//Debugf discard the message
+func (NopLogger) Debugf(format string, args ...interface{}) {}
+
+//Infof discard the message
+func (NopLogger) Infof(format string, args ...interface{}) {}
+
+//Warnf discard the message
+func (NopLogger) Warnf(format string, args ...interface{}) {}
+
+//Errorf discard the message
+func (NopLogger) Errorf(format string, args ...interface{}) {}
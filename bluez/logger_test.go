@@ -0,0 +1,102 @@
+package bluez_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+)
+
+// recordedLogger keep the debug messages
+type recordedLogger struct {
+	bluez.NopLogger
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (l *recordedLogger) Debugf(format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *recordedLogger) logged() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string{}, l.messages...)
+}
+
+func newAdapterClient(logger bluez.Logger) *bluez.Client {
+	return bluez.NewClient(&bluez.Config{
+		Name:   "org.bluez",
+		Iface:  bluez.Adapter1Interface,
+		Path:   "/org/bluez/hci0",
+		Bus:    bluez.SystemBus,
+		Logger: logger,
+	})
+}
+
+func TestLoggerCalls(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	l := &recordedLogger{}
+	bluez.SetLogger(l)
+	defer bluez.SetLogger(nil)
+
+	c := newAdapterClient(nil)
+	if err := c.Call("StartDiscovery", 0).Store(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Call("StartDiscovery", 0).Store(); !bluez.IsError(err, bluez.ErrInProgress) {
+		t.Fatalf("Expected InProgress, got %v", err)
+	}
+	if err := c.SetProperty("Alias", "bluetest"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"bluez: call /org/bluez/hci0 org.bluez.Adapter1.StartDiscovery []",
+		"bluez: call /org/bluez/hci0 org.bluez.Adapter1.StartDiscovery []",
+		"bluez: /org/bluez/hci0 org.bluez.Adapter1.StartDiscovery: org.bluez.Error.InProgress",
+		"bluez: set /org/bluez/hci0 org.bluez.Adapter1.Alias bluetest",
+	}
+	logged := l.logged()
+	if len(logged) != len(expected) {
+		t.Fatalf("Expected %d messages, got %q", len(expected), logged)
+	}
+	for i, msg := range expected {
+		if !strings.HasPrefix(logged[i], msg) {
+			t.Errorf("%d: expected %q, got %q", i, msg, logged[i])
+		}
+	}
+}
+
+func TestLoggerConfig(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	global := &recordedLogger{}
+	bluez.SetLogger(global)
+	defer bluez.SetLogger(nil)
+
+	// the client logger takes over the default one
+	l := &recordedLogger{}
+	if err := newAdapterClient(l).Call("StartDiscovery", 0).Store(); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.logged()) != 1 || len(global.logged()) != 0 {
+		t.Fatalf("Expected the call logged by the client logger, got %q and %q", l.logged(), global.logged())
+	}
+
+	bluez.SetLogger(nil)
+	if bluez.GetLogger() != log.StandardLogger() {
+		t.Fatal("Expected the logrus standard logger restored")
+	}
+}
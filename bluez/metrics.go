@@ -0,0 +1,86 @@
+package bluez
+
+import (
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// Operations recorded by the Metrics
+const (
+	MetricRead    = "read"
+	MetricWrite   = "write"
+	MetricNotify  = "notify"
+	MetricConnect = "connect"
+	MetricPair    = "pair"
+)
+
+//Metrics record the operations of the library, eg. to monitor a fleet of
+// gateways. See the metrics package for a Prometheus implementation
+type Metrics interface {
+	// Observe record an operation, eg. MetricRead, with its duration and its
+	// result. Notifications sent by an application have no duration
+	Observe(op string, duration time.Duration, err error)
+	// Error record an error returned by bluez to a call, by method and D-Bus
+	// error name, eg. org.bluez.Device1.Connect, org.bluez.Error.Failed
+	Error(method string, name string)
+}
+
+// metricMethods the calls recorded as operations, by method
+var metricMethods = map[string]string{
+	GattCharacteristic1Interface + ".ReadValue":  MetricRead,
+	GattCharacteristic1Interface + ".WriteValue": MetricWrite,
+	GattDescriptor1Interface + ".ReadValue":      MetricRead,
+	GattDescriptor1Interface + ".WriteValue":     MetricWrite,
+	Device1Interface + ".Connect":                MetricConnect,
+	Device1Interface + ".ConnectProfile":         MetricConnect,
+	Device1Interface + ".Pair":                   MetricPair,
+}
+
+var metrics = struct {
+	sync.RWMutex
+	m Metrics
+}{m: NopMetrics{}}
+
+//SetMetrics set the metrics recorder, nil disables the metrics
+func SetMetrics(m Metrics) {
+	if m == nil {
+		m = NopMetrics{}
+	}
+	metrics.Lock()
+	defer metrics.Unlock()
+	metrics.m = m
+}
+
+//GetMetrics return the metrics recorder
+func GetMetrics() Metrics {
+	metrics.RLock()
+	defer metrics.RUnlock()
+	return metrics.m
+}
+
+//NopMetrics discard all the metrics
+type NopMetrics struct{}
+
+//Observe discard the operation
+func (NopMetrics) Observe(op string, duration time.Duration, err error) {}
+
+//Error discard the error
+func (NopMetrics) Error(method string, name string) {}
+
+// observeCall record a call made by a Client, once the reply is received
+func observeCall(method string, start time.Time, err error) {
+	m := GetMetrics()
+	if op, ok := metricMethods[method]; ok {
+		m.Observe(op, time.Since(start), err)
+	}
+	switch e := err.(type) {
+	case *Error:
+		m.Error(method, e.Name)
+	case dbus.Error:
+		m.Error(method, e.Name)
+	case *dbus.Error:
+		m.Error(method, e.Name)
+	}
+}
@@ -0,0 +1,10 @@
+//Package metrics record the operations of the library with Prometheus. The
+// implementation depends on github.com/prometheus/client_golang and is built
+// with the prometheus tag:
+//
+//	go build -tags prometheus
+//
+//	m := metrics.NewPrometheus("ble")
+//	prometheus.MustRegister(m)
+//	bluez.SetMetrics(m)
+package metrics
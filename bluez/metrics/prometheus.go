@@ -0,0 +1,78 @@
+//go:build prometheus
+// +build prometheus
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//Prometheus a bluez.Metrics exposing the operations as Prometheus counters
+// and histograms
+type Prometheus struct {
+	operations *prometheus.CounterVec
+	failures   *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+	errors     *prometheus.CounterVec
+}
+
+//NewPrometheus create the collectors, named after namespace
+func NewPrometheus(namespace string) *Prometheus {
+	return &Prometheus{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "operations_total",
+			Help:      "Reads, writes, notifications, connections and pairings",
+		}, []string{"op"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "operations_failed_total",
+			Help:      "Operations that returned an error",
+		}, []string{"op"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of the operations",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"op"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bluez_errors_total",
+			Help:      "Errors returned by bluez, by method and name",
+		}, []string{"method", "name"}),
+	}
+}
+
+//Describe implement prometheus.Collector
+func (p *Prometheus) Describe(ch chan<- *prometheus.Desc) {
+	p.operations.Describe(ch)
+	p.failures.Describe(ch)
+	p.latency.Describe(ch)
+	p.errors.Describe(ch)
+}
+
+//Collect implement prometheus.Collector
+func (p *Prometheus) Collect(ch chan<- prometheus.Metric) {
+	p.operations.Collect(ch)
+	p.failures.Collect(ch)
+	p.latency.Collect(ch)
+	p.errors.Collect(ch)
+}
+
+//Observe record an operation
+func (p *Prometheus) Observe(op string, duration time.Duration, err error) {
+	p.operations.WithLabelValues(op).Inc()
+	if err != nil {
+		p.failures.WithLabelValues(op).Inc()
+	}
+	if duration > 0 {
+		p.latency.WithLabelValues(op).Observe(duration.Seconds())
+	}
+}
+
+//Error record an error returned by bluez
+func (p *Prometheus) Error(method string, name string) {
+	p.errors.WithLabelValues(method, name).Inc()
+}
@@ -0,0 +1,47 @@
+package bluez
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type recordedMetrics struct {
+	ops    []string
+	failed int
+	errors []string
+}
+
+func (r *recordedMetrics) Observe(op string, duration time.Duration, err error) {
+	r.ops = append(r.ops, op)
+	if err != nil {
+		r.failed++
+	}
+}
+
+func (r *recordedMetrics) Error(method string, name string) {
+	r.errors = append(r.errors, name)
+}
+
+func TestObserveCall(t *testing.T) {
+
+	r := &recordedMetrics{}
+	SetMetrics(r)
+	defer SetMetrics(nil)
+
+	start := time.Now()
+	observeCall(GattCharacteristic1Interface+".ReadValue", start, nil)
+	observeCall(Device1Interface+".Connect", start, ErrFailed)
+	observeCall(Adapter1Interface+".StartDiscovery", start, ErrInProgress)
+	observeCall(Adapter1Interface+".StopDiscovery", start, errors.New("timeout"))
+
+	if len(r.ops) != 2 || r.ops[0] != MetricRead || r.ops[1] != MetricConnect {
+		t.Fatalf("Unexpected operations %v", r.ops)
+	}
+	if r.failed != 1 {
+		t.Fatalf("Expected 1 failure, got %d", r.failed)
+	}
+	if len(r.errors) != 2 || r.errors[0] != ErrFailed.Name || r.errors[1] != ErrInProgress.Name {
+		t.Fatalf("Unexpected errors %v", r.errors)
+	}
+}
@@ -1,6 +1,8 @@
 package profile
 
 import (
+	"context"
+
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/bluez"
 )
@@ -48,6 +50,11 @@ func (a *Adapter1) Close() {
 	a.client.Disconnect()
 }
 
+//SetConnection send the calls on conn instead of the system bus
+func (a *Adapter1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
 //GetProperties load all available properties
 func (a *Adapter1) GetProperties() (*Adapter1Properties, error) {
 	err := a.client.GetProperties(a.Properties)
@@ -64,6 +71,20 @@ func (a *Adapter1) StartDiscovery() error {
 	return a.client.Call("StartDiscovery", 0).Store()
 }
 
+//StartDiscoveryContext start the discovery and stop it when the context is
+// done, eg. to bound a scan with context.WithTimeout
+func (a *Adapter1) StartDiscoveryContext(ctx context.Context) error {
+	err := a.client.CallWithContext(ctx, "StartDiscovery", 0).Store()
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		a.StopDiscovery()
+	}()
+	return nil
+}
+
 //StopDiscovery on the adapter
 func (a *Adapter1) StopDiscovery() error {
 	return a.client.Call("StopDiscovery", 0).Store()
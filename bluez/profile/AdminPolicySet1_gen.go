@@ -0,0 +1,73 @@
+// Code generated by bluez/gen from the introspection of org.bluez.AdminPolicySet1. DO NOT EDIT.
+
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// AdminPolicySet1Interface the bluez interface for AdminPolicySet1
+const AdminPolicySet1Interface = "org.bluez.AdminPolicySet1"
+
+// NewAdminPolicySet1 create a new AdminPolicySet1 client
+func NewAdminPolicySet1(path string) *AdminPolicySet1 {
+	a := new(AdminPolicySet1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: AdminPolicySet1Interface,
+			Path:  path,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(AdminPolicySet1Properties)
+	return a
+}
+
+// AdminPolicySet1 client
+type AdminPolicySet1 struct {
+	client     *bluez.Client
+	Properties *AdminPolicySet1Properties
+}
+
+// AdminPolicySet1Properties exposed properties of a AdminPolicySet1
+type AdminPolicySet1Properties struct {
+}
+
+// SetConnection use conn for the calls, eg. to register objects exported on
+// a private connection
+func (a *AdminPolicySet1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+// Close the connection
+func (a *AdminPolicySet1) Close() {
+	a.client.Disconnect()
+}
+
+// GetProperties load all available properties
+func (a *AdminPolicySet1) GetProperties() (*AdminPolicySet1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+// GetProperty get a property
+func (a *AdminPolicySet1) GetProperty(name string) (dbus.Variant, error) {
+	return a.client.GetProperty(name)
+}
+
+// Register for changes signalling
+func (a *AdminPolicySet1) Register() (chan *dbus.Signal, error) {
+	return a.client.Register(a.client.Config.Path, bluez.PropertiesInterface)
+}
+
+// Unregister for changes signalling
+func (a *AdminPolicySet1) Unregister(signal chan *dbus.Signal) error {
+	return a.client.Unregister(a.client.Config.Path, bluez.PropertiesInterface, signal)
+}
+
+// SetServiceAllowList call org.bluez.AdminPolicySet1.SetServiceAllowList
+func (a *AdminPolicySet1) SetServiceAllowList(uuids []string) error {
+	return a.client.Call("SetServiceAllowList", 0, uuids).Store()
+}
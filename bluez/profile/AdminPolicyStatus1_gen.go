@@ -0,0 +1,70 @@
+// Code generated by bluez/gen from the introspection of org.bluez.AdminPolicyStatus1. DO NOT EDIT.
+
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// AdminPolicyStatus1Interface the bluez interface for AdminPolicyStatus1
+const AdminPolicyStatus1Interface = "org.bluez.AdminPolicyStatus1"
+
+// NewAdminPolicyStatus1 create a new AdminPolicyStatus1 client
+func NewAdminPolicyStatus1(path string) *AdminPolicyStatus1 {
+	a := new(AdminPolicyStatus1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: AdminPolicyStatus1Interface,
+			Path:  path,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(AdminPolicyStatus1Properties)
+	return a
+}
+
+// AdminPolicyStatus1 client
+type AdminPolicyStatus1 struct {
+	client     *bluez.Client
+	Properties *AdminPolicyStatus1Properties
+}
+
+// AdminPolicyStatus1Properties exposed properties of a AdminPolicyStatus1
+type AdminPolicyStatus1Properties struct {
+	ServiceAllowList   []string
+	IsAffectedByPolicy bool
+}
+
+// SetConnection use conn for the calls, eg. to register objects exported on
+// a private connection
+func (a *AdminPolicyStatus1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+// Close the connection
+func (a *AdminPolicyStatus1) Close() {
+	a.client.Disconnect()
+}
+
+// GetProperties load all available properties
+func (a *AdminPolicyStatus1) GetProperties() (*AdminPolicyStatus1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+// GetProperty get a property
+func (a *AdminPolicyStatus1) GetProperty(name string) (dbus.Variant, error) {
+	return a.client.GetProperty(name)
+}
+
+// Register for changes signalling
+func (a *AdminPolicyStatus1) Register() (chan *dbus.Signal, error) {
+	return a.client.Register(a.client.Config.Path, bluez.PropertiesInterface)
+}
+
+// Unregister for changes signalling
+func (a *AdminPolicyStatus1) Unregister(signal chan *dbus.Signal) error {
+	return a.client.Unregister(a.client.Config.Path, bluez.PropertiesInterface, signal)
+}
@@ -0,0 +1,53 @@
+package profile_test
+
+import (
+	"testing"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+func TestAdminPolicy(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	d, err := b.AddDevice("hci0", "00:00:00:00:00:01", "sensor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetProperty("UUIDs", []string{
+		"0000180f-0000-1000-8000-00805f9b34fb",
+		"0000181a-0000-1000-8000-00805f9b34fb",
+	})
+
+	set := profile.NewAdminPolicySet1("/org/bluez/hci0")
+	if err = set.SetServiceAllowList([]string{"180F"}); err != nil {
+		t.Fatal(err)
+	}
+	status, err := profile.NewAdminPolicyStatus1("/org/bluez/hci0").GetProperties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.ServiceAllowList) != 1 || status.ServiceAllowList[0] != "0000180f-0000-1000-8000-00805f9b34fb" {
+		t.Fatalf("Unexpected allow list %v", status.ServiceAllowList)
+	}
+
+	device := profile.NewAdminPolicyStatus1(string(d.Path))
+	if props, err := device.GetProperties(); err != nil || !props.IsAffectedByPolicy {
+		t.Fatalf("Expected the device affected by the policy: %+v %v", props, err)
+	}
+
+	if err = set.SetServiceAllowList(nil); err != nil {
+		t.Fatal(err)
+	}
+	if props, err := device.GetProperties(); err != nil || props.IsAffectedByPolicy {
+		t.Fatalf("Expected the device allowed: %+v %v", props, err)
+	}
+
+	err = set.SetServiceAllowList([]string{"battery"})
+	if !bluez.IsError(err, bluez.ErrInvalidArguments) {
+		t.Fatalf("Expected InvalidArguments, got %v", err)
+	}
+}
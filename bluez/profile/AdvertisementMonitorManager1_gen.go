@@ -0,0 +1,80 @@
+// Code generated by bluez/gen from the introspection of org.bluez.AdvertisementMonitorManager1. DO NOT EDIT.
+
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// AdvertisementMonitorManager1Interface the bluez interface for AdvertisementMonitorManager1
+const AdvertisementMonitorManager1Interface = "org.bluez.AdvertisementMonitorManager1"
+
+// NewAdvertisementMonitorManager1 create a new AdvertisementMonitorManager1 client
+func NewAdvertisementMonitorManager1(path string) *AdvertisementMonitorManager1 {
+	a := new(AdvertisementMonitorManager1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: AdvertisementMonitorManager1Interface,
+			Path:  path,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(AdvertisementMonitorManager1Properties)
+	return a
+}
+
+// AdvertisementMonitorManager1 client
+type AdvertisementMonitorManager1 struct {
+	client     *bluez.Client
+	Properties *AdvertisementMonitorManager1Properties
+}
+
+// AdvertisementMonitorManager1Properties exposed properties of a AdvertisementMonitorManager1
+type AdvertisementMonitorManager1Properties struct {
+	SupportedMonitorTypes []string
+	SupportedFeatures     []string
+}
+
+// SetConnection use conn for the calls, eg. to register objects exported on
+// a private connection
+func (a *AdvertisementMonitorManager1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+// Close the connection
+func (a *AdvertisementMonitorManager1) Close() {
+	a.client.Disconnect()
+}
+
+// GetProperties load all available properties
+func (a *AdvertisementMonitorManager1) GetProperties() (*AdvertisementMonitorManager1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+// GetProperty get a property
+func (a *AdvertisementMonitorManager1) GetProperty(name string) (dbus.Variant, error) {
+	return a.client.GetProperty(name)
+}
+
+// Register for changes signalling
+func (a *AdvertisementMonitorManager1) Register() (chan *dbus.Signal, error) {
+	return a.client.Register(a.client.Config.Path, bluez.PropertiesInterface)
+}
+
+// Unregister for changes signalling
+func (a *AdvertisementMonitorManager1) Unregister(signal chan *dbus.Signal) error {
+	return a.client.Unregister(a.client.Config.Path, bluez.PropertiesInterface, signal)
+}
+
+// RegisterMonitor call org.bluez.AdvertisementMonitorManager1.RegisterMonitor
+func (a *AdvertisementMonitorManager1) RegisterMonitor(application dbus.ObjectPath) error {
+	return a.client.Call("RegisterMonitor", 0, application).Store()
+}
+
+// UnregisterMonitor call org.bluez.AdvertisementMonitorManager1.UnregisterMonitor
+func (a *AdvertisementMonitorManager1) UnregisterMonitor(application dbus.ObjectPath) error {
+	return a.client.Call("UnregisterMonitor", 0, application).Store()
+}
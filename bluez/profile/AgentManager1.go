@@ -0,0 +1,60 @@
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// Agent IO capabilities, see RegisterAgent
+const (
+	AgentCapabilityDisplayOnly     = "DisplayOnly"
+	AgentCapabilityDisplayYesNo    = "DisplayYesNo"
+	AgentCapabilityKeyboardOnly    = "KeyboardOnly"
+	AgentCapabilityNoInputNoOutput = "NoInputNoOutput"
+	AgentCapabilityKeyboardDisplay = "KeyboardDisplay"
+)
+
+// NewAgentManager1 create a new AgentManager1 client
+func NewAgentManager1() *AgentManager1 {
+	a := new(AgentManager1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: bluez.AgentManager1Interface,
+			Path:  "/org/bluez",
+			Bus:   bluez.SystemBus,
+		},
+	)
+	return a
+}
+
+// AgentManager1 client
+type AgentManager1 struct {
+	client *bluez.Client
+}
+
+// Close the connection
+func (a *AgentManager1) Close() {
+	a.client.Disconnect()
+}
+
+//SetConnection send the calls on conn, the connection the agent is exported
+// on
+func (a *AgentManager1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+//RegisterAgent register an agent handler with the given IO capability
+func (a *AgentManager1) RegisterAgent(agent dbus.ObjectPath, capability string) error {
+	return a.client.Call("RegisterAgent", 0, agent, capability).Store()
+}
+
+//UnregisterAgent unregister an agent that has been previously registered
+func (a *AgentManager1) UnregisterAgent(agent dbus.ObjectPath) error {
+	return a.client.Call("UnregisterAgent", 0, agent).Store()
+}
+
+//RequestDefaultAgent make the agent the default one for system requests
+func (a *AgentManager1) RequestDefaultAgent(agent dbus.ObjectPath) error {
+	return a.client.Call("RequestDefaultAgent", 0, agent).Store()
+}
@@ -0,0 +1,70 @@
+// Code generated by bluez/gen from the introspection of org.bluez.Battery1. DO NOT EDIT.
+
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// Battery1Interface the bluez interface for Battery1
+const Battery1Interface = "org.bluez.Battery1"
+
+// NewBattery1 create a new Battery1 client
+func NewBattery1(path string) *Battery1 {
+	a := new(Battery1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: Battery1Interface,
+			Path:  path,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(Battery1Properties)
+	return a
+}
+
+// Battery1 client
+type Battery1 struct {
+	client     *bluez.Client
+	Properties *Battery1Properties
+}
+
+// Battery1Properties exposed properties of a Battery1
+type Battery1Properties struct {
+	Percentage byte
+	Source     string
+}
+
+// SetConnection use conn for the calls, eg. to register objects exported on
+// a private connection
+func (a *Battery1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+// Close the connection
+func (a *Battery1) Close() {
+	a.client.Disconnect()
+}
+
+// GetProperties load all available properties
+func (a *Battery1) GetProperties() (*Battery1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+// GetProperty get a property
+func (a *Battery1) GetProperty(name string) (dbus.Variant, error) {
+	return a.client.GetProperty(name)
+}
+
+// Register for changes signalling
+func (a *Battery1) Register() (chan *dbus.Signal, error) {
+	return a.client.Register(a.client.Config.Path, bluez.PropertiesInterface)
+}
+
+// Unregister for changes signalling
+func (a *Battery1) Unregister(signal chan *dbus.Signal) error {
+	return a.client.Unregister(a.client.Config.Path, bluez.PropertiesInterface, signal)
+}
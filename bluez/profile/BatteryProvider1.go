@@ -0,0 +1,25 @@
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/util"
+)
+
+//BatteryProvider1Interface the interface of the batteries exported by a
+// battery provider
+const BatteryProvider1Interface = "org.bluez.BatteryProvider1"
+
+//BatteryProvider1Properties exposed properties for BatteryProvider1,
+// bluetoothd reports them as the Battery1 of Device
+type BatteryProvider1Properties struct {
+	// Percentage the battery level, from 0 to 100
+	Percentage byte `dbus:"emit"`
+	// Source describe where the level comes from, eg. "HFP"
+	Source string
+	Device dbus.ObjectPath
+}
+
+//ToMap serialize properties
+func (d *BatteryProvider1Properties) ToMap() (map[string]interface{}, error) {
+	return util.StructToMap(d), nil
+}
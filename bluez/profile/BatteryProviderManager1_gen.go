@@ -0,0 +1,78 @@
+// Code generated by bluez/gen from the introspection of org.bluez.BatteryProviderManager1. DO NOT EDIT.
+
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// BatteryProviderManager1Interface the bluez interface for BatteryProviderManager1
+const BatteryProviderManager1Interface = "org.bluez.BatteryProviderManager1"
+
+// NewBatteryProviderManager1 create a new BatteryProviderManager1 client
+func NewBatteryProviderManager1(path string) *BatteryProviderManager1 {
+	a := new(BatteryProviderManager1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: BatteryProviderManager1Interface,
+			Path:  path,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(BatteryProviderManager1Properties)
+	return a
+}
+
+// BatteryProviderManager1 client
+type BatteryProviderManager1 struct {
+	client     *bluez.Client
+	Properties *BatteryProviderManager1Properties
+}
+
+// BatteryProviderManager1Properties exposed properties of a BatteryProviderManager1
+type BatteryProviderManager1Properties struct {
+}
+
+// SetConnection use conn for the calls, eg. to register objects exported on
+// a private connection
+func (a *BatteryProviderManager1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+// Close the connection
+func (a *BatteryProviderManager1) Close() {
+	a.client.Disconnect()
+}
+
+// GetProperties load all available properties
+func (a *BatteryProviderManager1) GetProperties() (*BatteryProviderManager1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+// GetProperty get a property
+func (a *BatteryProviderManager1) GetProperty(name string) (dbus.Variant, error) {
+	return a.client.GetProperty(name)
+}
+
+// Register for changes signalling
+func (a *BatteryProviderManager1) Register() (chan *dbus.Signal, error) {
+	return a.client.Register(a.client.Config.Path, bluez.PropertiesInterface)
+}
+
+// Unregister for changes signalling
+func (a *BatteryProviderManager1) Unregister(signal chan *dbus.Signal) error {
+	return a.client.Unregister(a.client.Config.Path, bluez.PropertiesInterface, signal)
+}
+
+// RegisterBatteryProvider call org.bluez.BatteryProviderManager1.RegisterBatteryProvider
+func (a *BatteryProviderManager1) RegisterBatteryProvider(provider dbus.ObjectPath) error {
+	return a.client.Call("RegisterBatteryProvider", 0, provider).Store()
+}
+
+// UnregisterBatteryProvider call org.bluez.BatteryProviderManager1.UnregisterBatteryProvider
+func (a *BatteryProviderManager1) UnregisterBatteryProvider(provider dbus.ObjectPath) error {
+	return a.client.Call("UnregisterBatteryProvider", 0, provider).Store()
+}
@@ -1,10 +1,24 @@
 package profile
 
 import (
+	"context"
+	"errors"
+
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/bluez"
 )
 
+//Bearer the transport preferred when reconnecting to a dual mode device
+type Bearer string
+
+// Values accepted by PreferredBearer
+const (
+	BearerLastUsed Bearer = "last-used"
+	BearerLastSeen Bearer = "last-seen"
+	BearerBREDR    Bearer = "bredr"
+	BearerLE       Bearer = "le"
+)
+
 // NewDevice1 create a new Device1 client
 func NewDevice1(path string) *Device1 {
 	a := new(Device1)
@@ -37,6 +51,7 @@ type Device1Properties struct {
 	Paired           bool
 	ServicesResolved bool
 	Trusted          bool
+	WakeAllowed      bool
 	ServiceData      map[string]dbus.Variant
 	ManufacturerData map[uint16]dbus.Variant
 	RSSI             int16
@@ -48,6 +63,7 @@ type Device1Properties struct {
 	Icon             string
 	Modalias         string
 	Name             string
+	PreferredBearer  string
 	Appearance       uint16
 	Class            uint32
 }
@@ -57,6 +73,11 @@ func (d *Device1) Close() {
 	d.client.Disconnect()
 }
 
+//SetConnection send the calls on conn instead of the system bus
+func (d *Device1) SetConnection(conn *dbus.Conn) {
+	d.client.SetConnection(conn)
+}
+
 //Register for changes signalling
 func (d *Device1) Register() (chan *dbus.Signal, error) {
 	return d.client.Register(d.client.Config.Path, bluez.PropertiesInterface)
@@ -78,6 +99,35 @@ func (d *Device1) GetProperty(name string) (dbus.Variant, error) {
 	return d.client.GetProperty(name)
 }
 
+//SetProperty set a property
+func (d *Device1) SetProperty(name string, value interface{}) error {
+	return d.client.SetProperty(name, value)
+}
+
+//SetWakeAllowed allow or deny the device to wake up the host from suspend
+func (d *Device1) SetWakeAllowed(allowed bool) error {
+	err := d.SetProperty("WakeAllowed", dbus.MakeVariant(allowed))
+	if err != nil {
+		return err
+	}
+	d.Properties.WakeAllowed = allowed
+	return nil
+}
+
+//SetPreferredBearer set the bearer used to reconnect to a dual mode device.
+// The property is available only on recent bluez versions (experimental)
+func (d *Device1) SetPreferredBearer(bearer Bearer) error {
+	if _, err := d.GetProperty("PreferredBearer"); err != nil {
+		return errors.New("PreferredBearer is not supported: " + err.Error())
+	}
+	err := d.SetProperty("PreferredBearer", dbus.MakeVariant(string(bearer)))
+	if err != nil {
+		return err
+	}
+	d.Properties.PreferredBearer = string(bearer)
+	return nil
+}
+
 //CancelParing stop the pairing process
 func (d *Device1) CancelParing() error {
 	return d.client.Call("CancelParing", 0).Store()
@@ -85,12 +135,34 @@ func (d *Device1) CancelParing() error {
 
 //Connect to the device
 func (d *Device1) Connect() error {
-	return d.client.Call("Connect", 0).Store()
+	return d.ConnectContext(context.Background())
+}
+
+//ConnectContext connect to the device, aborting the connection attempt when
+// the context is done
+func (d *Device1) ConnectContext(ctx context.Context) error {
+	ctx, span := d.startSpan(ctx, "device.connect")
+	err := d.client.CallWithContext(ctx, "Connect", 0).Store()
+	if err != nil && (ctx.Err() != nil || err == context.DeadlineExceeded) {
+		d.Disconnect()
+	}
+	span.End(err)
+	return err
 }
 
 //ConnectProfile connect to the specific profile
 func (d *Device1) ConnectProfile(uuid string) error {
-	return d.client.Call("ConnectProfile", 0, uuid).Store()
+	return d.ConnectProfileContext(context.Background(), uuid)
+}
+
+//ConnectProfileContext connect to the specific profile, aborting when the
+// context is done
+func (d *Device1) ConnectProfileContext(ctx context.Context, uuid string) error {
+	err := d.client.CallWithContext(ctx, "ConnectProfile", 0, uuid).Store()
+	if err != nil && (ctx.Err() != nil || err == context.DeadlineExceeded) {
+		d.DisconnectProfile(uuid)
+	}
+	return err
 }
 
 //Disconnect from the device
@@ -105,5 +177,24 @@ func (d *Device1) DisconnectProfile(uuid string) error {
 
 //Pair with the device
 func (d *Device1) Pair() error {
-	return d.client.Call("Pair", 0).Store()
+	return d.PairContext(context.Background())
+}
+
+//PairContext pair with the device, canceling the pairing when the context is
+// done
+func (d *Device1) PairContext(ctx context.Context) error {
+	ctx, span := d.startSpan(ctx, "device.pair")
+	err := d.client.CallWithContext(ctx, "Pair", 0).Store()
+	if err != nil && (ctx.Err() != nil || err == context.DeadlineExceeded) {
+		d.client.Call("CancelPairing", 0).Store()
+	}
+	span.End(err)
+	return err
+}
+
+// startSpan trace an operation on the device
+func (d *Device1) startSpan(ctx context.Context, name string) (context.Context, bluez.Span) {
+	return bluez.StartSpan(ctx, name,
+		bluez.AttrPath, d.client.Config.Path,
+		bluez.AttrAddress, d.Properties.Address)
 }
@@ -0,0 +1,47 @@
+package profile_test
+
+import (
+	"testing"
+
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+func TestDevice1Settings(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	d, err := b.AddDevice("hci0", "00:00:00:00:00:01", "keyboard")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev := profile.NewDevice1(string(d.Path))
+	if dev.Properties.WakeAllowed || dev.Properties.PreferredBearer != string(profile.BearerLastUsed) {
+		t.Fatalf("Unexpected properties %+v", dev.Properties)
+	}
+
+	if err = dev.SetWakeAllowed(true); err != nil {
+		t.Fatal(err)
+	}
+	if !dev.Properties.WakeAllowed || !d.Property("WakeAllowed").(bool) {
+		t.Fatal("Expected WakeAllowed set")
+	}
+
+	if err = dev.SetPreferredBearer(profile.BearerLE); err != nil {
+		t.Fatal(err)
+	}
+	if dev.Properties.PreferredBearer != "le" || d.Property("PreferredBearer").(string) != "le" {
+		t.Fatalf("Expected the LE bearer, got %s", d.Property("PreferredBearer"))
+	}
+
+	// the property cannot be read, as with the older bluez versions
+	b.RemoveDevice(d.Path)
+	if err = dev.SetPreferredBearer(profile.BearerBREDR); err == nil {
+		t.Fatal("Expected an error without the property")
+	}
+	if dev.Properties.PreferredBearer != "le" {
+		t.Fatalf("Unexpected bearer %s", dev.Properties.PreferredBearer)
+	}
+}
@@ -1,11 +1,13 @@
 package profile
 
 import (
+	"context"
 	"errors"
+	"sync"
 
-	"github.com/fatih/structs"
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
 )
 
 // NewGattCharacteristic1 create a new GattCharacteristic1 client
@@ -31,6 +33,33 @@ func NewGattCharacteristic1(path string) (*GattCharacteristic1, error) {
 	return g, nil
 }
 
+//GattObserver receive the values read, written and notified by the
+// characteristics of the clients, eg. to record a session, see SetGattObserver
+type GattObserver interface {
+	ObserveRead(char *GattCharacteristic1, value []byte, err error)
+	ObserveWrite(char *GattCharacteristic1, value []byte, options map[string]dbus.Variant, err error)
+	// ObserveNotification a value received by Notifications
+	ObserveNotification(char *GattCharacteristic1, value []byte)
+}
+
+var gattObserver = struct {
+	sync.RWMutex
+	o GattObserver
+}{}
+
+//SetGattObserver set the observer of the characteristics, nil removes it
+func SetGattObserver(o GattObserver) {
+	gattObserver.Lock()
+	defer gattObserver.Unlock()
+	gattObserver.o = o
+}
+
+func getGattObserver() GattObserver {
+	gattObserver.RLock()
+	defer gattObserver.RUnlock()
+	return gattObserver.o
+}
+
 // GattCharacteristic1 client
 type GattCharacteristic1 struct {
 	client     *bluez.Client
@@ -61,7 +90,7 @@ func (d *GattCharacteristic1Properties) ToMap() (map[string]interface{}, error)
 			return nil, errors.New("GattCharacteristic1Properties: Descriptors contains an ObjectPath that is not valid")
 		}
 	}
-	return structs.Map(d), nil
+	return util.StructToMap(d), nil
 }
 
 // Close the connection
@@ -83,10 +112,12 @@ func (d *GattCharacteristic1) Register() (chan *dbus.Signal, error) {
 
 //Unregister for changes signalling
 func (d *GattCharacteristic1) Unregister(signal chan *dbus.Signal) error {
+	err := d.client.Unregister(d.client.Config.Path, bluez.PropertiesInterface, signal)
 	if d.channel != nil {
 		close(d.channel)
+		d.channel = nil
 	}
-	return d.client.Unregister(d.client.Config.Path, bluez.PropertiesInterface, signal)
+	return err
 }
 
 //GetProperties load all available properties
@@ -106,25 +137,125 @@ func (d *GattCharacteristic1) GetProperty(name string) (interface{}, error) {
 
 //ReadValue read a value from a characteristic
 func (d *GattCharacteristic1) ReadValue(options map[string]dbus.Variant) ([]byte, error) {
-	var b []byte
-	err := d.client.Call("ReadValue", 0, options).Store(&b)
-	return b, err
+	return d.ReadValueContext(context.Background(), options)
 }
 
 //WriteValue write a value to a characteristic
 func (d *GattCharacteristic1) WriteValue(b []byte, options map[string]dbus.Variant) error {
-	err := d.client.Call("WriteValue", 0, b, options).Store()
+	return d.WriteValueContext(context.Background(), b, options)
+}
+
+//ReadValueContext read a value from a characteristic, returning when the context is done
+func (d *GattCharacteristic1) ReadValueContext(ctx context.Context, options map[string]dbus.Variant) ([]byte, error) {
+	ctx, span := d.startSpan(ctx, "gatt.read")
+	var b []byte
+	err := d.queue(ctx, func() error {
+		return d.client.CallWithContext(ctx, "ReadValue", 0, options).Store(&b)
+	})
+	span.End(err)
+	if o := getGattObserver(); o != nil {
+		o.ObserveRead(d, b, err)
+	}
+	return b, err
+}
+
+//WriteValueContext write a value to a characteristic, returning when the context is done
+func (d *GattCharacteristic1) WriteValueContext(ctx context.Context, b []byte, options map[string]dbus.Variant) error {
+	ctx, span := d.startSpan(ctx, "gatt.write")
+	err := d.queue(ctx, func() error {
+		return d.client.CallWithContext(ctx, "WriteValue", 0, b, options).Store()
+	})
+	span.End(err)
+	if o := getGattObserver(); o != nil {
+		o.ObserveWrite(d, b, options, err)
+	}
 	return err
 }
 
+// startSpan trace an operation on the characteristic
+func (d *GattCharacteristic1) startSpan(ctx context.Context, name string) (context.Context, bluez.Span) {
+	return bluez.StartSpan(ctx, name,
+		bluez.AttrPath, d.Path,
+		bluez.AttrUUID, d.Properties.UUID)
+}
+
+// queue run an operation after the pending ones on the same device
+func (d *GattCharacteristic1) queue(ctx context.Context, fn func() error) error {
+	return bluez.GetOperationQueue().Do(ctx, bluez.DevicePath(d.Path), fn)
+}
+
 //StartNotify start notifications
 func (d *GattCharacteristic1) StartNotify() error {
-	return d.client.Call("StartNotify", 0).Store()
+	return d.queue(context.Background(), func() error {
+		return d.client.Call("StartNotify", 0).Store()
+	})
 }
 
 //StopNotify stop notifications
 func (d *GattCharacteristic1) StopNotify() error {
-	return d.client.Call("StopNotify", 0).Store()
+	return d.queue(context.Background(), func() error {
+		return d.client.Call("StopNotify", 0).Store()
+	})
+}
+
+//Notifications start the notifications and report the values received, until
+// the returned function is called
+func (d *GattCharacteristic1) Notifications() (<-chan []byte, func(), error) {
+
+	signals, err := d.Register()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = d.StartNotify(); err != nil {
+		d.Unregister(signals)
+		return nil, nil, err
+	}
+
+	values := make(chan []byte, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(values)
+		for {
+			var sig *dbus.Signal
+			select {
+			case <-done:
+				return
+			case sig = <-signals:
+			}
+			if sig == nil {
+				return
+			}
+			if sig.Name != bluez.PropertiesChanged || len(sig.Body) < 2 {
+				continue
+			}
+			if iface, _ := sig.Body[0].(string); iface != bluez.GattCharacteristic1Interface {
+				continue
+			}
+			changed, _ := sig.Body[1].(map[string]dbus.Variant)
+			value, ok := changed["Value"].Value().([]byte)
+			if !ok {
+				continue
+			}
+			if o := getGattObserver(); o != nil {
+				o.ObserveNotification(d, value)
+			}
+			select {
+			case values <- value:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			d.StopNotify()
+			d.Unregister(signals)
+		})
+	}
+	return values, stop, nil
 }
 
 //AcquireWrite acquire file descriptor and MTU for writing [experimental]
@@ -1,11 +1,12 @@
 package profile
 
 import (
+	"context"
 	"errors"
 
-	"github.com/fatih/structs"
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
 )
 
 // NewGattDescriptor1 create a new GattDescriptor1 client
@@ -43,7 +44,7 @@ func (d *GattDescriptor1Properties) ToMap() (map[string]interface{}, error) {
 	if !d.Characteristic.IsValid() {
 		return nil, errors.New("GattDescriptor1Properties: Characteristic ObjectPath is not valid")
 	}
-	return structs.Map(d), nil
+	return util.StructToMap(d), nil
 }
 
 // Close the connection
@@ -69,13 +70,31 @@ func (d *GattDescriptor1) GetProperties() (*GattDescriptor1Properties, error) {
 
 //ReadValue read a value from a descriptor
 func (d *GattDescriptor1) ReadValue(options map[string]dbus.Variant) ([]byte, error) {
-	var b []byte
-	err := d.client.Call("ReadValue", 0, options).Store(&b)
-	return b, err
+	return d.ReadValueContext(context.Background(), options)
 }
 
 //WriteValue write a value to a characteristic
 func (d *GattDescriptor1) WriteValue(b []byte, options map[string]dbus.Variant) error {
-	err := d.client.Call("WriteValue", 0, b, options).Store()
-	return err
+	return d.WriteValueContext(context.Background(), b, options)
+}
+
+//ReadValueContext read a value from a descriptor, returning when the context is done
+func (d *GattDescriptor1) ReadValueContext(ctx context.Context, options map[string]dbus.Variant) ([]byte, error) {
+	var b []byte
+	err := d.queue(ctx, func() error {
+		return d.client.CallWithContext(ctx, "ReadValue", 0, options).Store(&b)
+	})
+	return b, err
+}
+
+//WriteValueContext write a value to a descriptor, returning when the context is done
+func (d *GattDescriptor1) WriteValueContext(ctx context.Context, b []byte, options map[string]dbus.Variant) error {
+	return d.queue(ctx, func() error {
+		return d.client.CallWithContext(ctx, "WriteValue", 0, b, options).Store()
+	})
+}
+
+// queue run an operation after the pending ones on the same device
+func (d *GattDescriptor1) queue(ctx context.Context, fn func() error) error {
+	return bluez.GetOperationQueue().Do(ctx, bluez.DevicePath(d.client.Config.Path), fn)
 }
@@ -1,7 +1,8 @@
 package profile
 
 import (
-	log "github.com/Sirupsen/logrus"
+	"context"
+
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/bluez"
 )
@@ -43,12 +44,29 @@ func (a *GattManager1) Close() {
 	a.client.Disconnect()
 }
 
+//SetConnection send the calls on conn, the connection the application is
+// exported on
+func (a *GattManager1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
 //RegisterApplication add a new bluetooth Application
 func (a *GattManager1) RegisterApplication(app dbus.ObjectPath, options map[string]interface{}) error {
-	log.Debugf("Registering app %s", app)
+	bluez.GetLogger().Debugf("Registering app %s", app)
 	return a.client.Call("RegisterApplication", 0, app, options).Store()
 }
 
+//RegisterApplicationContext add a new bluetooth Application, giving up when
+// the context is done
+func (a *GattManager1) RegisterApplicationContext(ctx context.Context, app dbus.ObjectPath, options map[string]interface{}) error {
+	bluez.GetLogger().Debugf("Registering app %s", app)
+	err := a.client.CallWithContext(ctx, "RegisterApplication", 0, app, options).Store()
+	if err != nil && ctx.Err() != nil {
+		a.UnregisterApplication(app)
+	}
+	return err
+}
+
 //UnregisterApplication remove a bluetooth Application
 func (a *GattManager1) UnregisterApplication(app dbus.ObjectPath) error {
 	return a.client.Call("UnregisterApplication", 0, app).Store()
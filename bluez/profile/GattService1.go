@@ -1,9 +1,9 @@
 package profile
 
 import (
-	"github.com/fatih/structs"
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
 )
 
 // NewGattService1 create a new GattService1 client
@@ -39,7 +39,7 @@ type GattService1Properties struct {
 //ToMap serialize a properties struct to a map
 func (p *GattService1Properties) ToMap() (map[string]interface{}, error) {
 
-	m := structs.Map(p)
+	m := util.StructToMap(p)
 
 	if !p.Device.IsValid() {
 		delete(m, "Device")
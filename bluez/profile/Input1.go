@@ -0,0 +1,58 @@
+package profile
+
+import (
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//HumanInterfaceDeviceUUID the HID service class UUID
+const HumanInterfaceDeviceUUID = "00001124-0000-1000-8000-00805f9b34fb"
+
+// Values reported by ReconnectMode
+const (
+	// InputReconnectNone the device and the host do not reconnect
+	InputReconnectNone = "none"
+	// InputReconnectHost the host reconnects to the device
+	InputReconnectHost = "host"
+	// InputReconnectDevice the device reconnects to the host
+	InputReconnectDevice = "device"
+	// InputReconnectAny both sides may reconnect
+	InputReconnectAny = "any"
+)
+
+// NewInput1 create a new Input1 client for a device
+func NewInput1(devicePath string) *Input1 {
+	a := new(Input1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: bluez.Input1Interface,
+			Path:  devicePath,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(Input1Properties)
+	a.GetProperties()
+	return a
+}
+
+// Input1 client
+type Input1 struct {
+	client     *bluez.Client
+	Properties *Input1Properties
+}
+
+//Input1Properties exposed properties of an Input1
+type Input1Properties struct {
+	ReconnectMode string
+}
+
+// Close the connection
+func (a *Input1) Close() {
+	a.client.Disconnect()
+}
+
+//GetProperties load all available properties
+func (a *Input1) GetProperties() (*Input1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
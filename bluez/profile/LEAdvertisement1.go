@@ -1,7 +1,7 @@
 package profile
 
 import (
-	"github.com/fatih/structs"
+	"github.com/muka/go-bluetooth/util"
 )
 
 // LEAdvertisement1Properties exposed properties for LEAdvertisement1
@@ -13,12 +13,22 @@ type LEAdvertisement1Properties struct {
 	//ServiceData      map[string]interface{}
 	//Includes         []string
 	LocalName        string
-	//Appearance       uint16
 	//Duration         uint16
 	//Timeout          uint16
+
+	// Discoverable advertise as general discoverable, overriding the
+	// Discoverable property of the adapter. It is not exported when false,
+	// to leave the flags to the adapter
+	Discoverable bool `dbus:"omitempty"`
+	// ServiceData the data of the services by UUID, updated while
+	// advertising
+	ServiceData map[string]interface{} `dbus:"emit,omitempty"`
+	// Appearance the GAP appearance, eg. 0x0340 for a generic sensor, zero
+	// is not advertised
+	Appearance uint16 `dbus:"omitempty"`
 }
 
 //ToMap serialize properties
 func (d *LEAdvertisement1Properties) ToMap() (map[string]interface{}, error) {
-	return structs.Map(d), nil
+	return util.StructToMap(d), nil
 }
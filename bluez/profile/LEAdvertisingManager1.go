@@ -1,6 +1,8 @@
 package profile
 
 import (
+	"context"
+
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/bluez"
 )
@@ -36,11 +38,27 @@ func (a *LEAdvertisingManager1) Close() {
 	a.client.Disconnect()
 }
 
+//SetConnection send the calls on conn, the connection the registered objects
+// are exported on
+func (a *LEAdvertisingManager1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
 //RegisterAdvertisement add a new advertisement service
 func (a *LEAdvertisingManager1) RegisterAdvertisement(advertisement string, options map[string]interface{}) error {
 	return a.client.Call("RegisterAdvertisement", 0, dbus.ObjectPath(advertisement), options).Store()
 }
 
+//RegisterAdvertisementContext add a new advertisement service, giving up when
+// the context is done
+func (a *LEAdvertisingManager1) RegisterAdvertisementContext(ctx context.Context, advertisement string, options map[string]interface{}) error {
+	err := a.client.CallWithContext(ctx, "RegisterAdvertisement", 0, dbus.ObjectPath(advertisement), options).Store()
+	if err != nil && ctx.Err() != nil {
+		a.UnregisterAdvertisement(advertisement)
+	}
+	return err
+}
+
 //UnregisterAdvertisement drop an advertisement service
 func (a *LEAdvertisingManager1) UnregisterAdvertisement(advertisement string) error {
 	return a.client.Call("UnregisterAdvertisement", 0, dbus.ObjectPath(advertisement)).Store()
@@ -0,0 +1,251 @@
+package profile
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//Objects the objects of a service by path, interface and property name
+type Objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+//NewManagedObjects load the objects of a service once and keep them current
+// with the InterfacesAdded, InterfacesRemoved and PropertiesChanged signals
+func NewManagedObjects(name string, path string) (*ManagedObjects, error) {
+
+	m := &ManagedObjects{
+		om:      NewObjectManager(name, path),
+		objects: make(Objects),
+	}
+
+	conn, err := bluez.GetConnection(bluez.SystemBus)
+	if err != nil {
+		return nil, err
+	}
+	m.dispatcher = bluez.GetSignalDispatcher(conn)
+
+	// subscribe first, not to miss the changes happening while loading
+	m.changes, err = m.om.Register()
+	if err != nil {
+		return nil, err
+	}
+	m.properties, err = m.dispatcher.Subscribe(bluez.SignalFilter{
+		Path:          dbus.ObjectPath(path),
+		PathNamespace: true,
+		Interface:     bluez.PropertiesInterface,
+		Member:        "PropertiesChanged",
+	})
+	if err != nil {
+		m.om.Unregister(m.changes)
+		return nil, err
+	}
+
+	go m.watch(m.changes)
+	go m.watch(m.properties)
+
+	err = m.Refresh()
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+
+	return m, nil
+}
+
+//ManagedObjects a cache of GetManagedObjects
+type ManagedObjects struct {
+	om         *ObjectManager
+	dispatcher *bluez.SignalDispatcher
+	changes    chan *dbus.Signal
+	properties chan *dbus.Signal
+
+	mutex   sync.RWMutex
+	objects Objects
+	closed  bool
+}
+
+//Refresh reload all the objects
+func (m *ManagedObjects) Refresh() error {
+	objects, err := m.om.GetManagedObjects()
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	m.objects = objects
+	m.mutex.Unlock()
+	return nil
+}
+
+//Close stop tracking the changes
+func (m *ManagedObjects) Close() {
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+		return
+	}
+	m.closed = true
+	m.mutex.Unlock()
+
+	m.om.Unregister(m.changes)
+	if m.properties != nil {
+		m.dispatcher.Unsubscribe(m.properties)
+	}
+	close(m.changes)
+	if m.properties != nil {
+		close(m.properties)
+	}
+}
+
+func (m *ManagedObjects) watch(signals chan *dbus.Signal) {
+	for sig := range signals {
+		m.Update(sig)
+	}
+}
+
+//Update apply a signal to the cache. Signals are tracked by the cache, call
+// it to have the cache current before handling a signal received on another
+// subscription. Applying the same signal twice has no effect
+func (m *ManagedObjects) Update(sig *dbus.Signal) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	switch sig.Name {
+	case bluez.InterfacesAdded:
+		if len(sig.Body) < 2 {
+			return
+		}
+		path, _ := sig.Body[0].(dbus.ObjectPath)
+		ifaces, _ := sig.Body[1].(map[string]map[string]dbus.Variant)
+		if _, ok := m.objects[path]; !ok {
+			m.objects[path] = make(map[string]map[string]dbus.Variant)
+		}
+		for iface, props := range ifaces {
+			m.objects[path][iface] = props
+		}
+
+	case bluez.InterfacesRemoved:
+		if len(sig.Body) < 2 {
+			return
+		}
+		path, _ := sig.Body[0].(dbus.ObjectPath)
+		ifaces, _ := sig.Body[1].([]string)
+		obj, ok := m.objects[path]
+		if !ok {
+			return
+		}
+		for _, iface := range ifaces {
+			delete(obj, iface)
+		}
+		if len(obj) == 0 {
+			delete(m.objects, path)
+		}
+
+	case bluez.PropertiesChanged:
+		if len(sig.Body) < 3 {
+			return
+		}
+		iface, _ := sig.Body[0].(string)
+		changed, _ := sig.Body[1].(map[string]dbus.Variant)
+		invalidated, _ := sig.Body[2].([]string)
+		// objects of other services, eg. exported by this process, are skipped
+		props, ok := m.objects[sig.Path][iface]
+		if !ok {
+			return
+		}
+		// copy on write, maps returned by the getters are not modified
+		updated := make(map[string]dbus.Variant, len(props)+len(changed))
+		for name, value := range props {
+			updated[name] = value
+		}
+		for name, value := range changed {
+			updated[name] = value
+		}
+		for _, name := range invalidated {
+			delete(updated, name)
+		}
+		m.objects[sig.Path][iface] = updated
+	}
+}
+
+//Objects return a snapshot of the cached objects
+func (m *ManagedObjects) Objects() Objects {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	objects := make(Objects, len(m.objects))
+	for path, ifaces := range m.objects {
+		objects[path] = make(map[string]map[string]dbus.Variant, len(ifaces))
+		for iface, props := range ifaces {
+			objects[path][iface] = props
+		}
+	}
+	return objects
+}
+
+//Get return the properties of an object by interface, nil if not found
+func (m *ManagedObjects) Get(path dbus.ObjectPath, iface string) map[string]dbus.Variant {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.objects[path][iface]
+}
+
+//Property return a cached property
+func (m *ManagedObjects) Property(path dbus.ObjectPath, iface string, name string) (dbus.Variant, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	v, ok := m.objects[path][iface][name]
+	return v, ok
+}
+
+// find return the sorted paths of the objects of iface passing match
+func (m *ManagedObjects) find(iface string, match func(props map[string]dbus.Variant) bool) []dbus.ObjectPath {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	paths := make([]dbus.ObjectPath, 0)
+	for path, ifaces := range m.objects {
+		for name, props := range ifaces {
+			if (iface == "" || name == iface) && (match == nil || match(props)) {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
+	return paths
+}
+
+//ByInterface return the objects implementing iface, eg. bluez.Device1Interface
+func (m *ManagedObjects) ByInterface(iface string) []dbus.ObjectPath {
+	return m.find(iface, nil)
+}
+
+//ByUUID return the GATT services, characteristics and descriptors with the
+// given UUID, and the devices and adapters listing it
+func (m *ManagedObjects) ByUUID(uuid string) []dbus.ObjectPath {
+	return m.find("", func(props map[string]dbus.Variant) bool {
+		if v, ok := props["UUID"].Value().(string); ok && strings.EqualFold(v, uuid) {
+			return true
+		}
+		if list, ok := props["UUIDs"].Value().([]string); ok {
+			for _, v := range list {
+				if strings.EqualFold(v, uuid) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+//ByAddress return the devices with the given address, one per adapter that
+// discovered it
+func (m *ManagedObjects) ByAddress(address string) []dbus.ObjectPath {
+	return m.find(bluez.Device1Interface, func(props map[string]dbus.Variant) bool {
+		v, ok := props["Address"].Value().(string)
+		return ok && strings.EqualFold(v, address)
+	})
+}
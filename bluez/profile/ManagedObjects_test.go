@@ -0,0 +1,62 @@
+package profile_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+func TestManagedObjects(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	d1, err := b.AddDevice("hci0", "00:00:00:00:00:01", "one")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := profile.NewManagedObjects(bluez.OrgBluez, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	if len(cache.ByInterface(bluez.Adapter1Interface)) != 1 {
+		t.Fatal("Expected one adapter")
+	}
+	if list := cache.ByAddress("00:00:00:00:00:01"); len(list) != 1 || list[0] != d1.Path {
+		t.Fatalf("Unexpected devices %v", list)
+	}
+
+	wait := func(fn func() bool) {
+		for i := 0; i < 50; i++ {
+			if fn() {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatal("Timeout waiting for the cache update")
+	}
+
+	d1.SetProperty("UUIDs", []string{"0000180f-0000-1000-8000-00805f9b34fb"})
+	wait(func() bool {
+		return len(cache.ByUUID("0000180F-0000-1000-8000-00805F9B34FB")) == 1
+	})
+
+	d2, err := b.AddDevice("hci0", "00:00:00:00:00:02", "two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(func() bool {
+		return len(cache.ByInterface(bluez.Device1Interface)) == 2
+	})
+
+	b.RemoveDevice(d2.Path)
+	wait(func() bool {
+		return len(cache.ByAddress("00:00:00:00:00:02")) == 0
+	})
+}
@@ -0,0 +1,58 @@
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// NewMedia1 create a new Media1 client
+func NewMedia1(hostID string) *Media1 {
+	a := new(Media1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: bluez.Media1Interface,
+			Path:  "/org/bluez/" + hostID,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	return a
+}
+
+// Media1 client
+type Media1 struct {
+	client *bluez.Client
+}
+
+// Close the connection
+func (a *Media1) Close() {
+	a.client.Disconnect()
+}
+
+//SetConnection send the calls on conn, the connection the registered objects
+// are exported on
+func (a *Media1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+//RegisterEndpoint register a local MediaEndpoint1 to the sender, properties
+// must contain UUID, Codec and Capabilities
+func (a *Media1) RegisterEndpoint(endpoint dbus.ObjectPath, properties map[string]interface{}) error {
+	return a.client.Call("RegisterEndpoint", 0, endpoint, properties).Store()
+}
+
+//UnregisterEndpoint unregister a previously registered endpoint
+func (a *Media1) UnregisterEndpoint(endpoint dbus.ObjectPath) error {
+	return a.client.Call("UnregisterEndpoint", 0, endpoint).Store()
+}
+
+//RegisterPlayer register a local org.mpris.MediaPlayer2.Player, properties
+// holds its initial state
+func (a *Media1) RegisterPlayer(player dbus.ObjectPath, properties map[string]interface{}) error {
+	return a.client.Call("RegisterPlayer", 0, player, properties).Store()
+}
+
+//UnregisterPlayer unregister a previously registered player
+func (a *Media1) UnregisterPlayer(player dbus.ObjectPath) error {
+	return a.client.Call("UnregisterPlayer", 0, player).Store()
+}
@@ -0,0 +1,90 @@
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// NewMediaControl1 create a new MediaControl1 client for a device
+func NewMediaControl1(devicePath string) *MediaControl1 {
+	a := new(MediaControl1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: bluez.MediaControl1Interface,
+			Path:  devicePath,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(MediaControl1Properties)
+	a.GetProperties()
+	return a
+}
+
+// MediaControl1 client
+type MediaControl1 struct {
+	client     *bluez.Client
+	Properties *MediaControl1Properties
+}
+
+//MediaControl1Properties exposed properties of a MediaControl1
+type MediaControl1Properties struct {
+	Connected bool
+	Player    dbus.ObjectPath
+}
+
+// Close the connection
+func (a *MediaControl1) Close() {
+	a.client.Disconnect()
+}
+
+//GetProperties load all available properties
+func (a *MediaControl1) GetProperties() (*MediaControl1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+//Play resume playback
+func (a *MediaControl1) Play() error {
+	return a.client.Call("Play", 0).Store()
+}
+
+//Pause playback
+func (a *MediaControl1) Pause() error {
+	return a.client.Call("Pause", 0).Store()
+}
+
+//Stop playback
+func (a *MediaControl1) Stop() error {
+	return a.client.Call("Stop", 0).Store()
+}
+
+//Next move to the next track
+func (a *MediaControl1) Next() error {
+	return a.client.Call("Next", 0).Store()
+}
+
+//Previous move to the previous track
+func (a *MediaControl1) Previous() error {
+	return a.client.Call("Previous", 0).Store()
+}
+
+//VolumeUp raise the remote volume
+func (a *MediaControl1) VolumeUp() error {
+	return a.client.Call("VolumeUp", 0).Store()
+}
+
+//VolumeDown lower the remote volume
+func (a *MediaControl1) VolumeDown() error {
+	return a.client.Call("VolumeDown", 0).Store()
+}
+
+//FastForward start fast forwarding the current track
+func (a *MediaControl1) FastForward() error {
+	return a.client.Call("FastForward", 0).Store()
+}
+
+//Rewind start rewinding the current track
+func (a *MediaControl1) Rewind() error {
+	return a.client.Call("Rewind", 0).Store()
+}
@@ -0,0 +1,92 @@
+package profile_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+// fakeMedia record the AVRCP commands, as bluez forwards them to the device
+type fakeMedia struct {
+	mutex sync.Mutex
+	calls []string
+}
+
+func (m *fakeMedia) call(name string) *dbus.Error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.calls = append(m.calls, name)
+	return nil
+}
+
+// received return the commands received
+func (m *fakeMedia) received() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]string{}, m.calls...)
+}
+
+func (m *fakeMedia) Play() *dbus.Error        { return m.call("Play") }
+func (m *fakeMedia) Pause() *dbus.Error       { return m.call("Pause") }
+func (m *fakeMedia) Stop() *dbus.Error        { return m.call("Stop") }
+func (m *fakeMedia) Next() *dbus.Error        { return m.call("Next") }
+func (m *fakeMedia) Previous() *dbus.Error    { return m.call("Previous") }
+func (m *fakeMedia) VolumeUp() *dbus.Error    { return m.call("VolumeUp") }
+func (m *fakeMedia) VolumeDown() *dbus.Error  { return m.call("VolumeDown") }
+func (m *fakeMedia) FastForward() *dbus.Error { return m.call("FastForward") }
+func (m *fakeMedia) Rewind() *dbus.Error      { return m.call("Rewind") }
+
+// exportMedia export an AVRCP object on the fake bluez
+func exportMedia(t *testing.T, b *bluetest.Bluez, path dbus.ObjectPath, iface string, props map[string]*prop.Prop) (*fakeMedia, *prop.Properties) {
+	m := &fakeMedia{}
+	if err := b.Conn().Export(m, path, iface); err != nil {
+		t.Fatal(err)
+	}
+	return m, prop.New(b.Conn(), path, map[string]map[string]*prop.Prop{iface: props})
+}
+
+func TestMediaControl1(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	path := dbus.ObjectPath("/org/bluez/hci0/dev_00_00_00_00_00_01")
+	player := path + "/player0"
+	m, _ := exportMedia(t, b, path, bluez.MediaControl1Interface, map[string]*prop.Prop{
+		"Connected": {Value: true, Emit: prop.EmitTrue},
+		"Player":    {Value: player, Emit: prop.EmitTrue},
+	})
+
+	control := profile.NewMediaControl1(string(path))
+	if !control.Properties.Connected || control.Properties.Player != player {
+		t.Fatalf("Unexpected properties %+v", control.Properties)
+	}
+
+	commands := []struct {
+		name string
+		fn   func() error
+	}{
+		{"Play", control.Play},
+		{"Pause", control.Pause},
+		{"Stop", control.Stop},
+		{"Next", control.Next},
+		{"Previous", control.Previous},
+		{"VolumeUp", control.VolumeUp},
+		{"VolumeDown", control.VolumeDown},
+		{"FastForward", control.FastForward},
+		{"Rewind", control.Rewind},
+	}
+	for i, cmd := range commands {
+		if err := cmd.fn(); err != nil {
+			t.Fatalf("%s: %s", cmd.name, err)
+		}
+		if calls := m.received(); len(calls) != i+1 || calls[i] != cmd.name {
+			t.Fatalf("%s: expected the command sent, got %v", cmd.name, calls)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
+)
+
+// Player status values
+const (
+	MediaPlayerStatusPlaying     = "playing"
+	MediaPlayerStatusStopped     = "stopped"
+	MediaPlayerStatusPaused      = "paused"
+	MediaPlayerStatusForwardSeek = "forward-seek"
+	MediaPlayerStatusReverseSeek = "reverse-seek"
+	MediaPlayerStatusError       = "error"
+)
+
+// NewMediaPlayer1 create a new MediaPlayer1 client, see MediaControl1 Player
+// for the path of the active player of a device
+func NewMediaPlayer1(path string) *MediaPlayer1 {
+	a := new(MediaPlayer1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: bluez.MediaPlayer1Interface,
+			Path:  path,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(MediaPlayer1Properties)
+	a.GetProperties()
+	return a
+}
+
+// MediaPlayer1 client
+type MediaPlayer1 struct {
+	client     *bluez.Client
+	Properties *MediaPlayer1Properties
+}
+
+//MediaPlayer1Properties exposed properties of a MediaPlayer1
+type MediaPlayer1Properties struct {
+	Equalizer  string
+	Repeat     string
+	Shuffle    string
+	Scan       string
+	Status     string
+	Position   uint32
+	Track      map[string]dbus.Variant
+	Device     dbus.ObjectPath
+	Name       string
+	Type       string
+	Subtype    string
+	Browsable  bool
+	Searchable bool
+	Playlist   dbus.ObjectPath
+}
+
+//MediaTrack the metadata of the current track
+type MediaTrack struct {
+	Title          string
+	Artist         string
+	Album          string
+	Genre          string
+	NumberOfTracks uint32
+	TrackNumber    uint32
+	// Duration in milliseconds
+	Duration uint32
+}
+
+//GetTrack return the metadata of the current track
+func (p *MediaPlayer1Properties) GetTrack() MediaTrack {
+	track := MediaTrack{}
+	for key, val := range p.Track {
+		switch key {
+		case "Title":
+			track.Title, _ = val.Value().(string)
+		case "Artist":
+			track.Artist, _ = val.Value().(string)
+		case "Album":
+			track.Album, _ = val.Value().(string)
+		case "Genre":
+			track.Genre, _ = val.Value().(string)
+		case "NumberOfTracks":
+			track.NumberOfTracks, _ = val.Value().(uint32)
+		case "TrackNumber":
+			track.TrackNumber, _ = val.Value().(uint32)
+		case "Duration":
+			track.Duration, _ = val.Value().(uint32)
+		}
+	}
+	return track
+}
+
+// Close the connection
+func (a *MediaPlayer1) Close() {
+	a.client.Disconnect()
+}
+
+//Path return the player object path
+func (a *MediaPlayer1) Path() string {
+	return a.client.Config.Path
+}
+
+//GetProperties load all available properties
+func (a *MediaPlayer1) GetProperties() (*MediaPlayer1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+//GetProperty get a property
+func (a *MediaPlayer1) GetProperty(name string) (dbus.Variant, error) {
+	return a.client.GetProperty(name)
+}
+
+//SetProperty set a property, eg. Repeat or Shuffle
+func (a *MediaPlayer1) SetProperty(name string, value interface{}) error {
+	return a.client.SetProperty(name, dbus.MakeVariant(value))
+}
+
+//Register for changes signalling
+func (a *MediaPlayer1) Register() (chan *dbus.Signal, error) {
+	return a.client.Register(a.client.Config.Path, bluez.PropertiesInterface)
+}
+
+//Unregister for changes signalling
+func (a *MediaPlayer1) Unregister(signal chan *dbus.Signal) error {
+	return a.client.Unregister(a.client.Config.Path, bluez.PropertiesInterface, signal)
+}
+
+//Watch report the player properties every time they change, eg. on track
+// or status change. The channel must be drained to keep receiving updates
+// and is closed by calling the returned stop function
+func (a *MediaPlayer1) Watch() (<-chan MediaPlayer1Properties, func(), error) {
+
+	signals, err := a.Register()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := make(chan MediaPlayer1Properties, 1)
+	stop := func() {
+		a.Unregister(signals)
+		close(signals)
+	}
+
+	go func() {
+		defer close(changes)
+		for sig := range signals {
+
+			if sig == nil {
+				return
+			}
+
+			if string(sig.Path) != a.Path() || sig.Name != bluez.PropertiesChanged {
+				continue
+			}
+			if iface, ok := sig.Body[0].(string); !ok || iface != bluez.MediaPlayer1Interface {
+				continue
+			}
+			props, ok := sig.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
+
+			for key, val := range props {
+				// skip properties unknown to this version
+				util.MapToStruct(a.Properties, map[string]dbus.Variant{key: val})
+			}
+			changes <- *a.Properties
+		}
+	}()
+
+	return changes, stop, nil
+}
+
+//Play resume playback
+func (a *MediaPlayer1) Play() error {
+	return a.client.Call("Play", 0).Store()
+}
+
+//Pause playback
+func (a *MediaPlayer1) Pause() error {
+	return a.client.Call("Pause", 0).Store()
+}
+
+//Stop playback
+func (a *MediaPlayer1) Stop() error {
+	return a.client.Call("Stop", 0).Store()
+}
+
+//Next move to the next track
+func (a *MediaPlayer1) Next() error {
+	return a.client.Call("Next", 0).Store()
+}
+
+//Previous move to the previous track
+func (a *MediaPlayer1) Previous() error {
+	return a.client.Call("Previous", 0).Store()
+}
+
+//FastForward start fast forwarding the current track
+func (a *MediaPlayer1) FastForward() error {
+	return a.client.Call("FastForward", 0).Store()
+}
+
+//Rewind start rewinding the current track
+func (a *MediaPlayer1) Rewind() error {
+	return a.client.Call("Rewind", 0).Store()
+}
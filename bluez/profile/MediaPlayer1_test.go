@@ -0,0 +1,77 @@
+package profile_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+func TestMediaPlayer1(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	path := dbus.ObjectPath("/org/bluez/hci0/dev_00_00_00_00_00_01/player0")
+	m, props := exportMedia(t, b, path, bluez.MediaPlayer1Interface, map[string]*prop.Prop{
+		"Status":   {Value: profile.MediaPlayerStatusPaused, Emit: prop.EmitTrue},
+		"Repeat":   {Value: "off", Writable: true, Emit: prop.EmitTrue},
+		"Position": {Value: uint32(1500), Emit: prop.EmitTrue},
+		"Name":     {Value: "Music", Emit: prop.EmitTrue},
+		"Track": {Value: map[string]dbus.Variant{
+			"Title":       dbus.MakeVariant("Song"),
+			"Artist":      dbus.MakeVariant("Band"),
+			"TrackNumber": dbus.MakeVariant(uint32(3)),
+			"Duration":    dbus.MakeVariant(uint32(180000)),
+			// unknown entries are ignored
+			"Item": dbus.MakeVariant(path + "/item1"),
+		}, Emit: prop.EmitTrue},
+	})
+
+	player := profile.NewMediaPlayer1(string(path))
+	if player.Path() != string(path) || player.Properties.Status != profile.MediaPlayerStatusPaused ||
+		player.Properties.Position != 1500 || player.Properties.Name != "Music" {
+		t.Fatalf("Unexpected properties %+v", player.Properties)
+	}
+	track := player.Properties.GetTrack()
+	if track != (profile.MediaTrack{Title: "Song", Artist: "Band", TrackNumber: 3, Duration: 180000}) {
+		t.Fatalf("Unexpected track %+v", track)
+	}
+
+	if err := player.SetProperty("Repeat", "alltracks"); err != nil {
+		t.Fatal(err)
+	}
+	if repeat, _ := props.Get(bluez.MediaPlayer1Interface, "Repeat"); repeat.Value() != "alltracks" {
+		t.Fatalf("Unexpected Repeat %v", repeat)
+	}
+
+	changes, stop, err := player.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = player.Play(); err != nil {
+		t.Fatal(err)
+	}
+	if calls := m.received(); len(calls) != 1 || calls[0] != "Play" {
+		t.Fatalf("Unexpected commands %v", calls)
+	}
+
+	// the remote player reports the new status
+	props.SetMust(bluez.MediaPlayer1Interface, "Status", profile.MediaPlayerStatusPlaying)
+	select {
+	case p := <-changes:
+		if p.Status != profile.MediaPlayerStatusPlaying || p.Name != "Music" {
+			t.Fatalf("Unexpected change %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the change")
+	}
+
+	stop()
+	for range changes {
+	}
+}
@@ -0,0 +1,108 @@
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// Transport states
+const (
+	// MediaTransportStateIdle not streaming
+	MediaTransportStateIdle = "idle"
+	// MediaTransportStatePending streaming but not acquired
+	MediaTransportStatePending = "pending"
+	// MediaTransportStateActive streaming and acquired
+	MediaTransportStateActive = "active"
+)
+
+// NewMediaTransport1 create a new MediaTransport1 client
+func NewMediaTransport1(path string) *MediaTransport1 {
+	a := new(MediaTransport1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: bluez.MediaTransport1Interface,
+			Path:  path,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(MediaTransport1Properties)
+	a.GetProperties()
+	return a
+}
+
+// MediaTransport1 client
+type MediaTransport1 struct {
+	client     *bluez.Client
+	Properties *MediaTransport1Properties
+}
+
+//MediaTransport1Properties exposed properties of a MediaTransport1
+type MediaTransport1Properties struct {
+	Device        dbus.ObjectPath
+	UUID          string
+	Codec         byte
+	Configuration []byte
+	State         string
+	Delay         uint16
+	Volume        uint16
+	Endpoint      dbus.ObjectPath
+}
+
+// Close the connection
+func (a *MediaTransport1) Close() {
+	a.client.Disconnect()
+}
+
+//Path return the transport object path
+func (a *MediaTransport1) Path() string {
+	return a.client.Config.Path
+}
+
+//GetProperties load all available properties
+func (a *MediaTransport1) GetProperties() (*MediaTransport1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+//GetProperty get a property
+func (a *MediaTransport1) GetProperty(name string) (dbus.Variant, error) {
+	return a.client.GetProperty(name)
+}
+
+//SetVolume set the transport volume, in the range 0-127
+func (a *MediaTransport1) SetVolume(volume uint16) error {
+	return a.client.SetProperty("Volume", dbus.MakeVariant(volume))
+}
+
+//Register for changes signalling
+func (a *MediaTransport1) Register() (chan *dbus.Signal, error) {
+	return a.client.Register(a.client.Config.Path, bluez.PropertiesInterface)
+}
+
+//Unregister for changes signalling
+func (a *MediaTransport1) Unregister(signal chan *dbus.Signal) error {
+	return a.client.Unregister(a.client.Config.Path, bluez.PropertiesInterface, signal)
+}
+
+//Acquire the transport file descriptor with the read and write MTU
+func (a *MediaTransport1) Acquire() (dbus.UnixFD, uint16, uint16, error) {
+	var fd dbus.UnixFD
+	var mtuRead, mtuWrite uint16
+	err := a.client.Call("Acquire", 0).Store(&fd, &mtuRead, &mtuWrite)
+	return fd, mtuRead, mtuWrite, err
+}
+
+//TryAcquire acquire the transport only if it is in the pending state,
+// failing with org.bluez.Error.NotAvailable otherwise
+func (a *MediaTransport1) TryAcquire() (dbus.UnixFD, uint16, uint16, error) {
+	var fd dbus.UnixFD
+	var mtuRead, mtuWrite uint16
+	err := a.client.Call("TryAcquire", 0).Store(&fd, &mtuRead, &mtuWrite)
+	return fd, mtuRead, mtuWrite, err
+}
+
+//Release the transport file descriptor
+func (a *MediaTransport1) Release() error {
+	return a.client.Call("Release", 0).Store()
+}
@@ -0,0 +1,81 @@
+package profile
+
+import (
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/util"
+)
+
+// MPRIS playback status values
+const (
+	MprisPlaybackPlaying = "Playing"
+	MprisPlaybackPaused  = "Paused"
+	MprisPlaybackStopped = "Stopped"
+)
+
+// MPRIS loop status values
+const (
+	MprisLoopNone     = "None"
+	MprisLoopTrack    = "Track"
+	MprisLoopPlaylist = "Playlist"
+)
+
+//MprisPlayerProperties exposed properties of a player registered with Media1.RegisterPlayer
+type MprisPlayerProperties struct {
+	PlaybackStatus string                  `dbus:"emit"`
+	LoopStatus     string                  `dbus:"emit"`
+	Rate           float64                 `dbus:"emit"`
+	Shuffle        bool                    `dbus:"emit"`
+	Metadata       map[string]dbus.Variant `dbus:"emit"`
+	Volume         float64                 `dbus:"emit"`
+	// Position in microseconds, changes are signalled with Seeked
+	Position      int64
+	MinimumRate   float64
+	MaximumRate   float64
+	CanGoNext     bool `dbus:"emit"`
+	CanGoPrevious bool `dbus:"emit"`
+	CanPlay       bool `dbus:"emit"`
+	CanPause      bool `dbus:"emit"`
+	CanSeek       bool `dbus:"emit"`
+	CanControl    bool
+}
+
+//ToMap serialize properties
+func (d *MprisPlayerProperties) ToMap() (map[string]interface{}, error) {
+	return util.StructToMap(d), nil
+}
+
+//MprisTrack the metadata of the track being played
+type MprisTrack struct {
+	Title       string
+	Artist      string
+	Album       string
+	Genre       string
+	TrackNumber int32
+	Length      time.Duration
+}
+
+//ToMetadata convert the track to the MPRIS Metadata property
+func (t MprisTrack) ToMetadata() map[string]dbus.Variant {
+	m := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath("/org/mpris/MediaPlayer2/Track/current")),
+		"xesam:title":   dbus.MakeVariant(t.Title),
+	}
+	if t.Artist != "" {
+		m["xesam:artist"] = dbus.MakeVariant([]string{t.Artist})
+	}
+	if t.Album != "" {
+		m["xesam:album"] = dbus.MakeVariant(t.Album)
+	}
+	if t.Genre != "" {
+		m["xesam:genre"] = dbus.MakeVariant([]string{t.Genre})
+	}
+	if t.TrackNumber > 0 {
+		m["xesam:trackNumber"] = dbus.MakeVariant(t.TrackNumber)
+	}
+	if t.Length > 0 {
+		m["mpris:length"] = dbus.MakeVariant(int64(t.Length / time.Microsecond))
+	}
+	return m
+}
@@ -0,0 +1,77 @@
+package profile
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// PAN roles accepted by Network1.Connect and NetworkServer1.Register
+const (
+	NetworkRolePANU = "panu"
+	NetworkRoleNAP  = "nap"
+	NetworkRoleGN   = "gn"
+)
+
+// NewNetwork1 create a new Network1 client for a device
+func NewNetwork1(devicePath string) *Network1 {
+	a := new(Network1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: bluez.Network1Interface,
+			Path:  devicePath,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(Network1Properties)
+	a.GetProperties()
+	return a
+}
+
+// Network1 client
+type Network1 struct {
+	client     *bluez.Client
+	Properties *Network1Properties
+}
+
+//Network1Properties exposed properties of a Network1
+type Network1Properties struct {
+	Connected bool
+	// Interface the network interface name, eg. bnep0
+	Interface string
+	UUID      string
+}
+
+// Close the connection
+func (a *Network1) Close() {
+	a.client.Disconnect()
+}
+
+//GetProperties load all available properties
+func (a *Network1) GetProperties() (*Network1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+//Register for changes signalling
+func (a *Network1) Register() (chan *dbus.Signal, error) {
+	return a.client.Register(a.client.Config.Path, bluez.PropertiesInterface)
+}
+
+//Unregister for changes signalling
+func (a *Network1) Unregister(signal chan *dbus.Signal) error {
+	return a.client.Unregister(a.client.Config.Path, bluez.PropertiesInterface, signal)
+}
+
+//Connect to the network service of the device, uuid is one of nap, gn or
+// panu (or the full UUID). Return the name of the network interface, eg. bnep0
+func (a *Network1) Connect(uuid string) (string, error) {
+	var iface string
+	err := a.client.Call("Connect", 0, uuid).Store(&iface)
+	return iface, err
+}
+
+//Disconnect the network connection
+func (a *Network1) Disconnect() error {
+	return a.client.Call("Disconnect", 0).Store()
+}
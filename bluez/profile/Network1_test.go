@@ -0,0 +1,155 @@
+package profile_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+const napUUID = "00001116-0000-1000-8000-00805f9b34fb"
+
+// fakeNetwork a PAN connection of a device, as exposed by bluez
+type fakeNetwork struct {
+	props *prop.Properties
+}
+
+func (n *fakeNetwork) Connect(uuid string) (string, *dbus.Error) {
+	if uuid != profile.NetworkRoleNAP {
+		return "", bluez.ErrNotSupported.WithMessage(uuid).DBusError()
+	}
+	n.props.SetMust(bluez.Network1Interface, "Connected", true)
+	n.props.SetMust(bluez.Network1Interface, "Interface", "bnep0")
+	n.props.SetMust(bluez.Network1Interface, "UUID", napUUID)
+	return "bnep0", nil
+}
+
+func (n *fakeNetwork) Disconnect() *dbus.Error {
+	n.props.SetMust(bluez.Network1Interface, "Connected", false)
+	return nil
+}
+
+// fakeNetworkServer record the servers registered on an adapter
+type fakeNetworkServer struct {
+	mutex   sync.Mutex
+	servers map[string]string
+}
+
+func (s *fakeNetworkServer) Register(uuid string, bridge string) *dbus.Error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.servers[uuid]; ok {
+		return bluez.ErrAlreadyExists.DBusError()
+	}
+	s.servers[uuid] = bridge
+	return nil
+}
+
+func (s *fakeNetworkServer) Unregister(uuid string) *dbus.Error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.servers[uuid]; !ok {
+		return bluez.ErrDoesNotExist.DBusError()
+	}
+	delete(s.servers, uuid)
+	return nil
+}
+
+func (s *fakeNetworkServer) bridges() map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	bridges := map[string]string{}
+	for uuid, bridge := range s.servers {
+		bridges[uuid] = bridge
+	}
+	return bridges
+}
+
+func TestNetwork1(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	path := dbus.ObjectPath("/org/bluez/hci0/dev_00_00_00_00_00_01")
+	n := &fakeNetwork{}
+	n.props = prop.New(b.Conn(), path, map[string]map[string]*prop.Prop{
+		bluez.Network1Interface: {
+			"Connected": {Value: false, Emit: prop.EmitTrue},
+			"Interface": {Value: "", Emit: prop.EmitTrue},
+			"UUID":      {Value: "", Emit: prop.EmitTrue},
+		},
+	})
+	if err := b.Conn().Export(n, path, bluez.Network1Interface); err != nil {
+		t.Fatal(err)
+	}
+
+	network := profile.NewNetwork1(string(path))
+	defer network.Close()
+	if network.Properties.Connected {
+		t.Fatalf("Unexpected properties %+v", network.Properties)
+	}
+
+	if _, err := network.Connect(profile.NetworkRoleGN); !bluez.IsError(err, bluez.ErrNotSupported) {
+		t.Fatalf("Expected NotSupported connecting an unsupported role, got %v", err)
+	}
+	iface, err := network.Connect(profile.NetworkRoleNAP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iface != "bnep0" {
+		t.Fatalf("Expected bnep0, got %s", iface)
+	}
+	props, err := network.GetProperties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !props.Connected || props.Interface != "bnep0" || props.UUID != napUUID {
+		t.Fatalf("Unexpected properties %+v", props)
+	}
+
+	if err = network.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if props, _ = network.GetProperties(); props.Connected {
+		t.Fatalf("Expected disconnected, got %+v", props)
+	}
+}
+
+func TestNetworkServer1(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	// the fake adapter does not implement NetworkServer1, export it next to it
+	s := &fakeNetworkServer{servers: map[string]string{}}
+	if err := b.Conn().Export(s, "/org/bluez/hci0", bluez.NetworkServer1Interface); err != nil {
+		t.Fatal(err)
+	}
+
+	server := profile.NewNetworkServer1("hci0")
+	defer server.Close()
+
+	if err := server.Register(profile.NetworkRoleNAP, "pan0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Register(profile.NetworkRoleNAP, "pan0"); !bluez.IsError(err, bluez.ErrAlreadyExists) {
+		t.Fatalf("Expected AlreadyExists registering the role twice, got %v", err)
+	}
+	if bridges := s.bridges(); bridges[profile.NetworkRoleNAP] != "pan0" {
+		t.Fatalf("Expected the nap server on pan0, got %v", bridges)
+	}
+
+	if err := server.Unregister(profile.NetworkRoleNAP); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Unregister(profile.NetworkRoleNAP); !bluez.IsError(err, bluez.ErrDoesNotExist) {
+		t.Fatalf("Expected DoesNotExist unregistering a missing server, got %v", err)
+	}
+	if bridges := s.bridges(); len(bridges) != 0 {
+		t.Fatalf("Expected no servers, got %v", bridges)
+	}
+}
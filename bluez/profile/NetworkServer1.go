@@ -0,0 +1,40 @@
+package profile
+
+import (
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// NewNetworkServer1 create a new NetworkServer1 client for an adapter
+func NewNetworkServer1(hostID string) *NetworkServer1 {
+	a := new(NetworkServer1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: bluez.NetworkServer1Interface,
+			Path:  "/org/bluez/" + hostID,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	return a
+}
+
+// NetworkServer1 client
+type NetworkServer1 struct {
+	client *bluez.Client
+}
+
+// Close the connection
+func (a *NetworkServer1) Close() {
+	a.client.Disconnect()
+}
+
+//Register a server for the role uuid, one of nap, gn or panu. Incoming
+// connections are added to bridge, which must already exist
+func (a *NetworkServer1) Register(uuid string, bridge string) error {
+	return a.client.Call("Register", 0, uuid, bridge).Store()
+}
+
+//Unregister the server for the role uuid
+func (a *NetworkServer1) Unregister(uuid string) error {
+	return a.client.Call("Unregister", 0, uuid).Store()
+}
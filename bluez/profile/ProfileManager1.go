@@ -3,15 +3,65 @@ package profile
 import (
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
 )
 
+//SerialPortProfileUUID the Serial Port Profile (SPP) service class UUID
+const SerialPortProfileUUID = "00001101-0000-1000-8000-00805f9b34fb"
+
+// Hands-Free and Headset profiles service class UUIDs
+const (
+	HeadsetProfileUUID               = "00001108-0000-1000-8000-00805f9b34fb"
+	HeadsetAudioGatewayProfileUUID   = "00001112-0000-1000-8000-00805f9b34fb"
+	HandsfreeProfileUUID             = "0000111e-0000-1000-8000-00805f9b34fb"
+	HandsfreeAudioGatewayProfileUUID = "0000111f-0000-1000-8000-00805f9b34fb"
+)
+
+// Roles for a registered profile
+const (
+	ProfileRoleClient = "client"
+	ProfileRoleServer = "server"
+)
+
+// ProfileManager1Options the options accepted by RegisterProfile.
+// Empty values are not sent, so bluez defaults apply
+type ProfileManager1Options struct {
+	// Name human readable name for the profile
+	Name string `structs:",omitempty"`
+	// Service the primary service class UUID, if different from the profile UUID
+	Service string `structs:",omitempty"`
+	// Role client or server, for profiles with a single role
+	Role string `structs:",omitempty"`
+	// Channel the RFCOMM channel
+	Channel uint16 `structs:",omitempty"`
+	// PSM the L2CAP PSM
+	PSM uint16 `structs:",omitempty"`
+	// RequireAuthentication pairing is required for incoming connections
+	RequireAuthentication bool `structs:",omitempty"`
+	// RequireAuthorization the agent is asked to authorize incoming connections
+	RequireAuthorization bool `structs:",omitempty"`
+	// AutoConnect connect the profile when the device connects
+	AutoConnect bool `structs:",omitempty"`
+	// ServiceRecord a full SDP record in XML format, see sdp.Record
+	ServiceRecord string `structs:",omitempty"`
+	// Version the profile version
+	Version uint16 `structs:",omitempty"`
+	// Features the profile features
+	Features uint16 `structs:",omitempty"`
+}
+
+//ToMap serialize the options as expected by RegisterProfile
+func (o *ProfileManager1Options) ToMap() map[string]interface{} {
+	return util.StructToMap(o)
+}
+
 // NewProfileManager1 create a new ProfileManager1 client
 func NewProfileManager1(hostID string) *ProfileManager1 {
 	a := new(ProfileManager1)
 	a.client = bluez.NewClient(
 		&bluez.Config{
 			Name:  "org.bluez",
-			Iface: "org.bluez.ProfileManager1",
+			Iface: bluez.ProfileManager1Interface,
 			Path:  "/org/bluez",
 			Bus:   bluez.SystemBus,
 		},
@@ -29,6 +79,12 @@ func (a *ProfileManager1) Close() {
 	a.client.Disconnect()
 }
 
+//SetConnection send the calls on conn, the connection the registered objects
+// are exported on
+func (a *ProfileManager1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
 //RegisterProfile add a new Profile for an UUID
 func (a *ProfileManager1) RegisterProfile(profile string, UUID string, options map[string]interface{}) error {
 	return a.client.Call("RegisterProfile", 0, dbus.ObjectPath(profile), UUID, options).Store()
@@ -0,0 +1,222 @@
+package a2dp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//SBCSyncword the first byte of every SBC frame
+const SBCSyncword byte = 0x9c
+
+const rtpHeaderSize = 12
+
+// the SBC payload header stores the frame count on 4 bits
+const maxFramesPerPacket = 15
+
+// dynamic RTP payload type used by bluez and most devices
+const rtpPayloadType = 96
+
+//MediaPacket an RTP packet received on an A2DP transport, carrying SBC frames
+type MediaPacket struct {
+	SequenceNumber uint16
+	Timestamp      uint32
+	SSRC           uint32
+	// Fragmented the frame is split over several packets
+	Fragmented bool
+	// Frames the SBC frames, ready to be decoded
+	Frames [][]byte
+}
+
+//SBCFrameHeader the header of an SBC frame
+type SBCFrameHeader struct {
+	SampleRate  int
+	Blocks      int
+	ChannelMode byte
+	Allocation  byte
+	Subbands    int
+	Bitpool     int
+}
+
+//Channels return the number of channels
+func (h SBCFrameHeader) Channels() int {
+	if h.ChannelMode == SBCChannelModeMono {
+		return 1
+	}
+	return 2
+}
+
+//Length return the size in bytes of the frame, including the header
+func (h SBCFrameHeader) Length() int {
+	channels := h.Channels()
+	length := 4 + (4*h.Subbands*channels)/8
+	switch h.ChannelMode {
+	case SBCChannelModeMono, SBCChannelModeDualChannel:
+		length += (h.Blocks*channels*h.Bitpool + 7) / 8
+	case SBCChannelModeStereo:
+		length += (h.Blocks*h.Bitpool + 7) / 8
+	case SBCChannelModeJointStereo:
+		length += (h.Subbands + h.Blocks*h.Bitpool + 7) / 8
+	}
+	return length
+}
+
+//Samples return the number of PCM samples per channel encoded in the frame
+func (h SBCFrameHeader) Samples() int {
+	return h.Blocks * h.Subbands
+}
+
+//ParseSBCFrameHeader decode the header at the start of an SBC frame
+func ParseSBCFrameHeader(b []byte) (SBCFrameHeader, error) {
+
+	h := SBCFrameHeader{}
+	if len(b) < 4 {
+		return h, errors.New("SBC frame too short")
+	}
+	if b[0] != SBCSyncword {
+		return h, fmt.Errorf("Invalid SBC syncword 0x%02x", b[0])
+	}
+
+	h.SampleRate = []int{16000, 32000, 44100, 48000}[b[1]>>6]
+	h.Blocks = []int{4, 8, 12, 16}[b[1]>>4&0x03]
+	h.ChannelMode = []byte{
+		SBCChannelModeMono,
+		SBCChannelModeDualChannel,
+		SBCChannelModeStereo,
+		SBCChannelModeJointStereo,
+	}[b[1]>>2&0x03]
+	h.Allocation = SBCAllocationLoudness
+	if b[1]&0x02 != 0 {
+		h.Allocation = SBCAllocationSNR
+	}
+	h.Subbands = 4
+	if b[1]&0x01 != 0 {
+		h.Subbands = 8
+	}
+	h.Bitpool = int(b[2])
+
+	return h, nil
+}
+
+//ParseMediaPacket split an A2DP media packet in its SBC frames
+func ParseMediaPacket(b []byte) (*MediaPacket, error) {
+
+	if len(b) < rtpHeaderSize+1 {
+		return nil, errors.New("Media packet too short")
+	}
+	if b[0]>>6 != 2 {
+		return nil, fmt.Errorf("Unsupported RTP version %d", b[0]>>6)
+	}
+
+	offset := rtpHeaderSize + int(b[0]&0x0f)*4
+	if b[0]&0x10 != 0 {
+		// header extension: 16 bit profile, 16 bit length in words
+		if len(b) < offset+4 {
+			return nil, errors.New("Media packet too short")
+		}
+		offset += 4 + int(binary.BigEndian.Uint16(b[offset+2:]))*4
+	}
+	payload := b[offset:]
+	if b[0]&0x20 != 0 && len(payload) > 0 {
+		// strip padding
+		pad := int(payload[len(payload)-1])
+		if pad > len(payload) {
+			return nil, errors.New("Invalid RTP padding")
+		}
+		payload = payload[:len(payload)-pad]
+	}
+	if len(payload) < 1 {
+		return nil, errors.New("Media packet too short")
+	}
+
+	p := &MediaPacket{
+		SequenceNumber: binary.BigEndian.Uint16(b[2:]),
+		Timestamp:      binary.BigEndian.Uint32(b[4:]),
+		SSRC:           binary.BigEndian.Uint32(b[8:]),
+		Fragmented:     payload[0]&0x80 != 0,
+	}
+
+	count := int(payload[0] & 0x0f)
+	payload = payload[1:]
+
+	if p.Fragmented {
+		p.Frames = [][]byte{payload}
+		return p, nil
+	}
+
+	for i := 0; i < count; i++ {
+		h, err := ParseSBCFrameHeader(payload)
+		if err != nil {
+			return nil, err
+		}
+		length := h.Length()
+		if length > len(payload) {
+			return nil, errors.New("Truncated SBC frame")
+		}
+		p.Frames = append(p.Frames, payload[:length])
+		payload = payload[length:]
+	}
+
+	return p, nil
+}
+
+//EncodeMediaPacket build an A2DP media packet carrying complete SBC frames
+func EncodeMediaPacket(sequenceNumber uint16, timestamp uint32, ssrc uint32, frames [][]byte) ([]byte, error) {
+
+	if len(frames) == 0 || len(frames) > maxFramesPerPacket {
+		return nil, fmt.Errorf("Invalid frame count %d", len(frames))
+	}
+
+	size := rtpHeaderSize + 1
+	for _, frame := range frames {
+		size += len(frame)
+	}
+
+	b := make([]byte, rtpHeaderSize+1, size)
+	b[0] = 0x80
+	b[1] = rtpPayloadType
+	binary.BigEndian.PutUint16(b[2:], sequenceNumber)
+	binary.BigEndian.PutUint32(b[4:], timestamp)
+	binary.BigEndian.PutUint32(b[8:], ssrc)
+	b[rtpHeaderSize] = byte(len(frames))
+	for _, frame := range frames {
+		b = append(b, frame...)
+	}
+
+	return b, nil
+}
+
+//NewSBCFrameReader split a raw SBC stream, eg. the output of sbcenc, in frames
+func NewSBCFrameReader(r io.Reader) *SBCFrameReader {
+	return &SBCFrameReader{r: r}
+}
+
+//SBCFrameReader read SBC frames from a stream
+type SBCFrameReader struct {
+	r io.Reader
+}
+
+//ReadFrame return the next frame and its header
+func (f *SBCFrameReader) ReadFrame() ([]byte, SBCFrameHeader, error) {
+
+	header := make([]byte, 4)
+	_, err := io.ReadFull(f.r, header)
+	if err != nil {
+		return nil, SBCFrameHeader{}, err
+	}
+
+	h, err := ParseSBCFrameHeader(header)
+	if err != nil {
+		return nil, h, err
+	}
+
+	frame := make([]byte, h.Length())
+	copy(frame, header)
+	_, err = io.ReadFull(f.r, frame[4:])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return frame, h, err
+}
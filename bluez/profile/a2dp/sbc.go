@@ -0,0 +1,268 @@
+package a2dp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A2DP service class UUIDs, used as MediaEndpoint1 UUID
+const (
+	SourceUUID = "0000110a-0000-1000-8000-00805f9b34fb"
+	SinkUUID   = "0000110b-0000-1000-8000-00805f9b34fb"
+)
+
+//CodecSBC the mandatory A2DP codec
+const CodecSBC byte = 0x00
+
+// SBC sampling frequencies
+const (
+	SBCFrequency16000 byte = 0x80
+	SBCFrequency32000 byte = 0x40
+	SBCFrequency44100 byte = 0x20
+	SBCFrequency48000 byte = 0x10
+)
+
+// SBC channel modes
+const (
+	SBCChannelModeMono        byte = 0x08
+	SBCChannelModeDualChannel byte = 0x04
+	SBCChannelModeStereo      byte = 0x02
+	SBCChannelModeJointStereo byte = 0x01
+)
+
+// SBC block lengths
+const (
+	SBCBlockLength4  byte = 0x80
+	SBCBlockLength8  byte = 0x40
+	SBCBlockLength12 byte = 0x20
+	SBCBlockLength16 byte = 0x10
+)
+
+// SBC subbands
+const (
+	SBCSubbands4 byte = 0x08
+	SBCSubbands8 byte = 0x04
+)
+
+// SBC allocation methods
+const (
+	SBCAllocationSNR      byte = 0x02
+	SBCAllocationLoudness byte = 0x01
+)
+
+// SBC bitpool range
+const (
+	SBCMinBitpool byte = 2
+	SBCMaxBitpool byte = 64
+)
+
+//SBCCapabilities the SBC codec specific information element, used both for
+// capabilities, where each field may have several bits set, and for a
+// configuration, where a single bit is set
+type SBCCapabilities struct {
+	Frequencies       byte
+	ChannelModes      byte
+	BlockLengths      byte
+	Subbands          byte
+	AllocationMethods byte
+	MinBitpool        byte
+	MaxBitpool        byte
+}
+
+//DefaultSBCCapabilities support every SBC parameter, with the bitpool
+// capped at the value recommended for high quality joint stereo
+func DefaultSBCCapabilities() SBCCapabilities {
+	return SBCCapabilities{
+		Frequencies:       SBCFrequency16000 | SBCFrequency32000 | SBCFrequency44100 | SBCFrequency48000,
+		ChannelModes:      SBCChannelModeMono | SBCChannelModeDualChannel | SBCChannelModeStereo | SBCChannelModeJointStereo,
+		BlockLengths:      SBCBlockLength4 | SBCBlockLength8 | SBCBlockLength12 | SBCBlockLength16,
+		Subbands:          SBCSubbands4 | SBCSubbands8,
+		AllocationMethods: SBCAllocationSNR | SBCAllocationLoudness,
+		MinBitpool:        SBCMinBitpool,
+		MaxBitpool:        53,
+	}
+}
+
+//ParseSBCCapabilities decode the 4 bytes SBC information element
+func ParseSBCCapabilities(b []byte) (SBCCapabilities, error) {
+	if len(b) != 4 {
+		return SBCCapabilities{}, fmt.Errorf("Invalid SBC capabilities length %d", len(b))
+	}
+	return SBCCapabilities{
+		Frequencies:       b[0] >> 4 << 4,
+		ChannelModes:      b[0] & 0x0f,
+		BlockLengths:      b[1] >> 4 << 4,
+		Subbands:          (b[1] >> 2 & 0x03) << 2,
+		AllocationMethods: b[1] & 0x03,
+		MinBitpool:        b[2],
+		MaxBitpool:        b[3],
+	}, nil
+}
+
+//Bytes encode the SBC information element
+func (c SBCCapabilities) Bytes() []byte {
+	return []byte{
+		c.Frequencies | c.ChannelModes,
+		c.BlockLengths | c.Subbands | c.AllocationMethods,
+		c.MinBitpool,
+		c.MaxBitpool,
+	}
+}
+
+//SampleRate return the sampling frequency of a configuration, in Hz
+func (c SBCCapabilities) SampleRate() int {
+	switch {
+	case c.Frequencies&SBCFrequency48000 != 0:
+		return 48000
+	case c.Frequencies&SBCFrequency44100 != 0:
+		return 44100
+	case c.Frequencies&SBCFrequency32000 != 0:
+		return 32000
+	case c.Frequencies&SBCFrequency16000 != 0:
+		return 16000
+	}
+	return 0
+}
+
+//Channels return the number of channels of a configuration
+func (c SBCCapabilities) Channels() int {
+	if c.ChannelModes == SBCChannelModeMono {
+		return 1
+	}
+	return 2
+}
+
+// pick return the first preferred bit available in both masks
+func pick(local byte, remote byte, preferred ...byte) byte {
+	for _, bit := range preferred {
+		if local&remote&bit != 0 {
+			return bit
+		}
+	}
+	return 0
+}
+
+//SelectSBCConfiguration choose the best configuration supported by both the
+// local and the remote capabilities
+func SelectSBCConfiguration(local SBCCapabilities, remote SBCCapabilities) (SBCCapabilities, error) {
+
+	config := SBCCapabilities{
+		Frequencies: pick(local.Frequencies, remote.Frequencies,
+			SBCFrequency44100, SBCFrequency48000, SBCFrequency32000, SBCFrequency16000),
+		ChannelModes: pick(local.ChannelModes, remote.ChannelModes,
+			SBCChannelModeJointStereo, SBCChannelModeStereo, SBCChannelModeDualChannel, SBCChannelModeMono),
+		BlockLengths: pick(local.BlockLengths, remote.BlockLengths,
+			SBCBlockLength16, SBCBlockLength12, SBCBlockLength8, SBCBlockLength4),
+		Subbands: pick(local.Subbands, remote.Subbands,
+			SBCSubbands8, SBCSubbands4),
+		AllocationMethods: pick(local.AllocationMethods, remote.AllocationMethods,
+			SBCAllocationLoudness, SBCAllocationSNR),
+	}
+
+	if config.Frequencies == 0 || config.ChannelModes == 0 || config.BlockLengths == 0 ||
+		config.Subbands == 0 || config.AllocationMethods == 0 {
+		return config, errors.New("No common SBC configuration")
+	}
+
+	config.MinBitpool = local.MinBitpool
+	if remote.MinBitpool > config.MinBitpool {
+		config.MinBitpool = remote.MinBitpool
+	}
+	if config.MinBitpool < SBCMinBitpool {
+		config.MinBitpool = SBCMinBitpool
+	}
+	config.MaxBitpool = local.MaxBitpool
+	if remote.MaxBitpool < config.MaxBitpool {
+		config.MaxBitpool = remote.MaxBitpool
+	}
+	if config.MaxBitpool > SBCMaxBitpool {
+		config.MaxBitpool = SBCMaxBitpool
+	}
+	if config.MinBitpool > config.MaxBitpool {
+		return config, errors.New("No common SBC bitpool range")
+	}
+
+	return config, nil
+}
+
+//SBCQuality the quality levels recommended by the A2DP specification
+type SBCQuality int
+
+// SBC quality levels
+const (
+	SBCQualityMiddle SBCQuality = iota
+	SBCQualityHigh
+)
+
+// sbcBitpools the recommended bitpools of the A2DP specification (table 4.7),
+// by quality, mono or stereo and 44.1 or 48 kHz. The 44.1 kHz values are
+// used for the lower frequencies
+var sbcBitpools = map[SBCQuality][2][2]byte{
+	SBCQualityMiddle: {{35, 33}, {19, 18}},
+	SBCQualityHigh:   {{53, 51}, {31, 29}},
+}
+
+//Bitpool return the bitpool recommended by the A2DP specification for a
+// configuration, clamped to its bitpool range
+func (c SBCCapabilities) Bitpool(quality SBCQuality) byte {
+
+	mono := 0
+	if c.ChannelModes == SBCChannelModeMono {
+		mono = 1
+	}
+	rate := 0
+	if c.Frequencies == SBCFrequency48000 {
+		rate = 1
+	}
+	bitpool := sbcBitpools[quality][mono][rate]
+
+	if c.MaxBitpool != 0 && bitpool > c.MaxBitpool {
+		bitpool = c.MaxBitpool
+	}
+	if bitpool < c.MinBitpool {
+		bitpool = c.MinBitpool
+	}
+	return bitpool
+}
+
+//FrameHeader return the header of the frames produced by an encoder using
+// the configuration and bitpool
+func (c SBCCapabilities) FrameHeader(bitpool byte) SBCFrameHeader {
+	h := SBCFrameHeader{
+		SampleRate:  c.SampleRate(),
+		ChannelMode: c.ChannelModes,
+		Allocation:  c.AllocationMethods,
+		Bitpool:     int(bitpool),
+		Subbands:    8,
+	}
+	if c.Subbands == SBCSubbands4 {
+		h.Subbands = 4
+	}
+	switch c.BlockLengths {
+	case SBCBlockLength4:
+		h.Blocks = 4
+	case SBCBlockLength8:
+		h.Blocks = 8
+	case SBCBlockLength12:
+		h.Blocks = 12
+	default:
+		h.Blocks = 16
+	}
+	return h
+}
+
+//FramesPerPacket return how many frames of the given length fit in a media
+// packet not larger than mtu
+func FramesPerPacket(frameLength int, mtu int) int {
+	if frameLength <= 0 {
+		return 0
+	}
+	n := (mtu - rtpHeaderSize - 1) / frameLength
+	if n > maxFramesPerPacket {
+		n = maxFramesPerPacket
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
@@ -0,0 +1,168 @@
+package a2dp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSBCCapabilities(t *testing.T) {
+
+	caps := DefaultSBCCapabilities()
+	b := caps.Bytes()
+	if !bytes.Equal(b, []byte{0xff, 0xff, 2, 53}) {
+		t.Fatalf("Unexpected encoding %x", b)
+	}
+
+	parsed, err := ParseSBCCapabilities(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != caps {
+		t.Fatalf("Unexpected capabilities %+v", parsed)
+	}
+}
+
+func TestSelectSBCConfiguration(t *testing.T) {
+
+	remote := SBCCapabilities{
+		Frequencies:       SBCFrequency48000,
+		ChannelModes:      SBCChannelModeStereo | SBCChannelModeMono,
+		BlockLengths:      SBCBlockLength16 | SBCBlockLength8,
+		Subbands:          SBCSubbands8,
+		AllocationMethods: SBCAllocationLoudness,
+		MinBitpool:        10,
+		MaxBitpool:        35,
+	}
+
+	config, err := SelectSBCConfiguration(DefaultSBCCapabilities(), remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.SampleRate() != 48000 || config.ChannelModes != SBCChannelModeStereo ||
+		config.BlockLengths != SBCBlockLength16 || config.MinBitpool != 10 || config.MaxBitpool != 35 {
+		t.Fatalf("Unexpected configuration %+v", config)
+	}
+
+	remote.Frequencies = 0
+	if _, err := SelectSBCConfiguration(DefaultSBCCapabilities(), remote); err == nil {
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestSBCBitpool(t *testing.T) {
+
+	tests := []struct {
+		frequency   byte
+		channelMode byte
+		quality     SBCQuality
+		bitpool     byte
+	}{
+		{SBCFrequency44100, SBCChannelModeMono, SBCQualityMiddle, 19},
+		{SBCFrequency48000, SBCChannelModeMono, SBCQualityMiddle, 18},
+		{SBCFrequency44100, SBCChannelModeJointStereo, SBCQualityMiddle, 35},
+		{SBCFrequency48000, SBCChannelModeJointStereo, SBCQualityMiddle, 33},
+		{SBCFrequency44100, SBCChannelModeMono, SBCQualityHigh, 31},
+		{SBCFrequency48000, SBCChannelModeMono, SBCQualityHigh, 29},
+		{SBCFrequency44100, SBCChannelModeJointStereo, SBCQualityHigh, 53},
+		{SBCFrequency48000, SBCChannelModeJointStereo, SBCQualityHigh, 51},
+		{SBCFrequency32000, SBCChannelModeStereo, SBCQualityHigh, 53},
+	}
+
+	for _, test := range tests {
+		config := SBCCapabilities{
+			Frequencies:  test.frequency,
+			ChannelModes: test.channelMode,
+			MinBitpool:   SBCMinBitpool,
+			MaxBitpool:   SBCMaxBitpool,
+		}
+		bitpool := config.Bitpool(test.quality)
+		if bitpool != test.bitpool {
+			t.Errorf("%d Hz, mode %x, quality %d: expected bitpool %d, got %d",
+				config.SampleRate(), test.channelMode, test.quality, test.bitpool, bitpool)
+		}
+	}
+
+	// clamped to the negotiated range
+	config := SBCCapabilities{Frequencies: SBCFrequency44100, ChannelModes: SBCChannelModeStereo, MinBitpool: 2, MaxBitpool: 40}
+	if bitpool := config.Bitpool(SBCQualityHigh); bitpool != 40 {
+		t.Errorf("Expected the bitpool capped at 40, got %d", bitpool)
+	}
+	config = SBCCapabilities{Frequencies: SBCFrequency48000, ChannelModes: SBCChannelModeMono, MinBitpool: 20, MaxBitpool: 64}
+	if bitpool := config.Bitpool(SBCQualityMiddle); bitpool != 20 {
+		t.Errorf("Expected the bitpool raised to 20, got %d", bitpool)
+	}
+}
+
+func TestParseMediaPacket(t *testing.T) {
+
+	// 44.1kHz, 16 blocks, joint stereo, loudness, 8 subbands, bitpool 53
+	header := []byte{SBCSyncword, 0xbd, 53, 0x00}
+	h, err := ParseSBCFrameHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.SampleRate != 44100 || h.Blocks != 16 || h.ChannelMode != SBCChannelModeJointStereo || h.Subbands != 8 {
+		t.Fatalf("Unexpected header %+v", h)
+	}
+	if h.Length() != 119 {
+		t.Fatalf("Unexpected frame length %d", h.Length())
+	}
+
+	frame := make([]byte, h.Length())
+	copy(frame, header)
+
+	packet := []byte{0x80, 0x60, 0x00, 0x2a, 0, 0, 0x01, 0x00, 0, 0, 0, 1, 0x02}
+	packet = append(packet, frame...)
+	packet = append(packet, frame...)
+
+	p, err := ParseMediaPacket(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.SequenceNumber != 42 || p.Timestamp != 256 || len(p.Frames) != 2 || len(p.Frames[1]) != 119 {
+		t.Fatalf("Unexpected packet %+v", p)
+	}
+}
+
+func TestEncodeMediaPacket(t *testing.T) {
+
+	config := SBCCapabilities{
+		Frequencies:       SBCFrequency44100,
+		ChannelModes:      SBCChannelModeJointStereo,
+		BlockLengths:      SBCBlockLength16,
+		Subbands:          SBCSubbands8,
+		AllocationMethods: SBCAllocationLoudness,
+		MinBitpool:        2,
+		MaxBitpool:        64,
+	}
+	bitpool := config.Bitpool(SBCQualityHigh)
+	if bitpool != 53 {
+		t.Fatalf("Unexpected bitpool %d", bitpool)
+	}
+
+	h := config.FrameHeader(bitpool)
+	frame := make([]byte, h.Length())
+	copy(frame, []byte{SBCSyncword, 0xbd, bitpool, 0x00})
+
+	n := FramesPerPacket(len(frame), 672)
+	if n != 5 {
+		t.Fatalf("Unexpected frames per packet %d", n)
+	}
+
+	frames := make([][]byte, n)
+	for i := range frames {
+		frames[i] = frame
+	}
+	packet, err := EncodeMediaPacket(7, 1024, 1, frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := ParseMediaPacket(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.SequenceNumber != 7 || p.Timestamp != 1024 || len(p.Frames) != n {
+		t.Fatalf("Unexpected packet %+v", p)
+	}
+}
@@ -0,0 +1,151 @@
+package a2dp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//NewStream wrap an acquired transport file descriptor
+func NewStream(transport *profile.MediaTransport1, fd dbus.UnixFD, readMTU uint16, writeMTU uint16) *Stream {
+	return &Stream{
+		file:      os.NewFile(uintptr(fd), transport.Path()),
+		transport: transport,
+		ReadMTU:   int(readMTU),
+		WriteMTU:  int(writeMTU),
+	}
+}
+
+//Stream an acquired A2DP transport, each read returns a single media packet
+type Stream struct {
+	file      *os.File
+	transport *profile.MediaTransport1
+	ReadMTU   int
+	WriteMTU  int
+
+	sequenceNumber uint16
+	timestamp      uint32
+}
+
+//Transport return the transport the stream belongs to
+func (s *Stream) Transport() *profile.MediaTransport1 {
+	return s.transport
+}
+
+//Read a raw media packet, p should be at least ReadMTU long
+func (s *Stream) Read(p []byte) (int, error) {
+	return s.file.Read(p)
+}
+
+//ReadPacket read and parse the next media packet
+func (s *Stream) ReadPacket() (*MediaPacket, error) {
+	buf := make([]byte, s.ReadMTU)
+	n, err := s.file.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMediaPacket(buf[:n])
+}
+
+//Write a raw media packet, p must not exceed WriteMTU
+func (s *Stream) Write(p []byte) (int, error) {
+	if len(p) > s.WriteMTU {
+		return 0, fmt.Errorf("Packet size %d exceeds MTU %d", len(p), s.WriteMTU)
+	}
+	return s.file.Write(p)
+}
+
+//WriteFrames send SBC frames in a single media packet, see FramesPerPacket to
+// size the batch. Sequence number and timestamp are kept by the stream
+func (s *Stream) WriteFrames(frames [][]byte) error {
+
+	if len(frames) == 0 {
+		return nil
+	}
+
+	h, err := ParseSBCFrameHeader(frames[0])
+	if err != nil {
+		return err
+	}
+
+	packet, err := EncodeMediaPacket(s.sequenceNumber, s.timestamp, 1, frames)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Write(packet)
+	if err != nil {
+		return err
+	}
+
+	s.sequenceNumber++
+	s.timestamp += uint32(h.Samples() * len(frames))
+	return nil
+}
+
+//Close the file descriptor and release the transport
+func (s *Stream) Close() error {
+	err := s.file.Close()
+	s.transport.Release()
+	return err
+}
+
+//Acquire the transport immediately
+func Acquire(transport *profile.MediaTransport1) (*Stream, error) {
+	fd, readMTU, writeMTU, err := transport.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(transport, fd, readMTU, writeMTU), nil
+}
+
+//AcquireWhenPending wait for the remote device to start streaming, then
+// acquire the transport. This is the flow expected by a sink endpoint
+func AcquireWhenPending(transport *profile.MediaTransport1) (*Stream, error) {
+
+	signals, err := transport.Register()
+	if err != nil {
+		return nil, err
+	}
+	defer transport.Unregister(signals)
+
+	props, err := transport.GetProperties()
+	if err == nil && props.State == profile.MediaTransportStatePending {
+		return tryAcquire(transport)
+	}
+
+	for sig := range signals {
+
+		if sig == nil {
+			break
+		}
+
+		if string(sig.Path) != transport.Path() || sig.Name != bluez.PropertiesChanged {
+			continue
+		}
+		if iface, ok := sig.Body[0].(string); !ok || iface != bluez.MediaTransport1Interface {
+			continue
+		}
+		changes, ok := sig.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			continue
+		}
+		if state, ok := changes["State"]; ok && state.Value() == profile.MediaTransportStatePending {
+			return tryAcquire(transport)
+		}
+	}
+
+	return nil, errors.New("Transport " + transport.Path() + " closed before streaming")
+}
+
+func tryAcquire(transport *profile.MediaTransport1) (*Stream, error) {
+	fd, readMTU, writeMTU, err := transport.TryAcquire()
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(transport, fd, readMTU, writeMTU), nil
+}
@@ -0,0 +1,123 @@
+package profile_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+func TestDevice1ConnectContext(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	d, err := b.AddDevice("hci0", "00:00:00:00:00:01", "sensor")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the connection hangs until released, as with a device out of range
+	var attempts int32
+	release := make(chan struct{})
+	d.OnConnect = func() error {
+		atomic.AddInt32(&attempts, 1)
+		<-release
+		return nil
+	}
+
+	dev := profile.NewDevice1(string(d.Path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = dev.ConnectContext(ctx); err != context.Canceled {
+		t.Fatalf("Expected the canceled context error, got %v", err)
+	}
+	if atomic.LoadInt32(&attempts) != 0 {
+		t.Fatal("Expected no call with a canceled context")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err = dev.ConnectContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected the deadline error, got %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("Expected to return on the deadline, took %s", time.Since(start))
+	}
+
+	// the attempt completes on the remote side anyway
+	close(release)
+	for i := 0; !d.Property("Connected").(bool); i++ {
+		if i == 100 {
+			t.Fatal("Expected the device connected")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err = dev.ConnectContext(context.Background()); !bluez.IsError(err, bluez.ErrAlreadyConnected) {
+		t.Fatalf("Expected the bluez error parsed, got %v", err)
+	}
+}
+
+func TestDevice1PairContext(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	d, err := b.AddDevice("hci0", "00:00:00:00:00:01", "keyboard")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the pairing waits for a passkey nobody types
+	release := make(chan struct{})
+	defer close(release)
+	d.OnPair = func() error {
+		<-release
+		return nil
+	}
+
+	dev := profile.NewDevice1(string(d.Path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	if err = dev.PairContext(ctx); err != context.Canceled {
+		t.Fatalf("Expected the canceled context error, got %v", err)
+	}
+	if d.Property("Paired").(bool) {
+		t.Fatal("Expected the device not paired")
+	}
+}
+
+func TestAdapter1StartDiscoveryContext(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	a := profile.NewAdapter1("hci0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := a.StartDiscoveryContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Adapter("hci0").Property("Discovering").(bool) {
+		t.Fatal("Expected the discovery started")
+	}
+
+	// the discovery stops once the context is done
+	cancel()
+	for i := 0; b.Adapter("hci0").Property("Discovering").(bool); i++ {
+		if i == 100 {
+			t.Fatal("Expected the discovery stopped")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
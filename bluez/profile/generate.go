@@ -0,0 +1,5 @@
+package profile
+
+// The clients of the interfaces described in xml/ are generated, add the
+// introspection of a new bluetoothd interface there and run go generate
+//go:generate go run ../gen/main.go -out . xml
@@ -0,0 +1,284 @@
+package hfp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//CommandType the form of an AT command
+type CommandType int
+
+// AT command forms
+const (
+	// CommandExec eg. ATA or AT+CHUP
+	CommandExec CommandType = iota
+	// CommandSet eg. AT+VGS=10
+	CommandSet
+	// CommandRead eg. AT+CIND?
+	CommandRead
+	// CommandTest eg. AT+CIND=?
+	CommandTest
+)
+
+// Final result codes
+const (
+	ResultOK        = "OK"
+	ResultError     = "ERROR"
+	ResultCMEError  = "+CME ERROR"
+	ResultNoCarrier = "NO CARRIER"
+	ResultBusy      = "BUSY"
+	ResultNoAnswer  = "NO ANSWER"
+	ResultDelayed   = "DELAYED"
+	ResultBlacklist = "BLACKLISTED"
+	ResultRing      = "RING"
+)
+
+//Command an AT command sent by the hands-free unit. Name holds the command
+// without the AT prefix, eg. +VGS for extended commands or A and D for basic ones
+type Command struct {
+	Name string
+	Type CommandType
+	Args []string
+}
+
+//NewCommand create a command, with arguments it is a set command
+func NewCommand(name string, args ...interface{}) *Command {
+	c := &Command{Name: name}
+	if len(args) > 0 {
+		c.Type = CommandSet
+		c.Args = formatArgs(args)
+	}
+	return c
+}
+
+//ParseCommand parse a command line, eg. AT+BRSF=191
+func ParseCommand(line string) (*Command, error) {
+
+	line = strings.TrimSpace(line)
+	if len(line) < 3 || !strings.EqualFold(line[:2], "AT") {
+		return nil, errors.New("Invalid AT command " + line)
+	}
+	body := line[2:]
+
+	c := &Command{}
+
+	// basic commands are a single letter followed by their argument, eg. ATD1234;
+	if body[0] != '+' && body[0] != '%' && body[0] != '^' {
+		c.Name = strings.ToUpper(body[:1])
+		if len(body) > 1 {
+			c.Args = []string{body[1:]}
+		}
+		return c, nil
+	}
+
+	end := strings.IndexAny(body, "=?")
+	if end == -1 {
+		c.Name = strings.ToUpper(body)
+		return c, nil
+	}
+
+	c.Name = strings.ToUpper(body[:end])
+	rest := body[end:]
+	switch {
+	case rest == "=?":
+		c.Type = CommandTest
+	case rest == "?":
+		c.Type = CommandRead
+	case rest[0] == '=':
+		c.Type = CommandSet
+		c.Args = splitArgs(rest[1:])
+	default:
+		return nil, errors.New("Invalid AT command " + line)
+	}
+
+	return c, nil
+}
+
+//String encode the command, without the trailing carriage return
+func (c *Command) String() string {
+
+	if len(c.Name) == 1 {
+		return "AT" + c.Name + strings.Join(c.Args, "")
+	}
+
+	switch c.Type {
+	case CommandRead:
+		return "AT" + c.Name + "?"
+	case CommandTest:
+		return "AT" + c.Name + "=?"
+	case CommandSet:
+		return "AT" + c.Name + "=" + strings.Join(c.Args, ",")
+	}
+	return "AT" + c.Name
+}
+
+//Int return an argument as integer
+func (c *Command) Int(i int) (int, error) {
+	return intArg(c.Args, i)
+}
+
+//Str return an argument as string, removing the quotes
+func (c *Command) Str(i int) string {
+	return stringArg(c.Args, i)
+}
+
+//Result a response line sent by the audio gateway, either a final result
+// code like OK or an information response like +CIEV: 2,1
+type Result struct {
+	Name string
+	Args []string
+}
+
+//NewResult create a result
+func NewResult(name string, args ...interface{}) *Result {
+	return &Result{Name: name, Args: formatArgs(args)}
+}
+
+//ParseResult parse a response line
+func ParseResult(line string) *Result {
+
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "+") {
+		return &Result{Name: line}
+	}
+
+	sep := strings.Index(line, ":")
+	if sep == -1 {
+		return &Result{Name: line}
+	}
+
+	return &Result{
+		Name: line[:sep],
+		Args: splitArgs(strings.TrimSpace(line[sep+1:])),
+	}
+}
+
+//String encode the result, without the surrounding line breaks
+func (r *Result) String() string {
+	if len(r.Args) == 0 {
+		return r.Name
+	}
+	return r.Name + ": " + strings.Join(r.Args, ",")
+}
+
+//IsFinal return true for the result codes terminating a command
+func (r *Result) IsFinal() bool {
+	switch r.Name {
+	case ResultOK, ResultError, ResultCMEError, ResultNoCarrier,
+		ResultBusy, ResultNoAnswer, ResultDelayed, ResultBlacklist:
+		return true
+	}
+	return false
+}
+
+//Int return an argument as integer
+func (r *Result) Int(i int) (int, error) {
+	return intArg(r.Args, i)
+}
+
+//Str return an argument as string, removing the quotes
+func (r *Result) Str(i int) string {
+	return stringArg(r.Args, i)
+}
+
+// formatArgs encode arguments, strings are quoted unless they are already
+func formatArgs(args []interface{}) []string {
+	list := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			if strings.HasPrefix(v, "\"") || strings.HasPrefix(v, "(") {
+				list[i] = v
+			} else {
+				list[i] = strconv.Quote(v)
+			}
+		default:
+			list[i] = fmt.Sprint(v)
+		}
+	}
+	return list
+}
+
+// splitArgs split a comma separated list, keeping quoted strings and
+// parenthesized groups together
+func splitArgs(s string) []string {
+
+	if s == "" {
+		return nil
+	}
+
+	args := []string{}
+	depth := 0
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case '(':
+			if !quoted {
+				depth++
+			}
+		case ')':
+			if !quoted && depth > 0 {
+				depth--
+			}
+		case ',':
+			if !quoted && depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(s[start:]))
+}
+
+func intArg(args []string, i int) (int, error) {
+	if i >= len(args) {
+		return 0, fmt.Errorf("Missing argument %d", i)
+	}
+	return strconv.Atoi(strings.TrimSpace(args[i]))
+}
+
+func stringArg(args []string, i int) string {
+	if i >= len(args) {
+		return ""
+	}
+	return strings.Trim(args[i], "\"")
+}
+
+//Reader read AT lines terminated by carriage returns or line feeds, empty
+// lines are skipped
+type Reader struct {
+	r *bufio.Reader
+}
+
+//NewReader create a line reader
+func NewReader(r io.Reader) *Reader {
+	return &Reader{bufio.NewReader(r)}
+}
+
+//ReadLine return the next non empty line
+func (r *Reader) ReadLine() (string, error) {
+	line := []byte{}
+	for {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return string(line), nil
+			}
+			return "", err
+		}
+		if b == '\r' || b == '\n' {
+			if len(line) > 0 {
+				return string(line), nil
+			}
+			continue
+		}
+		line = append(line, b)
+	}
+}
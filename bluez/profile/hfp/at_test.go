@@ -0,0 +1,135 @@
+package hfp
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCommand(t *testing.T) {
+
+	cases := []struct {
+		line string
+		name string
+		typ  CommandType
+		args []string
+	}{
+		{"ATA", "A", CommandExec, nil},
+		{"ATD+390123456;", "D", CommandExec, []string{"+390123456;"}},
+		{"AT+CHUP", "+CHUP", CommandExec, nil},
+		{"AT+BRSF=191", "+BRSF", CommandSet, []string{"191"}},
+		{"at+cind=?", "+CIND", CommandTest, nil},
+		{"AT+CIND?", "+CIND", CommandRead, nil},
+		{"AT+CMER=3,0,0,1", "+CMER", CommandSet, []string{"3", "0", "0", "1"}},
+	}
+
+	for _, c := range cases {
+		cmd, err := ParseCommand(c.line)
+		if err != nil {
+			t.Fatalf("%s: %s", c.line, err)
+		}
+		if cmd.Name != c.name || cmd.Type != c.typ || strings.Join(cmd.Args, "|") != strings.Join(c.args, "|") {
+			t.Fatalf("%s: unexpected %+v", c.line, cmd)
+		}
+		if !strings.EqualFold(cmd.String(), c.line) {
+			t.Fatalf("%s: encoded as %s", c.line, cmd.String())
+		}
+	}
+
+	if _, err := ParseCommand("+CIND"); err == nil {
+		t.Fatal("Expected error for a line without AT prefix")
+	}
+}
+
+func TestParseResult(t *testing.T) {
+
+	r := ParseResult(`+CIND: ("service",(0,1)),("call",(0,1)),("callsetup",(0-3))`)
+	if r.Name != "+CIND" || len(r.Args) != 3 {
+		t.Fatalf("Unexpected %+v", r)
+	}
+
+	ind := parseIndicator(r.Args[2])
+	if ind.Name != IndicatorCallSetup || ind.Min != 0 || ind.Max != 3 {
+		t.Fatalf("Unexpected %+v", ind)
+	}
+
+	r = ParseResult(`+CLIP: "+390123456",145`)
+	if r.Str(0) != "+390123456" {
+		t.Fatalf("Unexpected number %s", r.Str(0))
+	}
+	if v, _ := r.Int(1); v != 145 {
+		t.Fatalf("Unexpected type %d", v)
+	}
+
+	if !ParseResult("+CME ERROR: 30").IsFinal() || ParseResult("RING").IsFinal() {
+		t.Fatal("Unexpected final result detection")
+	}
+
+	if NewResult("+CIEV", 2, 1).String() != "+CIEV: 2,1" {
+		t.Fatal("Unexpected result encoding")
+	}
+}
+
+func TestServiceLevelConnection(t *testing.T) {
+
+	agConn, hfConn := net.Pipe()
+	defer agConn.Close()
+	defer hfConn.Close()
+
+	answered := make(chan bool, 1)
+	ag := NewGateway(agConn, GatewayConfig{
+		OnAnswer: func() error {
+			answered <- true
+			return nil
+		},
+	})
+	go ag.Serve()
+
+	rings := make(chan string, 1)
+	volumes := make(chan int, 1)
+	hf := NewHandsfree(hfConn, HandsfreeConfig{
+		OnRing:          func(number string) { rings <- number },
+		OnSpeakerVolume: func(volume int) { volumes <- volume },
+	})
+
+	err := hf.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ag.Connected() {
+		t.Fatal("Service level connection not established")
+	}
+	if ag.RemoteFeatures() != HandsfreeDefaultFeatures {
+		t.Fatalf("Unexpected features %d", ag.RemoteFeatures())
+	}
+
+	go ag.Ring("0123456")
+	select {
+	case number := <-rings:
+		if number != "0123456" {
+			t.Fatalf("Unexpected number %s", number)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Ring not received")
+	}
+
+	err = hf.Answer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-answered
+	if v, _ := hf.Indicator(IndicatorCall); v != 1 {
+		t.Fatal("Call indicator not reported")
+	}
+
+	go ag.SetSpeakerVolume(9)
+	select {
+	case v := <-volumes:
+		if v != 9 {
+			t.Fatalf("Unexpected volume %d", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Volume not received")
+	}
+}
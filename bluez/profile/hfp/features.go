@@ -0,0 +1,100 @@
+package hfp
+
+// Audio gateway features, exchanged with +BRSF
+const (
+	GatewayThreeWayCalling     uint32 = 1 << 0
+	GatewayECNR                uint32 = 1 << 1
+	GatewayVoiceRecognition    uint32 = 1 << 2
+	GatewayInBandRingTone      uint32 = 1 << 3
+	GatewayVoiceTag            uint32 = 1 << 4
+	GatewayRejectCall          uint32 = 1 << 5
+	GatewayEnhancedCallStatus  uint32 = 1 << 6
+	GatewayEnhancedCallControl uint32 = 1 << 7
+	GatewayExtendedErrorCodes  uint32 = 1 << 8
+	GatewayCodecNegotiation    uint32 = 1 << 9
+	GatewayHFIndicators        uint32 = 1 << 10
+	GatewayESCOS4              uint32 = 1 << 11
+	GatewayDefaultFeatures            = GatewayRejectCall | GatewayEnhancedCallStatus | GatewayExtendedErrorCodes
+)
+
+// Hands-free unit features, exchanged with +BRSF
+const (
+	HandsfreeECNR                uint32 = 1 << 0
+	HandsfreeThreeWayCalling     uint32 = 1 << 1
+	HandsfreeCLIPresentation     uint32 = 1 << 2
+	HandsfreeVoiceRecognition    uint32 = 1 << 3
+	HandsfreeRemoteVolume        uint32 = 1 << 4
+	HandsfreeEnhancedCallStatus  uint32 = 1 << 5
+	HandsfreeEnhancedCallControl uint32 = 1 << 6
+	HandsfreeCodecNegotiation    uint32 = 1 << 7
+	HandsfreeHFIndicators        uint32 = 1 << 8
+	HandsfreeESCOS4              uint32 = 1 << 9
+	HandsfreeDefaultFeatures            = HandsfreeCLIPresentation | HandsfreeRemoteVolume
+)
+
+// Standard indicators reported by the audio gateway
+const (
+	IndicatorService   = "service"
+	IndicatorCall      = "call"
+	IndicatorCallSetup = "callsetup"
+	IndicatorCallHeld  = "callheld"
+	IndicatorSignal    = "signal"
+	IndicatorRoaming   = "roam"
+	IndicatorBattery   = "battchg"
+)
+
+// callsetup indicator values
+const (
+	CallSetupNone     = 0
+	CallSetupIncoming = 1
+	CallSetupOutgoing = 2
+	CallSetupAlerting = 3
+)
+
+//Indicator an audio gateway status indicator
+type Indicator struct {
+	Name  string
+	Min   int
+	Max   int
+	Value int
+}
+
+//DefaultIndicators the indicators defined by HFP, in the usual order
+func DefaultIndicators() []Indicator {
+	return []Indicator{
+		{Name: IndicatorService, Min: 0, Max: 1, Value: 1},
+		{Name: IndicatorCall, Min: 0, Max: 1},
+		{Name: IndicatorCallSetup, Min: 0, Max: 3},
+		{Name: IndicatorCallHeld, Min: 0, Max: 2},
+		{Name: IndicatorSignal, Min: 0, Max: 5, Value: 5},
+		{Name: IndicatorRoaming, Min: 0, Max: 1},
+		{Name: IndicatorBattery, Min: 0, Max: 5, Value: 5},
+	}
+}
+
+// the SDP SupportedFeatures share the first five +BRSF bits, bit 5 flags
+// wide band speech
+const (
+	sdpFeaturesMask   = 0x1f
+	sdpWidebandSpeech = 1 << 5
+)
+
+//GatewaySDPFeatures convert audio gateway features to the SDP record value,
+// as expected by service.NewHandsfreeAudioGatewayProfile1
+func GatewaySDPFeatures(features uint32) uint16 {
+	v := uint16(features & sdpFeaturesMask)
+	if features&GatewayCodecNegotiation != 0 {
+		v |= sdpWidebandSpeech
+	}
+	return v
+}
+
+//HandsfreeSDPFeatures convert hands-free features to the SDP record value,
+// as expected by service.NewHandsfreeProfile1
+func HandsfreeSDPFeatures(features uint32) uint16 {
+	v := uint16(features & sdpFeaturesMask)
+	if features&HandsfreeCodecNegotiation != 0 {
+		v |= sdpWidebandSpeech
+	}
+	return v
+}
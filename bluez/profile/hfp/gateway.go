@@ -0,0 +1,403 @@
+package hfp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//GatewayConfig Gateway configuration, every callback is optional
+type GatewayConfig struct {
+	// Features the supported audio gateway features, defaults to GatewayDefaultFeatures
+	Features uint32
+	// Indicators the reported indicators, defaults to DefaultIndicators
+	Indicators []Indicator
+
+	// OnConnected the service level connection has been established
+	OnConnected func()
+	// OnAnswer the hands-free unit answers the incoming call (ATA). Returning
+	// nil marks the call as active
+	OnAnswer func() error
+	// OnHangup the hands-free unit rejects or terminates the call (AT+CHUP)
+	OnHangup func() error
+	// OnDial the hands-free unit places a call (ATD). Returning nil sets
+	// the outgoing call setup indicator
+	OnDial func(number string) error
+	// OnButton the headset button has been pressed (AT+CKPD, HSP)
+	OnButton func()
+	// OnSpeakerVolume the hands-free unit changed the speaker gain (0-15)
+	OnSpeakerVolume func(volume int)
+	// OnMicrophoneVolume the hands-free unit changed the microphone gain (0-15)
+	OnMicrophoneVolume func(volume int)
+	// OnCommand handle the commands not managed by the gateway, information
+	// responses are sent before OK. Unknown commands are rejected with ERROR
+	OnCommand func(cmd *Command) ([]*Result, error)
+}
+
+//NewGateway handle the audio gateway side of an HFP or HSP connection, conn
+// is usually the ProfileConnection.Conn received by the Profile1. Call Serve
+// to process the commands
+func NewGateway(conn io.ReadWriteCloser, config GatewayConfig) *Gateway {
+
+	if config.Features == 0 {
+		config.Features = GatewayDefaultFeatures
+	}
+	if config.Indicators == nil {
+		config.Indicators = DefaultIndicators()
+	}
+
+	return &Gateway{
+		conn:       conn,
+		reader:     NewReader(conn),
+		config:     config,
+		indicators: config.Indicators,
+	}
+}
+
+//Gateway the audio gateway role, eg. a phone
+type Gateway struct {
+	conn       io.ReadWriteCloser
+	reader     *Reader
+	config     GatewayConfig
+	indicators []Indicator
+
+	mutex          sync.Mutex
+	remoteFeatures uint32
+	reporting      bool
+	clip           bool
+	connected      bool
+}
+
+//Serve process the commands until the connection is closed
+func (g *Gateway) Serve() error {
+	for {
+		line, err := g.reader.ReadLine()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		bluez.GetLogger().Debugf("hfp: > %s", line)
+
+		cmd, err := ParseCommand(line)
+		if err != nil {
+			bluez.GetLogger().Warnf("hfp: %s", err.Error())
+			g.Send(NewResult(ResultError))
+			continue
+		}
+
+		results, err := g.handle(cmd)
+		for _, r := range results {
+			g.Send(r)
+		}
+		if err != nil {
+			bluez.GetLogger().Debugf("hfp: %s failed: %s", cmd.Name, err.Error())
+			g.Send(NewResult(ResultError))
+			continue
+		}
+
+		err = g.Send(NewResult(ResultOK))
+		if err != nil {
+			return err
+		}
+
+		if cmd.Name == "+CMER" || cmd.Name == "+CHLD" {
+			g.checkConnected(cmd)
+		}
+	}
+}
+
+//Close the connection
+func (g *Gateway) Close() error {
+	return g.conn.Close()
+}
+
+//Send a result or an unsolicited response
+func (g *Gateway) Send(r *Result) error {
+	bluez.GetLogger().Debugf("hfp: < %s", r.String())
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	_, err := g.conn.Write([]byte("\r\n" + r.String() + "\r\n"))
+	return err
+}
+
+//Connected return true once the service level connection is established
+func (g *Gateway) Connected() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.connected
+}
+
+//RemoteFeatures return the features advertised by the hands-free unit
+func (g *Gateway) RemoteFeatures() uint32 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.remoteFeatures
+}
+
+//Indicator return the value of an indicator
+func (g *Gateway) Indicator(name string) (int, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for _, ind := range g.indicators {
+		if ind.Name == name {
+			return ind.Value, nil
+		}
+	}
+	return 0, errors.New("Unknown indicator " + name)
+}
+
+//SetIndicator change an indicator, reporting it with +CIEV when enabled by
+// the hands-free unit
+func (g *Gateway) SetIndicator(name string, value int) error {
+
+	g.mutex.Lock()
+	index := -1
+	for i := range g.indicators {
+		if g.indicators[i].Name == name {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		g.mutex.Unlock()
+		return errors.New("Unknown indicator " + name)
+	}
+	ind := &g.indicators[index]
+	if value < ind.Min || value > ind.Max {
+		g.mutex.Unlock()
+		return fmt.Errorf("Value %d out of range for %s", value, name)
+	}
+	changed := ind.Value != value
+	ind.Value = value
+	report := g.reporting
+	g.mutex.Unlock()
+
+	if !changed || !report {
+		return nil
+	}
+	// indicators are numbered from 1
+	return g.Send(NewResult("+CIEV", index+1, value))
+}
+
+//Ring signal an incoming call, call it every few seconds until the call is
+// answered. The caller number is sent when enabled by the hands-free unit
+func (g *Gateway) Ring(number string) error {
+
+	err := g.SetIndicator(IndicatorCallSetup, CallSetupIncoming)
+	if err != nil {
+		return err
+	}
+
+	err = g.Send(NewResult(ResultRing))
+	if err != nil {
+		return err
+	}
+
+	g.mutex.Lock()
+	clip := g.clip
+	g.mutex.Unlock()
+
+	if clip && number != "" {
+		return g.Send(NewResult("+CLIP", number, numberType(number)))
+	}
+	return nil
+}
+
+//Answered mark the call as active, eg. when answered on the phone itself
+func (g *Gateway) Answered() error {
+	err := g.SetIndicator(IndicatorCall, 1)
+	if err != nil {
+		return err
+	}
+	return g.SetIndicator(IndicatorCallSetup, CallSetupNone)
+}
+
+//Hangup mark the call as terminated
+func (g *Gateway) Hangup() error {
+	err := g.SetIndicator(IndicatorCallSetup, CallSetupNone)
+	if err != nil {
+		return err
+	}
+	return g.SetIndicator(IndicatorCall, 0)
+}
+
+//SetSpeakerVolume change the speaker gain of the hands-free unit (0-15)
+func (g *Gateway) SetSpeakerVolume(volume int) error {
+	if volume < 0 || volume > 15 {
+		return errors.New("Volume out of range")
+	}
+	return g.Send(NewResult("+VGS", volume))
+}
+
+//SetMicrophoneVolume change the microphone gain of the hands-free unit (0-15)
+func (g *Gateway) SetMicrophoneVolume(volume int) error {
+	if volume < 0 || volume > 15 {
+		return errors.New("Volume out of range")
+	}
+	return g.Send(NewResult("+VGM", volume))
+}
+
+// handle a command returning the information responses to send before OK
+func (g *Gateway) handle(cmd *Command) ([]*Result, error) {
+
+	c := g.config
+
+	switch cmd.Name {
+	case "+BRSF":
+		features, err := cmd.Int(0)
+		if err != nil {
+			return nil, err
+		}
+		g.mutex.Lock()
+		g.remoteFeatures = uint32(features)
+		g.mutex.Unlock()
+		return []*Result{NewResult("+BRSF", c.Features)}, nil
+
+	case "+CIND":
+		if cmd.Type != CommandTest && cmd.Type != CommandRead {
+			return nil, errors.New("Unsupported +CIND form")
+		}
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		args := make([]interface{}, len(g.indicators))
+		for i, ind := range g.indicators {
+			if cmd.Type == CommandRead {
+				args[i] = ind.Value
+				continue
+			}
+			values := fmt.Sprintf("%d-%d", ind.Min, ind.Max)
+			if ind.Max-ind.Min == 1 {
+				values = fmt.Sprintf("%d,%d", ind.Min, ind.Max)
+			}
+			args[i] = fmt.Sprintf("(\"%s\",(%s))", ind.Name, values)
+		}
+		return []*Result{NewResult("+CIND", args...)}, nil
+
+	case "+CMER":
+		// mode 3, keyp 0, disp 0, ind 0|1
+		ind, err := cmd.Int(3)
+		if err != nil {
+			return nil, err
+		}
+		g.mutex.Lock()
+		g.reporting = ind == 1
+		g.mutex.Unlock()
+		return nil, nil
+
+	case "+CHLD":
+		if cmd.Type == CommandTest {
+			return []*Result{NewResult("+CHLD", "(0,1,2,3)")}, nil
+		}
+		return g.custom(cmd)
+
+	case "+CLIP":
+		enable, err := cmd.Int(0)
+		if err != nil {
+			return nil, err
+		}
+		g.mutex.Lock()
+		g.clip = enable == 1
+		g.mutex.Unlock()
+		return nil, nil
+
+	case "A":
+		if c.OnAnswer != nil {
+			err := c.OnAnswer()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, g.Answered()
+
+	case "+CHUP":
+		if c.OnHangup != nil {
+			err := c.OnHangup()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, g.Hangup()
+
+	case "D":
+		number := strings.TrimSuffix(cmd.Str(0), ";")
+		if c.OnDial == nil {
+			return nil, errors.New("Dialing not supported")
+		}
+		err := c.OnDial(number)
+		if err != nil {
+			return nil, err
+		}
+		return nil, g.SetIndicator(IndicatorCallSetup, CallSetupOutgoing)
+
+	case "+CKPD":
+		if c.OnButton != nil {
+			c.OnButton()
+		}
+		return nil, nil
+
+	case "+VGS", "+VGM":
+		volume, err := cmd.Int(0)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.Name == "+VGS" && c.OnSpeakerVolume != nil {
+			c.OnSpeakerVolume(volume)
+		}
+		if cmd.Name == "+VGM" && c.OnMicrophoneVolume != nil {
+			c.OnMicrophoneVolume(volume)
+		}
+		return nil, nil
+
+	case "+CMEE", "+CCWA", "+NREC", "+BIA", "+BAC":
+		// accepted without further action
+		if c.OnCommand != nil {
+			return c.OnCommand(cmd)
+		}
+		return nil, nil
+	}
+
+	return g.custom(cmd)
+}
+
+func (g *Gateway) custom(cmd *Command) ([]*Result, error) {
+	if g.config.OnCommand == nil {
+		return nil, errors.New("Unsupported command " + cmd.Name)
+	}
+	return g.config.OnCommand(cmd)
+}
+
+// checkConnected flag the service level connection as established after the
+// indicators activation, or after +CHLD when both sides support three way calls
+func (g *Gateway) checkConnected(cmd *Command) {
+
+	g.mutex.Lock()
+	threeWay := g.remoteFeatures&HandsfreeThreeWayCalling != 0 &&
+		g.config.Features&GatewayThreeWayCalling != 0
+	if g.connected || (cmd.Name == "+CMER" && threeWay) {
+		g.mutex.Unlock()
+		return
+	}
+	g.connected = true
+	g.mutex.Unlock()
+
+	bluez.GetLogger().Debugf("hfp: service level connection established")
+	if g.config.OnConnected != nil {
+		g.config.OnConnected()
+	}
+}
+
+// numberType the type of address of a phone number, 145 for international
+// numbers and 129 otherwise
+func numberType(number string) int {
+	if strings.HasPrefix(number, "+") {
+		return 145
+	}
+	return 129
+}
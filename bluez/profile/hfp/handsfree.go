@@ -0,0 +1,380 @@
+package hfp
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// how long to wait for the audio gateway to answer a command
+const commandTimeout = 5 * time.Second
+
+//HandsfreeConfig Handsfree configuration, every callback is optional
+type HandsfreeConfig struct {
+	// Features the supported hands-free features, defaults to HandsfreeDefaultFeatures
+	Features uint32
+	// OnRing an incoming call is ringing, number is empty unless the gateway
+	// reports the calling line
+	OnRing func(number string)
+	// OnIndicator an audio gateway indicator changed, eg. call or callsetup
+	OnIndicator func(name string, value int)
+	// OnSpeakerVolume the gateway changed the speaker gain (0-15)
+	OnSpeakerVolume func(volume int)
+	// OnMicrophoneVolume the gateway changed the microphone gain (0-15)
+	OnMicrophoneVolume func(volume int)
+	// OnResult every other unsolicited response
+	OnResult func(r *Result)
+	// OnDisconnected the connection has been closed
+	OnDisconnected func()
+}
+
+//NewHandsfree handle the hands-free side of an HFP connection, conn is
+// usually the ProfileConnection.Conn received by the Profile1. Call Connect to
+// establish the service level connection
+func NewHandsfree(conn io.ReadWriteCloser, config HandsfreeConfig) *Handsfree {
+
+	if config.Features == 0 {
+		config.Features = HandsfreeDefaultFeatures
+	}
+
+	return &Handsfree{
+		conn:       conn,
+		reader:     NewReader(conn),
+		config:     config,
+		indicators: map[int]*Indicator{},
+	}
+}
+
+//Handsfree the hands-free role, eg. a car kit
+type Handsfree struct {
+	conn   io.ReadWriteCloser
+	reader *Reader
+	config HandsfreeConfig
+
+	// serialize the commands, a single one can be pending
+	cmdMutex sync.Mutex
+
+	mutex          sync.Mutex
+	pending        *pendingCommand
+	started        bool
+	remoteFeatures uint32
+	indicators     map[int]*Indicator
+	clip           bool
+}
+
+type pendingCommand struct {
+	name    string
+	results []*Result
+	done    chan *Result
+}
+
+//Connect start processing the responses and run the service level
+// connection setup
+func (h *Handsfree) Connect() error {
+
+	h.mutex.Lock()
+	if !h.started {
+		h.started = true
+		go h.run()
+	}
+	h.mutex.Unlock()
+
+	results, err := h.Send(NewCommand("+BRSF", h.config.Features))
+	if err != nil {
+		return err
+	}
+	if len(results) > 0 {
+		features, _ := results[0].Int(0)
+		h.mutex.Lock()
+		h.remoteFeatures = uint32(features)
+		h.mutex.Unlock()
+	}
+
+	results, err = h.Send(&Command{Name: "+CIND", Type: CommandTest})
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return errors.New("Missing +CIND response")
+	}
+	h.mutex.Lock()
+	for i, arg := range results[0].Args {
+		h.indicators[i+1] = parseIndicator(arg)
+	}
+	h.mutex.Unlock()
+
+	results, err = h.Send(&Command{Name: "+CIND", Type: CommandRead})
+	if err != nil {
+		return err
+	}
+	if len(results) > 0 {
+		h.mutex.Lock()
+		for i := range results[0].Args {
+			if ind, ok := h.indicators[i+1]; ok {
+				ind.Value, _ = results[0].Int(i)
+			}
+		}
+		h.mutex.Unlock()
+	}
+
+	// enable indicators reporting
+	_, err = h.Send(NewCommand("+CMER", 3, 0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	if h.config.Features&HandsfreeThreeWayCalling != 0 && h.RemoteFeatures()&GatewayThreeWayCalling != 0 {
+		_, err = h.Send(&Command{Name: "+CHLD", Type: CommandTest})
+		if err != nil {
+			return err
+		}
+	}
+
+	if h.config.Features&HandsfreeCLIPresentation != 0 {
+		_, err = h.Send(NewCommand("+CLIP", 1))
+		if err != nil {
+			bluez.GetLogger().Debugf("hfp: calling line identification not available: %s", err.Error())
+		}
+		h.mutex.Lock()
+		h.clip = err == nil
+		h.mutex.Unlock()
+	}
+
+	return nil
+}
+
+//Close the connection
+func (h *Handsfree) Close() error {
+	return h.conn.Close()
+}
+
+//RemoteFeatures return the features advertised by the audio gateway
+func (h *Handsfree) RemoteFeatures() uint32 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.remoteFeatures
+}
+
+//Indicator return the last known value of an indicator
+func (h *Handsfree) Indicator(name string) (int, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, ind := range h.indicators {
+		if ind.Name == name {
+			return ind.Value, nil
+		}
+	}
+	return 0, errors.New("Unknown indicator " + name)
+}
+
+//Answer the incoming call
+func (h *Handsfree) Answer() error {
+	_, err := h.Send(&Command{Name: "A"})
+	return err
+}
+
+//Hangup reject the incoming call or terminate the active one
+func (h *Handsfree) Hangup() error {
+	_, err := h.Send(NewCommand("+CHUP"))
+	return err
+}
+
+//Dial place a call
+func (h *Handsfree) Dial(number string) error {
+	_, err := h.Send(&Command{Name: "D", Args: []string{number + ";"}})
+	return err
+}
+
+//SetSpeakerVolume report the speaker gain to the gateway (0-15)
+func (h *Handsfree) SetSpeakerVolume(volume int) error {
+	if volume < 0 || volume > 15 {
+		return errors.New("Volume out of range")
+	}
+	_, err := h.Send(NewCommand("+VGS", volume))
+	return err
+}
+
+//SetMicrophoneVolume report the microphone gain to the gateway (0-15)
+func (h *Handsfree) SetMicrophoneVolume(volume int) error {
+	if volume < 0 || volume > 15 {
+		return errors.New("Volume out of range")
+	}
+	_, err := h.Send(NewCommand("+VGM", volume))
+	return err
+}
+
+//Send a command and wait for its final result, the information responses
+// are returned
+func (h *Handsfree) Send(cmd *Command) ([]*Result, error) {
+
+	h.cmdMutex.Lock()
+	defer h.cmdMutex.Unlock()
+
+	p := &pendingCommand{
+		name: cmd.Name,
+		done: make(chan *Result, 1),
+	}
+
+	h.mutex.Lock()
+	h.pending = p
+	h.mutex.Unlock()
+
+	defer func() {
+		h.mutex.Lock()
+		h.pending = nil
+		h.mutex.Unlock()
+	}()
+
+	bluez.GetLogger().Debugf("hfp: < %s", cmd.String())
+	_, err := h.conn.Write([]byte(cmd.String() + "\r"))
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case final, ok := <-p.done:
+		if !ok {
+			return nil, errors.New("Connection closed")
+		}
+		if final.Name != ResultOK {
+			return p.results, errors.New(cmd.Name + ": " + final.String())
+		}
+		return p.results, nil
+	case <-time.After(commandTimeout):
+		return nil, errors.New(cmd.Name + ": timeout")
+	}
+}
+
+func (h *Handsfree) run() {
+
+	defer func() {
+		h.mutex.Lock()
+		if h.pending != nil {
+			close(h.pending.done)
+			h.pending = nil
+		}
+		h.mutex.Unlock()
+		if h.config.OnDisconnected != nil {
+			h.config.OnDisconnected()
+		}
+	}()
+
+	for {
+		line, err := h.reader.ReadLine()
+		if err != nil {
+			if err != io.EOF {
+				bluez.GetLogger().Debugf("hfp: read failed: %s", err.Error())
+			}
+			return
+		}
+
+		bluez.GetLogger().Debugf("hfp: > %s", line)
+		r := ParseResult(line)
+
+		h.mutex.Lock()
+		p := h.pending
+		if p != nil && r.IsFinal() {
+			h.pending = nil
+			h.mutex.Unlock()
+			p.done <- r
+			continue
+		}
+		if p != nil && r.Name == p.name {
+			p.results = append(p.results, r)
+			h.mutex.Unlock()
+			continue
+		}
+		h.mutex.Unlock()
+
+		h.unsolicited(r)
+	}
+}
+
+// unsolicited dispatch a response not related to the pending command
+func (h *Handsfree) unsolicited(r *Result) {
+
+	c := h.config
+
+	switch r.Name {
+	case ResultRing:
+		// the number follows with +CLIP, when enabled
+		h.mutex.Lock()
+		clip := h.clip
+		h.mutex.Unlock()
+		if c.OnRing != nil && !clip {
+			c.OnRing("")
+		}
+		return
+	case "+CLIP":
+		if c.OnRing != nil {
+			c.OnRing(r.Str(0))
+		}
+		return
+	case "+CIEV":
+		index, err := r.Int(0)
+		if err != nil {
+			break
+		}
+		value, err := r.Int(1)
+		if err != nil {
+			break
+		}
+		h.mutex.Lock()
+		ind, ok := h.indicators[index]
+		if ok {
+			ind.Value = value
+		}
+		h.mutex.Unlock()
+		if ok && c.OnIndicator != nil {
+			c.OnIndicator(ind.Name, value)
+		}
+		return
+	case "+VGS", "+VGM":
+		volume, err := r.Int(0)
+		if err != nil {
+			break
+		}
+		if r.Name == "+VGS" && c.OnSpeakerVolume != nil {
+			c.OnSpeakerVolume(volume)
+		}
+		if r.Name == "+VGM" && c.OnMicrophoneVolume != nil {
+			c.OnMicrophoneVolume(volume)
+		}
+		return
+	}
+
+	if c.OnResult != nil {
+		c.OnResult(r)
+	}
+}
+
+// parseIndicator parse a +CIND test response entry, eg. ("call",(0,1))
+func parseIndicator(arg string) *Indicator {
+
+	ind := &Indicator{}
+	arg = strings.TrimSpace(arg)
+	arg = strings.TrimSuffix(strings.TrimPrefix(arg, "("), ")")
+
+	parts := splitArgs(arg)
+	if len(parts) == 0 {
+		return ind
+	}
+	ind.Name = strings.Trim(parts[0], "\"")
+	if len(parts) < 2 {
+		return ind
+	}
+
+	values := strings.Trim(parts[1], "()")
+	sep := strings.IndexAny(values, "-,")
+	if sep == -1 {
+		return ind
+	}
+	ind.Min, _ = intArg([]string{values[:sep]}, 0)
+	last := strings.LastIndexAny(values, "-,")
+	ind.Max, _ = intArg([]string{values[last+1:]}, 0)
+	return ind
+}
@@ -0,0 +1,473 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+//ApplicationConfig Application configuration
+type ApplicationConfig struct {
+	// ObjectPath the application root, where the ObjectManager is exported
+	ObjectPath dbus.ObjectPath
+	// CompanyID, ProductID and VersionID form the composition data of the node
+	CompanyID uint16
+	ProductID uint16
+	VersionID uint16
+	// CRPL the minimum number of replay protection list entries, defaults to 32
+	CRPL uint16
+
+	// OnJoinComplete the node has been provisioned or created, token has to be
+	// stored to Attach the node afterwards
+	OnJoinComplete func(token uint64)
+	// OnJoinFailed the provisioning failed
+	OnJoinFailed func(reason string)
+
+	// Agent answer the OOB requests during provisioning, optional
+	Agent *AgentConfig
+	// Provisioner make the application a provisioner, able to add new nodes
+	Provisioner *ProvisionerConfig
+
+	// Conn the connection to bluetooth-meshd, the application objects are
+	// exported on it
+	Conn *dbus.Conn
+	// BusAddress the bus to connect to when Conn is not set, defaults to the
+	// system bus
+	BusAddress string
+}
+
+//ApplicationProperties the properties of a mesh application
+type ApplicationProperties struct {
+	CompanyID uint16
+	ProductID uint16
+	VersionID uint16
+	CRPL      uint16
+}
+
+//ToMap serialize the properties
+func (p *ApplicationProperties) ToMap() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"CompanyID": p.CompanyID,
+		"ProductID": p.ProductID,
+		"VersionID": p.VersionID,
+		"CRPL":      p.CRPL,
+	}, nil
+}
+
+//NewApplication create a mesh application. Add the elements and models,
+// then Join a network or Attach a previously provisioned node
+func NewApplication(config *ApplicationConfig) (*Application, error) {
+
+	if config.ObjectPath == "" {
+		return nil, errors.New("objectPath is required")
+	}
+	if config.CRPL == 0 {
+		config.CRPL = 32
+	}
+
+	if config.Conn == nil {
+		conn, err := bluez.ResolveConnection(config.BusAddress)
+		if err != nil {
+			return nil, err
+		}
+		config.Conn = conn
+	}
+
+	om, err := service.NewObjectManager(config.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	app := &Application{
+		config:        config,
+		objectManager: om,
+		network:       NewNetwork1(),
+		elements:      make([]*Element, 0),
+	}
+	app.network.SetConnection(config.Conn)
+
+	if config.Agent != nil {
+		path := dbus.ObjectPath(app.rootPath() + "/agent")
+		app.agent = newProvisionAgent1(config.Agent, path, config.Conn)
+	}
+	if config.Provisioner != nil {
+		app.provisioner = newProvisioner(app, config.Provisioner)
+	}
+
+	return app, nil
+}
+
+//Application a local mesh node, with its elements and models
+type Application struct {
+	config        *ApplicationConfig
+	objectManager *service.ObjectManager
+	network       *Network1
+	elements      []*Element
+	agent         *ProvisionAgent1
+	provisioner   *provisioner
+
+	mutex   sync.Mutex
+	exposed bool
+	joining chan joinResult
+	token   uint64
+	node    *Node1
+}
+
+type joinResult struct {
+	token uint64
+	err   error
+}
+
+//Interface return the dbus interface name
+func (app *Application) Interface() string {
+	return bluez.MeshApplication1Interface
+}
+
+//Path return the application root path
+func (app *Application) Path() dbus.ObjectPath {
+	return app.config.ObjectPath
+}
+
+//Properties return the application properties
+func (app *Application) Properties() *ApplicationProperties {
+	return &ApplicationProperties{
+		CompanyID: app.config.CompanyID,
+		ProductID: app.config.ProductID,
+		VersionID: app.config.VersionID,
+		CRPL:      app.config.CRPL,
+	}
+}
+
+//AddElement add an element to the node, the first one is the primary element
+func (app *Application) AddElement(location uint16) (*Element, error) {
+
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	if app.exposed {
+		return nil, errors.New("Elements must be added before joining or attaching")
+	}
+
+	index := byte(len(app.elements))
+	e := &Element{
+		app:      app,
+		index:    index,
+		location: location,
+		path:     dbus.ObjectPath(fmt.Sprintf("%s/ele%02x", app.rootPath(), index)),
+		models:   make([]*Model, 0),
+	}
+	app.elements = append(app.elements, e)
+	return e, nil
+}
+
+//Elements return the elements of the node
+func (app *Application) Elements() []*Element {
+	return app.elements
+}
+
+//Node return the attached node, nil before Attach
+func (app *Application) Node() *Node1 {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	return app.node
+}
+
+//Token return the node token, available after Join or Attach
+func (app *Application) Token() uint64 {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	return app.token
+}
+
+func (app *Application) rootPath() string {
+	if app.Path() == "/" {
+		return ""
+	}
+	return string(app.Path())
+}
+
+//Expose the application and its elements to dbus
+func (app *Application) Expose() error {
+
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	if app.exposed {
+		return nil
+	}
+	if len(app.elements) == 0 {
+		return errors.New("At least one element is required")
+	}
+
+	conn := app.config.Conn
+
+	err := conn.Export(app.objectManager, app.Path(), bluez.ObjectManagerInterface)
+	if err != nil {
+		return err
+	}
+
+	err = conn.Export(app, app.Path(), app.Interface())
+	if err != nil {
+		return err
+	}
+
+	rootInterfaces := map[string]bluez.Properties{
+		app.Interface(): app.Properties(),
+	}
+	introspection := []introspect.Interface{
+		//Introspect
+		introspect.IntrospectData,
+		//ObjectManager
+		bluez.ObjectManagerIntrospectData,
+		//Application1
+		{
+			Name:    app.Interface(),
+			Methods: introspect.Methods(app),
+		},
+	}
+
+	if app.provisioner != nil {
+		err = app.provisioner.expose()
+		if err != nil {
+			return err
+		}
+		rootInterfaces[bluez.MeshProvisioner1Interface] = &provisionerProperties{}
+		introspection = append(introspection, app.provisioner.introspection())
+	}
+
+	err = app.objectManager.AddObject(app.Path(), rootInterfaces)
+	if err != nil {
+		return err
+	}
+
+	children := make([]introspect.Node, 0)
+
+	if app.agent != nil {
+		err = app.agent.expose()
+		if err != nil {
+			return err
+		}
+		err = app.objectManager.AddObject(app.agent.Path(), map[string]bluez.Properties{
+			app.agent.Interface(): app.agent.Properties(),
+		})
+		if err != nil {
+			return err
+		}
+		children = append(children, introspect.Node{Name: "agent"})
+	}
+	for _, e := range app.elements {
+
+		err = e.expose()
+		if err != nil {
+			return err
+		}
+
+		err = app.objectManager.AddObject(e.Path(), map[string]bluez.Properties{
+			e.Interface(): e.Properties(),
+		})
+		if err != nil {
+			return err
+		}
+
+		children = append(children, introspect.Node{
+			Name: string(e.Path())[len(app.rootPath())+1:],
+		})
+	}
+
+	node := &introspect.Node{
+		Interfaces: introspection,
+		Children:   children,
+	}
+
+	err = conn.Export(
+		introspect.NewIntrospectable(node),
+		app.Path(),
+		"org.freedesktop.DBus.Introspectable")
+	if err != nil {
+		return err
+	}
+
+	app.exposed = true
+	return nil
+}
+
+//Close remove the application from dbus, the node keeps its configuration
+func (app *Application) Close() {
+
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	if !app.exposed {
+		return
+	}
+
+	conn := app.config.Conn
+	for _, e := range app.elements {
+		e.unexpose()
+	}
+	if app.agent != nil {
+		app.agent.unexpose()
+	}
+	if app.provisioner != nil {
+		app.provisioner.unexpose()
+	}
+	conn.Export(nil, app.Path(), app.Interface())
+	conn.Export(nil, app.Path(), bluez.ObjectManagerInterface)
+	conn.Export(nil, app.Path(), "org.freedesktop.DBus.Introspectable")
+
+	app.exposed = false
+	app.node = nil
+}
+
+// waitJoin run a network call completed by JoinComplete or JoinFailed
+func (app *Application) waitJoin(call func() error) (uint64, error) {
+
+	err := app.Expose()
+	if err != nil {
+		return 0, err
+	}
+
+	result := make(chan joinResult, 1)
+	app.mutex.Lock()
+	if app.joining != nil {
+		app.mutex.Unlock()
+		return 0, errors.New("Join already in progress")
+	}
+	app.joining = result
+	app.mutex.Unlock()
+
+	err = call()
+	if err != nil {
+		app.mutex.Lock()
+		app.joining = nil
+		app.mutex.Unlock()
+		return 0, err
+	}
+
+	r := <-result
+	return r.token, r.err
+}
+
+//Join wait to be provisioned into a network, the device uuid is advertised in
+// the unprovisioned beacons. The returned token identifies the node for Attach
+func (app *Application) Join(uuid [16]byte) (uint64, error) {
+	return app.waitJoin(func() error {
+		return app.network.Join(app.Path(), uuid)
+	})
+}
+
+//CreateNetwork create a new network, the application becomes its first node
+func (app *Application) CreateNetwork(uuid [16]byte) (uint64, error) {
+	return app.waitJoin(func() error {
+		return app.network.CreateNetwork(app.Path(), uuid)
+	})
+}
+
+//Cancel a pending Join
+func (app *Application) Cancel() error {
+	err := app.network.Cancel()
+	app.complete(0, errors.New("Join canceled"))
+	return err
+}
+
+//Attach the application to a provisioned node, the configuration known by
+// the daemon is applied to the models
+func (app *Application) Attach(token uint64) error {
+
+	err := app.Expose()
+	if err != nil {
+		return err
+	}
+
+	path, config, err := app.network.Attach(app.Path(), token)
+	if err != nil {
+		return err
+	}
+
+	for _, ec := range config {
+		if int(ec.Index) >= len(app.elements) {
+			continue
+		}
+		e := app.elements[ec.Index]
+		for _, mc := range ec.Models {
+			e.UpdateModelConfiguration(mc.ID, mc.Config)
+		}
+	}
+
+	node := NewNode1(path)
+	node.SetConnection(app.config.Conn)
+	_, err = node.GetProperties()
+	if err != nil {
+		bluez.GetLogger().Warnf("mesh: failed to load node properties: %s", err.Error())
+	}
+
+	app.mutex.Lock()
+	app.token = token
+	app.node = node
+	app.mutex.Unlock()
+
+	bluez.GetLogger().Debugf("mesh: attached node %s", path)
+	return nil
+}
+
+//Leave remove the node from the network
+func (app *Application) Leave() error {
+
+	app.mutex.Lock()
+	token := app.token
+	app.mutex.Unlock()
+
+	if token == 0 {
+		return errors.New("Node not attached")
+	}
+
+	err := app.network.Leave(token)
+	if err != nil {
+		return err
+	}
+
+	app.mutex.Lock()
+	app.token = 0
+	app.node = nil
+	app.mutex.Unlock()
+	return nil
+}
+
+func (app *Application) complete(token uint64, err error) {
+	app.mutex.Lock()
+	result := app.joining
+	app.joining = nil
+	if err == nil {
+		app.token = token
+	}
+	app.mutex.Unlock()
+
+	if result != nil {
+		result <- joinResult{token, err}
+	}
+}
+
+//JoinComplete called when the node has been provisioned
+func (app *Application) JoinComplete(token uint64) *dbus.Error {
+	bluez.GetLogger().Debugf("mesh: JoinComplete")
+	app.complete(token, nil)
+	if app.config.OnJoinComplete != nil {
+		app.config.OnJoinComplete(token)
+	}
+	return nil
+}
+
+//JoinFailed called when the provisioning failed
+func (app *Application) JoinFailed(reason string) *dbus.Error {
+	bluez.GetLogger().Debugf("mesh: JoinFailed %s", reason)
+	app.complete(0, errors.New("Join failed: "+reason))
+	if app.config.OnJoinFailed != nil {
+		app.config.OnJoinFailed(reason)
+	}
+	return nil
+}
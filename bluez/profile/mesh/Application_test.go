@@ -0,0 +1,282 @@
+package mesh_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile/mesh"
+)
+
+const (
+	testToken = uint64(0x0123456789abcdef)
+	testNode  = dbus.ObjectPath("/org/bluez/mesh/node0123")
+)
+
+// fakeMeshd a bluetooth-meshd holding a single node
+type fakeMeshd struct {
+	conn *dbus.Conn
+
+	mutex sync.Mutex
+	app   dbus.ObjectPath
+	owner string
+	sent  [][]byte
+}
+
+func (d *fakeMeshd) Join(sender dbus.Sender, app dbus.ObjectPath, uuid []byte) *dbus.Error {
+	d.mutex.Lock()
+	d.owner, d.app = string(sender), app
+	d.mutex.Unlock()
+	// provisioning completes after the call returned
+	go d.conn.Object(string(sender), app).Call(bluez.MeshApplication1Interface+".JoinComplete", 0, testToken)
+	return nil
+}
+
+func (d *fakeMeshd) Attach(sender dbus.Sender, app dbus.ObjectPath, token uint64) (dbus.ObjectPath, []mesh.ElementConfiguration, *dbus.Error) {
+	if token != testToken {
+		return "", nil, dbus.NewError("org.bluez.mesh.Error.NotFound", nil)
+	}
+	d.mutex.Lock()
+	d.owner, d.app = string(sender), app
+	d.mutex.Unlock()
+	return testNode, []mesh.ElementConfiguration{
+		{Index: 0, Models: []mesh.ModelConfiguration{
+			{ID: mesh.GenericOnOffServer, Config: map[string]dbus.Variant{
+				"Bindings": dbus.MakeVariant([]uint16{1}),
+			}},
+		}},
+	}, nil
+}
+
+func (d *fakeMeshd) Leave(token uint64) *dbus.Error {
+	return nil
+}
+
+func (d *fakeMeshd) Send(element dbus.ObjectPath, destination uint16, keyIndex uint16, options map[string]dbus.Variant, data []byte) *dbus.Error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.sent = append(d.sent, data)
+	return nil
+}
+
+// call an interface of the application
+func (d *fakeMeshd) call(path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	d.mutex.Lock()
+	owner := d.owner
+	d.mutex.Unlock()
+	return d.conn.Object(owner, path).Call(method, 0, args...)
+}
+
+// dial a private connection to the bus
+func dial(t *testing.T, address string) *dbus.Conn {
+	conn, err := dbus.Dial(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Auth(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Hello(); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// startMeshd run a fake bluetooth-meshd on a private bus
+func startMeshd(t *testing.T) (*bluetest.Daemon, *fakeMeshd) {
+
+	daemon, err := bluetest.StartDaemon()
+	if err != nil {
+		t.Skipf("Cannot start the bus: %s", err.Error())
+	}
+
+	d := &fakeMeshd{conn: dial(t, daemon.Address)}
+	if _, err = d.conn.RequestName(bluez.MeshService, dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.conn.Export(d, mesh.NetworkPath, bluez.MeshNetwork1Interface); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.conn.Export(d, testNode, bluez.MeshNode1Interface); err != nil {
+		t.Fatal(err)
+	}
+	prop.New(d.conn, testNode, map[string]map[string]*prop.Prop{
+		bluez.MeshNode1Interface: {
+			"Addresses": {Value: []uint16{0x0100}},
+		},
+	})
+	return daemon, d
+}
+
+func newTestApplication(t *testing.T, address string) (*mesh.Application, *mesh.Element, *mesh.Model) {
+
+	app, err := mesh.NewApplication(&mesh.ApplicationConfig{
+		ObjectPath: "/bluetest/mesh",
+		CompanyID:  0x05f1,
+		Conn:       dial(t, address),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := app.AddElement(0x0100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := e.AddModel(mesh.GenericOnOffServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.AddModel(mesh.GenericOnOffServer); err == nil {
+		t.Fatal("Expected an error adding a model twice")
+	}
+	if _, err = e.AddVendorModel(0x05f1, 0x0001); err != nil {
+		t.Fatal(err)
+	}
+	return app, e, m
+}
+
+func TestApplicationJoin(t *testing.T) {
+
+	daemon, _ := startMeshd(t)
+	defer daemon.Close()
+
+	app, _, _ := newTestApplication(t, daemon.Address)
+	defer app.Close()
+
+	token, err := app.Join([16]byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != testToken || app.Token() != testToken {
+		t.Fatalf("Expected token %x, got %x", testToken, token)
+	}
+	if _, err = app.AddElement(0); err == nil {
+		t.Fatal("Expected an error adding an element once exposed")
+	}
+}
+
+func TestApplicationObjects(t *testing.T) {
+
+	daemon, d := startMeshd(t)
+	defer daemon.Close()
+
+	app, e, _ := newTestApplication(t, daemon.Address)
+	defer app.Close()
+
+	if err := app.Attach(testToken); err != nil {
+		t.Fatal(err)
+	}
+
+	// the daemon reads the composition of the node from the object manager
+	call := d.call(app.Path(), bluez.ObjectManagerInterface+".GetManagedObjects")
+	if call.Err != nil {
+		t.Fatal(call.Err)
+	}
+	// read the body as received, Store would wrap the values again
+	objects := call.Body[0].(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
+
+	props := objects[app.Path()][bluez.MeshApplication1Interface]
+	if props["CompanyID"].Value() != uint16(0x05f1) || props["CRPL"].Value() != uint16(32) {
+		t.Fatalf("Unexpected application properties %v", props)
+	}
+
+	props = objects[e.Path()][bluez.MeshElement1Interface]
+	if props["Index"].Value() != byte(0) || props["Location"].Value() != uint16(0x0100) {
+		t.Fatalf("Unexpected element properties %v", props)
+	}
+	if sig := props["Models"].Signature().String(); sig != "a(qa{sv})" {
+		t.Fatalf("Expected models as a(qa{sv}), got %s", sig)
+	}
+	if sig := props["VendorModels"].Signature().String(); sig != "a(qqa{sv})" {
+		t.Fatalf("Expected vendor models as a(qqa{sv}), got %s", sig)
+	}
+	models := props["Models"].Value().([][]interface{})
+	if len(models) != 1 || models[0][0] != mesh.GenericOnOffServer {
+		t.Fatalf("Unexpected models %v", models)
+	}
+	vendorModels := props["VendorModels"].Value().([][]interface{})
+	if len(vendorModels) != 1 || vendorModels[0][0] != uint16(0x05f1) || vendorModels[0][1] != uint16(0x0001) {
+		t.Fatalf("Unexpected vendor models %v", vendorModels)
+	}
+}
+
+func TestElementDispatch(t *testing.T) {
+
+	daemon, d := startMeshd(t)
+	defer daemon.Close()
+
+	app, e, m := newTestApplication(t, daemon.Address)
+	defer app.Close()
+
+	configs := make(chan mesh.ModelConfig, 2)
+	m.OnConfig = func(config mesh.ModelConfig) {
+		configs <- config
+	}
+	messages := make(chan *mesh.Message, 2)
+	e.OnMessage(func(msg *mesh.Message) {
+		messages <- msg
+	})
+
+	if err := e.Send(0x0200, 1, []byte{0x82, 0x04, 0x01}); err == nil {
+		t.Fatal("Expected an error sending before attaching")
+	}
+	if err := app.Attach(testToken); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stored configuration is applied on Attach
+	if config := <-configs; len(config.Bindings) != 1 || config.Bindings[0] != 1 {
+		t.Fatalf("Unexpected configuration %+v", config)
+	}
+
+	err := d.call(e.Path(), bluez.MeshElement1Interface+".MessageReceived",
+		uint16(0x0200), uint16(1), dbus.MakeVariant(uint16(0xc000)), []byte{0x82, 0x02, 0x01}).Store()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := <-messages
+	if msg.Source != 0x0200 || msg.Destination != 0xc000 || msg.KeyIndex != 1 || msg.DevKey ||
+		!bytes.Equal(msg.Data, []byte{0x82, 0x02, 0x01}) {
+		t.Fatalf("Unexpected message %+v", msg)
+	}
+
+	err = d.call(e.Path(), bluez.MeshElement1Interface+".DevKeyMessageReceived",
+		uint16(0x0001), true, uint16(0), []byte{0x80, 0x3d}).Store()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = <-messages
+	if !msg.DevKey || !msg.Remote || msg.Destination != 0x0100 {
+		t.Fatalf("Unexpected device key message %+v", msg)
+	}
+
+	err = d.call(e.Path(), bluez.MeshElement1Interface+".UpdateModelConfiguration",
+		mesh.GenericOnOffServer, map[string]dbus.Variant{
+			"PublicationPeriod": dbus.MakeVariant(uint32(1000)),
+			"Subscriptions":     dbus.MakeVariant([]dbus.Variant{dbus.MakeVariant(uint16(0xc001))}),
+		}).Store()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := <-configs
+	if config.PublicationPeriod != 1000 || len(config.Subscriptions) != 1 || config.Subscriptions[0] != uint16(0xc001) {
+		t.Fatalf("Unexpected configuration %+v", config)
+	}
+	if len(config.Bindings) != 1 {
+		t.Fatalf("Expected the bindings kept, got %+v", config)
+	}
+
+	if err = e.Send(0x0200, 1, []byte{0x82, 0x04, 0x01}); err != nil {
+		t.Fatal(err)
+	}
+	d.mutex.Lock()
+	sent := d.sent
+	d.mutex.Unlock()
+	if len(sent) != 1 || !bytes.Equal(sent[0], []byte{0x82, 0x04, 0x01}) {
+		t.Fatalf("Unexpected messages sent %x", sent)
+	}
+}
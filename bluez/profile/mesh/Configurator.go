@@ -0,0 +1,268 @@
+package mesh
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+//DefaultConfigTimeout how long to wait for a configuration status
+const DefaultConfigTimeout = 10 * time.Second
+
+type configRequest struct {
+	source uint16
+	opcode Opcode
+}
+
+//Configurator send configuration messages to remote nodes from a local
+// element, matching the request with the status messages it receives.
+// Handle has to be called from the element OnMessage handler
+type Configurator struct {
+	// Timeout how long to wait for a status, defaults to DefaultConfigTimeout
+	Timeout time.Duration
+
+	element  *Element
+	netIndex uint16
+
+	mutex   sync.Mutex
+	pending map[configRequest]chan []byte
+}
+
+//NewConfigurator create a configurator sending from element, remote
+// nodes are reached through the subnet netIndex
+func NewConfigurator(element *Element, netIndex uint16) *Configurator {
+	return &Configurator{
+		Timeout:  DefaultConfigTimeout,
+		element:  element,
+		netIndex: netIndex,
+		pending:  make(map[configRequest]chan []byte),
+	}
+}
+
+//Handle consume the status messages matching a pending request, return false
+// for any other message
+func (c *Configurator) Handle(msg *Message) bool {
+
+	if !msg.DevKey {
+		return false
+	}
+
+	opcode, params, err := ParseOpcode(msg.Data)
+	if err != nil {
+		return false
+	}
+
+	req := configRequest{msg.Source, opcode}
+	c.mutex.Lock()
+	result, ok := c.pending[req]
+	delete(c.pending, req)
+	c.mutex.Unlock()
+
+	if !ok {
+		return false
+	}
+	result <- params
+	return true
+}
+
+// wait register a request for the status opcode from destination, send it
+// and wait for the response parameters
+func (c *Configurator) wait(destination uint16, status Opcode, send func() error) ([]byte, error) {
+
+	req := configRequest{destination, status}
+	result := make(chan []byte, 1)
+
+	c.mutex.Lock()
+	if _, ok := c.pending[req]; ok {
+		c.mutex.Unlock()
+		return nil, errors.New("Request already in progress")
+	}
+	c.pending[req] = result
+	c.mutex.Unlock()
+
+	cancel := func() {
+		c.mutex.Lock()
+		delete(c.pending, req)
+		c.mutex.Unlock()
+	}
+
+	err := send()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultConfigTimeout
+	}
+
+	select {
+	case params := <-result:
+		return params, nil
+	case <-time.After(timeout):
+		cancel()
+		return nil, errors.New("Timeout waiting for " + status.String())
+	}
+}
+
+// request send a configuration message with the device key of destination
+func (c *Configurator) request(destination uint16, data []byte, status Opcode) ([]byte, error) {
+	return c.wait(destination, status, func() error {
+		return c.element.DevKeySend(destination, true, c.netIndex, data)
+	})
+}
+
+//CompositionData read a page of the composition data of a node
+func (c *Configurator) CompositionData(destination uint16, page byte) (*Composition, error) {
+	params, err := c.request(destination, CompositionDataGet(page), OpCompositionDataStatus)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCompositionDataStatus(params)
+}
+
+//AddAppKey send the application key appIndex, as stored by the daemon, to a
+// node. update replaces a key during a key refresh
+func (c *Configurator) AddAppKey(destination uint16, appIndex uint16, update bool) error {
+
+	node, err := c.element.node()
+	if err != nil {
+		return err
+	}
+
+	params, err := c.wait(destination, OpAppKeyStatus, func() error {
+		return node.AddAppKey(c.element.Path(), destination, appIndex, c.netIndex, update)
+	})
+	if err != nil {
+		return err
+	}
+
+	status, err := ParseAppKeyStatus(params)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+//DeleteAppKey remove an application key from a node
+func (c *Configurator) DeleteAppKey(destination uint16, appIndex uint16) error {
+	params, err := c.request(destination, AppKeyDelete(c.netIndex, appIndex), OpAppKeyStatus)
+	if err != nil {
+		return err
+	}
+	status, err := ParseAppKeyStatus(params)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+func (c *Configurator) modelApp(destination uint16, data []byte) error {
+	params, err := c.request(destination, data, OpModelAppStatus)
+	if err != nil {
+		return err
+	}
+	status, err := ParseModelAppStatus(params)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+//BindModel bind an application key to a model, element is the unicast address
+// of the element holding the model
+func (c *Configurator) BindModel(destination uint16, element uint16, appIndex uint16, model ModelIdentifier) error {
+	return c.modelApp(destination, ModelAppBind(element, appIndex, model))
+}
+
+//UnbindModel remove an application key binding from a model
+func (c *Configurator) UnbindModel(destination uint16, element uint16, appIndex uint16, model ModelIdentifier) error {
+	return c.modelApp(destination, ModelAppUnbind(element, appIndex, model))
+}
+
+//SetPublication set the publication parameters of a model
+func (c *Configurator) SetPublication(destination uint16, element uint16, pub Publication, model ModelIdentifier) error {
+	params, err := c.request(destination, ModelPublicationSet(element, pub, model), OpModelPublicationStatus)
+	if err != nil {
+		return err
+	}
+	status, err := ParseModelPublicationStatus(params)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+//Publication read the publication parameters of a model
+func (c *Configurator) Publication(destination uint16, element uint16, model ModelIdentifier) (*Publication, error) {
+	params, err := c.request(destination, ModelPublicationGet(element, model), OpModelPublicationStatus)
+	if err != nil {
+		return nil, err
+	}
+	status, err := ParseModelPublicationStatus(params)
+	if err != nil {
+		return nil, err
+	}
+	if err = status.Err(); err != nil {
+		return nil, err
+	}
+	return &status.Publication, nil
+}
+
+func (c *Configurator) subscription(destination uint16, data []byte) error {
+	params, err := c.request(destination, data, OpModelSubscriptionStatus)
+	if err != nil {
+		return err
+	}
+	status, err := ParseModelSubscriptionStatus(params)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+//AddSubscription subscribe a model to a group address
+func (c *Configurator) AddSubscription(destination uint16, element uint16, address uint16, model ModelIdentifier) error {
+	return c.subscription(destination, ModelSubscriptionAdd(element, address, model))
+}
+
+//AddVirtualSubscription subscribe a model to a virtual address
+func (c *Configurator) AddVirtualSubscription(destination uint16, element uint16, label [16]byte, model ModelIdentifier) error {
+	return c.subscription(destination, ModelSubscriptionVirtualAddressAdd(element, label, model))
+}
+
+//DeleteSubscription remove a group address from the subscriptions of a model
+func (c *Configurator) DeleteSubscription(destination uint16, element uint16, address uint16, model ModelIdentifier) error {
+	return c.subscription(destination, ModelSubscriptionDelete(element, address, model))
+}
+
+//DefaultTTL read the default TTL of a node
+func (c *Configurator) DefaultTTL(destination uint16) (byte, error) {
+	params, err := c.request(destination, DefaultTTLGet(), OpDefaultTTLStatus)
+	if err != nil {
+		return 0, err
+	}
+	if len(params) != 1 {
+		return 0, errors.New("Invalid Default TTL Status length")
+	}
+	return params[0], nil
+}
+
+//SetDefaultTTL set the default TTL of a node
+func (c *Configurator) SetDefaultTTL(destination uint16, ttl byte) error {
+	params, err := c.request(destination, DefaultTTLSet(ttl), OpDefaultTTLStatus)
+	if err != nil {
+		return err
+	}
+	if len(params) != 1 || params[0] != ttl {
+		return errors.New("Default TTL not set")
+	}
+	return nil
+}
+
+//ResetNode remove a node from the network
+func (c *Configurator) ResetNode(destination uint16) error {
+	_, err := c.request(destination, NodeReset(), OpNodeResetStatus)
+	return err
+}
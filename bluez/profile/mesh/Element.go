@@ -0,0 +1,300 @@
+package mesh
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//Message an access layer message received by an element
+type Message struct {
+	// Source the unicast address of the sender
+	Source uint16
+	// Destination the unicast or group address the message was sent to, zero
+	// for virtual addresses
+	Destination uint16
+	// VirtualLabel the label uuid of a virtual destination address
+	VirtualLabel []byte
+	// KeyIndex the application key index, or the network key index for
+	// messages encrypted with a device key
+	KeyIndex uint16
+	// DevKey the message is encrypted with a device key
+	DevKey bool
+	// Remote the device key is the one of the sender rather than the local one
+	Remote bool
+	// Data the access layer payload, opcode included
+	Data []byte
+}
+
+//MessageHandler called for every message received by an element
+type MessageHandler func(msg *Message)
+
+//ElementProperties the properties of an element
+type ElementProperties struct {
+	Index        byte
+	Models       []modelEntry
+	VendorModels []vendorModelEntry
+	Location     uint16
+}
+
+// a(qa{sv})
+type modelEntry struct {
+	ID      uint16
+	Options map[string]dbus.Variant
+}
+
+// a(qqa{sv})
+type vendorModelEntry struct {
+	Vendor  uint16
+	ID      uint16
+	Options map[string]dbus.Variant
+}
+
+//ToMap serialize the properties
+func (p *ElementProperties) ToMap() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"Index":        p.Index,
+		"Models":       p.Models,
+		"VendorModels": p.VendorModels,
+		"Location":     p.Location,
+	}, nil
+}
+
+//Element an addressable part of a node, holding a set of models
+type Element struct {
+	app      *Application
+	index    byte
+	location uint16
+	path     dbus.ObjectPath
+	models   []*Model
+
+	mutex         sync.Mutex
+	onMessage     MessageHandler
+	subscriptions []*subscription
+}
+
+//Interface return the dbus interface name
+func (e *Element) Interface() string {
+	return bluez.MeshElement1Interface
+}
+
+//Path return the element object path
+func (e *Element) Path() dbus.ObjectPath {
+	return e.path
+}
+
+//Index return the element index, 0 for the primary element
+func (e *Element) Index() byte {
+	return e.index
+}
+
+//Models return the models of the element
+func (e *Element) Models() []*Model {
+	return e.models
+}
+
+//OnMessage set the handler for the messages received by the element
+func (e *Element) OnMessage(handler MessageHandler) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.onMessage = handler
+}
+
+//AddModel add a SIG defined model, eg. GenericOnOffServer. The configuration
+// server is provided by the daemon and must not be added
+func (e *Element) AddModel(id uint16) (*Model, error) {
+	return e.addModel(&Model{ID: id})
+}
+
+//AddVendorModel add a vendor model identified by a company and model id
+func (e *Element) AddVendorModel(vendor uint16, id uint16) (*Model, error) {
+	return e.addModel(&Model{ID: id, Vendor: vendor, vendor: true})
+}
+
+func (e *Element) addModel(m *Model) (*Model, error) {
+
+	e.app.mutex.Lock()
+	exposed := e.app.exposed
+	e.app.mutex.Unlock()
+	if exposed {
+		return nil, errors.New("Models must be added before joining or attaching")
+	}
+
+	for _, other := range e.models {
+		if other.ID == m.ID && other.vendor == m.vendor && other.Vendor == m.Vendor {
+			return nil, errors.New("Model already added")
+		}
+	}
+
+	m.element = e
+	m.PublishEnabled = true
+	m.SubscribeEnabled = true
+	e.models = append(e.models, m)
+	return m, nil
+}
+
+//Model return a model of the element, vendor is ignored for SIG models
+func (e *Element) Model(id uint16, vendor uint16, isVendor bool) *Model {
+	for _, m := range e.models {
+		if m.ID == id && m.vendor == isVendor && (!isVendor || m.Vendor == vendor) {
+			return m
+		}
+	}
+	return nil
+}
+
+//Properties return the element properties
+func (e *Element) Properties() *ElementProperties {
+	p := &ElementProperties{
+		Index:        e.index,
+		Location:     e.location,
+		Models:       []modelEntry{},
+		VendorModels: []vendorModelEntry{},
+	}
+	for _, m := range e.models {
+		if m.vendor {
+			p.VendorModels = append(p.VendorModels, vendorModelEntry{m.Vendor, m.ID, m.options()})
+		} else {
+			p.Models = append(p.Models, modelEntry{m.ID, m.options()})
+		}
+	}
+	return p
+}
+
+func (e *Element) expose() error {
+
+	conn := e.app.config.Conn
+
+	err := conn.Export(e, e.Path(), e.Interface())
+	if err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			//Introspect
+			introspect.IntrospectData,
+			//Element1
+			{
+				Name:    e.Interface(),
+				Methods: introspect.Methods(e),
+			},
+		},
+	}
+
+	return conn.Export(
+		introspect.NewIntrospectable(node),
+		e.Path(),
+		"org.freedesktop.DBus.Introspectable")
+}
+
+func (e *Element) unexpose() {
+	conn := e.app.config.Conn
+	conn.Export(nil, e.Path(), e.Interface())
+	conn.Export(nil, e.Path(), "org.freedesktop.DBus.Introspectable")
+}
+
+func (e *Element) node() (*Node1, error) {
+	node := e.app.Node()
+	if node == nil {
+		return nil, errors.New("Node not attached")
+	}
+	return node, nil
+}
+
+//Send a message encrypted with an application key
+func (e *Element) Send(destination uint16, appKeyIndex uint16, data []byte) error {
+	node, err := e.node()
+	if err != nil {
+		return err
+	}
+	return node.Send(e.Path(), destination, appKeyIndex, nil, data)
+}
+
+//DevKeySend send a message encrypted with a device key, eg. a configuration
+// message. remote selects the device key of the destination
+func (e *Element) DevKeySend(destination uint16, remote bool, netIndex uint16, data []byte) error {
+	node, err := e.node()
+	if err != nil {
+		return err
+	}
+	return node.DevKeySend(e.Path(), destination, remote, netIndex, nil, data)
+}
+
+func (e *Element) dispatch(msg *Message) {
+
+	e.publish(msg)
+
+	e.mutex.Lock()
+	handler := e.onMessage
+	e.mutex.Unlock()
+
+	if handler == nil {
+		bluez.GetLogger().Debugf("mesh: no handler for message from %04x", msg.Source)
+		return
+	}
+	handler(msg)
+}
+
+//MessageReceived called for messages encrypted with an application key
+func (e *Element) MessageReceived(source uint16, keyIndex uint16, destination dbus.Variant, data []byte) *dbus.Error {
+
+	msg := &Message{
+		Source:   source,
+		KeyIndex: keyIndex,
+		Data:     data,
+	}
+	switch dst := destination.Value().(type) {
+	case uint16:
+		msg.Destination = dst
+	case []byte:
+		msg.VirtualLabel = dst
+	}
+
+	e.dispatch(msg)
+	return nil
+}
+
+//DevKeyMessageReceived called for messages encrypted with a device key
+func (e *Element) DevKeyMessageReceived(source uint16, remote bool, netIndex uint16, data []byte) *dbus.Error {
+	e.dispatch(&Message{
+		Source:      source,
+		Destination: e.address(),
+		KeyIndex:    netIndex,
+		DevKey:      true,
+		Remote:      remote,
+		Data:        data,
+	})
+	return nil
+}
+
+// address the unicast address of the element, if known
+func (e *Element) address() uint16 {
+	node := e.app.Node()
+	if node == nil || len(node.Properties.Addresses) == 0 {
+		return 0
+	}
+	return node.Properties.Addresses[0] + uint16(e.index)
+}
+
+//UpdateModelConfiguration called when a configuration client changes the
+// bindings, publication or subscriptions of a model
+func (e *Element) UpdateModelConfiguration(modelID uint16, config map[string]dbus.Variant) *dbus.Error {
+
+	vendor, isVendor := uint16(0), false
+	if v, ok := config["Vendor"]; ok {
+		vendor, isVendor = v.Value().(uint16)
+	}
+
+	m := e.Model(modelID, vendor, isVendor)
+	if m == nil {
+		bluez.GetLogger().Warnf("mesh: configuration for unknown model %04x", modelID)
+		return nil
+	}
+
+	m.updateConfig(config)
+	return nil
+}
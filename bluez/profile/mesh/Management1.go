@@ -0,0 +1,126 @@
+package mesh
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// NewManagement1 create a new Management1 client, available on the nodes of
+// provisioner applications
+func NewManagement1(nodePath dbus.ObjectPath) *Management1 {
+	a := new(Management1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  bluez.MeshService,
+			Iface: bluez.MeshManagement1Interface,
+			Path:  string(nodePath),
+			Bus:   bluez.SystemBus,
+		},
+	)
+	return a
+}
+
+// Management1 client
+type Management1 struct {
+	client *bluez.Client
+}
+
+// Close the connection
+func (a *Management1) Close() {
+	a.client.Disconnect()
+}
+
+//SetConnection send the calls on conn, the connection the registered objects
+// are exported on
+func (a *Management1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+//UnprovisionedScan start scanning for unprovisioned devices, results are
+// delivered to Provisioner1.ScanResult. A zero timeout scans until canceled
+func (a *Management1) UnprovisionedScan(seconds uint16) error {
+	options := map[string]interface{}{}
+	if seconds > 0 {
+		options["Seconds"] = seconds
+	}
+	return a.client.Call("UnprovisionedScan", 0, options).Store()
+}
+
+//UnprovisionedScanCancel stop scanning
+func (a *Management1) UnprovisionedScanCancel() error {
+	return a.client.Call("UnprovisionedScanCancel", 0).Store()
+}
+
+//AddNode provision the device with the given uuid, the outcome is reported
+// with Provisioner1.AddNodeComplete or AddNodeFailed
+func (a *Management1) AddNode(uuid [16]byte, options map[string]interface{}) error {
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	return a.client.Call("AddNode", 0, uuid[:], options).Store()
+}
+
+//CreateSubnet generate a new network key with the given index
+func (a *Management1) CreateSubnet(netIndex uint16) error {
+	return a.client.Call("CreateSubnet", 0, netIndex).Store()
+}
+
+//ImportSubnet add an existing network key
+func (a *Management1) ImportSubnet(netIndex uint16, key [16]byte) error {
+	return a.client.Call("ImportSubnet", 0, netIndex, key[:]).Store()
+}
+
+//UpdateSubnet generate a new value for a network key, starting a key refresh
+func (a *Management1) UpdateSubnet(netIndex uint16) error {
+	return a.client.Call("UpdateSubnet", 0, netIndex).Store()
+}
+
+//DeleteSubnet remove a network key and the application keys bound to it
+func (a *Management1) DeleteSubnet(netIndex uint16) error {
+	return a.client.Call("DeleteSubnet", 0, netIndex).Store()
+}
+
+//SetKeyPhase move a subnet to a key refresh phase, eg. KeyRefreshPhaseTwo
+func (a *Management1) SetKeyPhase(netIndex uint16, phase byte) error {
+	return a.client.Call("SetKeyPhase", 0, netIndex, phase).Store()
+}
+
+//CreateAppKey generate a new application key bound to a network key
+func (a *Management1) CreateAppKey(netIndex uint16, appIndex uint16) error {
+	return a.client.Call("CreateAppKey", 0, netIndex, appIndex).Store()
+}
+
+//ImportAppKey add an existing application key
+func (a *Management1) ImportAppKey(netIndex uint16, appIndex uint16, key [16]byte) error {
+	return a.client.Call("ImportAppKey", 0, netIndex, appIndex, key[:]).Store()
+}
+
+//UpdateAppKey generate a new value for an application key during a key refresh
+func (a *Management1) UpdateAppKey(appIndex uint16) error {
+	return a.client.Call("UpdateAppKey", 0, appIndex).Store()
+}
+
+//DeleteAppKey remove an application key
+func (a *Management1) DeleteAppKey(appIndex uint16) error {
+	return a.client.Call("DeleteAppKey", 0, appIndex).Store()
+}
+
+//ImportRemoteNode add the device key of a node provisioned elsewhere
+func (a *Management1) ImportRemoteNode(primary uint16, count byte, key [16]byte) error {
+	return a.client.Call("ImportRemoteNode", 0, primary, count, key[:]).Store()
+}
+
+//DeleteRemoteNode remove the device key of a node
+func (a *Management1) DeleteRemoteNode(primary uint16, count byte) error {
+	return a.client.Call("DeleteRemoteNode", 0, primary, count).Store()
+}
+
+//ExportKeys return all the keys known to the node
+func (a *Management1) ExportKeys() (*KeyExport, error) {
+	raw := map[string]dbus.Variant{}
+	err := a.client.Call("ExportKeys", 0).Store(&raw)
+	if err != nil {
+		return nil, err
+	}
+	return parseKeyExport(raw), nil
+}
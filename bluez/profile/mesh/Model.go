@@ -0,0 +1,102 @@
+package mesh
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/godbus/dbus"
+)
+
+//ModelConfig the configuration of a model set by a configuration client
+type ModelConfig struct {
+	// Bindings the application key indexes bound to the model
+	Bindings []uint16
+	// PublicationPeriod the publication period in milliseconds
+	PublicationPeriod uint32
+	// Subscriptions group addresses (uint16) or virtual labels ([]byte)
+	Subscriptions []interface{}
+}
+
+//Model a SIG or vendor model of an element
+type Model struct {
+	ID uint16
+	// Vendor the company identifier of vendor models
+	Vendor uint16
+	// PublishEnabled the model supports publication
+	PublishEnabled bool
+	// SubscribeEnabled the model supports subscriptions
+	SubscribeEnabled bool
+	// OnConfig called when the configuration changes
+	OnConfig func(config ModelConfig)
+
+	element *Element
+	vendor  bool
+
+	mutex  sync.Mutex
+	config ModelConfig
+}
+
+//IsVendor return true for vendor models
+func (m *Model) IsVendor() bool {
+	return m.vendor
+}
+
+//Element return the element holding the model
+func (m *Model) Element() *Element {
+	return m.element
+}
+
+//Config return the current configuration
+func (m *Model) Config() ModelConfig {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.config
+}
+
+func (m *Model) options() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"Publish":   dbus.MakeVariant(m.PublishEnabled),
+		"Subscribe": dbus.MakeVariant(m.SubscribeEnabled),
+	}
+}
+
+func (m *Model) updateConfig(values map[string]dbus.Variant) {
+
+	m.mutex.Lock()
+	for key, val := range values {
+		switch key {
+		case "Bindings":
+			m.config.Bindings, _ = val.Value().([]uint16)
+		case "PublicationPeriod":
+			m.config.PublicationPeriod, _ = val.Value().(uint32)
+		case "Subscriptions":
+			m.config.Subscriptions = []interface{}{}
+			list, _ := val.Value().([]dbus.Variant)
+			for _, sub := range list {
+				m.config.Subscriptions = append(m.config.Subscriptions, sub.Value())
+			}
+		}
+	}
+	config := m.config
+	m.mutex.Unlock()
+
+	if m.OnConfig != nil {
+		m.OnConfig(config)
+	}
+}
+
+//Publish send a message to the publication address of the model
+func (m *Model) Publish(data []byte) error {
+	node, err := m.element.node()
+	if err != nil {
+		return err
+	}
+	if !m.PublishEnabled {
+		return errors.New("Publication not supported by the model")
+	}
+	options := map[string]interface{}{}
+	if m.vendor {
+		options["Vendor"] = m.Vendor
+	}
+	return node.Publish(m.element.Path(), m.ID, options, data)
+}
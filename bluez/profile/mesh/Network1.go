@@ -0,0 +1,91 @@
+package mesh
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//NetworkPath the path of the bluetooth-meshd Network1 object
+const NetworkPath = "/org/bluez/mesh"
+
+// NewNetwork1 create a new Network1 client
+func NewNetwork1() *Network1 {
+	a := new(Network1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  bluez.MeshService,
+			Iface: bluez.MeshNetwork1Interface,
+			Path:  NetworkPath,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	return a
+}
+
+// Network1 client
+type Network1 struct {
+	client *bluez.Client
+}
+
+//ModelConfiguration the configuration of a model as stored by the daemon
+type ModelConfiguration struct {
+	ID     uint16
+	Config map[string]dbus.Variant
+}
+
+//ElementConfiguration the configuration of the models of an element
+type ElementConfiguration struct {
+	Index  byte
+	Models []ModelConfiguration
+}
+
+// Close the connection
+func (a *Network1) Close() {
+	a.client.Disconnect()
+}
+
+//SetConnection send the calls on conn, the connection the registered objects
+// are exported on
+func (a *Network1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+//Join request the provisioning of a new node, the application at appRoot is
+// notified with JoinComplete or JoinFailed
+func (a *Network1) Join(appRoot dbus.ObjectPath, uuid [16]byte) error {
+	return a.client.Call("Join", 0, appRoot, uuid[:]).Store()
+}
+
+//Cancel a pending Join
+func (a *Network1) Cancel() error {
+	return a.client.Call("Cancel", 0).Store()
+}
+
+//Attach a provisioned node to the application at appRoot, returning the node
+// object path and the current configuration of its models
+func (a *Network1) Attach(appRoot dbus.ObjectPath, token uint64) (dbus.ObjectPath, []ElementConfiguration, error) {
+	var node dbus.ObjectPath
+	config := []ElementConfiguration{}
+	err := a.client.Call("Attach", 0, appRoot, token).Store(&node, &config)
+	return node, config, err
+}
+
+//Leave remove the node from the network and delete its configuration
+func (a *Network1) Leave(token uint64) error {
+	return a.client.Call("Leave", 0, token).Store()
+}
+
+//CreateNetwork create a new network with the application as its first node
+// and provisioner, the token is returned with JoinComplete
+func (a *Network1) CreateNetwork(appRoot dbus.ObjectPath, uuid [16]byte) error {
+	return a.client.Call("CreateNetwork", 0, appRoot, uuid[:]).Store()
+}
+
+//Import create a node from an existing provisioning data, eg. to migrate a
+// node from another stack, the token is returned with JoinComplete
+func (a *Network1) Import(appRoot dbus.ObjectPath, uuid [16]byte, devKey [16]byte, netKey [16]byte, netIndex uint16, flags map[string]interface{}, ivIndex uint32, unicast uint16) error {
+	if flags == nil {
+		flags = map[string]interface{}{}
+	}
+	return a.client.Call("Import", 0, appRoot, uuid[:], devKey[:], netKey[:], netIndex, flags, ivIndex, unicast).Store()
+}
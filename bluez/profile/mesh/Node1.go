@@ -0,0 +1,95 @@
+package mesh
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// NewNode1 create a new Node1 client, the path is returned by Network1.Attach
+func NewNode1(path dbus.ObjectPath) *Node1 {
+	a := new(Node1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  bluez.MeshService,
+			Iface: bluez.MeshNode1Interface,
+			Path:  string(path),
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = new(Node1Properties)
+	return a
+}
+
+// Node1 client
+type Node1 struct {
+	client     *bluez.Client
+	Properties *Node1Properties
+}
+
+//Node1Properties exposed properties of a Node1
+type Node1Properties struct {
+	Features              map[string]dbus.Variant
+	Beacon                bool
+	IvUpdate              bool
+	IvIndex               uint32
+	SecondsSinceLastHeard uint32
+	Addresses             []uint16
+	SequenceNumber        uint32
+}
+
+// Close the connection
+func (a *Node1) Close() {
+	a.client.Disconnect()
+}
+
+//SetConnection send the calls on conn, the connection the registered objects
+// are exported on
+func (a *Node1) SetConnection(conn *dbus.Conn) {
+	a.client.SetConnection(conn)
+}
+
+//Path return the node object path
+func (a *Node1) Path() dbus.ObjectPath {
+	return dbus.ObjectPath(a.client.Config.Path)
+}
+
+//GetProperties load all available properties
+func (a *Node1) GetProperties() (*Node1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+//Send a message encrypted with an application key from a local element
+func (a *Node1) Send(element dbus.ObjectPath, destination uint16, keyIndex uint16, options map[string]interface{}, data []byte) error {
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	return a.client.Call("Send", 0, element, destination, keyIndex, options, data).Store()
+}
+
+//DevKeySend send a message encrypted with a device key, remote selects the
+// device key of the destination rather than the local one
+func (a *Node1) DevKeySend(element dbus.ObjectPath, destination uint16, remote bool, netIndex uint16, options map[string]interface{}, data []byte) error {
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	return a.client.Call("DevKeySend", 0, element, destination, remote, netIndex, options, data).Store()
+}
+
+//AddNetKey send a network key known by the local node to a remote node
+func (a *Node1) AddNetKey(element dbus.ObjectPath, destination uint16, subnetIndex uint16, netIndex uint16, update bool) error {
+	return a.client.Call("AddNetKey", 0, element, destination, subnetIndex, netIndex, update).Store()
+}
+
+//AddAppKey send an application key known by the local node to a remote node
+func (a *Node1) AddAppKey(element dbus.ObjectPath, destination uint16, appIndex uint16, netIndex uint16, update bool) error {
+	return a.client.Call("AddAppKey", 0, element, destination, appIndex, netIndex, update).Store()
+}
+
+//Publish send a message to the publication address of a model
+func (a *Node1) Publish(element dbus.ObjectPath, model uint16, options map[string]interface{}, data []byte) error {
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	return a.client.Call("Publish", 0, element, model, options, data).Store()
+}
@@ -0,0 +1,252 @@
+package mesh
+
+import (
+	"errors"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// Provisioning capabilities and requested actions
+const (
+	AgentBlink      = "blink"
+	AgentBeep       = "beep"
+	AgentVibrate    = "vibrate"
+	AgentOutNumeric = "out-numeric"
+	AgentOutAlpha   = "out-alpha"
+	AgentPush       = "push"
+	AgentTwist      = "twist"
+	AgentInNumeric  = "in-numeric"
+	AgentInAlpha    = "in-alpha"
+	AgentStaticOOB  = "static-oob"
+	AgentPublicOOB  = "public-oob"
+)
+
+//AgentConfig the provisioning agent configuration, the callbacks matching the
+// declared capabilities have to be set
+type AgentConfig struct {
+	// Capabilities the supported OOB methods, eg. AgentOutNumeric
+	Capabilities []string
+	// OutOfBandInfo where the OOB information is available, eg. "on-box"
+	OutOfBandInfo []string
+	// URI where the OOB information can be retrieved
+	URI string
+
+	// OnDisplayString show an alphanumeric value
+	OnDisplayString func(value string) error
+	// OnDisplayNumeric show a number or perform an action a number of times,
+	// action is eg. AgentOutNumeric or AgentBlink
+	OnDisplayNumeric func(action string, number uint32) error
+	// OnPromptNumeric ask the user for the number shown by the remote device,
+	// or the number of times action has been performed
+	OnPromptNumeric func(action string) (uint32, error)
+	// OnPromptStatic return the static OOB value or the alphanumeric value
+	// shown by the remote device, action is AgentStaticOOB or AgentInAlpha
+	OnPromptStatic func(action string) ([16]byte, error)
+	// OnPrivateKey return the private key, with the public-oob capability
+	OnPrivateKey func() ([]byte, error)
+	// OnPublicKey return the public key of the remote device, with public-oob
+	OnPublicKey func() ([]byte, error)
+	// OnCancel the provisioning has been canceled
+	OnCancel func()
+}
+
+//ProvisionAgent1Properties the properties of a provisioning agent
+type ProvisionAgent1Properties struct {
+	Capabilities  []string
+	OutOfBandInfo []string
+	URI           string
+}
+
+//ToMap serialize the properties
+func (p *ProvisionAgent1Properties) ToMap() (map[string]interface{}, error) {
+	m := map[string]interface{}{
+		"Capabilities":  p.Capabilities,
+		"OutOfBandInfo": p.OutOfBandInfo,
+	}
+	if p.URI != "" {
+		m["URI"] = p.URI
+	}
+	return m, nil
+}
+
+//ProvisionAgent1 the exported agent answering the provisioning requests
+type ProvisionAgent1 struct {
+	config *AgentConfig
+	path   dbus.ObjectPath
+	conn   *dbus.Conn
+}
+
+func newProvisionAgent1(config *AgentConfig, path dbus.ObjectPath, conn *dbus.Conn) *ProvisionAgent1 {
+	if config.Capabilities == nil {
+		config.Capabilities = []string{}
+	}
+	if config.OutOfBandInfo == nil {
+		config.OutOfBandInfo = []string{}
+	}
+	return &ProvisionAgent1{config, path, conn}
+}
+
+//Interface return the dbus interface name
+func (a *ProvisionAgent1) Interface() string {
+	return bluez.MeshProvisionAgent1Interface
+}
+
+//Path return the agent object path
+func (a *ProvisionAgent1) Path() dbus.ObjectPath {
+	return a.path
+}
+
+//Properties return the agent properties
+func (a *ProvisionAgent1) Properties() *ProvisionAgent1Properties {
+	return &ProvisionAgent1Properties{
+		Capabilities:  a.config.Capabilities,
+		OutOfBandInfo: a.config.OutOfBandInfo,
+		URI:           a.config.URI,
+	}
+}
+
+func (a *ProvisionAgent1) expose() error {
+
+	err := a.conn.Export(a, a.Path(), a.Interface())
+	if err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			//Introspect
+			introspect.IntrospectData,
+			//ProvisionAgent1
+			{
+				Name:    a.Interface(),
+				Methods: introspect.Methods(a),
+			},
+		},
+	}
+
+	return a.conn.Export(
+		introspect.NewIntrospectable(node),
+		a.Path(),
+		"org.freedesktop.DBus.Introspectable")
+}
+
+func (a *ProvisionAgent1) unexpose() {
+	a.conn.Export(nil, a.Path(), a.Interface())
+	a.conn.Export(nil, a.Path(), "org.freedesktop.DBus.Introspectable")
+}
+
+func agentError(err error) *dbus.Error {
+	return dbus.NewError("org.bluez.mesh.Error.Failed", []interface{}{err.Error()})
+}
+
+var errNotSupported = errors.New("Not supported")
+
+//PrivateKey return the private key used for public OOB provisioning
+func (a *ProvisionAgent1) PrivateKey() ([]byte, *dbus.Error) {
+	if a.config.OnPrivateKey == nil {
+		return nil, agentError(errNotSupported)
+	}
+	var key []byte
+	err := bluez.SafeCall(nil, "OnPrivateKey", func() (err error) {
+		key, err = a.config.OnPrivateKey()
+		return err
+	})
+	if err != nil {
+		return nil, agentError(err)
+	}
+	return key, nil
+}
+
+//PublicKey return the public key of the remote device
+func (a *ProvisionAgent1) PublicKey() ([]byte, *dbus.Error) {
+	if a.config.OnPublicKey == nil {
+		return nil, agentError(errNotSupported)
+	}
+	var key []byte
+	err := bluez.SafeCall(nil, "OnPublicKey", func() (err error) {
+		key, err = a.config.OnPublicKey()
+		return err
+	})
+	if err != nil {
+		return nil, agentError(err)
+	}
+	return key, nil
+}
+
+//DisplayString show an alphanumeric value to the user
+func (a *ProvisionAgent1) DisplayString(value string) *dbus.Error {
+	bluez.GetLogger().Debugf("mesh: DisplayString %s", value)
+	if a.config.OnDisplayString == nil {
+		return agentError(errNotSupported)
+	}
+	err := bluez.SafeCall(nil, "OnDisplayString", func() error {
+		return a.config.OnDisplayString(value)
+	})
+	if err != nil {
+		return agentError(err)
+	}
+	return nil
+}
+
+//DisplayNumeric show a number or perform an action
+func (a *ProvisionAgent1) DisplayNumeric(action string, number uint32) *dbus.Error {
+	bluez.GetLogger().Debugf("mesh: DisplayNumeric %s %d", action, number)
+	if a.config.OnDisplayNumeric == nil {
+		return agentError(errNotSupported)
+	}
+	err := bluez.SafeCall(nil, "OnDisplayNumeric", func() error {
+		return a.config.OnDisplayNumeric(action, number)
+	})
+	if err != nil {
+		return agentError(err)
+	}
+	return nil
+}
+
+//PromptNumeric ask the user for a number
+func (a *ProvisionAgent1) PromptNumeric(action string) (uint32, *dbus.Error) {
+	bluez.GetLogger().Debugf("mesh: PromptNumeric %s", action)
+	if a.config.OnPromptNumeric == nil {
+		return 0, agentError(errNotSupported)
+	}
+	var number uint32
+	err := bluez.SafeCall(nil, "OnPromptNumeric", func() (err error) {
+		number, err = a.config.OnPromptNumeric(action)
+		return err
+	})
+	if err != nil {
+		return 0, agentError(err)
+	}
+	return number, nil
+}
+
+//PromptStatic ask for a 16 bytes static value
+func (a *ProvisionAgent1) PromptStatic(action string) ([]byte, *dbus.Error) {
+	bluez.GetLogger().Debugf("mesh: PromptStatic %s", action)
+	if a.config.OnPromptStatic == nil {
+		return nil, agentError(errNotSupported)
+	}
+	var value [16]byte
+	err := bluez.SafeCall(nil, "OnPromptStatic", func() (err error) {
+		value, err = a.config.OnPromptStatic(action)
+		return err
+	})
+	if err != nil {
+		return nil, agentError(err)
+	}
+	return value[:], nil
+}
+
+//Cancel the pending request
+func (a *ProvisionAgent1) Cancel() *dbus.Error {
+	bluez.GetLogger().Debugf("mesh: agent Cancel")
+	if a.config.OnCancel != nil {
+		bluez.SafeCall(nil, "OnCancel", func() error {
+			a.config.OnCancel()
+			return nil
+		})
+	}
+	return nil
+}
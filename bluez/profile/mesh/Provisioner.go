@@ -0,0 +1,469 @@
+package mesh
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//UnprovisionedDevice a device found by an unprovisioned scan
+type UnprovisionedDevice struct {
+	UUID [16]byte
+	// OOBInfo the OOB information bitmask of the beacon
+	OOBInfo uint16
+	// URIHash the hash of the advertised URI, when present
+	URIHash []byte
+	RSSI    int16
+}
+
+//ParseUnprovisionedBeacon parse the payload of an unprovisioned device beacon,
+// as reported to Provisioner1.ScanResult
+func ParseUnprovisionedBeacon(data []byte) (*UnprovisionedDevice, error) {
+
+	if len(data) < 16 {
+		return nil, errors.New("Unprovisioned beacon too short")
+	}
+
+	d := &UnprovisionedDevice{}
+	copy(d.UUID[:], data[:16])
+	if len(data) >= 18 {
+		d.OOBInfo = binary.BigEndian.Uint16(data[16:18])
+	}
+	if len(data) >= 22 {
+		d.URIHash = data[18:22]
+	}
+	return d, nil
+}
+
+//ProvisionerConfig the configuration of provisioner applications
+type ProvisionerConfig struct {
+	// NetIndex the subnet new nodes are added to
+	NetIndex uint16
+	// NextUnicast the first unicast address to assign, incremented by the
+	// number of elements of every provisioned node. Defaults to 0x0100
+	NextUnicast uint16
+	// Keys persist the keys and nodes managed by the application, optional
+	Keys KeyStore
+
+	// OnScanResult called for every unprovisioned device found
+	OnScanResult func(device *UnprovisionedDevice)
+	// OnRequestProvData override the address assignment, count is the number
+	// of elements of the new node
+	OnRequestProvData func(count byte) (netIndex uint16, unicast uint16, err error)
+	// OnNodeAdded a node has been provisioned
+	OnNodeAdded func(uuid [16]byte, unicast uint16, count byte)
+	// OnNodeFailed the provisioning of a node failed
+	OnNodeFailed func(uuid [16]byte, reason string)
+}
+
+type provisionResult struct {
+	unicast uint16
+	count   byte
+	err     error
+}
+
+// provisioner the Provisioner1 methods, exported on the application root
+type provisioner struct {
+	app    *Application
+	config *ProvisionerConfig
+
+	mutex   sync.Mutex
+	pending map[[16]byte]chan provisionResult
+}
+
+func newProvisioner(app *Application, config *ProvisionerConfig) *provisioner {
+	if config.NextUnicast == 0 {
+		config.NextUnicast = 0x0100
+	}
+	return &provisioner{
+		app:     app,
+		config:  config,
+		pending: make(map[[16]byte]chan provisionResult),
+	}
+}
+
+// provisionerProperties the Provisioner1 interface has no properties
+type provisionerProperties struct{}
+
+func (p *provisionerProperties) ToMap() (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func (p *provisioner) expose() error {
+	return p.app.config.Conn.Export(p, p.app.Path(), bluez.MeshProvisioner1Interface)
+}
+
+func (p *provisioner) unexpose() {
+	p.app.config.Conn.Export(nil, p.app.Path(), bluez.MeshProvisioner1Interface)
+}
+
+func (p *provisioner) introspection() introspect.Interface {
+	return introspect.Interface{
+		Name:    bluez.MeshProvisioner1Interface,
+		Methods: introspect.Methods(p),
+	}
+}
+
+func toUUID(b []byte) [16]byte {
+	var uuid [16]byte
+	copy(uuid[:], b)
+	return uuid
+}
+
+//ScanResult called for every unprovisioned beacon received
+func (p *provisioner) ScanResult(rssi int16, data []byte, options map[string]dbus.Variant) *dbus.Error {
+	device, err := ParseUnprovisionedBeacon(data)
+	if err != nil {
+		bluez.GetLogger().Debugf("mesh: %s", err.Error())
+		return nil
+	}
+	device.RSSI = rssi
+	if p.config.OnScanResult != nil {
+		p.config.OnScanResult(device)
+	}
+	return nil
+}
+
+//RequestProvData return the subnet and the primary address of the new node
+func (p *provisioner) RequestProvData(count byte) (uint16, uint16, *dbus.Error) {
+
+	if p.config.OnRequestProvData != nil {
+		netIndex, unicast, err := p.config.OnRequestProvData(count)
+		if err != nil {
+			return 0, 0, dbus.NewError("org.bluez.mesh.Error.Abort", []interface{}{err.Error()})
+		}
+		return netIndex, unicast, nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	unicast := p.config.NextUnicast
+	if uint32(unicast)+uint32(count) > 0x8000 {
+		return 0, 0, dbus.NewError("org.bluez.mesh.Error.Abort", []interface{}{"No unicast addresses left"})
+	}
+	p.config.NextUnicast += uint16(count)
+	return p.config.NetIndex, unicast, nil
+}
+
+func (p *provisioner) complete(uuid [16]byte, r provisionResult) {
+	p.mutex.Lock()
+	result, ok := p.pending[uuid]
+	delete(p.pending, uuid)
+	p.mutex.Unlock()
+	if ok {
+		result <- r
+	}
+}
+
+//AddNodeComplete called when a node has been provisioned
+func (p *provisioner) AddNodeComplete(uuid []byte, unicast uint16, count byte) *dbus.Error {
+	id := toUUID(uuid)
+	bluez.GetLogger().Debugf("mesh: node %04x added", unicast)
+	p.complete(id, provisionResult{unicast, count, nil})
+	if p.config.OnNodeAdded != nil {
+		p.config.OnNodeAdded(id, unicast, count)
+	}
+	return nil
+}
+
+//AddNodeFailed called when the provisioning of a node failed
+func (p *provisioner) AddNodeFailed(uuid []byte, reason string) *dbus.Error {
+	id := toUUID(uuid)
+	bluez.GetLogger().Debugf("mesh: node provisioning failed: %s", reason)
+	p.complete(id, provisionResult{err: errors.New("Provisioning failed: " + reason)})
+	if p.config.OnNodeFailed != nil {
+		p.config.OnNodeFailed(id, reason)
+	}
+	return nil
+}
+
+func (app *Application) management() (*Management1, error) {
+	if app.provisioner == nil {
+		return nil, errors.New("Application is not a provisioner")
+	}
+	node := app.Node()
+	if node == nil {
+		return nil, errors.New("Node not attached")
+	}
+	m := NewManagement1(node.Path())
+	m.SetConnection(app.config.Conn)
+	return m, nil
+}
+
+//ScanUnprovisioned look for devices waiting to be provisioned, they are
+// reported to ProvisionerConfig.OnScanResult. A zero timeout scans until
+// StopScanUnprovisioned is called
+func (app *Application) ScanUnprovisioned(seconds uint16) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	return m.UnprovisionedScan(seconds)
+}
+
+//StopScanUnprovisioned stop a running scan
+func (app *Application) StopScanUnprovisioned() error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	return m.UnprovisionedScanCancel()
+}
+
+//Provision add a device to the network, waiting for the provisioning to
+// complete. The primary unicast address and the number of elements are returned
+func (app *Application) Provision(uuid [16]byte) (uint16, byte, error) {
+
+	m, err := app.management()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	p := app.provisioner
+	result := make(chan provisionResult, 1)
+	p.mutex.Lock()
+	if _, ok := p.pending[uuid]; ok {
+		p.mutex.Unlock()
+		return 0, 0, errors.New("Provisioning already in progress")
+	}
+	p.pending[uuid] = result
+	p.mutex.Unlock()
+
+	err = m.AddNode(uuid, nil)
+	if err != nil {
+		p.mutex.Lock()
+		delete(p.pending, uuid)
+		p.mutex.Unlock()
+		return 0, 0, err
+	}
+
+	r := <-result
+	if r.err == nil {
+		app.saveKeys(func(store KeyStore) error {
+			return store.SaveRemoteNode(RemoteNode{Primary: r.unicast, Count: r.count})
+		})
+	}
+	return r.unicast, r.count, r.err
+}
+
+// saveKeys call the key store, if any. Errors are only logged as the daemon
+// state has already changed
+func (app *Application) saveKeys(fn func(store KeyStore) error) {
+	if app.provisioner == nil || app.provisioner.config.Keys == nil {
+		return
+	}
+	err := fn(app.provisioner.config.Keys)
+	if err != nil {
+		bluez.GetLogger().Warnf("mesh: key store: %s", err.Error())
+	}
+}
+
+//CreateSubnet generate a new network key
+func (app *Application) CreateSubnet(netIndex uint16) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	err = m.CreateSubnet(netIndex)
+	if err != nil {
+		return err
+	}
+	app.saveKeys(func(store KeyStore) error {
+		return store.SaveNetKey(NetKey{Index: netIndex})
+	})
+	return nil
+}
+
+//ImportSubnet add an existing network key
+func (app *Application) ImportSubnet(key NetKey) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	err = m.ImportSubnet(key.Index, key.Value)
+	if err != nil {
+		return err
+	}
+	app.saveKeys(func(store KeyStore) error {
+		return store.SaveNetKey(key)
+	})
+	return nil
+}
+
+//UpdateSubnet start the key refresh of a network key
+func (app *Application) UpdateSubnet(netIndex uint16) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	err = m.UpdateSubnet(netIndex)
+	if err != nil {
+		return err
+	}
+	app.saveKeys(func(store KeyStore) error {
+		return store.SaveNetKey(NetKey{Index: netIndex, Phase: KeyRefreshPhaseOne})
+	})
+	return nil
+}
+
+//SetKeyPhase move a subnet to a key refresh phase
+func (app *Application) SetKeyPhase(netIndex uint16, phase byte) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	err = m.SetKeyPhase(netIndex, phase)
+	if err != nil {
+		return err
+	}
+	if phase == KeyRefreshPhaseThree {
+		phase = KeyRefreshPhaseNone
+	}
+	app.saveKeys(func(store KeyStore) error {
+		return store.SaveNetKey(NetKey{Index: netIndex, Phase: phase})
+	})
+	return nil
+}
+
+//DeleteSubnet remove a network key
+func (app *Application) DeleteSubnet(netIndex uint16) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	err = m.DeleteSubnet(netIndex)
+	if err != nil {
+		return err
+	}
+	app.saveKeys(func(store KeyStore) error {
+		return store.DeleteNetKey(netIndex)
+	})
+	return nil
+}
+
+//CreateAppKey generate a new application key bound to netIndex
+func (app *Application) CreateAppKey(netIndex uint16, appIndex uint16) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	err = m.CreateAppKey(netIndex, appIndex)
+	if err != nil {
+		return err
+	}
+	app.saveKeys(func(store KeyStore) error {
+		return store.SaveAppKey(AppKey{Index: appIndex, NetIndex: netIndex})
+	})
+	return nil
+}
+
+//ImportAppKey add an existing application key
+func (app *Application) ImportAppKey(key AppKey) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	err = m.ImportAppKey(key.NetIndex, key.Index, key.Value)
+	if err != nil {
+		return err
+	}
+	app.saveKeys(func(store KeyStore) error {
+		return store.SaveAppKey(key)
+	})
+	return nil
+}
+
+//UpdateAppKey generate a new value for an application key, the bound network
+// key has to be in key refresh phase one
+func (app *Application) UpdateAppKey(appIndex uint16) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	return m.UpdateAppKey(appIndex)
+}
+
+//DeleteAppKey remove an application key
+func (app *Application) DeleteAppKey(appIndex uint16) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	err = m.DeleteAppKey(appIndex)
+	if err != nil {
+		return err
+	}
+	app.saveKeys(func(store KeyStore) error {
+		return store.DeleteAppKey(appIndex)
+	})
+	return nil
+}
+
+//ImportRemoteNode add the device key of a node provisioned elsewhere, enabling
+// its configuration
+func (app *Application) ImportRemoteNode(node RemoteNode) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	err = m.ImportRemoteNode(node.Primary, node.Count, node.DeviceKey)
+	if err != nil {
+		return err
+	}
+	app.saveKeys(func(store KeyStore) error {
+		return store.SaveRemoteNode(node)
+	})
+	return nil
+}
+
+//DeleteRemoteNode remove the device key of a node, eg. after a Node Reset
+func (app *Application) DeleteRemoteNode(primary uint16, count byte) error {
+	m, err := app.management()
+	if err != nil {
+		return err
+	}
+	err = m.DeleteRemoteNode(primary, count)
+	if err != nil {
+		return err
+	}
+	app.saveKeys(func(store KeyStore) error {
+		return store.DeleteRemoteNode(primary)
+	})
+	return nil
+}
+
+//ExportKeys return the keys known to the node, with their values. The
+// key store, if any, is updated with the exported values
+func (app *Application) ExportKeys() (*KeyExport, error) {
+	m, err := app.management()
+	if err != nil {
+		return nil, err
+	}
+	export, err := m.ExportKeys()
+	if err != nil {
+		return nil, err
+	}
+	app.saveKeys(func(store KeyStore) error {
+		for _, key := range export.NetKeys {
+			if err := store.SaveNetKey(key); err != nil {
+				return err
+			}
+		}
+		for _, key := range export.AppKeys {
+			if err := store.SaveAppKey(key); err != nil {
+				return err
+			}
+		}
+		for _, node := range export.DevKeys {
+			if err := store.SaveRemoteNode(node); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return export, nil
+}
@@ -0,0 +1,36 @@
+package mesh
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseUnprovisionedBeacon(t *testing.T) {
+
+	uuid := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	data := append(append([]byte{}, uuid...), 0x40, 0x02, 0xde, 0xad, 0xbe, 0xef)
+
+	d, err := ParseUnprovisionedBeacon(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(d.UUID[:], uuid) {
+		t.Fatalf("Unexpected uuid %x", d.UUID)
+	}
+	if d.OOBInfo != 0x4002 {
+		t.Fatalf("Unexpected OOB info %04x", d.OOBInfo)
+	}
+	if !bytes.Equal(d.URIHash, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Fatalf("Unexpected URI hash %x", d.URIHash)
+	}
+
+	d, err = ParseUnprovisionedBeacon(uuid)
+	if err != nil || d.URIHash != nil {
+		t.Fatal("Expected a beacon without OOB info")
+	}
+
+	_, err = ParseUnprovisionedBeacon(uuid[:10])
+	if err == nil {
+		t.Fatal("Expected error on short beacon")
+	}
+}
@@ -0,0 +1,469 @@
+package mesh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Configuration model opcodes
+const (
+	OpAppKeyAdd                          Opcode = 0x00
+	OpAppKeyUpdate                       Opcode = 0x01
+	OpCompositionDataStatus              Opcode = 0x02
+	OpModelPublicationSet                Opcode = 0x03
+	OpAppKeyDelete                       Opcode = 0x8000
+	OpAppKeyStatus                       Opcode = 0x8003
+	OpCompositionDataGet                 Opcode = 0x8008
+	OpDefaultTTLGet                      Opcode = 0x800c
+	OpDefaultTTLSet                      Opcode = 0x800d
+	OpDefaultTTLStatus                   Opcode = 0x800e
+	OpModelPublicationGet                Opcode = 0x8018
+	OpModelPublicationStatus             Opcode = 0x8019
+	OpModelPublicationVirtualAddressSet  Opcode = 0x801a
+	OpModelSubscriptionAdd               Opcode = 0x801b
+	OpModelSubscriptionDelete            Opcode = 0x801c
+	OpModelSubscriptionStatus            Opcode = 0x801f
+	OpModelSubscriptionVirtualAddressAdd Opcode = 0x8020
+	OpModelAppBind                       Opcode = 0x803d
+	OpModelAppStatus                     Opcode = 0x803e
+	OpModelAppUnbind                     Opcode = 0x803f
+	OpNodeReset                          Opcode = 0x8049
+	OpNodeResetStatus                    Opcode = 0x804a
+)
+
+//StatusError a non success status returned by a configuration server
+type StatusError byte
+
+var statusMessages = map[StatusError]string{
+	0x01: "Invalid Address",
+	0x02: "Invalid Model",
+	0x03: "Invalid AppKey Index",
+	0x04: "Invalid NetKey Index",
+	0x05: "Insufficient Resources",
+	0x06: "Key Index Already Stored",
+	0x07: "Invalid Publish Parameters",
+	0x08: "Not a Subscribe Model",
+	0x09: "Storage Failure",
+	0x0a: "Feature Not Supported",
+	0x0b: "Cannot Update",
+	0x0c: "Cannot Remove",
+	0x0d: "Cannot Bind",
+	0x0e: "Temporarily Unable to Change State",
+	0x0f: "Cannot Set",
+	0x10: "Unspecified Error",
+	0x11: "Invalid Binding",
+}
+
+func (s StatusError) Error() string {
+	if msg, ok := statusMessages[s]; ok {
+		return msg
+	}
+	return fmt.Sprintf("Status %02x", byte(s))
+}
+
+// statusError return nil for the success status
+func statusError(status byte) error {
+	if status == 0 {
+		return nil
+	}
+	return StatusError(status)
+}
+
+//ModelIdentifier identify a SIG or a vendor model in configuration messages
+type ModelIdentifier struct {
+	ID uint16
+	// Vendor the company identifier of vendor models
+	Vendor   uint16
+	IsVendor bool
+}
+
+//SIGModel identify a SIG defined model
+func SIGModel(id uint16) ModelIdentifier {
+	return ModelIdentifier{ID: id}
+}
+
+//VendorModel identify a vendor model
+func VendorModel(vendor uint16, id uint16) ModelIdentifier {
+	return ModelIdentifier{ID: id, Vendor: vendor, IsVendor: true}
+}
+
+//Identifier return the identifier of a local model
+func (m *Model) Identifier() ModelIdentifier {
+	return ModelIdentifier{ID: m.ID, Vendor: m.Vendor, IsVendor: m.vendor}
+}
+
+func (m ModelIdentifier) bytes() []byte {
+	if m.IsVendor {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint16(b, m.Vendor)
+		binary.LittleEndian.PutUint16(b[2:], m.ID)
+		return b
+	}
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, m.ID)
+	return b
+}
+
+func parseModelIdentifier(b []byte) (ModelIdentifier, error) {
+	switch len(b) {
+	case 2:
+		return SIGModel(binary.LittleEndian.Uint16(b)), nil
+	case 4:
+		return VendorModel(binary.LittleEndian.Uint16(b), binary.LittleEndian.Uint16(b[2:])), nil
+	}
+	return ModelIdentifier{}, errors.New("Invalid model identifier")
+}
+
+// two 12 bits key indexes packed in three octets
+func packKeyIndexes(first, second uint16) []byte {
+	v := uint32(first&0xfff) | uint32(second&0xfff)<<12
+	return []byte{byte(v), byte(v >> 8), byte(v >> 16)}
+}
+
+func unpackKeyIndexes(b []byte) (uint16, uint16) {
+	v := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+	return uint16(v & 0xfff), uint16(v >> 12)
+}
+
+func put16(b []byte, values ...uint16) []byte {
+	for _, v := range values {
+		b = append(b, byte(v), byte(v>>8))
+	}
+	return b
+}
+
+//AppKeyAdd build a Config AppKey Add message. With bluetooth-meshd keys are
+// sent with Node1.AddAppKey instead
+func AppKeyAdd(netIndex uint16, appIndex uint16, key [16]byte) []byte {
+	params := append(packKeyIndexes(netIndex, appIndex), key[:]...)
+	return EncodeMessage(OpAppKeyAdd, params)
+}
+
+//AppKeyDelete build a Config AppKey Delete message
+func AppKeyDelete(netIndex uint16, appIndex uint16) []byte {
+	return EncodeMessage(OpAppKeyDelete, packKeyIndexes(netIndex, appIndex))
+}
+
+//AppKeyStatus the response to AppKey Add, Update and Delete
+type AppKeyStatus struct {
+	Status   byte
+	NetIndex uint16
+	AppIndex uint16
+}
+
+//Err return the error matching the status, nil on success
+func (s *AppKeyStatus) Err() error {
+	return statusError(s.Status)
+}
+
+//ParseAppKeyStatus parse the parameters of a Config AppKey Status
+func ParseAppKeyStatus(params []byte) (*AppKeyStatus, error) {
+	if len(params) != 4 {
+		return nil, errors.New("Invalid AppKey Status length")
+	}
+	s := &AppKeyStatus{Status: params[0]}
+	s.NetIndex, s.AppIndex = unpackKeyIndexes(params[1:4])
+	return s, nil
+}
+
+//ModelAppBind build a Config Model App Bind message, element is the unicast
+// address of the element holding the model
+func ModelAppBind(element uint16, appIndex uint16, model ModelIdentifier) []byte {
+	params := put16(nil, element, appIndex)
+	return EncodeMessage(OpModelAppBind, append(params, model.bytes()...))
+}
+
+//ModelAppUnbind build a Config Model App Unbind message
+func ModelAppUnbind(element uint16, appIndex uint16, model ModelIdentifier) []byte {
+	params := put16(nil, element, appIndex)
+	return EncodeMessage(OpModelAppUnbind, append(params, model.bytes()...))
+}
+
+//ModelAppStatus the response to Model App Bind and Unbind
+type ModelAppStatus struct {
+	Status   byte
+	Element  uint16
+	AppIndex uint16
+	Model    ModelIdentifier
+}
+
+//Err return the error matching the status, nil on success
+func (s *ModelAppStatus) Err() error {
+	return statusError(s.Status)
+}
+
+//ParseModelAppStatus parse the parameters of a Config Model App Status
+func ParseModelAppStatus(params []byte) (*ModelAppStatus, error) {
+	if len(params) != 7 && len(params) != 9 {
+		return nil, errors.New("Invalid Model App Status length")
+	}
+	model, err := parseModelIdentifier(params[5:])
+	if err != nil {
+		return nil, err
+	}
+	return &ModelAppStatus{
+		Status:   params[0],
+		Element:  binary.LittleEndian.Uint16(params[1:]),
+		AppIndex: binary.LittleEndian.Uint16(params[3:]),
+		Model:    model,
+	}, nil
+}
+
+//Publication the publication parameters of a model
+type Publication struct {
+	Address     uint16
+	AppKeyIndex uint16
+	// CredentialFlag use the friendship credentials
+	CredentialFlag bool
+	// TTL the publish TTL, 0xff uses the default TTL
+	TTL byte
+	// Period the publication period, zero disables periodic publishing
+	Period time.Duration
+	// RetransmitCount the number of retransmissions of each message (0-7)
+	RetransmitCount byte
+	// RetransmitInterval the interval between retransmissions, in 50ms steps
+	RetransmitInterval time.Duration
+}
+
+// publish period resolutions, see the mesh profile specification 4.3.2.16
+var periodResolutions = []time.Duration{
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	10 * time.Minute,
+}
+
+func encodePeriod(d time.Duration) byte {
+	if d <= 0 {
+		return 0
+	}
+	for i, res := range periodResolutions {
+		steps := (d + res - 1) / res
+		if steps <= 0x3f {
+			return byte(steps) | byte(i)<<6
+		}
+	}
+	return 0x3f | 3<<6
+}
+
+func decodePeriod(b byte) time.Duration {
+	return time.Duration(b&0x3f) * periodResolutions[b>>6]
+}
+
+func (p Publication) bytes() []byte {
+
+	index := p.AppKeyIndex & 0xfff
+	if p.CredentialFlag {
+		index |= 1 << 12
+	}
+
+	steps := byte(0)
+	if p.RetransmitInterval >= 50*time.Millisecond {
+		steps = byte(p.RetransmitInterval/(50*time.Millisecond)-1) & 0x1f
+	}
+
+	b := put16(nil, p.Address, index)
+	return append(b, p.TTL, encodePeriod(p.Period), p.RetransmitCount&0x07|steps<<3)
+}
+
+func parsePublication(b []byte) Publication {
+	index := binary.LittleEndian.Uint16(b[2:])
+	return Publication{
+		Address:            binary.LittleEndian.Uint16(b),
+		AppKeyIndex:        index & 0xfff,
+		CredentialFlag:     index&(1<<12) != 0,
+		TTL:                b[4],
+		Period:             decodePeriod(b[5]),
+		RetransmitCount:    b[6] & 0x07,
+		RetransmitInterval: time.Duration(b[6]>>3+1) * 50 * time.Millisecond,
+	}
+}
+
+//ModelPublicationSet build a Config Model Publication Set message
+func ModelPublicationSet(element uint16, pub Publication, model ModelIdentifier) []byte {
+	params := put16(nil, element)
+	params = append(params, pub.bytes()...)
+	return EncodeMessage(OpModelPublicationSet, append(params, model.bytes()...))
+}
+
+//ModelPublicationGet build a Config Model Publication Get message
+func ModelPublicationGet(element uint16, model ModelIdentifier) []byte {
+	return EncodeMessage(OpModelPublicationGet, append(put16(nil, element), model.bytes()...))
+}
+
+//ModelPublicationStatus the response to the publication messages
+type ModelPublicationStatus struct {
+	Status      byte
+	Element     uint16
+	Publication Publication
+	Model       ModelIdentifier
+}
+
+//Err return the error matching the status, nil on success
+func (s *ModelPublicationStatus) Err() error {
+	return statusError(s.Status)
+}
+
+//ParseModelPublicationStatus parse the parameters of a Config Model Publication Status
+func ParseModelPublicationStatus(params []byte) (*ModelPublicationStatus, error) {
+	if len(params) != 12 && len(params) != 14 {
+		return nil, errors.New("Invalid Model Publication Status length")
+	}
+	model, err := parseModelIdentifier(params[10:])
+	if err != nil {
+		return nil, err
+	}
+	return &ModelPublicationStatus{
+		Status:      params[0],
+		Element:     binary.LittleEndian.Uint16(params[1:]),
+		Publication: parsePublication(params[3:10]),
+		Model:       model,
+	}, nil
+}
+
+//ModelSubscriptionAdd build a Config Model Subscription Add message
+func ModelSubscriptionAdd(element uint16, address uint16, model ModelIdentifier) []byte {
+	params := put16(nil, element, address)
+	return EncodeMessage(OpModelSubscriptionAdd, append(params, model.bytes()...))
+}
+
+//ModelSubscriptionDelete build a Config Model Subscription Delete message
+func ModelSubscriptionDelete(element uint16, address uint16, model ModelIdentifier) []byte {
+	params := put16(nil, element, address)
+	return EncodeMessage(OpModelSubscriptionDelete, append(params, model.bytes()...))
+}
+
+//ModelSubscriptionVirtualAddressAdd build a Config Model Subscription Virtual
+// Address Add message
+func ModelSubscriptionVirtualAddressAdd(element uint16, label [16]byte, model ModelIdentifier) []byte {
+	params := append(put16(nil, element), label[:]...)
+	return EncodeMessage(OpModelSubscriptionVirtualAddressAdd, append(params, model.bytes()...))
+}
+
+//ModelSubscriptionStatus the response to the subscription messages
+type ModelSubscriptionStatus struct {
+	Status  byte
+	Element uint16
+	Address uint16
+	Model   ModelIdentifier
+}
+
+//Err return the error matching the status, nil on success
+func (s *ModelSubscriptionStatus) Err() error {
+	return statusError(s.Status)
+}
+
+//ParseModelSubscriptionStatus parse the parameters of a Config Model Subscription Status
+func ParseModelSubscriptionStatus(params []byte) (*ModelSubscriptionStatus, error) {
+	if len(params) != 7 && len(params) != 9 {
+		return nil, errors.New("Invalid Model Subscription Status length")
+	}
+	model, err := parseModelIdentifier(params[5:])
+	if err != nil {
+		return nil, err
+	}
+	return &ModelSubscriptionStatus{
+		Status:  params[0],
+		Element: binary.LittleEndian.Uint16(params[1:]),
+		Address: binary.LittleEndian.Uint16(params[3:]),
+		Model:   model,
+	}, nil
+}
+
+//CompositionDataGet build a Config Composition Data Get message
+func CompositionDataGet(page byte) []byte {
+	return EncodeMessage(OpCompositionDataGet, []byte{page})
+}
+
+// Node features in the composition data
+const (
+	FeatureRelay    uint16 = 1 << 0
+	FeatureProxy    uint16 = 1 << 1
+	FeatureFriend   uint16 = 1 << 2
+	FeatureLowPower uint16 = 1 << 3
+)
+
+//CompositionElement an element listed in the composition data
+type CompositionElement struct {
+	Location     uint16
+	Models       []uint16
+	VendorModels []ModelIdentifier
+}
+
+//Composition the composition data page 0 of a node
+type Composition struct {
+	Page      byte
+	CompanyID uint16
+	ProductID uint16
+	VersionID uint16
+	CRPL      uint16
+	Features  uint16
+	Elements  []CompositionElement
+}
+
+//ParseCompositionDataStatus parse the parameters of a Config Composition Data Status
+func ParseCompositionDataStatus(params []byte) (*Composition, error) {
+
+	if len(params) < 11 {
+		return nil, errors.New("Composition data too short")
+	}
+
+	c := &Composition{
+		Page:      params[0],
+		CompanyID: binary.LittleEndian.Uint16(params[1:]),
+		ProductID: binary.LittleEndian.Uint16(params[3:]),
+		VersionID: binary.LittleEndian.Uint16(params[5:]),
+		CRPL:      binary.LittleEndian.Uint16(params[7:]),
+		Features:  binary.LittleEndian.Uint16(params[9:]),
+		Elements:  []CompositionElement{},
+	}
+
+	b := params[11:]
+	for len(b) > 0 {
+
+		if len(b) < 4 {
+			return nil, errors.New("Truncated element")
+		}
+		e := CompositionElement{
+			Location:     binary.LittleEndian.Uint16(b),
+			Models:       []uint16{},
+			VendorModels: []ModelIdentifier{},
+		}
+		numS, numV := int(b[2]), int(b[3])
+		b = b[4:]
+
+		if len(b) < numS*2+numV*4 {
+			return nil, errors.New("Truncated models list")
+		}
+		for i := 0; i < numS; i++ {
+			e.Models = append(e.Models, binary.LittleEndian.Uint16(b))
+			b = b[2:]
+		}
+		for i := 0; i < numV; i++ {
+			model, _ := parseModelIdentifier(b[:4])
+			e.VendorModels = append(e.VendorModels, model)
+			b = b[4:]
+		}
+
+		c.Elements = append(c.Elements, e)
+	}
+
+	return c, nil
+}
+
+//DefaultTTLGet build a Config Default TTL Get message
+func DefaultTTLGet() []byte {
+	return EncodeMessage(OpDefaultTTLGet, nil)
+}
+
+//DefaultTTLSet build a Config Default TTL Set message
+func DefaultTTLSet(ttl byte) []byte {
+	return EncodeMessage(OpDefaultTTLSet, []byte{ttl})
+}
+
+//NodeReset build a Config Node Reset message, removing the node from the network
+func NodeReset() []byte {
+	return EncodeMessage(OpNodeReset, nil)
+}
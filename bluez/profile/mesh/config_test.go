@@ -0,0 +1,172 @@
+package mesh
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestOpcode(t *testing.T) {
+
+	for _, op := range []Opcode{OpAppKeyAdd, OpModelAppBind, VendorOpcode(0x01, 0x05f1)} {
+		data := EncodeMessage(op, []byte{0xaa})
+		parsed, params, err := ParseOpcode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != op || !bytes.Equal(params, []byte{0xaa}) {
+			t.Fatalf("Unexpected opcode %s %x", parsed, params)
+		}
+	}
+
+	vendor := VendorOpcode(0x01, 0x05f1)
+	if !bytes.Equal(vendor.Bytes(), []byte{0xc1, 0xf1, 0x05}) {
+		t.Fatalf("Unexpected vendor opcode encoding %x", vendor.Bytes())
+	}
+	if !vendor.IsVendor() || vendor.Company() != 0x05f1 {
+		t.Fatal("Expected a vendor opcode")
+	}
+
+	if _, _, err := ParseOpcode([]byte{0x80}); err == nil {
+		t.Fatal("Expected truncated opcode error")
+	}
+}
+
+func TestModelAppBind(t *testing.T) {
+
+	data := ModelAppBind(0x0102, 0x0001, SIGModel(GenericOnOffServer))
+	expected := []byte{0x80, 0x3d, 0x02, 0x01, 0x01, 0x00, 0x00, 0x10}
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("Unexpected Model App Bind %x", data)
+	}
+
+	data = ModelAppBind(0x0102, 0x0001, VendorModel(0x05f1, 0x0001))
+	expected = []byte{0x80, 0x3d, 0x02, 0x01, 0x01, 0x00, 0xf1, 0x05, 0x01, 0x00}
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("Unexpected vendor Model App Bind %x", data)
+	}
+
+	status, err := ParseModelAppStatus([]byte{0x02, 0x02, 0x01, 0x01, 0x00, 0x00, 0x10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Element != 0x0102 || status.Model.ID != GenericOnOffServer || status.Model.IsVendor {
+		t.Fatalf("Unexpected status %+v", status)
+	}
+	if status.Err() != StatusError(0x02) {
+		t.Fatalf("Unexpected error %v", status.Err())
+	}
+}
+
+func TestKeyIndexes(t *testing.T) {
+
+	b := packKeyIndexes(0x123, 0x456)
+	if !bytes.Equal(b, []byte{0x23, 0x61, 0x45}) {
+		t.Fatalf("Unexpected key indexes %x", b)
+	}
+	net, app := unpackKeyIndexes(b)
+	if net != 0x123 || app != 0x456 {
+		t.Fatalf("Unexpected key indexes %03x %03x", net, app)
+	}
+
+	status, err := ParseAppKeyStatus(append([]byte{0}, b...))
+	if err != nil || status.Err() != nil {
+		t.Fatal("Expected a successful AppKey Status")
+	}
+}
+
+func TestPublication(t *testing.T) {
+
+	pub := Publication{
+		Address:            0xc000,
+		AppKeyIndex:        1,
+		TTL:                5,
+		Period:             10 * time.Second,
+		RetransmitCount:    2,
+		RetransmitInterval: 100 * time.Millisecond,
+	}
+
+	data := ModelPublicationSet(0x0100, pub, SIGModel(GenericOnOffServer))
+	op, params, err := ParseOpcode(data)
+	if err != nil || op != OpModelPublicationSet {
+		t.Fatalf("Unexpected opcode %s", op)
+	}
+
+	status, err := ParseModelPublicationStatus(append([]byte{0}, params...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Publication != pub {
+		t.Fatalf("Unexpected publication %+v", status.Publication)
+	}
+}
+
+func TestParseCompositionDataStatus(t *testing.T) {
+
+	data := []byte{
+		0x00,       // page
+		0xf1, 0x05, // cid
+		0x01, 0x00, // pid
+		0x02, 0x00, // vid
+		0x20, 0x00, // crpl
+		0x03, 0x00, // relay, proxy
+		0x00, 0x01, 0x02, 0x01, // element
+		0x00, 0x00, 0x00, 0x10,
+		0xf1, 0x05, 0x01, 0x00,
+	}
+
+	c, err := ParseCompositionDataStatus(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.CompanyID != 0x05f1 || c.Features != FeatureRelay|FeatureProxy || len(c.Elements) != 1 {
+		t.Fatalf("Unexpected composition %+v", c)
+	}
+	e := c.Elements[0]
+	if e.Location != 0x0100 || len(e.Models) != 2 || e.Models[1] != GenericOnOffServer {
+		t.Fatalf("Unexpected element %+v", e)
+	}
+	if len(e.VendorModels) != 1 || e.VendorModels[0] != VendorModel(0x05f1, 0x0001) {
+		t.Fatalf("Unexpected vendor models %+v", e.VendorModels)
+	}
+
+	if _, err := ParseCompositionDataStatus(data[:len(data)-2]); err == nil {
+		t.Fatal("Expected truncated composition error")
+	}
+}
+
+func TestOpcodeRegistry(t *testing.T) {
+
+	op := RegisterVendorOpcode(0x02, 0x05f1, "ExampleVendorSet")
+	if OpcodeName(op) != "ExampleVendorSet" || op.String() != "ExampleVendorSet" {
+		t.Fatalf("Unexpected opcode name %s", op)
+	}
+	if OpModelAppBind.String() != "ModelAppBind" {
+		t.Fatalf("Unexpected opcode name %s", OpModelAppBind)
+	}
+	if Opcode(0x8fff).String() != "8fff" {
+		t.Fatalf("Unexpected opcode name %s", Opcode(0x8fff))
+	}
+}
+
+func TestElementEvents(t *testing.T) {
+
+	e := &Element{}
+	events, stop := e.Events(OpGenericOnOffSet)
+
+	e.publish(&Message{Source: 0x0100, Data: []byte{0x82, 0x01}})
+	e.publish(&Message{Source: 0x0100, KeyIndex: 1, Data: []byte{0x82, 0x02, 0x01, 0x07}})
+
+	ev := <-events
+	if ev.Opcode != OpGenericOnOffSet || ev.Name() != "GenericOnOffSet" || ev.KeyIndex != 1 {
+		t.Fatalf("Unexpected event %+v", ev)
+	}
+	if !bytes.Equal(ev.Params, []byte{0x01, 0x07}) {
+		t.Fatalf("Unexpected params %x", ev.Params)
+	}
+
+	stop()
+	if _, ok := <-events; ok {
+		t.Fatal("Expected the channel to be closed")
+	}
+}
@@ -0,0 +1,111 @@
+package mesh
+
+import (
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+// eventsBuffer the number of events queued for a subscriber before dropping
+const eventsBuffer = 16
+
+//Event a message received by an element, with the opcode decoded
+type Event struct {
+	// Element the element which received the message
+	Element *Element
+	Source  uint16
+	// Destination the unicast or group address, zero for virtual addresses
+	Destination  uint16
+	VirtualLabel []byte
+	// KeyIndex the application key index, or the network key index for
+	// messages encrypted with a device key
+	KeyIndex uint16
+	DevKey   bool
+	Remote   bool
+	Opcode   Opcode
+	// Params the message parameters, following the opcode
+	Params []byte
+}
+
+//Name return the registered name of the opcode, see RegisterOpcode
+func (e *Event) Name() string {
+	return OpcodeName(e.Opcode)
+}
+
+type subscription struct {
+	events  chan *Event
+	opcodes map[Opcode]bool
+}
+
+func (s *subscription) match(opcode Opcode) bool {
+	return len(s.opcodes) == 0 || s.opcodes[opcode]
+}
+
+//Events report the messages received by the element, restricted to the given
+// opcodes if any. Events are delivered along with the OnMessage handler. The
+// channel must be drained, events are dropped when it is full, and is closed
+// by calling the returned stop function
+func (e *Element) Events(opcodes ...Opcode) (<-chan *Event, func()) {
+
+	s := &subscription{
+		events:  make(chan *Event, eventsBuffer),
+		opcodes: make(map[Opcode]bool),
+	}
+	for _, op := range opcodes {
+		s.opcodes[op] = true
+	}
+
+	e.mutex.Lock()
+	e.subscriptions = append(e.subscriptions, s)
+	e.mutex.Unlock()
+
+	stop := func() {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		for i, other := range e.subscriptions {
+			if other == s {
+				e.subscriptions = append(e.subscriptions[:i], e.subscriptions[i+1:]...)
+				close(s.events)
+				return
+			}
+		}
+	}
+
+	return s.events, stop
+}
+
+// publish deliver a message to the subscribers
+func (e *Element) publish(msg *Message) {
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if len(e.subscriptions) == 0 {
+		return
+	}
+
+	opcode, params, err := ParseOpcode(msg.Data)
+	if err != nil {
+		bluez.GetLogger().Debugf("mesh: message from %04x: %s", msg.Source, err.Error())
+		return
+	}
+
+	for _, s := range e.subscriptions {
+		if !s.match(opcode) {
+			continue
+		}
+		event := &Event{
+			Element:      e,
+			Source:       msg.Source,
+			Destination:  msg.Destination,
+			VirtualLabel: msg.VirtualLabel,
+			KeyIndex:     msg.KeyIndex,
+			DevKey:       msg.DevKey,
+			Remote:       msg.Remote,
+			Opcode:       opcode,
+			Params:       params,
+		}
+		select {
+		case s.events <- event:
+		default:
+			bluez.GetLogger().Warnf("mesh: event %s from %04x dropped", opcode, msg.Source)
+		}
+	}
+}
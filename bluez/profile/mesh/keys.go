@@ -0,0 +1,130 @@
+package mesh
+
+import (
+	"github.com/godbus/dbus"
+)
+
+// Key refresh phases, see Management1.SetKeyPhase
+const (
+	KeyRefreshPhaseNone  byte = 0
+	KeyRefreshPhaseOne   byte = 1
+	KeyRefreshPhaseTwo   byte = 2
+	KeyRefreshPhaseThree byte = 3
+)
+
+//NetKey a network key. Value is zero for keys generated by the daemon until
+// they are retrieved with ExportKeys
+type NetKey struct {
+	Index uint16
+	Value [16]byte
+	// Updated the new value during a key refresh
+	Updated *[16]byte
+	Phase   byte
+}
+
+//AppKey an application key, bound to a network key
+type AppKey struct {
+	Index    uint16
+	NetIndex uint16
+	Value    [16]byte
+	// Updated the new value during a key refresh
+	Updated *[16]byte
+}
+
+//RemoteNode the device key of a provisioned node
+type RemoteNode struct {
+	// Primary the unicast address of the primary element
+	Primary uint16
+	// Count the number of elements, hence of addresses used by the node
+	Count byte
+	// DeviceKey is zero for nodes provisioned locally until exported
+	DeviceKey [16]byte
+}
+
+//KeyExport the keys returned by Management1.ExportKeys
+type KeyExport struct {
+	NetKeys []NetKey
+	AppKeys []AppKey
+	DevKeys []RemoteNode
+}
+
+//KeyStore persist the keys of the network as they are changed through the
+// Application, eg. to rebuild the network configuration elsewhere. Errors
+// are logged and do not revert the daemon state
+type KeyStore interface {
+	SaveNetKey(key NetKey) error
+	DeleteNetKey(index uint16) error
+	SaveAppKey(key AppKey) error
+	DeleteAppKey(index uint16) error
+	SaveRemoteNode(node RemoteNode) error
+	DeleteRemoteNode(primary uint16) error
+}
+
+func toKey(v interface{}) ([16]byte, bool) {
+	var key [16]byte
+	b, ok := v.([]byte)
+	if !ok || len(b) != 16 {
+		return key, false
+	}
+	copy(key[:], b)
+	return key, true
+}
+
+func variantDicts(v dbus.Variant) []map[string]dbus.Variant {
+	dicts, _ := v.Value().([]map[string]dbus.Variant)
+	return dicts
+}
+
+func dictUint16(dict map[string]dbus.Variant, name string) uint16 {
+	if v, ok := dict[name]; ok {
+		n, _ := v.Value().(uint16)
+		return n
+	}
+	return 0
+}
+
+func dictKey(dict map[string]dbus.Variant, name string) ([16]byte, bool) {
+	if v, ok := dict[name]; ok {
+		return toKey(v.Value())
+	}
+	return [16]byte{}, false
+}
+
+func parseKeyExport(raw map[string]dbus.Variant) *KeyExport {
+
+	export := &KeyExport{
+		NetKeys: []NetKey{},
+		AppKeys: []AppKey{},
+		DevKeys: []RemoteNode{},
+	}
+
+	for _, nk := range variantDicts(raw["NetKeys"]) {
+
+		key := NetKey{Index: dictUint16(nk, "index")}
+		key.Value, _ = dictKey(nk, "value")
+		if updated, ok := dictKey(nk, "updated"); ok {
+			key.Updated = &updated
+		}
+		if v, ok := nk["phase"]; ok {
+			key.Phase, _ = v.Value().(byte)
+		}
+		export.NetKeys = append(export.NetKeys, key)
+
+		for _, ak := range variantDicts(nk["AppKeys"]) {
+			app := AppKey{Index: dictUint16(ak, "index"), NetIndex: key.Index}
+			app.Value, _ = dictKey(ak, "value")
+			if updated, ok := dictKey(ak, "updated"); ok {
+				app.Updated = &updated
+			}
+			export.AppKeys = append(export.AppKeys, app)
+		}
+	}
+
+	for _, dk := range variantDicts(raw["DevKeys"]) {
+		node := RemoteNode{Primary: dictUint16(dk, "primary")}
+		node.DeviceKey, _ = dictKey(dk, "value")
+		export.DevKeys = append(export.DevKeys, node)
+	}
+
+	return export
+}
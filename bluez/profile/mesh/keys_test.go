@@ -0,0 +1,56 @@
+package mesh
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func TestParseKeyExport(t *testing.T) {
+
+	netKey := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	appKey := []byte{15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0}
+
+	raw := map[string]dbus.Variant{
+		"NetKeys": dbus.MakeVariant([]map[string]dbus.Variant{
+			{
+				"index": dbus.MakeVariant(uint16(0)),
+				"value": dbus.MakeVariant(netKey),
+				"phase": dbus.MakeVariant(byte(1)),
+				"AppKeys": dbus.MakeVariant([]map[string]dbus.Variant{
+					{
+						"index":   dbus.MakeVariant(uint16(2)),
+						"value":   dbus.MakeVariant(appKey),
+						"updated": dbus.MakeVariant(netKey),
+					},
+				}),
+			},
+		}),
+		"DevKeys": dbus.MakeVariant([]map[string]dbus.Variant{
+			{
+				"primary": dbus.MakeVariant(uint16(0x0100)),
+				"value":   dbus.MakeVariant(appKey),
+			},
+		}),
+	}
+
+	export := parseKeyExport(raw)
+
+	if len(export.NetKeys) != 1 || export.NetKeys[0].Phase != KeyRefreshPhaseOne || export.NetKeys[0].Value[1] != 1 {
+		t.Fatalf("Unexpected net keys %+v", export.NetKeys)
+	}
+	if len(export.AppKeys) != 1 {
+		t.Fatalf("Unexpected app keys %+v", export.AppKeys)
+	}
+	app := export.AppKeys[0]
+	if app.Index != 2 || app.NetIndex != 0 || app.Value[0] != 15 || app.Updated == nil {
+		t.Fatalf("Unexpected app key %+v", app)
+	}
+	if len(export.DevKeys) != 1 || export.DevKeys[0].Primary != 0x0100 {
+		t.Fatalf("Unexpected dev keys %+v", export.DevKeys)
+	}
+
+	if export = parseKeyExport(map[string]dbus.Variant{}); len(export.NetKeys) != 0 {
+		t.Fatal("Expected an empty export")
+	}
+}
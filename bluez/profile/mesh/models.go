@@ -0,0 +1,36 @@
+package mesh
+
+// SIG model identifiers
+const (
+	ConfigServer       uint16 = 0x0000
+	ConfigClient       uint16 = 0x0001
+	HealthServer       uint16 = 0x0002
+	HealthClient       uint16 = 0x0003
+	GenericOnOffServer uint16 = 0x1000
+	GenericOnOffClient uint16 = 0x1001
+	GenericLevelServer uint16 = 0x1002
+	GenericLevelClient uint16 = 0x1003
+)
+
+//UnassignedAddress the address of nodes not yet provisioned
+const UnassignedAddress uint16 = 0x0000
+
+// Fixed group addresses
+const (
+	AllProxiesAddress uint16 = 0xfffc
+	AllFriendsAddress uint16 = 0xfffd
+	AllRelaysAddress  uint16 = 0xfffe
+	AllNodesAddress   uint16 = 0xffff
+)
+
+// Generic OnOff and Generic Level opcodes
+const (
+	OpGenericOnOffGet               Opcode = 0x8201
+	OpGenericOnOffSet               Opcode = 0x8202
+	OpGenericOnOffSetUnacknowledged Opcode = 0x8203
+	OpGenericOnOffStatus            Opcode = 0x8204
+	OpGenericLevelGet               Opcode = 0x8205
+	OpGenericLevelSet               Opcode = 0x8206
+	OpGenericLevelSetUnacknowledged Opcode = 0x8207
+	OpGenericLevelStatus            Opcode = 0x8208
+)
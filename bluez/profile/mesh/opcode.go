@@ -0,0 +1,139 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+//Opcode an access layer opcode. One and two octets opcodes are stored as is,
+// eg. 0x00 or 0x8003, vendor opcodes as 0xC0xxxxxx with the company identifier
+// in the two low octets
+type Opcode uint32
+
+//VendorOpcode build a three octets vendor opcode
+func VendorOpcode(opcode byte, company uint16) Opcode {
+	return Opcode(0xc00000|uint32(opcode&0x3f)<<16) | Opcode(company)
+}
+
+//IsVendor return true for vendor opcodes
+func (o Opcode) IsVendor() bool {
+	return o >= 0xc00000
+}
+
+//Company return the company identifier of vendor opcodes
+func (o Opcode) Company() uint16 {
+	return uint16(o & 0xffff)
+}
+
+//Bytes encode the opcode as sent over the air
+func (o Opcode) Bytes() []byte {
+	switch {
+	case o.IsVendor():
+		// the company identifier is little endian
+		return []byte{byte(o >> 16), byte(o), byte(o >> 8)}
+	case o > 0x7f:
+		return []byte{byte(o >> 8), byte(o)}
+	}
+	return []byte{byte(o)}
+}
+
+func (o Opcode) String() string {
+	if name := OpcodeName(o); name != "" {
+		return name
+	}
+	if o.IsVendor() {
+		return fmt.Sprintf("%02x:%04x", byte(o>>16), o.Company())
+	}
+	return fmt.Sprintf("%04x", uint32(o))
+}
+
+//ParseOpcode split an access layer payload in its opcode and parameters
+func ParseOpcode(data []byte) (Opcode, []byte, error) {
+
+	if len(data) == 0 {
+		return 0, nil, errors.New("Empty message")
+	}
+
+	switch data[0] >> 6 {
+	case 0, 1:
+		if data[0] == 0x7f {
+			return 0, nil, errors.New("Reserved opcode")
+		}
+		return Opcode(data[0]), data[1:], nil
+	case 2:
+		if len(data) < 2 {
+			return 0, nil, errors.New("Truncated opcode")
+		}
+		return Opcode(uint32(data[0])<<8 | uint32(data[1])), data[2:], nil
+	}
+
+	if len(data) < 3 {
+		return 0, nil, errors.New("Truncated vendor opcode")
+	}
+	op := Opcode(uint32(data[0])<<16 | uint32(data[2])<<8 | uint32(data[1]))
+	return op, data[3:], nil
+}
+
+//EncodeMessage build an access layer payload
+func EncodeMessage(opcode Opcode, params []byte) []byte {
+	return append(opcode.Bytes(), params...)
+}
+
+var opcodes = struct {
+	sync.RWMutex
+	names map[Opcode]string
+}{names: map[Opcode]string{
+	OpAppKeyAdd:                          "AppKeyAdd",
+	OpAppKeyUpdate:                       "AppKeyUpdate",
+	OpCompositionDataStatus:              "CompositionDataStatus",
+	OpModelPublicationSet:                "ModelPublicationSet",
+	OpAppKeyDelete:                       "AppKeyDelete",
+	OpAppKeyStatus:                       "AppKeyStatus",
+	OpCompositionDataGet:                 "CompositionDataGet",
+	OpDefaultTTLGet:                      "DefaultTTLGet",
+	OpDefaultTTLSet:                      "DefaultTTLSet",
+	OpDefaultTTLStatus:                   "DefaultTTLStatus",
+	OpModelPublicationGet:                "ModelPublicationGet",
+	OpModelPublicationStatus:             "ModelPublicationStatus",
+	OpModelPublicationVirtualAddressSet:  "ModelPublicationVirtualAddressSet",
+	OpModelSubscriptionAdd:               "ModelSubscriptionAdd",
+	OpModelSubscriptionDelete:            "ModelSubscriptionDelete",
+	OpModelSubscriptionStatus:            "ModelSubscriptionStatus",
+	OpModelSubscriptionVirtualAddressAdd: "ModelSubscriptionVirtualAddressAdd",
+	OpModelAppBind:                       "ModelAppBind",
+	OpModelAppStatus:                     "ModelAppStatus",
+	OpModelAppUnbind:                     "ModelAppUnbind",
+	OpNodeReset:                          "NodeReset",
+	OpNodeResetStatus:                    "NodeResetStatus",
+	OpGenericOnOffGet:                    "GenericOnOffGet",
+	OpGenericOnOffSet:                    "GenericOnOffSet",
+	OpGenericOnOffSetUnacknowledged:      "GenericOnOffSetUnacknowledged",
+	OpGenericOnOffStatus:                 "GenericOnOffStatus",
+	OpGenericLevelGet:                    "GenericLevelGet",
+	OpGenericLevelSet:                    "GenericLevelSet",
+	OpGenericLevelSetUnacknowledged:      "GenericLevelSetUnacknowledged",
+	OpGenericLevelStatus:                 "GenericLevelStatus",
+}}
+
+//RegisterOpcode name an opcode, eg. the vendor opcodes of a custom model, so
+// that events and logs report it by name
+func RegisterOpcode(opcode Opcode, name string) {
+	opcodes.Lock()
+	defer opcodes.Unlock()
+	opcodes.names[opcode] = name
+}
+
+//RegisterVendorOpcode name a vendor opcode
+func RegisterVendorOpcode(opcode byte, company uint16, name string) Opcode {
+	op := VendorOpcode(opcode, company)
+	RegisterOpcode(op, name)
+	return op
+}
+
+//OpcodeName return the registered name of an opcode, empty if unknown
+func OpcodeName(opcode Opcode) string {
+	opcodes.RLock()
+	defer opcodes.RUnlock()
+	return opcodes.names[opcode]
+}
@@ -1,11 +1,19 @@
 package obex
 
 import (
-	log "github.com/Sirupsen/logrus"
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/bluez"
 )
 
+// Session targets supported by CreateSession
+const (
+	SessionTargetFTP  = "ftp"
+	SessionTargetMAP  = "map"
+	SessionTargetOPP  = "opp"
+	SessionTargetPBAP = "pbap"
+	SessionTargetSync = "sync"
+)
+
 // TODO: https://github.com/blueman-project/blueman/issues/218#issuecomment-89315974
 // NewObexClient1 create a new ObexClient1 client
 func NewObexClient1() *ObexClient1 {
@@ -55,7 +63,7 @@ func (a *ObexClient1) Close() {
 //
 // TODO: Use ObexSession1 struct instead of generic map for options
 func (a *ObexClient1) CreateSession(destination string, options map[string]interface{}) (string, error) {
-	log.Debugf("CreateSession to %s", destination)
+	bluez.GetLogger().Debugf("CreateSession to %s", destination)
 	var sessionPath string
 	err := a.client.Call("CreateSession", 0, destination, options).Store(&sessionPath)
 	return sessionPath, err
@@ -0,0 +1,208 @@
+package obex
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
+)
+
+// NewObexFileTransfer1 create a new FileTransfer1 client
+func NewObexFileTransfer1(sessionPath string) *ObexFileTransfer1 {
+	a := new(ObexFileTransfer1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez.obex",
+			Iface: "org.bluez.obex.FileTransfer1",
+			Path:  sessionPath,
+			Bus:   bluez.SessionBus,
+		},
+	)
+	return a
+}
+
+// ObexFileTransfer1 client
+type ObexFileTransfer1 struct {
+	client *bluez.Client
+}
+
+//FolderEntry an item returned by ListFolder
+type FolderEntry struct {
+	Name      string
+	Type      string // folder or file
+	Size      uint64 // items for folders, bytes for files
+	UserPerm  string
+	GroupPerm string
+	OtherPerm string
+	Modified  string
+	Accessed  string
+	Created   string
+}
+
+func parseFolderEntry(item map[string]dbus.Variant) FolderEntry {
+	e := FolderEntry{}
+	for key, val := range item {
+		switch key {
+		case "Name":
+			e.Name, _ = val.Value().(string)
+		case "Type":
+			e.Type, _ = val.Value().(string)
+		case "Size":
+			e.Size, _ = val.Value().(uint64)
+		case "User-perm":
+			e.UserPerm, _ = val.Value().(string)
+		case "Group-perm":
+			e.GroupPerm, _ = val.Value().(string)
+		case "Other-perm":
+			e.OtherPerm, _ = val.Value().(string)
+		case "Modified":
+			e.Modified, _ = val.Value().(string)
+		case "Accessed":
+			e.Accessed, _ = val.Value().(string)
+		case "Created":
+			e.Created, _ = val.Value().(string)
+		}
+	}
+	return e
+}
+
+//IsFolder return true if the entry is a folder
+func (e *FolderEntry) IsFolder() bool {
+	return e.Type == "folder"
+}
+
+// Close the connection
+func (a *ObexFileTransfer1) Close() {
+	a.client.Disconnect()
+}
+
+//
+// Change the current folder of the remote device.
+//
+// Possible errors:
+//  - org.bluez.obex.Error.InvalidArguments
+//  - org.bluez.obex.Error.Failed
+//
+func (a *ObexFileTransfer1) ChangeFolder(folder string) error {
+	return a.client.Call("ChangeFolder", 0, folder).Store()
+}
+
+//
+// Create a new folder in the remote device.
+//
+// Possible errors:
+//  - org.bluez.obex.Error.InvalidArguments
+//  - org.bluez.obex.Error.Failed
+//
+func (a *ObexFileTransfer1) CreateFolder(folder string) error {
+	return a.client.Call("CreateFolder", 0, folder).Store()
+}
+
+//
+// Returns a dictionary containing information about
+// the current folder content.
+//
+// Possible errors:
+//  - org.bluez.obex.Error.InvalidArguments
+//  - org.bluez.obex.Error.Failed
+//
+func (a *ObexFileTransfer1) ListFolder() ([]FolderEntry, error) {
+
+	var result []map[string]dbus.Variant
+	err := a.client.Call("ListFolder", 0).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]FolderEntry, len(result))
+	for i, item := range result {
+		entries[i] = parseFolderEntry(item)
+	}
+
+	return entries, nil
+}
+
+//
+// Copy the source file (from remote device) to the
+// target file (on local filesystem).
+//
+// If an empty target file is given, a name will be
+// automatically calculated for the temporary file.
+//
+// The returned path represents the newly created transfer,
+// which should be used to find out if the content has been
+// successfully transferred or if the operation fails.
+//
+// Possible errors:
+//  - org.bluez.obex.Error.InvalidArguments
+//  - org.bluez.obex.Error.Failed
+//
+func (a *ObexFileTransfer1) GetFile(targetfile string, sourcefile string) (string, *ObexTransfer1Properties, error) {
+
+	result := make(map[string]dbus.Variant)
+	var transferPath string
+	err := a.client.Call("GetFile", 0, targetfile, sourcefile).Store(&transferPath, &result)
+
+	transportProps := new(ObexTransfer1Properties)
+	util.MapToStruct(transportProps, result)
+
+	return transferPath, transportProps, err
+}
+
+//
+// Copy the source file (from local filesystem) to the
+// target file (on remote device).
+//
+// The returned path represents the newly created transfer,
+// which should be used to find out if the content has been
+// successfully transferred or if the operation fails.
+//
+// Possible errors:
+//  - org.bluez.obex.Error.InvalidArguments
+//  - org.bluez.obex.Error.Failed
+//
+func (a *ObexFileTransfer1) PutFile(sourcefile string, targetfile string) (string, *ObexTransfer1Properties, error) {
+
+	result := make(map[string]dbus.Variant)
+	var transferPath string
+	err := a.client.Call("PutFile", 0, sourcefile, targetfile).Store(&transferPath, &result)
+
+	transportProps := new(ObexTransfer1Properties)
+	util.MapToStruct(transportProps, result)
+
+	return transferPath, transportProps, err
+}
+
+//
+// Copy a file within the remote device from source file
+// to target file.
+//
+// Possible errors:
+//  - org.bluez.obex.Error.InvalidArguments
+//  - org.bluez.obex.Error.Failed
+//
+func (a *ObexFileTransfer1) CopyFile(sourcefile string, targetfile string) error {
+	return a.client.Call("CopyFile", 0, sourcefile, targetfile).Store()
+}
+
+//
+// Move a file within the remote device from source file
+// to the target file.
+//
+// Possible errors:
+//  - org.bluez.obex.Error.InvalidArguments
+//  - org.bluez.obex.Error.Failed
+//
+func (a *ObexFileTransfer1) MoveFile(sourcefile string, targetfile string) error {
+	return a.client.Call("MoveFile", 0, sourcefile, targetfile).Store()
+}
+
+//
+// Deletes the specified file/folder.
+//
+// Possible errors:
+//  - org.bluez.obex.Error.InvalidArguments
+//  - org.bluez.obex.Error.Failed
+//
+func (a *ObexFileTransfer1) Delete(file string) error {
+	return a.client.Call("Delete", 0, file).Store()
+}
@@ -0,0 +1,102 @@
+package obex
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
+)
+
+//ObexMessage1Interface the obexd interface for Message1
+const ObexMessage1Interface = "org.bluez.obex.Message1"
+
+// NewObexMessage1 create a new Message1 client
+func NewObexMessage1(path string) *ObexMessage1 {
+	a := new(ObexMessage1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez.obex",
+			Iface: ObexMessage1Interface,
+			Path:  path,
+			Bus:   bluez.SessionBus,
+		},
+	)
+	a.Properties = new(ObexMessage1Properties)
+	return a
+}
+
+// ObexMessage1 client
+type ObexMessage1 struct {
+	client     *bluez.Client
+	Properties *ObexMessage1Properties
+}
+
+// ObexMessage1Properties exposed properties for Message1
+type ObexMessage1Properties struct {
+	Folder           string // [readonly] Folder which the message belongs to
+	Subject          string // [readonly] Message subject
+	Timestamp        string // [readonly] Message timestamp
+	Sender           string // [readonly] Message sender name
+	SenderAddress    string // [readonly] Message sender address
+	ReplyTo          string // [readonly] Message Reply-To address
+	Recipient        string // [readonly] Message recipient name
+	RecipientAddress string // [readonly] Message recipient address
+	Type             string // [readonly] Message type: email, sms-gsm, sms-cdma or mms
+	Size             uint64 // [readonly] Message size in bytes
+	Status           string // [readonly] Message reception status: complete, fractioned or notification
+	Priority         bool   // [readonly] Message priority flag
+	Read             bool   // Message read flag
+	Deleted          bool   // [writeonly] Message deleted flag
+	Sent             bool   // [readonly] Message sent flag
+	Protected        bool   // [readonly] Message protected flag
+}
+
+// Close the connection
+func (a *ObexMessage1) Close() {
+	a.client.Disconnect()
+}
+
+//Path return the message object path
+func (a *ObexMessage1) Path() string {
+	return a.client.Config.Path
+}
+
+//GetProperties load all available properties
+func (a *ObexMessage1) GetProperties() (*ObexMessage1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+//SetRead mark the message as read or unread on the remote device
+func (a *ObexMessage1) SetRead(read bool) error {
+	return a.client.SetProperty("Read", dbus.MakeVariant(read))
+}
+
+//SetDeleted mark the message as deleted or undeleted on the remote device
+func (a *ObexMessage1) SetDeleted(deleted bool) error {
+	return a.client.SetProperty("Deleted", dbus.MakeVariant(deleted))
+}
+
+//
+// Download message and store it in the target file.
+//
+// If an empty target file is given, a temporary file
+// will be automatically generated.
+//
+// The returned path represents the newly created transfer,
+// which should be used to find out if the content has been
+// successfully transferred or if the operation fails.
+//
+// Possible errors: org.bluez.obex.Error.InvalidArguments
+//		org.bluez.obex.Error.Failed
+//
+func (a *ObexMessage1) Get(targetfile string, attachment bool) (string, *ObexTransfer1Properties, error) {
+
+	result := make(map[string]dbus.Variant)
+	var transferPath string
+	err := a.client.Call("Get", 0, targetfile, attachment).Store(&transferPath, &result)
+
+	transportProps := new(ObexTransfer1Properties)
+	util.MapToStruct(transportProps, result)
+
+	return transferPath, transportProps, err
+}
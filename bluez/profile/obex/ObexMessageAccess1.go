@@ -0,0 +1,240 @@
+package obex
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
+)
+
+// Message types
+const (
+	MessageTypeEmail   = "email"
+	MessageTypeSMSGSM  = "sms-gsm"
+	MessageTypeSMSCDMA = "sms-cdma"
+	MessageTypeMMS     = "mms"
+)
+
+// NewObexMessageAccess1 create a new MessageAccess1 client
+func NewObexMessageAccess1(sessionPath string) *ObexMessageAccess1 {
+	a := new(ObexMessageAccess1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez.obex",
+			Iface: "org.bluez.obex.MessageAccess1",
+			Path:  sessionPath,
+			Bus:   bluez.SessionBus,
+		},
+	)
+	return a
+}
+
+// ObexMessageAccess1 client
+type ObexMessageAccess1 struct {
+	client *bluez.Client
+}
+
+//MessageFilters options accepted by ListMessages.
+// Zero values are not sent, leaving the server defaults
+type MessageFilters struct {
+	// Offset of the first item
+	Offset uint16
+	// MaxCount maximum number of items
+	MaxCount uint16
+	// SubjectLength maximum length of the Subject property
+	SubjectLength byte
+	// Fields message properties to return, see ListFilterFields
+	Fields []string
+	// Types message types to return, eg. sms-gsm, email
+	Types []string
+	// PeriodBegin filter messages by timestamp, eg. 20180320T100000
+	PeriodBegin string
+	// PeriodEnd filter messages by timestamp
+	PeriodEnd string
+	// Read return only read (true) or unread (false) messages
+	Read *bool
+	// Recipient filter messages by recipient address
+	Recipient string
+	// Sender filter messages by sender address
+	Sender string
+	// Priority return only high (true) or non high (false) priority messages
+	Priority *bool
+}
+
+//ToMap convert the filters to the dictionary expected by obexd
+func (f *MessageFilters) ToMap() map[string]interface{} {
+	m := make(map[string]interface{})
+	if f == nil {
+		return m
+	}
+	if f.Offset > 0 {
+		m["Offset"] = f.Offset
+	}
+	if f.MaxCount > 0 {
+		m["MaxCount"] = f.MaxCount
+	}
+	if f.SubjectLength > 0 {
+		m["SubjectLength"] = f.SubjectLength
+	}
+	if len(f.Fields) > 0 {
+		m["Fields"] = f.Fields
+	}
+	if len(f.Types) > 0 {
+		m["Types"] = f.Types
+	}
+	if f.PeriodBegin != "" {
+		m["PeriodBegin"] = f.PeriodBegin
+	}
+	if f.PeriodEnd != "" {
+		m["PeriodEnd"] = f.PeriodEnd
+	}
+	if f.Read != nil {
+		m["Read"] = *f.Read
+	}
+	if f.Recipient != "" {
+		m["Recipient"] = f.Recipient
+	}
+	if f.Sender != "" {
+		m["Sender"] = f.Sender
+	}
+	if f.Priority != nil {
+		m["Priority"] = *f.Priority
+	}
+	return m
+}
+
+//MessageFolder an item returned by ListFolders
+type MessageFolder struct {
+	Name string
+}
+
+// Close the connection
+func (a *ObexMessageAccess1) Close() {
+	a.client.Disconnect()
+}
+
+//
+// Set working directory for current session, *name* may
+// be the directory name or '..[/dir]'.
+//
+// Possible errors: org.bluez.obex.Error.InvalidArguments
+//		org.bluez.obex.Error.Failed
+//
+func (a *ObexMessageAccess1) SetFolder(name string) error {
+	return a.client.Call("SetFolder", 0, name).Store()
+}
+
+//
+// Returns a dictionary containing information about
+// the current folder content.
+//
+// The following keys are defined:
+//
+//	string Name : Folder name
+//
+// Possible filters: Offset and MaxCount
+//
+// Possible errors: org.bluez.obex.Error.InvalidArguments
+//		org.bluez.obex.Error.Failed
+//
+func (a *ObexMessageAccess1) ListFolders(filters *MessageFilters) ([]MessageFolder, error) {
+
+	var result []map[string]dbus.Variant
+	err := a.client.Call("ListFolders", 0, filters.ToMap()).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make([]MessageFolder, 0, len(result))
+	for _, item := range result {
+		folder := MessageFolder{}
+		if name, ok := item["Name"]; ok {
+			folder.Name, _ = name.Value().(string)
+		}
+		folders = append(folders, folder)
+	}
+
+	return folders, nil
+}
+
+//
+// Return all available fields that can be used in Fields
+// filter.
+//
+// Possible errors: None
+//
+func (a *ObexMessageAccess1) ListFilterFields() ([]string, error) {
+	var fields []string
+	err := a.client.Call("ListFilterFields", 0).Store(&fields)
+	return fields, err
+}
+
+//
+// Returns an array containing the messages found in the
+// given subfolder of the current folder, or in the
+// current folder if folder is empty.
+//
+// Each message is represented by an object path followed
+// by a dictionary of the properties, see Message1.
+//
+// Possible errors: org.bluez.obex.Error.InvalidArguments
+//		org.bluez.obex.Error.Failed
+//
+func (a *ObexMessageAccess1) ListMessages(folder string, filters *MessageFilters) (map[dbus.ObjectPath]*ObexMessage1Properties, error) {
+
+	var result map[dbus.ObjectPath]map[string]dbus.Variant
+	err := a.client.Call("ListMessages", 0, folder, filters.ToMap()).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make(map[dbus.ObjectPath]*ObexMessage1Properties, len(result))
+	for path, item := range result {
+		props := new(ObexMessage1Properties)
+		util.MapToStruct(props, item)
+		messages[path] = props
+	}
+
+	return messages, nil
+}
+
+//
+// Request remote to update its inbox.
+//
+// Possible errors: org.bluez.obex.Error.Failed
+//
+func (a *ObexMessageAccess1) UpdateInbox() error {
+	return a.client.Call("UpdateInbox", 0).Store()
+}
+
+//
+// Transfer a message (in bMessage format) to the
+// remote device.
+//
+// The message is transferred either to the given
+// subfolder of the current folder, or to the current
+// folder if folder is empty.
+//
+// Possible args: Transparent, Retry, Charset
+//
+// The returned path represents the newly created transfer,
+// which should be used to find out if the content has been
+// successfully transferred or if the operation fails.
+//
+// Possible errors: org.bluez.obex.Error.InvalidArguments
+//		org.bluez.obex.Error.Failed
+//
+func (a *ObexMessageAccess1) PushMessage(sourcefile string, folder string, args map[string]interface{}) (string, *ObexTransfer1Properties, error) {
+
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	result := make(map[string]dbus.Variant)
+	var transferPath string
+	err := a.client.Call("PushMessage", 0, sourcefile, folder, args).Store(&transferPath, &result)
+
+	transportProps := new(ObexTransfer1Properties)
+	util.MapToStruct(transportProps, result)
+
+	return transferPath, transportProps, err
+}
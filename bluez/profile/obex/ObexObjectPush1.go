@@ -55,3 +55,53 @@ func (a *ObjectPush1) SendFile(sourcefile string) (string, *ObexTransfer1Propert
 
 	return sessionPath, transportProps, err
 }
+
+//
+// Pull the default business card from a remote device.
+//
+// If an empty target file is given, a name will be
+// automatically calculated for the temporary file.
+//
+// The returned path represents the newly created transfer,
+// which should be used to find out if the content has been
+// successfully transferred or if the operation fails.
+//
+// Possible errors:
+//  - org.bluez.obex.Error.InvalidArguments
+//  - org.bluez.obex.Error.Failed
+//
+func (a *ObjectPush1) PullBusinessCard(targetfile string) (string, *ObexTransfer1Properties, error) {
+
+	result := make(map[string]dbus.Variant)
+	var transferPath string
+	err := a.client.Call("PullBusinessCard", 0, targetfile).Store(&transferPath, &result)
+
+	transportProps := new(ObexTransfer1Properties)
+	util.MapToStruct(transportProps, result)
+
+	return transferPath, transportProps, err
+}
+
+//
+// Push the client's business card to the remote device
+// and then retrieve the remote business card and store
+// it in a local file.
+//
+// If an empty target file is given, a name will be
+// automatically calculated for the temporary file.
+//
+// Possible errors:
+//  - org.bluez.obex.Error.InvalidArguments
+//  - org.bluez.obex.Error.Failed
+//
+func (a *ObjectPush1) ExchangeBusinessCards(clientfile string, targetfile string) (string, *ObexTransfer1Properties, error) {
+
+	result := make(map[string]dbus.Variant)
+	var transferPath string
+	err := a.client.Call("ExchangeBusinessCards", 0, clientfile, targetfile).Store(&transferPath, &result)
+
+	transportProps := new(ObexTransfer1Properties)
+	util.MapToStruct(transportProps, result)
+
+	return transferPath, transportProps, err
+}
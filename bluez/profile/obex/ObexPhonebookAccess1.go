@@ -0,0 +1,259 @@
+package obex
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
+)
+
+// Phonebook locations
+const (
+	PhonebookLocationInternal = "int"
+	PhonebookLocationSIM1     = "sim1"
+	PhonebookLocationSIM2     = "sim2"
+)
+
+// Phonebook objects
+const (
+	PhonebookContacts  = "pb"
+	PhonebookIncoming  = "ich"
+	PhonebookOutgoing  = "och"
+	PhonebookMissed    = "mch"
+	PhonebookCombined  = "cch"
+	PhonebookSpeedDial = "spd"
+	PhonebookFavorites = "fav"
+)
+
+// vCard formats
+const (
+	PhonebookFormatVCard21 = "vcard21"
+	PhonebookFormatVCard30 = "vcard30"
+)
+
+// Listing orders
+const (
+	PhonebookOrderIndexed      = "indexed"
+	PhonebookOrderAlphanumeric = "alphanumeric"
+	PhonebookOrderPhonetic     = "phonetic"
+)
+
+// NewObexPhonebookAccess1 create a new PhonebookAccess1 client
+func NewObexPhonebookAccess1(sessionPath string) *ObexPhonebookAccess1 {
+	a := new(ObexPhonebookAccess1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez.obex",
+			Iface: "org.bluez.obex.PhonebookAccess1",
+			Path:  sessionPath,
+			Bus:   bluez.SessionBus,
+		},
+	)
+	a.Properties = new(ObexPhonebookAccess1Properties)
+	return a
+}
+
+// ObexPhonebookAccess1 client
+type ObexPhonebookAccess1 struct {
+	client     *bluez.Client
+	Properties *ObexPhonebookAccess1Properties
+}
+
+// ObexPhonebookAccess1Properties exposed properties for PhonebookAccess1
+type ObexPhonebookAccess1Properties struct {
+	Folder             string
+	DatabaseIdentifier string
+	PrimaryCounter     string
+	SecondaryCounter   string
+	FixedImageSize     bool
+}
+
+//PhonebookFilters options accepted by PullAll, Pull, List and Search.
+// Zero values are not sent, leaving the server defaults
+type PhonebookFilters struct {
+	// Format vcard21 or vcard30
+	Format string `structs:",omitempty"`
+	// Order indexed, alphanumeric or phonetic
+	Order string `structs:",omitempty"`
+	// Offset of the first item
+	Offset uint16 `structs:",omitempty"`
+	// MaxCount maximum number of items
+	MaxCount uint16 `structs:",omitempty"`
+	// Fields vCard fields to return, see ListFilterFields
+	Fields []string `structs:",omitempty"`
+	// FilterAll return only items having all the fields
+	FilterAll []string `structs:",omitempty"`
+	// FilterAny return only items having at least one of the fields
+	FilterAny []string `structs:",omitempty"`
+}
+
+//ToMap convert the filters to the dictionary expected by obexd
+func (f *PhonebookFilters) ToMap() map[string]interface{} {
+	if f == nil {
+		return map[string]interface{}{}
+	}
+	return util.StructToMap(f)
+}
+
+//PhonebookEntry an item returned by List and Search
+type PhonebookEntry struct {
+	// Handle the vCard name to use with Pull, eg. 1.vcf
+	Handle string
+	Name   string
+}
+
+// Close the connection
+func (a *ObexPhonebookAccess1) Close() {
+	a.client.Disconnect()
+}
+
+//GetProperties load all available properties
+func (a *ObexPhonebookAccess1) GetProperties() (*ObexPhonebookAccess1Properties, error) {
+	err := a.client.GetProperties(a.Properties)
+	return a.Properties, err
+}
+
+//
+// Select the phonebook object for other operations. Should
+// be call before all the other operations.
+//
+// location : Where the phonebook is stored, possible
+// inputs :
+//	"int" ( "internal" which is default )
+//	"sim1"
+//	"sim2"
+//	...
+//
+// phonebook : Possible inputs :
+//	"pb" :	phonebook for the saved contacts
+//	"ich":	incoming call history
+//	"och":	outgoing call history
+//	"mch":	missing call history
+//	"cch":	combination of ich och mch
+//	"spd":	speed dials entry ( only for "internal" )
+//	"fav":	favorites entry ( only for "internal" )
+//
+// Possible errors: org.bluez.obex.Error.InvalidArguments
+//		org.bluez.obex.Error.Failed
+//
+func (a *ObexPhonebookAccess1) Select(location string, phonebook string) error {
+	return a.client.Call("Select", 0, location, phonebook).Store()
+}
+
+//
+// Return the entire phonebook object from the PSE server
+// in plain string with vcard format, and store it in
+// a local file.
+//
+// If an empty target file is given, a name will be
+// automatically calculated for the temporary file.
+//
+// The returned path represents the newly created transfer,
+// which should be used to find out if the content has been
+// successfully transferred or if the operation fails.
+//
+// Possible errors: org.bluez.obex.Error.InvalidArguments
+//		org.bluez.obex.Error.Forbidden
+//
+func (a *ObexPhonebookAccess1) PullAll(targetfile string, filters *PhonebookFilters) (string, *ObexTransfer1Properties, error) {
+
+	result := make(map[string]dbus.Variant)
+	var transferPath string
+	err := a.client.Call("PullAll", 0, targetfile, filters.ToMap()).Store(&transferPath, &result)
+
+	transportProps := new(ObexTransfer1Properties)
+	util.MapToStruct(transportProps, result)
+
+	return transferPath, transportProps, err
+}
+
+//
+// Return an array of vcard-listing data where every entry
+// consists of a pair of strings containing the vcard
+// handle and the contact name. For example:
+//	"1.vcf" : "John"
+//
+// Possible errors: org.bluez.obex.Error.InvalidArguments
+//		org.bluez.obex.Error.Forbidden
+//
+func (a *ObexPhonebookAccess1) List(filters *PhonebookFilters) ([]PhonebookEntry, error) {
+	var entries []PhonebookEntry
+	err := a.client.Call("List", 0, filters.ToMap()).Store(&entries)
+	return entries, err
+}
+
+//
+// Given a vcard handle, retrieve the vcard in the current
+// phonebook object and store it in a local file.
+//
+// If an empty target file is given, a name will be
+// automatically calculated for the temporary file.
+//
+// Possible errors: org.bluez.obex.Error.InvalidArguments
+//		org.bluez.obex.Error.Forbidden
+//		org.bluez.obex.Error.Failed
+//
+func (a *ObexPhonebookAccess1) Pull(vcard string, targetfile string, filters *PhonebookFilters) (string, *ObexTransfer1Properties, error) {
+
+	result := make(map[string]dbus.Variant)
+	var transferPath string
+	err := a.client.Call("Pull", 0, vcard, targetfile, filters.ToMap()).Store(&transferPath, &result)
+
+	transportProps := new(ObexTransfer1Properties)
+	util.MapToStruct(transportProps, result)
+
+	return transferPath, transportProps, err
+}
+
+//
+// Search for entries matching the given condition and
+// return an array of vcard-listing data where every entry
+// consists of a pair of strings containing the vcard
+// handle and the contact name.
+//
+// field : name (default) | number | sound
+// value : string value to search for
+//
+// Possible errors: org.bluez.obex.Error.InvalidArguments
+//		org.bluez.obex.Error.Forbidden
+//		org.bluez.obex.Error.Failed
+//
+func (a *ObexPhonebookAccess1) Search(field string, value string, filters *PhonebookFilters) ([]PhonebookEntry, error) {
+	var entries []PhonebookEntry
+	err := a.client.Call("Search", 0, field, value, filters.ToMap()).Store(&entries)
+	return entries, err
+}
+
+//
+// Return the number of entries in the selected phonebook
+// object that are actually used (i.e. indexes that
+// correspond to non-NULL entries).
+//
+// Possible errors: org.bluez.obex.Error.Forbidden
+//		org.bluez.obex.Error.Failed
+//
+func (a *ObexPhonebookAccess1) GetSize() (uint16, error) {
+	var size uint16
+	err := a.client.Call("GetSize", 0).Store(&size)
+	return size, err
+}
+
+//
+// Attempt to update PrimaryCounter and SecondaryCounter.
+//
+// Possible errors: org.bluez.obex.Error.NotSupported
+//		org.bluez.obex.Error.Forbidden
+//		org.bluez.obex.Error.Failed
+//
+func (a *ObexPhonebookAccess1) UpdateVersion() error {
+	return a.client.Call("UpdateVersion", 0).Store()
+}
+
+//
+// Return All Available fields that can be used in Fields
+// filter.
+//
+func (a *ObexPhonebookAccess1) ListFilterFields() ([]string, error) {
+	var fields []string
+	err := a.client.Call("ListFilterFields", 0).Store(&fields)
+	return fields, err
+}
@@ -5,13 +5,25 @@ import (
 	"github.com/muka/go-bluetooth/bluez"
 )
 
+// Transfer status values
+const (
+	TransferStatusQueued    = "queued"
+	TransferStatusActive    = "active"
+	TransferStatusSuspended = "suspended"
+	TransferStatusComplete  = "complete"
+	TransferStatusError     = "error"
+)
+
+//ObexTransfer1Interface the obexd interface for Transfer1
+const ObexTransfer1Interface = "org.bluez.obex.Transfer1"
+
 // NewObexTransfer1 create a new ObexTransfer1 client
 func NewObexTransfer1(path string) *ObexTransfer1 {
 	a := new(ObexTransfer1)
 	a.client = bluez.NewClient(
 		&bluez.Config{
 			Name:  "org.bluez.obex",
-			Iface: "org.bluez.obex.Transfer1",
+			Iface: ObexTransfer1Interface,
 			Path:  path,
 			Bus:   bluez.SessionBus,
 		},
@@ -44,6 +56,21 @@ func (d *ObexTransfer1) Close() {
 	d.client.Disconnect()
 }
 
+//Path return the transfer object path
+func (d *ObexTransfer1) Path() string {
+	return d.client.Config.Path
+}
+
+//Register for changes signalling
+func (d *ObexTransfer1) Register() (chan *dbus.Signal, error) {
+	return d.client.Register(d.client.Config.Path, bluez.PropertiesInterface)
+}
+
+//Unregister for changes signalling
+func (d *ObexTransfer1) Unregister(signal chan *dbus.Signal) error {
+	return d.client.Unregister(d.client.Config.Path, bluez.PropertiesInterface, signal)
+}
+
 //GetProperties load all available properties
 func (d *ObexTransfer1) GetProperties() (*ObexTransfer1Properties, error) {
 	err := d.client.GetProperties(d.Properties)
@@ -0,0 +1,213 @@
+package obex
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+//ProgressCallback receive updates about a running transfer
+type ProgressCallback func(p TransferProgress)
+
+//NewFTPSession open a File Transfer session to a remote device
+func NewFTPSession(address string) (*FTPSession, error) {
+
+	client := NewObexClient1()
+	sessionPath, err := client.CreateSession(address, map[string]interface{}{
+		"Target": SessionTargetFTP,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FTPSession{
+		client:       client,
+		sessionPath:  sessionPath,
+		fileTransfer: NewObexFileTransfer1(sessionPath),
+	}
+
+	return s, nil
+}
+
+//FTPSession browse and transfer files on a remote device
+type FTPSession struct {
+	client       *ObexClient1
+	sessionPath  string
+	fileTransfer *ObexFileTransfer1
+}
+
+//Path return the session object path
+func (s *FTPSession) Path() string {
+	return s.sessionPath
+}
+
+//Close the session, aborting pending transfers
+func (s *FTPSession) Close() error {
+	return s.client.RemoveSession(s.sessionPath)
+}
+
+//ListFolder list the content of the current folder
+func (s *FTPSession) ListFolder() ([]FolderEntry, error) {
+	return s.fileTransfer.ListFolder()
+}
+
+//ChangeFolder change the current folder, use .. to move to the parent
+func (s *FTPSession) ChangeFolder(folder string) error {
+	return s.fileTransfer.ChangeFolder(folder)
+}
+
+//CreateFolder create a folder and move into it
+func (s *FTPSession) CreateFolder(folder string) error {
+	return s.fileTransfer.CreateFolder(folder)
+}
+
+//Delete a file or folder in the current folder
+func (s *FTPSession) Delete(name string) error {
+	return s.fileTransfer.Delete(name)
+}
+
+//Download copy a remote file to a local path, waiting for the transfer to complete
+func (s *FTPSession) Download(name string, targetfile string, onProgress ProgressCallback) error {
+	return download(s.sessionPath, targetfile, func() (string, error) {
+		transferPath, _, err := s.fileTransfer.GetFile(targetfile, name)
+		return transferPath, err
+	}, onProgress)
+}
+
+//Upload copy a local file to the current remote folder, waiting for the transfer to complete
+func (s *FTPSession) Upload(sourcefile string, name string, onProgress ProgressCallback) error {
+	_, err := runTransfer(s.sessionPath, func() (string, error) {
+		transferPath, _, err := s.fileTransfer.PutFile(sourcefile, name)
+		return transferPath, err
+	}, onProgress)
+	return err
+}
+
+//GetFile download a remote file and return a reader over its content.
+// The content is buffered on a temporary file which is removed on Close
+func (s *FTPSession) GetFile(name string, onProgress ProgressCallback) (io.ReadCloser, error) {
+
+	tmpfile, err := ioutil.TempFile("", "obex-ftp-")
+	if err != nil {
+		return nil, err
+	}
+	tmpfile.Close()
+
+	err = s.Download(name, tmpfile.Name(), onProgress)
+	if err != nil {
+		os.Remove(tmpfile.Name())
+		return nil, err
+	}
+
+	f, err := os.Open(tmpfile.Name())
+	if err != nil {
+		os.Remove(tmpfile.Name())
+		return nil, err
+	}
+
+	return &tempFile{f}, nil
+}
+
+//PutFile return a writer for a new remote file in the current folder.
+// The content is buffered on a temporary file and uploaded on Close,
+// which returns once the transfer is over
+func (s *FTPSession) PutFile(name string, onProgress ProgressCallback) (io.WriteCloser, error) {
+
+	tmpfile, err := ioutil.TempFile("", "obex-ftp-")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &uploadWriter{
+		tempFile:   tempFile{tmpfile},
+		session:    s,
+		name:       name,
+		onProgress: onProgress,
+	}
+
+	return w, nil
+}
+
+// tempFile remove the underlying file on Close
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// uploadWriter upload the written content on Close
+type uploadWriter struct {
+	tempFile
+	session    *FTPSession
+	name       string
+	onProgress ProgressCallback
+}
+
+func (w *uploadWriter) Close() error {
+	defer os.Remove(w.Name())
+
+	err := w.File.Close()
+	if err != nil {
+		return err
+	}
+
+	return w.session.Upload(w.Name(), w.name, w.onProgress)
+}
+
+// runTransfer start a transfer of the session and block until it is done
+func runTransfer(sessionPath string, start func() (string, error), onProgress ProgressCallback) (TransferProgress, error) {
+
+	progress, err := watchTransfer(sessionPath, start)
+	if err != nil {
+		return TransferProgress{}, err
+	}
+
+	var last TransferProgress
+	for p := range progress {
+		last = p
+		if onProgress != nil {
+			onProgress(p)
+		}
+	}
+
+	if last.Err != nil {
+		return last, last.Err
+	}
+	if last.Status != TransferStatusComplete {
+		return last, errors.New("Transfer " + last.Path + " interrupted")
+	}
+
+	return last, nil
+}
+
+// download run a transfer of the session to targetfile, the bytes transferred
+// and the file received are checked against the size announced, if any
+func download(sessionPath string, targetfile string, start func() (string, error), onProgress ProgressCallback) error {
+
+	last, err := runTransfer(sessionPath, start, onProgress)
+	if err != nil {
+		return err
+	}
+	if last.Size == 0 {
+		return nil
+	}
+	if last.Transferred != 0 && last.Transferred != last.Size {
+		return fmt.Errorf("Transfer %s incomplete: %d bytes transferred out of %d", last.Path, last.Transferred, last.Size)
+	}
+
+	info, err := os.Stat(targetfile)
+	if err != nil {
+		return err
+	}
+	if uint64(info.Size()) != last.Size {
+		return fmt.Errorf("Transfer %s incomplete: %d bytes received out of %d", last.Path, info.Size(), last.Size)
+	}
+
+	return nil
+}
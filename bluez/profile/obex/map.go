@@ -0,0 +1,207 @@
+package obex
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
+)
+
+//MessageEventType the kind of change reported by a MessageEvent
+type MessageEventType int
+
+// Message event types
+const (
+	// MessageAdded a new message is available, eg. an incoming SMS
+	MessageAdded MessageEventType = iota
+	// MessageRemoved a message has been removed
+	MessageRemoved
+)
+
+//MessageEvent a change of the messages available on the remote device
+type MessageEvent struct {
+	Type       MessageEventType
+	Path       dbus.ObjectPath
+	Properties *ObexMessage1Properties
+}
+
+//NewMAPSession open a Message Access session to a remote device, eg. a paired phone
+func NewMAPSession(address string) (*MAPSession, error) {
+
+	client := NewObexClient1()
+	sessionPath, err := client.CreateSession(address, map[string]interface{}{
+		"Target": SessionTargetMAP,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &MAPSession{
+		client:        client,
+		sessionPath:   sessionPath,
+		messageAccess: NewObexMessageAccess1(sessionPath),
+	}
+
+	return s, nil
+}
+
+//MAPSession list, fetch and update SMS and email messages on a remote device
+type MAPSession struct {
+	client        *ObexClient1
+	sessionPath   string
+	messageAccess *ObexMessageAccess1
+	objectManager *bluez.Client
+	signals       chan *dbus.Signal
+}
+
+//Path return the session object path
+func (s *MAPSession) Path() string {
+	return s.sessionPath
+}
+
+//Close the session, stopping notifications
+func (s *MAPSession) Close() error {
+	s.StopNotifications()
+	return s.client.RemoveSession(s.sessionPath)
+}
+
+//SetFolder change the current folder, eg. telecom/msg/inbox
+func (s *MAPSession) SetFolder(name string) error {
+	return s.messageAccess.SetFolder(name)
+}
+
+//ListFolders list the subfolders of the current folder
+func (s *MAPSession) ListFolders() ([]MessageFolder, error) {
+	return s.messageAccess.ListFolders(nil)
+}
+
+//ListMessages list the messages of a subfolder of the current folder, or of
+// the current folder if folder is empty
+func (s *MAPSession) ListMessages(folder string, filters *MessageFilters) (map[dbus.ObjectPath]*ObexMessage1Properties, error) {
+	return s.messageAccess.ListMessages(folder, filters)
+}
+
+//UpdateInbox ask the remote device to check for new messages
+func (s *MAPSession) UpdateInbox() error {
+	return s.messageAccess.UpdateInbox()
+}
+
+//GetMessage download a message, returning its content in bMessage format
+func (s *MAPSession) GetMessage(path dbus.ObjectPath, attachment bool) (string, error) {
+
+	tmpfile, err := ioutil.TempFile("", "obex-map-")
+	if err != nil {
+		return "", err
+	}
+	tmpfile.Close()
+	defer os.Remove(tmpfile.Name())
+
+	err = download(s.sessionPath, tmpfile.Name(), func() (string, error) {
+		transferPath, _, err := NewObexMessage1(string(path)).Get(tmpfile.Name(), attachment)
+		return transferPath, err
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := ioutil.ReadFile(tmpfile.Name())
+	return string(content), err
+}
+
+//MarkRead mark a message as read or unread
+func (s *MAPSession) MarkRead(path dbus.ObjectPath, read bool) error {
+	return NewObexMessage1(string(path)).SetRead(read)
+}
+
+//MarkDeleted mark a message as deleted or undeleted
+func (s *MAPSession) MarkDeleted(path dbus.ObjectPath, deleted bool) error {
+	return NewObexMessage1(string(path)).SetDeleted(deleted)
+}
+
+//Notifications report messages added to or removed from the session.
+// obexd registers for remote notifications when the session is created, new
+// messages are published as Message1 objects under the session path.
+// The channel is closed by StopNotifications or Close
+func (s *MAPSession) Notifications() (<-chan MessageEvent, error) {
+
+	if s.objectManager == nil {
+		s.objectManager = bluez.NewClient(
+			&bluez.Config{
+				Name:  "org.bluez.obex",
+				Iface: bluez.ObjectManagerInterface,
+				Path:  "/",
+				Bus:   bluez.SessionBus,
+			},
+		)
+	}
+
+	signals, err := s.objectManager.Register("/", bluez.ObjectManagerInterface)
+	if err != nil {
+		return nil, err
+	}
+	s.signals = signals
+
+	events := make(chan MessageEvent, 1)
+	prefix := s.sessionPath + "/"
+
+	go func() {
+		defer close(events)
+		for sig := range signals {
+
+			if sig == nil {
+				return
+			}
+
+			if len(sig.Body) < 2 {
+				continue
+			}
+
+			path, ok := sig.Body[0].(dbus.ObjectPath)
+			if !ok || !strings.HasPrefix(string(path), prefix) {
+				continue
+			}
+
+			switch sig.Name {
+			case bluez.InterfacesAdded:
+				ifaces, ok := sig.Body[1].(map[string]map[string]dbus.Variant)
+				if !ok {
+					continue
+				}
+				props, ok := ifaces[ObexMessage1Interface]
+				if !ok {
+					continue
+				}
+				msg := new(ObexMessage1Properties)
+				util.MapToStruct(msg, props)
+				events <- MessageEvent{MessageAdded, path, msg}
+			case bluez.InterfacesRemoved:
+				ifaces, ok := sig.Body[1].([]string)
+				if !ok {
+					continue
+				}
+				for _, iface := range ifaces {
+					if iface == ObexMessage1Interface {
+						events <- MessageEvent{MessageRemoved, path, nil}
+					}
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+//StopNotifications stop watching for new messages
+func (s *MAPSession) StopNotifications() error {
+	if s.signals == nil {
+		return nil
+	}
+	signals := s.signals
+	s.signals = nil
+	err := s.objectManager.Unregister("/", bluez.ObjectManagerInterface, signals)
+	close(signals)
+	return err
+}
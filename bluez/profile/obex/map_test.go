@@ -0,0 +1,111 @@
+package obex
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+const testMessagePath = testSessionPath + "/message1"
+
+// fakeMessage a Message1 downloading content
+type fakeMessage struct {
+	conn    *dbus.Conn
+	content string
+	status  string
+}
+
+func (m *fakeMessage) Get(targetfile string, attachment bool) (dbus.ObjectPath, map[string]dbus.Variant, *dbus.Error) {
+	exportTransfer(m.conn, TransferStatusQueued, 0)
+	go func() {
+		ioutil.WriteFile(targetfile, []byte(m.content), 0644)
+		m.conn.Emit(testTransferPath, bluez.PropertiesChanged, ObexTransfer1Interface, map[string]dbus.Variant{
+			"Size":   dbus.MakeVariant(uint64(len(m.content))),
+			"Status": dbus.MakeVariant(m.status),
+		}, []string{})
+		removeTransfer(m.conn)
+	}()
+	return testTransferPath, map[string]dbus.Variant{}, nil
+}
+
+func TestMAPGetMessage(t *testing.T) {
+
+	server, stop := startObexd(t)
+	defer stop()
+
+	msg := &fakeMessage{conn: server, content: "BEGIN:BMSG\r\nEND:BMSG\r\n", status: TransferStatusComplete}
+	if err := server.Export(msg, testMessagePath, ObexMessage1Interface); err != nil {
+		t.Fatal(err)
+	}
+	s := &MAPSession{sessionPath: testSessionPath}
+
+	content, err := s.GetMessage(testMessagePath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != msg.content {
+		t.Fatalf("Expected %q, got %q", msg.content, content)
+	}
+
+	msg.status = TransferStatusError
+	if content, err = s.GetMessage(testMessagePath, false); err == nil {
+		t.Fatalf("Expected an error, got %q", content)
+	}
+}
+
+func TestMAPNotifications(t *testing.T) {
+
+	server, stop := startObexd(t)
+	defer stop()
+
+	s := &MAPSession{sessionPath: testSessionPath}
+	events, err := s.Notifications()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() MessageEvent {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for the event")
+		}
+		return MessageEvent{}
+	}
+
+	// another session and another interface are ignored
+	server.Emit("/", bluez.InterfacesAdded, dbus.ObjectPath("/org/bluez/obex/client/session1/message1"),
+		map[string]map[string]dbus.Variant{ObexMessage1Interface: {}})
+	server.Emit("/", bluez.InterfacesAdded, dbus.ObjectPath(testTransferPath),
+		map[string]map[string]dbus.Variant{ObexTransfer1Interface: {}})
+	server.Emit("/", bluez.InterfacesAdded, dbus.ObjectPath(testMessagePath),
+		map[string]map[string]dbus.Variant{ObexMessage1Interface: {
+			"Subject": dbus.MakeVariant("hello"),
+			"Type":    dbus.MakeVariant("sms-gsm"),
+		}})
+	ev := next()
+	if ev.Type != MessageAdded || ev.Path != testMessagePath || ev.Properties.Subject != "hello" || ev.Properties.Type != "sms-gsm" {
+		t.Fatalf("Unexpected event %+v", ev)
+	}
+
+	server.Emit("/", bluez.InterfacesRemoved, dbus.ObjectPath(testMessagePath), []string{ObexMessage1Interface})
+	if ev = next(); ev.Type != MessageRemoved || ev.Path != testMessagePath {
+		t.Fatalf("Unexpected event %+v", ev)
+	}
+
+	if err = s.StopNotifications(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("Unexpected event after stop")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the events closed")
+	}
+}
@@ -0,0 +1,114 @@
+package obex
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+//NewPBAPSession open a Phonebook Access session to a remote device, eg. a paired phone
+func NewPBAPSession(address string) (*PBAPSession, error) {
+
+	client := NewObexClient1()
+	sessionPath, err := client.CreateSession(address, map[string]interface{}{
+		"Target": SessionTargetPBAP,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PBAPSession{
+		client:          client,
+		sessionPath:     sessionPath,
+		phonebookAccess: NewObexPhonebookAccess1(sessionPath),
+	}
+
+	return s, nil
+}
+
+//PBAPSession retrieve contacts and call history from a remote device
+type PBAPSession struct {
+	client          *ObexClient1
+	sessionPath     string
+	phonebookAccess *ObexPhonebookAccess1
+}
+
+//Path return the session object path
+func (s *PBAPSession) Path() string {
+	return s.sessionPath
+}
+
+//Close the session
+func (s *PBAPSession) Close() error {
+	return s.client.RemoveSession(s.sessionPath)
+}
+
+//Select the phonebook to operate on, eg. Select(PhonebookLocationInternal, PhonebookContacts)
+func (s *PBAPSession) Select(location string, phonebook string) error {
+	return s.phonebookAccess.Select(location, phonebook)
+}
+
+//GetSize return the number of entries in the selected phonebook
+func (s *PBAPSession) GetSize() (uint16, error) {
+	return s.phonebookAccess.GetSize()
+}
+
+//List return the handles and names of the entries in the selected phonebook
+func (s *PBAPSession) List(filters *PhonebookFilters) ([]PhonebookEntry, error) {
+	return s.phonebookAccess.List(filters)
+}
+
+//Search the selected phonebook, field is one of name, number or sound
+func (s *PBAPSession) Search(field string, value string, filters *PhonebookFilters) ([]PhonebookEntry, error) {
+	return s.phonebookAccess.Search(field, value, filters)
+}
+
+//PullAll download and parse every entry of the selected phonebook
+func (s *PBAPSession) PullAll(filters *PhonebookFilters) ([]VCard, error) {
+	return pullVCards(s.sessionPath, func(targetfile string) (string, error) {
+		transferPath, _, err := s.phonebookAccess.PullAll(targetfile, filters)
+		return transferPath, err
+	})
+}
+
+//Pull download and parse a single entry by its handle, as returned by List
+func (s *PBAPSession) Pull(handle string, filters *PhonebookFilters) (*VCard, error) {
+
+	cards, err := pullVCards(s.sessionPath, func(targetfile string) (string, error) {
+		transferPath, _, err := s.phonebookAccess.Pull(handle, targetfile, filters)
+		return transferPath, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
+		return nil, nil
+	}
+
+	return &cards[0], nil
+}
+
+// pullVCards run a transfer to a temporary file and parse its content
+func pullVCards(sessionPath string, start func(targetfile string) (string, error)) ([]VCard, error) {
+
+	tmpfile, err := ioutil.TempFile("", "obex-pbap-")
+	if err != nil {
+		return nil, err
+	}
+	tmpfile.Close()
+	defer os.Remove(tmpfile.Name())
+
+	err = download(sessionPath, tmpfile.Name(), func() (string, error) {
+		return start(tmpfile.Name())
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(tmpfile.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseVCards(f)
+}
@@ -0,0 +1,45 @@
+package obex
+
+import (
+	"path/filepath"
+)
+
+//PushFile send a file to a remote device via Object Push. A session is
+// created for the transfer and removed once it is over, progress is
+// reported on the returned channel
+func PushFile(address string, filePath string) (<-chan TransferProgress, error) {
+
+	// obexd requires an absolute path
+	sourcefile, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewObexClient1()
+	sessionPath, err := client.CreateSession(address, map[string]interface{}{
+		"Target": SessionTargetOPP,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	progress, err := watchTransfer(sessionPath, func() (string, error) {
+		transferPath, _, err := NewObjectPush1(sessionPath).SendFile(sourcefile)
+		return transferPath, err
+	})
+	if err != nil {
+		client.RemoveSession(sessionPath)
+		return nil, err
+	}
+
+	out := make(chan TransferProgress, 1)
+	go func() {
+		defer close(out)
+		for p := range progress {
+			out <- p
+		}
+		client.RemoveSession(sessionPath)
+	}()
+
+	return out, nil
+}
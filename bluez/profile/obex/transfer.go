@@ -0,0 +1,212 @@
+package obex
+
+import (
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//TransferProgress the state of an OBEX transfer
+type TransferProgress struct {
+	Path        string
+	Status      string
+	Size        uint64
+	Transferred uint64
+	Filename    string
+	Err         error
+}
+
+//Done return true once the transfer has completed or failed
+func (p TransferProgress) Done() bool {
+	return p.Status == TransferStatusComplete || p.Status == TransferStatusError || p.Err != nil
+}
+
+//Percent return the transferred percentage, 0 if the size is unknown
+func (p TransferProgress) Percent() float64 {
+	if p.Status == TransferStatusComplete {
+		return 100
+	}
+	if p.Size == 0 {
+		return 0
+	}
+	return float64(p.Transferred) * 100 / float64(p.Size)
+}
+
+func (p *TransferProgress) update(changes map[string]dbus.Variant) {
+	for field, val := range changes {
+		switch field {
+		case "Status":
+			p.Status, _ = val.Value().(string)
+		case "Size":
+			p.Size, _ = val.Value().(uint64)
+		case "Transferred":
+			p.Transferred, _ = val.Value().(uint64)
+		case "Filename":
+			p.Filename, _ = val.Value().(string)
+		}
+	}
+	if p.Status == TransferStatusError && p.Err == nil {
+		p.Err = errors.New("Transfer " + p.Path + " failed")
+	}
+}
+
+//ErrTransferOutcomeUnknown a transfer removed by obexd before its final
+// status was received, it may have failed
+var ErrTransferOutcomeUnknown = errors.New("Transfer removed before its outcome was known")
+
+// transferSettle how long a removed transfer waits for its final status,
+// the signals are not delivered in order
+const transferSettle = 200 * time.Millisecond
+
+//WatchTransfer report the progress of a transfer on the returned channel,
+// which is closed once the transfer completes or fails.
+// The channel must be drained to keep receiving updates. obexd removes the
+// transfers once done: when the final status of a transfer already removed
+// is not known, the last update carries ErrTransferOutcomeUnknown
+func WatchTransfer(transferPath string) (<-chan TransferProgress, error) {
+	return watchTransfer(transferPath, func() (string, error) {
+		return transferPath, nil
+	})
+}
+
+// watchTransfer subscribe to the transfers below namespace, eg. the session,
+// then start a transfer and report its progress. Subscribing first does not
+// miss the end of a transfer finishing early
+func watchTransfer(namespace string, start func() (string, error)) (<-chan TransferProgress, error) {
+
+	conn, err := bluez.GetConnection(bluez.SessionBus)
+	if err != nil {
+		return nil, err
+	}
+	dispatcher := bluez.GetSignalDispatcher(conn)
+	changes, err := dispatcher.Subscribe(bluez.SignalFilter{
+		Path:          dbus.ObjectPath(namespace),
+		PathNamespace: true,
+		Interface:     bluez.PropertiesInterface,
+		Member:        "PropertiesChanged",
+	})
+	if err != nil {
+		return nil, err
+	}
+	removals, err := dispatcher.Subscribe(bluez.SignalFilter{
+		Interface: bluez.ObjectManagerInterface,
+		Member:    "InterfacesRemoved",
+	})
+	if err != nil {
+		dispatcher.Unsubscribe(changes)
+		return nil, err
+	}
+	unsubscribe := func() {
+		dispatcher.Unsubscribe(changes)
+		dispatcher.Unsubscribe(removals)
+	}
+
+	transferPath, err := start()
+	if err != nil {
+		unsubscribe()
+		return nil, err
+	}
+
+	state := TransferProgress{Path: transferPath}
+	removed := false
+	props, err := NewObexTransfer1(transferPath).GetProperties()
+	switch {
+	case err == nil:
+		state.Status = props.Status
+		state.Size = props.Size
+		state.Transferred = props.Transferred
+		state.Filename = props.Filename
+	case isUnknownObject(err):
+		removed = true
+	default:
+		unsubscribe()
+		return nil, err
+	}
+
+	progress := make(chan TransferProgress, 1)
+
+	go func() {
+		defer close(progress)
+		defer unsubscribe()
+
+		var settle <-chan time.Time
+		if removed {
+			settle = time.After(transferSettle)
+		} else {
+			state.update(nil)
+			progress <- state
+		}
+
+		for !state.Done() {
+			select {
+			case sig := <-changes:
+				if c, ok := transferChanges(sig, transferPath); ok {
+					state.update(c)
+					if !removed || state.Done() {
+						progress <- state
+					}
+				}
+			case sig := <-removals:
+				if !removed && transferRemoved(sig, transferPath) {
+					removed = true
+					settle = time.After(transferSettle)
+				}
+			case <-settle:
+				state.Err = ErrTransferOutcomeUnknown
+				progress <- state
+			}
+		}
+	}()
+
+	return progress, nil
+}
+
+// transferChanges return the properties changed by a signal of the transfer
+func transferChanges(sig *dbus.Signal, transferPath string) (map[string]dbus.Variant, bool) {
+	if sig == nil || string(sig.Path) != transferPath || sig.Name != bluez.PropertiesChanged || len(sig.Body) < 2 {
+		return nil, false
+	}
+	if iface, ok := sig.Body[0].(string); !ok || iface != ObexTransfer1Interface {
+		return nil, false
+	}
+	changes, ok := sig.Body[1].(map[string]dbus.Variant)
+	return changes, ok
+}
+
+// transferRemoved return true if an InterfacesRemoved signal removes the
+// transfer
+func transferRemoved(sig *dbus.Signal, transferPath string) bool {
+	if sig == nil || sig.Name != bluez.InterfacesRemoved || len(sig.Body) < 2 {
+		return false
+	}
+	if path, ok := sig.Body[0].(dbus.ObjectPath); !ok || string(path) != transferPath {
+		return false
+	}
+	ifaces, _ := sig.Body[1].([]string)
+	for _, iface := range ifaces {
+		if iface == ObexTransfer1Interface {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnknownObject return true if the error reports an object not exported,
+// eg. a transfer removed by obexd. The name depends on the bus library of
+// the service
+func isUnknownObject(err error) bool {
+	var name string
+	switch e := err.(type) {
+	case dbus.Error:
+		name = e.Name
+	case *dbus.Error:
+		name = e.Name
+	default:
+		return false
+	}
+	return name == "org.freedesktop.DBus.Error.UnknownObject" ||
+		name == "org.freedesktop.DBus.Error.UnknownInterface" ||
+		name == "org.freedesktop.DBus.Error.UnknownMethod"
+}
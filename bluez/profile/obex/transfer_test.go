@@ -0,0 +1,273 @@
+package obex
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+)
+
+const (
+	testSessionPath  = "/org/bluez/obex/client/session0"
+	testTransferPath = testSessionPath + "/transfer0"
+)
+
+// startObexd run a private bus used as session bus, with org.bluez.obex owned
+// by the returned connection
+func startObexd(t *testing.T) (*dbus.Conn, func()) {
+
+	daemon, err := bluetest.StartDaemon()
+	if err != nil {
+		t.Skipf("Cannot start dbus-daemon: %s", err.Error())
+	}
+	server, err := dbus.Dial(daemon.Address)
+	if err == nil {
+		err = server.Auth(nil)
+	}
+	if err == nil {
+		err = server.Hello()
+	}
+	if err == nil {
+		_, err = server.RequestName("org.bluez.obex", dbus.NameFlagDoNotQueue)
+	}
+	if err != nil {
+		daemon.Close()
+		t.Fatal(err)
+	}
+	client, err := bluez.DialBus(daemon.Address)
+	if err != nil {
+		server.Close()
+		daemon.Close()
+		t.Fatal(err)
+	}
+	bluez.SetConnection(bluez.SessionBus, client)
+
+	return server, func() {
+		bluez.SetConnection(bluez.SessionBus, nil)
+		client.Close()
+		server.Close()
+		daemon.Close()
+	}
+}
+
+// exportTransfer expose a fake Transfer1
+func exportTransfer(conn *dbus.Conn, status string, size uint64) *prop.Properties {
+	return prop.New(conn, testTransferPath, map[string]map[string]*prop.Prop{
+		ObexTransfer1Interface: {
+			"Status":      {Value: status, Emit: prop.EmitTrue},
+			"Size":        {Value: size, Emit: prop.EmitTrue},
+			"Transferred": {Value: uint64(0), Emit: prop.EmitTrue},
+			"Filename":    {Value: "/tmp/file.txt", Emit: prop.EmitTrue},
+		},
+	})
+}
+
+// removeTransfer drop the fake Transfer1 like obexd once a transfer is over
+func removeTransfer(conn *dbus.Conn) {
+	conn.Export(nil, testTransferPath, "org.freedesktop.DBus.Properties")
+	conn.Emit("/", bluez.InterfacesRemoved, dbus.ObjectPath(testTransferPath), []string{ObexTransfer1Interface})
+}
+
+// collect the updates until the channel is closed
+func collect(t *testing.T, progress <-chan TransferProgress) []TransferProgress {
+	var updates []TransferProgress
+	for {
+		select {
+		case p, ok := <-progress:
+			if !ok {
+				return updates
+			}
+			updates = append(updates, p)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("The progress channel is still open after %v", updates)
+		}
+	}
+}
+
+func TestWatchTransfer(t *testing.T) {
+
+	server, stop := startObexd(t)
+	defer stop()
+
+	props := exportTransfer(server, TransferStatusActive, 100)
+	progress, err := WatchTransfer(testTransferPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := <-progress
+	if first.Status != TransferStatusActive || first.Size != 100 || first.Filename != "/tmp/file.txt" || first.Done() {
+		t.Fatalf("Unexpected initial state %+v", first)
+	}
+
+	props.SetMust(ObexTransfer1Interface, "Transferred", uint64(50))
+	props.SetMust(ObexTransfer1Interface, "Status", TransferStatusComplete)
+
+	updates := collect(t, progress)
+	last := updates[len(updates)-1]
+	if !last.Done() || last.Err != nil || last.Percent() != 100 {
+		t.Fatalf("Unexpected final state %+v", last)
+	}
+}
+
+func TestWatchTransferError(t *testing.T) {
+
+	server, stop := startObexd(t)
+	defer stop()
+
+	props := exportTransfer(server, TransferStatusQueued, 0)
+	progress, err := WatchTransfer(testTransferPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props.SetMust(ObexTransfer1Interface, "Status", TransferStatusError)
+
+	updates := collect(t, progress)
+	last := updates[len(updates)-1]
+	if last.Status != TransferStatusError || last.Err == nil {
+		t.Fatalf("Expected a failure, got %+v", last)
+	}
+}
+
+func TestWatchTransferDone(t *testing.T) {
+
+	server, stop := startObexd(t)
+	defer stop()
+
+	exportTransfer(server, TransferStatusComplete, 10)
+	progress, err := WatchTransfer(testTransferPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := collect(t, progress)
+	if len(updates) != 1 || updates[0].Status != TransferStatusComplete || updates[0].Percent() != 100 {
+		t.Fatalf("Expected a single complete update, got %+v", updates)
+	}
+}
+
+func TestWatchTransferRemoved(t *testing.T) {
+
+	_, stop := startObexd(t)
+	defer stop()
+
+	// obexd has already dropped the transfer
+	progress, err := WatchTransfer(testTransferPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := collect(t, progress)
+	if len(updates) != 1 || updates[0].Err != ErrTransferOutcomeUnknown || updates[0].Status == TransferStatusComplete {
+		t.Fatalf("Expected an unknown outcome, got %+v", updates)
+	}
+}
+
+func TestWatchTransferDisappears(t *testing.T) {
+
+	server, stop := startObexd(t)
+	defer stop()
+
+	props := exportTransfer(server, TransferStatusActive, 100)
+	progress, err := WatchTransfer(testTransferPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props.SetMust(ObexTransfer1Interface, "Transferred", uint64(50))
+	removeTransfer(server)
+
+	updates := collect(t, progress)
+	last := updates[len(updates)-1]
+	if last.Err != ErrTransferOutcomeUnknown || last.Status != TransferStatusActive {
+		t.Fatalf("Expected an unknown outcome, got %+v", last)
+	}
+}
+
+func TestWatchTransferNoService(t *testing.T) {
+
+	server, stop := startObexd(t)
+	defer stop()
+	server.ReleaseName("org.bluez.obex")
+
+	if _, err := WatchTransfer(testTransferPath); err == nil {
+		t.Fatal("Expected an error without obexd")
+	}
+}
+
+// fakeFileTransfer a FileTransfer1 starting a transfer run by run
+type fakeFileTransfer struct {
+	conn *dbus.Conn
+	run  func(targetfile string)
+}
+
+func (f *fakeFileTransfer) GetFile(targetfile string, sourcefile string) (dbus.ObjectPath, map[string]dbus.Variant, *dbus.Error) {
+	exportTransfer(f.conn, TransferStatusQueued, 0)
+	go f.run(targetfile)
+	return testTransferPath, map[string]dbus.Variant{}, nil
+}
+
+func TestFTPGetFile(t *testing.T) {
+
+	server, stop := startObexd(t)
+	defer stop()
+
+	fake := &fakeFileTransfer{conn: server}
+	if err := server.Export(fake, testSessionPath, "org.bluez.obex.FileTransfer1"); err != nil {
+		t.Fatal(err)
+	}
+	s := &FTPSession{sessionPath: testSessionPath, fileTransfer: NewObexFileTransfer1(testSessionPath)}
+
+	// the transfers end right away, possibly before GetFile returns. Like
+	// obexd, the last changes are emitted together
+	finish := func(content string, changes map[string]interface{}) func(string) {
+		return func(targetfile string) {
+			ioutil.WriteFile(targetfile, []byte(content), 0644)
+			variants := map[string]dbus.Variant{}
+			for name, value := range changes {
+				variants[name] = dbus.MakeVariant(value)
+			}
+			server.Emit(testTransferPath, bluez.PropertiesChanged, ObexTransfer1Interface, variants, []string{})
+			removeTransfer(server)
+		}
+	}
+	complete := func(size, transferred uint64) map[string]interface{} {
+		return map[string]interface{}{"Size": size, "Transferred": transferred, "Status": TransferStatusComplete}
+	}
+
+	tests := []struct {
+		name    string
+		run     func(string)
+		content string
+		err     error
+	}{
+		{"complete", finish("hello", complete(5, 5)), "hello", nil},
+		{"size unknown", finish("hello", complete(0, 5)), "hello", nil},
+		{"removed", finish("", map[string]interface{}{"Size": uint64(5)}), "", ErrTransferOutcomeUnknown},
+		{"failed", finish("", map[string]interface{}{"Status": TransferStatusError}), "", nil},
+		{"short", finish("hel", complete(5, 0)), "", nil},
+		{"partial", finish("hello", complete(5, 3)), "", nil},
+	}
+	for _, test := range tests {
+		fake.run = test.run
+		r, err := s.GetFile("file.txt", nil)
+		if test.content == "" {
+			if err == nil || r != nil {
+				t.Errorf("%s: expected an error, got %v", test.name, r)
+			} else if test.err != nil && err != test.err {
+				t.Errorf("%s: expected %s, got %s", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		content, _ := ioutil.ReadAll(r)
+		r.Close()
+		if string(content) != test.content {
+			t.Errorf("%s: expected %q, got %q", test.name, test.content, content)
+		}
+	}
+}
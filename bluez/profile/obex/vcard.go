@@ -0,0 +1,304 @@
+package obex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/quotedprintable"
+	"strings"
+)
+
+//VCardProperty a raw vCard content line
+type VCardProperty struct {
+	Name   string
+	Params map[string][]string
+	Value  string
+}
+
+//Types return the TYPE parameters of the property, lower case
+func (p VCardProperty) Types() []string {
+	types := make([]string, 0, len(p.Params["TYPE"]))
+	for _, t := range p.Params["TYPE"] {
+		types = append(types, strings.ToLower(t))
+	}
+	return types
+}
+
+//VCardName the structured N property
+type VCardName struct {
+	Family     string
+	Given      string
+	Additional string
+	Prefix     string
+	Suffix     string
+}
+
+//VCardValue a typed value, eg. a phone number or an email
+type VCardValue struct {
+	Value string
+	// Types eg. cell, home, work, pref
+	Types []string
+}
+
+//VCardAddress the structured ADR property
+type VCardAddress struct {
+	Types      []string
+	POBox      string
+	Extended   string
+	Street     string
+	Locality   string
+	Region     string
+	PostalCode string
+	Country    string
+}
+
+//VCard a contact returned by a phonebook
+type VCard struct {
+	Version       string
+	FormattedName string
+	Name          VCardName
+	Phones        []VCardValue
+	Emails        []VCardValue
+	Addresses     []VCardAddress
+	Organization  string
+	Title         string
+	Note          string
+	Birthday      string
+	URL           string
+	UID           string
+	Photo         []byte
+	// CallType missed, received or dialed, set for call history entries
+	CallType string
+	// CallTime the call timestamp, eg. 20050320T100000
+	CallTime string
+	// Properties every content line of the card, including the ones above
+	Properties []VCardProperty
+}
+
+// vCard 2.1 parameters without a name are types
+var vcardEncodings = map[string]bool{
+	"QUOTED-PRINTABLE": true,
+	"BASE64":           true,
+	"B":                true,
+	"8BIT":             true,
+	"7BIT":             true,
+}
+
+//ParseVCards parse a stream of vCard 2.1 or 3.0 objects
+func ParseVCards(r io.Reader) ([]VCard, error) {
+
+	lines, err := readVCardLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]VCard, 0)
+	var card *VCard
+	for _, line := range lines {
+
+		prop, ok := parseVCardLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case prop.Name == "BEGIN" && strings.EqualFold(prop.Value, "VCARD"):
+			if card != nil {
+				return nil, errors.New("vCard: nested BEGIN:VCARD")
+			}
+			card = &VCard{}
+		case prop.Name == "END" && strings.EqualFold(prop.Value, "VCARD"):
+			if card == nil {
+				return nil, errors.New("vCard: END:VCARD without BEGIN")
+			}
+			cards = append(cards, *card)
+			card = nil
+		case card != nil:
+			card.add(prop)
+		}
+	}
+
+	if card != nil {
+		return nil, errors.New("vCard: missing END:VCARD")
+	}
+
+	return cards, nil
+}
+
+// readVCardLines split the input in logical lines, unfolding continuation
+// lines and quoted-printable soft line breaks
+func readVCardLines(r io.Reader) ([]string, error) {
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	lines := make([]string, 0)
+	softBreak := false
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		switch {
+		case softBreak:
+			lines[len(lines)-1] += line
+		case len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			lines[len(lines)-1] += line[1:]
+		case line == "":
+			continue
+		default:
+			lines = append(lines, line)
+		}
+
+		last := lines[len(lines)-1]
+		softBreak = strings.HasSuffix(last, "=") &&
+			strings.Contains(strings.ToUpper(last[:strings.Index(last+":", ":")]), "QUOTED-PRINTABLE")
+		if softBreak {
+			lines[len(lines)-1] = last[:len(last)-1]
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+func parseVCardLine(line string) (VCardProperty, bool) {
+
+	prop := VCardProperty{Params: make(map[string][]string)}
+
+	sep := strings.Index(line, ":")
+	if sep == -1 {
+		return prop, false
+	}
+
+	parts := strings.Split(line[:sep], ";")
+	name := strings.ToUpper(parts[0])
+	// drop the group, eg. item1.TEL
+	if dot := strings.LastIndex(name, "."); dot != -1 {
+		name = name[dot+1:]
+	}
+	prop.Name = name
+
+	for _, param := range parts[1:] {
+		key := "TYPE"
+		val := param
+		if eq := strings.Index(param, "="); eq != -1 {
+			key = strings.ToUpper(param[:eq])
+			val = param[eq+1:]
+		} else if vcardEncodings[strings.ToUpper(param)] {
+			key = "ENCODING"
+		}
+		for _, v := range strings.Split(val, ",") {
+			prop.Params[key] = append(prop.Params[key], strings.Trim(v, `"`))
+		}
+	}
+
+	prop.Value = line[sep+1:]
+	if enc, ok := prop.Params["ENCODING"]; ok && strings.EqualFold(enc[0], "QUOTED-PRINTABLE") {
+		decoded, err := ioutil.ReadAll(quotedprintable.NewReader(strings.NewReader(prop.Value)))
+		if err == nil {
+			prop.Value = string(decoded)
+		}
+	}
+
+	return prop, true
+}
+
+// splitVCardValue split a structured value on unescaped separators,
+// unescaping each component
+func splitVCardValue(value string, sep byte) []string {
+	parts := make([]string, 0)
+	var b bytes.Buffer
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && i+1 < len(value):
+			i++
+			switch value[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(value[i])
+			}
+		case c == sep:
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return append(parts, b.String())
+}
+
+func unescapeVCardValue(value string) string {
+	return splitVCardValue(value, 0)[0]
+}
+
+func component(parts []string, i int) string {
+	if i < len(parts) {
+		return parts[i]
+	}
+	return ""
+}
+
+func (c *VCard) add(prop VCardProperty) {
+
+	c.Properties = append(c.Properties, prop)
+
+	switch prop.Name {
+	case "VERSION":
+		c.Version = prop.Value
+	case "FN":
+		c.FormattedName = unescapeVCardValue(prop.Value)
+	case "N":
+		parts := splitVCardValue(prop.Value, ';')
+		c.Name = VCardName{
+			Family:     component(parts, 0),
+			Given:      component(parts, 1),
+			Additional: component(parts, 2),
+			Prefix:     component(parts, 3),
+			Suffix:     component(parts, 4),
+		}
+	case "TEL":
+		c.Phones = append(c.Phones, VCardValue{unescapeVCardValue(prop.Value), prop.Types()})
+	case "EMAIL":
+		c.Emails = append(c.Emails, VCardValue{unescapeVCardValue(prop.Value), prop.Types()})
+	case "ADR":
+		parts := splitVCardValue(prop.Value, ';')
+		c.Addresses = append(c.Addresses, VCardAddress{
+			Types:      prop.Types(),
+			POBox:      component(parts, 0),
+			Extended:   component(parts, 1),
+			Street:     component(parts, 2),
+			Locality:   component(parts, 3),
+			Region:     component(parts, 4),
+			PostalCode: component(parts, 5),
+			Country:    component(parts, 6),
+		})
+	case "ORG":
+		c.Organization = strings.Join(splitVCardValue(prop.Value, ';'), " ")
+		c.Organization = strings.TrimSpace(c.Organization)
+	case "TITLE":
+		c.Title = unescapeVCardValue(prop.Value)
+	case "NOTE":
+		c.Note = unescapeVCardValue(prop.Value)
+	case "BDAY":
+		c.Birthday = prop.Value
+	case "URL":
+		c.URL = prop.Value
+	case "UID":
+		c.UID = prop.Value
+	case "PHOTO":
+		if enc, ok := prop.Params["ENCODING"]; ok && (strings.EqualFold(enc[0], "BASE64") || strings.EqualFold(enc[0], "B")) {
+			photo, err := base64.StdEncoding.DecodeString(strings.Replace(prop.Value, " ", "", -1))
+			if err == nil {
+				c.Photo = photo
+			}
+		}
+	case "X-IRMC-CALL-DATETIME":
+		if types := prop.Types(); len(types) > 0 {
+			c.CallType = types[0]
+		}
+		c.CallTime = prop.Value
+	}
+}
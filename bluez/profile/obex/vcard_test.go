@@ -0,0 +1,68 @@
+package obex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVCards(t *testing.T) {
+
+	data := "BEGIN:VCARD\r\n" +
+		"VERSION:2.1\r\n" +
+		"N;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:M=C3=BCller;J=\r\n" +
+		"=C3=BCrgen;;;\r\n" +
+		"FN:Jurgen Muller\r\n" +
+		"TEL;CELL;PREF:+49123456\r\n" +
+		"TEL;WORK:+49654321\r\n" +
+		"X-IRMC-CALL-DATETIME;MISSED:20180320T100000\r\n" +
+		"END:VCARD\r\n" +
+		"BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"FN:Jane Doe\r\n" +
+		"N:Doe;Jane;;;\r\n" +
+		"item1.EMAIL;TYPE=INTERNET,HOME:jane@example.com\r\n" +
+		"ADR;TYPE=HOME:;;1 Main St\\, Apt 2;Springfield;;12345;US\r\n" +
+		"NOTE:first line\\nsecond\r\n" +
+		"  line\r\n" +
+		"END:VCARD\r\n"
+
+	cards, err := ParseVCards(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 2 {
+		t.Fatalf("Expected 2 cards, got %d", len(cards))
+	}
+
+	c := cards[0]
+	if c.Name.Family != "Müller" || c.Name.Given != "Jürgen" {
+		t.Fatalf("Unexpected name %+v", c.Name)
+	}
+	if len(c.Phones) != 2 || c.Phones[0].Value != "+49123456" || c.Phones[0].Types[0] != "cell" || c.Phones[1].Types[0] != "work" {
+		t.Fatalf("Unexpected phones %+v", c.Phones)
+	}
+	if c.CallType != "missed" || c.CallTime != "20180320T100000" {
+		t.Fatalf("Unexpected call info %s %s", c.CallType, c.CallTime)
+	}
+
+	c = cards[1]
+	if c.FormattedName != "Jane Doe" || c.Version != "3.0" {
+		t.Fatalf("Unexpected card %+v", c)
+	}
+	if len(c.Emails) != 1 || c.Emails[0].Value != "jane@example.com" || len(c.Emails[0].Types) != 2 {
+		t.Fatalf("Unexpected emails %+v", c.Emails)
+	}
+	if len(c.Addresses) != 1 || c.Addresses[0].Street != "1 Main St, Apt 2" || c.Addresses[0].PostalCode != "12345" {
+		t.Fatalf("Unexpected addresses %+v", c.Addresses)
+	}
+	if c.Note != "first line\nsecond line" {
+		t.Fatalf("Unexpected note %q", c.Note)
+	}
+}
+
+func TestParseVCardsUnterminated(t *testing.T) {
+	_, err := ParseVCards(strings.NewReader("BEGIN:VCARD\nFN:x\n"))
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+}
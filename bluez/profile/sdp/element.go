@@ -0,0 +1,154 @@
+package sdp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/muka/go-bluetooth"
+)
+
+//Element an SDP data element
+type Element interface {
+	writeXML(b *bytes.Buffer, indent string)
+}
+
+type valueElement struct {
+	tag   string
+	value string
+}
+
+func (e *valueElement) writeXML(b *bytes.Buffer, indent string) {
+	b.WriteString(indent + "<" + e.tag)
+	if e.value != "" || e.tag != "nil" {
+		b.WriteString(` value="` + escape(e.value) + `"`)
+	}
+	b.WriteString(" />\n")
+}
+
+type listElement struct {
+	tag      string
+	elements []Element
+}
+
+func (e *listElement) writeXML(b *bytes.Buffer, indent string) {
+	b.WriteString(indent + "<" + e.tag + ">\n")
+	for _, el := range e.elements {
+		el.writeXML(b, indent+"\t")
+	}
+	b.WriteString(indent + "</" + e.tag + ">\n")
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func hexValue(v uint64, size int) string {
+	return fmt.Sprintf("0x%0*x", size*2, v)
+}
+
+//Nil a nil element
+func Nil() Element {
+	return &valueElement{tag: "nil"}
+}
+
+//Bool a boolean element
+func Bool(v bool) Element {
+	return &valueElement{tag: "boolean", value: strconv.FormatBool(v)}
+}
+
+//Uint8 an unsigned 8 bit integer element
+func Uint8(v uint8) Element {
+	return &valueElement{tag: "uint8", value: hexValue(uint64(v), 1)}
+}
+
+//Uint16 an unsigned 16 bit integer element
+func Uint16(v uint16) Element {
+	return &valueElement{tag: "uint16", value: hexValue(uint64(v), 2)}
+}
+
+//Uint32 an unsigned 32 bit integer element
+func Uint32(v uint32) Element {
+	return &valueElement{tag: "uint32", value: hexValue(uint64(v), 4)}
+}
+
+//Uint64 an unsigned 64 bit integer element
+func Uint64(v uint64) Element {
+	return &valueElement{tag: "uint64", value: hexValue(v, 8)}
+}
+
+//Int8 a signed 8 bit integer element
+func Int8(v int8) Element {
+	return &valueElement{tag: "int8", value: strconv.Itoa(int(v))}
+}
+
+//Int16 a signed 16 bit integer element
+func Int16(v int16) Element {
+	return &valueElement{tag: "int16", value: strconv.Itoa(int(v))}
+}
+
+//Int32 a signed 32 bit integer element
+func Int32(v int32) Element {
+	return &valueElement{tag: "int32", value: strconv.Itoa(int(v))}
+}
+
+//Int64 a signed 64 bit integer element
+func Int64(v int64) Element {
+	return &valueElement{tag: "int64", value: strconv.FormatInt(v, 10)}
+}
+
+//UUID16 a 16 bit UUID element
+func UUID16(v uint16) Element {
+	return &valueElement{tag: "uuid", value: hexValue(uint64(v), 2)}
+}
+
+//UUID32 a 32 bit UUID element
+func UUID32(v uint32) Element {
+	return &valueElement{tag: "uuid", value: hexValue(uint64(v), 4)}
+}
+
+//UUID an UUID element from its string form, eg. 1101, 0x1101 or
+// 00001101-0000-1000-8000-00805f9b34fb. UUIDs based on the Bluetooth
+// base UUID are shortened to their 16 or 32 bit form
+func UUID(uuid string) Element {
+	return &valueElement{tag: "uuid", value: normalizeUUID(uuid)}
+}
+
+func normalizeUUID(uuid string) string {
+	u, err := bluetooth.ParseUUID(uuid)
+	if err != nil {
+		// let bluez report the invalid value
+		return uuid
+	}
+	if v, ok := u.Uint16(); ok {
+		return hexValue(uint64(v), 2)
+	}
+	if v, ok := u.Uint32(); ok {
+		return hexValue(uint64(v), 4)
+	}
+	return strings.ToLower(u.String())
+}
+
+//Text a text string element
+func Text(s string) Element {
+	return &valueElement{tag: "text", value: s}
+}
+
+//URL an URL element
+func URL(s string) Element {
+	return &valueElement{tag: "url", value: s}
+}
+
+//Sequence a data element sequence
+func Sequence(elements ...Element) Element {
+	return &listElement{tag: "sequence", elements: elements}
+}
+
+//Alternate a data element alternative, one of the elements is to be selected
+func Alternate(elements ...Element) Element {
+	return &listElement{tag: "alternate", elements: elements}
+}
@@ -0,0 +1,178 @@
+package sdp
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Universal attribute IDs, see Bluetooth Core spec Vol 3, Part B, 5.1
+const (
+	AttrServiceRecordHandle               uint16 = 0x0000
+	AttrServiceClassIDList                uint16 = 0x0001
+	AttrServiceRecordState                uint16 = 0x0002
+	AttrServiceID                         uint16 = 0x0003
+	AttrProtocolDescriptorList            uint16 = 0x0004
+	AttrBrowseGroupList                   uint16 = 0x0005
+	AttrLanguageBaseAttributeIDList       uint16 = 0x0006
+	AttrServiceInfoTimeToLive             uint16 = 0x0007
+	AttrServiceAvailability               uint16 = 0x0008
+	AttrBluetoothProfileDescriptorList    uint16 = 0x0009
+	AttrDocumentationURL                  uint16 = 0x000A
+	AttrClientExecutableURL               uint16 = 0x000B
+	AttrIconURL                           uint16 = 0x000C
+	AttrAdditionalProtocolDescriptorLists uint16 = 0x000D
+
+	// offsets from the primary language base 0x0100
+	AttrServiceName        uint16 = 0x0100
+	AttrServiceDescription uint16 = 0x0101
+	AttrProviderName       uint16 = 0x0102
+
+	AttrSupportedFeatures uint16 = 0x0311
+)
+
+// Protocol UUIDs
+const (
+	ProtocolSDP    uint16 = 0x0001
+	ProtocolRFCOMM uint16 = 0x0003
+	ProtocolOBEX   uint16 = 0x0008
+	ProtocolBNEP   uint16 = 0x000F
+	ProtocolHIDP   uint16 = 0x0011
+	ProtocolAVCTP  uint16 = 0x0017
+	ProtocolAVDTP  uint16 = 0x0019
+	ProtocolL2CAP  uint16 = 0x0100
+)
+
+//PublicBrowseGroup the root of the browse hierarchy
+const PublicBrowseGroup uint16 = 0x1002
+
+//NewRecord create an empty service record
+func NewRecord() *Record {
+	return &Record{
+		attributes: make(map[uint16]Element),
+	}
+}
+
+//Record an SDP service record builder
+type Record struct {
+	attributes map[uint16]Element
+}
+
+//Set an attribute value
+func (r *Record) Set(id uint16, value Element) *Record {
+	r.attributes[id] = value
+	return r
+}
+
+//Get an attribute value, nil if not set
+func (r *Record) Get(id uint16) Element {
+	return r.attributes[id]
+}
+
+//ServiceClasses set the list of service classes the record conforms to
+func (r *Record) ServiceClasses(uuids ...string) *Record {
+	return r.Set(AttrServiceClassIDList, uuidSequence(uuids))
+}
+
+//Protocols set the protocol stack used to access the service, each
+// descriptor is built with Protocol
+func (r *Record) Protocols(descriptors ...Element) *Record {
+	return r.Set(AttrProtocolDescriptorList, Sequence(descriptors...))
+}
+
+//Protocol a protocol descriptor, eg. Protocol(ProtocolRFCOMM, Uint8(channel))
+func Protocol(uuid uint16, params ...Element) Element {
+	return Sequence(append([]Element{UUID16(uuid)}, params...)...)
+}
+
+//RFCOMM set the protocol stack to L2CAP + RFCOMM on channel
+func (r *Record) RFCOMM(channel uint8) *Record {
+	return r.Protocols(
+		Protocol(ProtocolL2CAP),
+		Protocol(ProtocolRFCOMM, Uint8(channel)),
+	)
+}
+
+//OBEX set the protocol stack to L2CAP + RFCOMM on channel + OBEX
+func (r *Record) OBEX(channel uint8) *Record {
+	return r.Protocols(
+		Protocol(ProtocolL2CAP),
+		Protocol(ProtocolRFCOMM, Uint8(channel)),
+		Protocol(ProtocolOBEX),
+	)
+}
+
+//L2CAP set the protocol stack to L2CAP on psm
+func (r *Record) L2CAP(psm uint16) *Record {
+	return r.Protocols(
+		Protocol(ProtocolL2CAP, Uint16(psm)),
+	)
+}
+
+//Profile add a profile descriptor with its version, eg. 0x0102 for v1.2
+func (r *Record) Profile(uuid string, version uint16) *Record {
+	descriptor := Sequence(UUID(uuid), Uint16(version))
+	if list, ok := r.attributes[AttrBluetoothProfileDescriptorList].(*listElement); ok {
+		list.elements = append(list.elements, descriptor)
+		return r
+	}
+	return r.Set(AttrBluetoothProfileDescriptorList, Sequence(descriptor))
+}
+
+//BrowseGroups set the browse groups the service belongs to
+func (r *Record) BrowseGroups(uuids ...string) *Record {
+	return r.Set(AttrBrowseGroupList, uuidSequence(uuids))
+}
+
+//Public make the service visible at the root of the browse hierarchy
+func (r *Record) Public() *Record {
+	return r.Set(AttrBrowseGroupList, Sequence(UUID16(PublicBrowseGroup)))
+}
+
+//Name set the service name
+func (r *Record) Name(name string) *Record {
+	return r.Set(AttrServiceName, Text(name))
+}
+
+//Description set the service description
+func (r *Record) Description(description string) *Record {
+	return r.Set(AttrServiceDescription, Text(description))
+}
+
+//Provider set the service provider name
+func (r *Record) Provider(provider string) *Record {
+	return r.Set(AttrProviderName, Text(provider))
+}
+
+//XML serialize the record in the format accepted by the ServiceRecord
+// option of ProfileManager1.RegisterProfile
+func (r *Record) XML() string {
+
+	ids := make([]int, 0, len(r.attributes))
+	for id := range r.attributes {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	var b bytes.Buffer
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" ?>\n<record>\n")
+	for _, id := range ids {
+		b.WriteString("\t<attribute id=\"" + hexValue(uint64(id), 2) + "\">\n")
+		r.attributes[uint16(id)].writeXML(&b, "\t\t")
+		b.WriteString("\t</attribute>\n")
+	}
+	b.WriteString("</record>\n")
+
+	return b.String()
+}
+
+func (r *Record) String() string {
+	return r.XML()
+}
+
+func uuidSequence(uuids []string) Element {
+	elements := make([]Element, len(uuids))
+	for i, uuid := range uuids {
+		elements[i] = UUID(uuid)
+	}
+	return Sequence(elements...)
+}
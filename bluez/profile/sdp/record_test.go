@@ -0,0 +1,69 @@
+package sdp
+
+import "testing"
+
+func TestRecordXML(t *testing.T) {
+
+	record := NewRecord().
+		ServiceClasses("00001101-0000-1000-8000-00805f9b34fb").
+		RFCOMM(22).
+		Profile("0x1101", 0x0102).
+		Public().
+		Name("Serial <Port>")
+
+	expected := `<?xml version="1.0" encoding="UTF-8" ?>
+<record>
+	<attribute id="0x0001">
+		<sequence>
+			<uuid value="0x1101" />
+		</sequence>
+	</attribute>
+	<attribute id="0x0004">
+		<sequence>
+			<sequence>
+				<uuid value="0x0100" />
+			</sequence>
+			<sequence>
+				<uuid value="0x0003" />
+				<uint8 value="0x16" />
+			</sequence>
+		</sequence>
+	</attribute>
+	<attribute id="0x0005">
+		<sequence>
+			<uuid value="0x1002" />
+		</sequence>
+	</attribute>
+	<attribute id="0x0009">
+		<sequence>
+			<sequence>
+				<uuid value="0x1101" />
+				<uint16 value="0x0102" />
+			</sequence>
+		</sequence>
+	</attribute>
+	<attribute id="0x0100">
+		<text value="Serial &lt;Port&gt;" />
+	</attribute>
+</record>
+`
+
+	if xml := record.XML(); xml != expected {
+		t.Fatalf("Unexpected record:\n%s", xml)
+	}
+}
+
+func TestUUID(t *testing.T) {
+	cases := map[string]string{
+		"1101":                                 "0x1101",
+		"0x110A":                               "0x110a",
+		"0x00011101":                           "0x00011101",
+		"0000110a-0000-1000-8000-00805F9B34FB": "0x110a",
+		"F000AA01-0451-4000-B000-000000000000": "f000aa01-0451-4000-b000-000000000000",
+	}
+	for in, out := range cases {
+		if v := normalizeUUID(in); v != out {
+			t.Fatalf("normalizeUUID(%s) = %s, expected %s", in, v, out)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package bluez
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+//MetricQueue the wait of an operation in the OperationQueue, reported to the
+// Metrics with ErrQueueFull when rejected
+const MetricQueue = "queue"
+
+//DefaultQueueDepth how many operations can wait for a device
+const DefaultQueueDepth = 32
+
+//ErrQueueFull the operation has been rejected, too many are waiting for the
+// device
+var ErrQueueFull = errors.New("Operation queue full")
+
+//OperationQueue serialize the operations on a remote device, bluetoothd
+// rejects concurrent GATT operations with InProgress. Operations on different
+// devices run in parallel
+type OperationQueue struct {
+	depth int
+
+	mutex   sync.Mutex
+	devices map[string]*deviceQueue
+}
+
+type deviceQueue struct {
+	slot chan struct{}
+	// users the running and waiting operations
+	users int
+}
+
+//NewOperationQueue create a queue, depth limits the operations waiting per
+// device, zero means no limit
+func NewOperationQueue(depth int) *OperationQueue {
+	return &OperationQueue{
+		depth:   depth,
+		devices: make(map[string]*deviceQueue),
+	}
+}
+
+var operationQueue = NewOperationQueue(DefaultQueueDepth)
+
+//GetOperationQueue return the queue used by the GATT clients
+func GetOperationQueue() *OperationQueue {
+	return operationQueue
+}
+
+//DevicePath return the path of the device an object belongs to, eg.
+// /org/bluez/hci0/dev_00_11_22_33_44_55 for one of its characteristics. Paths
+// not below a device are returned unchanged
+func DevicePath(path string) string {
+	parts := strings.SplitN(path, "/", 6)
+	if len(parts) >= 5 && strings.HasPrefix(parts[4], "dev_") {
+		return strings.Join(parts[:5], "/")
+	}
+	return path
+}
+
+//Do run fn once the previous operations on the device are done. It returns
+// ErrQueueFull if too many operations are waiting, or the context error if it
+// is done before fn could run
+func (q *OperationQueue) Do(ctx context.Context, device string, fn func() error) error {
+
+	q.mutex.Lock()
+	d, ok := q.devices[device]
+	if !ok {
+		d = &deviceQueue{slot: make(chan struct{}, 1)}
+		q.devices[device] = d
+	}
+	// the running operation is not waiting
+	if q.depth > 0 && d.users > q.depth {
+		q.mutex.Unlock()
+		GetMetrics().Observe(MetricQueue, 0, ErrQueueFull)
+		return ErrQueueFull
+	}
+	d.users++
+	q.mutex.Unlock()
+
+	defer q.release(device, d)
+
+	start := time.Now()
+	select {
+	case d.slot <- struct{}{}:
+	case <-ctx.Done():
+		GetMetrics().Observe(MetricQueue, time.Since(start), ctx.Err())
+		return ctx.Err()
+	}
+	GetMetrics().Observe(MetricQueue, time.Since(start), nil)
+
+	defer func() { <-d.slot }()
+	return fn()
+}
+
+func (q *OperationQueue) release(device string, d *deviceQueue) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	d.users--
+	if d.users == 0 {
+		delete(q.devices, device)
+	}
+}
+
+//Pending return the running and waiting operations of a device
+func (q *OperationQueue) Pending(device string) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if d, ok := q.devices[device]; ok {
+		return d.users
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package bluez
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDevicePath(t *testing.T) {
+	tests := map[string]string{
+		"/org/bluez/hci0/dev_00_11_22_33_44_55/service000a/char000b": "/org/bluez/hci0/dev_00_11_22_33_44_55",
+		"/org/bluez/hci0/dev_00_11_22_33_44_55":                      "/org/bluez/hci0/dev_00_11_22_33_44_55",
+		"/org/bluez/hci0":                                            "/org/bluez/hci0",
+	}
+	for path, expected := range tests {
+		if DevicePath(path) != expected {
+			t.Errorf("%s: expected %s, got %s", path, expected, DevicePath(path))
+		}
+	}
+}
+
+func TestOperationQueue(t *testing.T) {
+
+	q := NewOperationQueue(1)
+
+	var mutex sync.Mutex
+	running := map[string]int{}
+	max := map[string]int{}
+	op := func(device string) func() error {
+		return func() error {
+			mutex.Lock()
+			running[device]++
+			if running[device] > max[device] {
+				max[device] = running[device]
+			}
+			mutex.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mutex.Lock()
+			running[device]--
+			mutex.Unlock()
+			return nil
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	for _, device := range []string{"dev_1", "dev_2"} {
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func(device string) {
+				defer wg.Done()
+				if err := q.Do(context.Background(), device, op(device)); err != nil {
+					t.Error(err)
+				}
+			}(device)
+		}
+	}
+
+	// wait for both devices to be busy, the third operation exceeds the depth
+	for q.Pending("dev_1") < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := q.Do(context.Background(), "dev_1", op("dev_1")); err != ErrQueueFull {
+		t.Errorf("Expected %s, got %v", ErrQueueFull, err)
+	}
+
+	wg.Wait()
+	if max["dev_1"] != 1 || max["dev_2"] != 1 {
+		t.Fatalf("Operations of a device ran concurrently: %v", max)
+	}
+	if q.Pending("dev_1") != 0 {
+		t.Fatalf("Expected an empty queue, got %d", q.Pending("dev_1"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := make(chan bool)
+	go q.Do(context.Background(), "dev_1", func() error {
+		<-blocked
+		return nil
+	})
+	for q.Pending("dev_1") < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := q.Do(ctx, "dev_1", op("dev_1")); err != context.Canceled {
+		t.Errorf("Expected %s, got %v", context.Canceled, err)
+	}
+	close(blocked)
+}
@@ -0,0 +1,203 @@
+package bluez
+
+import (
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+//RecoveryRetries how many times a failing handler is retried after a restart,
+// eg. while bluetoothd has not yet exposed the adapters
+const RecoveryRetries = 20
+
+//RecoveryInterval the delay between the retries of a failing handler
+const RecoveryInterval = 500 * time.Millisecond
+
+//RecoveryStatus the status of bluetoothd reported by a RecoveryEvent
+type RecoveryStatus int
+
+const (
+	// RecoveryLost bluetoothd left the bus, the registrations are gone
+	RecoveryLost RecoveryStatus = iota
+	// RecoveryRecovered bluetoothd is back and the handlers have run
+	RecoveryRecovered
+)
+
+//RecoveryHandler set up again what a bluetoothd restart lost, eg. register
+// an application or an agent
+type RecoveryHandler func() error
+
+//RecoveryEvent a bluetoothd restart
+type RecoveryEvent struct {
+	Status RecoveryStatus
+	// Owner the unique name of the new bluetoothd
+	Owner string
+	// Errors the handlers still failing after RecoveryRetries, by name
+	Errors map[string]error
+}
+
+type recoveryHandler struct {
+	name string
+	fn   RecoveryHandler
+}
+
+//Recovery watch the owner of org.bluez on a connection and run the registered
+// handlers when bluetoothd restarts
+type Recovery struct {
+	conn *dbus.Conn
+
+	mutex     sync.Mutex
+	handlers  []*recoveryHandler
+	listeners map[chan *RecoveryEvent]bool
+	running   bool
+}
+
+var recoveries = struct {
+	sync.Mutex
+	list map[*dbus.Conn]*Recovery
+}{list: make(map[*dbus.Conn]*Recovery)}
+
+//GetRecovery return the recovery of a connection, watching for restarts from
+// the first use
+func GetRecovery(conn *dbus.Conn) (*Recovery, error) {
+
+	recoveries.Lock()
+	defer recoveries.Unlock()
+
+	if r, ok := recoveries.list[conn]; ok {
+		return r, nil
+	}
+
+	signals, err := GetSignalDispatcher(conn).Subscribe(SignalFilter{
+		Path:      "/org/freedesktop/DBus",
+		Interface: "org.freedesktop.DBus",
+		Member:    "NameOwnerChanged",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recovery{
+		conn:      conn,
+		listeners: make(map[chan *RecoveryEvent]bool),
+	}
+	go r.watch(signals)
+
+	recoveries.list[conn] = r
+	return r, nil
+}
+
+//Add a handler, a handler with the same name is replaced. Handlers run in
+// the order they have been added
+func (r *Recovery) Add(name string, fn RecoveryHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, h := range r.handlers {
+		if h.name == name {
+			// a running recovery may hold the previous handler
+			r.handlers[i] = &recoveryHandler{name, fn}
+			return
+		}
+	}
+	r.handlers = append(r.handlers, &recoveryHandler{name, fn})
+}
+
+//Remove a handler by name
+func (r *Recovery) Remove(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, h := range r.handlers {
+		if h.name == name {
+			r.handlers = append(r.handlers[:i], r.handlers[i+1:]...)
+			return
+		}
+	}
+}
+
+//Events receive the restarts of bluetoothd, call the returned function to
+// stop. Events are dropped if the channel is not read
+func (r *Recovery) Events() (<-chan *RecoveryEvent, func()) {
+	ch := make(chan *RecoveryEvent, 4)
+	r.mutex.Lock()
+	r.listeners[ch] = true
+	r.mutex.Unlock()
+	return ch, func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if r.listeners[ch] {
+			delete(r.listeners, ch)
+			close(ch)
+		}
+	}
+}
+
+func (r *Recovery) emit(ev *RecoveryEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for ch := range r.listeners {
+		select {
+		case ch <- ev:
+		default:
+			GetLogger().Warnf("Recovery event dropped, listener too slow")
+		}
+	}
+}
+
+func (r *Recovery) watch(signals chan *dbus.Signal) {
+	for sig := range signals {
+		if len(sig.Body) < 3 {
+			continue
+		}
+		name, _ := sig.Body[0].(string)
+		if name != OrgBluez {
+			continue
+		}
+		owner, _ := sig.Body[2].(string)
+		if owner == "" {
+			GetLogger().Warnf("bluetoothd left the bus")
+			r.emit(&RecoveryEvent{Status: RecoveryLost})
+			continue
+		}
+		GetLogger().Infof("bluetoothd is back as %s, recovering", owner)
+		go r.recover(owner)
+	}
+}
+
+func (r *Recovery) recover(owner string) {
+
+	r.mutex.Lock()
+	if r.running {
+		r.mutex.Unlock()
+		return
+	}
+	r.running = true
+	handlers := make([]*recoveryHandler, len(r.handlers))
+	copy(handlers, r.handlers)
+	r.mutex.Unlock()
+
+	errs := make(map[string]error)
+	for _, h := range handlers {
+		var err error
+		for i := 0; i < RecoveryRetries; i++ {
+			if err = h.fn(); err == nil {
+				break
+			}
+			time.Sleep(RecoveryInterval)
+		}
+		if err != nil {
+			GetLogger().Errorf("Recovery of %s failed: %s", h.name, err.Error())
+			errs[h.name] = err
+		}
+	}
+
+	r.mutex.Lock()
+	r.running = false
+	r.mutex.Unlock()
+
+	r.emit(&RecoveryEvent{
+		Status: RecoveryRecovered,
+		Owner:  owner,
+		Errors: errs,
+	})
+}
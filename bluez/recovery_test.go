@@ -0,0 +1,51 @@
+package bluez
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRecoveryReplace(t *testing.T) {
+
+	r := &Recovery{listeners: make(map[chan *RecoveryEvent]bool)}
+	events, cancel := r.Events()
+	defer cancel()
+
+	var mutex sync.Mutex
+	calls := map[string]int{}
+	handler := func(name string) RecoveryHandler {
+		return func() error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			calls[name]++
+			return nil
+		}
+	}
+	r.Add("first", handler("first"))
+	r.Add("second", handler("second"))
+
+	// replaced while a recovery runs
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			r.Add("first", handler("replaced"))
+		}
+		done <- true
+	}()
+	r.recover(":1.1")
+	<-done
+
+	ev := <-events
+	if ev.Status != RecoveryRecovered || ev.Owner != ":1.1" || len(ev.Errors) != 0 {
+		t.Fatalf("Unexpected event %+v", ev)
+	}
+	if len(r.handlers) != 2 || r.handlers[0].name != "first" {
+		t.Fatalf("Expected the handler replaced in place, got %v", r.handlers)
+	}
+
+	r.recover(":1.2")
+	<-events
+	if calls["first"]+calls["replaced"] != 2 || calls["replaced"] == 0 || calls["second"] != 2 {
+		t.Fatalf("Unexpected calls %v", calls)
+	}
+}
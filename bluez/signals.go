@@ -0,0 +1,238 @@
+package bluez
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+)
+
+//SignalFilter select the signals delivered to a subscriber, empty fields
+// match any value
+type SignalFilter struct {
+	// Path the object emitting the signal
+	Path dbus.ObjectPath
+	// PathNamespace match Path and the objects below it, eg. all the devices
+	// of an adapter
+	PathNamespace bool
+	Interface     string
+	Member        string
+}
+
+// rule the AddMatch rule installed on the bus for the filter, shared by all
+// the subscribers of the same interface and member
+func (f SignalFilter) rule() string {
+	rule := "type='signal'"
+	if f.Interface != "" {
+		rule += ",interface='" + f.Interface + "'"
+	}
+	if f.Member != "" {
+		rule += ",member='" + f.Member + "'"
+	}
+	return rule
+}
+
+//Match return true if the signal passes the filter
+func (f SignalFilter) Match(sig *dbus.Signal) bool {
+
+	if f.Path != "" {
+		if f.PathNamespace {
+			prefix := string(f.Path)
+			if prefix != "/" && sig.Path != f.Path && !strings.HasPrefix(string(sig.Path), prefix+"/") {
+				return false
+			}
+		} else if sig.Path != f.Path {
+			return false
+		}
+	}
+
+	i := strings.LastIndex(sig.Name, ".")
+	if i == -1 {
+		return false
+	}
+	if f.Interface != "" && sig.Name[:i] != f.Interface {
+		return false
+	}
+	if f.Member != "" && sig.Name[i+1:] != f.Member {
+		return false
+	}
+	return true
+}
+
+//SignalQueueSize how many signals are queued for a subscriber not reading
+// its channel, the following ones are dropped
+const SignalQueueSize = 256
+
+// subscription forward the signals of a subscriber from its own goroutine,
+// a slow subscriber does not hold back the others nor the connection
+type subscription struct {
+	filter  SignalFilter
+	ch      chan *dbus.Signal
+	queue   chan *dbus.Signal
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// deliver queue the signal without blocking, it is dropped if the queue is full
+func (s *subscription) deliver(sig *dbus.Signal) {
+	select {
+	case s.queue <- sig:
+	default:
+		GetLogger().Warnf("Signal %s of %s dropped, subscriber too slow", sig.Name, sig.Path)
+	}
+}
+
+// forward the queued signals to the subscriber until it unsubscribes
+func (s *subscription) forward() {
+	defer close(s.stopped)
+	for {
+		select {
+		case sig := <-s.queue:
+			select {
+			case s.ch <- sig:
+			case <-s.done:
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+//SignalDispatcher receive the signals of a connection and route them to the
+// subscribers. A single AddMatch is installed per interface and member, no
+// matter how many objects are watched, to stay below the bus match limits
+type SignalDispatcher struct {
+	conn    *dbus.Conn
+	signals chan *dbus.Signal
+
+	mutex         sync.Mutex
+	matches       map[string]int
+	subscriptions map[chan *dbus.Signal]*subscription
+}
+
+var dispatchers = struct {
+	sync.Mutex
+	list map[*dbus.Conn]*SignalDispatcher
+}{list: make(map[*dbus.Conn]*SignalDispatcher)}
+
+//GetSignalDispatcher return the dispatcher of a connection, created on first use
+func GetSignalDispatcher(conn *dbus.Conn) *SignalDispatcher {
+
+	dispatchers.Lock()
+	defer dispatchers.Unlock()
+
+	if d, ok := dispatchers.list[conn]; ok {
+		return d
+	}
+
+	d := &SignalDispatcher{
+		conn:          conn,
+		signals:       make(chan *dbus.Signal, 64),
+		matches:       make(map[string]int),
+		subscriptions: make(map[chan *dbus.Signal]*subscription),
+	}
+	conn.Signal(d.signals)
+	go d.run()
+
+	dispatchers.list[conn] = d
+	return d
+}
+
+//Subscribe receive the signals passing filter. Callers must Unsubscribe once
+// done, the channel is not closed by Unsubscribe and may be closed only after.
+// The signals are queued up to SignalQueueSize while the channel is not read
+func (d *SignalDispatcher) Subscribe(filter SignalFilter) (chan *dbus.Signal, error) {
+
+	rule := filter.rule()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.matches[rule] == 0 {
+		err := d.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule).Store()
+		if err != nil {
+			return nil, err
+		}
+	}
+	d.matches[rule]++
+
+	s := &subscription{
+		filter:  filter,
+		ch:      make(chan *dbus.Signal, 10),
+		queue:   make(chan *dbus.Signal, SignalQueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	d.subscriptions[s.ch] = s
+	go s.forward()
+	return s.ch, nil
+}
+
+//Unsubscribe stop delivering signals to ch, no signal is sent once it
+// returns. The match is removed from the bus with its last subscriber
+func (d *SignalDispatcher) Unsubscribe(ch chan *dbus.Signal) error {
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	s, ok := d.subscriptions[ch]
+	if !ok {
+		return nil
+	}
+	delete(d.subscriptions, ch)
+	close(s.done)
+	<-s.stopped
+
+	rule := s.filter.rule()
+	d.matches[rule]--
+	if d.matches[rule] > 0 {
+		return nil
+	}
+	delete(d.matches, rule)
+	return d.conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, rule).Store()
+}
+
+//Subscribers return the number of subscriptions
+func (d *SignalDispatcher) Subscribers() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return len(d.subscriptions)
+}
+
+//Matches return the number of match rules installed on the bus
+func (d *SignalDispatcher) Matches() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return len(d.matches)
+}
+
+func (d *SignalDispatcher) run() {
+
+	// the channel is closed with the connection
+	for sig := range d.signals {
+
+		d.mutex.Lock()
+		targets := make([]*subscription, 0)
+		for _, s := range d.subscriptions {
+			if s.filter.Match(sig) {
+				targets = append(targets, s)
+			}
+		}
+		d.mutex.Unlock()
+
+		if len(targets) > 0 {
+			recordSignal(sig)
+		}
+
+		for _, s := range targets {
+			s.deliver(sig)
+		}
+	}
+
+	dispatchers.Lock()
+	if dispatchers.list[d.conn] == d {
+		delete(dispatchers.list, d.conn)
+	}
+	dispatchers.Unlock()
+}
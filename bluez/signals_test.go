@@ -0,0 +1,45 @@
+package bluez
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func TestSignalFilter(t *testing.T) {
+
+	sig := &dbus.Signal{
+		Path: "/org/bluez/hci0/dev_00_11_22_33_44_55",
+		Name: PropertiesInterface + ".PropertiesChanged",
+	}
+
+	tests := []struct {
+		filter SignalFilter
+		match  bool
+	}{
+		{SignalFilter{}, true},
+		{SignalFilter{Path: sig.Path}, true},
+		{SignalFilter{Path: "/org/bluez/hci0"}, false},
+		{SignalFilter{Path: "/org/bluez/hci0", PathNamespace: true}, true},
+		{SignalFilter{Path: "/org/bluez/hci", PathNamespace: true}, false},
+		{SignalFilter{Path: "/", PathNamespace: true}, true},
+		{SignalFilter{Interface: PropertiesInterface}, true},
+		{SignalFilter{Interface: ObjectManagerInterface}, false},
+		{SignalFilter{Interface: PropertiesInterface, Member: "PropertiesChanged"}, true},
+		{SignalFilter{Member: "InterfacesAdded"}, false},
+	}
+
+	for i, test := range tests {
+		if test.filter.Match(sig) != test.match {
+			t.Errorf("%d: expected match %t for %+v", i, test.match, test.filter)
+		}
+	}
+}
+
+func TestSignalFilterRule(t *testing.T) {
+	f := SignalFilter{Path: "/org/bluez/hci0", Interface: PropertiesInterface, Member: "PropertiesChanged"}
+	expected := "type='signal',interface='" + PropertiesInterface + "',member='PropertiesChanged'"
+	if f.rule() != expected {
+		t.Fatalf("Unexpected rule %s", f.rule())
+	}
+}
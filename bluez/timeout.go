@@ -0,0 +1,58 @@
+package bluez
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var timeouts = struct {
+	sync.RWMutex
+	all     time.Duration
+	methods map[string]time.Duration
+}{methods: make(map[string]time.Duration)}
+
+//SetCallTimeout set the timeout of all the calls made by the clients, zero
+// waits for the reply as long as the bus does. A call timing out returns
+// context.DeadlineExceeded
+func SetCallTimeout(timeout time.Duration) {
+	timeouts.Lock()
+	defer timeouts.Unlock()
+	timeouts.all = timeout
+}
+
+//SetMethodTimeout set the timeout of a method, overriding SetCallTimeout, eg.
+// Device1Interface+".Connect" that can block for 40 seconds on an
+// unresponsive controller. Zero restores the global timeout, a negative
+// timeout disables it for the method
+func SetMethodTimeout(method string, timeout time.Duration) {
+	timeouts.Lock()
+	defer timeouts.Unlock()
+	if timeout == 0 {
+		delete(timeouts.methods, method)
+		return
+	}
+	timeouts.methods[method] = timeout
+}
+
+//CallTimeout return the timeout of a method, zero if none
+func CallTimeout(method string) time.Duration {
+	timeouts.RLock()
+	defer timeouts.RUnlock()
+	if timeout, ok := timeouts.methods[method]; ok {
+		if timeout < 0 {
+			return 0
+		}
+		return timeout
+	}
+	return timeouts.all
+}
+
+// withCallTimeout bound ctx with the timeout of method, if any
+func withCallTimeout(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	timeout := CallTimeout(method)
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,35 @@
+package bluez
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCallTimeout(t *testing.T) {
+
+	method := Device1Interface + ".Connect"
+	defer SetCallTimeout(0)
+	defer SetMethodTimeout(method, 0)
+
+	if CallTimeout(method) != 0 {
+		t.Fatal("Expected no timeout by default")
+	}
+
+	SetCallTimeout(time.Second)
+	if CallTimeout(method) != time.Second {
+		t.Fatalf("Expected the global timeout, got %s", CallTimeout(method))
+	}
+
+	SetMethodTimeout(method, 5*time.Second)
+	if CallTimeout(method) != 5*time.Second {
+		t.Fatalf("Expected the method timeout, got %s", CallTimeout(method))
+	}
+	if CallTimeout(Device1Interface+".Pair") != time.Second {
+		t.Fatal("Expected the global timeout for the other methods")
+	}
+
+	SetMethodTimeout(method, -1)
+	if CallTimeout(method) != 0 {
+		t.Fatalf("Expected the timeout disabled, got %s", CallTimeout(method))
+	}
+}
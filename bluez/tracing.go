@@ -0,0 +1,74 @@
+package bluez
+
+import (
+	"context"
+	"sync"
+)
+
+// Attributes of the traced operations
+const (
+	AttrAddress = "bluetooth.address"
+	AttrUUID    = "bluetooth.uuid"
+	AttrAdapter = "bluetooth.adapter"
+	AttrPath    = "dbus.path"
+)
+
+//Span a traced operation
+type Span interface {
+	// End the operation, err is recorded when not nil
+	End(err error)
+}
+
+//Tracer trace the main operations, eg. the registration of an application,
+// the connection to a device and the GATT reads and writes. See the tracing
+// package for an OpenTelemetry implementation
+type Tracer interface {
+	// Start a span as a child of the span of ctx, if any
+	Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span)
+}
+
+var tracer = struct {
+	sync.RWMutex
+	t Tracer
+}{t: NopTracer{}}
+
+//SetTracer set the tracer, nil disables the tracing
+func SetTracer(t Tracer) {
+	if t == nil {
+		t = NopTracer{}
+	}
+	tracer.Lock()
+	defer tracer.Unlock()
+	tracer.t = t
+}
+
+//GetTracer return the tracer
+func GetTracer() Tracer {
+	tracer.RLock()
+	defer tracer.RUnlock()
+	return tracer.t
+}
+
+//StartSpan start a span with the tracer, attrs are key value pairs, eg.
+// AttrAddress, "00:11:22:33:44:55". Empty values are skipped
+func StartSpan(ctx context.Context, name string, attrs ...string) (context.Context, Span) {
+	m := make(map[string]string, len(attrs)/2)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			m[attrs[i]] = attrs[i+1]
+		}
+	}
+	return GetTracer().Start(ctx, name, m)
+}
+
+//NopTracer discard the spans
+type NopTracer struct{}
+
+//Start return ctx and a span doing nothing
+func (NopTracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) End(err error) {}
@@ -0,0 +1,8 @@
+//Package tracing trace the operations of the library with OpenTelemetry. The
+// implementation depends on go.opentelemetry.io/otel and is built with the
+// otel tag:
+//
+//	go build -tags otel
+//
+//	bluez.SetTracer(tracing.New(otel.Tracer("ble-gateway")))
+package tracing
@@ -0,0 +1,47 @@
+//go:build otel
+// +build otel
+
+package tracing
+
+import (
+	"context"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//Tracer a bluez.Tracer creating OpenTelemetry spans
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+//New create a tracer, eg. with otel.Tracer(name)
+func New(t trace.Tracer) *Tracer {
+	return &Tracer{tracer: t}
+}
+
+//Start a client span carrying attrs
+func (t *Tracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, bluez.Span) {
+	kv := make([]attribute.KeyValue, 0, len(attrs))
+	for k, v := range attrs {
+		kv = append(kv, attribute.String(k, v))
+	}
+	ctx, s := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(kv...))
+	return ctx, &span{s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
@@ -0,0 +1,51 @@
+package bluez
+
+import (
+	"context"
+	"testing"
+)
+
+type recordedSpan struct {
+	name  string
+	attrs map[string]string
+	ended bool
+	err   error
+}
+
+func (s *recordedSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+type recordedTracer struct {
+	spans []*recordedSpan
+}
+
+func (r *recordedTracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span) {
+	s := &recordedSpan{name: name, attrs: attrs}
+	r.spans = append(r.spans, s)
+	return ctx, s
+}
+
+func TestStartSpan(t *testing.T) {
+
+	r := &recordedTracer{}
+	SetTracer(r)
+	defer SetTracer(nil)
+
+	_, span := StartSpan(context.Background(), "device.connect",
+		AttrPath, "/org/bluez/hci0/dev_00_11_22_33_44_55",
+		AttrAddress, "")
+	span.End(ErrFailed)
+
+	if len(r.spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(r.spans))
+	}
+	s := r.spans[0]
+	if s.name != "device.connect" || !s.ended || s.err != ErrFailed {
+		t.Fatalf("Unexpected span %+v", s)
+	}
+	if len(s.attrs) != 1 || s.attrs[AttrPath] == "" {
+		t.Fatalf("Unexpected attributes %v", s.attrs)
+	}
+}
@@ -0,0 +1,371 @@
+//Package bridge map characteristics to MQTT topics, for the BLE gateways
+// assembled from a configuration: the characteristics of the local
+// applications (server side) or of the remote devices (client side) are
+// published and written from their topics.
+//
+// The MQTT client is a Broker, NewMQTTBroker wraps the paho client when built
+// with -tags mqtt
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//Broker the MQTT client of a Bridge
+type Broker interface {
+	Publish(topic string, qos byte, retain bool, payload []byte) error
+	// Subscribe call fn with the payloads received on the topic
+	Subscribe(topic string, qos byte, fn func(payload []byte)) error
+	Unsubscribe(topic string) error
+}
+
+//Direction the way the values flow between a characteristic and its topic
+type Direction string
+
+// Directions of a mapping
+const (
+	// ToMQTT publish the values: the writes of the remote devices on the
+	// server side, the notifications or the polled reads on the client side
+	ToMQTT Direction = "to-mqtt"
+	// FromMQTT write the payloads received: update and notify the value on
+	// the server side, write the characteristic on the client side
+	FromMQTT Direction = "from-mqtt"
+	// Both publish on the topic and receive on the set topic
+	Both Direction = "both"
+)
+
+//Mapping a characteristic and its topic
+type Mapping struct {
+	// Device the address of the remote device on the client side, empty for
+	// the characteristics of the local application
+	Device string `yaml:"device"`
+	// Service the UUID of the service, optional if the characteristic UUID is
+	// unique
+	Service        string `yaml:"service"`
+	Characteristic string `yaml:"characteristic"`
+
+	Topic string `yaml:"topic"`
+	// SetTopic the topic received, defaults to the topic for FromMQTT and to
+	// topic/set for Both
+	SetTopic  string    `yaml:"set_topic"`
+	Direction Direction `yaml:"direction"`
+	QoS       byte      `yaml:"qos"`
+	Retain    bool      `yaml:"retain"`
+
+	// Transform the name of a transform, see RegisterTransform, and its
+	// options
+	Transform string            `yaml:"transform"`
+	Options   map[string]string `yaml:"options"`
+
+	// Poll read the characteristic periodically instead of subscribing to
+	// its notifications, client side
+	Poll time.Duration `yaml:"poll"`
+}
+
+//Config the mappings of a Bridge
+type Config struct {
+	Mappings []Mapping `yaml:"mappings"`
+	// Logger log the errors of the mappings, defaults to bluez.GetLogger()
+	Logger bluez.Logger `yaml:"-"`
+}
+
+//LoadConfig read a configuration in YAML
+func LoadConfig(file string) (*Config, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cfg := new(Config)
+	if err = yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	return cfg, nil
+}
+
+// mapping a Mapping resolved
+type mapping struct {
+	Mapping
+	transform Transform
+	// stop the notifications or the polling of the client side
+	stop func()
+}
+
+func (m *mapping) String() string {
+	if m.Device != "" {
+		return m.Device + "/" + m.Characteristic
+	}
+	return m.Characteristic
+}
+
+func (m *mapping) publishes() bool {
+	return m.Direction == ToMQTT || m.Direction == Both
+}
+
+func (m *mapping) receives() bool {
+	return m.Direction == FromMQTT || m.Direction == Both
+}
+
+//Bridge the mappings of a configuration, started with Start
+type Bridge struct {
+	broker   Broker
+	logger   bluez.Logger
+	mappings []*mapping
+
+	mutex   sync.Mutex
+	started bool
+}
+
+//New check the configuration and create its transforms
+func New(broker Broker, cfg *Config) (*Bridge, error) {
+
+	b := &Bridge{
+		broker: broker,
+		logger: cfg.Logger,
+	}
+	if b.logger == nil {
+		b.logger = bluez.GetLogger()
+	}
+
+	for i, m := range cfg.Mappings {
+		if m.Characteristic == "" || m.Topic == "" {
+			return nil, fmt.Errorf("Mapping %d: characteristic and topic are required", i)
+		}
+		if _, err := bluetooth.ParseUUID(m.Characteristic); err != nil {
+			return nil, fmt.Errorf("Mapping %d: %s", i, err)
+		}
+		switch m.Direction {
+		case "":
+			m.Direction = ToMQTT
+		case ToMQTT, FromMQTT, Both:
+		default:
+			return nil, fmt.Errorf("Mapping %d: invalid direction %s", i, m.Direction)
+		}
+		if m.QoS > 2 {
+			return nil, fmt.Errorf("Mapping %d: invalid QoS %d", i, m.QoS)
+		}
+		if m.SetTopic == "" {
+			m.SetTopic = m.Topic
+			if m.Direction == Both {
+				m.SetTopic = m.Topic + "/set"
+			}
+		}
+		if m.Direction == Both && m.SetTopic == m.Topic {
+			return nil, fmt.Errorf("Mapping %d: the set topic must differ from the topic", i)
+		}
+		t, err := NewTransform(m.Transform, m.Options)
+		if err != nil {
+			return nil, fmt.Errorf("Mapping %d: %s", i, err)
+		}
+		b.mappings = append(b.mappings, &mapping{Mapping: m, transform: t})
+	}
+	return b, nil
+}
+
+//Start the mappings: the server side mappings on the characteristics of app,
+// nil if there are none, the client side ones on the remote devices. The
+// remote devices must be known to bluez, eg. from a scan, they are connected
+// if needed. The write callbacks of the server side characteristics are
+// replaced
+func (b *Bridge) Start(ctx context.Context, app *service.Application) error {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.started {
+		return fmt.Errorf("Bridge already started")
+	}
+
+	for _, m := range b.mappings {
+		var err error
+		if m.Device == "" {
+			err = b.startServer(m, app)
+		} else {
+			err = b.startClient(ctx, m)
+		}
+		if err != nil {
+			b.stop()
+			return fmt.Errorf("%s: %s", m, err)
+		}
+	}
+	b.started = true
+	return nil
+}
+
+//Stop the mappings
+func (b *Bridge) Stop() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.stop()
+	b.started = false
+}
+
+func (b *Bridge) stop() {
+	for _, m := range b.mappings {
+		if m.stop != nil {
+			m.stop()
+			m.stop = nil
+		}
+	}
+}
+
+// publish a value of the characteristic of m
+func (b *Bridge) publish(m *mapping, value []byte) {
+	payload, err := m.transform.ToMQTT(value)
+	if err != nil {
+		b.logger.Warnf("bridge: %s: %s", m, err.Error())
+		return
+	}
+	if err = b.broker.Publish(m.Topic, m.QoS, m.Retain, payload); err != nil {
+		b.logger.Warnf("bridge: %s: publish %s: %s", m, m.Topic, err.Error())
+	}
+}
+
+// subscribe the set topic of m, calling write with the values
+func (b *Bridge) subscribe(m *mapping, write func(value []byte) error) error {
+	err := b.broker.Subscribe(m.SetTopic, m.QoS, func(payload []byte) {
+		value, err := m.transform.FromMQTT(payload)
+		if err == nil {
+			err = write(value)
+		}
+		if err != nil {
+			b.logger.Warnf("bridge: %s: %s", m, err.Error())
+		}
+	})
+	if err != nil {
+		return err
+	}
+	stop := m.stop
+	m.stop = func() {
+		if stop != nil {
+			stop()
+		}
+		b.broker.Unsubscribe(m.SetTopic)
+	}
+	return nil
+}
+
+func (b *Bridge) startServer(m *mapping, app *service.Application) error {
+
+	if app == nil {
+		return fmt.Errorf("No application for the server side mapping")
+	}
+	char, err := findCharacteristic(app, m.Service, m.Characteristic)
+	if err != nil {
+		return err
+	}
+
+	if m.publishes() {
+		char.SetWriteFunc(func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+			c.UpdateValue(value)
+			b.publish(m, value)
+			return nil
+		})
+	}
+	if m.receives() {
+		return b.subscribe(m, func(value []byte) error {
+			char.UpdateValue(value)
+			return nil
+		})
+	}
+	return nil
+}
+
+// findCharacteristic find a characteristic of an application by UUID, in the
+// service if not empty
+func findCharacteristic(app *service.Application, srvUUID, charUUID string) (*service.GattCharacteristic1, error) {
+	var found *service.GattCharacteristic1
+	for _, srv := range app.GetServices() {
+		if srvUUID != "" && !bluetooth.EqualUUID(srv.UUID(), srvUUID) {
+			continue
+		}
+		for _, char := range srv.GetCharacteristics() {
+			if !bluetooth.EqualUUID(char.UUID(), charUUID) {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("Characteristic %s found in several services", charUUID)
+			}
+			found = char
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("Characteristic %s not found", charUUID)
+	}
+	return found, nil
+}
+
+func (b *Bridge) startClient(ctx context.Context, m *mapping) error {
+
+	dev, err := api.GetDeviceByAddress(strings.ToUpper(m.Device))
+	if err != nil {
+		return err
+	}
+	if dev == nil {
+		return fmt.Errorf("Device not found")
+	}
+	if err = dev.ResolveServices(ctx); err != nil {
+		return err
+	}
+	char, err := dev.FindChar(m.Service, m.Characteristic)
+	if err != nil {
+		return err
+	}
+
+	if m.publishes() {
+		if m.Poll > 0 {
+			m.stop = b.poll(m, char)
+		} else {
+			values, stop, err := char.Notifications()
+			if err != nil {
+				return err
+			}
+			m.stop = stop
+			go func() {
+				for value := range values {
+					b.publish(m, value)
+				}
+			}()
+		}
+	}
+	if m.receives() {
+		return b.subscribe(m, func(value []byte) error {
+			return char.WriteValue(value, nil)
+		})
+	}
+	return nil
+}
+
+// poll read the characteristic of m every m.Poll and publish the value
+func (b *Bridge) poll(m *mapping, char *profile.GattCharacteristic1) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(m.Poll)
+		defer ticker.Stop()
+		for {
+			value, err := char.ReadValue(nil)
+			if err != nil {
+				b.logger.Warnf("bridge: %s: read: %s", m, err.Error())
+			} else {
+				b.publish(m, value)
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
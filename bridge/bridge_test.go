@@ -0,0 +1,239 @@
+package bridge
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestTransforms(t *testing.T) {
+
+	cases := []struct {
+		name    string
+		options map[string]string
+		value   []byte
+		payload string
+	}{
+		{"", nil, []byte{0x01, 0x02}, "\x01\x02"},
+		{"hex", nil, []byte{0x01, 0xab}, "01ab"},
+		{"base64", nil, []byte{0x01, 0xab}, "Aas="},
+		{"text", nil, []byte("hello"), "hello"},
+		{"number", map[string]string{"format": "sint16", "exponent": "-2"}, []byte{0x9c, 0xff}, "-1"},
+		{"number", map[string]string{"format": "uint8"}, []byte{0x64}, "100"},
+	}
+	for _, c := range cases {
+		tr, err := NewTransform(c.name, c.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload, err := tr.ToMQTT(c.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(payload) != c.payload {
+			t.Fatalf("%s: expected %q, got %q", c.name, c.payload, payload)
+		}
+		value, err := tr.FromMQTT(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != string(c.value) {
+			t.Fatalf("%s: expected %x, got %x", c.name, c.value, value)
+		}
+	}
+
+	tr, err := NewTransform("number", map[string]string{"format": "uint8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tr.FromMQTT([]byte("300")); err == nil {
+		t.Fatal("Out of range value accepted")
+	}
+	if _, err = NewTransform("number", nil); err == nil {
+		t.Fatal("Missing format accepted")
+	}
+	if _, err = NewTransform("unknown", nil); err == nil {
+		t.Fatal("Unknown transform accepted")
+	}
+}
+
+func TestConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "bridge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "bridge.yaml")
+	err = ioutil.WriteFile(file, []byte(`
+mappings:
+  - device: AA:BB:CC:DD:EE:FF
+    characteristic: 2a6e
+    topic: sensors/temperature
+    transform: number
+    options:
+      format: sint16
+      exponent: "-2"
+    poll: 30s
+  - characteristic: 2a19
+    topic: gateway/battery
+    direction: both
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Mappings) != 2 || cfg.Mappings[0].Poll != 30*time.Second {
+		t.Fatalf("Unexpected configuration %+v", cfg)
+	}
+
+	b, err := New(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := b.mappings[0]; m.Direction != ToMQTT || m.SetTopic != "sensors/temperature" {
+		t.Fatalf("Unexpected mapping %+v", m.Mapping)
+	}
+	if m := b.mappings[1]; m.SetTopic != "gateway/battery/set" {
+		t.Fatalf("Unexpected set topic %s", m.SetTopic)
+	}
+
+	invalid := []Mapping{
+		{Topic: "a"},
+		{Characteristic: "zz", Topic: "a"},
+		{Characteristic: "2a19", Topic: "a", Direction: "sideways"},
+		{Characteristic: "2a19", Topic: "a", Direction: Both, SetTopic: "a"},
+		{Characteristic: "2a19", Topic: "a", QoS: 3},
+	}
+	for _, m := range invalid {
+		if _, err = New(nil, &Config{Mappings: []Mapping{m}}); err == nil {
+			t.Fatalf("Invalid mapping accepted %+v", m)
+		}
+	}
+}
+
+// fakeBroker a Broker recording the messages published
+type fakeBroker struct {
+	mutex     sync.Mutex
+	published map[string][]string
+	handlers  map[string]func(payload []byte)
+}
+
+func (f *fakeBroker) Publish(topic string, qos byte, retain bool, payload []byte) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.published[topic] = append(f.published[topic], string(payload))
+	return nil
+}
+
+func (f *fakeBroker) Subscribe(topic string, qos byte, fn func(payload []byte)) error {
+	f.handlers[topic] = fn
+	return nil
+}
+
+func (f *fakeBroker) Unsubscribe(topic string) error {
+	delete(f.handlers, topic)
+	return nil
+}
+
+func TestBridge(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+		Logger:     bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: true,
+		UUID:    app.GenerateUUID("2233"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.AddService(srv); err != nil {
+		t.Fatal(err)
+	}
+	char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID:  app.GenerateUUID("3344"),
+		Flags: []string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicWrite},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.AddCharacteristic(char); err != nil {
+		t.Fatal(err)
+	}
+	err = profile.NewGattManager1("hci0").RegisterApplication(app.Path(), map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := b.Adapter("hci0").Applications()[0]
+
+	broker := &fakeBroker{published: map[string][]string{}, handlers: map[string]func([]byte){}}
+	br, err := New(broker, &Config{
+		Mappings: []Mapping{{
+			Characteristic: app.GenerateUUID("3344"),
+			Topic:          "bluetest/value",
+			Direction:      Both,
+			Transform:      "number",
+			Options:        map[string]string{"format": "uint16"},
+		}},
+		Logger: bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = br.Start(context.Background(), app); err != nil {
+		t.Fatal(err)
+	}
+
+	uuid := app.GenerateUUID("3344")
+	if err = remote.WriteValue(uuid, []byte{0x10, 0x27}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if p := broker.published["bluetest/value"]; len(p) != 1 || p[0] != "10000" {
+		t.Fatalf("Expected 10000 published, got %v", p)
+	}
+
+	set := broker.handlers["bluetest/value/set"]
+	if set == nil {
+		t.Fatal("Set topic not subscribed")
+	}
+	set([]byte("42"))
+	value, err := remote.ReadValue(uuid, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(value) != 2 || value[0] != 42 || value[1] != 0 {
+		t.Fatalf("Expected 2a00, got %x", value)
+	}
+
+	br.Stop()
+	if len(broker.handlers) != 0 {
+		t.Fatalf("Expected the set topic unsubscribed, got %v", broker.handlers)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build mqtt
+// +build mqtt
+
+package bridge
+
+import (
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+//MQTTTimeout how long the MQTTBroker waits for the broker to acknowledge
+const MQTTTimeout = 10 * time.Second
+
+//MQTTBroker a Broker on a paho client
+type MQTTBroker struct {
+	client mqtt.Client
+}
+
+//NewMQTTBroker wrap a connected client
+func NewMQTTBroker(client mqtt.Client) *MQTTBroker {
+	return &MQTTBroker{client: client}
+}
+
+func wait(token mqtt.Token) error {
+	if !token.WaitTimeout(MQTTTimeout) {
+		return mqtt.ErrNotConnected
+	}
+	return token.Error()
+}
+
+//Publish a payload, waiting for its acknowledgment with a QoS above 0
+func (b *MQTTBroker) Publish(topic string, qos byte, retain bool, payload []byte) error {
+	token := b.client.Publish(topic, qos, retain, payload)
+	if qos == 0 {
+		return nil
+	}
+	return wait(token)
+}
+
+//Subscribe a topic
+func (b *MQTTBroker) Subscribe(topic string, qos byte, fn func(payload []byte)) error {
+	return wait(b.client.Subscribe(topic, qos, func(c mqtt.Client, msg mqtt.Message) {
+		fn(msg.Payload())
+	}))
+}
+
+//Unsubscribe a topic
+func (b *MQTTBroker) Unsubscribe(topic string) error {
+	return wait(b.client.Unsubscribe(topic))
+}
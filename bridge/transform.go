@@ -0,0 +1,138 @@
+package bridge
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/muka/go-bluetooth/service/gatt"
+)
+
+//Transform convert the values between a characteristic and its topic
+type Transform interface {
+	// ToMQTT convert a characteristic value to a payload
+	ToMQTT(value []byte) ([]byte, error)
+	// FromMQTT convert a payload to a characteristic value
+	FromMQTT(payload []byte) ([]byte, error)
+}
+
+//TransformFactory create a transform from the options of a mapping
+type TransformFactory func(options map[string]string) (Transform, error)
+
+var (
+	transformsMutex sync.RWMutex
+	transforms      = map[string]TransformFactory{
+		"raw":    func(map[string]string) (Transform, error) { return rawTransform{}, nil },
+		"hex":    func(map[string]string) (Transform, error) { return hexTransform{}, nil },
+		"base64": func(map[string]string) (Transform, error) { return base64Transform{}, nil },
+		"text":   func(map[string]string) (Transform, error) { return textTransform{}, nil },
+		"number": newNumberTransform,
+	}
+)
+
+//RegisterTransform add a transform, available to the mappings by name. The
+// transforms raw (the default), hex, base64, text and number are built in
+func RegisterTransform(name string, factory TransformFactory) {
+	transformsMutex.Lock()
+	defer transformsMutex.Unlock()
+	transforms[name] = factory
+}
+
+//NewTransform create a registered transform
+func NewTransform(name string, options map[string]string) (Transform, error) {
+	if name == "" {
+		name = "raw"
+	}
+	transformsMutex.RLock()
+	factory, ok := transforms[name]
+	transformsMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown transform %s", name)
+	}
+	return factory(options)
+}
+
+// rawTransform pass the bytes unchanged
+type rawTransform struct{}
+
+func (rawTransform) ToMQTT(value []byte) ([]byte, error)     { return value, nil }
+func (rawTransform) FromMQTT(payload []byte) ([]byte, error) { return payload, nil }
+
+type hexTransform struct{}
+
+func (hexTransform) ToMQTT(value []byte) ([]byte, error) {
+	return []byte(hex.EncodeToString(value)), nil
+}
+
+func (hexTransform) FromMQTT(payload []byte) ([]byte, error) {
+	return hex.DecodeString(strings.TrimSpace(string(payload)))
+}
+
+type base64Transform struct{}
+
+func (base64Transform) ToMQTT(value []byte) ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(value)), nil
+}
+
+func (base64Transform) FromMQTT(payload []byte) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(payload)))
+}
+
+// textTransform the UTF-8 text of the value, without the trailing NULs
+type textTransform struct{}
+
+func (textTransform) ToMQTT(value []byte) ([]byte, error) {
+	return []byte(strings.TrimRight(string(value), "\x00")), nil
+}
+
+func (textTransform) FromMQTT(payload []byte) ([]byte, error) {
+	return payload, nil
+}
+
+// numberTransform a numeric value in a presentation format, published as a
+// decimal number
+type numberTransform struct {
+	format gatt.PresentationFormat
+}
+
+// newNumberTransform read the options format, eg. sint16, and exponent, eg.
+// -2 for a value in hundredths
+func newNumberTransform(options map[string]string) (Transform, error) {
+	t := &numberTransform{}
+	name := options["format"]
+	if name == "" {
+		return nil, fmt.Errorf("number: the format option is required")
+	}
+	format, err := gatt.ParseFormat(name)
+	if err != nil {
+		return nil, err
+	}
+	t.format.Format = format
+	if e, ok := options["exponent"]; ok {
+		v, err := strconv.ParseInt(e, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("number: invalid exponent %s", e)
+		}
+		t.format.Exponent = int8(v)
+	}
+	return t, nil
+}
+
+func (t *numberTransform) ToMQTT(value []byte) ([]byte, error) {
+	v, err := t.format.DecodeFloat(value)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+}
+
+func (t *numberTransform) FromMQTT(payload []byte) ([]byte, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(payload)), 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid number %q", payload)
+	}
+	return t.format.EncodeFloat(v)
+}
@@ -0,0 +1,225 @@
+package bluetooth
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Class the Class of Device of a classic device, eg. the Class property of
+// Device1 or the class of an inquiry: the service classes, the major and the
+// minor device class
+type Class uint32
+
+//NewClass encode a Class of Device
+func NewClass(major MajorClass, minor uint8, services ServiceClass) Class {
+	return Class(uint32(services&0x7ff)<<13 | uint32(major&0x1f)<<8 | uint32(minor&0x3f)<<2)
+}
+
+//ParseClass decode the 3 bytes of a Class of Device, little endian, eg. from
+// the EIR data
+func ParseClass(b []byte) (Class, error) {
+	if len(b) != 3 {
+		return 0, fmt.Errorf("Invalid Class of Device length %d", len(b))
+	}
+	return Class(uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16), nil
+}
+
+//Major return the major device class
+func (c Class) Major() MajorClass {
+	return MajorClass(c >> 8 & 0x1f)
+}
+
+//Minor return the minor device class, its meaning depends on the major class,
+// see MinorName
+func (c Class) Minor() uint8 {
+	return uint8(c >> 2 & 0x3f)
+}
+
+//Services return the major service classes
+func (c Class) Services() ServiceClass {
+	return ServiceClass(c >> 13 & 0x7ff)
+}
+
+//MinorName return the name of the minor device class, empty if not known
+func (c Class) MinorName() string {
+
+	minor := c.Minor()
+	switch c.Major() {
+	case MajorComputer:
+		return className(computerClasses, minor)
+	case MajorPhone:
+		return className(phoneClasses, minor)
+	case MajorNetwork:
+		// the load factor, on the 3 high bits
+		return className(networkClasses, minor>>3)
+	case MajorAudioVideo:
+		return className(audioVideoClasses, minor)
+	case MajorPeripheral:
+		parts := []string{}
+		if n := className(peripheralInputClasses, minor>>4); n != "" {
+			parts = append(parts, n)
+		}
+		if n := className(peripheralClasses, minor&0x0f); n != "" {
+			parts = append(parts, n)
+		}
+		return strings.Join(parts, ", ")
+	case MajorImaging:
+		// a bitmask, on the 4 high bits
+		parts := []string{}
+		for i, n := range imagingClasses {
+			if minor&(1<<uint(i+2)) != 0 {
+				parts = append(parts, n)
+			}
+		}
+		return strings.Join(parts, ", ")
+	case MajorWearable:
+		return className(wearableClasses, minor)
+	case MajorToy:
+		return className(toyClasses, minor)
+	case MajorHealth:
+		return className(healthClasses, minor)
+	}
+	return ""
+}
+
+//String describe the class, eg. "Phone (Smartphone) [Networking, Telephony]"
+func (c Class) String() string {
+	s := c.Major().String()
+	if minor := c.MinorName(); minor != "" {
+		s += " (" + minor + ")"
+	}
+	if services := c.Services(); services != 0 {
+		s += " [" + services.String() + "]"
+	}
+	return s
+}
+
+//MajorClass a major device class
+type MajorClass uint8
+
+// Major device classes
+const (
+	MajorMiscellaneous MajorClass = 0x00
+	MajorComputer      MajorClass = 0x01
+	MajorPhone         MajorClass = 0x02
+	MajorNetwork       MajorClass = 0x03
+	MajorAudioVideo    MajorClass = 0x04
+	MajorPeripheral    MajorClass = 0x05
+	MajorImaging       MajorClass = 0x06
+	MajorWearable      MajorClass = 0x07
+	MajorToy           MajorClass = 0x08
+	MajorHealth        MajorClass = 0x09
+	MajorUncategorized MajorClass = 0x1f
+)
+
+var majorClasses = map[MajorClass]string{
+	MajorMiscellaneous: "Miscellaneous",
+	MajorComputer:      "Computer",
+	MajorPhone:         "Phone",
+	MajorNetwork:       "Network Access Point",
+	MajorAudioVideo:    "Audio/Video",
+	MajorPeripheral:    "Peripheral",
+	MajorImaging:       "Imaging",
+	MajorWearable:      "Wearable",
+	MajorToy:           "Toy",
+	MajorHealth:        "Health",
+	MajorUncategorized: "Uncategorized",
+}
+
+func (m MajorClass) String() string {
+	if n, ok := majorClasses[m]; ok {
+		return n
+	}
+	return fmt.Sprintf("Reserved (0x%02x)", uint8(m))
+}
+
+//ServiceClass the major service classes, a bitmask
+type ServiceClass uint16
+
+// Major service classes
+const (
+	ServiceLimitedDiscoverable ServiceClass = 1 << 0
+	ServiceLEAudio             ServiceClass = 1 << 1
+	ServicePositioning         ServiceClass = 1 << 3
+	ServiceNetworking          ServiceClass = 1 << 4
+	ServiceRendering           ServiceClass = 1 << 5
+	ServiceCapturing           ServiceClass = 1 << 6
+	ServiceObjectTransfer      ServiceClass = 1 << 7
+	ServiceAudio               ServiceClass = 1 << 8
+	ServiceTelephony           ServiceClass = 1 << 9
+	ServiceInformation         ServiceClass = 1 << 10
+)
+
+var serviceClasses = []struct {
+	class ServiceClass
+	name  string
+}{
+	{ServiceLimitedDiscoverable, "Limited Discoverable Mode"},
+	{ServiceLEAudio, "LE Audio"},
+	{ServicePositioning, "Positioning"},
+	{ServiceNetworking, "Networking"},
+	{ServiceRendering, "Rendering"},
+	{ServiceCapturing, "Capturing"},
+	{ServiceObjectTransfer, "Object Transfer"},
+	{ServiceAudio, "Audio"},
+	{ServiceTelephony, "Telephony"},
+	{ServiceInformation, "Information"},
+}
+
+//Has return true if all the classes of s are set
+func (c ServiceClass) Has(s ServiceClass) bool {
+	return c&s == s
+}
+
+//Names return the names of the classes set
+func (c ServiceClass) Names() []string {
+	names := []string{}
+	for _, s := range serviceClasses {
+		if c.Has(s.class) {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+func (c ServiceClass) String() string {
+	return strings.Join(c.Names(), ", ")
+}
+
+func className(names []string, i uint8) string {
+	if int(i) < len(names) {
+		return names[i]
+	}
+	return ""
+}
+
+var computerClasses = []string{"Uncategorized", "Desktop Workstation", "Server-class Computer",
+	"Laptop", "Handheld PC/PDA", "Palm-size PC/PDA", "Wearable Computer", "Tablet"}
+
+var phoneClasses = []string{"Uncategorized", "Cellular", "Cordless", "Smartphone",
+	"Wired Modem or Voice Gateway", "Common ISDN Access"}
+
+var networkClasses = []string{"Fully Available", "1% to 17% Utilized", "17% to 33% Utilized",
+	"33% to 50% Utilized", "50% to 67% Utilized", "67% to 83% Utilized", "83% to 99% Utilized",
+	"No Service Available"}
+
+var audioVideoClasses = []string{"Uncategorized", "Wearable Headset", "Hands-free", "",
+	"Microphone", "Loudspeaker", "Headphones", "Portable Audio", "Car Audio", "Set-top Box",
+	"HiFi Audio", "VCR", "Video Camera", "Camcorder", "Video Monitor",
+	"Video Display and Loudspeaker", "Video Conferencing", "", "Gaming/Toy"}
+
+var peripheralInputClasses = []string{"", "Keyboard", "Pointing Device", "Combo Keyboard/Pointing Device"}
+
+var peripheralClasses = []string{"", "Joystick", "Gamepad", "Remote Control", "Sensing Device",
+	"Digitizer Tablet", "Card Reader", "Digital Pen", "Handheld Scanner", "Handheld Gestural Input"}
+
+var imagingClasses = []string{"Display", "Camera", "Scanner", "Printer"}
+
+var wearableClasses = []string{"", "Wristwatch", "Pager", "Jacket", "Helmet", "Glasses", "Pin"}
+
+var toyClasses = []string{"", "Robot", "Vehicle", "Doll/Action Figure", "Controller", "Game"}
+
+var healthClasses = []string{"Undefined", "Blood Pressure Monitor", "Thermometer", "Weighing Scale",
+	"Glucose Meter", "Pulse Oximeter", "Heart/Pulse Rate Monitor", "Health Data Display",
+	"Step Counter", "Body Composition Analyzer", "Peak Flow Monitor", "Medication Monitor",
+	"Knee Prosthesis", "Ankle Prosthesis", "Generic Health Manager", "Personal Mobility Device"}
@@ -0,0 +1,41 @@
+package bluetooth
+
+import "testing"
+
+func TestClass(t *testing.T) {
+
+	c, err := ParseClass([]byte{0x0c, 0x02, 0x5a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Major() != MajorPhone || c.Minor() != 3 || c.MinorName() != "Smartphone" {
+		t.Fatalf("Unexpected class %s", c)
+	}
+	if s := c.String(); s != "Phone (Smartphone) [Networking, Capturing, Object Transfer, Telephony]" {
+		t.Fatalf("Unexpected description %s", s)
+	}
+	if NewClass(MajorPhone, 3, c.Services()) != c {
+		t.Fatalf("Unexpected encoding %06x", uint32(NewClass(MajorPhone, 3, c.Services())))
+	}
+
+	cases := map[Class]string{
+		0x002540: "Peripheral (Keyboard) [Limited Discoverable Mode]",
+		0x000580: "Peripheral (Pointing Device)",
+		0x240404: "Audio/Video (Wearable Headset) [Rendering, Audio]",
+		0x000680: "Imaging (Printer)",
+		0x000660: "Imaging (Camera, Scanner)",
+		0x000300: "Network Access Point (Fully Available)",
+		0x000900: "Health (Undefined)",
+		0x001f00: "Uncategorized",
+		0x001500: "Reserved (0x15)",
+	}
+	for class, expected := range cases {
+		if class.String() != expected {
+			t.Errorf("%06x: expected %s, got %s", uint32(class), expected, class)
+		}
+	}
+
+	if _, err := ParseClass([]byte{0x0c, 0x02}); err == nil {
+		t.Fatal("Expected an invalid length")
+	}
+}
@@ -0,0 +1,181 @@
+//Command beacon-scan scan passively for the iBeacon and Eddystone beacons and
+// report the sightings, with a smoothed RSSI, to one or more outputs
+//
+//	beacon-scan [-adapter 0] [-user] [-type ibeacon,eddystone-uid] [-interval 1s]
+//	            [-output text,json,http,mqtt] [output flags]
+//
+// The scan uses a raw HCI socket and requires CAP_NET_RAW and CAP_NET_ADMIN.
+// With -user the adapter is taken over, bluetoothd must not be using it. The
+// mqtt output is built with -tags mqtt
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/beacon"
+	"github.com/muka/go-bluetooth/linux"
+	"github.com/muka/go-bluetooth/linux/hci"
+)
+
+//Sighting a beacon received, as reported to the outputs
+type Sighting struct {
+	Time    time.Time `json:"time"`
+	Address string    `json:"address"`
+	RSSI    int8      `json:"rssi"`
+	// Smoothed the moving average of the RSSI, see beacon.Smoother
+	Smoothed float64 `json:"rssi_smoothed"`
+	// Distance the estimate in meters, -1 if unknown
+	Distance float64 `json:"distance"`
+	*beacon.Beacon
+}
+
+// key identify the beacon of a sighting, the telemetry frames by their
+// sender
+func (s *Sighting) key() string {
+	if s.ID != "" {
+		return string(s.Type) + ":" + s.ID
+	}
+	return string(s.Type) + ":" + s.Address
+}
+
+func main() {
+
+	adapter := flag.Int("adapter", 0, "the adapter, eg. 0 for hci0")
+	user := flag.Bool("user", false, "take over the adapter with a user channel socket")
+	types := flag.String("type", "", "report the beacons of the types only, eg. ibeacon,eddystone-uid")
+	interval := flag.Duration("interval", time.Second, "report each beacon at most once per interval, 0 reports every frame")
+	alpha := flag.Float64("alpha", beacon.DefaultSmoothing, "the weight of a new RSSI in the moving average")
+	maxAge := flag.Duration("max-age", time.Minute, "restart the average of a beacon not seen for longer")
+	outputList := flag.String("output", "text", "the outputs, comma separated: "+strings.Join(outputNames(), ", "))
+	debug := flag.Bool("debug", false, "log the HCI errors")
+	for _, o := range outputs {
+		if o.flags != nil {
+			o.flags(flag.CommandLine)
+		}
+	}
+	flag.Parse()
+
+	if *debug {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	list, err := openOutputs(*outputList)
+	if err != nil {
+		fail(err)
+	}
+	defer closeOutputs(list)
+
+	filter := map[beacon.Type]bool{}
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter[beacon.Type(t)] = true
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	var socket *hci.Socket
+	if *user {
+		if err = linux.Down(*adapter); err != nil {
+			fail(err)
+		}
+		socket, err = hci.OpenUser(*adapter)
+	} else {
+		socket, err = hci.OpenRaw(*adapter)
+	}
+	if err != nil {
+		fail(err)
+	}
+	defer socket.Close()
+
+	reports, err := socket.Scan(ctx, hci.ScanOptions{})
+	if err != nil {
+		fail(err)
+	}
+
+	smoother := beacon.NewSmoother(*alpha, *maxAge)
+	reported := map[string]time.Time{}
+	// the averages expire with max-age, the rate limiting with them
+	expireInterval := *maxAge
+	if expireInterval == 0 {
+		expireInterval = time.Minute
+	}
+	expire := time.NewTicker(expireInterval)
+	defer expire.Stop()
+
+	for {
+		var r hci.AdvertisingReport
+		var ok bool
+		select {
+		case r, ok = <-reports:
+			if !ok {
+				return
+			}
+		case <-expire.C:
+			for _, key := range smoother.Expire() {
+				delete(reported, key)
+			}
+			continue
+		}
+
+		s, err := sighting(r)
+		if err != nil {
+			log.Debugf("%s: %s", r.Address, err.Error())
+			continue
+		}
+		if s == nil || (len(filter) > 0 && !filter[s.Type]) {
+			continue
+		}
+
+		key := s.key()
+		s.Smoothed = smoother.Add(key, s.RSSI)
+		s.Distance = beacon.Distance(s.Smoothed, s.MeasuredPower)
+		if last, ok := reported[key]; ok && s.Time.Sub(last) < *interval {
+			continue
+		}
+		reported[key] = s.Time
+
+		for _, o := range list {
+			if err := o.Write(s); err != nil {
+				log.Warnf("%s", err.Error())
+			}
+		}
+	}
+}
+
+// sighting decode the beacon of a report, nil if it has none
+func sighting(r hci.AdvertisingReport) (*Sighting, error) {
+	record, err := r.ScanRecord()
+	if err != nil {
+		return nil, err
+	}
+	b, err := beacon.Parse(record)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return &Sighting{
+		Time:     time.Now(),
+		Address:  r.Address,
+		RSSI:     r.RSSI,
+		Distance: -1,
+		Beacon:   b,
+	}, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "beacon-scan:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//Output receive the sightings, an output must not block the scan for long
+type Output interface {
+	Write(s *Sighting) error
+	Close() error
+}
+
+// plugin an output, registered by its file with registerOutput
+type plugin struct {
+	// flags register the flags of the output, optional
+	flags func(fs *flag.FlagSet)
+	open  func() (Output, error)
+}
+
+var outputs = map[string]plugin{}
+
+func registerOutput(name string, flags func(fs *flag.FlagSet), open func() (Output, error)) {
+	outputs[name] = plugin{flags: flags, open: open}
+}
+
+func outputNames() []string {
+	names := []string{}
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func openOutputs(list string) ([]Output, error) {
+	opened := []Output{}
+	for _, name := range strings.Split(list, ",") {
+		p, ok := outputs[strings.TrimSpace(name)]
+		if !ok {
+			closeOutputs(opened)
+			return nil, fmt.Errorf("Unknown output %s, available: %s", name, strings.Join(outputNames(), ", "))
+		}
+		o, err := p.open()
+		if err != nil {
+			closeOutputs(opened)
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		opened = append(opened, o)
+	}
+	return opened, nil
+}
+
+func closeOutputs(list []Output) {
+	for _, o := range list {
+		o.Close()
+	}
+}
+
+func init() {
+	registerOutput("text", nil, func() (Output, error) { return &textOutput{}, nil })
+	registerOutput("json", nil, func() (Output, error) {
+		return &jsonOutput{encoder: json.NewEncoder(os.Stdout)}, nil
+	})
+}
+
+// textOutput print a line per sighting
+type textOutput struct{}
+
+func (o *textOutput) Write(s *Sighting) error {
+	line := fmt.Sprintf("%s %s %4ddBm (%5.1f)", s.Time.Format("15:04:05.000"), s.Address, s.RSSI, s.Smoothed)
+	if s.Distance >= 0 {
+		line += fmt.Sprintf(" %5.1fm", s.Distance)
+	}
+	line += " " + string(s.Type)
+	if s.ID != "" {
+		line += " " + s.ID
+	}
+	if t := s.Telemetry; t != nil {
+		line += fmt.Sprintf(" battery %dmV temperature %.1fC uptime %ds", t.Battery, t.Temperature, t.Uptime/10)
+	}
+	_, err := fmt.Println(line)
+	return err
+}
+
+func (o *textOutput) Close() error {
+	return nil
+}
+
+// jsonOutput print the sightings as JSON lines
+type jsonOutput struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+func (o *jsonOutput) Write(s *Sighting) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.encoder.Encode(s)
+}
+
+func (o *jsonOutput) Close() error {
+	return nil
+}
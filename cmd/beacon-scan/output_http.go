@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var httpConfig struct {
+	url     string
+	batch   time.Duration
+	timeout time.Duration
+}
+
+func init() {
+	registerOutput("http", func(fs *flag.FlagSet) {
+		fs.StringVar(&httpConfig.url, "http-url", "", "http: the URL the sightings are posted to, as a JSON array")
+		fs.DurationVar(&httpConfig.batch, "http-batch", 5*time.Second, "http: post the sightings collected every interval")
+		fs.DurationVar(&httpConfig.timeout, "http-timeout", 10*time.Second, "http: the timeout of a post")
+	}, openHTTP)
+}
+
+// httpOutput post the sightings in batches, a batch failing to post is
+// dropped
+type httpOutput struct {
+	client *http.Client
+	url    string
+
+	mutex   sync.Mutex
+	pending []*Sighting
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func openHTTP() (Output, error) {
+	if httpConfig.url == "" {
+		return nil, errors.New("-http-url is required")
+	}
+	if httpConfig.batch <= 0 {
+		return nil, errors.New("-http-batch must be positive")
+	}
+	o := &httpOutput{
+		client:  &http.Client{Timeout: httpConfig.timeout},
+		url:     httpConfig.url,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go o.run(httpConfig.batch)
+	return o, nil
+}
+
+func (o *httpOutput) Write(s *Sighting) error {
+	o.mutex.Lock()
+	o.pending = append(o.pending, s)
+	o.mutex.Unlock()
+	return nil
+}
+
+func (o *httpOutput) run(interval time.Duration) {
+	defer close(o.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-o.done:
+			o.flush()
+			return
+		}
+		if err := o.flush(); err != nil {
+			log.Warnf("http: %s", err.Error())
+		}
+	}
+}
+
+func (o *httpOutput) flush() error {
+
+	o.mutex.Lock()
+	batch := o.pending
+	o.pending = nil
+	o.mutex.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	res, err := o.client.Post(o.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("%s replied %s, %d sightings dropped", o.url, res.Status, len(batch))
+	}
+	return nil
+}
+
+// Close post the pending sightings
+func (o *httpOutput) Close() error {
+	close(o.done)
+	<-o.stopped
+	return nil
+}
@@ -0,0 +1,88 @@
+//go:build mqtt
+// +build mqtt
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+var mqttConfig struct {
+	broker   string
+	topic    string
+	clientID string
+	username string
+	password string
+	qos      int
+}
+
+func init() {
+	registerOutput("mqtt", func(fs *flag.FlagSet) {
+		hostname, _ := os.Hostname()
+		fs.StringVar(&mqttConfig.broker, "mqtt-broker", "tcp://localhost:1883", "mqtt: the broker")
+		fs.StringVar(&mqttConfig.topic, "mqtt-topic", "beacons/{type}/{id}",
+			"mqtt: the topic of the sightings, {type}, {id} and {address} are replaced")
+		fs.StringVar(&mqttConfig.clientID, "mqtt-client-id", "beacon-scan-"+hostname, "mqtt: the client identifier")
+		fs.StringVar(&mqttConfig.username, "mqtt-username", "", "mqtt: the user name")
+		fs.StringVar(&mqttConfig.password, "mqtt-password", "", "mqtt: the password")
+		fs.IntVar(&mqttConfig.qos, "mqtt-qos", 0, "mqtt: the QoS of the messages")
+	}, openMQTT)
+}
+
+// mqttOutput publish each sighting as JSON
+type mqttOutput struct {
+	client mqtt.Client
+}
+
+func openMQTT() (Output, error) {
+	if mqttConfig.qos < 0 || mqttConfig.qos > 2 {
+		return nil, fmt.Errorf("Invalid QoS %d", mqttConfig.qos)
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(mqttConfig.broker).
+		SetClientID(mqttConfig.clientID).
+		SetUsername(mqttConfig.username).
+		SetPassword(mqttConfig.password).
+		SetAutoReconnect(true)
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(10 * time.Second) {
+		return nil, fmt.Errorf("Timeout connecting to %s", mqttConfig.broker)
+	}
+	if err := token.Error(); err != nil {
+		return nil, err
+	}
+	return &mqttOutput{client: client}, nil
+}
+
+func (o *mqttOutput) topic(s *Sighting) string {
+	id := s.ID
+	if id == "" {
+		id = s.Address
+	}
+	return strings.NewReplacer("{type}", string(s.Type), "{id}", id, "{address}", s.Address).
+		Replace(mqttConfig.topic)
+}
+
+func (o *mqttOutput) Write(s *Sighting) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	// not waiting for the token, the client queues the messages while
+	// reconnecting
+	o.client.Publish(o.topic(s), byte(mqttConfig.qos), false, b)
+	return nil
+}
+
+func (o *mqttOutput) Close() error {
+	o.client.Disconnect(250)
+	return nil
+}
@@ -0,0 +1,45 @@
+//go:build grpc
+// +build grpc
+
+package main
+
+import (
+	"flag"
+	"net"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/remote"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func init() {
+	addr := flag.String("grpc", "127.0.0.1:8081", "the address of the gRPC server, empty to disable it")
+	servers = append(servers, func(c *remote.Controller) error {
+		if *addr == "" {
+			return nil
+		}
+		if err := checkAddr(*addr); err != nil {
+			return err
+		}
+		var options []grpc.ServerOption
+		if *token != "" {
+			options = append(options, remote.GRPCToken(*token)...)
+		}
+		if *tlsCert != "" {
+			creds, err := credentials.NewServerTLSFromFile(*tlsCert, *tlsKey)
+			if err != nil {
+				return err
+			}
+			options = append(options, grpc.Creds(creds))
+		}
+		l, err := net.Listen("tcp", *addr)
+		if err != nil {
+			return err
+		}
+		s := grpc.NewServer(options...)
+		remote.RegisterGRPC(s, c)
+		log.Infof("gRPC server listening on %s", *addr)
+		return s.Serve(l)
+	})
+}
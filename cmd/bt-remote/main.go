@@ -0,0 +1,112 @@
+//Command bt-remote serve the Bluetooth stack of a gateway to the remote
+// clients, over HTTP and, built with -tags grpc, over gRPC, see the remote
+// package
+//
+//	bt-remote [-adapter hci0] [-http 127.0.0.1:8080] [-grpc 127.0.0.1:8081]
+//		[-token secret] [-tls-cert cert.pem -tls-key key.pem]
+//
+// The servers listen on the loopback interface by default. Any other address
+// requires a token, checked on every request, or TLS, or both
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/remote"
+)
+
+// servers the listeners enabled by the build, started with the controller,
+// they return nil when disabled by their flags
+var servers []func(c *remote.Controller) error
+
+var (
+	token   = flag.String("token", os.Getenv("BT_REMOTE_TOKEN"), "the bearer token required from the clients, defaults to $BT_REMOTE_TOKEN")
+	tlsCert = flag.String("tls-cert", "", "the certificate of the servers, enabling TLS")
+	tlsKey  = flag.String("tls-key", "", "the private key of the certificate")
+)
+
+func main() {
+
+	adapterID := flag.String("adapter", "hci0", "the adapter")
+	httpAddr := flag.String("http", "127.0.0.1:8080", "the address of the HTTP server, empty to disable it")
+	debug := flag.Bool("debug", false, "log the bluez calls")
+	flag.Parse()
+
+	if *debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Error("-tls-cert and -tls-key go together")
+		return
+	}
+
+	c := remote.NewController(*adapterID)
+	defer c.Close()
+
+	errs := make(chan error, len(servers)+1)
+	if *httpAddr != "" {
+		go func() {
+			errs <- serveHTTP(*httpAddr, c)
+		}()
+	}
+	for _, serve := range servers {
+		go func(serve func(c *remote.Controller) error) {
+			errs <- serve(c)
+		}(serve)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	for {
+		select {
+		case err := <-errs:
+			if err != nil {
+				log.Error(err)
+				return
+			}
+		case <-interrupt:
+			return
+		}
+	}
+}
+
+// serveHTTP run the HTTP server, with the token and TLS if set
+func serveHTTP(addr string, c *remote.Controller) error {
+	if err := checkAddr(addr); err != nil {
+		return err
+	}
+	var h http.Handler = remote.NewHandler(c)
+	if *token != "" {
+		h = remote.RequireToken(*token, h)
+	}
+	log.Infof("HTTP server listening on %s", addr)
+	if *tlsCert != "" {
+		return http.ListenAndServeTLS(addr, *tlsCert, *tlsKey, h)
+	}
+	return http.ListenAndServe(addr, h)
+}
+
+// checkAddr refuse an address reachable from the network without a token or
+// TLS
+func checkAddr(addr string) error {
+	if *token != "" || *tlsCert != "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("Refusing to listen on %s without -token or -tls-cert", addr)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/api"
+)
+
+const resolveTimeout = 20 * time.Second
+
+// connectDevice connect to a device found by a previous scan and wait for
+// its services to be resolved
+func connectDevice(address string) (*api.Device, error) {
+
+	dev, err := api.GetDeviceByAddress(strings.ToUpper(address))
+	if err != nil {
+		return nil, err
+	}
+	if dev == nil {
+		return nil, fmt.Errorf("Device %s not found, run a scan first", address)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	if err = dev.ResolveServices(ctx); err != nil {
+		if err == context.DeadlineExceeded {
+			return nil, fmt.Errorf("Timeout resolving the services of %s", address)
+		}
+		return nil, err
+	}
+	return dev, nil
+}
+
+func connect(args []string) error {
+	fs := flag.NewFlagSet("connect", flag.ContinueOnError)
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	dev, err := connectDevice(args[0])
+	if err != nil {
+		return err
+	}
+	props, err := dev.GetProperties()
+	if err != nil {
+		return err
+	}
+	printDevice(props)
+	return nil
+}
+
+func services(args []string) error {
+
+	fs := flag.NewFlagSet("services", flag.ContinueOnError)
+	values := fs.Bool("values", false, "read the values")
+	asJSON := fs.Bool("json", false, "print the JSON description, see api.ImportGATT")
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	dev, err := connectDevice(args[0])
+	if err != nil {
+		return err
+	}
+
+	options := api.ExportGATTOptions{Values: *values}
+	if *asJSON {
+		b, err := dev.ExportGATTJSON(options)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	db, err := dev.ExportGATT(options)
+	if err != nil {
+		return err
+	}
+	for _, s := range db.Services {
+		fmt.Printf("%04x service %s\n", s.Handle, bluetooth.DescribeUUID(s.UUID))
+		for _, c := range s.Characteristics {
+			fmt.Printf("%04x   characteristic %s %s", c.Handle, bluetooth.DescribeUUID(c.UUID), strings.Join(c.Flags, ","))
+			if c.Value != nil {
+				fmt.Printf(" %x", []byte(c.Value))
+			}
+			fmt.Println()
+			for _, d := range c.Descriptors {
+				fmt.Printf("%04x     descriptor %s", d.Handle, bluetooth.DescribeUUID(d.UUID))
+				if d.Value != nil {
+					fmt.Printf(" %x", []byte(d.Value))
+				}
+				fmt.Println()
+			}
+		}
+	}
+	return nil
+}
+
+func read(args []string) error {
+
+	fs := flag.NewFlagSet("read", flag.ContinueOnError)
+	args, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+
+	dev, err := connectDevice(args[0])
+	if err != nil {
+		return err
+	}
+	char, err := dev.GetCharByUUID(args[1])
+	if err != nil {
+		return err
+	}
+	value, err := char.ReadValue(nil)
+	if err != nil {
+		return err
+	}
+	printValue(value)
+	return nil
+}
+
+func write(args []string) error {
+
+	fs := flag.NewFlagSet("write", flag.ContinueOnError)
+	text := fs.Bool("text", false, "write the value as text")
+	command := fs.Bool("command", false, "write without response")
+	args, err := parseArgs(fs, args, 3)
+	if err != nil {
+		return err
+	}
+
+	value := []byte(args[2])
+	if !*text {
+		value, err = hex.DecodeString(strings.TrimPrefix(args[2], "0x"))
+		if err != nil {
+			return fmt.Errorf("Invalid value %s: %s", args[2], err)
+		}
+	}
+
+	dev, err := connectDevice(args[0])
+	if err != nil {
+		return err
+	}
+	char, err := dev.GetCharByUUID(args[1])
+	if err != nil {
+		return err
+	}
+
+	options := map[string]dbus.Variant{}
+	if *command {
+		options["type"] = dbus.MakeVariant("command")
+	}
+	return char.WriteValue(value, options)
+}
+
+func subscribe(args []string) error {
+
+	fs := flag.NewFlagSet("subscribe", flag.ContinueOnError)
+	args, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+
+	dev, err := connectDevice(args[0])
+	if err != nil {
+		return err
+	}
+	char, err := dev.GetCharByUUID(args[1])
+	if err != nil {
+		return err
+	}
+	values, stop, err := char.Notifications()
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	for {
+		select {
+		case value, ok := <-values:
+			if !ok {
+				return nil
+			}
+			fmt.Print(time.Now().Format("15:04:05.000 "))
+			printValue(value)
+		case <-interrupt:
+			return nil
+		}
+	}
+}
+
+// printValue print a value in hex, followed by the text if printable
+func printValue(value []byte) {
+	line := hex.EncodeToString(value)
+	printable := len(value) > 0
+	for _, b := range value {
+		if b < 0x20 || b > 0x7e {
+			printable = false
+			break
+		}
+	}
+	if printable {
+		line += fmt.Sprintf(" %q", value)
+	}
+	fmt.Println(line)
+}
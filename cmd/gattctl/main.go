@@ -0,0 +1,94 @@
+//Command gattctl a GATT client and server for the command line, built on the
+// api and service packages
+//
+//	gattctl scan [-name substring] [-service uuid] [-rssi dBm] [-timeout 10s]
+//	gattctl connect <address>
+//	gattctl services [-values] [-json] <address>
+//	gattctl read <address> <characteristic uuid>
+//	gattctl write [-text] [-command] <address> <characteristic uuid> <hex value|text>
+//	gattctl subscribe <address> <characteristic uuid>
+//	gattctl serve <application.yaml>
+//	gattctl simulate [-script script.json] [-name name] [-advertise=false] <export.json>
+//
+// see battery.yaml for the description of an application served, simulate
+// serves the JSON printed by services -json -values, see the simulator
+// package for the scripts
+//
+// the flags -adapter and -debug come before the command
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/api"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"scan":      {"[-name substring] [-service uuid] [-rssi dBm] [-timeout 10s]", scan},
+	"connect":   {"<address>", connect},
+	"services":  {"[-values] [-json] <address>", services},
+	"read":      {"<address> <characteristic uuid>", read},
+	"write":     {"[-text] [-command] <address> <characteristic uuid> <hex value|text>", write},
+	"subscribe": {"<address> <characteristic uuid>", subscribe},
+	"serve":     {"<application.yaml>", serve},
+	"simulate":  {"[-script script.json] [-name name] [-advertise=false] <export.json>", simulate},
+}
+
+var adapterID string
+
+func main() {
+
+	flag.StringVar(&adapterID, "adapter", "hci0", "the adapter")
+	debug := flag.Bool("debug", false, "log the bluez calls")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *debug {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gattctl: unknown command %s\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	err := cmd.run(flag.Args()[1:])
+	api.Exit()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gattctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gattctl [-adapter hci0] [-debug] <command> [arguments]")
+	for _, name := range []string{"scan", "connect", "services", "read", "write", "subscribe", "serve", "simulate"} {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", name, commands[name].usage)
+	}
+}
+
+// parseArgs parse the flags of a command and check the count of the
+// remaining arguments
+func parseArgs(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != n {
+		return nil, fmt.Errorf("%s: expected %d arguments, got %d", fs.Name(), n, fs.NArg())
+	}
+	return fs.Args(), nil
+}
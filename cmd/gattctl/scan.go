@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/emitter"
+)
+
+// scanFilter the filters of the scan command, applied on the properties of
+// the devices found
+type scanFilter struct {
+	name    string
+	service string
+	rssi    int
+}
+
+func (f *scanFilter) match(props *profile.Device1Properties) bool {
+	if f.name != "" && !strings.Contains(strings.ToLower(props.Name), strings.ToLower(f.name)) {
+		return false
+	}
+	if f.rssi != 0 && (props.RSSI == 0 || int(props.RSSI) < f.rssi) {
+		return false
+	}
+	if f.service != "" {
+		for _, uuid := range props.UUIDs {
+			if bluetooth.EqualUUID(uuid, f.service) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func scan(args []string) error {
+
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	filter := &scanFilter{}
+	fs.StringVar(&filter.name, "name", "", "show the devices with a name containing the substring")
+	fs.StringVar(&filter.service, "service", "", "show the devices advertising the service UUID")
+	fs.IntVar(&filter.rssi, "rssi", 0, "show the devices with a RSSI above the value, eg. -70")
+	timeout := fs.Duration("timeout", 10*time.Second, "the duration of the scan")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	var mutex sync.Mutex
+	seen := map[string]bool{}
+	show := func(dev *api.Device) {
+		props, err := dev.GetProperties()
+		if err != nil || !filter.match(props) {
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if seen[props.Address] {
+			return
+		}
+		seen[props.Address] = true
+		printDevice(props)
+	}
+
+	devices, err := api.GetDevices()
+	if err != nil {
+		return err
+	}
+	for i := range devices {
+		show(&devices[i])
+	}
+
+	cb := emitter.NewCallback(func(ev emitter.Event) {
+		discovery := ev.GetData().(api.DiscoveredDeviceEvent)
+		if discovery.Status == api.DeviceAdded {
+			show(discovery.Device)
+		}
+	})
+	if err = api.On("discovery", cb); err != nil {
+		return err
+	}
+	defer api.Off("discovery", cb)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err = api.StartDiscoveryContext(ctx, adapterID); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func printDevice(props *profile.Device1Properties) {
+	line := fmt.Sprintf("%s %4ddBm %q", props.Address, props.RSSI, props.Name)
+	if len(props.UUIDs) > 0 {
+		names := make([]string, len(props.UUIDs))
+		for i, uuid := range props.UUIDs {
+			names[i] = bluetooth.DescribeUUID(uuid)
+		}
+		line += " [" + strings.Join(names, ", ") + "]"
+	}
+	fmt.Println(line)
+}
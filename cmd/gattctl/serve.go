@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// appConfig the YAML description of an application, see battery.yaml
+type appConfig struct {
+	Name      string          `yaml:"name"`
+	Advertise bool            `yaml:"advertise"`
+	Services  []serviceConfig `yaml:"services"`
+}
+
+type serviceConfig struct {
+	UUID            string       `yaml:"uuid"`
+	Secondary       bool         `yaml:"secondary"`
+	Advertise       bool         `yaml:"advertise"`
+	Characteristics []charConfig `yaml:"characteristics"`
+}
+
+type charConfig struct {
+	UUID        string       `yaml:"uuid"`
+	Flags       []string     `yaml:"flags"`
+	Value       string       `yaml:"value"`
+	Text        string       `yaml:"text"`
+	Descriptors []descConfig `yaml:"descriptors"`
+}
+
+type descConfig struct {
+	UUID  string   `yaml:"uuid"`
+	Flags []string `yaml:"flags"`
+	Value string   `yaml:"value"`
+	Text  string   `yaml:"text"`
+}
+
+// configValue return the hex value or the text
+func configValue(value, text string) ([]byte, error) {
+	if text != "" {
+		return []byte(text), nil
+	}
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid value %s: %s", value, err)
+	}
+	return b, nil
+}
+
+// loadAppConfig read an application file, see parseAppConfig
+func loadAppConfig(file string) (*appConfig, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseAppConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	return cfg, nil
+}
+
+// parseAppConfig decode the YAML description of an application, checking the
+// UUIDs and the values before anything is exported
+func parseAppConfig(b []byte) (*appConfig, error) {
+	cfg := new(appConfig)
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Services) == 0 {
+		return nil, errors.New("no services")
+	}
+	for _, s := range cfg.Services {
+		if _, err := bluetooth.ParseUUID(s.UUID); err != nil {
+			return nil, err
+		}
+		for _, c := range s.Characteristics {
+			if _, err := bluetooth.ParseUUID(c.UUID); err != nil {
+				return nil, err
+			}
+			if _, err := configValue(c.Value, c.Text); err != nil {
+				return nil, fmt.Errorf("%s: %s", c.UUID, err)
+			}
+			for _, d := range c.Descriptors {
+				if _, err := bluetooth.ParseUUID(d.UUID); err != nil {
+					return nil, err
+				}
+				if _, err := configValue(d.Value, d.Text); err != nil {
+					return nil, fmt.Errorf("%s: %s", d.UUID, err)
+				}
+			}
+		}
+	}
+	return cfg, nil
+}
+
+func serve(args []string) error {
+
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAppConfig(args[0])
+	if err != nil {
+		return err
+	}
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		ObjectName: "org.bluez",
+		ObjectPath: "/org/bluez/gattctl",
+		LocalName:  cfg.Name,
+	})
+	if err != nil {
+		return err
+	}
+	if err = app.Run(); err != nil {
+		return err
+	}
+
+	for _, s := range cfg.Services {
+		if err = addConfigService(app, s, cfg.Advertise); err != nil {
+			return err
+		}
+	}
+
+	if err = app.Register(adapterID); err != nil {
+		return err
+	}
+	defer app.Unregister()
+
+	if cfg.Advertise {
+		if err = app.StartAdvertising(adapterID); err != nil {
+			return err
+		}
+		defer app.StopAdvertising()
+	}
+
+	fmt.Printf("Serving %s on %s, press Ctrl+C to stop\n", args[0], adapterID)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+	return nil
+}
+
+func addConfigService(app *service.Application, s serviceConfig, advertise bool) error {
+
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: !s.Secondary,
+		UUID:    bluetooth.CanonicalUUID(s.UUID),
+	}, advertise && s.Advertise)
+	if err != nil {
+		return err
+	}
+	if err = app.AddService(srv); err != nil {
+		return err
+	}
+
+	for _, c := range s.Characteristics {
+		value, err := configValue(c.Value, c.Text)
+		if err != nil {
+			return err
+		}
+		char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+			UUID:  bluetooth.CanonicalUUID(c.UUID),
+			Flags: c.Flags,
+			Value: value,
+		})
+		if err != nil {
+			return err
+		}
+		uuid := bluetooth.DescribeUUID(c.UUID)
+		char.SetWriteFunc(func(char *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+			fmt.Printf("write %s ", uuid)
+			printValue(value)
+			char.UpdateValue(value)
+			return nil
+		})
+		if err = srv.AddCharacteristic(char); err != nil {
+			return err
+		}
+
+		for _, d := range c.Descriptors {
+			value, err := configValue(d.Value, d.Text)
+			if err != nil {
+				return err
+			}
+			desc, err := char.CreateDescriptor(&profile.GattDescriptor1Properties{
+				UUID:  bluetooth.CanonicalUUID(d.UUID),
+				Flags: d.Flags,
+				Value: value,
+			})
+			if err != nil {
+				return err
+			}
+			if err = char.AddDescriptor(desc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
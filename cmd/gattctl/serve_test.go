@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLoadAppConfig(t *testing.T) {
+
+	cfg, err := loadAppConfig("battery.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "gattctl" || !cfg.Advertise || len(cfg.Services) != 2 {
+		t.Fatalf("Unexpected application: %+v", cfg)
+	}
+
+	battery := cfg.Services[0]
+	if battery.UUID != "180f" || !battery.Advertise || len(battery.Characteristics) != 1 {
+		t.Fatalf("Unexpected battery service: %+v", battery)
+	}
+	level := battery.Characteristics[0]
+	value, err := configValue(level.Value, level.Text)
+	if err != nil || !bytes.Equal(value, []byte{0x64}) {
+		t.Fatalf("Unexpected level value: %x %v", value, err)
+	}
+	if len(level.Flags) != 2 || len(level.Descriptors) != 1 || level.Descriptors[0].Text != "Battery Level" {
+		t.Fatalf("Unexpected level characteristic: %+v", level)
+	}
+
+	text := cfg.Services[1].Characteristics[0]
+	value, err = configValue(text.Value, text.Text)
+	if err != nil || string(value) != "hello" {
+		t.Fatalf("Unexpected text value: %q %v", value, err)
+	}
+}
+
+func TestParseAppConfigErrors(t *testing.T) {
+
+	tests := []struct {
+		name, yaml, err string
+	}{
+		{"empty", "name: x\n", "no services"},
+		{"unknown field", "name: x\ncolor: red\nservices: [{uuid: 180f}]\n", "color"},
+		{"service uuid", "services: [{uuid: nope}]\n", "nope"},
+		{"char uuid", "services: [{uuid: 180f, characteristics: [{uuid: 2a1}]}]\n", "2a1"},
+		{"char value", "services: [{uuid: 180f, characteristics: [{uuid: 2a19, value: zz}]}]\n", "Invalid value zz"},
+		{"desc uuid", "services: [{uuid: 180f, characteristics: [{uuid: 2a19, descriptors: [{uuid: x}]}]}]\n", "x"},
+		{"desc value", "services: [{uuid: 180f, characteristics: [{uuid: 2a19, descriptors: [{uuid: 2901, value: 1}]}]}]\n", "Invalid value 1"},
+		{"syntax", "services: [\n", ""},
+	}
+	for _, test := range tests {
+		_, err := parseAppConfig([]byte(test.yaml))
+		if err == nil {
+			t.Errorf("%s: expected an error", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected an error about %q, got %s", test.name, test.err, err)
+		}
+	}
+}
+
+func TestLoadAppConfigMissing(t *testing.T) {
+	if _, err := loadAppConfig("missing.yaml"); err == nil {
+		t.Fatal("Expected an error")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/simulator"
+)
+
+func simulate(args []string) error {
+
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	scriptFile := fs.String("script", "", "play the values of the JSON script")
+	name := fs.String("name", "", "the local name, defaults to the name of the device exported")
+	advertise := fs.Bool("advertise", true, "advertise the primary services")
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	db, err := simulator.Load(args[0])
+	if err != nil {
+		return err
+	}
+	config := &simulator.Config{
+		AdapterID: adapterID,
+		Name:      *name,
+		Advertise: *advertise,
+		OnWrite: func(serviceUUID, charUUID string, value []byte) {
+			fmt.Printf("write %s ", bluetooth.DescribeUUID(charUUID))
+			printValue(value)
+		},
+	}
+	if *scriptFile != "" {
+		if config.Script, err = simulator.LoadScript(*scriptFile); err != nil {
+			return err
+		}
+	}
+
+	sim, err := simulator.New(db, config)
+	if err != nil {
+		return err
+	}
+	defer sim.Close()
+	if err = sim.Start(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Simulating %s on %s, press Ctrl+C to stop\n", args[0], adapterID)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+	return nil
+}
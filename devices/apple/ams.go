@@ -0,0 +1,258 @@
+package apple
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth/api"
+)
+
+// AMS UUIDs
+const (
+	AMSUUID             = "89D3502B-0F36-433A-8EF4-C502AD55F8DC"
+	RemoteCommandUUID   = "9B3C81D8-57B1-4A8A-B8DF-0E56F7CA51C2"
+	EntityUpdateUUID    = "2F7CABCE-808D-411F-9A0C-BB92BA96C102"
+	EntityAttributeUUID = "C6B2F38C-23AB-46D8-A6AB-A3A870BBD5D7"
+)
+
+// Remote commands
+const (
+	RemotePlay               uint8 = 0
+	RemotePause              uint8 = 1
+	RemoteTogglePlayPause    uint8 = 2
+	RemoteNextTrack          uint8 = 3
+	RemotePreviousTrack      uint8 = 4
+	RemoteVolumeUp           uint8 = 5
+	RemoteVolumeDown         uint8 = 6
+	RemoteAdvanceRepeatMode  uint8 = 7
+	RemoteAdvanceShuffleMode uint8 = 8
+	RemoteSkipForward        uint8 = 9
+	RemoteSkipBackward       uint8 = 10
+	RemoteLikeTrack          uint8 = 11
+	RemoteDislikeTrack       uint8 = 12
+	RemoteBookmarkTrack      uint8 = 13
+)
+
+// Entities
+const (
+	EntityPlayer uint8 = 0
+	EntityQueue  uint8 = 1
+	EntityTrack  uint8 = 2
+)
+
+// Attributes of the entities
+const (
+	PlayerName         uint8 = 0
+	PlayerPlaybackInfo uint8 = 1
+	PlayerVolume       uint8 = 2
+
+	QueueIndex       uint8 = 0
+	QueueCount       uint8 = 1
+	QueueShuffleMode uint8 = 2
+	QueueRepeatMode  uint8 = 3
+
+	TrackArtist   uint8 = 0
+	TrackAlbum    uint8 = 1
+	TrackTitle    uint8 = 2
+	TrackDuration uint8 = 3
+)
+
+// Playback states
+const (
+	PlaybackPaused         uint8 = 0
+	PlaybackPlaying        uint8 = 1
+	PlaybackRewinding      uint8 = 2
+	PlaybackFastForwarding uint8 = 3
+)
+
+//ErrRemoteCommandNotSupported the media player does not support the command
+// in its current state
+var ErrRemoteCommandNotSupported = errors.New("Remote command not supported")
+
+//EntityUpdate the new value of an attribute of an entity, a truncated value
+// is read in full with AMS.Attribute
+type EntityUpdate struct {
+	Entity    uint8
+	Attribute uint8
+	Truncated bool
+	Value     string
+}
+
+//ParseEntityUpdate decode a notification of the Entity Update
+func ParseEntityUpdate(b []byte) (EntityUpdate, error) {
+	if len(b) < 3 {
+		return EntityUpdate{}, errors.New("Invalid entity update")
+	}
+	return EntityUpdate{
+		Entity:    b[0],
+		Attribute: b[1],
+		Truncated: b[2]&0x01 != 0,
+		Value:     string(b[3:]),
+	}, nil
+}
+
+//PlaybackInfo the value of PlayerPlaybackInfo
+type PlaybackInfo struct {
+	State uint8
+	// Rate eg. 1.0 while playing
+	Rate    float64
+	Elapsed time.Duration
+}
+
+//ParsePlaybackInfo decode the PlaybackState,PlaybackRate,ElapsedTime value
+// of PlayerPlaybackInfo
+func ParsePlaybackInfo(value string) (PlaybackInfo, error) {
+	p := PlaybackInfo{}
+	fields := strings.Split(value, ",")
+	if len(fields) != 3 {
+		return p, errors.New("Invalid playback info " + value)
+	}
+	state, err := strconv.ParseUint(fields[0], 10, 8)
+	if err != nil {
+		return p, err
+	}
+	p.State = uint8(state)
+	if p.Rate, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return p, err
+	}
+	elapsed, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return p, err
+	}
+	p.Elapsed = time.Duration(elapsed * float64(time.Second))
+	return p, nil
+}
+
+//AMS a client of the Apple Media Service of a connected iOS device
+type AMS struct {
+	remote    characteristic
+	update    characteristic
+	attribute characteristic
+	updates   chan EntityUpdate
+	stops     []func()
+
+	mutex     sync.Mutex
+	supported map[uint8]bool
+	// attribute serialize the reads of the Entity Attribute
+	attributeMutex sync.Mutex
+}
+
+//NewAMS subscribe to the Remote Command and Entity Update of the iOS device,
+// the updates of the attributes subscribed to with Subscribe are reported on
+// Updates until Close
+func NewAMS(dev *api.Device) (*AMS, error) {
+	list, err := chars(dev, RemoteCommandUUID, EntityUpdateUUID, EntityAttributeUUID)
+	if err != nil {
+		return nil, err
+	}
+	return newAMS(list[0], list[1], list[2])
+}
+
+func newAMS(remote, update, attribute characteristic) (*AMS, error) {
+
+	a := &AMS{
+		remote:    remote,
+		update:    update,
+		attribute: attribute,
+		updates:   make(chan EntityUpdate, 16),
+	}
+
+	commands, stopRemote, err := remote.Notifications()
+	if err != nil {
+		return nil, err
+	}
+	updates, stopUpdate, err := update.Notifications()
+	if err != nil {
+		stopRemote()
+		return nil, err
+	}
+	a.stops = []func(){stopRemote, stopUpdate}
+
+	go func() {
+		for b := range commands {
+			supported := make(map[uint8]bool, len(b))
+			for _, c := range b {
+				supported[c] = true
+			}
+			a.mutex.Lock()
+			a.supported = supported
+			a.mutex.Unlock()
+		}
+	}()
+	go func() {
+		defer close(a.updates)
+		for b := range updates {
+			u, err := ParseEntityUpdate(b)
+			if err != nil {
+				continue
+			}
+			a.updates <- u
+		}
+	}()
+
+	return a, nil
+}
+
+//Updates return the entity updates, the channel must be drained to keep
+// receiving them
+func (a *AMS) Updates() <-chan EntityUpdate {
+	return a.updates
+}
+
+//Close stop the notifications
+func (a *AMS) Close() {
+	for _, stop := range a.stops {
+		stop()
+	}
+}
+
+//Subscribe to the updates of attributes of an entity, replacing the previous
+// subscription to the entity. The current values are notified
+func (a *AMS) Subscribe(entity uint8, attributes ...uint8) error {
+	return a.update.WriteValue(append([]byte{entity}, attributes...), nil)
+}
+
+//Supported return the commands supported by the media player, nil until it
+// reported them
+func (a *AMS) Supported() []uint8 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.supported == nil {
+		return nil
+	}
+	list := []uint8{}
+	for c := RemotePlay; c <= RemoteBookmarkTrack; c++ {
+		if a.supported[c] {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+//Command send a remote command, eg. RemoteTogglePlayPause
+func (a *AMS) Command(command uint8) error {
+	a.mutex.Lock()
+	supported := a.supported == nil || a.supported[command]
+	a.mutex.Unlock()
+	if !supported {
+		return ErrRemoteCommandNotSupported
+	}
+	return a.remote.WriteValue([]byte{command}, nil)
+}
+
+//Attribute read the full value of an attribute, eg. of a truncated update
+func (a *AMS) Attribute(entity uint8, attribute uint8) (string, error) {
+	a.attributeMutex.Lock()
+	defer a.attributeMutex.Unlock()
+	if err := a.attribute.WriteValue([]byte{entity, attribute}, nil); err != nil {
+		return "", err
+	}
+	b, err := a.attribute.ReadValue(nil)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,74 @@
+package apple
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestParsePlaybackInfo(t *testing.T) {
+	p, err := ParsePlaybackInfo("1,1.0,12.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.State != PlaybackPlaying || p.Rate != 1 || p.Elapsed != 12500*time.Millisecond {
+		t.Fatalf("Unexpected playback info %+v", p)
+	}
+	if _, err = ParsePlaybackInfo("1,1.0"); err == nil {
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestAMS(t *testing.T) {
+
+	remote, update, attribute := newFakeChar(), newFakeChar(), newFakeChar()
+	a, err := newAMS(remote, update, attribute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	if err = a.Subscribe(EntityTrack, TrackArtist, TrackTitle); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(update.last(), []byte{EntityTrack, TrackArtist, TrackTitle}) {
+		t.Fatalf("Unexpected subscription %x", update.last())
+	}
+
+	update.values <- append([]byte{EntityTrack, TrackTitle, 0x01}, "A very long ti"...)
+	select {
+	case u := <-a.Updates():
+		if u.Entity != EntityTrack || u.Attribute != TrackTitle || !u.Truncated || u.Value != "A very long ti" {
+			t.Fatalf("Unexpected update %+v", u)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an update")
+	}
+
+	attribute.value = []byte("A very long title")
+	title, err := a.Attribute(EntityTrack, TrackTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "A very long title" || !bytes.Equal(attribute.last(), []byte{EntityTrack, TrackTitle}) {
+		t.Fatalf("Unexpected title %s", title)
+	}
+
+	remote.values <- []byte{RemotePlay, RemotePause, RemoteNextTrack}
+	deadline := time.Now().Add(time.Second)
+	for len(a.Supported()) != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Unexpected commands %v", a.Supported())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err = a.Command(RemoteVolumeUp); err != ErrRemoteCommandNotSupported {
+		t.Fatalf("Expected %s, got %v", ErrRemoteCommandNotSupported, err)
+	}
+	if err = a.Command(RemoteNextTrack); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(remote.last(), []byte{RemoteNextTrack}) {
+		t.Fatalf("Unexpected command %x", remote.last())
+	}
+}
@@ -0,0 +1,370 @@
+package apple
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth/api"
+)
+
+// ANCS UUIDs
+const (
+	ANCSUUID               = "7905F431-B5CE-4E99-A40F-4B1E122D00D0"
+	NotificationSourceUUID = "9FBF120D-6301-42D9-8C58-25E699A21DBD"
+	ControlPointUUID       = "69D1D8F3-45E1-49A8-9821-9BBDFDAAD9D9"
+	DataSourceUUID         = "22EAC6E9-24D6-4BB5-BE44-B36ACE7C7BFB"
+)
+
+// Notification events
+const (
+	EventAdded    uint8 = 0
+	EventModified uint8 = 1
+	EventRemoved  uint8 = 2
+)
+
+// Notification event flags
+const (
+	FlagSilent         uint8 = 1 << 0
+	FlagImportant      uint8 = 1 << 1
+	FlagPreExisting    uint8 = 1 << 2
+	FlagPositiveAction uint8 = 1 << 3
+	FlagNegativeAction uint8 = 1 << 4
+)
+
+// Notification categories
+const (
+	CategoryOther              uint8 = 0
+	CategoryIncomingCall       uint8 = 1
+	CategoryMissedCall         uint8 = 2
+	CategoryVoicemail          uint8 = 3
+	CategorySocial             uint8 = 4
+	CategorySchedule           uint8 = 5
+	CategoryEmail              uint8 = 6
+	CategoryNews               uint8 = 7
+	CategoryHealthAndFitness   uint8 = 8
+	CategoryBusinessAndFinance uint8 = 9
+	CategoryLocation           uint8 = 10
+	CategoryEntertainment      uint8 = 11
+)
+
+// Control point commands
+const (
+	CommandGetNotificationAttributes uint8 = 0
+	CommandGetAppAttributes          uint8 = 1
+	CommandPerformNotificationAction uint8 = 2
+)
+
+// Notification attributes
+const (
+	AttributeAppIdentifier       uint8 = 0
+	AttributeTitle               uint8 = 1
+	AttributeSubtitle            uint8 = 2
+	AttributeMessage             uint8 = 3
+	AttributeMessageSize         uint8 = 4
+	AttributeDate                uint8 = 5
+	AttributePositiveActionLabel uint8 = 6
+	AttributeNegativeActionLabel uint8 = 7
+)
+
+//AppAttributeDisplayName the app attribute of the name of an app
+const AppAttributeDisplayName uint8 = 0
+
+// Notification actions
+const (
+	ActionPositive uint8 = 0
+	ActionNegative uint8 = 1
+)
+
+//DefaultANCSTimeout the time allowed to the iOS device to send the
+// attributes requested
+var DefaultANCSTimeout = 5 * time.Second
+
+//DefaultMaxLength the maximum length of the title, subtitle and message
+// requested by Notification
+const DefaultMaxLength = 255
+
+//ErrANCSTimeout the attributes were not received in time
+var ErrANCSTimeout = errors.New("ANCS attributes not received")
+
+//NotificationEvent a notification added, modified or removed on the iOS
+// device
+type NotificationEvent struct {
+	Event         uint8
+	Flags         uint8
+	Category      uint8
+	CategoryCount uint8
+	UID           uint32
+}
+
+//ParseNotificationEvent decode a value of the Notification Source
+func ParseNotificationEvent(b []byte) (NotificationEvent, error) {
+	if len(b) != 8 {
+		return NotificationEvent{}, errors.New("Invalid notification source value")
+	}
+	return NotificationEvent{
+		Event:         b[0],
+		Flags:         b[1],
+		Category:      b[2],
+		CategoryCount: b[3],
+		UID:           binary.LittleEndian.Uint32(b[4:]),
+	}, nil
+}
+
+//AttributeRequest an attribute requested, MaxLength is required by the
+// title, the subtitle and the message
+type AttributeRequest struct {
+	ID        uint8
+	MaxLength uint16
+}
+
+func hasLength(id uint8) bool {
+	return id == AttributeTitle || id == AttributeSubtitle || id == AttributeMessage
+}
+
+//Notification the attributes of a notification
+type Notification struct {
+	UID            uint32
+	AppIdentifier  string
+	Title          string
+	Subtitle       string
+	Message        string
+	Date           time.Time
+	PositiveAction string
+	NegativeAction string
+}
+
+// parseAttributes decode the attribute tuples of a response, complete is
+// false until the count attributes are received
+func parseAttributes(b []byte, count int) (map[uint8]string, bool, error) {
+	attrs := make(map[uint8]string, count)
+	for len(attrs) < count {
+		if len(b) < 3 {
+			return nil, false, nil
+		}
+		size := int(binary.LittleEndian.Uint16(b[1:]))
+		if len(b) < 3+size {
+			return nil, false, nil
+		}
+		attrs[b[0]] = string(b[3 : 3+size])
+		b = b[3+size:]
+	}
+	if len(b) > 0 {
+		return nil, true, errors.New("Unexpected data after the ANCS attributes")
+	}
+	return attrs, true, nil
+}
+
+// ancsRequest a request waiting for its response on the Data Source
+type ancsRequest struct {
+	// header the command and the notification UID or the app identifier the
+	// response starts with
+	header []byte
+	count  int
+	data   []byte
+	done   chan map[uint8]string
+}
+
+//ANCS a client of the Apple Notification Center Service of a connected iOS
+// device
+type ANCS struct {
+	control    characteristic
+	events     chan NotificationEvent
+	stopSource func()
+	stopData   func()
+
+	// request serialize the requests, mutex protects pending
+	request sync.Mutex
+	mutex   sync.Mutex
+	pending *ancsRequest
+}
+
+//NewANCS subscribe to the notifications of the iOS device, the events
+// are reported on Events until Close
+func NewANCS(dev *api.Device) (*ANCS, error) {
+	list, err := chars(dev, ControlPointUUID, NotificationSourceUUID, DataSourceUUID)
+	if err != nil {
+		return nil, err
+	}
+	return newANCS(list[0], list[1], list[2])
+}
+
+func newANCS(control, source, data characteristic) (*ANCS, error) {
+
+	a := &ANCS{
+		control: control,
+		events:  make(chan NotificationEvent, 16),
+	}
+
+	// the Data Source is subscribed first, as required by ANCS
+	dataValues, stopData, err := data.Notifications()
+	if err != nil {
+		return nil, err
+	}
+	sourceValues, stopSource, err := source.Notifications()
+	if err != nil {
+		stopData()
+		return nil, err
+	}
+	a.stopData, a.stopSource = stopData, stopSource
+
+	go func() {
+		for b := range dataValues {
+			a.data(b)
+		}
+	}()
+	go func() {
+		defer close(a.events)
+		for b := range sourceValues {
+			ev, err := ParseNotificationEvent(b)
+			if err != nil {
+				continue
+			}
+			a.events <- ev
+		}
+	}()
+
+	return a, nil
+}
+
+//Events return the notification events, the pre-existing notifications are
+// reported with FlagPreExisting after the subscription. The channel must be
+// drained to keep receiving the events
+func (a *ANCS) Events() <-chan NotificationEvent {
+	return a.events
+}
+
+//Close stop the notifications
+func (a *ANCS) Close() {
+	a.stopSource()
+	a.stopData()
+}
+
+// data handle a value of the Data Source, a response may span several
+// notifications
+func (a *ANCS) data(b []byte) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	r := a.pending
+	if r == nil {
+		return
+	}
+	r.data = append(r.data, b...)
+	if len(r.data) < len(r.header) {
+		return
+	}
+	if string(r.data[:len(r.header)]) != string(r.header) {
+		// a late response to a previous request
+		r.data = nil
+		return
+	}
+	attrs, complete, err := parseAttributes(r.data[len(r.header):], r.count)
+	if !complete {
+		return
+	}
+	if err != nil {
+		attrs = nil
+	}
+	a.pending = nil
+	r.done <- attrs
+}
+
+// send write a command and wait for the attributes of the response
+func (a *ANCS) send(command []byte, header []byte, count int) (map[uint8]string, error) {
+
+	a.request.Lock()
+	defer a.request.Unlock()
+
+	r := &ancsRequest{
+		header: header,
+		count:  count,
+		done:   make(chan map[uint8]string, 1),
+	}
+	a.mutex.Lock()
+	a.pending = r
+	a.mutex.Unlock()
+	defer func() {
+		a.mutex.Lock()
+		if a.pending == r {
+			a.pending = nil
+		}
+		a.mutex.Unlock()
+	}()
+
+	if err := a.control.WriteValue(command, nil); err != nil {
+		return nil, err
+	}
+
+	select {
+	case attrs := <-r.done:
+		if attrs == nil {
+			return nil, errors.New("Invalid ANCS response")
+		}
+		return attrs, nil
+	case <-time.After(DefaultANCSTimeout):
+		return nil, ErrANCSTimeout
+	}
+}
+
+//NotificationAttributes request attributes of a notification
+func (a *ANCS) NotificationAttributes(uid uint32, attrs ...AttributeRequest) (map[uint8]string, error) {
+	header := []byte{CommandGetNotificationAttributes, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(header[1:], uid)
+	command := append([]byte{}, header...)
+	for _, attr := range attrs {
+		command = append(command, attr.ID)
+		if hasLength(attr.ID) {
+			command = append(command, byte(attr.MaxLength), byte(attr.MaxLength>>8))
+		}
+	}
+	return a.send(command, header, len(attrs))
+}
+
+//Notification request all the attributes of a notification
+func (a *ANCS) Notification(uid uint32) (*Notification, error) {
+
+	attrs, err := a.NotificationAttributes(uid,
+		AttributeRequest{ID: AttributeAppIdentifier},
+		AttributeRequest{ID: AttributeTitle, MaxLength: DefaultMaxLength},
+		AttributeRequest{ID: AttributeSubtitle, MaxLength: DefaultMaxLength},
+		AttributeRequest{ID: AttributeMessage, MaxLength: DefaultMaxLength},
+		AttributeRequest{ID: AttributeDate},
+		AttributeRequest{ID: AttributePositiveActionLabel},
+		AttributeRequest{ID: AttributeNegativeActionLabel},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Notification{
+		UID:            uid,
+		AppIdentifier:  attrs[AttributeAppIdentifier],
+		Title:          attrs[AttributeTitle],
+		Subtitle:       attrs[AttributeSubtitle],
+		Message:        attrs[AttributeMessage],
+		PositiveAction: attrs[AttributePositiveActionLabel],
+		NegativeAction: attrs[AttributeNegativeActionLabel],
+	}
+	if date := attrs[AttributeDate]; date != "" {
+		// UTS #35 yyyyMMdd'T'HHmmSS, in the time zone of the iOS device
+		n.Date, _ = time.ParseInLocation("20060102T150405", date, time.Local)
+	}
+	return n, nil
+}
+
+//AppAttributes request attributes of an app, eg. AppAttributeDisplayName
+func (a *ANCS) AppAttributes(appIdentifier string, attrs ...uint8) (map[uint8]string, error) {
+	header := append([]byte{CommandGetAppAttributes}, appIdentifier...)
+	header = append(header, 0)
+	command := append(append([]byte{}, header...), attrs...)
+	return a.send(command, header, len(attrs))
+}
+
+//PerformAction perform the positive or negative action of a notification,
+// eg. to accept or decline a call
+func (a *ANCS) PerformAction(uid uint32, action uint8) error {
+	command := []byte{CommandPerformNotificationAction, 0, 0, 0, 0, action}
+	binary.LittleEndian.PutUint32(command[1:], uid)
+	return a.control.WriteValue(command, nil)
+}
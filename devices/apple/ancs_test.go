@@ -0,0 +1,81 @@
+package apple
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestANCS(t *testing.T) {
+
+	control, source, data := newFakeChar(), newFakeChar(), newFakeChar()
+	control.onWrite = func(b []byte) error {
+		if b[0] != CommandGetNotificationAttributes {
+			return nil
+		}
+		// split the response over two notifications
+		data.values <- []byte{CommandGetNotificationAttributes, 7, 0, 0, 0, AttributeAppIdentifier, 19, 0}
+		data.values <- []byte("com.apple.mobilesms\x01\x03\x00Bob\x05\x0f\x0020180301T083000")
+		return nil
+	}
+
+	a, err := newANCS(control, source, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	source.values <- []byte{EventAdded, FlagImportant | FlagNegativeAction, CategorySocial, 1, 7, 0, 0, 0}
+	select {
+	case ev := <-a.Events():
+		if ev.UID != 7 || ev.Category != CategorySocial || ev.Flags&FlagNegativeAction == 0 {
+			t.Fatalf("Unexpected event %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an event")
+	}
+
+	attrs, err := a.NotificationAttributes(7,
+		AttributeRequest{ID: AttributeAppIdentifier},
+		AttributeRequest{ID: AttributeTitle, MaxLength: 32},
+		AttributeRequest{ID: AttributeDate},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{CommandGetNotificationAttributes, 7, 0, 0, 0, AttributeAppIdentifier, AttributeTitle, 32, 0, AttributeDate}
+	if !bytes.Equal(control.last(), expected) {
+		t.Fatalf("Expected %x, got %x", expected, control.last())
+	}
+	if attrs[AttributeAppIdentifier] != "com.apple.mobilesms" || attrs[AttributeTitle] != "Bob" {
+		t.Fatalf("Unexpected attributes %v", attrs)
+	}
+
+	if err = a.PerformAction(7, ActionNegative); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(control.last(), []byte{CommandPerformNotificationAction, 7, 0, 0, 0, ActionNegative}) {
+		t.Fatalf("Unexpected action %x", control.last())
+	}
+}
+
+func TestANCSTimeout(t *testing.T) {
+
+	DefaultANCSTimeout = 50 * time.Millisecond
+	defer func() { DefaultANCSTimeout = 5 * time.Second }()
+
+	control, source, data := newFakeChar(), newFakeChar(), newFakeChar()
+	a, err := newANCS(control, source, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	if _, err = a.AppAttributes("com.apple.mobilesms", AppAttributeDisplayName); err != ErrANCSTimeout {
+		t.Fatalf("Expected %s, got %v", ErrANCSTimeout, err)
+	}
+	expected := append([]byte{CommandGetAppAttributes}, "com.apple.mobilesms\x00\x00"...)
+	if !bytes.Equal(control.last(), expected) {
+		t.Fatalf("Expected %x, got %x", expected, control.last())
+	}
+}
@@ -0,0 +1,33 @@
+//Package apple clients of the services exposed by the iOS devices to the
+// accessories, the Apple Notification Center Service and the Apple Media
+// Service. The accessory must be bonded with the iOS device
+package apple
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+// characteristic the client operations used on the characteristics of the
+// iOS device, implemented by *profile.GattCharacteristic1
+type characteristic interface {
+	ReadValue(options map[string]dbus.Variant) ([]byte, error)
+	WriteValue(b []byte, options map[string]dbus.Variant) error
+	Notifications() (<-chan []byte, func(), error)
+}
+
+var _ characteristic = (*profile.GattCharacteristic1)(nil)
+
+// chars return the characteristics of a device by UUID
+func chars(dev *api.Device, uuids ...string) ([]characteristic, error) {
+	list := make([]characteristic, len(uuids))
+	for i, uuid := range uuids {
+		char, err := dev.GetCharByUUID(uuid)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = char
+	}
+	return list, nil
+}
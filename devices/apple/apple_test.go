@@ -0,0 +1,51 @@
+package apple
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus"
+)
+
+// fakeChar a characteristic of a fake iOS device, onWrite answers the writes
+type fakeChar struct {
+	mutex   sync.Mutex
+	values  chan []byte
+	written [][]byte
+	onWrite func(b []byte) error
+	// value the value read
+	value []byte
+}
+
+func newFakeChar() *fakeChar {
+	return &fakeChar{values: make(chan []byte, 16)}
+}
+
+func (c *fakeChar) ReadValue(options map[string]dbus.Variant) ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.value, nil
+}
+
+func (c *fakeChar) WriteValue(b []byte, options map[string]dbus.Variant) error {
+	c.mutex.Lock()
+	c.written = append(c.written, b)
+	c.mutex.Unlock()
+	if c.onWrite != nil {
+		return c.onWrite(b)
+	}
+	return nil
+}
+
+func (c *fakeChar) Notifications() (<-chan []byte, func(), error) {
+	var once sync.Once
+	return c.values, func() { once.Do(func() { close(c.values) }) }, nil
+}
+
+func (c *fakeChar) last() []byte {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.written) == 0 {
+		return nil
+	}
+	return c.written[len(c.written)-1]
+}
@@ -0,0 +1,313 @@
+//Package dfu update the firmware of the Nordic nRF devices with the Secure
+// DFU protocol of the nRF5 SDK bootloader
+package dfu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+// Secure DFU UUIDs
+const (
+	ServiceUUID      = "0000FE59-0000-1000-8000-00805F9B34FB"
+	ControlPointUUID = "8EC90001-F315-4F60-9FB8-838830DAEA50"
+	PacketUUID       = "8EC90002-F315-4F60-9FB8-838830DAEA50"
+)
+
+// Control point op codes
+const (
+	OpCreate       uint8 = 0x01
+	OpSetPRN       uint8 = 0x02
+	OpCalculateCRC uint8 = 0x03
+	OpExecute      uint8 = 0x04
+	OpSelect       uint8 = 0x06
+	OpResponse     uint8 = 0x60
+)
+
+// Object types
+const (
+	ObjectCommand uint8 = 0x01
+	ObjectData    uint8 = 0x02
+)
+
+// Result codes
+const (
+	ResultInvalid             uint8 = 0x00
+	ResultSuccess             uint8 = 0x01
+	ResultOpCodeNotSupported  uint8 = 0x02
+	ResultInvalidParameter    uint8 = 0x03
+	ResultInsufficientMemory  uint8 = 0x04
+	ResultInvalidObject       uint8 = 0x05
+	ResultUnsupportedType     uint8 = 0x07
+	ResultOperationNotAllowed uint8 = 0x08
+	ResultOperationFailed     uint8 = 0x0A
+	ResultExtendedError       uint8 = 0x0B
+)
+
+//DefaultPacketSize the bytes written at once to the packet characteristic,
+// the payload of the default ATT MTU
+const DefaultPacketSize = 20
+
+//DefaultTimeout the time allowed to the device to answer a command
+const DefaultTimeout = 10 * time.Second
+
+//ResponseError the device rejected a command
+type ResponseError struct {
+	OpCode uint8
+	Result uint8
+	// Extended the extended error code of ResultExtendedError
+	Extended uint8
+}
+
+func (e *ResponseError) Error() string {
+	if e.Result == ResultExtendedError {
+		return fmt.Sprintf("DFU op code 0x%02x failed with extended error 0x%02x", e.OpCode, e.Extended)
+	}
+	return fmt.Sprintf("DFU op code 0x%02x failed with result 0x%02x", e.OpCode, e.Result)
+}
+
+//CRCError the CRC computed by the device does not match the data sent
+type CRCError struct {
+	Offset   uint32
+	Expected uint32
+	CRC      uint32
+}
+
+func (e *CRCError) Error() string {
+	return fmt.Sprintf("DFU CRC mismatch at offset %d: expected 0x%08x, got 0x%08x", e.Offset, e.Expected, e.CRC)
+}
+
+// characteristic the client operations used on the DFU characteristics,
+// implemented by *profile.GattCharacteristic1
+type characteristic interface {
+	WriteValue(b []byte, options map[string]dbus.Variant) error
+	Notifications() (<-chan []byte, func(), error)
+}
+
+var _ characteristic = (*profile.GattCharacteristic1)(nil)
+
+//Options configure a Client
+type Options struct {
+	// PRN the packets between the Packet Receipt Notifications, the CRC is
+	// checked at each of them. Zero disables them
+	PRN uint16
+	// PacketSize DefaultPacketSize if zero, up to the ATT MTU minus 3
+	PacketSize int
+	// Timeout DefaultTimeout if zero
+	Timeout time.Duration
+	// Progress called after each object with the firmware bytes sent
+	Progress func(sent int, total int)
+}
+
+//Client the DFU client of a device running the bootloader
+type Client struct {
+	control   characteristic
+	packet    characteristic
+	responses <-chan []byte
+	stop      func()
+	opts      Options
+}
+
+//New connect to the DFU service of a device, it must be in bootloader mode
+func New(dev *api.Device, opts Options) (*Client, error) {
+	control, err := dev.GetCharByUUID(ControlPointUUID)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := dev.GetCharByUUID(PacketUUID)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(control, packet, opts)
+}
+
+func newClient(control, packet characteristic, opts Options) (*Client, error) {
+	if opts.PacketSize == 0 {
+		opts.PacketSize = DefaultPacketSize
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	responses, stop, err := control.Notifications()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		control:   control,
+		packet:    packet,
+		responses: responses,
+		stop:      stop,
+		opts:      opts,
+	}, nil
+}
+
+//Close stop the notifications of the control point
+func (c *Client) Close() {
+	c.stop()
+}
+
+// wait the response of an op code, checking its result
+func (c *Client) wait(op uint8) ([]byte, error) {
+	timeout := time.After(c.opts.Timeout)
+	for {
+		select {
+		case b, ok := <-c.responses:
+			if !ok {
+				return nil, fmt.Errorf("DFU notifications stopped")
+			}
+			if len(b) < 3 || b[0] != OpResponse || b[1] != op {
+				continue
+			}
+			if b[2] != ResultSuccess {
+				e := &ResponseError{OpCode: op, Result: b[2]}
+				if e.Result == ResultExtendedError && len(b) > 3 {
+					e.Extended = b[3]
+				}
+				return nil, e
+			}
+			return b[3:], nil
+		case <-timeout:
+			return nil, fmt.Errorf("DFU op code 0x%02x: no response", op)
+		}
+	}
+}
+
+// request write a command and wait for its response
+func (c *Client) request(op uint8, params ...byte) ([]byte, error) {
+	if err := c.control.WriteValue(append([]byte{op}, params...), nil); err != nil {
+		return nil, err
+	}
+	return c.wait(op)
+}
+
+//ObjectInfo the state of the current object of a type
+type ObjectInfo struct {
+	MaxSize uint32
+	Offset  uint32
+	CRC     uint32
+}
+
+//Select return the state of the current object of a type
+func (c *Client) Select(objectType uint8) (*ObjectInfo, error) {
+	b, err := c.request(OpSelect, objectType)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 12 {
+		return nil, fmt.Errorf("Invalid DFU select response")
+	}
+	return &ObjectInfo{
+		MaxSize: binary.LittleEndian.Uint32(b),
+		Offset:  binary.LittleEndian.Uint32(b[4:]),
+		CRC:     binary.LittleEndian.Uint32(b[8:]),
+	}, nil
+}
+
+// checksum decode a CRC response and compare it to the CRC and the length
+// of the data sent
+func checksum(b []byte, crc uint32, length int) error {
+	if len(b) < 8 {
+		return fmt.Errorf("Invalid DFU CRC response")
+	}
+	offset := binary.LittleEndian.Uint32(b)
+	received := binary.LittleEndian.Uint32(b[4:])
+	if int(offset) != length || received != crc {
+		return &CRCError{Offset: offset, Expected: crc, CRC: received}
+	}
+	return nil
+}
+
+// sendObject create an object and write its data, crc and length cover the
+// previous objects of the same type, as the CRC computed by the device. It
+// returns the CRC including the object
+func (c *Client) sendObject(objectType uint8, data []byte, crc uint32, length int) (uint32, error) {
+
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(data)))
+	if _, err := c.request(OpCreate, append([]byte{objectType}, size...)...); err != nil {
+		return 0, err
+	}
+
+	options := map[string]dbus.Variant{"type": dbus.MakeVariant("command")}
+	packets := 0
+	for offset := 0; offset < len(data); offset += c.opts.PacketSize {
+		end := offset + c.opts.PacketSize
+		if end > len(data) {
+			end = len(data)
+		}
+		if err := c.packet.WriteValue(data[offset:end], options); err != nil {
+			return 0, err
+		}
+		crc = crc32.Update(crc, crc32.IEEETable, data[offset:end])
+		packets++
+		if c.opts.PRN > 0 && packets%int(c.opts.PRN) == 0 && end < len(data) {
+			b, err := c.wait(OpCalculateCRC)
+			if err != nil {
+				return 0, err
+			}
+			if err = checksum(b, crc, length+end); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	b, err := c.request(OpCalculateCRC)
+	if err != nil {
+		return 0, err
+	}
+	if err = checksum(b, crc, length+len(data)); err != nil {
+		return 0, err
+	}
+	_, err = c.request(OpExecute)
+	return crc, err
+}
+
+//Update send an init packet, the .dat file of the package, and its firmware,
+// the .bin file. The device resets once the firmware is validated
+func (c *Client) Update(init []byte, firmware []byte) error {
+
+	prn := make([]byte, 2)
+	binary.LittleEndian.PutUint16(prn, c.opts.PRN)
+	if _, err := c.request(OpSetPRN, prn...); err != nil {
+		return err
+	}
+
+	info, err := c.Select(ObjectCommand)
+	if err != nil {
+		return err
+	}
+	if uint32(len(init)) > info.MaxSize {
+		return fmt.Errorf("DFU init packet too large: %d > %d", len(init), info.MaxSize)
+	}
+	if _, err = c.sendObject(ObjectCommand, init, 0, 0); err != nil {
+		return err
+	}
+
+	info, err = c.Select(ObjectData)
+	if err != nil {
+		return err
+	}
+	if info.MaxSize == 0 {
+		return fmt.Errorf("Invalid DFU data object size")
+	}
+	var crc uint32
+	for offset := 0; offset < len(firmware); offset += int(info.MaxSize) {
+		end := offset + int(info.MaxSize)
+		if end > len(firmware) {
+			end = len(firmware)
+		}
+		if crc, err = c.sendObject(ObjectData, firmware[offset:end], crc, offset); err != nil {
+			return err
+		}
+		if c.opts.Progress != nil {
+			c.opts.Progress(end, len(firmware))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package dfu
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// target a fake bootloader, handling the control point writes
+type target struct {
+	mutex     sync.Mutex
+	responses chan []byte
+	prn       int
+	maxSize   uint32
+	objects   map[uint8][]byte
+	current   uint8
+	packets   int
+	// corrupt alter the CRC of the responses
+	corrupt bool
+}
+
+func newTarget() *target {
+	return &target{
+		responses: make(chan []byte, 64),
+		maxSize:   64,
+		objects:   make(map[uint8][]byte),
+	}
+}
+
+func (t *target) crcResponse(op uint8) []byte {
+	data := t.objects[t.current]
+	b := []byte{OpResponse, op, ResultSuccess, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(b[3:], uint32(len(data)))
+	crc := crc32.ChecksumIEEE(data)
+	if t.corrupt {
+		crc++
+	}
+	binary.LittleEndian.PutUint32(b[7:], crc)
+	return b
+}
+
+// control the control point characteristic
+type control struct{ t *target }
+
+func (c control) WriteValue(b []byte, options map[string]dbus.Variant) error {
+	t := c.t
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	switch b[0] {
+	case OpSetPRN:
+		t.prn = int(binary.LittleEndian.Uint16(b[1:]))
+		t.responses <- []byte{OpResponse, OpSetPRN, ResultSuccess}
+	case OpSelect:
+		r := []byte{OpResponse, OpSelect, ResultSuccess, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(r[3:], t.maxSize)
+		t.responses <- r
+	case OpCreate:
+		t.current = b[1]
+		t.packets = 0
+		t.responses <- []byte{OpResponse, OpCreate, ResultSuccess}
+	case OpCalculateCRC:
+		t.responses <- t.crcResponse(OpCalculateCRC)
+	case OpExecute:
+		t.responses <- []byte{OpResponse, OpExecute, ResultSuccess}
+	default:
+		t.responses <- []byte{OpResponse, b[0], ResultOpCodeNotSupported}
+	}
+	return nil
+}
+
+func (c control) Notifications() (<-chan []byte, func(), error) {
+	return c.t.responses, func() {}, nil
+}
+
+// packet the packet characteristic
+type packet struct{ t *target }
+
+func (p packet) WriteValue(b []byte, options map[string]dbus.Variant) error {
+	t := p.t
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if v, _ := options["type"].Value().(string); v != "command" {
+		return nil
+	}
+	t.objects[t.current] = append(t.objects[t.current], b...)
+	t.packets++
+	if t.prn > 0 && t.packets%t.prn == 0 {
+		t.responses <- t.crcResponse(OpCalculateCRC)
+	}
+	return nil
+}
+
+func (p packet) Notifications() (<-chan []byte, func(), error) {
+	return nil, func() {}, nil
+}
+
+func TestUpdate(t *testing.T) {
+
+	target := newTarget()
+	progress := []int{}
+	c, err := newClient(control{target}, packet{target}, Options{
+		PRN:      2,
+		Timeout:  time.Second,
+		Progress: func(sent, total int) { progress = append(progress, sent) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	init := bytes.Repeat([]byte{0x12}, 30)
+	firmware := make([]byte, 150)
+	for i := range firmware {
+		firmware[i] = byte(i)
+	}
+	if err = c.Update(init, firmware); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(target.objects[ObjectCommand], init) || !bytes.Equal(target.objects[ObjectData], firmware) {
+		t.Fatal("Unexpected objects received")
+	}
+	if len(progress) != 3 || progress[2] != 150 {
+		t.Fatalf("Unexpected progress %v", progress)
+	}
+}
+
+func TestUpdateCRCError(t *testing.T) {
+
+	target := newTarget()
+	target.corrupt = true
+	c, err := newClient(control{target}, packet{target}, Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Update([]byte{1, 2, 3}, []byte{4, 5, 6})
+	if _, ok := err.(*CRCError); !ok {
+		t.Fatalf("Expected a CRC error, got %v", err)
+	}
+}
+
+func TestOpenPackage(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dfu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	files := map[string]string{
+		"manifest.json": `{"manifest": {"application": {"bin_file": "app.bin", "dat_file": "app.dat"}}}`,
+		"app.bin":       "firmware",
+		"app.dat":       "init",
+	}
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	w.Close()
+	f.Close()
+
+	images, err := OpenPackage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 || images[0].Type != "application" || string(images[0].Init) != "init" || string(images[0].Firmware) != "firmware" {
+		t.Fatalf("Unexpected images %+v", images)
+	}
+}
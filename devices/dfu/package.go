@@ -0,0 +1,86 @@
+package dfu
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+//Image a firmware image of a DFU package
+type Image struct {
+	// Type eg. application or softdevice_bootloader
+	Type     string
+	Init     []byte
+	Firmware []byte
+}
+
+// manifest the manifest.json of a package built by nrfutil
+type manifest struct {
+	Manifest map[string]struct {
+		BinFile string `json:"bin_file"`
+		DatFile string `json:"dat_file"`
+	} `json:"manifest"`
+}
+
+// the images in the order they are sent, the bootloader before the
+// application it validates
+var imageOrder = []string{"softdevice_bootloader", "softdevice", "bootloader", "application"}
+
+//OpenPackage read the images of a zip package built by nrfutil, in the order
+// they are to be sent with Update. The device resets after each image, the
+// next one is sent once it is back in bootloader mode
+func OpenPackage(path string) ([]Image, error) {
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("DFU package: %s not found", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return ioutil.ReadAll(rc)
+	}
+
+	b, err := read("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	m := manifest{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	images := []Image{}
+	for _, kind := range imageOrder {
+		entry, ok := m.Manifest[kind]
+		if !ok {
+			continue
+		}
+		img := Image{Type: kind}
+		if img.Init, err = read(entry.DatFile); err != nil {
+			return nil, err
+		}
+		if img.Firmware, err = read(entry.BinFile); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("DFU package: no image in the manifest")
+	}
+	return images, nil
+}
@@ -0,0 +1,260 @@
+package mcumgr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// the subset of CBOR (RFC 7049) used by SMP: integers, byte and text
+// strings, arrays, maps with text keys and the simple values
+
+// CBOR major types
+const (
+	cborUint   = 0
+	cborNegint = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// cborBreak the end of an indefinite length item
+const cborBreak = 0xff
+
+var errCBORTruncated = errors.New("CBOR: truncated data")
+
+// cborHead encode the head of an item
+func cborHead(major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return []byte{m | byte(n)}
+	case n <= 0xff:
+		return []byte{m | 24, byte(n)}
+	case n <= 0xffff:
+		b := []byte{m | 25, 0, 0}
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		return b
+	case n <= 0xffffffff:
+		b := []byte{m | 26, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		return b
+	}
+	b := []byte{m | 27, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint64(b[1:], n)
+	return b
+}
+
+// cborEncode encode a value, the keys of the maps are sorted
+func cborEncode(v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return []byte{0xf6}, nil
+	case bool:
+		if x {
+			return []byte{0xf5}, nil
+		}
+		return []byte{0xf4}, nil
+	case int:
+		return cborEncode(int64(x))
+	case int64:
+		if x < 0 {
+			return cborHead(cborNegint, uint64(-1-x)), nil
+		}
+		return cborHead(cborUint, uint64(x)), nil
+	case uint:
+		return cborHead(cborUint, uint64(x)), nil
+	case uint32:
+		return cborHead(cborUint, uint64(x)), nil
+	case uint64:
+		return cborHead(cborUint, x), nil
+	case []byte:
+		return append(cborHead(cborBytes, uint64(len(x))), x...), nil
+	case string:
+		return append(cborHead(cborText, uint64(len(x))), x...), nil
+	case []interface{}:
+		b := cborHead(cborArray, uint64(len(x)))
+		for _, item := range x {
+			e, err := cborEncode(item)
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, e...)
+		}
+		return b, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b := cborHead(cborMap, uint64(len(x)))
+		for _, k := range keys {
+			b = append(b, append(cborHead(cborText, uint64(len(k))), k...)...)
+			e, err := cborEncode(x[k])
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, e...)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("CBOR: unsupported type %T", v)
+}
+
+// cborDecode decode an item, the integers are decoded as int64 or uint64,
+// the maps as map[string]interface{}. It returns the bytes consumed
+func cborDecode(b []byte) (interface{}, int, error) {
+
+	if len(b) == 0 {
+		return nil, 0, errCBORTruncated
+	}
+	major := b[0] >> 5
+	info := b[0] & 0x1f
+	pos := 1
+
+	// indefinite length strings, arrays and maps
+	indefinite := info == 31 && major >= cborBytes && major <= cborMap
+
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info == 24:
+		if len(b) < 2 {
+			return nil, 0, errCBORTruncated
+		}
+		n = uint64(b[1])
+		pos = 2
+	case info == 25:
+		if len(b) < 3 {
+			return nil, 0, errCBORTruncated
+		}
+		n = uint64(binary.BigEndian.Uint16(b[1:]))
+		pos = 3
+	case info == 26:
+		if len(b) < 5 {
+			return nil, 0, errCBORTruncated
+		}
+		n = uint64(binary.BigEndian.Uint32(b[1:]))
+		pos = 5
+	case info == 27:
+		if len(b) < 9 {
+			return nil, 0, errCBORTruncated
+		}
+		n = binary.BigEndian.Uint64(b[1:])
+		pos = 9
+	case indefinite:
+	default:
+		return nil, 0, fmt.Errorf("CBOR: invalid additional information %d", info)
+	}
+
+	switch major {
+	case cborUint:
+		return n, pos, nil
+	case cborNegint:
+		return -1 - int64(n), pos, nil
+	case cborBytes, cborText:
+		var s []byte
+		if indefinite {
+			// a sequence of definite length chunks
+			s = []byte{}
+			for {
+				if pos >= len(b) {
+					return nil, 0, errCBORTruncated
+				}
+				if b[pos] == cborBreak {
+					pos++
+					break
+				}
+				chunk, size, err := cborDecode(b[pos:])
+				if err != nil {
+					return nil, 0, err
+				}
+				switch c := chunk.(type) {
+				case []byte:
+					s = append(s, c...)
+				case string:
+					s = append(s, c...)
+				}
+				pos += size
+			}
+		} else {
+			if uint64(len(b)-pos) < n {
+				return nil, 0, errCBORTruncated
+			}
+			s = append([]byte{}, b[pos:pos+int(n)]...)
+			pos += int(n)
+		}
+		if major == cborText {
+			return string(s), pos, nil
+		}
+		return s, pos, nil
+	case cborArray:
+		list := []interface{}{}
+		for i := uint64(0); indefinite || i < n; i++ {
+			if indefinite {
+				if pos >= len(b) {
+					return nil, 0, errCBORTruncated
+				}
+				if b[pos] == cborBreak {
+					pos++
+					break
+				}
+			}
+			item, size, err := cborDecode(b[pos:])
+			if err != nil {
+				return nil, 0, err
+			}
+			list = append(list, item)
+			pos += size
+		}
+		return list, pos, nil
+	case cborMap:
+		m := map[string]interface{}{}
+		for i := uint64(0); indefinite || i < n; i++ {
+			if indefinite {
+				if pos >= len(b) {
+					return nil, 0, errCBORTruncated
+				}
+				if b[pos] == cborBreak {
+					pos++
+					break
+				}
+			}
+			key, size, err := cborDecode(b[pos:])
+			if err != nil {
+				return nil, 0, err
+			}
+			pos += size
+			value, size, err := cborDecode(b[pos:])
+			if err != nil {
+				return nil, 0, err
+			}
+			pos += size
+			m[fmt.Sprint(key)] = value
+		}
+		return m, pos, nil
+	case cborTag:
+		item, size, err := cborDecode(b[pos:])
+		if err != nil {
+			return nil, 0, err
+		}
+		return item, pos + size, nil
+	}
+
+	// simple values
+	switch info {
+	case 20:
+		return false, pos, nil
+	case 21:
+		return true, pos, nil
+	case 22, 23:
+		return nil, pos, nil
+	}
+	return nil, 0, fmt.Errorf("CBOR: unsupported simple value %d", info)
+}
@@ -0,0 +1,386 @@
+//Package mcumgr manage the Zephyr and MCUboot devices with the Simple
+// Management Protocol (SMP) of mcumgr, over its GATT transport
+package mcumgr
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+// SMP UUIDs
+const (
+	ServiceUUID        = "8D53DC1D-1DB7-4CD3-868B-8A527460AA84"
+	CharacteristicUUID = "DA2E7828-FBCE-4E01-AE9E-261174997C48"
+)
+
+// SMP operations
+const (
+	OpRead          uint8 = 0
+	OpReadResponse  uint8 = 1
+	OpWrite         uint8 = 2
+	OpWriteResponse uint8 = 3
+)
+
+// Management groups
+const (
+	GroupOS    uint16 = 0
+	GroupImage uint16 = 1
+)
+
+// Commands of the groups
+const (
+	OSEcho      uint8 = 0
+	OSReset     uint8 = 5
+	ImageState  uint8 = 0
+	ImageUpload uint8 = 1
+)
+
+// Return codes
+const (
+	RCOK           = 0
+	RCUnknown      = 1
+	RCNoMemory     = 2
+	RCInvalid      = 3
+	RCTimeout      = 4
+	RCNoEntry      = 5
+	RCBadState     = 6
+	RCTooLarge     = 7
+	RCNotSupported = 8
+	RCCorrupt      = 9
+	RCBusy         = 10
+)
+
+// headerSize the size of the SMP header
+const headerSize = 8
+
+//DefaultPacketSize the bytes written at once to the characteristic, the
+// payload of the default ATT MTU. The device reassembles the requests split
+// across writes
+const DefaultPacketSize = 20
+
+//DefaultChunkSize the image bytes sent by each upload request, the requests
+// must fit the SMP buffers of the device
+const DefaultChunkSize = 128
+
+//DefaultTimeout the time allowed to the device to answer a request
+const DefaultTimeout = 10 * time.Second
+
+//ResponseError the device rejected a request with a return code
+type ResponseError struct {
+	Group uint16
+	ID    uint8
+	RC    int
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("SMP command %d of group %d failed with rc %d", e.ID, e.Group, e.RC)
+}
+
+// characteristic the client operations used on the SMP characteristic,
+// implemented by *profile.GattCharacteristic1
+type characteristic interface {
+	WriteValue(b []byte, options map[string]dbus.Variant) error
+	Notifications() (<-chan []byte, func(), error)
+}
+
+var _ characteristic = (*profile.GattCharacteristic1)(nil)
+
+//Options configure a Client
+type Options struct {
+	// PacketSize DefaultPacketSize if zero, up to the ATT MTU minus 3
+	PacketSize int
+	// ChunkSize DefaultChunkSize if zero
+	ChunkSize int
+	// Timeout DefaultTimeout if zero
+	Timeout time.Duration
+}
+
+//Client the SMP client of a device
+type Client struct {
+	char          characteristic
+	notifications <-chan []byte
+	stop          func()
+	opts          Options
+	// mutex serialize the requests, a response is matched by its sequence
+	mutex    sync.Mutex
+	sequence uint8
+	buffer   []byte
+}
+
+//New connect to the SMP service of a device
+func New(dev *api.Device, opts Options) (*Client, error) {
+	char, err := dev.GetCharByUUID(CharacteristicUUID)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(char, opts)
+}
+
+func newClient(char characteristic, opts Options) (*Client, error) {
+	if opts.PacketSize == 0 {
+		opts.PacketSize = DefaultPacketSize
+	}
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	notifications, stop, err := char.Notifications()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		char:          char,
+		notifications: notifications,
+		stop:          stop,
+		opts:          opts,
+	}, nil
+}
+
+//Close stop the notifications of the characteristic
+func (c *Client) Close() {
+	c.stop()
+}
+
+// header encode an SMP header
+func header(op uint8, length int, group uint16, sequence uint8, id uint8) []byte {
+	b := make([]byte, headerSize)
+	b[0] = op
+	binary.BigEndian.PutUint16(b[2:], uint16(length))
+	binary.BigEndian.PutUint16(b[4:], group)
+	b[6] = sequence
+	b[7] = id
+	return b
+}
+
+// next return the next complete packet of the notifications, a response
+// may be split across them
+func (c *Client) next(timeout <-chan time.Time) ([]byte, error) {
+	for {
+		if len(c.buffer) >= headerSize {
+			size := headerSize + int(binary.BigEndian.Uint16(c.buffer[2:]))
+			if len(c.buffer) >= size {
+				packet := c.buffer[:size]
+				c.buffer = c.buffer[size:]
+				return packet, nil
+			}
+		}
+		select {
+		case b, ok := <-c.notifications:
+			if !ok {
+				return nil, fmt.Errorf("SMP notifications stopped")
+			}
+			c.buffer = append(c.buffer, b...)
+		case <-timeout:
+			// drop the partial packet, the next response starts a new one
+			c.buffer = nil
+			return nil, fmt.Errorf("SMP: no response")
+		}
+	}
+}
+
+//Request send a request of a group and wait for its response. The payload
+// and the response are CBOR maps, a non zero "rc" is returned as a
+// *ResponseError
+func (c *Client) Request(op uint8, group uint16, id uint8, payload map[string]interface{}) (map[string]interface{}, error) {
+
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	body, err := cborEncode(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	sequence := c.sequence
+	c.sequence++
+	b := append(header(op, len(body), group, sequence, id), body...)
+
+	options := map[string]dbus.Variant{"type": dbus.MakeVariant("command")}
+	for offset := 0; offset < len(b); offset += c.opts.PacketSize {
+		end := offset + c.opts.PacketSize
+		if end > len(b) {
+			end = len(b)
+		}
+		if err = c.char.WriteValue(b[offset:end], options); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := time.After(c.opts.Timeout)
+	for {
+		packet, err := c.next(timeout)
+		if err != nil {
+			return nil, err
+		}
+		// a late response of a previous request
+		if packet[0]&0x07 != op+1 || packet[6] != sequence ||
+			binary.BigEndian.Uint16(packet[4:]) != group || packet[7] != id {
+			continue
+		}
+
+		value, _, err := cborDecode(packet[headerSize:])
+		if err != nil {
+			return nil, err
+		}
+		rsp, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Invalid SMP response")
+		}
+		if rc, ok := toInt(rsp["rc"]); ok && rc != RCOK {
+			return rsp, &ResponseError{Group: group, ID: id, RC: int(rc)}
+		}
+		return rsp, nil
+	}
+}
+
+// toInt return a decoded CBOR integer
+func toInt(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case uint64:
+		return int64(x), true
+	case int64:
+		return x, true
+	}
+	return 0, false
+}
+
+//Echo send a string the device returns, eg. to check the transport
+func (c *Client) Echo(s string) (string, error) {
+	rsp, err := c.Request(OpWrite, GroupOS, OSEcho, map[string]interface{}{"d": s})
+	if err != nil {
+		return "", err
+	}
+	r, _ := rsp["r"].(string)
+	return r, nil
+}
+
+//Reset reboot the device, eg. to run the image set with Test or Confirm
+func (c *Client) Reset() error {
+	_, err := c.Request(OpWrite, GroupOS, OSReset, nil)
+	return err
+}
+
+//Image the state of an image slot
+type Image struct {
+	Image   int
+	Slot    int
+	Version string
+	// Hash the SHA256 of the image, used by Test and Confirm
+	Hash      []byte
+	Bootable  bool
+	Pending   bool
+	Confirmed bool
+	Active    bool
+	Permanent bool
+}
+
+// imageState decode the image state response
+func imageState(rsp map[string]interface{}) []Image {
+	list, _ := rsp["images"].([]interface{})
+	images := make([]Image, 0, len(list))
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		image := Image{}
+		if v, ok := toInt(m["image"]); ok {
+			image.Image = int(v)
+		}
+		if v, ok := toInt(m["slot"]); ok {
+			image.Slot = int(v)
+		}
+		image.Version, _ = m["version"].(string)
+		image.Hash, _ = m["hash"].([]byte)
+		image.Bootable, _ = m["bootable"].(bool)
+		image.Pending, _ = m["pending"].(bool)
+		image.Confirmed, _ = m["confirmed"].(bool)
+		image.Active, _ = m["active"].(bool)
+		image.Permanent, _ = m["permanent"].(bool)
+		images = append(images, image)
+	}
+	return images
+}
+
+//Images list the images of the slots of the device
+func (c *Client) Images() ([]Image, error) {
+	rsp, err := c.Request(OpRead, GroupImage, ImageState, nil)
+	if err != nil {
+		return nil, err
+	}
+	return imageState(rsp), nil
+}
+
+//Test mark an image to be run once at the next reset, it is reverted
+// unless confirmed
+func (c *Client) Test(hash []byte) ([]Image, error) {
+	rsp, err := c.Request(OpWrite, GroupImage, ImageState, map[string]interface{}{
+		"hash":    hash,
+		"confirm": false,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imageState(rsp), nil
+}
+
+//Confirm make an image permanent, a nil hash confirms the running image
+func (c *Client) Confirm(hash []byte) ([]Image, error) {
+	payload := map[string]interface{}{"confirm": true}
+	if hash != nil {
+		payload["hash"] = hash
+	}
+	rsp, err := c.Request(OpWrite, GroupImage, ImageState, payload)
+	if err != nil {
+		return nil, err
+	}
+	return imageState(rsp), nil
+}
+
+//Upload write an image to the secondary slot, progress is called after each
+// chunk with the bytes stored by the device. An interrupted upload resumes
+// at the offset returned by the device
+func (c *Client) Upload(image []byte, progress func(sent int, total int)) error {
+
+	sum := sha256.Sum256(image)
+	offset := 0
+	for offset < len(image) {
+		end := offset + c.opts.ChunkSize
+		if end > len(image) {
+			end = len(image)
+		}
+		payload := map[string]interface{}{
+			"off":  uint64(offset),
+			"data": image[offset:end],
+		}
+		if offset == 0 {
+			payload["len"] = uint64(len(image))
+			payload["sha"] = sum[:]
+		}
+		rsp, err := c.Request(OpWrite, GroupImage, ImageUpload, payload)
+		if err != nil {
+			return err
+		}
+		next, ok := toInt(rsp["off"])
+		if !ok || next < 0 || next > int64(len(image)) {
+			return fmt.Errorf("Invalid SMP upload response")
+		}
+		offset = int(next)
+		if progress != nil {
+			progress(offset, len(image))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package mcumgr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// device a fake SMP server, answering the requests once reassembled
+type device struct {
+	t             *testing.T
+	notifications chan []byte
+	buffer        []byte
+	image         []byte
+	// handle return the response of a request
+	handle func(op uint8, group uint16, id uint8, req map[string]interface{}) map[string]interface{}
+}
+
+func newDevice(t *testing.T) *device {
+	return &device{t: t, notifications: make(chan []byte, 64)}
+}
+
+func (d *device) WriteValue(b []byte, options map[string]dbus.Variant) error {
+	if len(b) > DefaultPacketSize {
+		d.t.Fatalf("Write of %d bytes", len(b))
+	}
+	d.buffer = append(d.buffer, b...)
+	if len(d.buffer) < headerSize {
+		return nil
+	}
+	size := headerSize + int(binary.BigEndian.Uint16(d.buffer[2:]))
+	if len(d.buffer) < size {
+		return nil
+	}
+	packet := d.buffer[:size]
+	d.buffer = nil
+
+	value, _, err := cborDecode(packet[headerSize:])
+	if err != nil {
+		d.t.Fatal(err)
+	}
+	group := binary.BigEndian.Uint16(packet[4:])
+	rsp := d.handle(packet[0], group, packet[7], value.(map[string]interface{}))
+	body, err := cborEncode(rsp)
+	if err != nil {
+		d.t.Fatal(err)
+	}
+	r := append(header(packet[0]+1, len(body), group, packet[6], packet[7]), body...)
+	// split the response across notifications
+	for len(r) > 10 {
+		d.notifications <- r[:10]
+		r = r[10:]
+	}
+	d.notifications <- r
+	return nil
+}
+
+func (d *device) Notifications() (<-chan []byte, func(), error) {
+	return d.notifications, func() {}, nil
+}
+
+func TestCBOR(t *testing.T) {
+
+	v := map[string]interface{}{
+		"a": uint64(1000),
+		"b": int64(-25),
+		"c": []byte{1, 2, 3},
+		"d": "text",
+		"e": []interface{}{true, false, nil},
+		"f": map[string]interface{}{"g": uint64(0x1ffffffff)},
+	}
+	b, err := cborEncode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, size, err := cborDecode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != len(b) {
+		t.Fatalf("Decoded %d of %d bytes", size, len(b))
+	}
+	if !reflect.DeepEqual(decoded, v) {
+		t.Fatalf("Decoded %v", decoded)
+	}
+
+	// indefinite length map, as encoded by tinycbor
+	b = []byte{0xbf, 0x62, 'r', 'c', 0x00, 0x61, 'x', 0x5f, 0x41, 1, 0x41, 2, 0xff, 0xff}
+	decoded, _, err = cborDecode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, map[string]interface{}{"rc": uint64(0), "x": []byte{1, 2}}) {
+		t.Fatalf("Decoded %v", decoded)
+	}
+
+	if _, _, err = cborDecode([]byte{0x65, 'a'}); err == nil {
+		t.Fatal("Truncated text decoded")
+	}
+}
+
+func TestEcho(t *testing.T) {
+
+	d := newDevice(t)
+	d.handle = func(op uint8, group uint16, id uint8, req map[string]interface{}) map[string]interface{} {
+		if op != OpWrite || group != GroupOS || id != OSEcho {
+			return map[string]interface{}{"rc": RCNotSupported}
+		}
+		return map[string]interface{}{"r": req["d"]}
+	}
+	c, err := newClient(d, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	s := "a string longer than a single notification"
+	r, err := c.Echo(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != s {
+		t.Fatalf("Echo returned %q", r)
+	}
+
+	err = c.Reset()
+	if e, ok := err.(*ResponseError); !ok || e.RC != RCNotSupported || e.ID != OSReset {
+		t.Fatalf("Expected RCNotSupported, got %v", err)
+	}
+}
+
+func TestImages(t *testing.T) {
+
+	d := newDevice(t)
+	hash := bytes.Repeat([]byte{0xab}, 32)
+	var confirm interface{}
+	d.handle = func(op uint8, group uint16, id uint8, req map[string]interface{}) map[string]interface{} {
+		if op == OpWrite {
+			if !bytes.Equal(req["hash"].([]byte), hash) {
+				return map[string]interface{}{"rc": RCNoEntry}
+			}
+			confirm = req["confirm"]
+		}
+		return map[string]interface{}{
+			"images": []interface{}{
+				map[string]interface{}{
+					"slot":      0,
+					"version":   "1.2.0",
+					"hash":      hash,
+					"bootable":  true,
+					"confirmed": true,
+					"active":    true,
+				},
+			},
+		}
+	}
+	c, err := newClient(d, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	images, err := c.Images()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 || images[0].Version != "1.2.0" || !images[0].Active || !bytes.Equal(images[0].Hash, hash) {
+		t.Fatalf("Unexpected images %v", images)
+	}
+
+	if _, err = c.Test(hash); err != nil {
+		t.Fatal(err)
+	}
+	if confirm != false {
+		t.Fatalf("Test sent confirm %v", confirm)
+	}
+	if _, err = c.Confirm(hash); err != nil {
+		t.Fatal(err)
+	}
+	if confirm != true {
+		t.Fatalf("Confirm sent confirm %v", confirm)
+	}
+	if _, err = c.Confirm([]byte{1}); err == nil {
+		t.Fatal("Unknown image confirmed")
+	}
+}
+
+func TestUpload(t *testing.T) {
+
+	d := newDevice(t)
+	image := make([]byte, 1000)
+	for i := range image {
+		image[i] = byte(i)
+	}
+	d.handle = func(op uint8, group uint16, id uint8, req map[string]interface{}) map[string]interface{} {
+		off, _ := toInt(req["off"])
+		if off == 0 {
+			size, _ := toInt(req["len"])
+			d.image = make([]byte, 0, size)
+			if len(req["sha"].([]byte)) != 32 {
+				return map[string]interface{}{"rc": RCInvalid}
+			}
+		}
+		if int(off) != len(d.image) {
+			return map[string]interface{}{"rc": RCInvalid}
+		}
+		d.image = append(d.image, req["data"].([]byte)...)
+		return map[string]interface{}{"rc": 0, "off": len(d.image)}
+	}
+	c, err := newClient(d, Options{ChunkSize: 300, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	calls := 0
+	err = c.Upload(image, func(sent, total int) {
+		calls++
+		if total != len(image) {
+			t.Fatalf("Unexpected total %d", total)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 {
+		t.Fatalf("Expected 4 chunks, got %d", calls)
+	}
+	if !bytes.Equal(d.image, image) {
+		t.Fatal("Image mismatch")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
 	"github.com/muka/go-bluetooth/api"
 	"github.com/muka/go-bluetooth/bluez/profile"
 	"github.com/muka/go-bluetooth/emitter"
@@ -136,7 +137,7 @@ func getDeviceInfoUUID(name string) string {
 	if sensorTagUUIDs[name] == "" {
 		panic("Not found " + name)
 	}
-	return "0000" + sensorTagUUIDs[name] + "-0000-1000-8000-00805F9B34FB"
+	return bluetooth.MustParseUUID(sensorTagUUIDs[name]).String()
 }
 
 //retryCall n. times, sleep millis, callback
@@ -0,0 +1,58 @@
+//shows how to receive A2DP audio from a phone, printing the SBC stream parameters
+package main
+
+import (
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/bluez/profile/a2dp"
+	"github.com/muka/go-bluetooth/service"
+)
+
+const endpointPath = "/org/bluez/example/a2dp_sink"
+const adapterID = "hci0"
+
+func main() {
+
+	log.SetLevel(log.DebugLevel)
+
+	endpoint, err := service.NewA2DPSinkEndpoint(endpointPath, func(c *service.MediaConfiguration) {
+
+		config, err := a2dp.ParseSBCCapabilities(c.Configuration)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		log.Infof("Configured %s: %dHz %d channels", c.Device, config.SampleRate(), config.Channels())
+
+		stream, err := a2dp.AcquireWhenPending(c.MediaTransport())
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		defer stream.Close()
+
+		for {
+			packet, err := stream.ReadPacket()
+			if err != nil {
+				log.Infof("Stream closed: %s", err)
+				return
+			}
+			log.Debugf("Packet %d: %d SBC frames", packet.SequenceNumber, len(packet.Frames))
+		}
+	})
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	err = endpoint.Register(adapterID)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	defer endpoint.Unregister()
+
+	log.Info("A2DP sink registered, connect a phone and start playing")
+	select {}
+}
@@ -0,0 +1,103 @@
+//shows how to stream an SBC file, eg. encoded with sbcenc, to a speaker
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/bluez/profile/a2dp"
+	"github.com/muka/go-bluetooth/service"
+)
+
+const endpointPath = "/org/bluez/example/a2dp_source"
+const adapterID = "hci0"
+
+func main() {
+
+	log.SetLevel(log.DebugLevel)
+
+	if len(os.Args) < 2 {
+		log.Errorf("Usage: %s file.sbc", os.Args[0])
+		os.Exit(1)
+	}
+	filename := os.Args[1]
+
+	endpoint, err := service.NewA2DPSourceEndpoint(endpointPath, func(c *service.MediaConfiguration) {
+
+		config, err := a2dp.ParseSBCCapabilities(c.Configuration)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		log.Infof("Configured %s: %dHz, recommended bitpool %d", c.Device, config.SampleRate(), config.Bitpool(a2dp.SBCQualityHigh))
+
+		stream, err := a2dp.Acquire(c.MediaTransport())
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		defer stream.Close()
+
+		err = play(stream, filename)
+		if err != nil {
+			log.Error(err)
+		}
+	})
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	err = endpoint.Register(adapterID)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	defer endpoint.Unregister()
+
+	log.Info("A2DP source registered, connect a speaker to start streaming")
+	select {}
+}
+
+func play(stream *a2dp.Stream, filename string) error {
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := a2dp.NewSBCFrameReader(f)
+	start := time.Now()
+	var played time.Duration
+
+	for {
+		frame, h, err := reader.ReadFrame()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		frames := [][]byte{frame}
+		for len(frames) < a2dp.FramesPerPacket(len(frame), stream.WriteMTU) {
+			next, _, err := reader.ReadFrame()
+			if err != nil {
+				break
+			}
+			frames = append(frames, next)
+		}
+
+		err = stream.WriteFrames(frames)
+		if err != nil {
+			return err
+		}
+
+		// pace the stream in real time
+		played += time.Duration(h.Samples()*len(frames)) * time.Second / time.Duration(h.SampleRate)
+		time.Sleep(played - time.Since(start))
+	}
+}
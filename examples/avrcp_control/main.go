@@ -0,0 +1,47 @@
+//shows how to control the playback of a connected phone and follow track changes
+package main
+
+import (
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+func main() {
+
+	log.SetLevel(log.DebugLevel)
+
+	if len(os.Args) < 2 {
+		log.Errorf("Usage: %s /org/bluez/hci0/dev_XX_XX_XX_XX_XX_XX", os.Args[0])
+		os.Exit(1)
+	}
+
+	control := profile.NewMediaControl1(os.Args[1])
+	if !control.Properties.Connected || control.Properties.Player == "" {
+		log.Error("No media player available on the device")
+		os.Exit(1)
+	}
+
+	player := profile.NewMediaPlayer1(string(control.Properties.Player))
+	track := player.Properties.GetTrack()
+	log.Infof("%s: %s - %s", player.Properties.Status, track.Artist, track.Title)
+
+	err := control.Play()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	changes, stop, err := player.Watch()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	defer stop()
+
+	for props := range changes {
+		track := props.GetTrack()
+		log.Infof("%s: %s - %s (%d/%dms)", props.Status, track.Artist, track.Title, props.Position, track.Duration)
+	}
+}
@@ -0,0 +1,109 @@
+//shows how to act as a Hands-Free audio gateway, ringing the connected
+// hands-free unit until the call is answered
+package main
+
+import (
+	"os"
+	"os/signal"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/bluez/profile/hfp"
+	"github.com/muka/go-bluetooth/linux"
+	"github.com/muka/go-bluetooth/service"
+)
+
+const profilePath = "/org/bluez/example/hfp_ag"
+
+func main() {
+
+	log.SetLevel(log.DebugLevel)
+
+	features := hfp.GatewayDefaultFeatures
+
+	ag, err := service.NewHandsfreeAudioGatewayProfile1(profilePath, 0, hfp.GatewaySDPFeatures(features), func(c *service.ProfileConnection) {
+		defer c.Conn.Close()
+		log.Infof("Connected %s", c.Conn.RemoteAddr())
+
+		answered := make(chan bool, 1)
+		gw := hfp.NewGateway(c.Conn, hfp.GatewayConfig{
+			Features: features,
+			OnConnected: func() {
+				log.Info("Service level connection established")
+			},
+			OnAnswer: func() error {
+				log.Info("Call answered")
+				answered <- true
+				return nil
+			},
+			OnHangup: func() error {
+				log.Info("Call terminated")
+				return nil
+			},
+			OnSpeakerVolume: func(volume int) {
+				log.Infof("Speaker volume %d", volume)
+			},
+		})
+
+		go ring(gw, c.Conn.RemoteAddr().(*linux.BtAddr).Address, answered)
+
+		err := gw.Serve()
+		if err != nil {
+			log.Error(err)
+		}
+		log.Infof("Disconnected %s", c.Conn.RemoteAddr())
+	})
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	err = ag.Register()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	defer ag.Unregister()
+
+	log.Info("Hands-Free gateway registered, connect a headset")
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+}
+
+// ring wait for the service level connection, then ring until the call is
+// answered, then open the audio link
+func ring(gw *hfp.Gateway, address string, answered chan bool) {
+
+	for !gw.Connected() {
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+	for {
+		gw.Ring("0123456789")
+		select {
+		case <-answered:
+			sco, err := linux.DialSCO(address, 5*time.Second)
+			if err != nil {
+				log.Errorf("Audio connection failed: %s", err)
+				return
+			}
+			defer sco.Close()
+			log.Infof("Audio connected, MTU %d", sco.MTU)
+
+			// loop the microphone back to the speaker
+			buf := make([]byte, sco.MTU)
+			for {
+				n, err := sco.Read(buf)
+				if err != nil {
+					return
+				}
+				sco.Write(buf[:n])
+			}
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,108 @@
+//shows how to run a mesh node with a Generic OnOff server, waiting to be
+// provisioned on the first run and attaching to the network afterwards
+package main
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/bluez/profile/mesh"
+)
+
+const appPath = "/org/bluez/example/mesh"
+const tokenFile = "mesh-token"
+
+// the device uuid advertised while waiting to be provisioned
+const deviceUUID = "b7e2ab3ea6cb4f2a8d4c1f0b94f2e5d1"
+
+func main() {
+
+	log.SetLevel(log.DebugLevel)
+
+	app, err := mesh.NewApplication(&mesh.ApplicationConfig{
+		ObjectPath: appPath,
+		CompanyID:  0x05f1,
+		ProductID:  0x0001,
+		VersionID:  0x0001,
+	})
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	defer app.Close()
+
+	element, err := app.AddElement(0)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	_, err = element.AddModel(mesh.GenericOnOffServer)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	events, stop := element.Events(
+		mesh.OpGenericOnOffGet,
+		mesh.OpGenericOnOffSet,
+		mesh.OpGenericOnOffSetUnacknowledged,
+	)
+	defer stop()
+
+	go func() {
+		state := byte(0)
+		for ev := range events {
+			if ev.Opcode != mesh.OpGenericOnOffGet && len(ev.Params) > 0 {
+				state = ev.Params[0]
+				log.Infof("OnOff set to %d by %04x", state, ev.Source)
+			}
+			if ev.Opcode == mesh.OpGenericOnOffSetUnacknowledged {
+				continue
+			}
+			status := mesh.EncodeMessage(mesh.OpGenericOnOffStatus, []byte{state})
+			err := element.Send(ev.Source, ev.KeyIndex, status)
+			if err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	token, err := loadToken()
+	if err != nil {
+		var uuid [16]byte
+		raw, _ := hex.DecodeString(deviceUUID)
+		copy(uuid[:], raw)
+
+		log.Infof("Waiting to be provisioned, device uuid %s", deviceUUID)
+		token, err = app.Join(uuid)
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		ioutil.WriteFile(tokenFile, []byte(strconv.FormatUint(token, 16)), 0600)
+	}
+
+	err = app.Attach(token)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	log.Infof("Attached, addresses %v", app.Node().Properties.Addresses)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+}
+
+func loadToken() (uint64, error) {
+	raw, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(raw)), 16, 64)
+}
@@ -0,0 +1,127 @@
+//shows how to create a mesh network and provision the first unprovisioned
+// device found, the OOB number is read from the console. The composition
+// data of the new node is read once provisioned
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/bluez/profile/mesh"
+)
+
+const appPath = "/org/bluez/example/provisioner"
+const tokenFile = "mesh-provisioner-token"
+const networkUUID = "3d9a1fa2c2e84c7e9f6a59e11d47f0c3"
+
+func main() {
+
+	log.SetLevel(log.DebugLevel)
+
+	found := make(chan [16]byte, 1)
+
+	app, err := mesh.NewApplication(&mesh.ApplicationConfig{
+		ObjectPath: appPath,
+		CompanyID:  0x05f1,
+		ProductID:  0x0002,
+		VersionID:  0x0001,
+		Agent: &mesh.AgentConfig{
+			Capabilities: []string{mesh.AgentOutNumeric, mesh.AgentInNumeric},
+			OnDisplayNumeric: func(action string, number uint32) error {
+				log.Infof("Enter %d on the device", number)
+				return nil
+			},
+			OnPromptNumeric: func(action string) (uint32, error) {
+				var number uint32
+				fmt.Printf("Number shown by the device (%s): ", action)
+				_, err := fmt.Scanln(&number)
+				return number, err
+			},
+		},
+		Provisioner: &mesh.ProvisionerConfig{
+			OnScanResult: func(device *mesh.UnprovisionedDevice) {
+				log.Infof("Found %x rssi %d", device.UUID, device.RSSI)
+				select {
+				case found <- device.UUID:
+				default:
+				}
+			},
+		},
+	})
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	defer app.Close()
+
+	element, err := app.AddElement(0)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	config := mesh.NewConfigurator(element, 0)
+	element.OnMessage(func(msg *mesh.Message) {
+		if !config.Handle(msg) {
+			log.Debugf("Message from %04x: %x", msg.Source, msg.Data)
+		}
+	})
+
+	token, err := loadToken()
+	if err != nil {
+		var uuid [16]byte
+		raw, _ := hex.DecodeString(networkUUID)
+		copy(uuid[:], raw)
+
+		token, err = app.CreateNetwork(uuid)
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		ioutil.WriteFile(tokenFile, []byte(strconv.FormatUint(token, 16)), 0600)
+	}
+
+	err = app.Attach(token)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	err = app.ScanUnprovisioned(30)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	uuid := <-found
+	app.StopScanUnprovisioned()
+
+	unicast, count, err := app.Provision(uuid)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	log.Infof("Provisioned %x at %04x with %d elements", uuid, unicast, count)
+
+	composition, err := config.CompositionData(unicast, 0)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	for i, e := range composition.Elements {
+		log.Infof("Element %d: models %04x vendor models %v", i, e.Models, e.VendorModels)
+	}
+}
+
+func loadToken() (uint64, error) {
+	raw, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(raw)), 16, 64)
+}
@@ -12,8 +12,6 @@ package main
 
 import (
 	"os"
-	"sync"
-	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/muka/go-bluetooth/api"
@@ -66,79 +64,19 @@ func SendFile(targetAddress string, filePath string) {
 		log.Debug("already paired")
 	}
 
-	sessionArgs := map[string]interface{}{}
-	sessionArgs["Target"] = "opp"
-
-	obexClient := obex.NewObexClient1()
-
-	tries := 1
-	maxRetry := 20
-	var sessionPath string
-	for tries < maxRetry {
-		log.Debug("Create Session...")
-		sessionPath, err = obexClient.CreateSession(targetAddress, sessionArgs)
-		if err == nil {
-			break
-		}
-
-		tries++
-
-		if err != nil {
-			log.Error(err)
-		}
-	}
-	if tries >= maxRetry {
-		log.Fatal("Max tries reached")
-	}
-
-	log.Debug("Session created: ", sessionPath)
-
-	obexSession := obex.NewObexSession1(sessionPath)
-	sessionProps, err := obexSession.GetProperties()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	log.Debug("Source		: ", sessionProps.Source)
-	log.Debug("Destination	: ", sessionProps.Destination)
-	log.Debug("Channel		: ", sessionProps.Channel)
-	log.Debug("Target		: ", sessionProps.Target)
-	log.Debug("Root			: ", sessionProps.Root)
-
-	log.Debug("Init transmission on ", sessionPath)
-	obexObjectPush := obex.NewObjectPush1(sessionPath)
 	log.Debug("Send File: ", filePath)
-	transPath, transProps, err := obexObjectPush.SendFile(filePath)
+	progress, err := obex.PushFile(targetAddress, filePath)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Debug("Transmission initiated: ", transPath)
-	log.Debug("Status      : ", transProps.Status)
-	log.Debug("Session     : ", transProps.Session)
-	log.Debug("Name        : ", transProps.Name)
-	log.Debug("Type        : ", transProps.Type)
-	log.Debug("Time        : ", transProps.Time)
-	log.Debug("Size        : ", transProps.Size)
-	log.Debug("Transferred : ", transProps.Transferred)
-	log.Debug("Filename    : ", transProps.Filename)
-
-	for transProps.Transferred < transProps.Size {
-		time.Sleep(1 * time.Second)
-
-		obexTransfer := obex.NewObexTransfer1(transPath)
-		transProps, err = obexTransfer.GetProperties()
-		if err != nil {
-			log.Fatal(err)
+	for p := range progress {
+		log.Debug("Status      : ", p.Status)
+		log.Debug("Progress    : ", p.Percent())
+		if p.Err != nil {
+			log.Fatal(p.Err)
 		}
-		transferedPercent := (100 / float64(transProps.Size)) * float64(transProps.Transferred)
-
-		log.Debug("Progress    : ", transferedPercent)
 	}
 
-	obexClient.RemoveSession(sessionPath)
-	log.Debug(sessionPath)
-
+	log.Debug("Transfer completed")
 }
-
-var wg sync.WaitGroup
@@ -0,0 +1,53 @@
+//shows how to connect to the NAP of a phone, or to serve a NAP over a bridge
+package main
+
+import (
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/linux"
+)
+
+const adapterID = "hci0"
+const bridgeName = "pan0"
+
+func main() {
+
+	log.SetLevel(log.DebugLevel)
+
+	if len(os.Args) > 1 {
+		// connect to the phone NAP, then run a dhcp client on the interface
+		network := profile.NewNetwork1(os.Args[1])
+		iface, err := network.Connect(profile.NetworkRoleNAP)
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		log.Infof("Connected on %s", iface)
+		return
+	}
+
+	bridge := linux.NewBridge(bridgeName)
+	err := bridge.Create()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	err = bridge.AddAddress("192.168.44.1/24")
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	server := profile.NewNetworkServer1(adapterID)
+	err = server.Register(profile.NetworkRoleNAP, bridgeName)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	defer server.Unregister(profile.NetworkRoleNAP)
+
+	log.Infof("NAP registered on %s, run a dhcp server on the bridge", bridgeName)
+	select {}
+}
@@ -0,0 +1,49 @@
+//shows how to run a Serial Port Profile server echoing back received data
+package main
+
+import (
+	"bufio"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/muka/go-bluetooth/service"
+)
+
+const profilePath = "/org/bluez/example/spp"
+const channel = 22
+
+func main() {
+
+	log.SetLevel(log.DebugLevel)
+
+	spp, err := service.NewSerialPortProfile1(profilePath, channel, func(c *service.ProfileConnection) {
+		log.Infof("Connected %s", c.Conn.RemoteAddr())
+		defer c.Conn.Close()
+
+		scanner := bufio.NewScanner(c.Conn)
+		for scanner.Scan() {
+			line := scanner.Text()
+			log.Debugf("Received: %s", line)
+			_, err := c.Conn.Write([]byte(line + "\n"))
+			if err != nil {
+				log.Error(err)
+				return
+			}
+		}
+		log.Infof("Disconnected %s", c.Conn.RemoteAddr())
+	})
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	err = spp.Register()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	defer spp.Unregister()
+
+	log.Info("Serial Port Profile registered, waiting for connections")
+	select {}
+}
@@ -0,0 +1,177 @@
+//Command gen generate the assigned numbers tables of the bluetooth package
+// from the YAML files of the Bluetooth SIG public repository, see
+//
+//	https://bitbucket.org/bluetooth-SIG/public
+//
+// The tables are written to assigned_gen.go, see assigned.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// tables the variables generated and the file they are read from, relative
+// to the root of the repository
+var tables = []struct {
+	Name string
+	File string
+}{
+	{"declarationNames", "assigned_numbers/uuids/declarations.yaml"},
+	{"serviceNames", "assigned_numbers/uuids/service_uuids.yaml"},
+	{"characteristicNames", "assigned_numbers/uuids/characteristic_uuids.yaml"},
+	{"descriptorNames", "assigned_numbers/uuids/descriptors.yaml"},
+	{"memberNames", "assigned_numbers/uuids/member_uuids.yaml"},
+	{"companyNames", "assigned_numbers/company_identifiers/company_identifiers.yaml"},
+}
+
+func main() {
+
+	out := flag.String("out", "assigned_gen.go", "the generated file")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: gen [-out assigned_gen.go] public-repository-dir")
+		os.Exit(2)
+	}
+
+	list := []Table{}
+	for _, t := range tables {
+		f, err := os.Open(filepath.Join(flag.Arg(0), t.File))
+		if err != nil {
+			fail(err)
+		}
+		entries, err := Parse(f)
+		f.Close()
+		if err != nil {
+			fail(fmt.Errorf("%s: %s", t.File, err))
+		}
+		list = append(list, Table{Name: t.Name, File: t.File, Entries: entries})
+	}
+
+	src, err := Generate(list)
+	if err != nil {
+		fail(err)
+	}
+	if err = ioutil.WriteFile(*out, src, 0644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "gen:", err)
+	os.Exit(1)
+}
+
+//Entry an assigned number and its name
+type Entry struct {
+	Value uint16
+	Name  string
+}
+
+//Table the entries of a file
+type Table struct {
+	Name    string
+	File    string
+	Entries []Entry
+}
+
+//Parse read the entries of a file of the assigned numbers. The files are
+// lists of the form
+//
+//	uuids:
+//	  - uuid: 0x180F
+//	    name: Battery
+//	    id: org.bluetooth.service.battery_service
+//
+// the company identifiers have a value instead of the uuid
+func Parse(r io.Reader) ([]Entry, error) {
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		s := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(s, "- ") {
+			key, value := field(s[2:])
+			if key != "uuid" && key != "value" {
+				continue
+			}
+			v, err := strconv.ParseUint(value, 0, 16)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid value %s", line, value)
+			}
+			entries = append(entries, Entry{Value: uint16(v)})
+			continue
+		}
+		if key, value := field(s); key == "name" && len(entries) > 0 {
+			entries[len(entries)-1].Name = unquote(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if e.Name == "" {
+			return nil, fmt.Errorf("missing name of 0x%04X", e.Value)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Value < entries[j].Value })
+	return entries, nil
+}
+
+func field(s string) (string, string) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return "", ""
+	}
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+}
+
+// unquote remove the YAML quotes, a quote is doubled in a single quoted
+// string, eg. 'Bang & Olufsen''s'
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
+
+//Generate the source of the tables
+func Generate(list []Table) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := tablesTemplate.Execute(buf, list); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+var tablesTemplate = template.Must(template.New("tables").Parse(`// Code generated by gen from the assigned numbers of the Bluetooth SIG. DO NOT EDIT.
+
+package bluetooth
+{{range .}}
+// {{.Name}} from {{.File}}
+var {{.Name}} = map[uint16]string{
+{{- range .Entries}}
+	{{printf "0x%04X" .Value}}: {{printf "%q" .Name}},
+{{- end}}
+}
+{{end}}`))
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+
+	entries, err := Parse(strings.NewReader(`company_identifiers:
+  - value: 0x004C
+    name: 'Apple, Inc.'
+  - value: 0x0000
+    name: Ericsson AB
+  - value: 0x0171
+    name: 'Amazon.com Services LLC''s'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Entry{{0x0000, "Ericsson AB"}, {0x004C, "Apple, Inc."}, {0x0171, "Amazon.com Services LLC's"}}
+	if len(entries) != len(expected) {
+		t.Fatalf("Unexpected entries %v", entries)
+	}
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Fatalf("Unexpected entry %v, expected %v", entries[i], expected[i])
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("uuids:\n  - uuid: 0x180F\n")); err == nil {
+		t.Fatal("Expected a missing name")
+	}
+	if _, err := Parse(strings.NewReader("uuids:\n  - uuid: 0x1800F\n    name: x\n")); err == nil {
+		t.Fatal("Expected an invalid value")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate([]Table{{Name: "serviceNames", File: "service_uuids.yaml", Entries: []Entry{{0x180F, "Battery Service"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "\t0x180F: \"Battery Service\",\n") {
+		t.Fatalf("Unexpected source\n%s", src)
+	}
+}
@@ -0,0 +1,387 @@
+//Package hid build the HID report descriptors, as used by the Report Map
+// characteristic of HID over GATT or by a UHID device
+package hid
+
+import (
+	"fmt"
+	"sort"
+)
+
+//UsagePage a HID usage page
+type UsagePage uint16
+
+// Usage pages
+const (
+	PageGenericDesktop UsagePage = 0x01
+	PageSimulation     UsagePage = 0x02
+	PageKeyboard       UsagePage = 0x07
+	PageLED            UsagePage = 0x08
+	PageButton         UsagePage = 0x09
+	PageConsumer       UsagePage = 0x0C
+	PageDigitizer      UsagePage = 0x0D
+	PageVendor         UsagePage = 0xFF00
+)
+
+//Usage a usage of a page
+type Usage uint16
+
+// Usages of the generic desktop page
+const (
+	UsagePointer   Usage = 0x01
+	UsageMouse     Usage = 0x02
+	UsageJoystick  Usage = 0x04
+	UsageGamepad   Usage = 0x05
+	UsageKeyboard  Usage = 0x06
+	UsageKeypad    Usage = 0x07
+	UsageX         Usage = 0x30
+	UsageY         Usage = 0x31
+	UsageZ         Usage = 0x32
+	UsageRx        Usage = 0x33
+	UsageRy        Usage = 0x34
+	UsageRz        Usage = 0x35
+	UsageWheel     Usage = 0x38
+	UsageHatSwitch Usage = 0x39
+)
+
+// Usages of the consumer page
+const (
+	UsageConsumerControl Usage = 0x01
+	UsagePlayPause       Usage = 0xCD
+	UsageMute            Usage = 0xE2
+	UsageVolumeUp        Usage = 0xE9
+	UsageVolumeDown      Usage = 0xEA
+)
+
+//CollectionType the type of a collection
+type CollectionType uint8
+
+// Collection types
+const (
+	CollectionPhysical    CollectionType = 0x00
+	CollectionApplication CollectionType = 0x01
+	CollectionLogical     CollectionType = 0x02
+	CollectionReport      CollectionType = 0x03
+)
+
+//Flags the flags of the Input, Output and Feature items, the zero value is
+// data, array, absolute
+type Flags uint8
+
+// Flags of the main items
+const (
+	Constant Flags = 1 << 0
+	Variable Flags = 1 << 1
+	Relative Flags = 1 << 2
+	Wrap     Flags = 1 << 3
+	Nullable Flags = 1 << 6
+)
+
+//ReportType the type of a report
+type ReportType uint8
+
+// Report types, with the values of the Report Reference descriptor of HID
+// over GATT
+const (
+	Input   ReportType = 0x01
+	Output  ReportType = 0x02
+	Feature ReportType = 0x03
+)
+
+func (t ReportType) String() string {
+	switch t {
+	case Input:
+		return "input"
+	case Output:
+		return "output"
+	case Feature:
+		return "feature"
+	}
+	return fmt.Sprintf("report type %d", uint8(t))
+}
+
+// Item tags, with the item type
+const (
+	tagInput           = 0x80
+	tagOutput          = 0x90
+	tagCollection      = 0xA0
+	tagFeature         = 0xB0
+	tagEndCollection   = 0xC0
+	tagUsagePage       = 0x04
+	tagLogicalMinimum  = 0x14
+	tagLogicalMaximum  = 0x24
+	tagPhysicalMinimum = 0x34
+	tagPhysicalMaximum = 0x44
+	tagUnitExponent    = 0x54
+	tagUnit            = 0x64
+	tagReportSize      = 0x74
+	tagReportID        = 0x84
+	tagReportCount     = 0x94
+	tagUsage           = 0x08
+	tagUsageMinimum    = 0x18
+	tagUsageMaximum    = 0x28
+)
+
+//Report a report of a descriptor
+type Report struct {
+	// ID zero if the descriptor has no report IDs
+	ID   uint8
+	Type ReportType
+	// Size in bytes, without the report ID
+	Size int
+}
+
+type reportKey struct {
+	id  uint8
+	typ ReportType
+}
+
+//Descriptor a report descriptor builder. The calls can be chained, the
+// descriptor is validated by Build:
+//
+//  b, err := hid.New().UsagePage(hid.PageGenericDesktop).Usage(hid.UsageMouse).
+//      Collection(hid.CollectionApplication).
+//      ...
+//      EndCollection().Build()
+type Descriptor struct {
+	b     []byte
+	items int
+	err   error
+
+	// the global and local state
+	page        bool
+	size        uint32
+	count       uint32
+	logicalMin  int32
+	logicalMax  int32
+	id          uint8
+	ids         bool
+	unnumbered  bool
+	depth       int
+	reportsBits map[reportKey]uint32
+}
+
+//New create an empty descriptor
+func New() *Descriptor {
+	return &Descriptor{reportsBits: make(map[reportKey]uint32)}
+}
+
+// fail record the first validation error
+func (d *Descriptor) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("hid: item %d: %s", d.items, fmt.Sprintf(format, args...))
+	}
+}
+
+// unsigned add a short item with the smallest unsigned encoding of v
+func (d *Descriptor) unsigned(tag byte, v uint32) *Descriptor {
+	d.items++
+	switch {
+	case v <= 0xff:
+		d.b = append(d.b, tag|1, byte(v))
+	case v <= 0xffff:
+		d.b = append(d.b, tag|2, byte(v), byte(v>>8))
+	default:
+		d.b = append(d.b, tag|3, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+	}
+	return d
+}
+
+// signed add a short item with the smallest signed encoding of v
+func (d *Descriptor) signed(tag byte, v int32) *Descriptor {
+	d.items++
+	switch {
+	case v >= -0x80 && v <= 0x7f:
+		d.b = append(d.b, tag|1, byte(v))
+	case v >= -0x8000 && v <= 0x7fff:
+		d.b = append(d.b, tag|2, byte(v), byte(v>>8))
+	default:
+		d.b = append(d.b, tag|3, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+	}
+	return d
+}
+
+//UsagePage set the usage page of the next usages
+func (d *Descriptor) UsagePage(page UsagePage) *Descriptor {
+	d.page = true
+	return d.unsigned(tagUsagePage, uint32(page))
+}
+
+//Usage add a usage of the current page
+func (d *Descriptor) Usage(usage Usage) *Descriptor {
+	if !d.page {
+		d.fail("Usage before UsagePage")
+	}
+	return d.unsigned(tagUsage, uint32(usage))
+}
+
+//UsageRange add the usages from min to max
+func (d *Descriptor) UsageRange(min Usage, max Usage) *Descriptor {
+	if !d.page {
+		d.fail("UsageRange before UsagePage")
+	}
+	if min > max {
+		d.fail("invalid usage range %d-%d", min, max)
+	}
+	return d.unsigned(tagUsageMinimum, uint32(min)).unsigned(tagUsageMaximum, uint32(max))
+}
+
+//Logical set the range of the values of the next fields
+func (d *Descriptor) Logical(min int32, max int32) *Descriptor {
+	if min > max {
+		d.fail("invalid logical range %d-%d", min, max)
+	}
+	d.logicalMin, d.logicalMax = min, max
+	return d.signed(tagLogicalMinimum, min).signed(tagLogicalMaximum, max)
+}
+
+//Physical set the physical range of the next fields
+func (d *Descriptor) Physical(min int32, max int32) *Descriptor {
+	if min > max {
+		d.fail("invalid physical range %d-%d", min, max)
+	}
+	return d.signed(tagPhysicalMinimum, min).signed(tagPhysicalMaximum, max)
+}
+
+//Unit set the unit of the next fields, as a HID unit code, and its exponent
+func (d *Descriptor) Unit(unit uint32, exponent int8) *Descriptor {
+	if exponent < -8 || exponent > 7 {
+		d.fail("invalid unit exponent %d", exponent)
+	}
+	return d.unsigned(tagUnit, unit).unsigned(tagUnitExponent, uint32(exponent)&0x0f)
+}
+
+//ReportID set the ID of the next fields. Either all the fields or none have
+// an ID
+func (d *Descriptor) ReportID(id uint8) *Descriptor {
+	if id == 0 {
+		d.fail("report ID 0 is reserved")
+	}
+	if d.unnumbered {
+		d.fail("ReportID after fields without report ID")
+	}
+	d.id = id
+	d.ids = true
+	return d.unsigned(tagReportID, uint32(id))
+}
+
+//ReportSize set the size in bits of the next fields
+func (d *Descriptor) ReportSize(bits uint32) *Descriptor {
+	if bits == 0 {
+		d.fail("ReportSize 0")
+	}
+	d.size = bits
+	return d.unsigned(tagReportSize, bits)
+}
+
+//ReportCount set the number of the next fields
+func (d *Descriptor) ReportCount(count uint32) *Descriptor {
+	if count == 0 {
+		d.fail("ReportCount 0")
+	}
+	d.count = count
+	return d.unsigned(tagReportCount, count)
+}
+
+// main add an Input, Output or Feature item
+func (d *Descriptor) main(tag byte, typ ReportType, flags Flags) *Descriptor {
+	if d.size == 0 || d.count == 0 {
+		d.fail("%s item without ReportSize and ReportCount", typ)
+	}
+	if flags&Constant == 0 && !d.page {
+		d.fail("%s item without UsagePage", typ)
+	}
+	if d.size < 32 && flags&Constant == 0 {
+		// the values must fit the fields, signed if the minimum is negative
+		max := int64(1)<<d.size - 1
+		min := int64(0)
+		if d.logicalMin < 0 {
+			max = int64(1)<<(d.size-1) - 1
+			min = -max - 1
+		}
+		if int64(d.logicalMin) < min || int64(d.logicalMax) > max {
+			d.fail("logical range %d-%d does not fit %d bits", d.logicalMin, d.logicalMax, d.size)
+		}
+	}
+	if !d.ids {
+		d.unnumbered = true
+	}
+	d.reportsBits[reportKey{id: d.id, typ: typ}] += d.size * d.count
+	return d.unsigned(tag, uint32(flags))
+}
+
+//Input add input fields
+func (d *Descriptor) Input(flags Flags) *Descriptor {
+	return d.main(tagInput, Input, flags)
+}
+
+//Output add output fields
+func (d *Descriptor) Output(flags Flags) *Descriptor {
+	return d.main(tagOutput, Output, flags)
+}
+
+//Feature add feature fields
+func (d *Descriptor) Feature(flags Flags) *Descriptor {
+	return d.main(tagFeature, Feature, flags)
+}
+
+//Collection open a collection, closed by EndCollection
+func (d *Descriptor) Collection(kind CollectionType) *Descriptor {
+	d.depth++
+	return d.unsigned(tagCollection, uint32(kind))
+}
+
+//EndCollection close the last collection
+func (d *Descriptor) EndCollection() *Descriptor {
+	if d.depth == 0 {
+		d.fail("EndCollection without Collection")
+	} else {
+		d.depth--
+	}
+	d.items++
+	d.b = append(d.b, tagEndCollection)
+	return d
+}
+
+//Reports return the reports defined so far, sorted by ID and type
+func (d *Descriptor) Reports() []Report {
+	reports := make([]Report, 0, len(d.reportsBits))
+	for key, bits := range d.reportsBits {
+		reports = append(reports, Report{ID: key.id, Type: key.typ, Size: int((bits + 7) / 8)})
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].ID != reports[j].ID {
+			return reports[i].ID < reports[j].ID
+		}
+		return reports[i].Type < reports[j].Type
+	})
+	return reports
+}
+
+//Build validate the descriptor and return its bytes
+func (d *Descriptor) Build() ([]byte, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.b) == 0 {
+		return nil, fmt.Errorf("hid: empty descriptor")
+	}
+	if d.depth != 0 {
+		return nil, fmt.Errorf("hid: %d collections not closed", d.depth)
+	}
+	for key, bits := range d.reportsBits {
+		if bits%8 != 0 {
+			return nil, fmt.Errorf("hid: %s report %d is %d bits, add padding to a whole byte", key.typ, key.id, bits)
+		}
+	}
+	return append([]byte{}, d.b...), nil
+}
+
+//MustBuild return the bytes of a valid descriptor, it panics on validation
+// errors. For the descriptors defined by the program
+func (d *Descriptor) MustBuild() []byte {
+	b, err := d.Build()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
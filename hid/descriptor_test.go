@@ -0,0 +1,86 @@
+package hid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func mouse() *Descriptor {
+	return New().
+		UsagePage(PageGenericDesktop).Usage(UsageMouse).
+		Collection(CollectionApplication).
+		ReportID(1).
+		UsagePage(PageButton).UsageRange(1, 3).Logical(0, 1).
+		ReportSize(1).ReportCount(3).Input(Variable).
+		ReportSize(5).ReportCount(1).Input(Constant).
+		UsagePage(PageGenericDesktop).Usage(UsageX).Usage(UsageY).
+		Logical(-127, 127).ReportSize(8).ReportCount(2).Input(Variable | Relative).
+		EndCollection()
+}
+
+func TestBuild(t *testing.T) {
+
+	b, err := mouse().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x05, 0x01, 0x09, 0x02, 0xa1, 0x01, 0x85, 0x01,
+		0x05, 0x09, 0x19, 0x01, 0x29, 0x03, 0x15, 0x00, 0x25, 0x01,
+		0x75, 0x01, 0x95, 0x03, 0x81, 0x02,
+		0x75, 0x05, 0x95, 0x01, 0x81, 0x01,
+		0x05, 0x01, 0x09, 0x30, 0x09, 0x31, 0x15, 0x81, 0x25, 0x7f,
+		0x75, 0x08, 0x95, 0x02, 0x81, 0x06,
+		0xc0,
+	}
+	if !bytes.Equal(b, expected) {
+		t.Fatalf("Unexpected descriptor %x", b)
+	}
+
+	reports := mouse().Reports()
+	if len(reports) != 1 || reports[0] != (Report{ID: 1, Type: Input, Size: 3}) {
+		t.Fatalf("Unexpected reports %+v", reports)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	b := New().UsagePage(PageVendor).Usage(0x01).
+		Collection(CollectionApplication).
+		Logical(-1000, 100000).Unit(0x1001, -2).
+		ReportSize(32).ReportCount(1).Feature(Variable).
+		EndCollection().MustBuild()
+	expected := []byte{
+		0x06, 0x00, 0xff, 0x09, 0x01, 0xa1, 0x01,
+		0x16, 0x18, 0xfc, 0x27, 0xa0, 0x86, 0x01, 0x00,
+		0x66, 0x01, 0x10, 0x55, 0x0e,
+		0x75, 0x20, 0x95, 0x01, 0xb1, 0x02, 0xc0,
+	}
+	if !bytes.Equal(b, expected) {
+		t.Fatalf("Unexpected descriptor %x", b)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	cases := []struct {
+		d   *Descriptor
+		err string
+	}{
+		{New(), "empty"},
+		{New().Usage(UsageMouse), "before UsagePage"},
+		{New().UsagePage(PageButton).Collection(CollectionApplication), "not closed"},
+		{New().EndCollection(), "without Collection"},
+		{New().UsagePage(PageButton).Input(Variable), "without ReportSize"},
+		{New().UsagePage(PageButton).Logical(0, 1).ReportSize(1).ReportCount(3).Input(Variable), "whole byte"},
+		{New().UsagePage(PageButton).Logical(0, 300).ReportSize(8).ReportCount(1).Input(Variable), "does not fit"},
+		{New().UsagePage(PageButton).Logical(0, 1).ReportSize(8).ReportCount(1).Input(Variable).ReportID(2), "without report ID"},
+		{New().ReportID(0), "reserved"},
+		{New().Logical(10, 1), "logical range"},
+	}
+	for i, c := range cases {
+		_, err := c.d.Build()
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%d: expected %q, got %v", i, c.err, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package linux
+
+import (
+	"os"
+)
+
+// NewBridge initialize a new Bridge
+func NewBridge(name string) *Bridge {
+	return &Bridge{name}
+}
+
+// Bridge an iproute2 network bridge wrapper, used by a NAP server to
+// attach the incoming bnep interfaces
+type Bridge struct {
+	Name string
+}
+
+//Exists return true if the bridge interface is present
+func (b *Bridge) Exists() bool {
+	_, err := os.Stat("/sys/class/net/" + b.Name + "/bridge")
+	return err == nil
+}
+
+//Create the bridge, if missing, and bring it up
+func (b *Bridge) Create() error {
+	if !b.Exists() {
+		_, err := CmdExec("ip", "link", "add", "name", b.Name, "type", "bridge")
+		if err != nil {
+			return err
+		}
+	}
+	_, err := CmdExec("ip", "link", "set", b.Name, "up")
+	return err
+}
+
+//AddAddress assign an address to the bridge, eg. 192.168.44.1/24
+func (b *Bridge) AddAddress(cidr string) error {
+	_, err := CmdExec("ip", "addr", "replace", cidr, "dev", b.Name)
+	return err
+}
+
+//Delete the bridge
+func (b *Bridge) Delete() error {
+	_, err := CmdExec("ip", "link", "delete", b.Name, "type", "bridge")
+	return err
+}
@@ -0,0 +1,132 @@
+package hci
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/muka/go-bluetooth/linux"
+)
+
+// Accept list command opcodes
+var (
+	OpLEReadAcceptListSize   = Opcode(OGFLEController, 0x000f)
+	OpLEClearAcceptList      = Opcode(OGFLEController, 0x0010)
+	OpLEAddToAcceptList      = Opcode(OGFLEController, 0x0011)
+	OpLERemoveFromAcceptList = Opcode(OGFLEController, 0x0012)
+)
+
+// Filter policies of LESetScanParameters
+const (
+	// ScanFilterNone report all the advertisements
+	ScanFilterNone uint8 = 0x00
+	// ScanFilterAcceptList report the advertisements of the devices in the
+	// accept list only
+	ScanFilterAcceptList uint8 = 0x01
+)
+
+//AcceptListEntry a device of the controller accept list
+type AcceptListEntry struct {
+	// AddressType AddressPublic or AddressRandom
+	AddressType uint8
+	Address     string
+}
+
+func (e AcceptListEntry) params() ([]byte, error) {
+	addr, err := linux.ParseAddress(e.Address)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{e.AddressType}, addr[:]...), nil
+}
+
+//LEReadAcceptListSize read how many devices the accept list holds
+type LEReadAcceptListSize struct{}
+
+//Opcode of the command
+func (LEReadAcceptListSize) Opcode() uint16 { return OpLEReadAcceptListSize }
+
+//Params of the command
+func (LEReadAcceptListSize) Params() []byte { return nil }
+
+//LEClearAcceptList remove all the devices of the accept list
+type LEClearAcceptList struct{}
+
+//Opcode of the command
+func (LEClearAcceptList) Opcode() uint16 { return OpLEClearAcceptList }
+
+//Params of the command
+func (LEClearAcceptList) Params() []byte { return nil }
+
+//LEAddToAcceptList add a device to the accept list
+type LEAddToAcceptList struct {
+	AcceptListEntry
+}
+
+//Opcode of the command
+func (LEAddToAcceptList) Opcode() uint16 { return OpLEAddToAcceptList }
+
+//Params of the command, nil if the address is invalid
+func (c LEAddToAcceptList) Params() []byte {
+	b, _ := c.params()
+	return b
+}
+
+//LERemoveFromAcceptList remove a device from the accept list
+type LERemoveFromAcceptList struct {
+	AcceptListEntry
+}
+
+//Opcode of the command
+func (LERemoveFromAcceptList) Opcode() uint16 { return OpLERemoveFromAcceptList }
+
+//Params of the command, nil if the address is invalid
+func (c LERemoveFromAcceptList) Params() []byte {
+	b, _ := c.params()
+	return b
+}
+
+//AcceptListSize return how many devices the accept list of the controller
+// holds
+func (s *Socket) AcceptListSize() (int, error) {
+	ret, err := s.Send(LEReadAcceptListSize{})
+	if err != nil {
+		return 0, err
+	}
+	if len(ret) < 2 {
+		return 0, errors.New("Invalid LEReadAcceptListSize reply")
+	}
+	return int(ret[1]), nil
+}
+
+//SetAcceptList replace the devices of the accept list. Scan with
+// ScanFilterAcceptList to receive only their advertisements. The list can not
+// be changed while scanning or advertising with a filter policy using it.
+//
+// bluetoothd manages the list of the adapters it controls, use mgmt.AddDevice
+// instead unless the adapter is opened with OpenUser
+func (s *Socket) SetAcceptList(entries []AcceptListEntry) error {
+
+	for _, e := range entries {
+		if _, err := e.params(); err != nil {
+			return err
+		}
+	}
+
+	size, err := s.AcceptListSize()
+	if err != nil {
+		return err
+	}
+	if len(entries) > size {
+		return fmt.Errorf("The accept list holds %d devices, %d given", size, len(entries))
+	}
+
+	if _, err := s.Send(LEClearAcceptList{}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := s.Send(LEAddToAcceptList{e}); err != nil {
+			return fmt.Errorf("%s: %s", e.Address, err)
+		}
+	}
+	return nil
+}
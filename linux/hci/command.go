@@ -0,0 +1,249 @@
+package hci
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/muka/go-bluetooth/linux"
+)
+
+// Command groups (OGF)
+const (
+	OGFLinkControl   = 0x01
+	OGFHostControl   = 0x03
+	OGFInformational = 0x04
+	OGFLEController  = 0x08
+	OGFVendor        = 0x3f
+)
+
+//Opcode build the opcode of a command from its group and command field
+func Opcode(ogf uint8, ocf uint16) uint16 {
+	return uint16(ogf)<<10 | ocf&0x03ff
+}
+
+// Command opcodes
+var (
+	OpReset                      = Opcode(OGFHostControl, 0x0003)
+	OpReadBDAddr                 = Opcode(OGFInformational, 0x0009)
+	OpLESetRandomAddress         = Opcode(OGFLEController, 0x0005)
+	OpLESetAdvertisingParameters = Opcode(OGFLEController, 0x0006)
+	OpLESetAdvertisingData       = Opcode(OGFLEController, 0x0008)
+	OpLESetScanResponseData      = Opcode(OGFLEController, 0x0009)
+	OpLESetAdvertiseEnable       = Opcode(OGFLEController, 0x000a)
+	OpLESetScanParameters        = Opcode(OGFLEController, 0x000b)
+	OpLESetScanEnable            = Opcode(OGFLEController, 0x000c)
+)
+
+// Advertising types of LESetAdvertisingParameters
+const (
+	AdvInd        = 0x00
+	AdvDirectInd  = 0x01
+	AdvScanInd    = 0x02
+	AdvNonconnInd = 0x03
+)
+
+// Address types
+const (
+	AddressPublic = 0x00
+	AddressRandom = 0x01
+)
+
+//MaxAdvertisingData the size of the legacy advertising and scan response data
+const MaxAdvertisingData = 31
+
+//Command an HCI command
+type Command interface {
+	Opcode() uint16
+	// Params the encoded parameters
+	Params() []byte
+}
+
+//Reset the controller
+type Reset struct{}
+
+//Opcode of the command
+func (Reset) Opcode() uint16 { return OpReset }
+
+//Params of the command
+func (Reset) Params() []byte { return nil }
+
+//ReadBDAddr read the public address of the controller, see ParseBDAddr
+type ReadBDAddr struct{}
+
+//Opcode of the command
+func (ReadBDAddr) Opcode() uint16 { return OpReadBDAddr }
+
+//Params of the command
+func (ReadBDAddr) Params() []byte { return nil }
+
+//ParseBDAddr decode the return parameters of ReadBDAddr
+func ParseBDAddr(ret []byte) (string, error) {
+	if len(ret) < 7 {
+		return "", errors.New("Invalid ReadBDAddr reply")
+	}
+	return FormatAddress(ret[1:7]), nil
+}
+
+//LESetRandomAddress set the random address used by the advertising and the
+// scanning
+type LESetRandomAddress struct {
+	Address string
+}
+
+//Opcode of the command
+func (LESetRandomAddress) Opcode() uint16 { return OpLESetRandomAddress }
+
+//Params of the command, nil if the address is invalid
+func (c LESetRandomAddress) Params() []byte {
+	b, err := linux.ParseAddress(c.Address)
+	if err != nil {
+		return nil
+	}
+	return b[:]
+}
+
+//LESetAdvertisingParameters set the legacy advertising parameters
+type LESetAdvertisingParameters struct {
+	// IntervalMin and IntervalMax in units of 0.625ms, from 0x0020 to 0x4000
+	IntervalMin     uint16
+	IntervalMax     uint16
+	Type            uint8
+	OwnAddressType  uint8
+	PeerAddressType uint8
+	// PeerAddress the target of directed advertising
+	PeerAddress string
+	// ChannelMap the advertising channels, 0x07 for all
+	ChannelMap   uint8
+	FilterPolicy uint8
+}
+
+//Opcode of the command
+func (LESetAdvertisingParameters) Opcode() uint16 { return OpLESetAdvertisingParameters }
+
+//Params of the command
+func (c LESetAdvertisingParameters) Params() []byte {
+	b := make([]byte, 15)
+	binary.LittleEndian.PutUint16(b[0:], c.IntervalMin)
+	binary.LittleEndian.PutUint16(b[2:], c.IntervalMax)
+	b[4] = c.Type
+	b[5] = c.OwnAddressType
+	b[6] = c.PeerAddressType
+	if c.PeerAddress != "" {
+		addr, _ := linux.ParseAddress(c.PeerAddress)
+		copy(b[7:13], addr[:])
+	}
+	b[13] = c.ChannelMap
+	b[14] = c.FilterPolicy
+	return b
+}
+
+//LESetAdvertisingData set the legacy advertising data, up to
+// MaxAdvertisingData bytes
+type LESetAdvertisingData struct {
+	Data []byte
+}
+
+//Opcode of the command
+func (LESetAdvertisingData) Opcode() uint16 { return OpLESetAdvertisingData }
+
+//Params of the command
+func (c LESetAdvertisingData) Params() []byte {
+	return advertisingData(c.Data)
+}
+
+//LESetScanResponseData set the legacy scan response data, up to
+// MaxAdvertisingData bytes
+type LESetScanResponseData struct {
+	Data []byte
+}
+
+//Opcode of the command
+func (LESetScanResponseData) Opcode() uint16 { return OpLESetScanResponseData }
+
+//Params of the command
+func (c LESetScanResponseData) Params() []byte {
+	return advertisingData(c.Data)
+}
+
+// advertisingData the data padded to MaxAdvertisingData, after its length
+func advertisingData(data []byte) []byte {
+	if len(data) > MaxAdvertisingData {
+		data = data[:MaxAdvertisingData]
+	}
+	b := make([]byte, 1+MaxAdvertisingData)
+	b[0] = byte(len(data))
+	copy(b[1:], data)
+	return b
+}
+
+//LESetAdvertiseEnable start or stop the legacy advertising
+type LESetAdvertiseEnable struct {
+	Enable bool
+}
+
+//Opcode of the command
+func (LESetAdvertiseEnable) Opcode() uint16 { return OpLESetAdvertiseEnable }
+
+//Params of the command
+func (c LESetAdvertiseEnable) Params() []byte {
+	return []byte{boolByte(c.Enable)}
+}
+
+//LESetScanParameters set the scan parameters
+type LESetScanParameters struct {
+	// Active send scan requests
+	Active bool
+	// Interval and Window in units of 0.625ms, from 0x0004 to 0x4000
+	Interval       uint16
+	Window         uint16
+	OwnAddressType uint8
+	FilterPolicy   uint8
+}
+
+//Opcode of the command
+func (LESetScanParameters) Opcode() uint16 { return OpLESetScanParameters }
+
+//Params of the command
+func (c LESetScanParameters) Params() []byte {
+	b := make([]byte, 7)
+	b[0] = boolByte(c.Active)
+	binary.LittleEndian.PutUint16(b[1:], c.Interval)
+	binary.LittleEndian.PutUint16(b[3:], c.Window)
+	b[5] = c.OwnAddressType
+	b[6] = c.FilterPolicy
+	return b
+}
+
+//LESetScanEnable start or stop scanning, the reports are received as LE meta
+// events, see ParseAdvertisingReports
+type LESetScanEnable struct {
+	Enable           bool
+	FilterDuplicates bool
+}
+
+//Opcode of the command
+func (LESetScanEnable) Opcode() uint16 { return OpLESetScanEnable }
+
+//Params of the command
+func (c LESetScanEnable) Params() []byte {
+	return []byte{boolByte(c.Enable), boolByte(c.FilterDuplicates)}
+}
+
+//Vendor a vendor specific command
+type Vendor struct {
+	OCF  uint16
+	Data []byte
+}
+
+//Opcode of the command
+func (c Vendor) Opcode() uint16 { return Opcode(OGFVendor, c.OCF) }
+
+//Params of the command
+func (c Vendor) Params() []byte { return c.Data }
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
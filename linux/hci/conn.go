@@ -0,0 +1,163 @@
+package hci
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/muka/go-bluetooth/linux"
+	"golang.org/x/sys/unix"
+)
+
+// Link types of HCIGETCONNINFO
+const (
+	linkACL = 0x01
+	linkLE  = 0x80
+)
+
+// hciGetConnInfo _IOR('H', 213, int)
+const hciGetConnInfo = 0x800448d5
+
+//OpLEConnectionUpdate the opcode of LEConnectionUpdate
+var OpLEConnectionUpdate = Opcode(OGFLEController, 0x0013)
+
+//ConnectionParams the parameters of an LE connection
+type ConnectionParams struct {
+	// IntervalMin and IntervalMax in units of 1.25ms, from 6 (7.5ms) to
+	// 3200 (4s), eg. 6 for a HID device or 800 (1s) for a sensor
+	IntervalMin uint16
+	IntervalMax uint16
+	// Latency the connection events the peripheral can skip, up to 499
+	Latency uint16
+	// SupervisionTimeout in units of 10ms, from 10 (100ms) to 3200 (32s). It
+	// must be larger than (1 + Latency) * IntervalMax * 2
+	SupervisionTimeout uint16
+}
+
+//Validate check the parameters against the ranges of the Core specification
+func (p ConnectionParams) Validate() error {
+	if p.IntervalMin < 6 || p.IntervalMax > 3200 || p.IntervalMin > p.IntervalMax {
+		return fmt.Errorf("Invalid connection interval %d-%d", p.IntervalMin, p.IntervalMax)
+	}
+	if p.Latency > 499 {
+		return fmt.Errorf("Invalid connection latency %d", p.Latency)
+	}
+	if p.SupervisionTimeout < 10 || p.SupervisionTimeout > 3200 {
+		return fmt.Errorf("Invalid supervision timeout %d", p.SupervisionTimeout)
+	}
+	// timeout * 10ms > (1 + latency) * interval * 1.25ms * 2
+	if uint32(p.SupervisionTimeout)*4 <= (1+uint32(p.Latency))*uint32(p.IntervalMax) {
+		return errors.New("Supervision timeout too short for the interval and latency")
+	}
+	return nil
+}
+
+//LEConnectionUpdate request new parameters for a connection, the result is
+// received as an LEConnectionUpdateComplete event, see
+// ParseConnectionUpdateComplete
+type LEConnectionUpdate struct {
+	Handle uint16
+	ConnectionParams
+	// MinCELength and MaxCELength the expected length of the connection
+	// events, in units of 0.625ms, usually zero
+	MinCELength uint16
+	MaxCELength uint16
+}
+
+//Opcode of the command
+func (LEConnectionUpdate) Opcode() uint16 { return OpLEConnectionUpdate }
+
+//Params of the command
+func (c LEConnectionUpdate) Params() []byte {
+	b := make([]byte, 14)
+	binary.LittleEndian.PutUint16(b[0:], c.Handle)
+	binary.LittleEndian.PutUint16(b[2:], c.IntervalMin)
+	binary.LittleEndian.PutUint16(b[4:], c.IntervalMax)
+	binary.LittleEndian.PutUint16(b[6:], c.Latency)
+	binary.LittleEndian.PutUint16(b[8:], c.SupervisionTimeout)
+	binary.LittleEndian.PutUint16(b[10:], c.MinCELength)
+	binary.LittleEndian.PutUint16(b[12:], c.MaxCELength)
+	return b
+}
+
+//ConnectionUpdate the parameters in use after a connection update
+type ConnectionUpdate struct {
+	Status uint8
+	Handle uint16
+	// Interval in units of 1.25ms
+	Interval uint16
+	Latency  uint16
+	// SupervisionTimeout in units of 10ms
+	SupervisionTimeout uint16
+}
+
+//ParseConnectionUpdateComplete decode the parameters of an
+// LEConnectionUpdateComplete subevent
+func ParseConnectionUpdateComplete(params []byte) (*ConnectionUpdate, error) {
+	if len(params) < 9 {
+		return nil, errors.New("Invalid connection update complete event")
+	}
+	return &ConnectionUpdate{
+		Status:             params[0],
+		Handle:             binary.LittleEndian.Uint16(params[1:]),
+		Interval:           binary.LittleEndian.Uint16(params[3:]),
+		Latency:            binary.LittleEndian.Uint16(params[5:]),
+		SupervisionTimeout: binary.LittleEndian.Uint16(params[7:]),
+	}, nil
+}
+
+// struct hci_conn_info_req followed by a struct hci_conn_info, from
+// <bluetooth/hci.h>
+type connInfoRequest struct {
+	Bdaddr   [6]byte
+	Type     uint8
+	_        uint8
+	Handle   uint16
+	Addr     [6]byte
+	LinkType uint8
+	Out      uint8
+	State    uint16
+	LinkMode uint32
+}
+
+//ConnectionHandle return the handle of the LE connection to a device, it
+// requires a raw socket
+func (s *Socket) ConnectionHandle(address string) (uint16, error) {
+
+	bdaddr, err := linux.ParseAddress(address)
+	if err != nil {
+		return 0, err
+	}
+
+	req := connInfoRequest{
+		Bdaddr: bdaddr,
+		Type:   linkLE,
+	}
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(s.fd), hciGetConnInfo, uintptr(unsafe.Pointer(&req)))
+	if errno != 0 {
+		return 0, fmt.Errorf("No LE connection to %s: %s", address, errno.Error())
+	}
+	return req.Handle, nil
+}
+
+//UpdateConnection request new parameters for the LE connection to a device.
+// The controller replies with an LEConnectionUpdateComplete event once the
+// peer accepted them, see Events
+func (s *Socket) UpdateConnection(address string, params ConnectionParams) error {
+
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	handle, err := s.ConnectionHandle(address)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Send(LEConnectionUpdate{
+		Handle:           handle,
+		ConnectionParams: params,
+	})
+	return err
+}
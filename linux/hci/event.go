@@ -0,0 +1,166 @@
+package hci
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Event codes
+const (
+	EventDisconnectionComplete = 0x05
+	EventCommandComplete       = 0x0e
+	EventCommandStatus         = 0x0f
+	EventHardwareError         = 0x10
+	EventLEMeta                = 0x3e
+	EventVendor                = 0xff
+)
+
+// LE meta subevent codes
+const (
+	LEConnectionComplete         = 0x01
+	LEAdvertisingReport          = 0x02
+	LEConnectionUpdateComplete   = 0x03
+	LEEnhancedConnectionComplete = 0x0a
+)
+
+//StatusSuccess the status of a successful command
+const StatusSuccess = 0x00
+
+//StatusError a command failed with a status, see the error codes of the
+// Core specification, Vol 1 Part F
+type StatusError struct {
+	Opcode uint16
+	Status uint8
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HCI command 0x%04x failed with status 0x%02x", e.Opcode, e.Status)
+}
+
+//Event an HCI event
+type Event struct {
+	Code   uint8
+	Params []byte
+}
+
+//ParseEvent decode an event packet, without the packet type
+func ParseEvent(b []byte) (*Event, error) {
+	if len(b) < 2 {
+		return nil, fmt.Errorf("HCI event too short (%d bytes)", len(b))
+	}
+	if int(b[1]) != len(b)-2 {
+		return nil, fmt.Errorf("HCI event 0x%02x: invalid length %d", b[0], b[1])
+	}
+	params := make([]byte, len(b)-2)
+	copy(params, b[2:])
+	return &Event{Code: b[0], Params: params}, nil
+}
+
+//CommandOpcode return the opcode of the command a CommandComplete or a
+// CommandStatus event replies to
+func (e *Event) CommandOpcode() (uint16, bool) {
+	switch e.Code {
+	case EventCommandComplete:
+		if len(e.Params) >= 3 {
+			return binary.LittleEndian.Uint16(e.Params[1:]), true
+		}
+	case EventCommandStatus:
+		if len(e.Params) >= 4 {
+			return binary.LittleEndian.Uint16(e.Params[2:]), true
+		}
+	}
+	return 0, false
+}
+
+// commandResult return the return parameters of the command an event replies
+// to, after checking its status
+func (e *Event) commandResult() ([]byte, error) {
+	opcode, _ := e.CommandOpcode()
+	switch e.Code {
+	case EventCommandComplete:
+		ret := e.Params[3:]
+		// most commands start their return parameters with a status, it is
+		// left to the caller to decode the others
+		if len(ret) > 0 && ret[0] != StatusSuccess {
+			return ret, &StatusError{Opcode: opcode, Status: ret[0]}
+		}
+		return ret, nil
+	case EventCommandStatus:
+		if e.Params[0] != StatusSuccess {
+			return nil, &StatusError{Opcode: opcode, Status: e.Params[0]}
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("HCI event 0x%02x is not a command reply", e.Code)
+}
+
+//Subevent return the subevent code and parameters of an LE meta event
+func (e *Event) Subevent() (uint8, []byte, bool) {
+	if e.Code != EventLEMeta || len(e.Params) < 1 {
+		return 0, nil, false
+	}
+	return e.Params[0], e.Params[1:], true
+}
+
+//AdvertisingReport a report of an LE advertising report event. The
+// advertising channel is not reported by the controllers
+type AdvertisingReport struct {
+	// EventType eg. 0x00 for connectable undirected advertising, the low
+	// byte of the event type of the extended reports
+	EventType   uint8
+	AddressType uint8
+	// Address in the usual XX:XX:XX:XX:XX:XX form
+	Address string
+	// Data the AD structures
+	Data []byte
+	RSSI int8
+
+	// Extended true for an extended advertising report, with the fields below
+	Extended     bool
+	PrimaryPHY   uint8
+	SecondaryPHY uint8
+	SID          uint8
+	// TxPower in dBm, 127 if not available
+	TxPower int8
+}
+
+//ParseAdvertisingReports decode the parameters of an LE advertising report
+// subevent
+func ParseAdvertisingReports(params []byte) ([]AdvertisingReport, error) {
+
+	if len(params) < 1 {
+		return nil, fmt.Errorf("Empty advertising report")
+	}
+	num := int(params[0])
+	b := params[1:]
+
+	reports := make([]AdvertisingReport, 0, num)
+	for i := 0; i < num; i++ {
+		if len(b) < 9 {
+			return nil, fmt.Errorf("Advertising report %d truncated", i)
+		}
+		r := AdvertisingReport{
+			EventType:   b[0],
+			AddressType: b[1],
+			Address:     FormatAddress(b[2:8]),
+		}
+		size := int(b[8])
+		if len(b) < 9+size+1 {
+			return nil, fmt.Errorf("Advertising report %d truncated", i)
+		}
+		r.Data = append([]byte{}, b[9:9+size]...)
+		r.RSSI = int8(b[9+size])
+		reports = append(reports, r)
+		b = b[9+size+1:]
+	}
+	return reports, nil
+}
+
+//FormatAddress format a little endian device address, as sent by the
+// controller
+func FormatAddress(b []byte) string {
+	if len(b) < 6 {
+		return ""
+	}
+	return fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X", b[5], b[4], b[3], b[2], b[1], b[0])
+}
@@ -0,0 +1,237 @@
+//Package hci give raw access to the HCI commands and events of an adapter,
+// for the operations the bluez D-Bus API does not expose, eg. vendor commands
+// or a fine control of the advertising.
+//
+// A raw socket shares the adapter with bluetoothd, a user channel socket takes
+// it over: the adapter must be down and bluetoothd ignores it while open. Both
+// require CAP_NET_RAW and CAP_NET_ADMIN
+package hci
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// HCI packet types
+const (
+	PacketCommand = 0x01
+	PacketACL     = 0x02
+	PacketSCO     = 0x03
+	PacketEvent   = 0x04
+)
+
+//DefaultCommandTimeout how long Send waits for the controller to reply
+const DefaultCommandTimeout = 2 * time.Second
+
+// socket options, see <bluetooth/hci.h>
+const (
+	solHCI    = 0
+	hciFilter = 2
+)
+
+//ErrTimeout the controller did not reply to a command
+var ErrTimeout = errors.New("HCI command timeout")
+
+//ErrClosed the socket has been closed
+var ErrClosed = errors.New("HCI socket closed")
+
+//Socket an HCI socket bound to an adapter
+type Socket struct {
+	// fd the descriptor of file, for the ioctls
+	fd   int
+	file *os.File
+	// Timeout the wait for the reply of a command, DefaultCommandTimeout if zero
+	Timeout time.Duration
+
+	// mutex serialize the commands, a controller replies to one at a time
+	mutex  sync.Mutex
+	events chan *Event
+
+	replyMutex sync.Mutex
+	reply      chan *Event
+	opcode     uint16
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+//OpenRaw open a raw socket on the adapter, eg. 0 for hci0. bluetoothd keeps
+// managing the adapter
+func OpenRaw(dev int) (*Socket, error) {
+	return open(dev, unix.HCI_CHANNEL_RAW)
+}
+
+//OpenUser open a user channel socket on the adapter, giving exclusive access
+// to it. The adapter must be down, see linux.Down
+func OpenUser(dev int) (*Socket, error) {
+	return open(dev, unix.HCI_CHANNEL_USER)
+}
+
+func open(dev int, channel uint16) (*Socket, error) {
+
+	fd, err := unix.Socket(unix.AF_BLUETOOTH, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.BTPROTO_HCI)
+	if err != nil {
+		return nil, err
+	}
+
+	err = unix.Bind(fd, &unix.SockaddrHCI{Dev: uint16(dev), Channel: channel})
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	if channel == unix.HCI_CHANNEL_RAW {
+		// a raw socket receives nothing until a filter is set, accept all the
+		// events
+		filter := make([]byte, 14)
+		filter[0] = 1 << PacketEvent
+		for i := 4; i < 12; i++ {
+			filter[i] = 0xff
+		}
+		err = unix.SetsockoptString(fd, solHCI, hciFilter, string(filter))
+		if err != nil {
+			unix.Close(fd)
+			return nil, err
+		}
+	}
+
+	return newSocket(fd), nil
+}
+
+func newSocket(fd int) *Socket {
+	s := &Socket{
+		fd:     fd,
+		file:   os.NewFile(uintptr(fd), "hci"),
+		events: make(chan *Event, 64),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+//Events receive the events not replying to a command, eg. the LE meta
+// events. Events are dropped while the channel is full
+func (s *Socket) Events() <-chan *Event {
+	return s.events
+}
+
+//Send a command and wait for its CommandComplete or CommandStatus event. The
+// return parameters of CommandComplete are returned, a status other than
+// success as a *StatusError
+func (s *Socket) Send(cmd Command) ([]byte, error) {
+	return s.SendRaw(cmd.Opcode(), cmd.Params())
+}
+
+//SendRaw send a command from its opcode and parameters, see Send
+func (s *Socket) SendRaw(opcode uint16, params []byte) ([]byte, error) {
+
+	if len(params) > 255 {
+		return nil, errors.New("HCI command parameters too long")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	reply := make(chan *Event, 1)
+	s.replyMutex.Lock()
+	s.reply = reply
+	s.opcode = opcode
+	s.replyMutex.Unlock()
+
+	defer func() {
+		s.replyMutex.Lock()
+		s.reply = nil
+		s.replyMutex.Unlock()
+	}()
+
+	packet := make([]byte, 4+len(params))
+	packet[0] = PacketCommand
+	packet[1] = byte(opcode)
+	packet[2] = byte(opcode >> 8)
+	packet[3] = byte(len(params))
+	copy(packet[4:], params)
+
+	if _, err := s.file.Write(packet); err != nil {
+		return nil, err
+	}
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultCommandTimeout
+	}
+
+	select {
+	case ev := <-reply:
+		return ev.commandResult()
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	case <-s.done:
+		return nil, ErrClosed
+	}
+}
+
+//Close the socket
+func (s *Socket) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.file.Close()
+	})
+	return err
+}
+
+func (s *Socket) run() {
+
+	defer close(s.events)
+
+	buf := make([]byte, 1+2+255)
+	for {
+		n, err := s.file.Read(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				log.Errorf("hci: read: %s", err.Error())
+			}
+			return
+		}
+
+		if n < 1 || buf[0] != PacketEvent {
+			continue
+		}
+		ev, err := ParseEvent(buf[1:n])
+		if err != nil {
+			log.Debugf("hci: %s", err.Error())
+			continue
+		}
+		s.dispatch(ev)
+	}
+}
+
+func (s *Socket) dispatch(ev *Event) {
+
+	if opcode, ok := ev.CommandOpcode(); ok {
+		s.replyMutex.Lock()
+		reply := s.reply
+		match := reply != nil && opcode == s.opcode
+		s.replyMutex.Unlock()
+		if match {
+			select {
+			case reply <- ev:
+			default:
+			}
+			return
+		}
+	}
+
+	select {
+	case s.events <- ev:
+	default:
+		log.Debugf("hci: event 0x%02x dropped", ev.Code)
+	}
+}
@@ -0,0 +1,411 @@
+package hci
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/muka/go-bluetooth"
+	"golang.org/x/sys/unix"
+)
+
+// fakeController return a socket connected to a fake controller
+func fakeController(t *testing.T) (*Socket, *os.File) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unix.SetNonblock(fds[0], true)
+	return newSocket(fds[0]), os.NewFile(uintptr(fds[1]), "controller")
+}
+
+func TestCommands(t *testing.T) {
+
+	if Opcode(OGFLEController, 0x000a) != 0x200a {
+		t.Fatalf("Unexpected opcode 0x%04x", OpLESetAdvertiseEnable)
+	}
+
+	p := LESetAdvertisingParameters{
+		IntervalMin: 0x00a0,
+		IntervalMax: 0x00a0,
+		Type:        AdvNonconnInd,
+		PeerAddress: "00:11:22:33:44:55",
+		ChannelMap:  0x07,
+	}.Params()
+	expected := []byte{0xa0, 0x00, 0xa0, 0x00, 0x03, 0x00, 0x00, 0x55, 0x44, 0x33, 0x22, 0x11, 0x00, 0x07, 0x00}
+	if !bytes.Equal(p, expected) {
+		t.Fatalf("Unexpected parameters %x", p)
+	}
+
+	p = LESetAdvertisingData{Data: []byte{0x02, 0x01, 0x06}}.Params()
+	if len(p) != 32 || p[0] != 3 || p[3] != 0x06 {
+		t.Fatalf("Unexpected advertising data %x", p)
+	}
+
+	p = LESetScanParameters{Active: true, Interval: 0x0010, Window: 0x0010}.Params()
+	if !bytes.Equal(p, []byte{0x01, 0x10, 0x00, 0x10, 0x00, 0x00, 0x00}) {
+		t.Fatalf("Unexpected scan parameters %x", p)
+	}
+}
+
+func TestParseAdvertisingReports(t *testing.T) {
+
+	ev, err := ParseEvent([]byte{EventLEMeta, 15, LEAdvertisingReport, 1,
+		0x00, 0x01, 0x55, 0x44, 0x33, 0x22, 0x11, 0x00, 3, 0x02, 0x01, 0x06, 0xc4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, params, ok := ev.Subevent()
+	if !ok || sub != LEAdvertisingReport {
+		t.Fatal("Expected an advertising report")
+	}
+	reports, err := ParseAdvertisingReports(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Address != "00:11:22:33:44:55" || reports[0].RSSI != -60 ||
+		!bytes.Equal(reports[0].Data, []byte{0x02, 0x01, 0x06}) {
+		t.Fatalf("Unexpected reports %v", reports)
+	}
+
+	if _, err := ParseEvent([]byte{EventLEMeta, 3, 0x01}); err == nil {
+		t.Fatal("Expected an invalid length")
+	}
+}
+
+func TestParseScanRecord(t *testing.T) {
+
+	r, err := ParseScanRecord([]byte{
+		0x02, ADFlags, 0x06,
+		0x05, ADComplete16, 0x0f, 0x18, 0x0a, 0x18,
+		0x05, ADCompleteName, 'T', 'a', 'g', '1',
+		0x05, ADServiceData16, 0x0f, 0x18, 0x64, 0x00,
+		0x05, ADManufacturerSpecific, 0x4c, 0x00, 0x02, 0x15,
+		0x02, ADTxPower, 0xf4,
+		0x00, 0x00,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Flags != 0x06 || r.Name != "Tag1" || r.ShortName || r.TxPower != -12 || len(r.Structures) != 6 {
+		t.Fatalf("Unexpected record %+v", r)
+	}
+	if len(r.Services) != 2 || r.Services[0] != bluetooth.UUID16(0x180f) {
+		t.Fatalf("Unexpected services %v", r.Services)
+	}
+	if !bytes.Equal(r.ServiceData[bluetooth.UUID16(0x180f)], []byte{0x64, 0x00}) ||
+		!bytes.Equal(r.ManufacturerData[0x004c], []byte{0x02, 0x15}) {
+		t.Fatalf("Unexpected data %v %v", r.ServiceData, r.ManufacturerData)
+	}
+	expected := `name "Tag1" services [180F (Battery Service), 180A (Device Information)] ` +
+		`service data 180F (Battery Service) 6400 manufacturer 0x004C (Apple, Inc.) 0215 tx power -12dBm`
+	if r.String() != expected {
+		t.Fatalf("Unexpected description %s", r.String())
+	}
+
+	if _, err := ParseScanRecord([]byte{0x05, ADComplete16, 0x0f, 0x18}); err == nil {
+		t.Fatal("Expected a truncated record")
+	}
+	if _, err := ParseScanRecord([]byte{0x04, ADComplete16, 0x0f, 0x18, 0x0a}); err == nil {
+		t.Fatal("Expected an invalid list of UUIDs")
+	}
+}
+
+func TestSocket(t *testing.T) {
+
+	s, controller := fakeController(t)
+	defer s.Close()
+	defer controller.Close()
+
+	go func() {
+		buf := make([]byte, 260)
+		for {
+			n, err := controller.Read(buf)
+			if err != nil {
+				return
+			}
+			if n < 4 || buf[0] != PacketCommand {
+				continue
+			}
+			// an unrelated event, then the reply
+			controller.Write([]byte{PacketEvent, EventLEMeta, 2, LEConnectionComplete, 0x00})
+			switch buf[1] {
+			case byte(OpReadBDAddr):
+				controller.Write([]byte{PacketEvent, EventCommandComplete, 10, 1, buf[1], buf[2],
+					0x00, 0x55, 0x44, 0x33, 0x22, 0x11, 0x00})
+			case byte(OpReset):
+				controller.Write([]byte{PacketEvent, EventCommandComplete, 4, 1, buf[1], buf[2], 0x0c})
+			}
+		}
+	}()
+
+	ret, err := s.Send(ReadBDAddr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := ParseBDAddr(ret)
+	if err != nil || addr != "00:11:22:33:44:55" {
+		t.Fatalf("Unexpected address %s (%v)", addr, err)
+	}
+
+	_, err = s.Send(Reset{})
+	if e, ok := err.(*StatusError); !ok || e.Status != 0x0c || e.Opcode != OpReset {
+		t.Fatalf("Expected a status error, got %v", err)
+	}
+
+	select {
+	case ev := <-s.Events():
+		if sub, _, _ := ev.Subevent(); sub != LEConnectionComplete {
+			t.Fatalf("Unexpected event %v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an event")
+	}
+
+	s.Timeout = 50 * time.Millisecond
+	if _, err := s.Send(Vendor{OCF: 0x0001}); err != ErrTimeout {
+		t.Fatalf("Expected %s, got %v", ErrTimeout, err)
+	}
+}
+
+func TestConnectionParams(t *testing.T) {
+
+	p := ConnectionParams{IntervalMin: 6, IntervalMax: 12, Latency: 0, SupervisionTimeout: 100}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, invalid := range []ConnectionParams{
+		{IntervalMin: 5, IntervalMax: 12, SupervisionTimeout: 100},
+		{IntervalMin: 12, IntervalMax: 6, SupervisionTimeout: 100},
+		{IntervalMin: 6, IntervalMax: 12, Latency: 500, SupervisionTimeout: 100},
+		// 100ms is not larger than 2 * 5 * 10 * 1.25ms
+		{IntervalMin: 6, IntervalMax: 10, Latency: 4, SupervisionTimeout: 10},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Fatalf("Expected %v to be invalid", invalid)
+		}
+	}
+
+	b := LEConnectionUpdate{Handle: 0x0040, ConnectionParams: p}.Params()
+	if !bytes.Equal(b, []byte{0x40, 0x00, 0x06, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x64, 0x00, 0x00, 0x00, 0x00, 0x00}) {
+		t.Fatalf("Unexpected parameters %x", b)
+	}
+
+	u, err := ParseConnectionUpdateComplete([]byte{0x00, 0x40, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x64, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Handle != 0x40 || u.Interval != 12 || u.SupervisionTimeout != 100 {
+		t.Fatalf("Unexpected update %v", u)
+	}
+
+	if size := unsafe.Sizeof(connInfoRequest{}); size != 24 {
+		t.Fatalf("Unexpected hci_conn_info_req size %d", size)
+	}
+}
+
+func TestPHY(t *testing.T) {
+
+	s, controller := fakeController(t)
+	defer s.Close()
+	defer controller.Close()
+
+	commands := make(chan []byte, 10)
+	go func() {
+		buf := make([]byte, 260)
+		for {
+			n, err := controller.Read(buf)
+			if err != nil {
+				return
+			}
+			cmd := append([]byte{}, buf[:n]...)
+			commands <- cmd
+			reply := []byte{PacketEvent, EventCommandComplete, 4, 1, cmd[1], cmd[2], StatusSuccess}
+			if cmd[1] == byte(OpLEReadLocalFeatures) {
+				// 2M PHY only
+				reply = append(reply, 0x00, 0x01, 0, 0, 0, 0, 0, 0)
+				reply[2] += 8
+			}
+			controller.Write(reply)
+		}
+	}()
+
+	if err := s.SetDefaultPHY(PreferPHYCoded, PreferPHYCoded); err == nil {
+		t.Fatal("Expected the Coded PHY to be rejected")
+	}
+	<-commands
+
+	if err := s.SetDefaultPHY(PreferPHY2M, 0); err != nil {
+		t.Fatal(err)
+	}
+	<-commands
+	if cmd := <-commands; !bytes.Equal(cmd[4:], []byte{0x02, PreferPHY2M, 0}) {
+		t.Fatalf("Unexpected parameters %x", cmd[4:])
+	}
+
+	phy, err := ParsePHYUpdate([]byte{0x00, 0x40, 0x00, PHY2M, PHY1M})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if phy.Handle != 0x40 || phy.TX != PHY2M || phy.RX != PHY1M {
+		t.Fatalf("Unexpected PHY %v", phy)
+	}
+}
+
+func TestSetAcceptList(t *testing.T) {
+
+	s, controller := fakeController(t)
+	defer s.Close()
+	defer controller.Close()
+
+	commands := make(chan []byte, 10)
+	go func() {
+		buf := make([]byte, 260)
+		for {
+			n, err := controller.Read(buf)
+			if err != nil {
+				return
+			}
+			cmd := append([]byte{}, buf[:n]...)
+			commands <- cmd
+			reply := []byte{PacketEvent, EventCommandComplete, 4, 1, cmd[1], cmd[2], StatusSuccess}
+			if cmd[1] == byte(OpLEReadAcceptListSize) {
+				reply = append(reply, 1)
+				reply[2]++
+			}
+			controller.Write(reply)
+		}
+	}()
+
+	entries := []AcceptListEntry{
+		{AddressType: AddressRandom, Address: "C0:11:22:33:44:55"},
+		{AddressType: AddressPublic, Address: "00:11:22:33:44:55"},
+	}
+	if err := s.SetAcceptList(entries); err == nil {
+		t.Fatal("Expected the list to be too long")
+	}
+	<-commands
+
+	if err := s.SetAcceptList(entries[:1]); err != nil {
+		t.Fatal(err)
+	}
+	<-commands
+	if cmd := <-commands; cmd[1] != byte(OpLEClearAcceptList) {
+		t.Fatalf("Expected the list to be cleared, got %x", cmd)
+	}
+	if cmd := <-commands; cmd[1] != byte(OpLEAddToAcceptList) || !bytes.Equal(cmd[4:], []byte{AddressRandom, 0x55, 0x44, 0x33, 0x22, 0x11, 0xc0}) {
+		t.Fatalf("Unexpected command %x", cmd)
+	}
+}
+
+func TestScan(t *testing.T) {
+
+	s, controller := fakeController(t)
+	defer controller.Close()
+
+	commands := make(chan []byte, 10)
+	go func() {
+		buf := make([]byte, 260)
+		for {
+			n, err := controller.Read(buf)
+			if err != nil {
+				return
+			}
+			cmd := append([]byte{}, buf[:n]...)
+			commands <- cmd
+			controller.Write([]byte{PacketEvent, EventCommandComplete, 4, 1, cmd[1], cmd[2], StatusSuccess})
+			if cmd[1] == byte(OpLESetScanEnable) && cmd[4] == 1 {
+				controller.Write([]byte{PacketEvent, EventLEMeta, 15, LEAdvertisingReport, 1,
+					0x03, 0x01, 0x55, 0x44, 0x33, 0x22, 0x11, 0xc0, 3, 0x02, 0x01, 0x06, 0xb0})
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reports, err := s.Scan(ctx, ScanOptions{Interval: 0x0020})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-reports:
+		if r.Address != "C0:11:22:33:44:55" || r.AddressType != AddressRandom || r.RSSI != -80 {
+			t.Fatalf("Unexpected report %v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a report")
+	}
+
+	cancel()
+	for range reports {
+	}
+	<-commands
+	if cmd := <-commands; cmd[1] != byte(OpLESetScanParameters) || !bytes.Equal(cmd[4:], []byte{0x00, 0x20, 0x00, 0x20, 0x00, 0x00, 0x00}) {
+		t.Fatalf("Unexpected scan parameters %x", cmd)
+	}
+	<-commands
+	if cmd := <-commands; cmd[1] != byte(OpLESetScanEnable) || cmd[4] != 0 {
+		t.Fatalf("Expected the scan to stop, got %x", cmd)
+	}
+	s.Close()
+
+	ext := []byte{1, 0x13, 0x00, 0x00, 0x55, 0x44, 0x33, 0x22, 0x11, 0x00, PHY1M, PHY2M, 0x01, 0x04, 0xc4,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0x01, 0x02}
+	list, err := ParseExtendedAdvertisingReports(ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || !list[0].Extended || list[0].SecondaryPHY != PHY2M || list[0].TxPower != 4 ||
+		list[0].RSSI != -60 || !bytes.Equal(list[0].Data, []byte{0x01, 0x02}) {
+		t.Fatalf("Unexpected reports %v", list)
+	}
+}
+
+func TestTxPower(t *testing.T) {
+
+	s, controller := fakeController(t)
+	defer s.Close()
+	defer controller.Close()
+
+	go func() {
+		buf := make([]byte, 260)
+		for {
+			n, err := controller.Read(buf)
+			if err != nil || n < 4 {
+				return
+			}
+			reply := []byte{PacketEvent, EventCommandComplete, 4, 1, buf[1], buf[2], StatusSuccess}
+			switch buf[1] {
+			case byte(OpLEReadAdvertisingChannelTxPower):
+				reply = append(reply, 0xfc)
+			case byte(OpLEReadTransmitPower):
+				reply = append(reply, 0xec, 0x0a)
+			}
+			reply[2] = byte(len(reply) - 3)
+			controller.Write(reply)
+		}
+	}()
+
+	power, err := s.AdvertisingTxPower()
+	if err != nil || power != -4 {
+		t.Fatalf("Unexpected TX power %d (%v)", power, err)
+	}
+	min, max, err := s.TxPowerRange()
+	if err != nil || min != -20 || max != 10 {
+		t.Fatalf("Unexpected TX power range %d %d (%v)", min, max, err)
+	}
+
+	b := LESetExtendedAdvertisingParameters{
+		Properties:  AdvPropConnectable | AdvPropLegacy,
+		IntervalMin: 0x0800,
+		IntervalMax: 0x010000,
+		TxPower:     -8,
+		PrimaryPHY:  PHY1M,
+	}.Params()
+	if len(b) != 25 || b[1] != 0x11 || b[4] != 0x08 || b[8] != 0x01 || int8(b[19]) != -8 || b[20] != PHY1M {
+		t.Fatalf("Unexpected parameters %x", b)
+	}
+}
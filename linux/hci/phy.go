@@ -0,0 +1,229 @@
+package hci
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// PHYs of an LE connection
+const (
+	PHY1M    uint8 = 0x01
+	PHY2M    uint8 = 0x02
+	PHYCoded uint8 = 0x03
+)
+
+// PHY preferences, a bitmask of the PHYs of LESetDefaultPHY and LESetPHY
+const (
+	PreferPHY1M    uint8 = 1 << 0
+	PreferPHY2M    uint8 = 1 << 1
+	PreferPHYCoded uint8 = 1 << 2
+)
+
+// LE features of LEReadLocalFeatures
+const (
+	FeatureLE2MPHY    = 8
+	FeatureLECodedPHY = 11
+)
+
+//LEPHYUpdateComplete the subevent reporting the PHY of a connection after a
+// change, see ParsePHYUpdate
+const LEPHYUpdateComplete = 0x0c
+
+// PHY command opcodes
+var (
+	OpLEReadLocalFeatures = Opcode(OGFLEController, 0x0003)
+	OpLEReadPHY           = Opcode(OGFLEController, 0x0030)
+	OpLESetDefaultPHY     = Opcode(OGFLEController, 0x0031)
+	OpLESetPHY            = Opcode(OGFLEController, 0x0032)
+)
+
+//LEReadLocalFeatures read the LE features of the controller, see
+// ParseLEFeatures
+type LEReadLocalFeatures struct{}
+
+//Opcode of the command
+func (LEReadLocalFeatures) Opcode() uint16 { return OpLEReadLocalFeatures }
+
+//Params of the command
+func (LEReadLocalFeatures) Params() []byte { return nil }
+
+//LEFeatures the LE features supported by a controller
+type LEFeatures uint64
+
+//Has return true if the feature bit is set, eg. FeatureLE2MPHY
+func (f LEFeatures) Has(bit uint) bool {
+	return f&(1<<bit) != 0
+}
+
+//ParseLEFeatures decode the return parameters of LEReadLocalFeatures
+func ParseLEFeatures(ret []byte) (LEFeatures, error) {
+	if len(ret) < 9 {
+		return 0, errors.New("Invalid LEReadLocalFeatures reply")
+	}
+	return LEFeatures(binary.LittleEndian.Uint64(ret[1:])), nil
+}
+
+//LEReadPHY read the PHY of a connection
+type LEReadPHY struct {
+	Handle uint16
+}
+
+//Opcode of the command
+func (LEReadPHY) Opcode() uint16 { return OpLEReadPHY }
+
+//Params of the command
+func (c LEReadPHY) Params() []byte {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, c.Handle)
+	return b
+}
+
+//LESetDefaultPHY set the PHYs preferred for the next connections, zero
+// leaves the choice to the controller
+type LESetDefaultPHY struct {
+	TX uint8
+	RX uint8
+}
+
+//Opcode of the command
+func (LESetDefaultPHY) Opcode() uint16 { return OpLESetDefaultPHY }
+
+//Params of the command
+func (c LESetDefaultPHY) Params() []byte {
+	return []byte{allPHYs(c.TX, c.RX), c.TX, c.RX}
+}
+
+//LESetPHY request the PHYs of a connection, the result is received as an
+// LEPHYUpdateComplete event
+type LESetPHY struct {
+	Handle uint16
+	TX     uint8
+	RX     uint8
+	// Options the coding of the Coded PHY, 0 no preference, 1 S=2, 2 S=8
+	Options uint16
+}
+
+//Opcode of the command
+func (LESetPHY) Opcode() uint16 { return OpLESetPHY }
+
+//Params of the command
+func (c LESetPHY) Params() []byte {
+	b := make([]byte, 7)
+	binary.LittleEndian.PutUint16(b, c.Handle)
+	b[2] = allPHYs(c.TX, c.RX)
+	b[3] = c.TX
+	b[4] = c.RX
+	binary.LittleEndian.PutUint16(b[5:], c.Options)
+	return b
+}
+
+// allPHYs set the no preference bits for the directions left at zero
+func allPHYs(tx, rx uint8) uint8 {
+	var all uint8
+	if tx == 0 {
+		all |= 0x01
+	}
+	if rx == 0 {
+		all |= 0x02
+	}
+	return all
+}
+
+//PHY the PHYs of a connection
+type PHY struct {
+	Handle uint16
+	TX     uint8
+	RX     uint8
+}
+
+//ParsePHY decode the return parameters of LEReadPHY
+func ParsePHY(ret []byte) (*PHY, error) {
+	if len(ret) < 5 {
+		return nil, errors.New("Invalid LEReadPHY reply")
+	}
+	return &PHY{
+		Handle: binary.LittleEndian.Uint16(ret[1:]),
+		TX:     ret[3],
+		RX:     ret[4],
+	}, nil
+}
+
+//ParsePHYUpdate decode the parameters of an LEPHYUpdateComplete subevent,
+// the status is returned as a *StatusError
+func ParsePHYUpdate(params []byte) (*PHY, error) {
+	if len(params) < 5 {
+		return nil, errors.New("Invalid PHY update complete event")
+	}
+	if params[0] != StatusSuccess {
+		return nil, &StatusError{Opcode: OpLESetPHY, Status: params[0]}
+	}
+	return ParsePHY(params)
+}
+
+//LEFeatures read the LE features of the controller, eg. to check the
+// support of the 2M or Coded PHY
+func (s *Socket) LEFeatures() (LEFeatures, error) {
+	ret, err := s.Send(LEReadLocalFeatures{})
+	if err != nil {
+		return 0, err
+	}
+	return ParseLEFeatures(ret)
+}
+
+//ReadPHY return the PHYs of the LE connection to a device
+func (s *Socket) ReadPHY(address string) (*PHY, error) {
+	handle, err := s.ConnectionHandle(address)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := s.Send(LEReadPHY{Handle: handle})
+	if err != nil {
+		return nil, err
+	}
+	return ParsePHY(ret)
+}
+
+//SetPHY request the PHYs of the LE connection to a device, as a bitmask of
+// PreferPHY*. A PHY not supported by the controller is rejected, the result
+// is received as an LEPHYUpdateComplete event, see Events
+func (s *Socket) SetPHY(address string, tx uint8, rx uint8) error {
+
+	if err := s.checkPHYs(tx | rx); err != nil {
+		return err
+	}
+
+	handle, err := s.ConnectionHandle(address)
+	if err != nil {
+		return err
+	}
+	_, err = s.Send(LESetPHY{Handle: handle, TX: tx, RX: rx})
+	return err
+}
+
+//SetDefaultPHY set the PHYs preferred for the next connections, as a bitmask
+// of PreferPHY*, eg. PreferPHY2M for throughput or PreferPHYCoded for range
+func (s *Socket) SetDefaultPHY(tx uint8, rx uint8) error {
+	if err := s.checkPHYs(tx | rx); err != nil {
+		return err
+	}
+	_, err := s.Send(LESetDefaultPHY{TX: tx, RX: rx})
+	return err
+}
+
+// checkPHYs check the controller supports the preferred PHYs
+func (s *Socket) checkPHYs(phys uint8) error {
+	if phys&(PreferPHY2M|PreferPHYCoded) == 0 {
+		return nil
+	}
+	features, err := s.LEFeatures()
+	if err != nil {
+		return err
+	}
+	if phys&PreferPHY2M != 0 && !features.Has(FeatureLE2MPHY) {
+		return errors.New("LE 2M PHY not supported by the controller")
+	}
+	if phys&PreferPHYCoded != 0 && !features.Has(FeatureLECodedPHY) {
+		return errors.New("LE Coded PHY not supported by the controller")
+	}
+	return nil
+}
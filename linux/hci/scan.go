@@ -0,0 +1,164 @@
+package hci
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//LEExtendedAdvertisingReport the subevent of the advertising reports of the
+// controllers supporting extended advertising
+const LEExtendedAdvertisingReport = 0x0d
+
+//DefaultScanBuffer the reports buffered by Scan
+const DefaultScanBuffer = 256
+
+//ScanOptions configure Scan
+type ScanOptions struct {
+	// Active send scan requests, the scan responses are reported separately
+	Active bool
+	// Interval and Window in units of 0.625ms, 0x0010 (10ms) if zero.
+	// Scanning continuously (Window equal to Interval) misses fewer
+	// advertisements
+	Interval uint16
+	Window   uint16
+	// FilterDuplicates let the controller report each device once
+	FilterDuplicates bool
+	// FilterPolicy eg. ScanFilterAcceptList to report the devices of the
+	// accept list only
+	FilterPolicy uint8
+	// OwnAddressType the address used by the scan requests
+	OwnAddressType uint8
+	// Buffer the reports buffered, DefaultScanBuffer if zero. The reports are
+	// dropped while the buffer is full
+	Buffer int
+}
+
+//Scan the advertising reports until ctx is done, without creating the Device1
+// objects of bluetoothd. The events of the socket are consumed while
+// scanning, the channel is closed when the scan stops.
+//
+// bluetoothd may be scanning on the same adapter, use a user channel socket
+// to have it for the scan only
+func (s *Socket) Scan(ctx context.Context, opts ScanOptions) (<-chan AdvertisingReport, error) {
+
+	interval := opts.Interval
+	if interval == 0 {
+		interval = 0x0010
+	}
+	window := opts.Window
+	if window == 0 {
+		window = interval
+	}
+	size := opts.Buffer
+	if size == 0 {
+		size = DefaultScanBuffer
+	}
+
+	// a scan left running, eg. by a previous run, rejects the parameters
+	s.Send(LESetScanEnable{Enable: false})
+
+	_, err := s.Send(LESetScanParameters{
+		Active:         opts.Active,
+		Interval:       interval,
+		Window:         window,
+		OwnAddressType: opts.OwnAddressType,
+		FilterPolicy:   opts.FilterPolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.Send(LESetScanEnable{Enable: true, FilterDuplicates: opts.FilterDuplicates})
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make(chan AdvertisingReport, size)
+	go func() {
+		defer close(reports)
+		defer func() {
+			if _, err := s.Send(LESetScanEnable{Enable: false}); err != nil && err != ErrClosed {
+				log.Debugf("hci: stop scan: %s", err.Error())
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-s.Events():
+				if !ok {
+					return
+				}
+				list, err := ev.AdvertisingReports()
+				if err != nil {
+					log.Debugf("hci: %s", err.Error())
+					continue
+				}
+				for _, r := range list {
+					select {
+					case reports <- r:
+					default:
+					}
+				}
+			}
+		}
+	}()
+
+	return reports, nil
+}
+
+//AdvertisingReports decode the reports of an LE advertising report or
+// extended advertising report event, nil for the other events
+func (e *Event) AdvertisingReports() ([]AdvertisingReport, error) {
+	sub, params, ok := e.Subevent()
+	if !ok {
+		return nil, nil
+	}
+	switch sub {
+	case LEAdvertisingReport:
+		return ParseAdvertisingReports(params)
+	case LEExtendedAdvertisingReport:
+		return ParseExtendedAdvertisingReports(params)
+	}
+	return nil, nil
+}
+
+//ParseExtendedAdvertisingReports decode the parameters of an LE extended
+// advertising report subevent
+func ParseExtendedAdvertisingReports(params []byte) ([]AdvertisingReport, error) {
+
+	if len(params) < 1 {
+		return nil, fmt.Errorf("Empty extended advertising report")
+	}
+	num := int(params[0])
+	b := params[1:]
+
+	reports := make([]AdvertisingReport, 0, num)
+	for i := 0; i < num; i++ {
+		if len(b) < 24 {
+			return nil, fmt.Errorf("Extended advertising report %d truncated", i)
+		}
+		size := int(b[23])
+		if len(b) < 24+size {
+			return nil, fmt.Errorf("Extended advertising report %d truncated", i)
+		}
+		r := AdvertisingReport{
+			EventType:    uint8(binary.LittleEndian.Uint16(b)),
+			AddressType:  b[2],
+			Address:      FormatAddress(b[3:9]),
+			Extended:     true,
+			PrimaryPHY:   b[9],
+			SecondaryPHY: b[10],
+			SID:          b[11],
+			TxPower:      int8(b[12]),
+			RSSI:         int8(b[13]),
+			Data:         append([]byte{}, b[24:24+size]...),
+		}
+		reports = append(reports, r)
+		b = b[24+size:]
+	}
+	return reports, nil
+}
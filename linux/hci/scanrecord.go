@@ -0,0 +1,217 @@
+package hci
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/muka/go-bluetooth"
+)
+
+// AD types of the advertising data, from the assigned numbers
+const (
+	ADFlags                = 0x01
+	ADIncomplete16         = 0x02
+	ADComplete16           = 0x03
+	ADIncomplete32         = 0x04
+	ADComplete32           = 0x05
+	ADIncomplete128        = 0x06
+	ADComplete128          = 0x07
+	ADShortName            = 0x08
+	ADCompleteName         = 0x09
+	ADTxPower              = 0x0a
+	ADClassOfDevice        = 0x0d
+	ADServiceData16        = 0x16
+	ADAppearance           = 0x19
+	ADServiceData32        = 0x20
+	ADServiceData128       = 0x21
+	ADManufacturerSpecific = 0xff
+)
+
+//ADStructure an AD structure of the advertising data
+type ADStructure struct {
+	Type uint8
+	Data []byte
+}
+
+//ParseAD split the advertising data in its AD structures. A zero length ends
+// the data, as in the padding of the legacy advertising
+func ParseAD(b []byte) ([]ADStructure, error) {
+	list := []ADStructure{}
+	for len(b) > 0 {
+		size := int(b[0])
+		if size == 0 {
+			break
+		}
+		if len(b) < 1+size {
+			return nil, fmt.Errorf("AD structure 0x%02x truncated", b[1])
+		}
+		list = append(list, ADStructure{Type: b[1], Data: b[2 : 1+size]})
+		b = b[1+size:]
+	}
+	return list, nil
+}
+
+//ScanRecord the fields of the advertising data
+type ScanRecord struct {
+	Flags uint8
+	// Name the complete or the shortened local name
+	Name      string
+	ShortName bool
+	// Services the service UUIDs, complete or incomplete lists
+	Services []bluetooth.UUID
+	// ServiceData by service UUID
+	ServiceData map[bluetooth.UUID][]byte
+	// ManufacturerData by company identifier
+	ManufacturerData map[uint16][]byte
+	// TxPower in dBm, 127 if not advertised
+	TxPower    int8
+	Appearance uint16
+	// Class the Class of Device of the EIR data of a classic device, zero if
+	// not sent
+	Class bluetooth.Class
+	// Structures all the AD structures, including the ones decoded above
+	Structures []ADStructure
+}
+
+//ParseScanRecord decode the advertising data, eg. AdvertisingReport.Data
+func ParseScanRecord(data []byte) (*ScanRecord, error) {
+
+	list, err := ParseAD(data)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ScanRecord{
+		ServiceData:      make(map[bluetooth.UUID][]byte),
+		ManufacturerData: make(map[uint16][]byte),
+		TxPower:          127,
+		Structures:       list,
+	}
+	for _, ad := range list {
+		b := ad.Data
+		switch ad.Type {
+		case ADFlags:
+			if len(b) > 0 {
+				r.Flags = b[0]
+			}
+		case ADShortName, ADCompleteName:
+			r.Name = string(b)
+			r.ShortName = ad.Type == ADShortName
+		case ADIncomplete16, ADComplete16:
+			err = r.addServices(b, 2)
+		case ADIncomplete32, ADComplete32:
+			err = r.addServices(b, 4)
+		case ADIncomplete128, ADComplete128:
+			err = r.addServices(b, 16)
+		case ADServiceData16:
+			err = r.addServiceData(b, 2)
+		case ADServiceData32:
+			err = r.addServiceData(b, 4)
+		case ADServiceData128:
+			err = r.addServiceData(b, 16)
+		case ADTxPower:
+			if len(b) > 0 {
+				r.TxPower = int8(b[0])
+			}
+		case ADAppearance:
+			if len(b) >= 2 {
+				r.Appearance = binary.LittleEndian.Uint16(b)
+			}
+		case ADClassOfDevice:
+			r.Class, err = bluetooth.ParseClass(b)
+		case ADManufacturerSpecific:
+			if len(b) < 2 {
+				return nil, fmt.Errorf("Manufacturer data truncated")
+			}
+			r.ManufacturerData[binary.LittleEndian.Uint16(b)] = b[2:]
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+//ParseEIR decode the extended inquiry response data of a classic device, eg.
+// of a mgmt DeviceFound event. It has the format of the advertising data,
+// padded with zeros
+func ParseEIR(data []byte) (*ScanRecord, error) {
+	return ParseScanRecord(data)
+}
+
+//ScanRecord decode the advertising data of the report
+func (r AdvertisingReport) ScanRecord() (*ScanRecord, error) {
+	return ParseScanRecord(r.Data)
+}
+
+func (r *ScanRecord) addServices(b []byte, size int) error {
+	if len(b)%size != 0 {
+		return fmt.Errorf("Invalid list of %dbit UUIDs", size*8)
+	}
+	for i := 0; i < len(b); i += size {
+		u, err := bluetooth.UUIDFromBytes(b[i : i+size])
+		if err != nil {
+			return err
+		}
+		r.Services = append(r.Services, u)
+	}
+	return nil
+}
+
+func (r *ScanRecord) addServiceData(b []byte, size int) error {
+	if len(b) < size {
+		return fmt.Errorf("Service data truncated")
+	}
+	u, err := bluetooth.UUIDFromBytes(b[:size])
+	if err != nil {
+		return err
+	}
+	r.ServiceData[u] = b[size:]
+	return nil
+}
+
+//String describe the record with the names of the assigned numbers, for the
+// logs
+func (r *ScanRecord) String() string {
+
+	parts := []string{}
+	if r.Name != "" {
+		parts = append(parts, fmt.Sprintf("name %q", r.Name))
+	}
+	if len(r.Services) > 0 {
+		list := make([]string, len(r.Services))
+		for i, u := range r.Services {
+			list[i] = u.Describe()
+		}
+		parts = append(parts, "services ["+strings.Join(list, ", ")+"]")
+	}
+
+	uuids := make([]bluetooth.UUID, 0, len(r.ServiceData))
+	for u := range r.ServiceData {
+		uuids = append(uuids, u)
+	}
+	sort.Slice(uuids, func(i, j int) bool { return uuids[i].String() < uuids[j].String() })
+	for _, u := range uuids {
+		parts = append(parts, fmt.Sprintf("service data %s %x", u.Describe(), r.ServiceData[u]))
+	}
+
+	ids := make([]int, 0, len(r.ManufacturerData))
+	for id := range r.ManufacturerData {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("manufacturer %s %x",
+			bluetooth.DescribeCompany(uint16(id)), r.ManufacturerData[uint16(id)]))
+	}
+
+	if r.Class != 0 {
+		parts = append(parts, "class "+r.Class.String())
+	}
+	if r.TxPower != 127 {
+		parts = append(parts, fmt.Sprintf("tx power %ddBm", r.TxPower))
+	}
+	return strings.Join(parts, " ")
+}
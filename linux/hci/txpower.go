@@ -0,0 +1,177 @@
+package hci
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+//TxPowerNotAvailable the TX power reported when the controller has no
+// preference or does not know it
+const TxPowerNotAvailable int8 = 127
+
+// TX power command opcodes
+var (
+	OpReadTransmitPowerLevel             = Opcode(OGFHostControl, 0x002d)
+	OpLEReadAdvertisingChannelTxPower    = Opcode(OGFLEController, 0x0007)
+	OpLESetExtendedAdvertisingParameters = Opcode(OGFLEController, 0x0036)
+	OpLEReadTransmitPower                = Opcode(OGFLEController, 0x004b)
+)
+
+//LEReadAdvertisingChannelTxPower read the TX power of the legacy advertising
+type LEReadAdvertisingChannelTxPower struct{}
+
+//Opcode of the command
+func (LEReadAdvertisingChannelTxPower) Opcode() uint16 { return OpLEReadAdvertisingChannelTxPower }
+
+//Params of the command
+func (LEReadAdvertisingChannelTxPower) Params() []byte { return nil }
+
+//ReadTransmitPowerLevel read the TX power of a connection
+type ReadTransmitPowerLevel struct {
+	Handle uint16
+	// Max read the maximum level instead of the current one
+	Max bool
+}
+
+//Opcode of the command
+func (ReadTransmitPowerLevel) Opcode() uint16 { return OpReadTransmitPowerLevel }
+
+//Params of the command
+func (c ReadTransmitPowerLevel) Params() []byte {
+	b := make([]byte, 3)
+	binary.LittleEndian.PutUint16(b, c.Handle)
+	b[2] = boolByte(c.Max)
+	return b
+}
+
+//LEReadTransmitPower read the TX power range supported by the controller
+type LEReadTransmitPower struct{}
+
+//Opcode of the command
+func (LEReadTransmitPower) Opcode() uint16 { return OpLEReadTransmitPower }
+
+//Params of the command
+func (LEReadTransmitPower) Params() []byte { return nil }
+
+// Advertising event properties of LESetExtendedAdvertisingParameters
+const (
+	AdvPropConnectable uint16 = 1 << 0
+	AdvPropScannable   uint16 = 1 << 1
+	AdvPropDirected    uint16 = 1 << 2
+	AdvPropLegacy      uint16 = 1 << 4
+	AdvPropAnonymous   uint16 = 1 << 5
+	AdvPropTxPower     uint16 = 1 << 6
+)
+
+//LESetExtendedAdvertisingParameters set the parameters of an advertising
+// set, including its TX power. The controller returns the TX power it
+// selected, see ParseSelectedTxPower
+type LESetExtendedAdvertisingParameters struct {
+	Handle     uint8
+	Properties uint16
+	// IntervalMin and IntervalMax in units of 0.625ms, 24 bits
+	IntervalMin     uint32
+	IntervalMax     uint32
+	ChannelMap      uint8
+	OwnAddressType  uint8
+	PeerAddressType uint8
+	PeerAddress     string
+	FilterPolicy    uint8
+	// TxPower the maximum TX power in dBm, TxPowerNotAvailable for no
+	// preference
+	TxPower            int8
+	PrimaryPHY         uint8
+	SecondaryMaxSkip   uint8
+	SecondaryPHY       uint8
+	SID                uint8
+	ScanRequestsNotify bool
+}
+
+//Opcode of the command
+func (LESetExtendedAdvertisingParameters) Opcode() uint16 {
+	return OpLESetExtendedAdvertisingParameters
+}
+
+//Params of the command
+func (c LESetExtendedAdvertisingParameters) Params() []byte {
+	b := make([]byte, 25)
+	b[0] = c.Handle
+	binary.LittleEndian.PutUint16(b[1:], c.Properties)
+	put24(b[3:], c.IntervalMin)
+	put24(b[6:], c.IntervalMax)
+	b[9] = c.ChannelMap
+	b[10] = c.OwnAddressType
+	b[11] = c.PeerAddressType
+	if c.PeerAddress != "" {
+		if params, err := (AcceptListEntry{Address: c.PeerAddress}).params(); err == nil {
+			copy(b[12:18], params[1:])
+		}
+	}
+	b[18] = c.FilterPolicy
+	b[19] = byte(c.TxPower)
+	b[20] = c.PrimaryPHY
+	b[21] = c.SecondaryMaxSkip
+	b[22] = c.SecondaryPHY
+	b[23] = c.SID
+	b[24] = boolByte(c.ScanRequestsNotify)
+	return b
+}
+
+func put24(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
+
+//ParseSelectedTxPower decode the return parameters of
+// LESetExtendedAdvertisingParameters
+func ParseSelectedTxPower(ret []byte) (int8, error) {
+	if len(ret) < 2 {
+		return 0, errors.New("Invalid LESetExtendedAdvertisingParameters reply")
+	}
+	return int8(ret[1]), nil
+}
+
+//AdvertisingTxPower return the TX power of the legacy advertising in dBm, eg.
+// to fill the TX power level of the advertising data for the proximity
+// calibration of the receivers
+func (s *Socket) AdvertisingTxPower() (int8, error) {
+	ret, err := s.Send(LEReadAdvertisingChannelTxPower{})
+	if err != nil {
+		return 0, err
+	}
+	if len(ret) < 2 {
+		return 0, errors.New("Invalid LEReadAdvertisingChannelTxPower reply")
+	}
+	return int8(ret[1]), nil
+}
+
+//ConnectionTxPower return the current or the maximum TX power of the LE
+// connection to a device, in dBm
+func (s *Socket) ConnectionTxPower(address string, max bool) (int8, error) {
+	handle, err := s.ConnectionHandle(address)
+	if err != nil {
+		return 0, err
+	}
+	ret, err := s.Send(ReadTransmitPowerLevel{Handle: handle, Max: max})
+	if err != nil {
+		return 0, err
+	}
+	if len(ret) < 4 {
+		return 0, errors.New("Invalid ReadTransmitPowerLevel reply")
+	}
+	return int8(ret[3]), nil
+}
+
+//TxPowerRange return the minimum and maximum TX power of the controller in
+// dBm, the limits of LESetExtendedAdvertisingParameters.TxPower
+func (s *Socket) TxPowerRange() (int8, int8, error) {
+	ret, err := s.Send(LEReadTransmitPower{})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(ret) < 3 {
+		return 0, 0, errors.New("Invalid LEReadTransmitPower reply")
+	}
+	return int8(ret[1]), int8(ret[2]), nil
+}
@@ -0,0 +1,306 @@
+package linux
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	btprotoL2CAP = 0
+	solBluetooth = 274
+	btSecurity   = 4
+	btSndMTU     = 12
+	btRcvMTU     = 13
+)
+
+// Address types of an LE CoC peer
+const (
+	L2CAPAddressLEPublic uint8 = 1
+	L2CAPAddressLERandom uint8 = 2
+)
+
+// Security levels of an L2CAP channel, see BT_SECURITY in <bluetooth/bluetooth.h>
+const (
+	L2CAPSecurityLow    uint8 = 1
+	L2CAPSecurityMedium uint8 = 2
+	L2CAPSecurityHigh   uint8 = 3
+	L2CAPSecurityFIPS   uint8 = 4
+)
+
+// struct sockaddr_l2 from <bluetooth/l2cap.h>
+type rawSockaddrL2 struct {
+	Family     uint16
+	PSM        uint16
+	Bdaddr     [6]uint8
+	CID        uint16
+	BdaddrType uint8
+	_          uint8
+}
+
+//L2CAPOptions configure an LE credit based connection oriented channel
+type L2CAPOptions struct {
+	// AddressType of the remote device, L2CAPAddressLEPublic if zero
+	AddressType uint8
+	// Adapter the address of the local adapter, any adapter if empty
+	Adapter string
+	// Security the level required on the link, eg. L2CAPSecurityMedium to
+	// encrypt it. Zero keeps the kernel default
+	Security uint8
+	// RecvMTU the largest SDU accepted, the peer is granted credits for it.
+	// Zero keeps the kernel default
+	RecvMTU uint16
+	// Timeout of the connection, zero waits for the kernel connection timeout
+	Timeout time.Duration
+}
+
+//L2CAPConn an LE CoC. Every Read returns a single SDU and a Write sends one,
+// up to SendMTU bytes. The kernel handles the credits: a Write blocks while
+// the peer grants none, use a write deadline to bound the wait
+type L2CAPConn struct {
+	*SocketConn
+	// SendMTU the largest SDU accepted by the peer
+	SendMTU int
+	// RecvMTU the largest SDU accepted from the peer
+	RecvMTU int
+}
+
+//DialL2CAP open an LE CoC to psm on a remote device, eg. a dynamic PSM
+// from 0x0080 to 0x00ff published by the peer
+func DialL2CAP(address string, psm uint16, opts L2CAPOptions) (*L2CAPConn, error) {
+
+	bdaddr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if psm == 0 {
+		return nil, errors.New("Invalid PSM")
+	}
+
+	fd, err := l2capSocket(opts, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	addrType := opts.AddressType
+	if addrType == 0 {
+		addrType = L2CAPAddressLEPublic
+	}
+
+	sa := rawSockaddrL2{
+		Family:     afBluetooth,
+		PSM:        psm,
+		Bdaddr:     bdaddr,
+		BdaddrType: addrType,
+	}
+
+	err = connectSocket(fd, unsafe.Pointer(&sa), unsafe.Sizeof(sa), opts.Timeout)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	local := &BtAddr{Proto: "l2cap", Address: strings.ToUpper(opts.Adapter)}
+	remote := &BtAddr{Proto: "l2cap", Address: strings.ToUpper(address), Channel: psm}
+
+	return newL2CAPConn(fd, local, remote)
+}
+
+// l2capSocket open a socket bound to the adapter and psm, with the options set
+// before the channel is established
+func l2capSocket(opts L2CAPOptions, psm uint16) (int, error) {
+
+	var local [6]byte
+	if opts.Adapter != "" {
+		var err error
+		local, err = ParseAddress(opts.Adapter)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	fd, err := unix.Socket(afBluetooth, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, btprotoL2CAP)
+	if err != nil {
+		return -1, err
+	}
+
+	// the source address type selects LE, the kernel then uses LE credit based
+	// flow control for the channel
+	sa := rawSockaddrL2{
+		Family:     afBluetooth,
+		PSM:        psm,
+		Bdaddr:     local,
+		BdaddrType: L2CAPAddressLEPublic,
+	}
+	_, _, errno := unix.Syscall(unix.SYS_BIND, uintptr(fd), uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa))
+	if errno != 0 {
+		unix.Close(fd)
+		return -1, errno
+	}
+
+	if opts.Security != 0 {
+		// struct bt_security
+		sec := [2]byte{opts.Security, 0}
+		_, _, errno = unix.Syscall6(unix.SYS_SETSOCKOPT, uintptr(fd), solBluetooth, btSecurity, uintptr(unsafe.Pointer(&sec)), unsafe.Sizeof(sec), 0)
+		if errno != 0 {
+			unix.Close(fd)
+			return -1, errno
+		}
+	}
+
+	if opts.RecvMTU != 0 {
+		mtu := opts.RecvMTU
+		_, _, errno = unix.Syscall6(unix.SYS_SETSOCKOPT, uintptr(fd), solBluetooth, btRcvMTU, uintptr(unsafe.Pointer(&mtu)), unsafe.Sizeof(mtu), 0)
+		if errno != 0 {
+			unix.Close(fd)
+			return -1, errno
+		}
+	}
+
+	return fd, nil
+}
+
+func newL2CAPConn(fd int, local, remote *BtAddr) (*L2CAPConn, error) {
+
+	// the MTUs are read before the descriptor is handed over to the runtime
+	mtu := func(opt uintptr) (int, error) {
+		var v uint16
+		size := uint32(unsafe.Sizeof(v))
+		_, _, errno := unix.Syscall6(unix.SYS_GETSOCKOPT, uintptr(fd), solBluetooth, opt, uintptr(unsafe.Pointer(&v)), uintptr(unsafe.Pointer(&size)), 0)
+		if errno != 0 {
+			return 0, errno
+		}
+		return int(v), nil
+	}
+
+	send, err := mtu(btSndMTU)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	recv, err := mtu(btRcvMTU)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	conn, err := NewSocketConn(fd, local, remote)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	return &L2CAPConn{conn, send, recv}, nil
+}
+
+// interval at which a pending Accept checks if the listener has been closed
+const l2capAcceptPoll = 200 * time.Millisecond
+
+//ListenL2CAP accept the LE CoC opened by remote devices on psm, zero lets the
+// kernel pick a dynamic PSM, see L2CAPListener.PSM. opts.Timeout is ignored
+func ListenL2CAP(psm uint16, opts L2CAPOptions) (*L2CAPListener, error) {
+
+	fd, err := l2capSocket(opts, psm)
+	if err != nil {
+		return nil, err
+	}
+
+	err = unix.Listen(fd, 5)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	// read the PSM assigned by the kernel
+	var sa rawSockaddrL2
+	size := uint32(unsafe.Sizeof(sa))
+	_, _, errno := unix.Syscall(unix.SYS_GETSOCKNAME, uintptr(fd), uintptr(unsafe.Pointer(&sa)), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		unix.Close(fd)
+		return nil, errno
+	}
+
+	l := &L2CAPListener{
+		fd:   fd,
+		addr: &BtAddr{Proto: "l2cap", Address: strings.ToUpper(opts.Adapter), Channel: sa.PSM},
+	}
+
+	return l, nil
+}
+
+//L2CAPListener accept LE CoC connections, it implements net.Listener
+type L2CAPListener struct {
+	fd     int
+	addr   *BtAddr
+	mutex  sync.Mutex
+	closed bool
+}
+
+//PSM return the PSM the listener is bound to
+func (l *L2CAPListener) PSM() uint16 {
+	return l.addr.Channel
+}
+
+//Accept wait for the next connection, see AcceptL2CAP
+func (l *L2CAPListener) Accept() (net.Conn, error) {
+	return l.AcceptL2CAP()
+}
+
+//AcceptL2CAP wait for the next connection
+func (l *L2CAPListener) AcceptL2CAP() (*L2CAPConn, error) {
+	for {
+
+		if l.isClosed() {
+			return nil, errors.New("L2CAP listener closed")
+		}
+
+		fds := []unix.PollFd{{Fd: int32(l.fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, int(l2capAcceptPoll/time.Millisecond))
+		if err == unix.EINTR || n == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var sa rawSockaddrL2
+		size := uint32(unsafe.Sizeof(sa))
+		nfd, _, errno := unix.Syscall6(unix.SYS_ACCEPT4, uintptr(l.fd), uintptr(unsafe.Pointer(&sa)), uintptr(unsafe.Pointer(&size)), unix.SOCK_CLOEXEC, 0, 0)
+		if errno == unix.EAGAIN || errno == unix.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return nil, errno
+		}
+
+		remote := &BtAddr{Proto: "l2cap", Address: formatAddress(sa.Bdaddr), Channel: sa.PSM}
+		return newL2CAPConn(int(nfd), l.addr, remote)
+	}
+}
+
+func (l *L2CAPListener) isClosed() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.closed
+}
+
+//Addr return the listening address
+func (l *L2CAPListener) Addr() net.Addr {
+	return l.addr
+}
+
+//Close stop listening, a pending Accept returns an error
+func (l *L2CAPListener) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	return unix.Close(l.fd)
+}
@@ -0,0 +1,23 @@
+package linux
+
+import (
+	"net"
+	"testing"
+	"unsafe"
+)
+
+func TestDialL2CAPInvalid(t *testing.T) {
+
+	if size := unsafe.Sizeof(rawSockaddrL2{}); size != 14 {
+		t.Fatalf("Unexpected sockaddr_l2 size %d", size)
+	}
+
+	if _, err := DialL2CAP("00:11:22:33:44", 0x80, L2CAPOptions{}); err == nil {
+		t.Fatal("Expected an invalid address")
+	}
+	if _, err := DialL2CAP("00:11:22:33:44:55", 0, L2CAPOptions{}); err == nil {
+		t.Fatal("Expected an invalid PSM")
+	}
+}
+
+var _ net.Listener = &L2CAPListener{}
@@ -0,0 +1,463 @@
+package mgmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/muka/go-bluetooth/linux"
+	"github.com/muka/go-bluetooth/linux/hci"
+)
+
+// Command opcodes
+const (
+	OpReadVersion         = 0x0001
+	OpReadIndexList       = 0x0003
+	OpReadInfo            = 0x0004
+	OpSetPowered          = 0x0005
+	OpSetDiscoverable     = 0x0006
+	OpSetConnectable      = 0x0007
+	OpSetBondable         = 0x0009
+	OpSetSSP              = 0x000b
+	OpSetLE               = 0x000d
+	OpSetLocalName        = 0x000f
+	OpLoadLongTermKeys    = 0x0013
+	OpReadLocalOOBData    = 0x0020
+	OpAddRemoteOOBData    = 0x0021
+	OpSetBREDR            = 0x002a
+	OpSetStaticAddress    = 0x002b
+	OpAddDevice           = 0x0033
+	OpRemoveDevice        = 0x0034
+	OpLoadConnParams      = 0x0035
+	OpReadLocalOOBExtData = 0x003b
+)
+
+// Event codes
+const (
+	EventCommandComplete    = 0x0001
+	EventCommandStatus      = 0x0002
+	EventControllerError    = 0x0003
+	EventIndexAdded         = 0x0004
+	EventIndexRemoved       = 0x0005
+	EventNewSettings        = 0x0006
+	EventLocalNameChanged   = 0x0008
+	EventNewLongTermKey     = 0x000a
+	EventDeviceConnected    = 0x000b
+	EventDeviceDisconnected = 0x000c
+	EventDeviceFound        = 0x0012
+	EventDiscovering        = 0x0013
+	EventDeviceAdded        = 0x001a
+	EventDeviceRemoved      = 0x001b
+	EventNewConnParams      = 0x001c
+)
+
+//Settings the settings of a controller, a bitmask of Setting*
+type Settings uint32
+
+// Settings bits
+const (
+	SettingPowered Settings = 1 << iota
+	SettingConnectable
+	SettingFastConnectable
+	SettingDiscoverable
+	SettingBondable
+	SettingLinkSecurity
+	SettingSSP
+	SettingBREDR
+	SettingHS
+	SettingLE
+	SettingAdvertising
+	SettingSecureConn
+	SettingDebugKeys
+	SettingPrivacy
+	SettingConfiguration
+	SettingStaticAddress
+)
+
+//Has return true if all the settings are set
+func (s Settings) Has(settings Settings) bool {
+	return s&settings == settings
+}
+
+// Address types
+const (
+	AddressBREDR    = 0x00
+	AddressLEPublic = 0x01
+	AddressLERandom = 0x02
+)
+
+// Discoverable modes of SetDiscoverable
+const (
+	DiscoverableOff     = 0x00
+	DiscoverableGeneral = 0x01
+	DiscoverableLimited = 0x02
+)
+
+//Version the version of the management interface
+type Version struct {
+	Version  uint8
+	Revision uint16
+}
+
+//Info the information of a controller
+type Info struct {
+	Address      string
+	Version      uint8
+	Manufacturer uint16
+	Supported    Settings
+	Current      Settings
+	Class        [3]byte
+	Name         string
+	ShortName    string
+}
+
+//ReadVersion read the version of the management interface
+func (s *Socket) ReadVersion() (*Version, error) {
+	ret, err := s.Send(OpReadVersion, IndexNone, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(ret) < 3 {
+		return nil, errors.New("Invalid ReadVersion reply")
+	}
+	return &Version{
+		Version:  ret[0],
+		Revision: binary.LittleEndian.Uint16(ret[1:]),
+	}, nil
+}
+
+//ReadIndexList return the indexes of the controllers, eg. 0 for hci0
+func (s *Socket) ReadIndexList() ([]uint16, error) {
+	ret, err := s.Send(OpReadIndexList, IndexNone, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(ret) < 2 {
+		return nil, errors.New("Invalid ReadIndexList reply")
+	}
+	num := int(binary.LittleEndian.Uint16(ret))
+	if len(ret) < 2+2*num {
+		return nil, errors.New("Invalid ReadIndexList reply")
+	}
+	list := make([]uint16, num)
+	for i := range list {
+		list[i] = binary.LittleEndian.Uint16(ret[2+2*i:])
+	}
+	return list, nil
+}
+
+//ReadInfo read the information of a controller
+func (s *Socket) ReadInfo(index uint16) (*Info, error) {
+	ret, err := s.Send(OpReadInfo, index, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInfo(ret)
+}
+
+//ParseInfo decode the return parameters of ReadInfo
+func ParseInfo(ret []byte) (*Info, error) {
+	if len(ret) < 280 {
+		return nil, fmt.Errorf("Invalid ReadInfo reply (%d bytes)", len(ret))
+	}
+	info := &Info{
+		Address:      formatAddress(ret[0:6]),
+		Version:      ret[6],
+		Manufacturer: binary.LittleEndian.Uint16(ret[7:]),
+		Supported:    Settings(binary.LittleEndian.Uint32(ret[9:])),
+		Current:      Settings(binary.LittleEndian.Uint32(ret[13:])),
+		Name:         cString(ret[20:269]),
+		ShortName:    cString(ret[269:280]),
+	}
+	copy(info.Class[:], ret[17:20])
+	return info, nil
+}
+
+// setMode send a command taking a single mode byte and returning the current
+// settings
+func (s *Socket) setMode(opcode uint16, index uint16, params ...byte) (Settings, error) {
+	ret, err := s.Send(opcode, index, params)
+	if err != nil {
+		return 0, err
+	}
+	if len(ret) < 4 {
+		return 0, fmt.Errorf("Invalid reply to 0x%04x", opcode)
+	}
+	return Settings(binary.LittleEndian.Uint32(ret)), nil
+}
+
+//SetPowered power a controller on or off, the current settings are returned
+func (s *Socket) SetPowered(index uint16, on bool) (Settings, error) {
+	return s.setMode(OpSetPowered, index, boolByte(on))
+}
+
+//SetConnectable make a controller connectable
+func (s *Socket) SetConnectable(index uint16, on bool) (Settings, error) {
+	return s.setMode(OpSetConnectable, index, boolByte(on))
+}
+
+//SetBondable make a controller bondable
+func (s *Socket) SetBondable(index uint16, on bool) (Settings, error) {
+	return s.setMode(OpSetBondable, index, boolByte(on))
+}
+
+//SetSSP enable the secure simple pairing of BR/EDR
+func (s *Socket) SetSSP(index uint16, on bool) (Settings, error) {
+	return s.setMode(OpSetSSP, index, boolByte(on))
+}
+
+//SetLE enable the LE support of a controller
+func (s *Socket) SetLE(index uint16, on bool) (Settings, error) {
+	return s.setMode(OpSetLE, index, boolByte(on))
+}
+
+//SetBREDR enable the BR/EDR support of a controller, eg. to make a dual mode
+// controller LE only. The controller must be powered off to disable it
+func (s *Socket) SetBREDR(index uint16, on bool) (Settings, error) {
+	return s.setMode(OpSetBREDR, index, boolByte(on))
+}
+
+//SetDiscoverable set the discoverable mode, eg. DiscoverableGeneral, for
+// timeout seconds, zero for no timeout. The controller must be connectable
+func (s *Socket) SetDiscoverable(index uint16, mode uint8, timeout uint16) (Settings, error) {
+	return s.setMode(OpSetDiscoverable, index, mode, byte(timeout), byte(timeout>>8))
+}
+
+//SetStaticAddress set the static random address of a powered off LE
+// controller, an empty address removes it
+func (s *Socket) SetStaticAddress(index uint16, address string) (Settings, error) {
+	var bdaddr [6]byte
+	if address != "" {
+		var err error
+		bdaddr, err = linux.ParseAddress(address)
+		if err != nil {
+			return 0, err
+		}
+		// the two most significant bits of a static address are set
+		if bdaddr[5]&0xc0 != 0xc0 {
+			return 0, errors.New("Invalid static address " + address)
+		}
+	}
+	return s.setMode(OpSetStaticAddress, index, bdaddr[:]...)
+}
+
+//SetLocalName set the name of a controller, up to 248 bytes, and its short
+// name up to 10 bytes
+func (s *Socket) SetLocalName(index uint16, name string, shortName string) error {
+	if len(name) > 248 || len(shortName) > 10 {
+		return errors.New("Local name too long")
+	}
+	params := make([]byte, 249+11)
+	copy(params, name)
+	copy(params[249:], shortName)
+	_, err := s.Send(OpSetLocalName, index, params)
+	return err
+}
+
+// Long term key types
+const (
+	LTKUnauthenticated     = 0x00
+	LTKAuthenticated       = 0x01
+	LTKUnauthenticatedP256 = 0x02
+	LTKAuthenticatedP256   = 0x03
+	LTKDebugP256           = 0x04
+)
+
+//LongTermKey an LE long term key, eg. restored from a backup
+type LongTermKey struct {
+	Address     string
+	AddressType uint8
+	Type        uint8
+	// Central (master) the key is used when the controller is the central
+	Central bool
+	// EncSize the key size, from 7 to 16
+	EncSize uint8
+	EDiv    uint16
+	Rand    uint64
+	Value   [16]byte
+}
+
+// longTermKeySize the size of an encoded LongTermKey
+const longTermKeySize = 36
+
+//LoadLongTermKeys replace the long term keys of a controller
+func (s *Socket) LoadLongTermKeys(index uint16, keys []LongTermKey) error {
+	params, err := encodeLongTermKeys(keys)
+	if err != nil {
+		return err
+	}
+	_, err = s.Send(OpLoadLongTermKeys, index, params)
+	return err
+}
+
+func encodeLongTermKeys(keys []LongTermKey) ([]byte, error) {
+	if 2+len(keys)*longTermKeySize > 0xffff {
+		return nil, errors.New("Too many long term keys")
+	}
+	b := make([]byte, 2+len(keys)*longTermKeySize)
+	binary.LittleEndian.PutUint16(b, uint16(len(keys)))
+	for i, k := range keys {
+		addr, err := linux.ParseAddress(k.Address)
+		if err != nil {
+			return nil, err
+		}
+		p := b[2+i*longTermKeySize:]
+		copy(p, addr[:])
+		p[6] = k.AddressType
+		p[7] = k.Type
+		p[8] = boolByte(k.Central)
+		p[9] = k.EncSize
+		binary.LittleEndian.PutUint16(p[10:], k.EDiv)
+		binary.LittleEndian.PutUint64(p[12:], k.Rand)
+		copy(p[20:36], k.Value[:])
+	}
+	return b, nil
+}
+
+// Actions of AddDevice
+const (
+	// ActionReport report the device advertisements while scanning in the
+	// background, through the controller accept list
+	ActionReport = 0x00
+	// ActionAllow allow the device to connect
+	ActionAllow = 0x01
+	// ActionAutoConnect connect the device whenever it advertises
+	ActionAutoConnect = 0x02
+)
+
+//AddDevice add a device to the kernel list of the controller, the LE devices
+// populate the controller accept list used for the background scan and the
+// auto connections. This reduces the host wakeups to the advertisements of
+// the listed devices
+func (s *Socket) AddDevice(index uint16, address string, addressType uint8, action uint8) error {
+	addr, err := linux.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	_, err = s.Send(OpAddDevice, index, append(addr[:], addressType, action))
+	return err
+}
+
+//RemoveDevice remove a device added by AddDevice, an empty address removes
+// all of them
+func (s *Socket) RemoveDevice(index uint16, address string, addressType uint8) error {
+	var addr [6]byte
+	if address != "" {
+		var err error
+		addr, err = linux.ParseAddress(address)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := s.Send(OpRemoveDevice, index, append(addr[:], addressType))
+	return err
+}
+
+//DeviceConnParams the LE connection parameters of a device, see
+// LoadConnParams
+type DeviceConnParams struct {
+	Address     string
+	AddressType uint8
+	hci.ConnectionParams
+}
+
+// connParamsSize the size of an encoded DeviceConnParams
+const connParamsSize = 15
+
+//LoadConnParams replace the connection parameters the kernel uses for the
+// devices, eg. a short interval for a HID device. They apply to the next
+// connections, see hci.Socket.UpdateConnection for an active one
+func (s *Socket) LoadConnParams(index uint16, params []DeviceConnParams) error {
+	b, err := encodeConnParams(params)
+	if err != nil {
+		return err
+	}
+	_, err = s.Send(OpLoadConnParams, index, b)
+	return err
+}
+
+func encodeConnParams(params []DeviceConnParams) ([]byte, error) {
+	if 2+len(params)*connParamsSize > 0xffff {
+		return nil, errors.New("Too many connection parameters")
+	}
+	b := make([]byte, 2+len(params)*connParamsSize)
+	binary.LittleEndian.PutUint16(b, uint16(len(params)))
+	for i, p := range params {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %s", p.Address, err)
+		}
+		addr, err := linux.ParseAddress(p.Address)
+		if err != nil {
+			return nil, err
+		}
+		e := b[2+i*connParamsSize:]
+		copy(e, addr[:])
+		e[6] = p.AddressType
+		binary.LittleEndian.PutUint16(e[7:], p.IntervalMin)
+		binary.LittleEndian.PutUint16(e[9:], p.IntervalMax)
+		binary.LittleEndian.PutUint16(e[11:], p.Latency)
+		binary.LittleEndian.PutUint16(e[13:], p.SupervisionTimeout)
+	}
+	return b, nil
+}
+
+// Flags of a DeviceFound event
+const (
+	DeviceFoundConfirmName    = 1 << 0
+	DeviceFoundLegacyPairing  = 1 << 1
+	DeviceFoundNotConnectable = 1 << 2
+)
+
+//DeviceFound a device found during a discovery, the parameters of an
+// EventDeviceFound
+type DeviceFound struct {
+	Address     string
+	AddressType uint8
+	RSSI        int8
+	// Flags eg. DeviceFoundLegacyPairing
+	Flags uint32
+	// EIR the extended inquiry response or advertising data, see Record
+	EIR []byte
+}
+
+//ParseDeviceFound decode the parameters of an EventDeviceFound
+func ParseDeviceFound(params []byte) (*DeviceFound, error) {
+	if len(params) < 14 {
+		return nil, fmt.Errorf("Invalid DeviceFound event (%d bytes)", len(params))
+	}
+	size := int(binary.LittleEndian.Uint16(params[12:]))
+	if len(params) < 14+size {
+		return nil, fmt.Errorf("DeviceFound event truncated")
+	}
+	return &DeviceFound{
+		Address:     formatAddress(params[0:6]),
+		AddressType: params[6],
+		RSSI:        int8(params[7]),
+		Flags:       binary.LittleEndian.Uint32(params[8:]),
+		EIR:         append([]byte{}, params[14:14+size]...),
+	}, nil
+}
+
+//Record decode the EIR data, eg. the Class of Device of a classic device
+func (d *DeviceFound) Record() (*hci.ScanRecord, error) {
+	return hci.ParseEIR(d.EIR)
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func formatAddress(b []byte) string {
+	return fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X", b[5], b[4], b[3], b[2], b[1], b[0])
+}
@@ -0,0 +1,248 @@
+//Package mgmt implement the kernel Bluetooth management interface, the
+// socket bluetoothd uses to configure the controllers. It gives control over
+// what the bluez D-Bus API hides, eg. the LE and BR/EDR modes or the static
+// address. See doc/mgmt-api.txt in the bluez sources.
+//
+// The socket requires CAP_NET_ADMIN, the changes are seen by bluetoothd as
+// made by another management client
+package mgmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+//IndexNone the index of the commands and events not related to a controller
+const IndexNone = 0xffff
+
+//DefaultCommandTimeout how long Send waits for the kernel to reply
+const DefaultCommandTimeout = 2 * time.Second
+
+//ErrTimeout the kernel did not reply to a command
+var ErrTimeout = errors.New("mgmt command timeout")
+
+//ErrClosed the socket has been closed
+var ErrClosed = errors.New("mgmt socket closed")
+
+//StatusError a command failed with a status, eg. StatusNotPowered
+type StatusError struct {
+	Opcode uint16
+	Status uint8
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("mgmt command 0x%04x failed with status 0x%02x", e.Opcode, e.Status)
+}
+
+// Command statuses
+const (
+	StatusSuccess          = 0x00
+	StatusUnknownCommand   = 0x01
+	StatusNotConnected     = 0x02
+	StatusFailed           = 0x03
+	StatusBusy             = 0x0a
+	StatusRejected         = 0x0b
+	StatusNotSupported     = 0x0c
+	StatusInvalidParams    = 0x0d
+	StatusNotPowered       = 0x0f
+	StatusInvalidIndex     = 0x11
+	StatusPermissionDenied = 0x14
+)
+
+//Event a management event, CommandComplete and CommandStatus events are
+// consumed by Send
+type Event struct {
+	Code uint16
+	// Index the controller, IndexNone if not related to one
+	Index  uint16
+	Params []byte
+}
+
+//ParseEvent decode an event packet
+func ParseEvent(b []byte) (*Event, error) {
+	if len(b) < 6 {
+		return nil, fmt.Errorf("mgmt event too short (%d bytes)", len(b))
+	}
+	size := int(binary.LittleEndian.Uint16(b[4:]))
+	if size != len(b)-6 {
+		return nil, fmt.Errorf("mgmt event 0x%04x: invalid length %d", binary.LittleEndian.Uint16(b), size)
+	}
+	params := make([]byte, size)
+	copy(params, b[6:])
+	return &Event{
+		Code:   binary.LittleEndian.Uint16(b),
+		Index:  binary.LittleEndian.Uint16(b[2:]),
+		Params: params,
+	}, nil
+}
+
+//Socket a management socket
+type Socket struct {
+	file *os.File
+	// Timeout the wait for the reply of a command, DefaultCommandTimeout if zero
+	Timeout time.Duration
+
+	// mutex serialize the commands
+	mutex  sync.Mutex
+	events chan *Event
+
+	replyMutex sync.Mutex
+	reply      chan *Event
+	opcode     uint16
+	index      uint16
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+//Open a management socket
+func Open() (*Socket, error) {
+
+	fd, err := unix.Socket(unix.AF_BLUETOOTH, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.BTPROTO_HCI)
+	if err != nil {
+		return nil, err
+	}
+
+	err = unix.Bind(fd, &unix.SockaddrHCI{Dev: IndexNone, Channel: unix.HCI_CHANNEL_CONTROL})
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	return newSocket(os.NewFile(uintptr(fd), "mgmt")), nil
+}
+
+func newSocket(file *os.File) *Socket {
+	s := &Socket{
+		file:   file,
+		events: make(chan *Event, 64),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+//Events receive the events, eg. EventNewSettings or EventDeviceConnected.
+// Events are dropped while the channel is full
+func (s *Socket) Events() <-chan *Event {
+	return s.events
+}
+
+//Send a command to a controller and wait for its reply, the return
+// parameters are returned, a status other than success as a *StatusError
+func (s *Socket) Send(opcode uint16, index uint16, params []byte) ([]byte, error) {
+
+	if len(params) > 0xffff {
+		return nil, errors.New("mgmt command parameters too long")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	reply := make(chan *Event, 1)
+	s.replyMutex.Lock()
+	s.reply = reply
+	s.opcode = opcode
+	s.index = index
+	s.replyMutex.Unlock()
+
+	defer func() {
+		s.replyMutex.Lock()
+		s.reply = nil
+		s.replyMutex.Unlock()
+	}()
+
+	packet := make([]byte, 6+len(params))
+	binary.LittleEndian.PutUint16(packet, opcode)
+	binary.LittleEndian.PutUint16(packet[2:], index)
+	binary.LittleEndian.PutUint16(packet[4:], uint16(len(params)))
+	copy(packet[6:], params)
+
+	if _, err := s.file.Write(packet); err != nil {
+		return nil, err
+	}
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultCommandTimeout
+	}
+
+	select {
+	case ev := <-reply:
+		status := ev.Params[2]
+		if status != StatusSuccess {
+			return nil, &StatusError{Opcode: opcode, Status: status}
+		}
+		return ev.Params[3:], nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	case <-s.done:
+		return nil, ErrClosed
+	}
+}
+
+//Close the socket
+func (s *Socket) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.file.Close()
+	})
+	return err
+}
+
+func (s *Socket) run() {
+
+	defer close(s.events)
+
+	buf := make([]byte, 6+0xffff)
+	for {
+		n, err := s.file.Read(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				log.Errorf("mgmt: read: %s", err.Error())
+			}
+			return
+		}
+
+		ev, err := ParseEvent(buf[:n])
+		if err != nil {
+			log.Debugf("mgmt: %s", err.Error())
+			continue
+		}
+		s.dispatch(ev)
+	}
+}
+
+func (s *Socket) dispatch(ev *Event) {
+
+	if (ev.Code == EventCommandComplete || ev.Code == EventCommandStatus) && len(ev.Params) >= 3 {
+		opcode := binary.LittleEndian.Uint16(ev.Params)
+		s.replyMutex.Lock()
+		reply := s.reply
+		match := reply != nil && opcode == s.opcode && ev.Index == s.index
+		s.replyMutex.Unlock()
+		if match {
+			select {
+			case reply <- ev:
+			default:
+			}
+		}
+		return
+	}
+
+	select {
+	case s.events <- ev:
+	default:
+		log.Debugf("mgmt: event 0x%04x dropped", ev.Code)
+	}
+}
@@ -0,0 +1,250 @@
+package mgmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth"
+	"golang.org/x/sys/unix"
+)
+
+// fakeKernel return a socket connected to a fake management interface
+func fakeKernel(t *testing.T) (*Socket, *os.File) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unix.SetNonblock(fds[0], true)
+	return newSocket(os.NewFile(uintptr(fds[0]), "mgmt")), os.NewFile(uintptr(fds[1]), "kernel")
+}
+
+func event(code uint16, index uint16, params ...byte) []byte {
+	b := make([]byte, 6+len(params))
+	binary.LittleEndian.PutUint16(b, code)
+	binary.LittleEndian.PutUint16(b[2:], index)
+	binary.LittleEndian.PutUint16(b[4:], uint16(len(params)))
+	copy(b[6:], params)
+	return b
+}
+
+func TestSocket(t *testing.T) {
+
+	s, kernel := fakeKernel(t)
+	defer s.Close()
+	defer kernel.Close()
+
+	commands := make(chan []byte, 10)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := kernel.Read(buf)
+			if err != nil {
+				return
+			}
+			cmd := append([]byte{}, buf[:n]...)
+			commands <- cmd
+			opcode := binary.LittleEndian.Uint16(cmd)
+			index := binary.LittleEndian.Uint16(cmd[2:])
+			kernel.Write(event(EventNewSettings, 0, 0x01, 0x02, 0x00, 0x00))
+			switch opcode {
+			case OpReadIndexList:
+				kernel.Write(event(EventCommandComplete, index, 0x03, 0x00, StatusSuccess, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00))
+			case OpSetLE:
+				kernel.Write(event(EventCommandComplete, index, 0x0d, 0x00, StatusSuccess, 0x01, 0x02, 0x00, 0x00))
+			default:
+				kernel.Write(event(EventCommandStatus, index, cmd[0], cmd[1], StatusNotPowered))
+			}
+		}
+	}()
+
+	list, err := s.ReadIndexList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0] != 0 || list[1] != 1 {
+		t.Fatalf("Unexpected index list %v", list)
+	}
+
+	settings, err := s.SetLE(1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !settings.Has(SettingPowered|SettingLE) || settings.Has(SettingBREDR) {
+		t.Fatalf("Unexpected settings 0x%x", settings)
+	}
+	<-commands
+	if cmd := <-commands; binary.LittleEndian.Uint16(cmd[2:]) != 1 || cmd[6] != 1 {
+		t.Fatalf("Unexpected command %x", cmd)
+	}
+
+	_, err = s.SetStaticAddress(0, "C0:11:22:33:44:55")
+	if e, ok := err.(*StatusError); !ok || e.Status != StatusNotPowered {
+		t.Fatalf("Expected a status error, got %v", err)
+	}
+	if cmd := <-commands; cmd[6] != 0x55 || cmd[11] != 0xc0 {
+		t.Fatalf("Unexpected address %x", cmd[6:])
+	}
+	if _, err = s.SetStaticAddress(0, "00:11:22:33:44:55"); err == nil {
+		t.Fatal("Expected an invalid static address")
+	}
+
+	select {
+	case ev := <-s.Events():
+		if ev.Code != EventNewSettings || binary.LittleEndian.Uint32(ev.Params) != 0x0201 {
+			t.Fatalf("Unexpected event %v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an event")
+	}
+}
+
+func TestEncodeLongTermKeys(t *testing.T) {
+
+	b, err := encodeLongTermKeys([]LongTermKey{{
+		Address:     "00:11:22:33:44:55",
+		AddressType: AddressLEPublic,
+		Type:        LTKAuthenticatedP256,
+		EncSize:     16,
+		Value:       [16]byte{0x01},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 2+36 || b[0] != 1 || b[2] != 0x55 || b[8] != AddressLEPublic ||
+		b[9] != LTKAuthenticatedP256 || b[11] != 16 || b[22] != 0x01 {
+		t.Fatalf("Unexpected keys %x", b)
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	ret := make([]byte, 280)
+	copy(ret, []byte{0x55, 0x44, 0x33, 0x22, 0x11, 0x00, 0x09})
+	binary.LittleEndian.PutUint32(ret[13:], uint32(SettingPowered|SettingLE))
+	copy(ret[20:], "hci0 name")
+	info, err := ParseInfo(ret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Address != "00:11:22:33:44:55" || info.Version != 9 || info.Name != "hci0 name" ||
+		!info.Current.Has(SettingLE) {
+		t.Fatalf("Unexpected info %v", info)
+	}
+}
+
+func TestParseDeviceFound(t *testing.T) {
+	params := []byte{0x55, 0x44, 0x33, 0x22, 0x11, 0x00, 0x00, 0xc4, DeviceFoundLegacyPairing, 0, 0, 0, 11, 0,
+		0x04, 0x0d, 0x0c, 0x02, 0x5a, 0x04, 0x09, 'T', 'e', 'l', 0x00}
+	found, err := ParseDeviceFound(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Address != "00:11:22:33:44:55" || found.RSSI != -60 || found.Flags != DeviceFoundLegacyPairing {
+		t.Fatalf("Unexpected device %+v", found)
+	}
+	record, err := found.Record()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Name != "Tel" || record.Class.Major() != bluetooth.MajorPhone || record.Class.MinorName() != "Smartphone" ||
+		!record.Class.Services().Has(bluetooth.ServiceTelephony|bluetooth.ServiceNetworking) {
+		t.Fatalf("Unexpected record %s", record)
+	}
+	if _, err = ParseDeviceFound(params[:20]); err == nil {
+		t.Fatal("Expected a truncated event")
+	}
+}
+
+func TestEncodeConnParams(t *testing.T) {
+	p := DeviceConnParams{Address: "00:11:22:33:44:55", AddressType: AddressLERandom}
+	p.IntervalMin, p.IntervalMax, p.SupervisionTimeout = 6, 12, 100
+	b, err := encodeConnParams([]DeviceConnParams{p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 2+15 || b[2] != 0x55 || b[8] != AddressLERandom || b[9] != 6 || b[11] != 12 || b[15] != 100 {
+		t.Fatalf("Unexpected parameters %x", b)
+	}
+	p.IntervalMin = 1
+	if _, err := encodeConnParams([]DeviceConnParams{p}); err == nil {
+		t.Fatal("Expected invalid parameters")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	if index, err := Index("hci1"); err != nil || index != 1 {
+		t.Fatalf("Unexpected index %d %v", index, err)
+	}
+	for _, id := range []string{"", "hci", "usb0", "hci65535"} {
+		if _, err := Index(id); err == nil {
+			t.Errorf("%s: expected an error", id)
+		}
+	}
+}
+
+func TestParseLocalOOBData(t *testing.T) {
+
+	ret := make([]byte, 64)
+	for i := range ret {
+		ret[i] = byte(i)
+	}
+	data, err := parseLocalOOBData(ret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Hash192[0] != 0 || data.Randomizer192[0] != 16 || data.Hash256[0] != 32 ||
+		len(data.Randomizer256) != 16 || data.Randomizer256[15] != 63 {
+		t.Fatalf("Unexpected data %+v", data)
+	}
+
+	// no Secure Connections
+	data, err = parseLocalOOBData(ret[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Randomizer192) != 16 || data.Hash256 != nil || data.Randomizer256 != nil {
+		t.Fatalf("Unexpected data %+v", data)
+	}
+
+	if _, err = parseLocalOOBData(ret[:40]); err == nil {
+		t.Fatal("Expected an invalid reply")
+	}
+}
+
+func TestParseLocalOOBExtData(t *testing.T) {
+	eir := []byte{0x07, 0x1b, 0x55, 0x44, 0x33, 0x22, 0x11, 0xc0}
+	ret := append([]byte{1<<AddressLEPublic | 1<<AddressLERandom, byte(len(eir)), 0}, eir...)
+	parsed, err := parseLocalOOBExtData(ret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed, eir) {
+		t.Fatalf("Unexpected EIR %x", parsed)
+	}
+	if _, err = parseLocalOOBExtData(ret[:5]); err == nil {
+		t.Fatal("Expected a truncated reply")
+	}
+}
+
+func TestEncodeRemoteOOBData(t *testing.T) {
+
+	value := bytes.Repeat([]byte{0xaa}, 16)
+	b, err := encodeRemoteOOBData("00:11:22:33:44:55", AddressLEPublic, &OOBData{Hash256: value, Randomizer256: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the missing P-192 values are zeros
+	if len(b) != 7+64 || b[0] != 0x55 || b[6] != AddressLEPublic ||
+		!bytes.Equal(b[7:39], make([]byte, 32)) || !bytes.Equal(b[39:], bytes.Repeat([]byte{0xaa}, 32)) {
+		t.Fatalf("Unexpected parameters %x", b)
+	}
+
+	if _, err = encodeRemoteOOBData("00:11:22:33:44:55", AddressBREDR, &OOBData{Hash192: value[:8]}); err == nil {
+		t.Fatal("Expected an invalid value length")
+	}
+	if _, err = encodeRemoteOOBData("00:11:22:33:44:55", AddressBREDR, nil); err == nil {
+		t.Fatal("Expected missing data")
+	}
+}
@@ -0,0 +1,123 @@
+package mgmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/muka/go-bluetooth/linux"
+)
+
+// oobValueSize the size of the OOB hash and randomizer values
+const oobValueSize = 16
+
+//OOBData out of band pairing data, 16 bytes values.
+// The P-192 pair is used by BR/EDR Secure Simple Pairing, the P-256 pair
+// holds the Secure Connections confirmation and random values
+type OOBData struct {
+	Hash192       []byte
+	Randomizer192 []byte
+	Hash256       []byte
+	Randomizer256 []byte
+}
+
+//Index return the index of an adapter, eg. 0 for hci0
+func Index(adapterID string) (uint16, error) {
+	index, err := strconv.ParseUint(strings.TrimPrefix(adapterID, "hci"), 10, 16)
+	if err != nil || index == IndexNone {
+		return 0, errors.New("Invalid adapter " + adapterID)
+	}
+	return uint16(index), nil
+}
+
+//ReadLocalOOBData generate and return the local OOB data of a BR/EDR controller,
+// to be passed to the remote (eg. via NFC). The P-256 values are returned when
+// Secure Connections is enabled. Every call invalidates the previous values
+func (s *Socket) ReadLocalOOBData(index uint16) (*OOBData, error) {
+	ret, err := s.Send(OpReadLocalOOBData, index, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseLocalOOBData(ret)
+}
+
+func parseLocalOOBData(ret []byte) (*OOBData, error) {
+	if len(ret) != 2*oobValueSize && len(ret) != 4*oobValueSize {
+		return nil, fmt.Errorf("Invalid ReadLocalOOBData reply (%d bytes)", len(ret))
+	}
+	value := func(i int) []byte {
+		return append([]byte{}, ret[i*oobValueSize:(i+1)*oobValueSize]...)
+	}
+	data := &OOBData{
+		Hash192:       value(0),
+		Randomizer192: value(1),
+	}
+	if len(ret) == 4*oobValueSize {
+		data.Hash256 = value(2)
+		data.Randomizer256 = value(3)
+	}
+	return data, nil
+}
+
+//ReadLocalOOBExtData return the local OOB data as EIR, for the address types in
+// addressTypes: 1<<AddressBREDR, or 1<<AddressLEPublic|1<<AddressLERandom for
+// LE, where the EIR holds the address, the role and the Secure Connections
+// values. See hci.ParseEIR
+func (s *Socket) ReadLocalOOBExtData(index uint16, addressTypes uint8) ([]byte, error) {
+	ret, err := s.Send(OpReadLocalOOBExtData, index, []byte{addressTypes})
+	if err != nil {
+		return nil, err
+	}
+	return parseLocalOOBExtData(ret)
+}
+
+func parseLocalOOBExtData(ret []byte) ([]byte, error) {
+	if len(ret) < 3 {
+		return nil, fmt.Errorf("Invalid ReadLocalOOBExtData reply (%d bytes)", len(ret))
+	}
+	size := int(binary.LittleEndian.Uint16(ret[1:]))
+	if len(ret) < 3+size {
+		return nil, errors.New("ReadLocalOOBExtData reply truncated")
+	}
+	return append([]byte{}, ret[3:3+size]...), nil
+}
+
+//AddRemoteOOBData store the OOB data received from a remote device, it will be
+// used by the next pairing with that address. The missing values are sent as
+// zeros, which disables the matching pairing method. The P-192 values are not
+// used with LE addresses
+func (s *Socket) AddRemoteOOBData(index uint16, address string, addressType uint8, data *OOBData) error {
+	params, err := encodeRemoteOOBData(address, addressType, data)
+	if err != nil {
+		return err
+	}
+	_, err = s.Send(OpAddRemoteOOBData, index, params)
+	return err
+}
+
+func encodeRemoteOOBData(address string, addressType uint8, data *OOBData) ([]byte, error) {
+
+	if data == nil {
+		return nil, errors.New("OOB data is required")
+	}
+	addr, err := linux.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 7+4*oobValueSize)
+	copy(b, addr[:])
+	b[6] = addressType
+	for i, value := range [][]byte{data.Hash192, data.Randomizer192, data.Hash256, data.Randomizer256} {
+		if len(value) == 0 {
+			continue
+		}
+		if len(value) != oobValueSize {
+			return nil, errors.New("OOB values must be 16 bytes long")
+		}
+		copy(b[7+i*oobValueSize:], value)
+	}
+	return b, nil
+}
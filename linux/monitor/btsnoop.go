@@ -0,0 +1,58 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// btsnoopMonitor the datalink of the btsnoop files holding monitor packets,
+// as written by btmon -w
+const btsnoopMonitor = 2001
+
+// btsnoopEpoch the btsnoop timestamps count the microseconds since year 0
+const btsnoopEpoch = 0x00dcddb30f2f8000
+
+//BtsnoopWriter write monitor packets to a btsnoop file, readable by btmon -r
+// or Wireshark
+type BtsnoopWriter struct {
+	w      io.Writer
+	header bool
+}
+
+//NewBtsnoopWriter create a writer, the file header is written with the first
+// packet
+func NewBtsnoopWriter(w io.Writer) *BtsnoopWriter {
+	return &BtsnoopWriter{w: w}
+}
+
+//Write a packet
+func (b *BtsnoopWriter) Write(p *Packet) error {
+
+	if !b.header {
+		h := make([]byte, 16)
+		copy(h, "btsnoop\x00")
+		binary.BigEndian.PutUint32(h[8:], 1)
+		binary.BigEndian.PutUint32(h[12:], btsnoopMonitor)
+		if _, err := b.w.Write(h); err != nil {
+			return err
+		}
+		b.header = true
+	}
+
+	t := p.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	r := make([]byte, 24+len(p.Data))
+	binary.BigEndian.PutUint32(r, uint32(len(p.Data)))
+	binary.BigEndian.PutUint32(r[4:], uint32(len(p.Data)))
+	// the flags of the monitor datalink hold the index and the opcode
+	binary.BigEndian.PutUint32(r[8:], uint32(p.Index)<<16|uint32(p.Opcode))
+	binary.BigEndian.PutUint64(r[16:], uint64(t.UnixNano()/int64(time.Microsecond))+btsnoopEpoch)
+	copy(r[24:], p.Data)
+
+	_, err := b.w.Write(r)
+	return err
+}
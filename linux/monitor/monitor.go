@@ -0,0 +1,171 @@
+//Package monitor read the Bluetooth monitor socket, the HCI traffic of all
+// the adapters as seen by btmon. The packets can be decoded or written to a
+// btsnoop file, eg. to capture diagnostics on demand.
+//
+// The socket requires CAP_NET_RAW
+package monitor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/muka/go-bluetooth/linux/hci"
+	"golang.org/x/sys/unix"
+)
+
+// Opcodes of the monitor packets
+const (
+	OpNewIndex    = 0
+	OpDelIndex    = 1
+	OpCommand     = 2
+	OpEvent       = 3
+	OpACLTx       = 4
+	OpACLRx       = 5
+	OpSCOTx       = 6
+	OpSCORx       = 7
+	OpOpenIndex   = 8
+	OpCloseIndex  = 9
+	OpIndexInfo   = 10
+	OpVendorDiag  = 11
+	OpSystemNote  = 12
+	OpUserLogging = 13
+	OpISOTx       = 14
+	OpISORx       = 15
+	OpCtrlOpen    = 16
+	OpCtrlClose   = 17
+	OpCtrlCommand = 18
+	OpCtrlEvent   = 19
+)
+
+//IndexNone the index of the packets not related to an adapter
+const IndexNone = 0xffff
+
+//Packet a packet of the monitor socket
+type Packet struct {
+	Opcode uint16
+	// Index the adapter, eg. 0 for hci0
+	Index uint16
+	// Time the packet has been read
+	Time time.Time
+	Data []byte
+}
+
+//ParsePacket decode a monitor packet
+func ParsePacket(b []byte) (*Packet, error) {
+	if len(b) < 6 {
+		return nil, fmt.Errorf("Monitor packet too short (%d bytes)", len(b))
+	}
+	size := int(binary.LittleEndian.Uint16(b[4:]))
+	if size != len(b)-6 {
+		return nil, fmt.Errorf("Monitor packet: invalid length %d", size)
+	}
+	return &Packet{
+		Opcode: binary.LittleEndian.Uint16(b),
+		Index:  binary.LittleEndian.Uint16(b[2:]),
+		Data:   append([]byte{}, b[6:]...),
+	}, nil
+}
+
+//Command return the opcode and parameters of an OpCommand packet
+func (p *Packet) Command() (uint16, []byte, bool) {
+	if p.Opcode != OpCommand || len(p.Data) < 3 {
+		return 0, nil, false
+	}
+	return binary.LittleEndian.Uint16(p.Data), p.Data[3:], true
+}
+
+//Event return the event of an OpEvent packet
+func (p *Packet) Event() (*hci.Event, bool) {
+	if p.Opcode != OpEvent {
+		return nil, false
+	}
+	ev, err := hci.ParseEvent(p.Data)
+	if err != nil {
+		return nil, false
+	}
+	return ev, true
+}
+
+//Index an adapter announced by an OpNewIndex packet
+type Index struct {
+	// Type 0x00 primary controller, 0x01 AMP
+	Type uint8
+	// Bus eg. 0x01 USB, 0x03 UART
+	Bus     uint8
+	Address string
+	Name    string
+}
+
+//NewIndex return the adapter of an OpNewIndex packet
+func (p *Packet) NewIndex() (*Index, bool) {
+	if p.Opcode != OpNewIndex || len(p.Data) < 16 {
+		return nil, false
+	}
+	return &Index{
+		Type:    p.Data[0],
+		Bus:     p.Data[1],
+		Address: hci.FormatAddress(p.Data[2:8]),
+		Name:    strings.TrimRight(string(p.Data[8:16]), "\x00"),
+	}, true
+}
+
+//Monitor the monitor socket
+type Monitor struct {
+	file *os.File
+	buf  []byte
+}
+
+//Open the monitor socket, the open adapters are announced first with
+// OpNewIndex packets
+func Open() (*Monitor, error) {
+
+	fd, err := unix.Socket(unix.AF_BLUETOOTH, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.BTPROTO_HCI)
+	if err != nil {
+		return nil, err
+	}
+
+	err = unix.Bind(fd, &unix.SockaddrHCI{Dev: IndexNone, Channel: unix.HCI_CHANNEL_MONITOR})
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	return newMonitor(os.NewFile(uintptr(fd), "monitor")), nil
+}
+
+func newMonitor(file *os.File) *Monitor {
+	return &Monitor{
+		file: file,
+		buf:  make([]byte, 6+0xffff),
+	}
+}
+
+//Read the next packet, it blocks until one is received or the monitor is
+// closed. It is not safe for concurrent use
+func (m *Monitor) Read() (*Packet, error) {
+	for {
+		n, err := m.file.Read(m.buf)
+		if err != nil {
+			return nil, err
+		}
+		p, err := ParsePacket(m.buf[:n])
+		if err != nil {
+			continue
+		}
+		p.Time = time.Now()
+		return p, nil
+	}
+}
+
+//SetReadDeadline set the deadline of Read
+func (m *Monitor) SetReadDeadline(t time.Time) error {
+	return m.file.SetReadDeadline(t)
+}
+
+//Close the socket, a pending Read returns an error
+func (m *Monitor) Close() error {
+	return m.file.Close()
+}
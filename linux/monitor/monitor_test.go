@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth/linux/hci"
+	"golang.org/x/sys/unix"
+)
+
+func TestMonitor(t *testing.T) {
+
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unix.SetNonblock(fds[0], true)
+	m := newMonitor(os.NewFile(uintptr(fds[0]), "monitor"))
+	defer m.Close()
+	kernel := os.NewFile(uintptr(fds[1]), "kernel")
+	defer kernel.Close()
+
+	kernel.Write([]byte{OpNewIndex, 0, 0, 0, 16, 0, 0x00, 0x01, 0x55, 0x44, 0x33, 0x22, 0x11, 0x00, 'h', 'c', 'i', '0', 0, 0, 0, 0})
+	kernel.Write([]byte{OpCommand, 0, 0, 0, 4, 0, 0x0c, 0x20, 1, 0x01})
+	kernel.Write([]byte{OpEvent, 0, 0, 0, 6, 0, hci.EventCommandComplete, 4, 1, 0x0c, 0x20, 0x00})
+
+	m.SetReadDeadline(time.Now().Add(time.Second))
+
+	p, err := m.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index, ok := p.NewIndex(); !ok || index.Name != "hci0" || index.Address != "00:11:22:33:44:55" {
+		t.Fatalf("Unexpected index %v", index)
+	}
+
+	p, err = m.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opcode, params, ok := p.Command(); !ok || opcode != hci.OpLESetScanEnable || !bytes.Equal(params, []byte{0x01}) {
+		t.Fatalf("Unexpected command %v", p)
+	}
+
+	p, err = m.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, ok := p.Event(); !ok || ev.Code != hci.EventCommandComplete {
+		t.Fatalf("Unexpected event %v", p)
+	}
+
+	buf := new(bytes.Buffer)
+	w := NewBtsnoopWriter(buf)
+	if err := w.Write(p); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if len(b) != 16+24+6 || string(b[:8]) != "btsnoop\x00" || binary.BigEndian.Uint32(b[12:]) != btsnoopMonitor {
+		t.Fatalf("Unexpected btsnoop %x", b)
+	}
+	if binary.BigEndian.Uint32(b[16:]) != 6 || binary.BigEndian.Uint32(b[24:]) != OpEvent {
+		t.Fatalf("Unexpected record %x", b[16:])
+	}
+}
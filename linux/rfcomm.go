@@ -0,0 +1,133 @@
+package linux
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	afBluetooth   = 31
+	btprotoRFCOMM = 3
+)
+
+// struct sockaddr_rc from <bluetooth/rfcomm.h>
+type rawSockaddrRFCOMM struct {
+	Family  uint16
+	Bdaddr  [6]uint8
+	Channel uint8
+}
+
+//ParseAddress parse an address in the form 00:11:22:33:44:55 to the
+// little endian bdaddr_t representation used by the kernel
+func ParseAddress(address string) ([6]byte, error) {
+	var b [6]byte
+	parts := strings.Split(address, ":")
+	if len(parts) != 6 {
+		return b, errors.New("Invalid bluetooth address " + address)
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 16, 8)
+		if err != nil || len(p) != 2 {
+			return b, errors.New("Invalid bluetooth address " + address)
+		}
+		b[5-i] = byte(v)
+	}
+	return b, nil
+}
+
+//DialRFCOMM connect to an RFCOMM channel on a remote device
+func DialRFCOMM(address string, channel uint8) (*SocketConn, error) {
+	return DialRFCOMMTimeout(address, channel, 0)
+}
+
+//DialRFCOMMTimeout connect to an RFCOMM channel on a remote device, failing
+// if the connection is not established within timeout. A zero timeout waits
+// for the kernel connection timeout
+func DialRFCOMMTimeout(address string, channel uint8, timeout time.Duration) (*SocketConn, error) {
+
+	bdaddr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Socket(afBluetooth, unix.SOCK_STREAM|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, btprotoRFCOMM)
+	if err != nil {
+		return nil, err
+	}
+
+	sa := rawSockaddrRFCOMM{
+		Family:  afBluetooth,
+		Bdaddr:  bdaddr,
+		Channel: channel,
+	}
+
+	err = connectSocket(fd, unsafe.Pointer(&sa), unsafe.Sizeof(sa), timeout)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	local := &BtAddr{Proto: "rfcomm"}
+	remote := &BtAddr{Proto: "rfcomm", Address: strings.ToUpper(address), Channel: uint16(channel)}
+
+	return NewSocketConn(fd, local, remote)
+}
+
+// connectSocket run a non-blocking connect and wait for its completion
+func connectSocket(fd int, sa unsafe.Pointer, size uintptr, timeout time.Duration) error {
+
+	_, _, errno := unix.Syscall(unix.SYS_CONNECT, uintptr(fd), uintptr(sa), size)
+	switch errno {
+	case 0:
+		return nil
+	case unix.EINPROGRESS, unix.EAGAIN:
+	default:
+		return errno
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		wait := -1
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				return syscall.ETIMEDOUT
+			}
+			wait = int(left / time.Millisecond)
+			if wait == 0 {
+				wait = 1
+			}
+		}
+
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLOUT}}
+		n, err := unix.Poll(fds, wait)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+
+		soerr, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_ERROR)
+		if err != nil {
+			return err
+		}
+		if soerr != 0 {
+			return syscall.Errno(soerr)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,22 @@
+package linux
+
+import "testing"
+
+func TestParseAddress(t *testing.T) {
+
+	b, err := ParseAddress("00:11:22:AA:bb:FF")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [6]byte{0xff, 0xbb, 0xaa, 0x22, 0x11, 0x00}
+	if b != expected {
+		t.Fatalf("Unexpected bdaddr %x", b)
+	}
+
+	for _, invalid := range []string{"", "00:11:22:33:44", "00:11:22:33:44:GG", "001:11:22:33:44:55"} {
+		if _, err := ParseAddress(invalid); err == nil {
+			t.Fatalf("Expected an error parsing %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	btprotoSCO = 2
+	solSCO     = 17
+	scoOptions = 1
+)
+
+// interval at which a pending Accept checks if the listener has been closed
+const scoAcceptPoll = 200 * time.Millisecond
+
+// struct sockaddr_sco from <bluetooth/sco.h>
+type rawSockaddrSCO struct {
+	Family uint16
+	Bdaddr [6]uint8
+}
+
+//DialSCO open the synchronous audio link to a remote device, usually after
+// the service level connection of HFP or HSP has been established
+func DialSCO(address string, timeout time.Duration) (*SCOConn, error) {
+
+	bdaddr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Socket(afBluetooth, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, btprotoSCO)
+	if err != nil {
+		return nil, err
+	}
+
+	sa := rawSockaddrSCO{
+		Family: afBluetooth,
+		Bdaddr: bdaddr,
+	}
+
+	err = connectSocket(fd, unsafe.Pointer(&sa), unsafe.Sizeof(sa), timeout)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	local := &BtAddr{Proto: "sco"}
+	remote := &BtAddr{Proto: "sco", Address: strings.ToUpper(address)}
+
+	return newSCOConn(fd, local, remote)
+}
+
+//ListenSCO accept incoming audio links on the adapter with the given address,
+// an empty address listens on every adapter
+func ListenSCO(address string) (*SCOListener, error) {
+
+	var bdaddr [6]byte
+	if address != "" {
+		var err error
+		bdaddr, err = ParseAddress(address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fd, err := unix.Socket(afBluetooth, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, btprotoSCO)
+	if err != nil {
+		return nil, err
+	}
+
+	sa := rawSockaddrSCO{
+		Family: afBluetooth,
+		Bdaddr: bdaddr,
+	}
+
+	_, _, errno := unix.Syscall(unix.SYS_BIND, uintptr(fd), uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa))
+	if errno != 0 {
+		unix.Close(fd)
+		return nil, errno
+	}
+
+	err = unix.Listen(fd, 1)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	l := &SCOListener{
+		fd:   fd,
+		addr: &BtAddr{Proto: "sco", Address: strings.ToUpper(address)},
+	}
+
+	return l, nil
+}
+
+//SCOListener accept SCO audio connections
+type SCOListener struct {
+	fd     int
+	addr   *BtAddr
+	mutex  sync.Mutex
+	closed bool
+}
+
+//Accept wait for the next audio connection
+func (l *SCOListener) Accept() (*SCOConn, error) {
+	for {
+
+		if l.isClosed() {
+			return nil, errors.New("SCO listener closed")
+		}
+
+		fds := []unix.PollFd{{Fd: int32(l.fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, int(scoAcceptPoll/time.Millisecond))
+		if err == unix.EINTR || n == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// accept the raw address, x/sys/unix does not decode SCO addresses
+		var sa rawSockaddrSCO
+		size := uint32(unsafe.Sizeof(sa))
+		nfd, _, errno := unix.Syscall6(unix.SYS_ACCEPT4, uintptr(l.fd), uintptr(unsafe.Pointer(&sa)), uintptr(unsafe.Pointer(&size)), unix.SOCK_CLOEXEC, 0, 0)
+		if errno == unix.EAGAIN || errno == unix.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return nil, errno
+		}
+
+		remote := &BtAddr{Proto: "sco", Address: formatAddress(sa.Bdaddr)}
+		return newSCOConn(int(nfd), l.addr, remote)
+	}
+}
+
+func (l *SCOListener) isClosed() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.closed
+}
+
+//Addr return the listening address
+func (l *SCOListener) Addr() *BtAddr {
+	return l.addr
+}
+
+//Close stop listening, a pending Accept returns an error
+func (l *SCOListener) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	return unix.Close(l.fd)
+}
+
+//SCOConn an audio connection, every Read returns a single packet and writes
+// should not exceed MTU bytes
+type SCOConn struct {
+	*SocketConn
+	MTU int
+}
+
+func newSCOConn(fd int, local, remote *BtAddr) (*SCOConn, error) {
+
+	// struct sco_options, the socket option has to be read before the
+	// descriptor is handed over to the runtime
+	var mtu uint16
+	size := uint32(unsafe.Sizeof(mtu))
+	_, _, errno := unix.Syscall6(unix.SYS_GETSOCKOPT, uintptr(fd), solSCO, scoOptions, uintptr(unsafe.Pointer(&mtu)), uintptr(unsafe.Pointer(&size)), 0)
+	if errno != 0 {
+		unix.Close(fd)
+		return nil, errno
+	}
+
+	conn, err := NewSocketConn(fd, local, remote)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	return &SCOConn{conn, int(mtu)}, nil
+}
+
+// formatAddress convert a little endian bdaddr_t to the 00:11:22:33:44:55 form
+func formatAddress(b [6]byte) string {
+	return fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X", b[5], b[4], b[3], b[2], b[1], b[0])
+}
@@ -0,0 +1,102 @@
+package linux
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+//BtAddr the address of a bluetooth socket endpoint
+type BtAddr struct {
+	// Proto the socket protocol, eg. rfcomm or l2cap
+	Proto string
+	// Address the device address
+	Address string
+	// Channel the RFCOMM channel or L2CAP PSM
+	Channel uint16
+}
+
+//Network return the socket protocol
+func (a *BtAddr) Network() string {
+	return a.Proto
+}
+
+func (a *BtAddr) String() string {
+	return a.Address + "/" + strconv.Itoa(int(a.Channel))
+}
+
+//NewSocketConn wrap a connected bluetooth socket file descriptor as a net.Conn.
+// The descriptor is owned by the returned connection and closed with it
+func NewSocketConn(fd int, local, remote *BtAddr) (*SocketConn, error) {
+
+	// a non-blocking descriptor is registered with the runtime poller,
+	// this enables deadlines and unblocks pending reads on Close
+	err := syscall.SetNonblock(fd, true)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &SocketConn{
+		file:   os.NewFile(uintptr(fd), remote.Network()+":"+remote.String()),
+		local:  local,
+		remote: remote,
+	}
+
+	return c, nil
+}
+
+//SocketConn a net.Conn backed by a bluetooth socket
+type SocketConn struct {
+	file   *os.File
+	local  *BtAddr
+	remote *BtAddr
+}
+
+//Read data from the socket
+func (c *SocketConn) Read(b []byte) (int, error) {
+	return c.file.Read(b)
+}
+
+//Write data to the socket
+func (c *SocketConn) Write(b []byte) (int, error) {
+	return c.file.Write(b)
+}
+
+//Close the socket
+func (c *SocketConn) Close() error {
+	return c.file.Close()
+}
+
+//Fd return the socket file descriptor, eg. to hand it over to an audio
+// stack. As with os.File.Fd the descriptor is put in blocking mode and
+// deadlines stop working
+func (c *SocketConn) Fd() uintptr {
+	return c.file.Fd()
+}
+
+//LocalAddr return the local adapter address
+func (c *SocketConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+//RemoteAddr return the remote device address
+func (c *SocketConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+//SetDeadline set read and write deadlines
+func (c *SocketConn) SetDeadline(t time.Time) error {
+	return c.file.SetDeadline(t)
+}
+
+//SetReadDeadline set the read deadline
+func (c *SocketConn) SetReadDeadline(t time.Time) error {
+	return c.file.SetReadDeadline(t)
+}
+
+//SetWriteDeadline set the write deadline
+func (c *SocketConn) SetWriteDeadline(t time.Time) error {
+	return c.file.SetWriteDeadline(t)
+}
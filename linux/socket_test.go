@@ -0,0 +1,63 @@
+package linux
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSocketConn(t *testing.T) {
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := os.NewFile(uintptr(fds[1]), "remote")
+	defer remote.Close()
+
+	c, err := NewSocketConn(fds[0], &BtAddr{Proto: "rfcomm", Address: "00:11:22:33:44:55"},
+		&BtAddr{Proto: "rfcomm", Address: "AA:BB:CC:DD:EE:FF", Channel: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var _ net.Conn = c
+
+	if c.LocalAddr().String() != "00:11:22:33:44:55/0" || c.RemoteAddr().Network() != "rfcomm" ||
+		c.RemoteAddr().String() != "AA:BB:CC:DD:EE:FF/3" {
+		t.Fatalf("Unexpected addresses %s %s", c.LocalAddr(), c.RemoteAddr())
+	}
+
+	if _, err = c.Write([]byte("ATZ\r")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	if n, err := remote.Read(buf); err != nil || string(buf[:n]) != "ATZ\r" {
+		t.Fatalf("Unexpected data %q %v", buf[:n], err)
+	}
+
+	// the descriptor is non-blocking, deadlines apply
+	c.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err = c.Read(buf); !os.IsTimeout(err) {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+
+	// Close unblocks a pending read
+	c.SetReadDeadline(time.Time{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Read(buf)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	c.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Expected an error reading a closed connection")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read not unblocked by Close")
+	}
+}
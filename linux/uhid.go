@@ -0,0 +1,296 @@
+package linux
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//UHIDPath the uhid character device
+const UHIDPath = "/dev/uhid"
+
+//BusBluetooth the HID bus type of Bluetooth devices
+const BusBluetooth uint16 = 0x05
+
+// HID report types
+const (
+	HIDFeatureReport uint8 = 0
+	HIDOutputReport  uint8 = 1
+	HIDInputReport   uint8 = 2
+)
+
+// uhid event types, see linux/uhid.h
+const (
+	uhidDestroy        uint32 = 1
+	uhidStart          uint32 = 2
+	uhidStop           uint32 = 3
+	uhidOpen           uint32 = 4
+	uhidClose          uint32 = 5
+	uhidOutput         uint32 = 6
+	uhidGetReport      uint32 = 9
+	uhidGetReportReply uint32 = 10
+	uhidCreate2        uint32 = 11
+	uhidInput2         uint32 = 12
+	uhidSetReport      uint32 = 13
+	uhidSetReportReply uint32 = 14
+)
+
+const (
+	uhidDataMax       = 4096
+	uhidEventSize     = 4 + 128 + 64 + 64 + 2 + 2 + 4*4 + uhidDataMax
+	uhidMaxDescriptor = 4096
+)
+
+//UHIDConfig describe the virtual HID device to create
+type UHIDConfig struct {
+	Name string
+	// Phys the physical location, eg. the adapter address
+	Phys string
+	// Uniq the unique identifier, eg. the device address
+	Uniq             string
+	Bus              uint16
+	Vendor           uint32
+	Product          uint32
+	Version          uint32
+	Country          uint32
+	ReportDescriptor []byte
+}
+
+//UHIDHandlers hooks called for the requests the kernel sends to the device.
+// Every hook is optional
+type UHIDHandlers struct {
+	// OnStart the HID driver is bound to the device
+	OnStart func()
+	// OnStop the HID driver is unbound
+	OnStop func()
+	// OnOpen a user space program opened the input device
+	OnOpen func()
+	// OnClose the last user space program closed the input device
+	OnClose func()
+	// OnOutput an output report to forward to the device, eg. keyboard LEDs
+	OnOutput func(reportType uint8, data []byte)
+	// OnGetReport read a report from the device
+	OnGetReport func(reportNumber uint8, reportType uint8) ([]byte, error)
+	// OnSetReport write a report to the device
+	OnSetReport func(reportNumber uint8, reportType uint8, data []byte) error
+}
+
+//NewUHIDDevice create a kernel HID device backed by user space, reports
+// received from a remote device can then be forwarded with Input
+func NewUHIDDevice(config UHIDConfig, handlers UHIDHandlers) (*UHIDDevice, error) {
+
+	if len(config.ReportDescriptor) == 0 || len(config.ReportDescriptor) > uhidMaxDescriptor {
+		return nil, errors.New("Invalid report descriptor size")
+	}
+	if config.Bus == 0 {
+		config.Bus = BusBluetooth
+	}
+
+	file, err := os.OpenFile(UHIDPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &UHIDDevice{
+		file:     file,
+		handlers: handlers,
+	}
+
+	err = d.write(encodeUHIDCreate2(config))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	go d.run()
+
+	return d, nil
+}
+
+//UHIDDevice a virtual HID device
+type UHIDDevice struct {
+	file     *os.File
+	handlers UHIDHandlers
+	mutex    sync.Mutex
+	closed   bool
+}
+
+func (d *UHIDDevice) write(ev []byte) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return errors.New("uhid device closed")
+	}
+	_, err := d.file.Write(ev)
+	return err
+}
+
+//Input forward an input report to the kernel
+func (d *UHIDDevice) Input(report []byte) error {
+	if len(report) > uhidDataMax {
+		return errors.New("Report too large")
+	}
+	ev := make([]byte, uhidEventSize)
+	binary.LittleEndian.PutUint32(ev, uhidInput2)
+	binary.LittleEndian.PutUint16(ev[4:], uint16(len(report)))
+	copy(ev[6:], report)
+	return d.write(ev)
+}
+
+//Close destroy the device
+func (d *UHIDDevice) Close() error {
+	ev := make([]byte, uhidEventSize)
+	binary.LittleEndian.PutUint32(ev, uhidDestroy)
+	d.write(ev)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.closed = true
+	return d.file.Close()
+}
+
+func (d *UHIDDevice) run() {
+	buf := make([]byte, uhidEventSize)
+	for {
+		n, err := d.file.Read(buf)
+		if err != nil {
+			if err != io.EOF && !d.isClosed() {
+				log.Errorf("uhid: read failed: %s", err.Error())
+			}
+			return
+		}
+		if n < 4 {
+			continue
+		}
+		d.handle(buf[:n])
+	}
+}
+
+func (d *UHIDDevice) isClosed() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.closed
+}
+
+func (d *UHIDDevice) handle(ev []byte) {
+
+	h := d.handlers
+	payload := ev[4:]
+
+	switch binary.LittleEndian.Uint32(ev) {
+	case uhidStart:
+		if h.OnStart != nil {
+			h.OnStart()
+		}
+	case uhidStop:
+		if h.OnStop != nil {
+			h.OnStop()
+		}
+	case uhidOpen:
+		if h.OnOpen != nil {
+			h.OnOpen()
+		}
+	case uhidClose:
+		if h.OnClose != nil {
+			h.OnClose()
+		}
+	case uhidOutput:
+		// data[4096], size u16, rtype u8
+		if h.OnOutput != nil && len(payload) >= uhidDataMax+3 {
+			size := int(binary.LittleEndian.Uint16(payload[uhidDataMax:]))
+			if size > uhidDataMax {
+				size = uhidDataMax
+			}
+			h.OnOutput(payload[uhidDataMax+2], payload[:size])
+		}
+	case uhidGetReport:
+		// id u32, rnum u8, rtype u8
+		if len(payload) < 6 {
+			return
+		}
+		id := binary.LittleEndian.Uint32(payload)
+		var data []byte
+		var err error
+		if h.OnGetReport != nil {
+			data, err = h.OnGetReport(payload[4], payload[5])
+		} else {
+			err = errors.New("not supported")
+		}
+		d.write(encodeUHIDGetReportReply(id, data, err))
+	case uhidSetReport:
+		// id u32, rnum u8, rtype u8, size u16, data[4096]
+		if len(payload) < 8 {
+			return
+		}
+		id := binary.LittleEndian.Uint32(payload)
+		size := int(binary.LittleEndian.Uint16(payload[6:]))
+		if size > len(payload)-8 {
+			size = len(payload) - 8
+		}
+		var err error
+		if h.OnSetReport != nil {
+			err = h.OnSetReport(payload[4], payload[5], payload[8:8+size])
+		} else {
+			err = errors.New("not supported")
+		}
+		d.write(encodeUHIDSetReportReply(id, err))
+	}
+}
+
+func putString(b []byte, s string) {
+	n := copy(b, s)
+	if n == len(b) {
+		n--
+	}
+	b[n] = 0
+}
+
+func encodeUHIDCreate2(c UHIDConfig) []byte {
+	ev := make([]byte, uhidEventSize)
+	binary.LittleEndian.PutUint32(ev, uhidCreate2)
+	p := ev[4:]
+	putString(p[0:128], c.Name)
+	putString(p[128:192], c.Phys)
+	putString(p[192:256], c.Uniq)
+	binary.LittleEndian.PutUint16(p[256:], uint16(len(c.ReportDescriptor)))
+	binary.LittleEndian.PutUint16(p[258:], c.Bus)
+	binary.LittleEndian.PutUint32(p[260:], c.Vendor)
+	binary.LittleEndian.PutUint32(p[264:], c.Product)
+	binary.LittleEndian.PutUint32(p[268:], c.Version)
+	binary.LittleEndian.PutUint32(p[272:], c.Country)
+	copy(p[276:], c.ReportDescriptor)
+	return ev
+}
+
+// EIO as returned to the kernel on failures
+const uhidErrIO = 5
+
+func encodeUHIDGetReportReply(id uint32, data []byte, err error) []byte {
+	ev := make([]byte, uhidEventSize)
+	binary.LittleEndian.PutUint32(ev, uhidGetReportReply)
+	binary.LittleEndian.PutUint32(ev[4:], id)
+	if err != nil {
+		binary.LittleEndian.PutUint16(ev[8:], uhidErrIO)
+		return ev
+	}
+	if len(data) > uhidDataMax {
+		data = data[:uhidDataMax]
+	}
+	binary.LittleEndian.PutUint16(ev[10:], uint16(len(data)))
+	copy(ev[12:], data)
+	return ev
+}
+
+func encodeUHIDSetReportReply(id uint32, err error) []byte {
+	ev := make([]byte, uhidEventSize)
+	binary.LittleEndian.PutUint32(ev, uhidSetReportReply)
+	binary.LittleEndian.PutUint32(ev[4:], id)
+	if err != nil {
+		binary.LittleEndian.PutUint16(ev[8:], uhidErrIO)
+	}
+	return ev
+}
@@ -0,0 +1,33 @@
+package linux
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestEncodeUHIDCreate2(t *testing.T) {
+
+	ev := encodeUHIDCreate2(UHIDConfig{
+		Name:             "keyboard",
+		Bus:              BusBluetooth,
+		Vendor:           0x046d,
+		Product:          0xb342,
+		ReportDescriptor: []byte{0x05, 0x01, 0x09, 0x06},
+	})
+
+	if len(ev) != 4376 {
+		t.Fatalf("Unexpected event size %d", len(ev))
+	}
+	if binary.LittleEndian.Uint32(ev) != uhidCreate2 {
+		t.Fatal("Unexpected event type")
+	}
+	if string(ev[4:12]) != "keyboard" || ev[12] != 0 {
+		t.Fatal("Unexpected name")
+	}
+	if binary.LittleEndian.Uint16(ev[260:]) != 4 || binary.LittleEndian.Uint16(ev[262:]) != BusBluetooth {
+		t.Fatal("Unexpected descriptor size or bus")
+	}
+	if binary.LittleEndian.Uint32(ev[264:]) != 0x046d || ev[280] != 0x05 {
+		t.Fatal("Unexpected vendor or descriptor")
+	}
+}
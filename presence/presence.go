@@ -0,0 +1,136 @@
+//Package presence broadcast small readings without connections, eg. a
+// counter or a last seen value, in the ServiceData of the advertisement of a
+// peripheral, and decode them on the scanners.
+//
+// The payload of the service data is a version byte, a counter incremented on
+// each update, little endian, and the value:
+//
+//	01 2a00 64
+//
+// The counter lets the scanners tell a new reading from the same
+// advertisement received again, see Packet.Newer
+package presence
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/linux/hci"
+	"github.com/muka/go-bluetooth/service"
+)
+
+//Version the version of the payload format
+const Version = 1
+
+//MaxValueSize the largest value fitting a legacy advertisement with the
+// flags and the service data of a 16 bit UUID
+const MaxValueSize = 20
+
+// headerSize the version and the counter
+const headerSize = 3
+
+//Packet a presence payload
+type Packet struct {
+	Counter uint16
+	Value   []byte
+}
+
+//Marshal encode the payload of the service data
+func (p *Packet) Marshal() ([]byte, error) {
+	if len(p.Value) > MaxValueSize {
+		return nil, fmt.Errorf("Value too long: %d bytes, max %d", len(p.Value), MaxValueSize)
+	}
+	b := make([]byte, headerSize, headerSize+len(p.Value))
+	b[0] = Version
+	binary.LittleEndian.PutUint16(b[1:], p.Counter)
+	return append(b, p.Value...), nil
+}
+
+//Unmarshal decode the payload of the service data
+func Unmarshal(b []byte) (*Packet, error) {
+	if len(b) < headerSize {
+		return nil, fmt.Errorf("Invalid presence data %x", b)
+	}
+	if b[0] != Version {
+		return nil, fmt.Errorf("Unsupported presence version %d", b[0])
+	}
+	return &Packet{
+		Counter: binary.LittleEndian.Uint16(b[1:]),
+		Value:   append([]byte{}, b[headerSize:]...),
+	}, nil
+}
+
+//Newer return true if the packet follows prev, nil for the first packet
+// received. The counter wraps around, a packet is newer if it is less than
+// half the range ahead
+func (p *Packet) Newer(prev *Packet) bool {
+	if prev == nil {
+		return true
+	}
+	diff := p.Counter - prev.Counter
+	return diff != 0 && diff < 0x8000
+}
+
+//Parse decode the presence data of a service from a scan record, nil if
+// the record has none
+func Parse(r *hci.ScanRecord, serviceUUID bluetooth.UUID) (*Packet, error) {
+	b, ok := r.ServiceData[serviceUUID]
+	if !ok {
+		return nil, nil
+	}
+	return Unmarshal(b)
+}
+
+//ParseServiceData decode the presence data of a service from the
+// ServiceData property of a bluez device, nil if it has none
+func ParseServiceData(serviceData map[string]dbus.Variant, serviceUUID string) (*Packet, error) {
+	for uuid, v := range serviceData {
+		if !bluetooth.EqualUUID(uuid, serviceUUID) {
+			continue
+		}
+		b, ok := v.Value().([]byte)
+		if !ok {
+			return nil, fmt.Errorf("Invalid service data of type %T", v.Value())
+		}
+		return Unmarshal(b)
+	}
+	return nil, nil
+}
+
+//Advertiser update the presence data in the advertisement of an application
+type Advertiser struct {
+	app         *service.Application
+	serviceUUID string
+	counter     uint16
+}
+
+//NewAdvertiser advertise the presence data of a service with the
+// advertisement of app, see Update
+func NewAdvertiser(app *service.Application, serviceUUID string) (*Advertiser, error) {
+	if _, err := bluetooth.ParseUUID(serviceUUID); err != nil {
+		return nil, err
+	}
+	return &Advertiser{app: app, serviceUUID: serviceUUID}, nil
+}
+
+//Update advertise a new value, incrementing the counter. It returns the
+// packet advertised
+func (a *Advertiser) Update(value []byte) (*Packet, error) {
+	p := &Packet{Counter: a.counter + 1, Value: value}
+	b, err := p.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	if err = a.app.SetServiceData(a.serviceUUID, b); err != nil {
+		return nil, err
+	}
+	a.counter = p.Counter
+	return p, nil
+}
+
+//Stop remove the presence data from the advertisement
+func (a *Advertiser) Stop() error {
+	return a.app.SetServiceData(a.serviceUUID, nil)
+}
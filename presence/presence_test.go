@@ -0,0 +1,167 @@
+package presence
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/linux/hci"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestMarshal(t *testing.T) {
+
+	p := &Packet{Counter: 42, Value: []byte{0x64}}
+	b, err := p.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(b) != "012a0064" {
+		t.Fatalf("Unexpected payload %x", b)
+	}
+
+	decoded, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Counter != 42 || !bytes.Equal(decoded.Value, p.Value) {
+		t.Fatalf("Unexpected packet %+v", decoded)
+	}
+
+	if _, err = (&Packet{Value: make([]byte, MaxValueSize+1)}).Marshal(); err == nil {
+		t.Fatal("Expected an error for a value too long")
+	}
+	if _, err = Unmarshal([]byte{0x02, 0, 0}); err == nil {
+		t.Fatal("Expected an error for an unknown version")
+	}
+	if _, err = Unmarshal([]byte{0x01}); err == nil {
+		t.Fatal("Expected an error for a truncated payload")
+	}
+}
+
+func TestNewer(t *testing.T) {
+	for _, c := range []struct {
+		prev, next uint16
+		newer      bool
+	}{
+		{1, 2, true},
+		{2, 2, false},
+		{3, 2, false},
+		{0xffff, 0, true},
+		{0xfff0, 0x0010, true},
+	} {
+		if (&Packet{Counter: c.next}).Newer(&Packet{Counter: c.prev}) != c.newer {
+			t.Fatalf("Expected %d newer than %d: %v", c.next, c.prev, c.newer)
+		}
+	}
+	if !(&Packet{}).Newer(nil) {
+		t.Fatal("Expected the first packet newer")
+	}
+}
+
+func TestParse(t *testing.T) {
+
+	b, err := hex.DecodeString("020106" + "07161a18" + "012a0064")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := hci.ParseScanRecord(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Parse(record, bluetooth.UUID16(0x181a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.Counter != 42 {
+		t.Fatalf("Unexpected packet %+v", p)
+	}
+	if p, err = Parse(record, bluetooth.UUID16(0x180f)); p != nil || err != nil {
+		t.Fatalf("Expected no packet, got %+v %v", p, err)
+	}
+
+	data := map[string]dbus.Variant{
+		"0000181a-0000-1000-8000-00805f9b34fb": dbus.MakeVariant([]byte{1, 1, 0, 7}),
+	}
+	p, err = ParseServiceData(data, "181A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.Counter != 1 || !bytes.Equal(p.Value, []byte{7}) {
+		t.Fatalf("Unexpected packet %+v", p)
+	}
+}
+
+func TestPresenceAdvertiser(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+		LocalName:  "bluetest",
+		Logger:     bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err = app.StartAdvertising("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer app.StopAdvertising()
+
+	a := b.Adapter("hci0")
+	advertised := func() *Packet {
+		list := a.Advertisements()
+		if len(list) != 1 {
+			t.Fatalf("Expected one advertisement, got %d", len(list))
+		}
+		if err := list[0].Refresh(); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := list[0].Properties()["ServiceData"].Value().(map[string]dbus.Variant)
+		p, err := ParseServiceData(data, "181A")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	if p := advertised(); p != nil {
+		t.Fatalf("Unexpected presence data %+v", p)
+	}
+
+	adv, err := NewAdvertiser(app, "181A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// registered again with the service data
+	if _, err = adv.Update([]byte{0x64}); err != nil {
+		t.Fatal(err)
+	}
+	if p := advertised(); p == nil || p.Counter != 1 || p.Value[0] != 0x64 {
+		t.Fatalf("Unexpected presence data %+v", p)
+	}
+	// updated in place
+	if _, err = adv.Update([]byte{0x63}); err != nil {
+		t.Fatal(err)
+	}
+	if p := advertised(); p == nil || p.Counter != 2 || p.Value[0] != 0x63 {
+		t.Fatalf("Unexpected presence data %+v", p)
+	}
+	if err = adv.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if p := advertised(); p != nil {
+		t.Fatalf("Expected the presence data removed, got %+v", p)
+	}
+}
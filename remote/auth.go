@@ -0,0 +1,30 @@
+package remote
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+//RequireToken serve h to the requests carrying the bearer token in their
+// header Authorization: Bearer <token>, the others are rejected with 401
+func RequireToken(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validToken(token, r.Header.Get("Authorization")) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// validToken check an Authorization header against the token, in constant
+// time
+func validToken(token, authorization string) bool {
+	const prefix = "Bearer "
+	if token == "" || len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(authorization[len(prefix):]), []byte(token)) == 1
+}
@@ -0,0 +1,250 @@
+//go:build grpc
+// +build grpc
+
+package remote
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+//GRPCService the name of the gRPC service, its methods are the ones of the
+// Controller and take the JSON messages below. The messages are encoded with
+// the json codec, the clients set the content subtype json, eg. with
+// grpc.CallContentSubtype("json")
+const GRPCService = "remote.Remote"
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec the codec of the service messages
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)   { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(b []byte, v interface{}) error { return json.Unmarshal(b, v) }
+func (jsonCodec) Name() string                            { return "json" }
+
+//DeviceRequest the request of Connect, Disconnect and Services
+type DeviceRequest struct {
+	Address string `json:"address"`
+	// Values read the values, Services
+	Values bool `json:"values,omitempty"`
+}
+
+//CharRequest the request of Read, Write, Subscribe, ReadPeripheral and
+// UpdatePeripheral, with the address of a device or the ID of a peripheral
+type CharRequest struct {
+	Address        string `json:"address,omitempty"`
+	Peripheral     string `json:"peripheral,omitempty"`
+	Service        string `json:"service,omitempty"`
+	Characteristic string `json:"characteristic"`
+	Value
+}
+
+//PeripheralRequest the request of RemovePeripheral and WatchPeripheral, and
+// the response of AddPeripheral
+type PeripheralRequest struct {
+	ID string `json:"id"`
+}
+
+//Empty the empty request or response
+type Empty struct{}
+
+// grpcServer the gRPC interface of a Controller
+type grpcServer interface {
+	controller() *Controller
+}
+
+func (c *Controller) controller() *Controller {
+	return c
+}
+
+// grpcError convert the errors of the controller
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch errorCode(err) {
+	case InvalidArgument:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case NotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case AlreadyExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err == context.DeadlineExceeded {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
+
+// unary a method taking a request of the type returned by req
+func unary(name string, req func() interface{}, fn func(ctx context.Context, c *Controller, req interface{}) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := req()
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			c := srv.(grpcServer).controller()
+			call := func(ctx context.Context, in interface{}) (interface{}, error) {
+				out, err := fn(ctx, c, in)
+				return out, grpcError(err)
+			}
+			if interceptor == nil {
+				return call(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + GRPCService + "/" + name}
+			return interceptor(ctx, in, info, call)
+		},
+	}
+}
+
+// serverStream a method streaming the responses of a request
+func serverStream(name string, req func() interface{}, fn func(ctx context.Context, c *Controller, req interface{}, send func(interface{})) error) grpc.StreamDesc {
+	return grpc.StreamDesc{
+		StreamName:    name,
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			in := req()
+			if err := stream.RecvMsg(in); err != nil {
+				return err
+			}
+			ctx, cancel := context.WithCancel(stream.Context())
+			defer cancel()
+			var sendErr error
+			err := fn(ctx, srv.(grpcServer).controller(), in, func(v interface{}) {
+				if sendErr != nil {
+					return
+				}
+				if sendErr = stream.SendMsg(v); sendErr != nil {
+					cancel()
+				}
+			})
+			if sendErr != nil {
+				return sendErr
+			}
+			return grpcError(err)
+		},
+	}
+}
+
+var grpcServiceDesc = grpc.ServiceDesc{
+	ServiceName: GRPCService,
+	HandlerType: (*grpcServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("Devices", func() interface{} { return new(Empty) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				list, err := c.Devices()
+				return &list, err
+			}),
+		unary("Connect", func() interface{} { return new(DeviceRequest) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				return c.Connect(ctx, req.(*DeviceRequest).Address)
+			}),
+		unary("Disconnect", func() interface{} { return new(DeviceRequest) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				return &Empty{}, c.Disconnect(req.(*DeviceRequest).Address)
+			}),
+		unary("Services", func() interface{} { return new(DeviceRequest) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				r := req.(*DeviceRequest)
+				return c.Services(ctx, r.Address, r.Values)
+			}),
+		unary("Read", func() interface{} { return new(CharRequest) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				r := req.(*CharRequest)
+				value, err := c.Read(ctx, r.Address, r.Service, r.Characteristic)
+				return &Value{Value: value}, err
+			}),
+		unary("Write", func() interface{} { return new(CharRequest) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				r := req.(*CharRequest)
+				return &Empty{}, c.Write(ctx, r.Address, r.Service, r.Characteristic, r.Value.Value, r.Command)
+			}),
+		unary("Peripherals", func() interface{} { return new(Empty) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				list := c.Peripherals()
+				return &list, nil
+			}),
+		unary("AddPeripheral", func() interface{} { return new(Peripheral) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				id, err := c.AddPeripheral(req.(*Peripheral))
+				return &PeripheralRequest{ID: id}, err
+			}),
+		unary("RemovePeripheral", func() interface{} { return new(PeripheralRequest) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				return &Empty{}, c.RemovePeripheral(req.(*PeripheralRequest).ID)
+			}),
+		unary("ReadPeripheral", func() interface{} { return new(CharRequest) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				r := req.(*CharRequest)
+				value, err := c.ReadPeripheral(r.Peripheral, r.Service, r.Characteristic)
+				return &Value{Value: value}, err
+			}),
+		unary("UpdatePeripheral", func() interface{} { return new(CharRequest) },
+			func(ctx context.Context, c *Controller, req interface{}) (interface{}, error) {
+				r := req.(*CharRequest)
+				return &Empty{}, c.UpdatePeripheral(r.Peripheral, r.Service, r.Characteristic, r.Value.Value)
+			}),
+	},
+	Streams: []grpc.StreamDesc{
+		serverStream("Scan", func() interface{} { return new(ScanRequest) },
+			func(ctx context.Context, c *Controller, req interface{}, send func(interface{})) error {
+				return c.Scan(ctx, req.(*ScanRequest), func(dev *DeviceInfo) { send(dev) })
+			}),
+		serverStream("Subscribe", func() interface{} { return new(CharRequest) },
+			func(ctx context.Context, c *Controller, req interface{}, send func(interface{})) error {
+				r := req.(*CharRequest)
+				return c.Subscribe(ctx, r.Address, r.Service, r.Characteristic, func(value []byte) {
+					send(&Value{Value: value})
+				})
+			}),
+		serverStream("WatchPeripheral", func() interface{} { return new(PeripheralRequest) },
+			func(ctx context.Context, c *Controller, req interface{}, send func(interface{})) error {
+				return c.WatchPeripheral(ctx, req.(*PeripheralRequest).ID, func(ev *WriteEvent) { send(ev) })
+			}),
+	},
+}
+
+//RegisterGRPC serve a controller on a gRPC server
+func RegisterGRPC(s *grpc.Server, c *Controller) {
+	s.RegisterService(&grpcServiceDesc, c)
+}
+
+//GRPCToken the server options rejecting with Unauthenticated the calls
+// without the bearer token in their metadata authorization: Bearer <token>,
+// see RequireToken
+func GRPCToken(token string) []grpc.ServerOption {
+	check := func(ctx context.Context) error {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, authorization := range md.Get("authorization") {
+			if validToken(token, authorization) {
+				return nil
+			}
+		}
+		return status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := check(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := check(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
@@ -0,0 +1,283 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/muka/go-bluetooth/api"
+)
+
+//Value the body of the reads and writes of a characteristic, and the lines
+// of the notifications streams
+type Value struct {
+	Value api.HexValue `json:"value"`
+	// Command write without response
+	Command bool `json:"command,omitempty"`
+}
+
+// handler the HTTP interface of a Controller
+type handler struct {
+	c *Controller
+}
+
+//NewHandler serve a controller over HTTP, the bodies are JSON and the
+// streams are a JSON document per line:
+//
+//	GET    /devices                                    the known devices
+//	GET    /scan?timeout=&name=&service=&rssi=         stream the devices found
+//	POST   /devices/{address}/connect
+//	POST   /devices/{address}/disconnect
+//	GET    /devices/{address}/services?values=true     see api.GattDatabase
+//	GET    /devices/{address}/chars/{uuid}?service=    read a Value
+//	PUT    /devices/{address}/chars/{uuid}?service=    write a Value
+//	GET    /devices/{address}/chars/{uuid}/notify      stream the Values
+//	GET    /peripherals
+//	POST   /peripherals                                add a Peripheral
+//	DELETE /peripherals/{id}
+//	GET    /peripherals/{id}/chars/{uuid}?service=     read a Value
+//	PUT    /peripherals/{id}/chars/{uuid}?service=     update a Value
+//	GET    /peripherals/{id}/writes                    stream the WriteEvents
+//
+// The errors are returned as {"error": "message"}
+func NewHandler(c *Controller) http.Handler {
+	return &handler{c: c}
+}
+
+// route the method and the path of a request, with the {} segments in args
+type route struct {
+	method string
+	path   []string
+	fn     func(h *handler, w http.ResponseWriter, r *http.Request, args []string)
+}
+
+var routes = []route{
+	{"GET", []string{"devices"}, (*handler).devices},
+	{"GET", []string{"scan"}, (*handler).scan},
+	{"POST", []string{"devices", "{}", "connect"}, (*handler).connect},
+	{"POST", []string{"devices", "{}", "disconnect"}, (*handler).disconnect},
+	{"GET", []string{"devices", "{}", "services"}, (*handler).services},
+	{"GET", []string{"devices", "{}", "chars", "{}"}, (*handler).read},
+	{"PUT", []string{"devices", "{}", "chars", "{}"}, (*handler).write},
+	{"GET", []string{"devices", "{}", "chars", "{}", "notify"}, (*handler).notify},
+	{"GET", []string{"peripherals"}, (*handler).peripherals},
+	{"POST", []string{"peripherals"}, (*handler).addPeripheral},
+	{"DELETE", []string{"peripherals", "{}"}, (*handler).removePeripheral},
+	{"GET", []string{"peripherals", "{}", "chars", "{}"}, (*handler).readPeripheral},
+	{"PUT", []string{"peripherals", "{}", "chars", "{}"}, (*handler).updatePeripheral},
+	{"GET", []string{"peripherals", "{}", "writes"}, (*handler).watchPeripheral},
+}
+
+// match return the {} segments of path if it matches the route
+func (rt *route) match(path []string) ([]string, bool) {
+	if len(path) != len(rt.path) {
+		return nil, false
+	}
+	var args []string
+	for i, s := range rt.path {
+		if s == "{}" {
+			args = append(args, path[i])
+		} else if s != path[i] {
+			return nil, false
+		}
+	}
+	return args, true
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	found := false
+	for _, rt := range routes {
+		args, ok := rt.match(path)
+		if !ok {
+			continue
+		}
+		if rt.method == r.Method {
+			rt.fn(h, w, r, args)
+			return
+		}
+		found = true
+	}
+	if found {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	writeError(w, http.StatusNotFound, "Not found")
+}
+
+// httpStatus the status of an error returned by the controller
+func httpStatus(err error) int {
+	switch errorCode(err) {
+	case InvalidArgument:
+		return http.StatusBadRequest
+	case NotFound:
+		return http.StatusNotFound
+	case AlreadyExists:
+		return http.StatusConflict
+	}
+	if err == context.DeadlineExceeded {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// reply write the result of a call, v or err
+func reply(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		writeError(w, httpStatus(err), err.Error())
+		return
+	}
+	if v == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// decode the JSON body of a request in v, replying on error
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// stream the values sent by fn as JSON lines, flushed one by one. The status
+// is sent with the first value, an error before it is replied as usual
+func stream(w http.ResponseWriter, fn func(send func(v interface{})) error) {
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	started := false
+	start := func() {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+	}
+	err := fn(func(v interface{}) {
+		start()
+		enc.Encode(v)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+	if err != nil && !started {
+		reply(w, nil, err)
+		return
+	}
+	start()
+}
+
+func (h *handler) devices(w http.ResponseWriter, r *http.Request, args []string) {
+	list, err := h.c.Devices()
+	reply(w, list, err)
+}
+
+func (h *handler) scan(w http.ResponseWriter, r *http.Request, args []string) {
+	q := r.URL.Query()
+	req := &ScanRequest{
+		Timeout: q.Get("timeout"),
+		Name:    q.Get("name"),
+		Service: q.Get("service"),
+	}
+	if s := q.Get("rssi"); s != "" {
+		rssi, err := strconv.ParseInt(s, 10, 16)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid rssi "+s)
+			return
+		}
+		req.RSSI = int16(rssi)
+	}
+	stream(w, func(send func(v interface{})) error {
+		return h.c.Scan(r.Context(), req, func(dev *DeviceInfo) { send(dev) })
+	})
+}
+
+func (h *handler) connect(w http.ResponseWriter, r *http.Request, args []string) {
+	dev, err := h.c.Connect(r.Context(), args[0])
+	reply(w, dev, err)
+}
+
+func (h *handler) disconnect(w http.ResponseWriter, r *http.Request, args []string) {
+	reply(w, nil, h.c.Disconnect(args[0]))
+}
+
+func (h *handler) services(w http.ResponseWriter, r *http.Request, args []string) {
+	values, _ := strconv.ParseBool(r.URL.Query().Get("values"))
+	db, err := h.c.Services(r.Context(), args[0], values)
+	reply(w, db, err)
+}
+
+func (h *handler) read(w http.ResponseWriter, r *http.Request, args []string) {
+	value, err := h.c.Read(r.Context(), args[0], r.URL.Query().Get("service"), args[1])
+	reply(w, &Value{Value: value}, err)
+}
+
+func (h *handler) write(w http.ResponseWriter, r *http.Request, args []string) {
+	v := new(Value)
+	if !decode(w, r, v) {
+		return
+	}
+	reply(w, nil, h.c.Write(r.Context(), args[0], r.URL.Query().Get("service"), args[1], v.Value, v.Command))
+}
+
+func (h *handler) notify(w http.ResponseWriter, r *http.Request, args []string) {
+	stream(w, func(send func(v interface{})) error {
+		return h.c.Subscribe(r.Context(), args[0], r.URL.Query().Get("service"), args[1], func(value []byte) {
+			send(&Value{Value: value})
+		})
+	})
+}
+
+func (h *handler) peripherals(w http.ResponseWriter, r *http.Request, args []string) {
+	reply(w, h.c.Peripherals(), nil)
+}
+
+func (h *handler) addPeripheral(w http.ResponseWriter, r *http.Request, args []string) {
+	p := new(Peripheral)
+	if !decode(w, r, p) {
+		return
+	}
+	id, err := h.c.AddPeripheral(p)
+	if err != nil {
+		reply(w, nil, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
+
+func (h *handler) removePeripheral(w http.ResponseWriter, r *http.Request, args []string) {
+	reply(w, nil, h.c.RemovePeripheral(args[0]))
+}
+
+func (h *handler) readPeripheral(w http.ResponseWriter, r *http.Request, args []string) {
+	value, err := h.c.ReadPeripheral(args[0], r.URL.Query().Get("service"), args[1])
+	reply(w, &Value{Value: value}, err)
+}
+
+func (h *handler) updatePeripheral(w http.ResponseWriter, r *http.Request, args []string) {
+	v := new(Value)
+	if !decode(w, r, v) {
+		return
+	}
+	reply(w, nil, h.c.UpdatePeripheral(args[0], r.URL.Query().Get("service"), args[1], v.Value))
+}
+
+func (h *handler) watchPeripheral(w http.ResponseWriter, r *http.Request, args []string) {
+	stream(w, func(send func(v interface{})) error {
+		return h.c.WatchPeripheral(r.Context(), args[0], func(ev *WriteEvent) { send(ev) })
+	})
+}
@@ -0,0 +1,265 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/service"
+	"github.com/muka/go-bluetooth/simulator"
+)
+
+//Peripheral a GATT application served by the gateway, its services are
+// described as in the JSON export of a device, see the simulator package
+type Peripheral struct {
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Advertise bool                  `json:"advertise,omitempty"`
+	Services  []api.GattServiceInfo `json:"services"`
+}
+
+//WriteEvent a write of a remote device to a characteristic of a peripheral
+type WriteEvent struct {
+	Time           time.Time    `json:"time"`
+	Service        string       `json:"service"`
+	Characteristic string       `json:"characteristic"`
+	Value          api.HexValue `json:"value"`
+}
+
+type peripheral struct {
+	Peripheral
+	sim *simulator.Simulator
+
+	mutex    sync.Mutex
+	watchers map[int]func(*WriteEvent)
+	next     int
+	// closed when the peripheral is removed
+	closed chan struct{}
+}
+
+// watch call fn with the writes until the returned func is called
+func (p *peripheral) watch(fn func(*WriteEvent)) func() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	id := p.next
+	p.next++
+	p.watchers[id] = fn
+	return func() {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		delete(p.watchers, id)
+	}
+}
+
+func (p *peripheral) written(ev *WriteEvent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, fn := range p.watchers {
+		fn(ev)
+	}
+}
+
+// char find a characteristic by UUID, in the service if not empty
+func (p *peripheral) char(serviceUUID, charUUID string) (*service.GattCharacteristic1, error) {
+	char, err := p.sim.Char(serviceUUID, charUUID)
+	if err != nil {
+		return nil, errorf(NotFound, "%s", err)
+	}
+	return char, nil
+}
+
+func (p *peripheral) close() {
+	close(p.closed)
+	p.sim.Close()
+}
+
+// peripherals the peripherals of a Controller
+type peripherals struct {
+	adapterID string
+
+	mutex sync.Mutex
+	list  map[string]*peripheral
+	next  int
+}
+
+func newPeripherals(adapterID string) *peripherals {
+	return &peripherals{
+		adapterID: adapterID,
+		list:      map[string]*peripheral{},
+	}
+}
+
+func (ps *peripherals) get(id string) (*peripheral, error) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	p, ok := ps.list[id]
+	if !ok {
+		return nil, errorf(NotFound, "Peripheral %s not found", id)
+	}
+	return p, nil
+}
+
+func (ps *peripherals) close() {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	for id, p := range ps.list {
+		p.close()
+		delete(ps.list, id)
+	}
+}
+
+//Peripherals list the peripherals, ordered by ID
+func (c *Controller) Peripherals() []*Peripheral {
+	ps := c.peripherals
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	list := make([]*Peripheral, 0, len(ps.list))
+	for _, p := range ps.list {
+		desc := p.Peripheral
+		list = append(list, &desc)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, _ := strconv.Atoi(list[i].ID)
+		b, _ := strconv.Atoi(list[j].ID)
+		return a < b
+	})
+	return list
+}
+
+//AddPeripheral serve and register an application, advertised if requested,
+// see simulator.New. The writes of the remote devices update the values and
+// are passed to WatchPeripheral. It returns the ID of the peripheral
+func (c *Controller) AddPeripheral(desc *Peripheral) (string, error) {
+
+	db := &api.GattDatabase{Name: desc.Name, Services: desc.Services}
+	if err := simulator.Validate(db); err != nil {
+		return "", errorf(InvalidArgument, "%s", err)
+	}
+
+	ps := c.peripherals
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if desc.Advertise {
+		for _, p := range ps.list {
+			if p.Advertise {
+				return "", errorf(AlreadyExists, "Peripheral %s is advertising", p.ID)
+			}
+		}
+	}
+
+	id := strconv.Itoa(ps.next)
+	p := &peripheral{
+		Peripheral: *desc,
+		watchers:   map[int]func(*WriteEvent){},
+		closed:     make(chan struct{}),
+	}
+	p.ID = id
+
+	var err error
+	p.sim, err = simulator.New(db, &simulator.Config{
+		AdapterID:  ps.adapterID,
+		ObjectPath: dbus.ObjectPath("/org/bluez/remote/peripheral" + id),
+		Advertise:  desc.Advertise,
+		OnWrite: func(serviceUUID, charUUID string, value []byte) {
+			p.written(&WriteEvent{
+				Time:           time.Now(),
+				Service:        serviceUUID,
+				Characteristic: charUUID,
+				Value:          value,
+			})
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if err = p.sim.Start(); err != nil {
+		p.sim.Close()
+		return "", err
+	}
+
+	ps.next++
+	ps.list[id] = p
+	return id, nil
+}
+
+//RemovePeripheral stop advertising and unregister a peripheral
+func (c *Controller) RemovePeripheral(id string) error {
+	ps := c.peripherals
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	p, ok := ps.list[id]
+	if !ok {
+		return errorf(NotFound, "Peripheral %s not found", id)
+	}
+	p.close()
+	delete(ps.list, id)
+	return nil
+}
+
+//ReadPeripheral return the value of a characteristic of a peripheral
+func (c *Controller) ReadPeripheral(id, serviceUUID, charUUID string) ([]byte, error) {
+	p, err := c.peripherals.get(id)
+	if err != nil {
+		return nil, err
+	}
+	char, err := p.char(serviceUUID, charUUID)
+	if err != nil {
+		return nil, err
+	}
+	value, dbusErr := char.ReadValue(map[string]interface{}{})
+	if dbusErr != nil {
+		return nil, fmt.Errorf("%s", dbusErr.Error())
+	}
+	return value, nil
+}
+
+//UpdatePeripheral set the value of a characteristic of a peripheral,
+// notified to the subscribed devices
+func (c *Controller) UpdatePeripheral(id, serviceUUID, charUUID string, value []byte) error {
+	p, err := c.peripherals.get(id)
+	if err != nil {
+		return err
+	}
+	char, err := p.char(serviceUUID, charUUID)
+	if err != nil {
+		return err
+	}
+	char.UpdateValue(value)
+	return nil
+}
+
+//WatchPeripheral call fn with the writes of the remote devices to a
+// peripheral until ctx is done or the peripheral is removed
+func (c *Controller) WatchPeripheral(ctx context.Context, id string, fn func(*WriteEvent)) error {
+	p, err := c.peripherals.get(id)
+	if err != nil {
+		return err
+	}
+	events := make(chan *WriteEvent, 16)
+	stop := p.watch(func(ev *WriteEvent) {
+		select {
+		case events <- ev:
+		default:
+			// a slow client loses the writes rather than blocking the
+			// peripheral
+		}
+	})
+	defer stop()
+
+	for {
+		select {
+		case ev := <-events:
+			fn(ev)
+		case <-ctx.Done():
+			return nil
+		case <-p.closed:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,346 @@
+//Package remote expose the Bluetooth stack of a gateway to the other processes
+// and hosts. A Controller scans, connects, reads, writes and subscribes to the
+// characteristics of the remote devices, and manages the local peripherals;
+// NewHandler serves it over HTTP with JSON bodies and, built with -tags grpc,
+// RegisterGRPC over gRPC. The scans, the notifications and the writes to the
+// peripherals are streamed.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/emitter"
+)
+
+const (
+	//DefaultScanTimeout the duration of a scan without timeout
+	DefaultScanTimeout = 10 * time.Second
+	//ConnectTimeout the time given to connect a device and resolve its
+	// services when the context has no deadline
+	ConnectTimeout = 30 * time.Second
+)
+
+//Code the kind of an Error, mapped to the HTTP and gRPC status codes
+type Code int
+
+// Codes of an Error
+const (
+	Unknown Code = iota
+	InvalidArgument
+	NotFound
+	AlreadyExists
+)
+
+//Error an error of a request
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func errorf(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// errorCode return the code of err, Unknown if it is not an Error
+func errorCode(err error) Code {
+	if e, ok := err.(*Error); ok {
+		return e.Code
+	}
+	return Unknown
+}
+
+//DeviceInfo the properties of a remote device
+type DeviceInfo struct {
+	Address   string   `json:"address"`
+	Name      string   `json:"name,omitempty"`
+	RSSI      int16    `json:"rssi,omitempty"`
+	UUIDs     []string `json:"uuids,omitempty"`
+	Connected bool     `json:"connected"`
+	Paired    bool     `json:"paired"`
+}
+
+func deviceInfo(props *profile.Device1Properties) *DeviceInfo {
+	return &DeviceInfo{
+		Address:   props.Address,
+		Name:      props.Name,
+		RSSI:      props.RSSI,
+		UUIDs:     props.UUIDs,
+		Connected: props.Connected,
+		Paired:    props.Paired,
+	}
+}
+
+//ScanRequest the filters of a scan
+type ScanRequest struct {
+	// Timeout the duration of the scan, eg. 30s, defaults to
+	// DefaultScanTimeout
+	Timeout string `json:"timeout,omitempty"`
+	// Name match the devices with a name containing the substring, ignoring
+	// the case
+	Name string `json:"name,omitempty"`
+	// Service match the devices advertising the service UUID
+	Service string `json:"service,omitempty"`
+	// RSSI match the devices with a RSSI above the value, eg. -70
+	RSSI int16 `json:"rssi,omitempty"`
+}
+
+func (r *ScanRequest) match(props *profile.Device1Properties) bool {
+	if r.Name != "" && !strings.Contains(strings.ToLower(props.Name), strings.ToLower(r.Name)) {
+		return false
+	}
+	if r.RSSI != 0 && (props.RSSI == 0 || props.RSSI < r.RSSI) {
+		return false
+	}
+	if r.Service != "" {
+		for _, uuid := range props.UUIDs {
+			if bluetooth.EqualUUID(uuid, r.Service) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+//Controller drive the adapter of the gateway on behalf of the clients
+type Controller struct {
+	adapterID   string
+	peripherals *peripherals
+}
+
+//NewController create a controller of an adapter, eg. hci0
+func NewController(adapterID string) *Controller {
+	return &Controller{
+		adapterID:   adapterID,
+		peripherals: newPeripherals(adapterID),
+	}
+}
+
+//Close remove the peripherals
+func (c *Controller) Close() {
+	c.peripherals.close()
+}
+
+//Devices list the devices known to bluez
+func (c *Controller) Devices() ([]*DeviceInfo, error) {
+	devices, err := api.GetDevices()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*DeviceInfo, 0, len(devices))
+	for i := range devices {
+		props, err := devices[i].GetProperties()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, deviceInfo(props))
+	}
+	return list, nil
+}
+
+//Scan discover the devices matching the request, calling fn once per device,
+// starting with the known devices. Scan returns at the end of the timeout or
+// when ctx is done
+func (c *Controller) Scan(ctx context.Context, req *ScanRequest, fn func(*DeviceInfo)) error {
+
+	timeout := DefaultScanTimeout
+	if req.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(req.Timeout)
+		if err != nil || timeout <= 0 {
+			return errorf(InvalidArgument, "Invalid timeout %s", req.Timeout)
+		}
+	}
+	if req.Service != "" {
+		if _, err := bluetooth.ParseUUID(req.Service); err != nil {
+			return errorf(InvalidArgument, "%s", err)
+		}
+	}
+
+	// fn is called from the emitter too, not after the return of Scan
+	var mutex sync.Mutex
+	seen := map[string]bool{}
+	done := false
+	found := func(dev *api.Device) {
+		props, err := dev.GetProperties()
+		if err != nil || !req.match(props) {
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if done || seen[props.Address] {
+			return
+		}
+		seen[props.Address] = true
+		fn(deviceInfo(props))
+	}
+	defer func() {
+		mutex.Lock()
+		done = true
+		mutex.Unlock()
+	}()
+
+	devices, err := api.GetDevices()
+	if err != nil {
+		return err
+	}
+	for i := range devices {
+		found(&devices[i])
+	}
+
+	cb := emitter.NewCallback(func(ev emitter.Event) {
+		discovery := ev.GetData().(api.DiscoveredDeviceEvent)
+		if discovery.Status == api.DeviceAdded {
+			found(discovery.Device)
+		}
+	})
+	if err = api.On("discovery", cb); err != nil {
+		return err
+	}
+	defer api.Off("discovery", cb)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err = api.StartDiscoveryContext(ctx, c.adapterID); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// device return a device known to bluez
+func (c *Controller) device(address string) (*api.Device, error) {
+	dev, err := api.GetDeviceByAddress(strings.ToUpper(address))
+	if err != nil {
+		return nil, err
+	}
+	if dev == nil {
+		return nil, errorf(NotFound, "Device %s not found", address)
+	}
+	return dev, nil
+}
+
+// connected return a device connected, with its services resolved
+func (c *Controller) connected(ctx context.Context, address string) (*api.Device, error) {
+	dev, err := c.device(address)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ConnectTimeout)
+		defer cancel()
+	}
+	if err = dev.ResolveServices(ctx); err != nil {
+		return nil, err
+	}
+	return dev, nil
+}
+
+// char return a characteristic of a connected device
+func (c *Controller) char(ctx context.Context, address, serviceUUID, charUUID string) (*profile.GattCharacteristic1, error) {
+	if _, err := bluetooth.ParseUUID(charUUID); err != nil {
+		return nil, errorf(InvalidArgument, "%s", err)
+	}
+	dev, err := c.connected(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	char, err := dev.FindChar(serviceUUID, charUUID)
+	if err != nil {
+		return nil, errorf(NotFound, "%s: %s", address, err)
+	}
+	return char, nil
+}
+
+//Connect a device found by a scan and resolve its services
+func (c *Controller) Connect(ctx context.Context, address string) (*DeviceInfo, error) {
+	dev, err := c.connected(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	props, err := dev.GetProperties()
+	if err != nil {
+		return nil, err
+	}
+	return deviceInfo(props), nil
+}
+
+//Disconnect a device
+func (c *Controller) Disconnect(address string) error {
+	dev, err := c.device(address)
+	if err != nil {
+		return err
+	}
+	return dev.Disconnect()
+}
+
+//Services describe the GATT database of a device, connected if needed, see
+// api.Device.ExportGATT
+func (c *Controller) Services(ctx context.Context, address string, values bool) (*api.GattDatabase, error) {
+	dev, err := c.connected(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return dev.ExportGATT(api.ExportGATTOptions{Values: values})
+}
+
+//Read the value of a characteristic, the service UUID is optional if the
+// characteristic UUID is unique on the device
+func (c *Controller) Read(ctx context.Context, address, serviceUUID, charUUID string) ([]byte, error) {
+	char, err := c.char(ctx, address, serviceUUID, charUUID)
+	if err != nil {
+		return nil, err
+	}
+	return char.ReadValue(nil)
+}
+
+//Write the value of a characteristic, without response if command is true
+func (c *Controller) Write(ctx context.Context, address, serviceUUID, charUUID string, value []byte, command bool) error {
+	char, err := c.char(ctx, address, serviceUUID, charUUID)
+	if err != nil {
+		return err
+	}
+	options := map[string]dbus.Variant{}
+	if command {
+		options["type"] = dbus.MakeVariant("command")
+	}
+	return char.WriteValue(value, options)
+}
+
+//Subscribe to the notifications of a characteristic, calling fn with the
+// values until ctx is done or the device disconnects
+func (c *Controller) Subscribe(ctx context.Context, address, serviceUUID, charUUID string, fn func(value []byte)) error {
+	char, err := c.char(ctx, address, serviceUUID, charUUID)
+	if err != nil {
+		return err
+	}
+	values, stop, err := char.Notifications()
+	if err != nil {
+		return err
+	}
+	defer stop()
+	for {
+		select {
+		case value, ok := <-values:
+			if !ok {
+				return nil
+			}
+			fn(value)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+)
+
+func TestHandlerErrors(t *testing.T) {
+
+	c := NewController("hci0")
+	defer c.Close()
+	h := NewHandler(c)
+
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/unknown", "", http.StatusNotFound},
+		{"DELETE", "/devices", "", http.StatusMethodNotAllowed},
+		{"GET", "/scan?timeout=never", "", http.StatusBadRequest},
+		{"GET", "/scan?rssi=loud", "", http.StatusBadRequest},
+		{"GET", "/scan?service=nope", "", http.StatusBadRequest},
+		{"POST", "/peripherals", "{", http.StatusBadRequest},
+		{"POST", "/peripherals", `{"services": []}`, http.StatusBadRequest},
+		{"POST", "/peripherals", `{"services": [{"uuid": "xyz"}]}`, http.StatusBadRequest},
+		{"DELETE", "/peripherals/7", "", http.StatusNotFound},
+		{"PUT", "/peripherals/7/chars/2a19", `{"value": "64"}`, http.StatusNotFound},
+		{"PUT", "/peripherals/7/chars/2a19", `{"value": "zz"}`, http.StatusBadRequest},
+		{"GET", "/peripherals/7/writes", "", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s %s: expected %d, got %d %s", test.method, test.path, test.status, w.Code, w.Body)
+			continue
+		}
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] == "" {
+			t.Errorf("%s %s: expected an error message, got %s", test.method, test.path, w.Body)
+		}
+	}
+}
+
+func TestPeripheralsEmpty(t *testing.T) {
+
+	c := NewController("hci0")
+	defer c.Close()
+
+	w := httptest.NewRecorder()
+	NewHandler(c).ServeHTTP(w, httptest.NewRequest("GET", "/peripherals", nil))
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Fatalf("Expected an empty list, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+
+	h := RequireToken("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		authorization string
+		status        int
+	}{
+		{"", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Basic secret", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusNoContent},
+		{"bearer secret", http.StatusNoContent},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/devices", nil)
+		if test.authorization != "" {
+			r.Header.Set("Authorization", test.authorization)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%q: expected %d, got %d %s", test.authorization, test.status, w.Code, w.Body)
+		}
+	}
+}
+
+func TestScanReadWrite(t *testing.T) {
+
+	b, err := bluetest.Start()
+	if err != nil {
+		t.Skipf("Cannot start the fake bluez: %s", err.Error())
+	}
+	defer b.Close()
+	adapter, err := b.AddAdapter("hci0", "00:11:22:33:44:55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err := b.AddDevice("hci0", "AA:BB:CC:DD:EE:01", "Known")
+	if err != nil {
+		t.Fatal(err)
+	}
+	level, err := dev.AddCharacteristic("180f", "2a19", []string{bluez.FlagCharacteristicRead}, []byte{0x64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert, err := dev.AddCharacteristic("1802", "2a06", []string{bluez.FlagCharacteristicWriteWithoutResponse}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewController("hci0")
+	defer c.Close()
+	h := NewHandler(c)
+
+	// a device found during the scan
+	go func() {
+		for i := 0; i < 50 && !adapter.Property("Discovering").(bool); i++ {
+			time.Sleep(20 * time.Millisecond)
+		}
+		b.AddDevice("hci0", "AA:BB:CC:DD:EE:02", "Found")
+	}()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/scan?timeout=2s", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Scan: %d %s", w.Code, w.Body)
+	}
+	found := map[string]string{}
+	lines := bufio.NewScanner(w.Body)
+	for lines.Scan() {
+		info := new(DeviceInfo)
+		if err := json.Unmarshal(lines.Bytes(), info); err != nil {
+			t.Fatalf("Scan: %s: %s", lines.Text(), err)
+		}
+		found[info.Address] = info.Name
+	}
+	if found["AA:BB:CC:DD:EE:01"] != "Known" || found["AA:BB:CC:DD:EE:02"] != "Found" {
+		t.Fatalf("Scan: expected the known and the found devices, got %v", found)
+	}
+	// the discovery stops in the background at the end of the scan
+	for i := 0; adapter.Property("Discovering").(bool); i++ {
+		if i == 50 {
+			t.Fatal("Scan: the discovery is still running")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/devices/aa:bb:cc:dd:ee:01/chars/2a19?service=180f", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Read: %d %s", w.Code, w.Body)
+	}
+	v := new(Value)
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil || !bytes.Equal(v.Value, level.Value()) {
+		t.Fatalf("Read: expected %x, got %s", level.Value(), w.Body)
+	}
+	if !dev.Property("Connected").(bool) {
+		t.Fatal("Read: the device is not connected")
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/devices/AA:BB:CC:DD:EE:01/chars/2a06", strings.NewReader(`{"value": "02", "command": true}`)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Write: %d %s", w.Code, w.Body)
+	}
+	if !bytes.Equal(alert.Value(), []byte{0x02}) {
+		t.Fatalf("Write: expected 02, got %x", alert.Value())
+	}
+
+	// the characteristic does not accept writes with response
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/devices/AA:BB:CC:DD:EE:01/chars/2a06", strings.NewReader(`{"value": "01"}`)))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("Write: expected %d, got %d %s", http.StatusBadGateway, w.Code, w.Body)
+	}
+}
+
+func TestRemotePeripheral(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	c := NewController("hci0")
+	defer c.Close()
+	server := httptest.NewServer(NewHandler(c))
+	defer server.Close()
+
+	do := func(method, path, body string, status int) []byte {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		data, _ := ioutil.ReadAll(res.Body)
+		if res.StatusCode != status {
+			t.Fatalf("%s %s: expected %d, got %d %s", method, path, status, res.StatusCode, data)
+		}
+		return data
+	}
+
+	body := do("POST", "/peripherals", `{"name": "bluetest", "services": [{"uuid": "180f", "primary": true,
+		"characteristics": [{"uuid": "2a19", "flags": ["read", "write", "notify"], "value": "64"}]}]}`, http.StatusCreated)
+	if string(body) != "{\"id\":\"0\"}\n" {
+		t.Fatalf("Expected the id 0, got %s", body)
+	}
+	central := b.Adapter("hci0").Applications()[0]
+	uuid := bluetooth.CanonicalUUID("2a19")
+
+	value, err := central.ReadValue(uuid, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(value) != 1 || value[0] != 100 {
+		t.Fatalf("Expected 64, got %x", value)
+	}
+
+	do("PUT", "/peripherals/0/chars/2a19", `{"value": "32"}`, http.StatusNoContent)
+	if value, err = central.ReadValue(uuid, nil); err != nil || len(value) != 1 || value[0] != 50 {
+		t.Fatalf("Expected 32, got %x %v", value, err)
+	}
+
+	if err = central.WriteValue(uuid, []byte{10}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if body = do("GET", "/peripherals/0/chars/2a19", "", http.StatusOK); string(body) != "{\"value\":\"0a\"}\n" {
+		t.Fatalf("Expected 0a, got %s", body)
+	}
+
+	do("DELETE", "/peripherals/0", "", http.StatusNoContent)
+	do("GET", "/peripherals/0/chars/2a19", "", http.StatusNotFound)
+}
@@ -0,0 +1,116 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//Recorder capture the interactions with the characteristics of a device,
+// made through bluez/profile by any part of the program. There is one
+// recorder at a time, see profile.SetGattObserver
+type Recorder struct {
+	prefix string
+	start  time.Time
+
+	mutex  sync.Mutex
+	enc    *json.Encoder
+	err    error
+	closed bool
+}
+
+//NewRecorder write the header of a session, with the GATT database of the
+// device and its values, and start recording. The device must be connected
+// and its services resolved, see api.Device.ResolveServices
+func NewRecorder(dev *api.Device, w io.Writer) (*Recorder, error) {
+
+	db, err := dev.ExportGATT(api.ExportGATTOptions{Values: true})
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{
+		prefix: dev.Path + "/",
+		start:  time.Now(),
+		enc:    json.NewEncoder(w),
+	}
+	err = r.enc.Encode(&Header{
+		Version:  Version,
+		Start:    r.start,
+		Database: db,
+	})
+	if err != nil {
+		return nil, err
+	}
+	profile.SetGattObserver(r)
+	return r, nil
+}
+
+//Close stop recording, it returns the first error writing the session
+func (r *Recorder) Close() error {
+	profile.SetGattObserver(nil)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.closed = true
+	return r.err
+}
+
+// record an interaction of a characteristic of the device
+func (r *Recorder) record(char *profile.GattCharacteristic1, i Interaction, err error) {
+
+	if !strings.HasPrefix(char.Path, r.prefix) {
+		return
+	}
+	i.Offset = time.Since(r.start)
+	i.Characteristic = strings.ToLower(char.Properties.UUID)
+	i.Service = serviceUUID(char)
+	if err != nil {
+		i.Error = err.Error()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed || r.err != nil {
+		return
+	}
+	if err := r.enc.Encode(&i); err != nil {
+		r.err = fmt.Errorf("Recording: %s", err)
+	}
+}
+
+// serviceUUID return the UUID of the service of a characteristic
+func serviceUUID(char *profile.GattCharacteristic1) string {
+	manager, err := api.GetManager()
+	if err != nil {
+		return ""
+	}
+	uuid, _ := manager.GetCache().Property(char.Properties.Service, bluez.GattService1Interface, "UUID")
+	s, _ := uuid.Value().(string)
+	return strings.ToLower(s)
+}
+
+//ObserveRead record a read
+func (r *Recorder) ObserveRead(char *profile.GattCharacteristic1, value []byte, err error) {
+	r.record(char, Interaction{Op: OpRead, Value: value}, err)
+}
+
+//ObserveWrite record a write
+func (r *Recorder) ObserveWrite(char *profile.GattCharacteristic1, value []byte, options map[string]dbus.Variant, err error) {
+	command := false
+	if t, ok := options["type"]; ok {
+		command = t.Value() == "command"
+	}
+	r.record(char, Interaction{Op: OpWrite, Value: value, Command: command}, err)
+}
+
+//ObserveNotification record a notification
+func (r *Recorder) ObserveNotification(char *profile.GattCharacteristic1, value []byte) {
+	r.record(char, Interaction{Op: OpNotify, Value: value}, nil)
+}
@@ -0,0 +1,123 @@
+package replay
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/simulator"
+)
+
+func TestRead(t *testing.T) {
+
+	s, err := Read(strings.NewReader(`{"version": 1, "start": "2018-10-01T10:00:00Z", "database": {"name": "sensor", "services": []}}
+{"offset": 1000000, "op": "read", "service": "180f", "characteristic": "2a19", "value": "64"}
+{"offset": 2000000, "op": "write", "service": "180f", "characteristic": "2a19", "value": "00", "command": true}
+{"offset": 3000000, "op": "notify", "service": "180f", "characteristic": "2a19", "value": "63"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Database.Name != "sensor" || len(s.Interactions) != 3 {
+		t.Fatalf("Unexpected session %+v", s)
+	}
+	i := s.Interactions[2]
+	if i.Op != OpNotify || i.Offset != 3*time.Millisecond || len(i.Value) != 1 || i.Value[0] != 0x63 {
+		t.Fatalf("Unexpected interaction %+v", i)
+	}
+	if !s.Interactions[1].Command {
+		t.Fatal("Expected a write without response")
+	}
+
+	// a header written by hand, over several lines
+	s, err = Read(strings.NewReader(`{"version": 1, "database": {"services": [
+	{"uuid": "180f", "primary": true}]}}
+{"offset": 1000000, "op": "read", "service": "180f", "characteristic": "2a19", "value": "64"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Database.Services) != 1 || len(s.Interactions) != 1 {
+		t.Fatalf("Unexpected session %+v", s)
+	}
+
+	invalid := []string{
+		``,
+		`{"version": 2, "database": {}}`,
+		`{"version": 1}`,
+		`{"version": 1, "database": {}}` + "\n" + `{"op": "indicate"}`,
+		`{"version": 1, "database": {}}` + "\n" + `{"op": "read", "value": "zz"}`,
+		`{"version": 1, "database": {}}` + "\n" + `{"op": "read"`,
+	}
+	for _, session := range invalid {
+		if _, err = Read(strings.NewReader(session)); err == nil {
+			t.Errorf("Expected an error reading %q", session)
+		}
+	}
+}
+
+func TestRecordedError(t *testing.T) {
+	for s, expected := range map[string]string{
+		"org.bluez.Error.NotPermitted: Read not permitted": "org.bluez.Error.NotPermitted",
+		"org.bluez.Error.InProgress":                       "org.bluez.Error.InProgress",
+		"context deadline exceeded":                        "org.bluez.Error.Failed",
+		"Invalid argument: 3":                              "org.bluez.Error.Failed",
+	} {
+		if err := recordedError(s); err.Name != expected {
+			t.Errorf("%s: expected %s, got %s", s, expected, err.Name)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	session, err := Read(strings.NewReader(`{"version": 1, "database": {"services": [
+	{"uuid": "180f", "primary": true, "characteristics": [{"uuid": "2a19", "flags": ["read", "write", "notify"], "value": "64"}]}]}}
+{"offset": 1000000, "op": "read", "service": "180f", "characteristic": "2a19", "value": "63"}
+{"offset": 2000000, "op": "write", "service": "180f", "characteristic": "2a19", "value": "01"}
+{"offset": 5000000, "op": "notify", "service": "180f", "characteristic": "2a19", "value": "62"}
+{"offset": 6000000, "op": "read", "service": "180f", "characteristic": "2a19", "value": "62"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReplayer(session, &simulator.Config{Logger: bluez.NopLogger{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err = r.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	central := b.Adapter("hci0").Applications()[0]
+	uuid := bluetooth.CanonicalUUID("2a19")
+	if value, err := central.ReadValue(uuid, nil); err != nil || len(value) != 1 || value[0] != 0x63 {
+		t.Fatalf("Expected 63, got %x %v", value, err)
+	}
+	if err = central.WriteValue(uuid, []byte{2}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := central.ReadValue(uuid, nil); err != nil || len(value) != 1 || value[0] != 0x62 {
+		t.Fatalf("Expected 62, got %x %v", value, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	mismatches, err := r.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || !strings.Contains(mismatches[0], "expected 01, got 02") {
+		t.Fatalf("Expected the write mismatch, got %v", mismatches)
+	}
+	if _, err = central.ReadValue(uuid, nil); !bluez.IsError(err, bluez.ErrFailed) {
+		t.Fatalf("Expected %s on the unexpected read, got %v", bluez.ErrFailed, err)
+	}
+}
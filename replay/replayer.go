@@ -0,0 +1,232 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+	"github.com/muka/go-bluetooth/simulator"
+)
+
+//Replayer serve a session from a simulated peripheral
+type Replayer struct {
+	sim *simulator.Simulator
+	// interactions the replayed ones, without the GAP services served by
+	// bluez
+	interactions []Interaction
+
+	mutex      sync.Mutex
+	cond       *sync.Cond
+	consumed   []bool
+	mismatches []string
+	stopped    bool
+	// stop closed by Close, done by the end of play
+	stop chan struct{}
+	done chan struct{}
+}
+
+// charKey identify a characteristic in the interactions
+func charKey(serviceUUID, charUUID string) string {
+	return bluetooth.CanonicalUUID(serviceUUID) + "/" + bluetooth.CanonicalUUID(charUUID)
+}
+
+//NewReplayer serve the database of a session with a simulator, the
+// simulator options are in config, its script and OnWrite are replaced.
+// Start the replay with Start
+func NewReplayer(s *Session, config *simulator.Config) (*Replayer, error) {
+
+	r := &Replayer{stop: make(chan struct{})}
+	r.cond = sync.NewCond(&r.mutex)
+	for _, i := range s.Interactions {
+		if simulator.IsGAPService(i.Service) {
+			continue
+		}
+		r.interactions = append(r.interactions, i)
+	}
+	r.consumed = make([]bool, len(r.interactions))
+
+	config.Script = nil
+	config.OnWrite = r.written
+	sim, err := simulator.New(s.Database, config)
+	if err != nil {
+		return nil, err
+	}
+	r.sim = sim
+
+	for _, srv := range s.Database.Services {
+		if simulator.IsGAPService(srv.UUID) {
+			continue
+		}
+		for _, c := range srv.Characteristics {
+			char, err := sim.Char(srv.UUID, c.UUID)
+			if err != nil {
+				// a characteristic repeated in the service, the reads are
+				// served from the value
+				continue
+			}
+			key := charKey(srv.UUID, c.UUID)
+			char.SetReadFunc(func(char *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+				return r.read(key, char)
+			})
+		}
+	}
+	return r, nil
+}
+
+// next return the index of the first interaction not consumed of a
+// characteristic, -1 if there are none
+func (r *Replayer) next(op, key string) int {
+	for n, i := range r.interactions {
+		if !r.consumed[n] && i.Op == op && charKey(i.Service, i.Characteristic) == key {
+			return n
+		}
+	}
+	return -1
+}
+
+func (r *Replayer) mismatch(format string, args ...interface{}) {
+	r.mismatches = append(r.mismatches, fmt.Sprintf(format, args...))
+}
+
+// read answer a read with the next recorded value of the characteristic
+func (r *Replayer) read(key string, char *service.GattCharacteristic1) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	n := r.next(OpRead, key)
+	if n < 0 {
+		r.mismatch("Unexpected read of %s", key)
+		return nil, bluez.ErrFailed.WithMessage("Unexpected read")
+	}
+	r.consumed[n] = true
+	r.cond.Broadcast()
+	i := r.interactions[n]
+	if i.Error != "" {
+		return nil, recordedError(i.Error)
+	}
+	char.UpdateValue(i.Value)
+	return i.Value, nil
+}
+
+// recordedError rebuild the bluez error of an interaction, eg.
+// "org.bluez.Error.NotPermitted: Read not permitted", the other errors are
+// replied as Failed. The D-Bus replies need a valid error name
+func recordedError(s string) *bluez.Error {
+	name, message := s, ""
+	if n := strings.Index(s, ": "); n >= 0 {
+		name, message = s[:n], s[n+2:]
+	}
+	if !strings.HasPrefix(name, "org.bluez.") || strings.ContainsAny(name, " :") {
+		return bluez.ErrFailed.WithMessage(s)
+	}
+	return &bluez.Error{Name: name, Message: message}
+}
+
+// written check a write against the next recorded one of the characteristic
+func (r *Replayer) written(serviceUUID, charUUID string, value []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := charKey(serviceUUID, charUUID)
+	n := r.next(OpWrite, key)
+	if n < 0 {
+		r.mismatch("Unexpected write of %s: %x", key, value)
+		return
+	}
+	r.consumed[n] = true
+	r.cond.Broadcast()
+	if expected := r.interactions[n].Value; !bytes.Equal(expected, value) {
+		r.mismatch("Write %d of %s: expected %x, got %x", n, key, []byte(expected), value)
+	}
+}
+
+//Start register the simulated peripheral and play the notifications: each
+// one is sent once the reads and the writes recorded before it are replayed,
+// after the recorded delay
+func (r *Replayer) Start() error {
+	if err := r.sim.Start(); err != nil {
+		return err
+	}
+	r.done = make(chan struct{})
+	go r.play()
+	return nil
+}
+
+func (r *Replayer) play() {
+	defer close(r.done)
+	last := time.Duration(0)
+	for n, i := range r.interactions {
+		if i.Op != OpNotify {
+			r.mutex.Lock()
+			for !r.consumed[n] && !r.stopped {
+				r.cond.Wait()
+			}
+			stopped := r.stopped
+			r.mutex.Unlock()
+			if stopped {
+				return
+			}
+			last = i.Offset
+			continue
+		}
+
+		if delay := i.Offset - last; delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.stop:
+				return
+			}
+		}
+		last = i.Offset
+		r.mutex.Lock()
+		r.consumed[n] = true
+		r.mutex.Unlock()
+		char, err := r.sim.Char(i.Service, i.Characteristic)
+		if err != nil {
+			continue
+		}
+		char.UpdateValue(i.Value)
+	}
+}
+
+//Wait for the end of the replay or for ctx to be done, it returns the
+// mismatches
+func (r *Replayer) Wait(ctx context.Context) ([]string, error) {
+	if r.done == nil {
+		return nil, fmt.Errorf("Replayer not started")
+	}
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return r.Mismatches(), ctx.Err()
+	}
+	return r.Mismatches(), nil
+}
+
+//Mismatches return the reads and the writes differing from the session
+func (r *Replayer) Mismatches() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.mismatches...)
+}
+
+//Close stop the replay and the simulated peripheral
+func (r *Replayer) Close() {
+	r.mutex.Lock()
+	if !r.stopped {
+		r.stopped = true
+		close(r.stop)
+		r.cond.Broadcast()
+	}
+	r.mutex.Unlock()
+	if r.done != nil {
+		<-r.done
+	}
+	r.sim.Close()
+}
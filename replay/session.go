@@ -0,0 +1,109 @@
+//Package replay record the GATT interactions of a central with a peripheral
+// and serve them back from a simulated peripheral, for the regression tests
+// of the drivers built on the library.
+//
+// A Recorder captures the reads, the writes and the notifications of the
+// characteristics of a device, with their timings, as JSON lines: a Header
+// with the GATT database of the device then an Interaction per line. A
+// Replayer serves the database with the simulator package, answers the reads
+// with the recorded values, checks the writes and plays the notifications at
+// their recorded pace.
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/muka/go-bluetooth/api"
+)
+
+//Version the version of the session format
+const Version = 1
+
+// Operations of an Interaction
+const (
+	OpRead   = "read"
+	OpWrite  = "write"
+	OpNotify = "notify"
+)
+
+//Header the first line of a session
+type Header struct {
+	Version  int               `json:"version"`
+	Start    time.Time         `json:"start"`
+	Database *api.GattDatabase `json:"database"`
+}
+
+//Interaction a read, a write or a notification of a characteristic
+type Interaction struct {
+	// Offset the time elapsed since the start of the recording
+	Offset         time.Duration `json:"offset"`
+	Op             string        `json:"op"`
+	Service        string        `json:"service"`
+	Characteristic string        `json:"characteristic"`
+	Value          api.HexValue  `json:"value,omitempty"`
+	// Command a write without response
+	Command bool   `json:"command,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+//Session a recording
+type Session struct {
+	Header
+	Interactions []Interaction
+}
+
+//Read decode a session. The header and the interactions are JSON values,
+// one per line as written by the Recorder, a value spanning several lines is
+// accepted too
+func Read(r io.Reader) (*Session, error) {
+
+	s := new(Session)
+	dec := json.NewDecoder(r)
+
+	if err := dec.Decode(&s.Header); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("Empty session")
+		}
+		return nil, fmt.Errorf("Invalid header: %s", err)
+	}
+	if s.Version != Version {
+		return nil, fmt.Errorf("Unsupported version %d", s.Version)
+	}
+	if s.Database == nil {
+		return nil, fmt.Errorf("No database in the header")
+	}
+
+	for n := 1; ; n++ {
+		var i Interaction
+		if err := dec.Decode(&i); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("Interaction %d: %s", n, err)
+		}
+		switch i.Op {
+		case OpRead, OpWrite, OpNotify:
+		default:
+			return nil, fmt.Errorf("Interaction %d: invalid op %s", n, i.Op)
+		}
+		s.Interactions = append(s.Interactions, i)
+	}
+	return s, nil
+}
+
+//Load read a session file
+func Load(file string) (*Session, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	return s, nil
+}
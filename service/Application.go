@@ -1,16 +1,22 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/godbus/dbus"
 	"github.com/godbus/dbus/introspect"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/api"
 	"github.com/muka/go-bluetooth/bluez"
 	"github.com/muka/go-bluetooth/bluez/profile"
 )
 
-//UUIDSuffix fixed 128bit UUID [0000]+[xxxx]+[-0000-1000-8000-00805F9B34FB]
+//UUIDSuffix fixed 128bit UUID [0000]+[xxxx]+[-0000-1000-8000-00805F9B34FB], the
+// suffix of bluetooth.BaseUUID
 const UUIDSuffix = "-0000-1000-8000-00805F9B34FB"
 
 //NewApplication instantiate a new application service
@@ -23,20 +29,20 @@ func NewApplication(config *ApplicationConfig) (*Application, error) {
 		return nil, errors.New("objectPath is required")
 	}
 
-	if config.conn == nil {
-		conn, err := dbus.SystemBus()
+	if config.Conn == nil {
+		conn, err := bluez.ResolveConnection(config.BusAddress)
 		if err != nil {
 			return nil, err
 		}
-		config.conn = conn
+		config.Conn = conn
 	}
 
-	om, err := NewObjectManager(config.conn)
+	om, err := NewObjectManager(config.Conn)
 	if err != nil {
 		return nil, err
 	}
 
-	// props, err := NewProperties(config.conn)
+	// props, err := NewProperties(config.Conn)
 	// if err != nil {
 	// 	return nil, err
 	// }
@@ -45,6 +51,7 @@ func NewApplication(config *ApplicationConfig) (*Application, error) {
 		config:        config,
 		objectManager: om,
 		services:      make(map[dbus.ObjectPath]*GattService1),
+		tree:          newIntrospectionTree(config.Conn),
 	}
 
 	return s, nil
@@ -66,11 +73,56 @@ type GattDescriptorReadCallback func(app *Application, service_uuid string, char
 type ApplicationConfig struct {
 	UUIDSuffix   string
 	UUID         string
-	conn         *dbus.Conn
 	ObjectName   string
 	ObjectPath   dbus.ObjectPath
 	serviceIndex int
 	LocalName    string
+	// Appearance the GAP appearance advertised, see SetIdentity
+	Appearance uint16
+
+	// Security requirements enforced on all the characteristics and descriptors
+	Security SecurityPolicy
+
+	// NotifyWindow coalesce the value updates of a characteristic or a
+	// descriptor happening within the window in a single PropertiesChanged,
+	// zero emits each update
+	NotifyWindow time.Duration
+
+	// MaxConnections the maximum of devices connected at the same time to
+	// the adapter the application is registered on, zero is unlimited. The
+	// devices in excess are disconnected and the advertisement is stopped
+	// while the maximum is reached
+	MaxConnections int
+	// AcceptConnection decide if a device connecting may stay connected,
+	// optional
+	AcceptConnection func(device dbus.ObjectPath) bool
+
+	// AdvertiseWhileConnected keep advertising once a central is connected,
+	// so that the other centrals can still find the device. Some
+	// controllers stop advertising on a connection, bluez then releases the
+	// advertisement or keeps it registered: it is registered again on the
+	// release and on each connection to the adapter. Otherwise the
+	// application stops advertising when it is released, see Advertising
+	AdvertiseWhileConnected bool
+
+	// PairingCapability the IO capability of the agent registered by
+	// EnterPairingMode, defaults to NoInputNoOutput
+	PairingCapability string
+	// OnPairing receive the pairing events of the device accepted by
+	// EnterPairingMode and reply to them, it must not block. When not set
+	// the confirmations and the authorizations are accepted
+	OnPairing func(ev api.PairingEvent)
+
+	// Logger log the requests received by the application, defaults to
+	// bluez.GetLogger()
+	Logger bluez.Logger
+
+	// Conn the connection the application is exported and registered on,
+	// defaults to the system bus or to BusAddress
+	Conn *dbus.Conn
+	// BusAddress the address of the bus to connect to when Conn is not set,
+	// eg. unix:path=/run/dbus/proxy_bus_socket
+	BusAddress string
 
 	WriteFunc     GattWriteCallback
 	ReadFunc      GattReadCallback
@@ -86,6 +138,33 @@ type Application struct {
 
 	adMgr         *profile.LEAdvertisingManager1
 	advertisement *LEAdvertisement1
+	gattManager   *profile.GattManager1
+	tree          *introspectionTree
+	// adapterID the adapter the application is registered on
+	adapterID string
+	// discoverable advertise as general discoverable, see EnterPairingMode
+	discoverable bool
+	// connections enforce MaxConnections and AcceptConnection
+	connections *connectionPolicy
+	// serviceData advertised, see SetServiceData
+	serviceData      map[string]interface{}
+	serviceDataMutex sync.Mutex
+	// advertisingAdapter the adapter of the advertisement
+	advertisingAdapter string
+	// advertisingMutex serialize the changes of the advertisement, from the
+	// callers, the rotations and the releases by bluez
+	advertisingMutex sync.Mutex
+	// advertisingWatch stop watching the connections, see
+	// AdvertiseWhileConnected
+	advertisingWatch func()
+	// paired the devices known to be paired while the application is
+	// registered, see CheckSecurity
+	paired      map[dbus.ObjectPath]bool
+	pairedMutex sync.Mutex
+	pairedWatch func()
+
+	// batch the objects added since Begin, nil when not batching
+	batch map[dbus.ObjectPath]map[string]bluez.Properties
 }
 
 //GetObjectManager return the object manager interface handler
@@ -93,6 +172,14 @@ func (app *Application) GetObjectManager() *ObjectManager {
 	return app.objectManager
 }
 
+//Logger return the application logger
+func (app *Application) Logger() bluez.Logger {
+	if app.config.Logger != nil {
+		return app.config.Logger
+	}
+	return bluez.GetLogger()
+}
+
 //Path return the object path
 func (app *Application) Path() dbus.ObjectPath {
 	return app.config.ObjectPath
@@ -103,13 +190,13 @@ func (app *Application) Name() string {
 	return app.config.ObjectName
 }
 
-// GenerateUUID generate a 128bit UUID
+// GenerateUUID generate a 128bit UUID, in its canonical form
 func (app *Application) GenerateUUID(uuidVal string) string {
 	base := app.config.UUID
 	if len(uuidVal) == 8 {
 		base = ""
 	}
-	return base + uuidVal + app.config.UUIDSuffix
+	return bluetooth.CanonicalUUID(base + uuidVal + app.config.UUIDSuffix)
 }
 
 //CreateService create a new GattService1 instance
@@ -130,7 +217,7 @@ func (app *Application) CreateService(props *profile.GattService1Properties, adv
 		app:        app,
 		objectPath: dbus.ObjectPath(path),
 		ID:         app.config.serviceIndex,
-		conn:       app.config.conn,
+		conn:       app.config.Conn,
 		advertised: advertise,
 	}
 	s, err := NewGattService1(c, props)
@@ -147,17 +234,7 @@ func (app *Application) AddService(service *GattService1) error {
 		return err
 	}
 
-	err = app.exportTree()
-	if err != nil {
-		return err
-	}
-
-	err = app.GetObjectManager().AddObject(service.Path(), service.Properties())
-	if err != nil {
-		return err
-	}
-
-	return err
+	return app.addObject(service.Path(), service.Properties())
 }
 
 //RemoveService remove an exposed service
@@ -165,14 +242,14 @@ func (app *Application) RemoveService(service *GattService1) error {
 	if _, ok := app.services[service.Path()]; ok {
 
 		delete(app.services, service.Path())
-		err := app.GetObjectManager().RemoveObject(service.Path())
+		err := app.removeObject(service.Path())
 
 		//TODO: remove chars + descritptors too
 		if err != nil {
 			return err
 		}
 
-		err = app.exportTree()
+		err = app.tree.remove(service.Path())
 		if err != nil {
 			return err
 		}
@@ -180,6 +257,49 @@ func (app *Application) RemoveService(service *GattService1) error {
 	return nil
 }
 
+//Begin start a batch: the services, characteristics and descriptors added
+// until Commit are exported and signaled at once, instead of one by one.
+// Build the whole application in a batch for a faster startup
+func (app *Application) Begin() {
+	if app.batch != nil {
+		return
+	}
+	app.batch = make(map[dbus.ObjectPath]map[string]bluez.Properties)
+	app.tree.begin()
+}
+
+//Commit export the objects added since Begin, the introspection of each node
+// and the ObjectManager are updated in one pass
+func (app *Application) Commit() error {
+	if app.batch == nil {
+		return nil
+	}
+	batch := app.batch
+	app.batch = nil
+
+	err := app.tree.flush()
+	if err != nil {
+		return err
+	}
+	return app.GetObjectManager().AddObjects(batch)
+}
+
+// addObject add an object to the ObjectManager, or to the batch
+func (app *Application) addObject(path dbus.ObjectPath, props map[string]bluez.Properties) error {
+	if app.batch != nil {
+		app.batch[path] = props
+		return nil
+	}
+	return app.GetObjectManager().AddObject(path, props)
+}
+
+func (app *Application) removeObject(path dbus.ObjectPath) error {
+	if app.batch != nil {
+		delete(app.batch, path)
+	}
+	return app.GetObjectManager().RemoveObject(path)
+}
+
 //GetServices return the registered services
 func (app *Application) GetServices() map[dbus.ObjectPath]*GattService1 {
 	return app.services
@@ -188,7 +308,7 @@ func (app *Application) GetServices() map[dbus.ObjectPath]*GattService1 {
 //expose dbus interfaces
 func (app *Application) expose() error {
 
-	conn := app.config.conn
+	conn := app.config.Conn
 	_, err := conn.RequestName(app.Name(), dbus.NameFlagDoNotQueue&dbus.NameFlagReplaceExisting)
 	if err != nil {
 		return err
@@ -200,57 +320,20 @@ func (app *Application) expose() error {
 		return err
 	}
 
-	err = app.exportTree()
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (app *Application) exportTree() error {
-
-	childrenNode := make([]introspect.Node, 0)
-
-	for servicePath, service := range app.GetServices() {
-		childrenNode = append(childrenNode, introspect.Node{
-			Name: string(servicePath)[1:],
-		})
-		for charPath, char := range service.GetCharacteristics() {
-			childrenNode = append(childrenNode, introspect.Node{
-				Name: string(charPath)[1:],
-			})
-			for descPath := range char.GetDescriptors() {
-				childrenNode = append(childrenNode, introspect.Node{
-					Name: string(descPath)[1:],
-				})
-			}
-		}
-	}
-
-	// must include also child nodes
-	node := &introspect.Node{
-		Interfaces: []introspect.Interface{
-			//Introspect
-			introspect.IntrospectData,
-			//ObjectManager
-			bluez.ObjectManagerIntrospectData,
-		},
-		Children: childrenNode,
-	}
-
-	err := app.config.conn.Export(
-		introspect.NewIntrospectable(node),
-		app.Path(),
-		"org.freedesktop.DBus.Introspectable")
-
-	return err
+	// must include also child nodes, listed as they are added
+	return app.tree.add(app.Path(), []introspect.Interface{
+		//Introspect
+		introspect.IntrospectData,
+		//ObjectManager
+		bluez.ObjectManagerIntrospectData,
+	})
 }
 
 // CallbackError error from a callback
 type CallbackError struct {
 	msg  string
 	code int
+	err  error
 }
 
 func (e *CallbackError) Error() string {
@@ -269,6 +352,31 @@ const CallbackNotRegistered = -1
 //CallbackFunctionError callback reported an error
 const CallbackFunctionError = -2
 
+//CallbackPanic callback panicked, bluez receives org.bluez.Error.Failed
+const CallbackPanic = -3
+
+// callbackError convert the error of a callback
+func callbackError(err error) *CallbackError {
+	if _, ok := err.(*bluez.PanicError); ok {
+		return NewCallbackError(CallbackPanic, err.Error())
+	}
+	result := NewCallbackError(CallbackFunctionError, err.Error())
+	result.err = err
+	return result
+}
+
+//DBusError return the error replied to bluez
+func (e *CallbackError) DBusError() *dbus.Error {
+	if e.code == CallbackPanic {
+		return bluez.ErrFailed.DBusError()
+	}
+	// a bluez error keeps its name, the message is sent as the error body
+	if err, ok := e.err.(*bluez.Error); ok {
+		return err.DBusError()
+	}
+	return dbus.NewError(e.msg, nil)
+}
+
 //HandleRead Handle application read
 func (app *Application) HandleRead(srvUUID string, uuid string) ([]byte, *CallbackError) {
 	if app.config.ReadFunc == nil {
@@ -277,9 +385,14 @@ func (app *Application) HandleRead(srvUUID string, uuid string) ([]byte, *Callba
 	}
 
 	var cberr *CallbackError
-	b, err := app.config.ReadFunc(app, srvUUID, uuid)
+	var b []byte
+	err := bluez.SafeCall(app.Logger(), "ReadFunc", func() (err error) {
+		b, err = app.config.ReadFunc(app, srvUUID, uuid)
+		return err
+	})
+	app.recordEvent(bluez.EventRead, uuid, b, err)
 	if err != nil {
-		cberr = NewCallbackError(-2, err.Error())
+		cberr = callbackError(err)
 	}
 
 	return b, cberr
@@ -291,9 +404,12 @@ func (app *Application) HandleWrite(srvUUID string, uuid string, value []byte) *
 		return NewCallbackError(-1, "No callback registered.")
 	}
 
-	err := app.config.WriteFunc(app, srvUUID, uuid, value)
+	err := bluez.SafeCall(app.Logger(), "WriteFunc", func() error {
+		return app.config.WriteFunc(app, srvUUID, uuid, value)
+	})
+	app.recordEvent(bluez.EventWrite, uuid, value, err)
 	if err != nil {
-		return NewCallbackError(-2, err.Error())
+		return callbackError(err)
 	}
 
 	return nil
@@ -307,9 +423,14 @@ func (app *Application) HandleDescriptorRead(srvUUID string, charUUID string, de
 	}
 
 	var cberr *CallbackError
-	b, err := app.config.DescReadFunc(app, srvUUID, charUUID, descUUID)
+	var b []byte
+	err := bluez.SafeCall(app.Logger(), "DescReadFunc", func() (err error) {
+		b, err = app.config.DescReadFunc(app, srvUUID, charUUID, descUUID)
+		return err
+	})
+	app.recordEvent(bluez.EventRead, descUUID, b, err)
 	if err != nil {
-		cberr = NewCallbackError(-2, err.Error())
+		cberr = callbackError(err)
 	}
 
 	return b, cberr
@@ -321,14 +442,31 @@ func (app *Application) HandleDescriptorWrite(srvUUID string, charUUID string, d
 		return NewCallbackError(-1, "No callback registered.")
 	}
 
-	err := app.config.DescWriteFunc(app, srvUUID, charUUID, descUUID, value)
+	err := bluez.SafeCall(app.Logger(), "DescWriteFunc", func() error {
+		return app.config.DescWriteFunc(app, srvUUID, charUUID, descUUID, value)
+	})
+	app.recordEvent(bluez.EventWrite, descUUID, value, err)
 	if err != nil {
-		return NewCallbackError(-2, err.Error())
+		return callbackError(err)
 	}
 
 	return nil
 }
 
+// recordEvent record a read or write served by the callbacks
+func (app *Application) recordEvent(t string, uuid string, value []byte, err error) {
+	e := bluez.Event{
+		Type: t,
+		Path: string(app.Path()),
+		Name: uuid,
+		Data: bluez.EventData(value),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	bluez.RecordEvent(e)
+}
+
 //Run start the application
 func (app *Application) Run() error {
 
@@ -342,6 +480,13 @@ func (app *Application) Run() error {
 
 //StartAdvertising advertise information for a service
 func (app *Application) StartAdvertising(deviceInterface string) error {
+	app.advertisingMutex.Lock()
+	defer app.advertisingMutex.Unlock()
+	return app.startAdvertising(deviceInterface)
+}
+
+// startAdvertising register the advertisement, with advertisingMutex held
+func (app *Application) startAdvertising(deviceInterface string) error {
 	if app.advertisement != nil && app.adMgr != nil {
 		// Already advertising
 		return nil
@@ -350,7 +495,7 @@ func (app *Application) StartAdvertising(deviceInterface string) error {
 	path := "/org/bluez/advertisement/0"
 
 	config := &LEAdvertisement1Config{
-		conn:       app.config.conn,
+		conn:       app.config.Conn,
 		objectPath: dbus.ObjectPath(path),
 	}
 
@@ -366,6 +511,9 @@ func (app *Application) StartAdvertising(deviceInterface string) error {
 		Type:         "peripheral",
 		LocalName:    app.config.LocalName,
 		ServiceUUIDs: serviceUUIDs,
+		Discoverable: app.discoverable,
+		ServiceData:  app.advertisedServiceData(),
+		Appearance:   app.config.Appearance,
 	}
 
 	var err error
@@ -381,18 +529,55 @@ func (app *Application) StartAdvertising(deviceInterface string) error {
 		app.advertisement = nil
 		return err
 	}
-
-	options := make(map[string]interface{})
+	adv := app.advertisement
+	adv.onRelease = func() {
+		app.advertisementReleased(adv)
+	}
 
 	app.adMgr = profile.NewLEAdvertisingManager1(deviceInterface)
+	app.adMgr.SetConnection(app.config.Conn)
 
-	err = app.adMgr.RegisterAdvertisement(path, options)
+	err = app.registerAdvertisement(deviceInterface)
 	if err != nil {
 		app.advertisement = nil
 		app.adMgr = nil
 		return err
 	}
 
+	app.advertisingAdapter = deviceInterface
+	if app.config.AdvertiseWhileConnected {
+		if err = app.watchAdvertisingConnections(adv, deviceInterface); err != nil {
+			app.Logger().Warnf("Cannot watch the connections: %s", err.Error())
+		}
+	}
+	app.recover(app.advertisementRecovery(), func() error {
+		app.advertisingMutex.Lock()
+		defer app.advertisingMutex.Unlock()
+		if app.advertisement != adv {
+			// stopped meanwhile
+			return nil
+		}
+		return app.registerAdvertisement(deviceInterface)
+	})
+
+	return nil
+}
+
+// registerAdvertisement register the exposed advertisement and make the
+// adapter discoverable, with advertisingMutex held
+func (app *Application) registerAdvertisement(deviceInterface string) (err error) {
+
+	_, span := bluez.StartSpan(context.Background(), "advertisement.start",
+		bluez.AttrAdapter, deviceInterface,
+		bluez.AttrPath, string(app.advertisement.config.objectPath))
+	defer func() { span.End(err) }()
+
+	options := make(map[string]interface{})
+	err = app.adMgr.RegisterAdvertisement(string(app.advertisement.config.objectPath), options)
+	if err != nil {
+		return err
+	}
+
 	adapter := profile.NewAdapter1(deviceInterface)
 	err = adapter.SetProperty("Discoverable", dbus.MakeVariant(true))
 	if err != nil {
@@ -409,12 +594,27 @@ func (app *Application) StartAdvertising(deviceInterface string) error {
 
 //StopAdvertising stop advertising information on a service
 func (app *Application) StopAdvertising() error {
+	app.advertisingMutex.Lock()
+	defer app.advertisingMutex.Unlock()
+	return app.stopAdvertising()
+}
+
+// stopAdvertising unregister the advertisement, with advertisingMutex held
+func (app *Application) stopAdvertising() error {
 	if app.advertisement == nil || app.adMgr == nil {
 		// Not advertising
 		return nil
 	}
 
+	_, span := bluez.StartSpan(context.Background(), "advertisement.stop",
+		bluez.AttrPath, string(app.advertisement.config.objectPath))
 	err := app.adMgr.UnregisterAdvertisement(string(app.advertisement.config.objectPath))
+	span.End(err)
+	app.forget(app.advertisementRecovery())
+	if app.advertisingWatch != nil {
+		app.advertisingWatch()
+		app.advertisingWatch = nil
+	}
 
 	app.advertisement = nil
 	app.adMgr = nil
@@ -425,3 +625,104 @@ func (app *Application) StopAdvertising() error {
 
 	return nil
 }
+
+//Register the application with the GattManager1 of an adapter, the
+// registration is renewed when bluetoothd restarts
+func (app *Application) Register(adapterID string) error {
+
+	if app.gattManager != nil {
+		return errors.New("Application already registered")
+	}
+
+	gattManager := profile.NewGattManager1(adapterID)
+	gattManager.SetConnection(app.config.Conn)
+
+	register := func() error {
+		_, span := bluez.StartSpan(context.Background(), "application.register",
+			bluez.AttrAdapter, adapterID,
+			bluez.AttrPath, string(app.Path()))
+		err := gattManager.RegisterApplication(app.Path(), map[string]interface{}{})
+		span.End(err)
+		return err
+	}
+	err := register()
+	if err != nil {
+		return err
+	}
+	powered := false
+	if props, err := profile.NewAdapter1(adapterID).GetProperties(); err == nil {
+		powered = props.Powered
+	}
+
+	if err = app.watchConnectionPolicy(adapterID); err != nil {
+		gattManager.UnregisterApplication(app.Path())
+		return err
+	}
+	if err = app.watchPairedDevices(); err != nil {
+		app.unwatchConnectionPolicy()
+		gattManager.UnregisterApplication(app.Path())
+		return err
+	}
+
+	app.gattManager = gattManager
+	app.adapterID = adapterID
+	app.recover(app.adapterRecovery(), func() error {
+		return app.setupAdapter(adapterID, powered)
+	})
+	app.recover(app.applicationRecovery(), register)
+	return nil
+}
+
+// setupAdapter restore the adapter after a bluetoothd restart, before the
+// registrations: bluetoothd may start with the adapters off, eg. without
+// AutoEnable
+func (app *Application) setupAdapter(adapterID string, powered bool) error {
+	if !powered {
+		return nil
+	}
+	return profile.NewAdapter1(adapterID).SetProperty("Powered", dbus.MakeVariant(true))
+}
+
+//Unregister the application from the GattManager1
+func (app *Application) Unregister() error {
+	if app.gattManager == nil {
+		return nil
+	}
+	app.forget(app.applicationRecovery())
+	app.forget(app.adapterRecovery())
+	app.unwatchConnectionPolicy()
+	app.unwatchPairedDevices()
+	err := app.gattManager.UnregisterApplication(app.Path())
+	app.gattManager = nil
+	app.adapterID = ""
+	return err
+}
+
+func (app *Application) adapterRecovery() string {
+	return "service.adapter:" + string(app.Path())
+}
+
+func (app *Application) applicationRecovery() string {
+	return "service.application:" + string(app.Path())
+}
+
+func (app *Application) advertisementRecovery() string {
+	return "service.advertisement:" + string(app.Path())
+}
+
+// recover add a recovery handler on the application connection
+func (app *Application) recover(name string, fn bluez.RecoveryHandler) {
+	recovery, err := bluez.GetRecovery(app.config.Conn)
+	if err != nil {
+		app.Logger().Warnf("Cannot watch for bluetoothd restarts: %s", err.Error())
+		return
+	}
+	recovery.Add(name, fn)
+}
+
+func (app *Application) forget(name string) {
+	recovery, err := bluez.GetRecovery(app.config.Conn)
+	if err == nil {
+		recovery.Remove(name)
+	}
+}
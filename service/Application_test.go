@@ -0,0 +1,325 @@
+package service_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestGattApplication(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+		ReadFunc: func(app *service.Application, srvUUID string, charUUID string) ([]byte, error) {
+			return []byte{42}, nil
+		},
+		WriteFunc: func(app *service.Application, srvUUID string, charUUID string, value []byte) error {
+			panic("bad handler")
+		},
+		Logger: bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: true,
+		UUID:    app.GenerateUUID("2233"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.AddService(srv); err != nil {
+		t.Fatal(err)
+	}
+	char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID:  app.GenerateUUID("3344"),
+		Flags: []string{bluez.FlagCharacteristicRead},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.AddCharacteristic(char); err != nil {
+		t.Fatal(err)
+	}
+
+	err = profile.NewGattManager1("hci0").RegisterApplication(app.Path(), map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apps := b.Adapter("hci0").Applications()
+	if len(apps) != 1 {
+		t.Fatalf("Expected 1 application, got %d", len(apps))
+	}
+	if len(apps[0].Services()) != 1 {
+		t.Fatalf("Expected 1 service, got %v", apps[0].Services())
+	}
+
+	children := func(path dbus.ObjectPath) []string {
+		node, err := introspect.Call(b.Conn().Object(apps[0].Sender, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, child := range node.Children {
+			names = append(names, child.Name)
+		}
+		return names
+	}
+	if names := children(app.Path()); len(names) != 1 || names[0] != "service1" {
+		t.Fatalf("Unexpected application children %v", names)
+	}
+	if names := children(srv.Path()); len(names) != 1 || names[0] != "char1" {
+		t.Fatalf("Unexpected service children %v", names)
+	}
+
+	value, err := apps[0].ReadValue(app.GenerateUUID("3344"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(value) != 1 || value[0] != 42 {
+		t.Fatalf("Unexpected value %v", value)
+	}
+
+	// a panic in a callback fails the call only
+	err = apps[0].WriteValue(app.GenerateUUID("3344"), []byte{1}, nil)
+	if !bluez.IsError(err, bluez.ErrFailed) {
+		t.Fatalf("Expected %s, got %v", bluez.ErrFailed, err)
+	}
+	if _, err = apps[0].ReadValue(app.GenerateUUID("3344"), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGattApplicationBatch(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.Begin()
+	for i := 0; i < 3; i++ {
+		srv, err := app.CreateService(&profile.GattService1Properties{
+			Primary: true,
+			UUID:    app.GenerateUUID("223" + strconv.Itoa(i)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = app.AddService(srv); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 2; j++ {
+			char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+				UUID:  app.GenerateUUID("33" + strconv.Itoa(i) + strconv.Itoa(j)),
+				Flags: []string{bluez.FlagCharacteristicRead},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = srv.AddCharacteristic(char); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	objects, _ := app.GetObjectManager().GetManagedObjects()
+	if len(objects) != 0 {
+		t.Fatalf("Expected no objects before commit, got %d", len(objects))
+	}
+	if err = app.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = profile.NewGattManager1("hci0").RegisterApplication(app.Path(), map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apps := b.Adapter("hci0").Applications()
+	if len(apps) != 1 {
+		t.Fatalf("Expected 1 application, got %d", len(apps))
+	}
+	if len(apps[0].Services()) != 3 {
+		t.Fatalf("Expected 3 services, got %v", apps[0].Services())
+	}
+	if len(apps[0].Objects()) != 9 {
+		t.Fatalf("Expected 9 objects, got %d", len(apps[0].Objects()))
+	}
+
+	node, err := introspect.Call(b.Conn().Object(apps[0].Sender, app.Path()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(node.Children) != 3 {
+		t.Fatalf("Unexpected application children %v", node.Children)
+	}
+
+	dump := app.Snapshot()
+	if dump.Error != "" {
+		t.Fatal(dump.Error)
+	}
+	if len(dump.Exported) != 9 || len(dump.Visible) != 9 {
+		t.Fatalf("Expected 9 objects, got %d exported and %d visible", len(dump.Exported), len(dump.Visible))
+	}
+	if len(dump.Exported[0].Introspection) == 0 {
+		t.Fatalf("Missing introspection for %s", dump.Exported[0].Path)
+	}
+	if _, err := app.Dump(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitRecovered wait for the handlers run after a restart
+func waitRecovered(t *testing.T, events <-chan *bluez.RecoveryEvent) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Status != bluez.RecoveryRecovered {
+				continue
+			}
+			if len(ev.Errors) > 0 {
+				t.Fatalf("Recovery failed: %v", ev.Errors)
+			}
+			return
+		case <-timeout:
+			t.Fatal("Timeout waiting for the recovery")
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+		LocalName:  "bluetest",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: true,
+		UUID:    app.GenerateUUID("2233"),
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.AddService(srv); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = app.Register("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Unregister()
+	if err = app.StartAdvertising("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer app.StopAdvertising()
+
+	recovery, err := bluez.GetRecovery(b.ClientConn())
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, cancel := recovery.Events()
+	defer cancel()
+
+	if err = b.Restart(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitRecovered(t, events)
+
+	adapter := b.Adapter("hci0")
+	if len(adapter.Applications()) != 1 {
+		t.Fatalf("Expected the application to be registered again")
+	}
+	if len(adapter.Advertisements()) != 1 {
+		t.Fatalf("Expected the advertisement to be registered again")
+	}
+}
+
+func TestRecoveryAdapter(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Register("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Unregister()
+
+	recovery, err := bluez.GetRecovery(b.ClientConn())
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, cancel := recovery.Events()
+	defer cancel()
+
+	// bluetoothd is back with the adapter off
+	if err = b.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	waitRecovered(t, events)
+
+	adapter := b.Adapter("hci0")
+	if !adapter.Property("Powered").(bool) {
+		t.Fatal("Expected the adapter powered again")
+	}
+	if len(adapter.Applications()) != 1 {
+		t.Fatal("Expected the application to be registered again")
+	}
+}
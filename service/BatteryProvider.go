@@ -0,0 +1,294 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//BatteryProviderConfig BatteryProvider configuration
+type BatteryProviderConfig struct {
+	// ObjectPath the root of the batteries, exported as an ObjectManager
+	ObjectPath dbus.ObjectPath
+	// Source the default Source of the batteries, eg. "HFP" or "HID"
+	Source string
+
+	// Conn the connection the provider is exported on, defaults to the
+	// system bus or to BusAddress
+	Conn *dbus.Conn
+	// BusAddress the bus to connect to when Conn is not set, defaults to the
+	// system bus
+	BusAddress string
+}
+
+//NewBatteryProvider create a provider reporting the battery levels of remote
+// devices to bluetoothd, eg. as received by a profile implementation. Call
+// Register to make the levels available as the Battery1 of the devices
+func NewBatteryProvider(config *BatteryProviderConfig) (*BatteryProvider, error) {
+
+	if config.ObjectPath == "" {
+		return nil, errors.New("objectPath is required")
+	}
+
+	if config.Conn == nil {
+		conn, err := bluez.ResolveConnection(config.BusAddress)
+		if err != nil {
+			return nil, err
+		}
+		config.Conn = conn
+	}
+
+	om, err := NewObjectManager(config.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BatteryProvider{
+		config:        config,
+		objectManager: om,
+		tree:          newIntrospectionTree(config.Conn),
+		batteries:     make(map[dbus.ObjectPath]*Battery),
+	}, nil
+}
+
+//BatteryProvider the batteries of the remote devices, registered with the
+// BatteryProviderManager1 of an adapter
+type BatteryProvider struct {
+	config        *BatteryProviderConfig
+	objectManager *ObjectManager
+	tree          *introspectionTree
+
+	mutex     sync.Mutex
+	exposed   bool
+	batteries map[dbus.ObjectPath]*Battery
+	manager   *profile.BatteryProviderManager1
+}
+
+//Path return the object path
+func (p *BatteryProvider) Path() dbus.ObjectPath {
+	return p.config.ObjectPath
+}
+
+// expose the ObjectManager of the provider, once
+func (p *BatteryProvider) expose() error {
+
+	if p.exposed {
+		return nil
+	}
+
+	err := p.config.Conn.Export(p.objectManager, p.Path(), bluez.ObjectManagerInterface)
+	if err != nil {
+		return err
+	}
+
+	err = p.tree.add(p.Path(), []introspect.Interface{
+		//Introspect
+		introspect.IntrospectData,
+		//ObjectManager
+		bluez.ObjectManagerIntrospectData,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.exposed = true
+	return nil
+}
+
+//AddBattery report the battery level of a device, eg.
+// /org/bluez/hci0/dev_00_11_22_33_44_55. The level is updated with
+// Battery.SetPercentage
+func (p *BatteryProvider) AddBattery(device dbus.ObjectPath, percentage byte) (*Battery, error) {
+
+	if percentage > 100 {
+		return nil, fmt.Errorf("Invalid battery percentage %d", percentage)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.batteries[device]; ok {
+		return nil, fmt.Errorf("Battery of %s already added", device)
+	}
+
+	err := p.expose()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Battery{
+		path: dbus.ObjectPath(string(p.Path()) + "/" + path.Base(string(device))),
+		properties: &profile.BatteryProvider1Properties{
+			Percentage: percentage,
+			Source:     p.config.Source,
+			Device:     device,
+		},
+	}
+
+	b.PropertiesInterface, err = NewProperties(p.config.Conn)
+	if err != nil {
+		return nil, err
+	}
+	err = b.PropertiesInterface.AddProperties(b.Interface(), b.properties)
+	if err != nil {
+		return nil, err
+	}
+	b.PropertiesInterface.Expose(b.Path())
+
+	err = p.tree.add(b.Path(), []introspect.Interface{
+		//Introspect
+		introspect.IntrospectData,
+		//Properties
+		prop.IntrospectData,
+		//BatteryProvider1
+		{
+			Name:       b.Interface(),
+			Properties: b.PropertiesInterface.Introspection(b.Interface()),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.objectManager.AddObject(b.Path(), map[string]bluez.Properties{
+		b.Interface(): b.properties,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.batteries[device] = b
+	return b, nil
+}
+
+//Battery return the battery of a device, nil if not added
+func (p *BatteryProvider) Battery(device dbus.ObjectPath) *Battery {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.batteries[device]
+}
+
+//RemoveBattery stop reporting the battery level of a device, eg. once
+// disconnected
+func (p *BatteryProvider) RemoveBattery(device dbus.ObjectPath) error {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	b, ok := p.batteries[device]
+	if !ok {
+		return nil
+	}
+	delete(p.batteries, device)
+
+	err := p.objectManager.RemoveObject(b.Path())
+	if err != nil {
+		return err
+	}
+	p.config.Conn.Export(nil, b.Path(), bluez.PropertiesInterface)
+	return p.tree.remove(b.Path())
+}
+
+//Register the provider with the BatteryProviderManager1 of an adapter, the
+// registration is renewed when bluetoothd restarts
+func (p *BatteryProvider) Register(adapterID string) error {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.manager != nil {
+		return errors.New("Battery provider already registered")
+	}
+
+	err := p.expose()
+	if err != nil {
+		return err
+	}
+
+	manager := profile.NewBatteryProviderManager1("/org/bluez/" + adapterID)
+	manager.SetConnection(p.config.Conn)
+
+	register := func() error {
+		return manager.RegisterBatteryProvider(p.Path())
+	}
+	if err = register(); err != nil {
+		return err
+	}
+
+	p.manager = manager
+	if recovery, err := bluez.GetRecovery(p.config.Conn); err == nil {
+		recovery.Add(p.recovery(), register)
+	} else {
+		bluez.GetLogger().Warnf("Cannot watch for bluetoothd restarts: %s", err.Error())
+	}
+	return nil
+}
+
+//Unregister the provider from the BatteryProviderManager1, bluetoothd drops
+// the batteries
+func (p *BatteryProvider) Unregister() error {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.manager == nil {
+		return nil
+	}
+	if recovery, err := bluez.GetRecovery(p.config.Conn); err == nil {
+		recovery.Remove(p.recovery())
+	}
+	err := p.manager.UnregisterBatteryProvider(p.Path())
+	p.manager = nil
+	return err
+}
+
+func (p *BatteryProvider) recovery() string {
+	return "service.batteryprovider:" + string(p.Path())
+}
+
+//Battery the battery of a remote device, exported as BatteryProvider1
+type Battery struct {
+	path                dbus.ObjectPath
+	properties          *profile.BatteryProvider1Properties
+	PropertiesInterface *Properties
+}
+
+//Interface return the dbus interface name
+func (b *Battery) Interface() string {
+	return profile.BatteryProvider1Interface
+}
+
+//Path return the object path
+func (b *Battery) Path() dbus.ObjectPath {
+	return b.path
+}
+
+//Device return the path of the device of the battery
+func (b *Battery) Device() dbus.ObjectPath {
+	return b.properties.Device
+}
+
+//Percentage return the battery level
+func (b *Battery) Percentage() byte {
+	v, _ := b.PropertiesInterface.Get(b.Interface())["Percentage"].(byte)
+	return v
+}
+
+//SetPercentage update the battery level, from 0 to 100
+func (b *Battery) SetPercentage(percentage byte) error {
+	if percentage > 100 {
+		return fmt.Errorf("Invalid battery percentage %d", percentage)
+	}
+	// stored in the properties by the Set callback, under the interface lock
+	if err := b.PropertiesInterface.Instance().Set(b.Interface(), "Percentage", dbus.MakeVariant(percentage)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestBatteryProvider(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	provider, err := service.NewBatteryProvider(&service.BatteryProviderConfig{
+		ObjectPath: "/bluetest/battery",
+		Source:     "HFP",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	device := dbus.ObjectPath("/org/bluez/hci0/dev_00_00_00_00_00_01")
+	battery, err := provider.AddBattery(device, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = provider.AddBattery(device, 80); err == nil {
+		t.Fatal("Battery added twice")
+	}
+
+	if err = provider.Register("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Unregister()
+
+	providers := b.Adapter("hci0").BatteryProviders()
+	if len(providers) != 1 {
+		t.Fatalf("Expected a battery provider, got %d", len(providers))
+	}
+	if level := providers[0].Batteries()[device]; level != 80 {
+		t.Fatalf("Expected 80%%, got %d", level)
+	}
+
+	if err = battery.SetPercentage(101); err == nil {
+		t.Fatal("Invalid percentage accepted")
+	}
+	if err = battery.SetPercentage(55); err != nil {
+		t.Fatal(err)
+	}
+	if battery.Percentage() != 55 {
+		t.Fatalf("Expected 55%%, got %d", battery.Percentage())
+	}
+	if err = providers[0].Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if level := providers[0].Batteries()[device]; level != 55 {
+		t.Fatalf("Expected 55%%, got %d", level)
+	}
+
+	if err = provider.RemoveBattery(device); err != nil {
+		t.Fatal(err)
+	}
+	if err = providers[0].Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if len(providers[0].Batteries()) != 0 {
+		t.Fatalf("Expected no battery, got %v", providers[0].Batteries())
+	}
+}
@@ -3,10 +3,10 @@ package service
 import (
 	"strconv"
 
-	log "github.com/Sirupsen/logrus"
 	"github.com/godbus/dbus"
 	"github.com/godbus/dbus/introspect"
 	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth"
 	"github.com/muka/go-bluetooth/bluez"
 	"github.com/muka/go-bluetooth/bluez/profile"
 )
@@ -50,6 +50,41 @@ type GattCharacteristic1 struct {
 	descriptors         map[dbus.ObjectPath]*GattDescriptor1
 	descIndex           int
 	notifying           bool
+
+	readFunc    CharacteristicReadFunc
+	writeFunc   CharacteristicWriteFunc
+	confirmFunc func(c *GattCharacteristic1)
+	validator   CharacteristicValidator
+}
+
+//CharacteristicReadFunc handle the reads of a characteristic, see SetReadFunc
+type CharacteristicReadFunc func(c *GattCharacteristic1, options map[string]interface{}) ([]byte, error)
+
+//CharacteristicWriteFunc handle the writes of a characteristic, see
+// SetWriteFunc
+type CharacteristicWriteFunc func(c *GattCharacteristic1, value []byte, options map[string]interface{}) error
+
+//SetReadFunc handle the reads of the characteristic with fn instead of the
+// ReadFunc of the application, eg. for a ready-made service
+func (s *GattCharacteristic1) SetReadFunc(fn CharacteristicReadFunc) {
+	s.readFunc = fn
+}
+
+//SetWriteFunc handle the writes of the characteristic with fn instead of the
+// WriteFunc of the application
+func (s *GattCharacteristic1) SetWriteFunc(fn CharacteristicWriteFunc) {
+	s.writeFunc = fn
+}
+
+//SetConfirmFunc call fn when bluez reports an indication was confirmed by
+// the remote device
+func (s *GattCharacteristic1) SetConfirmFunc(fn func(c *GattCharacteristic1)) {
+	s.confirmFunc = fn
+}
+
+//UUID return the UUID of the characteristic
+func (s *GattCharacteristic1) UUID() string {
+	return s.properties.UUID
 }
 
 //Interface return the dbus interface name
@@ -96,6 +131,7 @@ func (s *GattCharacteristic1) CreateDescriptor(props *profile.GattDescriptor1Pro
 	}
 
 	props.Characteristic = config.objectPath
+	props.Flags = s.config.service.GetApp().config.Security.ApplyFlags(props.Flags)
 
 	desc, err := NewGattDescriptor1(config, props)
 	return desc, err
@@ -111,28 +147,41 @@ func (s *GattCharacteristic1) AddDescriptor(desc *GattDescriptor1) error {
 		return err
 	}
 
-	err = s.config.service.GetApp().exportTree()
-	if err != nil {
-		return err
-	}
-
-	om := s.config.service.GetApp().GetObjectManager()
-	return om.AddObject(desc.Path(), desc.Properties())
+	return s.config.service.GetApp().addObject(desc.Path(), desc.Properties())
 }
 
 //RemoveDescriptor remove a characteristic
 func (s *GattCharacteristic1) RemoveDescriptor(char *GattDescriptor1) error {
 	if _, ok := s.descriptors[char.Path()]; ok {
 		delete(s.descriptors, char.Path())
-		om := s.config.service.GetApp().GetObjectManager()
-		return om.RemoveObject(char.Path())
+		err := s.config.service.GetApp().tree.remove(char.Path())
+		if err != nil {
+			return err
+		}
+		return s.config.service.GetApp().removeObject(char.Path())
 	}
 	return nil
 }
 
 //ReadValue read a value
 func (s *GattCharacteristic1) ReadValue(options map[string]interface{}) ([]byte, *dbus.Error) {
-	log.Debug("Characteristic.ReadValue")
+	s.logger().Debugf("Characteristic.ReadValue %s %v", bluetooth.DescribeUUID(s.properties.UUID), options)
+
+	if secErr := s.config.service.GetApp().CheckSecurity(options); secErr != nil {
+		return nil, secErr.DBusError()
+	}
+
+	if s.readFunc != nil {
+		var b []byte
+		err := bluez.SafeCall(s.logger(), "ReadFunc", func() (err error) {
+			b, err = s.readFunc(s, options)
+			return err
+		})
+		if err != nil {
+			return nil, callbackError(err).DBusError()
+		}
+		return b, nil
+	}
 
 	b, err := s.config.service.config.app.HandleRead(s.config.service.properties.UUID, s.properties.UUID)
 
@@ -140,9 +189,9 @@ func (s *GattCharacteristic1) ReadValue(options map[string]interface{}) ([]byte,
 	if err != nil {
 		if err.code == -1 {
 			// No registered callback, so we'll just use our stored value
-			b = s.properties.Value
+			b, _ = s.PropertiesInterface.Get(s.Interface())["Value"].([]byte)
 		} else {
-			dberr = dbus.NewError(err.Error(), nil)
+			dberr = err.DBusError()
 		}
 	}
 
@@ -151,7 +200,25 @@ func (s *GattCharacteristic1) ReadValue(options map[string]interface{}) ([]byte,
 
 //WriteValue write a value
 func (s *GattCharacteristic1) WriteValue(value []byte, options map[string]interface{}) *dbus.Error {
-	log.Debug("Characteristic.WriteValue")
+	s.logger().Debugf("Characteristic.WriteValue %s %x %v", bluetooth.DescribeUUID(s.properties.UUID), value, options)
+
+	if secErr := s.config.service.GetApp().CheckSecurity(options); secErr != nil {
+		return secErr.DBusError()
+	}
+
+	if err := s.validate(value, options); err != nil {
+		return err.DBusError()
+	}
+
+	if s.writeFunc != nil {
+		err := bluez.SafeCall(s.logger(), "WriteFunc", func() error {
+			return s.writeFunc(s, value, options)
+		})
+		if err != nil {
+			return callbackError(err).DBusError()
+		}
+		return nil
+	}
 
 	err := s.config.service.config.app.HandleWrite(s.config.service.properties.UUID, s.properties.UUID, value)
 
@@ -161,29 +228,52 @@ func (s *GattCharacteristic1) WriteValue(value []byte, options map[string]interf
 			s.UpdateValue(value)
 			return nil
 		}
-		dberr := dbus.NewError(err.Error(), nil)
+		dberr := err.DBusError()
 		return dberr
 	}
 
 	return nil
 }
 
+func (s *GattCharacteristic1) logger() bluez.Logger {
+	return s.config.service.GetApp().Logger()
+}
+
 //UpdateValue update a value
 func (s *GattCharacteristic1) UpdateValue(value []byte) {
-	s.properties.Value = value
+	s.logger().Debugf("Characteristic %s Value changed %x", bluetooth.DescribeUUID(s.properties.UUID), value)
+	// stored in the properties by the Set callback, under the interface lock
 	s.PropertiesInterface.Instance().Set(s.Interface(), "Value", dbus.MakeVariant(value))
+	if s.notifying {
+		bluez.GetMetrics().Observe(bluez.MetricNotify, 0, nil)
+	}
 }
 
 //StartNotify start notification
 func (s *GattCharacteristic1) StartNotify() *dbus.Error {
-	log.Debug("Characteristic.StartNotify")
+	s.logger().Debugf("Characteristic.StartNotify %s", bluetooth.DescribeUUID(s.properties.UUID))
 	s.notifying = true
 	return nil
 }
 
+//Notifying return true if a remote device subscribed to the notifications
+// or indications
+func (s *GattCharacteristic1) Notifying() bool {
+	return s.notifying
+}
+
+//Confirm an indication was confirmed by the remote device
+func (s *GattCharacteristic1) Confirm() *dbus.Error {
+	s.logger().Debugf("Characteristic.Confirm %s", bluetooth.DescribeUUID(s.properties.UUID))
+	if s.confirmFunc != nil {
+		s.confirmFunc(s)
+	}
+	return nil
+}
+
 //StopNotify stop notification
 func (s *GattCharacteristic1) StopNotify() *dbus.Error {
-	log.Debug("Characteristic.StopNotify")
+	s.logger().Debugf("Characteristic.StopNotify %s", bluetooth.DescribeUUID(s.properties.UUID))
 	s.notifying = false
 	return nil
 }
@@ -202,27 +292,21 @@ func (s *GattCharacteristic1) Expose() error {
 		s.PropertiesInterface.AddProperties(iface, props)
 	}
 
+	s.PropertiesInterface.Coalesce(s.config.service.GetApp().config.NotifyWindow)
 	s.PropertiesInterface.Expose(s.Path())
 
-	node := &introspect.Node{
-		Interfaces: []introspect.Interface{
-			//Introspect
-			introspect.IntrospectData,
-			//Properties
-			prop.IntrospectData,
-			//GattCharacteristic1
-			{
-				Name:       s.Interface(),
-				Methods:    introspect.Methods(s),
-				Properties: s.PropertiesInterface.Introspection(s.Interface()),
-			},
+	err = s.config.service.GetApp().tree.add(s.Path(), []introspect.Interface{
+		//Introspect
+		introspect.IntrospectData,
+		//Properties
+		prop.IntrospectData,
+		//GattCharacteristic1
+		{
+			Name:       s.Interface(),
+			Methods:    introspect.Methods(s),
+			Properties: s.PropertiesInterface.Introspection(s.Interface()),
 		},
-	}
-
-	err = conn.Export(
-		introspect.NewIntrospectable(node),
-		s.Path(),
-		"org.freedesktop.DBus.Introspectable")
+	})
 	if err != nil {
 		return err
 	}
@@ -4,6 +4,7 @@ import (
 	"github.com/godbus/dbus"
 	"github.com/godbus/dbus/introspect"
 	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth"
 	"github.com/muka/go-bluetooth/bluez"
 	"github.com/muka/go-bluetooth/bluez/profile"
 )
@@ -43,6 +44,32 @@ type GattDescriptor1 struct {
 	config              *GattDescriptor1Config
 	properties          *profile.GattDescriptor1Properties
 	PropertiesInterface *Properties
+
+	readFunc  DescriptorReadFunc
+	writeFunc DescriptorWriteFunc
+}
+
+//DescriptorReadFunc handle the reads of a descriptor, see SetReadFunc
+type DescriptorReadFunc func(d *GattDescriptor1, options map[string]interface{}) ([]byte, error)
+
+//DescriptorWriteFunc handle the writes of a descriptor, see SetWriteFunc
+type DescriptorWriteFunc func(d *GattDescriptor1, value []byte, options map[string]interface{}) error
+
+//SetReadFunc handle the reads of the descriptor with fn instead of the
+// DescReadFunc of the application
+func (s *GattDescriptor1) SetReadFunc(fn DescriptorReadFunc) {
+	s.readFunc = fn
+}
+
+//SetWriteFunc handle the writes of the descriptor with fn instead of the
+// DescWriteFunc of the application
+func (s *GattDescriptor1) SetWriteFunc(fn DescriptorWriteFunc) {
+	s.writeFunc = fn
+}
+
+//UUID return the UUID of the descriptor
+func (s *GattDescriptor1) UUID() string {
+	return s.properties.UUID
 }
 
 //Path return the object path
@@ -65,6 +92,23 @@ func (s *GattDescriptor1) Properties() map[string]bluez.Properties {
 
 //ReadValue read a value
 func (s *GattDescriptor1) ReadValue(options map[string]interface{}) ([]byte, *dbus.Error) {
+	s.logger().Debugf("Descriptor.ReadValue %s %v", bluetooth.DescribeUUID(s.properties.UUID), options)
+	if secErr := s.config.characteristic.config.service.GetApp().CheckSecurity(options); secErr != nil {
+		return nil, secErr.DBusError()
+	}
+
+	if s.readFunc != nil {
+		var b []byte
+		err := bluez.SafeCall(s.logger(), "DescReadFunc", func() (err error) {
+			b, err = s.readFunc(s, options)
+			return err
+		})
+		if err != nil {
+			return nil, callbackError(err).DBusError()
+		}
+		return b, nil
+	}
+
 	b, err := s.config.characteristic.config.service.config.app.HandleDescriptorRead(
 		s.config.characteristic.config.service.properties.UUID, s.config.characteristic.properties.UUID,
 		s.properties.UUID)
@@ -73,9 +117,9 @@ func (s *GattDescriptor1) ReadValue(options map[string]interface{}) ([]byte, *db
 	if err != nil {
 		if err.code == -1 {
 			// No registered callback, so we'll just use our stored value
-			b = s.properties.Value
+			b, _ = s.PropertiesInterface.Get(s.Interface())["Value"].([]byte)
 		} else {
-			dberr = dbus.NewError(err.Error(), nil)
+			dberr = err.DBusError()
 		}
 	}
 
@@ -84,6 +128,21 @@ func (s *GattDescriptor1) ReadValue(options map[string]interface{}) ([]byte, *db
 
 //WriteValue write a value
 func (s *GattDescriptor1) WriteValue(value []byte, options map[string]interface{}) *dbus.Error {
+	s.logger().Debugf("Descriptor.WriteValue %s %x %v", bluetooth.DescribeUUID(s.properties.UUID), value, options)
+	if secErr := s.config.characteristic.config.service.GetApp().CheckSecurity(options); secErr != nil {
+		return secErr.DBusError()
+	}
+
+	if s.writeFunc != nil {
+		err := bluez.SafeCall(s.logger(), "DescWriteFunc", func() error {
+			return s.writeFunc(s, value, options)
+		})
+		if err != nil {
+			return callbackError(err).DBusError()
+		}
+		return nil
+	}
+
 	err := s.config.characteristic.config.service.config.app.HandleDescriptorWrite(
 		s.config.characteristic.config.service.properties.UUID, s.config.characteristic.properties.UUID,
 		s.properties.UUID, value)
@@ -94,16 +153,21 @@ func (s *GattDescriptor1) WriteValue(value []byte, options map[string]interface{
 			s.UpdateValue(value)
 			return nil
 		}
-		dberr := dbus.NewError(err.Error(), nil)
+		dberr := err.DBusError()
 		return dberr
 	}
 
 	return nil
 }
 
+func (s *GattDescriptor1) logger() bluez.Logger {
+	return s.config.characteristic.config.service.GetApp().Logger()
+}
+
 //UpdateValue update a descriptor value
 func (s *GattDescriptor1) UpdateValue(value []byte) error {
-	s.properties.Value = value
+	s.logger().Debugf("Descriptor %s Value changed %x", bluetooth.DescribeUUID(s.properties.UUID), value)
+	// stored in the properties by the Set callback, under the interface lock
 	err := s.PropertiesInterface.Instance().Set(s.Interface(), "Value", dbus.MakeVariant(value))
 	if err != nil {
 		return err
@@ -125,27 +189,21 @@ func (s *GattDescriptor1) Expose() error {
 		s.PropertiesInterface.AddProperties(iface, props)
 	}
 
+	s.PropertiesInterface.Coalesce(s.config.characteristic.config.service.GetApp().config.NotifyWindow)
 	s.PropertiesInterface.Expose(s.Path())
 
-	node := &introspect.Node{
-		Interfaces: []introspect.Interface{
-			//Introspect
-			introspect.IntrospectData,
-			//Properties
-			prop.IntrospectData,
-			//GattCharacteristic1
-			{
-				Name:       s.Interface(),
-				Methods:    introspect.Methods(s),
-				Properties: s.PropertiesInterface.Introspection(s.Interface()),
-			},
+	err = s.config.characteristic.config.service.GetApp().tree.add(s.Path(), []introspect.Interface{
+		//Introspect
+		introspect.IntrospectData,
+		//Properties
+		prop.IntrospectData,
+		//GattCharacteristic1
+		{
+			Name:       s.Interface(),
+			Methods:    introspect.Methods(s),
+			Properties: s.PropertiesInterface.Introspection(s.Interface()),
 		},
-	}
-
-	err = conn.Export(
-		introspect.NewIntrospectable(node),
-		s.Path(),
-		"org.freedesktop.DBus.Introspectable")
+	})
 	if err != nil {
 		return err
 	}
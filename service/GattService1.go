@@ -51,6 +51,11 @@ type GattService1 struct {
 	PropertiesInterface *Properties
 }
 
+//UUID return the UUID of the service
+func (s *GattService1) UUID() string {
+	return s.properties.UUID
+}
+
 //Interface return the dbus interface name
 func (s *GattService1) Interface() string {
 	return bluez.GattService1Interface
@@ -105,6 +110,7 @@ func (s *GattService1) CreateCharacteristic(props *profile.GattCharacteristic1Pr
 	}
 
 	props.Service = s.Path()
+	props.Flags = s.GetApp().config.Security.ApplyFlags(props.Flags)
 
 	char, err := NewGattCharacteristic1(config, props)
 	return char, err
@@ -120,21 +126,18 @@ func (s *GattService1) AddCharacteristic(char *GattCharacteristic1) error {
 		return err
 	}
 
-	err = s.GetApp().exportTree()
-	if err != nil {
-		return err
-	}
-
-	om := s.config.app.GetObjectManager()
-	return om.AddObject(char.Path(), char.Properties())
+	return s.GetApp().addObject(char.Path(), char.Properties())
 }
 
 //RemoveCharacteristic remove a characteristic
 func (s *GattService1) RemoveCharacteristic(char *GattCharacteristic1) error {
 	if _, ok := s.characteristics[char.Path()]; ok {
 		delete(s.characteristics, char.Path())
-		om := s.config.app.GetObjectManager()
-		return om.RemoveObject(char.Path())
+		err := s.GetApp().tree.remove(char.Path())
+		if err != nil {
+			return err
+		}
+		return s.GetApp().removeObject(char.Path())
 	}
 	return nil
 }
@@ -155,25 +158,18 @@ func (s *GattService1) Expose() error {
 
 	s.PropertiesInterface.Expose(s.Path())
 
-	node := &introspect.Node{
-		Interfaces: []introspect.Interface{
-			//Introspect
-			introspect.IntrospectData,
-			//Properties
-			prop.IntrospectData,
-			//GattService1
-			{
-				Name:       s.Interface(),
-				Methods:    introspect.Methods(s),
-				Properties: s.PropertiesInterface.Introspection(s.Interface()),
-			},
+	err = s.GetApp().tree.add(s.Path(), []introspect.Interface{
+		//Introspect
+		introspect.IntrospectData,
+		//Properties
+		prop.IntrospectData,
+		//GattService1
+		{
+			Name:       s.Interface(),
+			Methods:    introspect.Methods(s),
+			Properties: s.PropertiesInterface.Introspection(s.Interface()),
 		},
-	}
-
-	err = conn.Export(
-		introspect.NewIntrospectable(node),
-		s.Path(),
-		"org.freedesktop.DBus.Introspectable")
+	})
 	if err != nil {
 		return err
 	}
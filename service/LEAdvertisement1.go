@@ -41,6 +41,8 @@ type LEAdvertisement1 struct {
 	config              *LEAdvertisement1Config
 	properties          *profile.LEAdvertisement1Properties
 	PropertiesInterface *Properties
+	// onRelease called when bluez releases the advertisement
+	onRelease func()
 }
 
 //Interface return the dbus interface name
@@ -65,8 +67,11 @@ func (s *LEAdvertisement1) Properties() map[string]bluez.Properties {
 // cleanup tasks. There is no need to call
 // UnregisterAdvertisement because when this method gets
 // called it has already been unregistered.
-func (s *LEAdvertisement1) Release() {
-	//callback here ?
+func (s *LEAdvertisement1) Release() *dbus.Error {
+	if s.onRelease != nil {
+		go s.onRelease()
+	}
+	return nil
 }
 
 //Expose the char to dbus
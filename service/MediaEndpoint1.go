@@ -0,0 +1,260 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/bluez/profile/a2dp"
+)
+
+//MediaConfiguration a configuration set by bluez on a MediaEndpoint1
+type MediaConfiguration struct {
+	Transport     dbus.ObjectPath
+	Device        dbus.ObjectPath
+	UUID          string
+	Codec         byte
+	Configuration []byte
+	Properties    map[string]dbus.Variant
+}
+
+//MediaTransport return a client for the configured transport
+func (c *MediaConfiguration) MediaTransport() *profile.MediaTransport1 {
+	return profile.NewMediaTransport1(string(c.Transport))
+}
+
+//MediaConfigurationCallback called when a transport is configured
+type MediaConfigurationCallback func(c *MediaConfiguration)
+
+//MediaEndpoint1Config MediaEndpoint1 configuration
+type MediaEndpoint1Config struct {
+	// ObjectPath where the endpoint is exported
+	ObjectPath dbus.ObjectPath
+	// UUID the role of the endpoint, eg. a2dp.SinkUUID
+	UUID string
+	// Codec the codec id, eg. a2dp.CodecSBC
+	Codec byte
+	// Capabilities the codec specific capabilities
+	Capabilities []byte
+	// SelectConfiguration choose a configuration among the remote
+	// capabilities. When empty and Codec is SBC, a2dp.SelectSBCConfiguration is used
+	SelectConfiguration func(capabilities []byte) ([]byte, error)
+
+	OnSetConfiguration   MediaConfigurationCallback
+	OnClearConfiguration func(transport dbus.ObjectPath)
+	OnRelease            func()
+
+	// Conn the connection the endpoint is exported on
+	Conn *dbus.Conn
+	// BusAddress the bus to connect to when Conn is not set, defaults to the
+	// system bus
+	BusAddress string
+}
+
+// NewMediaEndpoint1 create a new MediaEndpoint1, call Register to expose it to bluez
+func NewMediaEndpoint1(config *MediaEndpoint1Config) (*MediaEndpoint1, error) {
+
+	if config.ObjectPath == "" {
+		return nil, errors.New("objectPath is required")
+	}
+	if config.UUID == "" {
+		return nil, errors.New("UUID is required")
+	}
+
+	if config.SelectConfiguration == nil && config.Codec == a2dp.CodecSBC {
+		local, err := a2dp.ParseSBCCapabilities(config.Capabilities)
+		if err != nil {
+			return nil, err
+		}
+		config.SelectConfiguration = func(capabilities []byte) ([]byte, error) {
+			remote, err := a2dp.ParseSBCCapabilities(capabilities)
+			if err != nil {
+				return nil, err
+			}
+			selected, err := a2dp.SelectSBCConfiguration(local, remote)
+			if err != nil {
+				return nil, err
+			}
+			return selected.Bytes(), nil
+		}
+	}
+
+	if config.Conn == nil {
+		conn, err := bluez.ResolveConnection(config.BusAddress)
+		if err != nil {
+			return nil, err
+		}
+		config.Conn = conn
+	}
+
+	e := &MediaEndpoint1{
+		config: config,
+	}
+
+	return e, nil
+}
+
+//NewA2DPSinkEndpoint create an SBC sink endpoint, to receive audio from
+// phones and other sources
+func NewA2DPSinkEndpoint(path dbus.ObjectPath, onSetConfiguration MediaConfigurationCallback) (*MediaEndpoint1, error) {
+	return NewMediaEndpoint1(&MediaEndpoint1Config{
+		ObjectPath:         path,
+		UUID:               a2dp.SinkUUID,
+		Codec:              a2dp.CodecSBC,
+		Capabilities:       a2dp.DefaultSBCCapabilities().Bytes(),
+		OnSetConfiguration: onSetConfiguration,
+	})
+}
+
+//NewA2DPSourceEndpoint create an SBC source endpoint, to stream audio to
+// speakers and headsets. Once configured, acquire the transport with a2dp.Acquire
+func NewA2DPSourceEndpoint(path dbus.ObjectPath, onSetConfiguration MediaConfigurationCallback) (*MediaEndpoint1, error) {
+	return NewMediaEndpoint1(&MediaEndpoint1Config{
+		ObjectPath:         path,
+		UUID:               a2dp.SourceUUID,
+		Codec:              a2dp.CodecSBC,
+		Capabilities:       a2dp.DefaultSBCCapabilities().Bytes(),
+		OnSetConfiguration: onSetConfiguration,
+	})
+}
+
+//MediaEndpoint1 an exported org.bluez.MediaEndpoint1
+type MediaEndpoint1 struct {
+	config *MediaEndpoint1Config
+	media  *profile.Media1
+}
+
+//Interface return the dbus interface name
+func (e *MediaEndpoint1) Interface() string {
+	return bluez.MediaEndpoint1Interface
+}
+
+//Path return the object path
+func (e *MediaEndpoint1) Path() dbus.ObjectPath {
+	return e.config.ObjectPath
+}
+
+//Expose the endpoint to dbus
+func (e *MediaEndpoint1) Expose() error {
+
+	conn := e.config.Conn
+
+	err := conn.Export(e, e.Path(), e.Interface())
+	if err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			//Introspect
+			introspect.IntrospectData,
+			//MediaEndpoint1
+			{
+				Name:    e.Interface(),
+				Methods: introspect.Methods(e),
+			},
+		},
+	}
+
+	return conn.Export(
+		introspect.NewIntrospectable(node),
+		e.Path(),
+		"org.freedesktop.DBus.Introspectable")
+}
+
+//Register expose the endpoint and register it with the Media1 of an adapter
+func (e *MediaEndpoint1) Register(adapterID string) error {
+
+	err := e.Expose()
+	if err != nil {
+		return err
+	}
+
+	e.media = profile.NewMedia1(adapterID)
+	e.media.SetConnection(e.config.Conn)
+	return e.media.RegisterEndpoint(e.Path(), map[string]interface{}{
+		"UUID":         e.config.UUID,
+		"Codec":        e.config.Codec,
+		"Capabilities": e.config.Capabilities,
+	})
+}
+
+//Unregister remove the endpoint from bluez
+func (e *MediaEndpoint1) Unregister() error {
+
+	var err error
+	if e.media != nil {
+		err = e.media.UnregisterEndpoint(e.Path())
+		e.media = nil
+	}
+
+	e.config.Conn.Export(nil, e.Path(), e.Interface())
+	e.config.Conn.Export(nil, e.Path(), "org.freedesktop.DBus.Introspectable")
+
+	return err
+}
+
+//SetConfiguration set configuration for the transport
+func (e *MediaEndpoint1) SetConfiguration(transport dbus.ObjectPath, properties map[string]dbus.Variant) *dbus.Error {
+	bluez.GetLogger().Debugf("MediaEndpoint.SetConfiguration %s", transport)
+
+	c := &MediaConfiguration{
+		Transport:  transport,
+		Properties: properties,
+	}
+	if v, ok := properties["Device"]; ok {
+		c.Device, _ = v.Value().(dbus.ObjectPath)
+	}
+	if v, ok := properties["UUID"]; ok {
+		c.UUID, _ = v.Value().(string)
+	}
+	if v, ok := properties["Codec"]; ok {
+		c.Codec, _ = v.Value().(byte)
+	}
+	if v, ok := properties["Configuration"]; ok {
+		c.Configuration, _ = v.Value().([]byte)
+	}
+
+	if e.config.OnSetConfiguration != nil {
+		go e.config.OnSetConfiguration(c)
+	}
+	return nil
+}
+
+//SelectConfiguration select preferable configuration from the supported capabilities
+func (e *MediaEndpoint1) SelectConfiguration(capabilities []byte) ([]byte, *dbus.Error) {
+	bluez.GetLogger().Debugf("MediaEndpoint.SelectConfiguration %x", capabilities)
+
+	if e.config.SelectConfiguration == nil {
+		return nil, bluez.ErrNotSupported.DBusError()
+	}
+
+	config, err := e.config.SelectConfiguration(capabilities)
+	if err != nil {
+		bluez.GetLogger().Warnf("MediaEndpoint: %s", err.Error())
+		return nil, bluez.ErrInvalidArguments.WithMessage(err.Error()).DBusError()
+	}
+
+	return config, nil
+}
+
+//ClearConfiguration clear transport configuration
+func (e *MediaEndpoint1) ClearConfiguration(transport dbus.ObjectPath) *dbus.Error {
+	bluez.GetLogger().Debugf("MediaEndpoint.ClearConfiguration %s", transport)
+	if e.config.OnClearConfiguration != nil {
+		e.config.OnClearConfiguration(transport)
+	}
+	return nil
+}
+
+//Release called when bluez unregisters the endpoint
+func (e *MediaEndpoint1) Release() *dbus.Error {
+	bluez.GetLogger().Debugf("MediaEndpoint.Release")
+	e.media = nil
+	if e.config.OnRelease != nil {
+		e.config.OnRelease()
+	}
+	return nil
+}
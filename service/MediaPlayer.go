@@ -0,0 +1,337 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/godbus/dbus/prop"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+// Commands received from AVRCP controllers
+const (
+	MediaPlayerCommandPlay        = "Play"
+	MediaPlayerCommandPause       = "Pause"
+	MediaPlayerCommandPlayPause   = "PlayPause"
+	MediaPlayerCommandStop        = "Stop"
+	MediaPlayerCommandNext        = "Next"
+	MediaPlayerCommandPrevious    = "Previous"
+	MediaPlayerCommandSeek        = "Seek"
+	MediaPlayerCommandSetPosition = "SetPosition"
+)
+
+//MediaPlayerSeekThreshold the distance from the expected playback position
+// above which UpdatePosition signals a seek to the controllers
+const MediaPlayerSeekThreshold = time.Second
+
+// go method names exported under a different dbus name
+var mediaPlayerMethods = map[string]string{
+	"SeekOffset": "Seek",
+}
+
+//MediaPlayerCommand a playback command sent by a remote controller, eg. a car kit
+type MediaPlayerCommand struct {
+	Name string
+	// Offset relative move for Seek
+	Offset time.Duration
+	// Position absolute position for SetPosition
+	Position time.Duration
+}
+
+//MediaPlayerConfig MediaPlayer configuration
+type MediaPlayerConfig struct {
+	// ObjectPath where the player is exported
+	ObjectPath dbus.ObjectPath
+	// OnCommand called for every command received, the player state is not
+	// changed automatically, use UpdateStatus, UpdateTrack and UpdatePosition
+	OnCommand func(cmd MediaPlayerCommand)
+
+	// Conn the connection the player is exported on, defaults to the system
+	// bus or to BusAddress
+	Conn *dbus.Conn
+	// BusAddress the bus to connect to when Conn is not set, defaults to the
+	// system bus
+	BusAddress string
+}
+
+// NewMediaPlayer create a new player, call Register to make it available to AVRCP controllers
+func NewMediaPlayer(config *MediaPlayerConfig) (*MediaPlayer, error) {
+
+	if config.ObjectPath == "" {
+		return nil, errors.New("objectPath is required")
+	}
+
+	if config.Conn == nil {
+		conn, err := bluez.ResolveConnection(config.BusAddress)
+		if err != nil {
+			return nil, err
+		}
+		config.Conn = conn
+	}
+
+	propInterface, err := NewProperties(config.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	props := &profile.MprisPlayerProperties{
+		PlaybackStatus: profile.MprisPlaybackStopped,
+		LoopStatus:     profile.MprisLoopNone,
+		Rate:           1,
+		Metadata:       profile.MprisTrack{}.ToMetadata(),
+		Volume:         1,
+		MinimumRate:    1,
+		MaximumRate:    1,
+		CanGoNext:      true,
+		CanGoPrevious:  true,
+		CanPlay:        true,
+		CanPause:       true,
+		CanSeek:        false,
+		CanControl:     true,
+	}
+
+	p := &MediaPlayer{
+		config:              config,
+		properties:          props,
+		PropertiesInterface: propInterface,
+		status:              props.PlaybackStatus,
+	}
+
+	err = propInterface.AddProperties(p.Interface(), props)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+//MediaPlayer a local player exported as org.mpris.MediaPlayer2.Player, so that
+// AVRCP controllers can display and control the playback
+type MediaPlayer struct {
+	config              *MediaPlayerConfig
+	properties          *profile.MprisPlayerProperties
+	PropertiesInterface *Properties
+	media               *profile.Media1
+
+	// the last position set and when, to tell a seek from the playback progress
+	mutex      sync.Mutex
+	status     string
+	position   time.Duration
+	positionAt time.Time
+}
+
+//Interface return the dbus interface name
+func (p *MediaPlayer) Interface() string {
+	return bluez.MprisPlayerInterface
+}
+
+//Path return the object path
+func (p *MediaPlayer) Path() dbus.ObjectPath {
+	return p.config.ObjectPath
+}
+
+//Properties return the player properties
+func (p *MediaPlayer) Properties() *profile.MprisPlayerProperties {
+	return p.properties
+}
+
+//Expose the player to dbus
+func (p *MediaPlayer) Expose() error {
+
+	conn := p.config.Conn
+
+	// Seek would clash with the io.Seeker signature
+	err := conn.ExportWithMap(p, mediaPlayerMethods, p.Path(), p.Interface())
+	if err != nil {
+		return err
+	}
+
+	p.PropertiesInterface.Expose(p.Path())
+
+	methods := introspect.Methods(p)
+	for i := range methods {
+		if name, ok := mediaPlayerMethods[methods[i].Name]; ok {
+			methods[i].Name = name
+		}
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			//Introspect
+			introspect.IntrospectData,
+			//Properties
+			prop.IntrospectData,
+			//Player
+			{
+				Name:       p.Interface(),
+				Methods:    methods,
+				Properties: p.PropertiesInterface.Introspection(p.Interface()),
+				Signals: []introspect.Signal{
+					{
+						Name: "Seeked",
+						Args: []introspect.Arg{{Name: "Position", Type: "x"}},
+					},
+				},
+			},
+		},
+	}
+
+	return conn.Export(
+		introspect.NewIntrospectable(node),
+		p.Path(),
+		"org.freedesktop.DBus.Introspectable")
+}
+
+//Register expose the player and register it with the Media1 of an adapter
+func (p *MediaPlayer) Register(adapterID string) error {
+
+	err := p.Expose()
+	if err != nil {
+		return err
+	}
+
+	props, err := p.properties.ToMap()
+	if err != nil {
+		return err
+	}
+
+	p.media = profile.NewMedia1(adapterID)
+	p.media.SetConnection(p.config.Conn)
+	return p.media.RegisterPlayer(p.Path(), props)
+}
+
+//Unregister remove the player from bluez
+func (p *MediaPlayer) Unregister() error {
+
+	var err error
+	if p.media != nil {
+		err = p.media.UnregisterPlayer(p.Path())
+		p.media = nil
+	}
+
+	p.config.Conn.Export(nil, p.Path(), p.Interface())
+	p.config.Conn.Export(nil, p.Path(), bluez.PropertiesInterface)
+	p.config.Conn.Export(nil, p.Path(), "org.freedesktop.DBus.Introspectable")
+
+	return err
+}
+
+func (p *MediaPlayer) set(name string, value interface{}) error {
+	dbusErr := p.PropertiesInterface.Instance().Set(p.Interface(), name, dbus.MakeVariant(value))
+	if dbusErr != nil {
+		return dbusErr
+	}
+	return nil
+}
+
+//UpdateStatus update the playback status, one of profile.MprisPlayback*
+func (p *MediaPlayer) UpdateStatus(status string) error {
+	err := p.set("PlaybackStatus", status)
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := time.Now()
+	p.position, p.positionAt = p.expectedPosition(now), now
+	p.status = status
+	return nil
+}
+
+//UpdateTrack update the metadata of the track being played
+func (p *MediaPlayer) UpdateTrack(track profile.MprisTrack) error {
+	return p.set("Metadata", track.ToMetadata())
+}
+
+//UpdatePosition update the playback position. Controllers are signalled a
+// seek only when the position moves away from the playback progress by more
+// than MediaPlayerSeekThreshold, eg. after a Seek or SetPosition command
+func (p *MediaPlayer) UpdatePosition(position time.Duration) error {
+	us := int64(position / time.Microsecond)
+	p.PropertiesInterface.Instance().SetMust(p.Interface(), "Position", us)
+	p.properties.Position = us
+
+	p.mutex.Lock()
+	now := time.Now()
+	drift := position - p.expectedPosition(now)
+	p.position, p.positionAt = position, now
+	p.mutex.Unlock()
+
+	if drift <= MediaPlayerSeekThreshold && drift >= -MediaPlayerSeekThreshold {
+		return nil
+	}
+	return p.config.Conn.Emit(p.Path(), p.Interface()+".Seeked", us)
+}
+
+// expectedPosition the position reached at now from the last one set, the
+// rate is fixed to 1
+func (p *MediaPlayer) expectedPosition(now time.Time) time.Duration {
+	if p.status != profile.MprisPlaybackPlaying || p.positionAt.IsZero() {
+		return p.position
+	}
+	return p.position + now.Sub(p.positionAt)
+}
+
+//UpdateVolume update the volume, in the range 0-1
+func (p *MediaPlayer) UpdateVolume(volume float64) error {
+	return p.set("Volume", volume)
+}
+
+func (p *MediaPlayer) command(cmd MediaPlayerCommand) *dbus.Error {
+	bluez.GetLogger().Debugf("MediaPlayer.%s", cmd.Name)
+	if p.config.OnCommand != nil {
+		p.config.OnCommand(cmd)
+	}
+	return nil
+}
+
+//Play start or resume playback
+func (p *MediaPlayer) Play() *dbus.Error {
+	return p.command(MediaPlayerCommand{Name: MediaPlayerCommandPlay})
+}
+
+//Pause playback
+func (p *MediaPlayer) Pause() *dbus.Error {
+	return p.command(MediaPlayerCommand{Name: MediaPlayerCommandPause})
+}
+
+//PlayPause toggle playback
+func (p *MediaPlayer) PlayPause() *dbus.Error {
+	return p.command(MediaPlayerCommand{Name: MediaPlayerCommandPlayPause})
+}
+
+//Stop playback
+func (p *MediaPlayer) Stop() *dbus.Error {
+	return p.command(MediaPlayerCommand{Name: MediaPlayerCommandStop})
+}
+
+//Next skip to the next track
+func (p *MediaPlayer) Next() *dbus.Error {
+	return p.command(MediaPlayerCommand{Name: MediaPlayerCommandNext})
+}
+
+//Previous skip to the previous track
+func (p *MediaPlayer) Previous() *dbus.Error {
+	return p.command(MediaPlayerCommand{Name: MediaPlayerCommandPrevious})
+}
+
+//SeekOffset move the position by offset microseconds, exported as Seek
+func (p *MediaPlayer) SeekOffset(offset int64) *dbus.Error {
+	return p.command(MediaPlayerCommand{
+		Name:   MediaPlayerCommandSeek,
+		Offset: time.Duration(offset) * time.Microsecond,
+	})
+}
+
+//SetPosition move to an absolute position in microseconds
+func (p *MediaPlayer) SetPosition(trackID dbus.ObjectPath, position int64) *dbus.Error {
+	return p.command(MediaPlayerCommand{
+		Name:     MediaPlayerCommandSetPosition,
+		Position: time.Duration(position) * time.Microsecond,
+	})
+}
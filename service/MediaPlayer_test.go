@@ -0,0 +1,175 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestMediaPlayerSeeked(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	p, err := service.NewMediaPlayer(&service.MediaPlayerConfig{
+		ObjectPath: "/bluetest/player",
+		Conn:       b.ClientConn(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Expose(); err != nil {
+		t.Fatal(err)
+	}
+
+	// listen on the fake bluez connection, as a controller would
+	rule := "type='signal',interface='" + bluez.MprisPlayerInterface + "',member='Seeked'"
+	err = b.Conn().BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule).Store()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 10)
+	b.Conn().Signal(signals)
+	defer b.Conn().RemoveSignal(signals)
+
+	steps := []struct {
+		status   string
+		sleep    time.Duration
+		position time.Duration
+	}{
+		{profile.MprisPlaybackPlaying, 0, 0},
+		// playback progress
+		{"", 200 * time.Millisecond, 200 * time.Millisecond},
+		// seek forward
+		{"", 0, 30 * time.Second},
+		{profile.MprisPlaybackPaused, 200 * time.Millisecond, 30*time.Second + 200*time.Millisecond},
+		// paused, the position does not move
+		{"", 200 * time.Millisecond, 30*time.Second + 200*time.Millisecond},
+		// seek back
+		{"", 0, 10 * time.Second},
+	}
+	for _, step := range steps {
+		time.Sleep(step.sleep)
+		if step.status != "" {
+			if err = p.UpdateStatus(step.status); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = p.UpdatePosition(step.position); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	received := map[int64]bool{}
+	timeout := time.After(500 * time.Millisecond)
+	for done := false; !done; {
+		select {
+		case sig := <-signals:
+			if sig.Name == bluez.MprisPlayerInterface+".Seeked" {
+				received[sig.Body[0].(int64)] = true
+			}
+		case <-timeout:
+			done = true
+		}
+	}
+
+	if len(received) != 2 || !received[30000000] || !received[10000000] {
+		t.Fatalf("Expected the seeks to 30s and 10s, got %v", received)
+	}
+}
+
+func TestMediaPlayerCommands(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	commands := make(chan service.MediaPlayerCommand, 10)
+	p, err := service.NewMediaPlayer(&service.MediaPlayerConfig{
+		ObjectPath: "/bluetest/player",
+		OnCommand: func(cmd service.MediaPlayerCommand) {
+			commands <- cmd
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Register("hci0"); err != nil {
+		t.Fatal(err)
+	}
+
+	players := b.Adapter("hci0").Players()
+	if len(players) != 1 || players[0].Path != "/bluetest/player" {
+		t.Fatalf("Expected the player registered, got %v", players)
+	}
+	player := players[0]
+	if status, ok := player.Properties["PlaybackStatus"]; !ok || status.Value() != profile.MprisPlaybackStopped {
+		t.Fatalf("Unexpected registration properties %v", player.Properties)
+	}
+
+	calls := []struct {
+		method   string
+		args     []interface{}
+		expected service.MediaPlayerCommand
+	}{
+		{"Play", nil, service.MediaPlayerCommand{Name: service.MediaPlayerCommandPlay}},
+		{"PlayPause", nil, service.MediaPlayerCommand{Name: service.MediaPlayerCommandPlayPause}},
+		{"Next", nil, service.MediaPlayerCommand{Name: service.MediaPlayerCommandNext}},
+		// exported as Seek, not as the go method name
+		{"Seek", []interface{}{int64(-5000000)}, service.MediaPlayerCommand{
+			Name: service.MediaPlayerCommandSeek, Offset: -5 * time.Second}},
+		{"SetPosition", []interface{}{dbus.ObjectPath("/org/mpris/MediaPlayer2/Track/current"), int64(90000000)},
+			service.MediaPlayerCommand{Name: service.MediaPlayerCommandSetPosition, Position: 90 * time.Second}},
+	}
+	for _, call := range calls {
+		if err = player.Command(call.method, call.args...); err != nil {
+			t.Fatalf("%s: %s", call.method, err)
+		}
+		select {
+		case cmd := <-commands:
+			if cmd != call.expected {
+				t.Errorf("%s: expected %+v, got %+v", call.method, call.expected, cmd)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: timeout waiting for the command", call.method)
+		}
+	}
+
+	// the state is read by the controllers
+	err = p.UpdateTrack(profile.MprisTrack{Title: "Song", Artist: "Band", TrackNumber: 2, Length: 3 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.UpdateStatus(profile.MprisPlaybackPlaying); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.UpdateVolume(0.5); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := player.Property("Metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metadata.Value().(map[string]dbus.Variant)
+	if m["xesam:title"].Value() != "Song" || m["xesam:trackNumber"].Value() != int32(2) ||
+		m["mpris:length"].Value() != int64(180000000) || m["xesam:artist"].Value().([]string)[0] != "Band" {
+		t.Fatalf("Unexpected metadata %v", m)
+	}
+	if status, _ := player.Property("PlaybackStatus"); status.Value() != profile.MprisPlaybackPlaying {
+		t.Fatalf("Unexpected status %v", status)
+	}
+	if volume, _ := player.Property("Volume"); volume.Value() != 0.5 {
+		t.Fatalf("Unexpected volume %v", volume)
+	}
+
+	if err = p.Unregister(); err != nil {
+		t.Fatal(err)
+	}
+	if players = b.Adapter("hci0").Players(); len(players) != 0 {
+		t.Fatalf("Expected the player unregistered, got %v", players)
+	}
+}
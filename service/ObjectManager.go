@@ -2,8 +2,8 @@ package service
 
 import (
 	"errors"
+	"sort"
 
-	log "github.com/Sirupsen/logrus"
 	"github.com/godbus/dbus"
 	"github.com/muka/go-bluetooth/bluez"
 )
@@ -46,14 +46,27 @@ func (o *ObjectManager) SignalRemoved(path dbus.ObjectPath, ifaces []string) err
 
 // GetManagedObject return an up to date view of a single object state
 func (o *ObjectManager) GetManagedObject(objpath dbus.ObjectPath) (map[string]map[string]dbus.Variant, error) {
-	props, err := o.GetManagedObjects()
-	if err != nil {
-		return nil, err
+	ifs, ok := o.objects[objpath]
+	if !ok {
+		return nil, errors.New("Object not found")
 	}
-	if p, ok := props[objpath]; ok {
-		return p, nil
+	return serializeObject(ifs)
+}
+
+// serializeObject convert the properties of an object by interface
+func serializeObject(ifs map[string]bluez.Properties) (map[string]map[string]dbus.Variant, error) {
+	props := make(map[string]map[string]dbus.Variant)
+	for i, m := range ifs {
+		l, err := m.ToMap()
+		if err != nil {
+			return nil, err
+		}
+		props[i] = make(map[string]dbus.Variant, len(l))
+		for k, v := range l {
+			props[i][k] = dbus.MakeVariant(v)
+		}
 	}
-	return nil, errors.New("Object not found")
+	return props, nil
 }
 
 // GetManagedObjects return an up to date view of the object state
@@ -61,23 +74,12 @@ func (o *ObjectManager) GetManagedObjects() (map[dbus.ObjectPath]map[string]map[
 
 	props := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
 	for path, ifs := range o.objects {
-		if _, ok := props[path]; !ok {
-			props[path] = make(map[string]map[string]dbus.Variant)
-		}
-		for i, m := range ifs {
-			if _, ok := props[path][i]; !ok {
-				props[path][i] = make(map[string]dbus.Variant)
-			}
-			l, err := m.ToMap()
-			if err != nil {
-				log.Errorf("Failed to serialize properties: %s", err.Error())
-				return nil, DbusErr
-			}
-			for k, v := range l {
-				vrt := dbus.MakeVariant(v)
-				props[path][i][k] = vrt
-			}
+		p, err := serializeObject(ifs)
+		if err != nil {
+			bluez.GetLogger().Errorf("Failed to serialize properties: %s", err.Error())
+			return nil, DbusErr
 		}
+		props[path] = p
 	}
 
 	return props, nil
@@ -89,6 +91,24 @@ func (o *ObjectManager) AddObject(path dbus.ObjectPath, val map[string]bluez.Pro
 	return o.SignalAdded(path)
 }
 
+//AddObjects add a set of objects at once, eg. the objects of an application
+// committed in a batch. Objects are signaled parents first
+func (o *ObjectManager) AddObjects(objects map[dbus.ObjectPath]map[string]bluez.Properties) error {
+	paths := make([]dbus.ObjectPath, 0, len(objects))
+	for path, val := range objects {
+		o.objects[path] = val
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
+	for _, path := range paths {
+		err := o.SignalAdded(path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 //RemoveObject remove an object from the list
 func (o *ObjectManager) RemoveObject(path dbus.ObjectPath) error {
 	if s, ok := o.objects[path]; ok {
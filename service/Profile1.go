@@ -0,0 +1,326 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/linux"
+)
+
+//ProfileConnection a connection handed over by bluez to a Profile1
+type ProfileConnection struct {
+	Device     dbus.ObjectPath
+	Conn       net.Conn
+	Properties map[string]dbus.Variant
+}
+
+//ProfileConnectionCallback called when a remote device connects to the profile
+type ProfileConnectionCallback func(c *ProfileConnection)
+
+//ProfileDisconnectionCallback called when bluez requests to close a connection
+type ProfileDisconnectionCallback func(device dbus.ObjectPath)
+
+//Profile1Config Profile1 configuration
+type Profile1Config struct {
+	// ObjectPath where the profile is exported
+	ObjectPath dbus.ObjectPath
+	// UUID of the profile, eg. profile.SerialPortProfileUUID
+	UUID string
+	// Options passed to RegisterProfile
+	Options *profile.ProfileManager1Options
+
+	OnConnection    ProfileConnectionCallback
+	OnDisconnection ProfileDisconnectionCallback
+	OnRelease       func()
+
+	// Conn the connection the profile is exported on
+	Conn *dbus.Conn
+	// BusAddress the bus to connect to when Conn is not set, defaults to the
+	// system bus
+	BusAddress string
+}
+
+// NewProfile1 create a new Profile1, call Register to expose it to bluez
+func NewProfile1(config *Profile1Config) (*Profile1, error) {
+
+	if config.ObjectPath == "" {
+		return nil, errors.New("objectPath is required")
+	}
+	if config.UUID == "" {
+		return nil, errors.New("UUID is required")
+	}
+	if config.Options == nil {
+		config.Options = &profile.ProfileManager1Options{}
+	}
+
+	if config.Conn == nil {
+		conn, err := bluez.ResolveConnection(config.BusAddress)
+		if err != nil {
+			return nil, err
+		}
+		config.Conn = conn
+	}
+
+	p := &Profile1{
+		config:      config,
+		connections: make(map[dbus.ObjectPath]net.Conn),
+	}
+
+	return p, nil
+}
+
+//NewSerialPortProfile1 create a Serial Port Profile server on an RFCOMM channel
+func NewSerialPortProfile1(path dbus.ObjectPath, channel uint16, onConnection ProfileConnectionCallback) (*Profile1, error) {
+	return NewProfile1(&Profile1Config{
+		ObjectPath: path,
+		UUID:       profile.SerialPortProfileUUID,
+		Options: &profile.ProfileManager1Options{
+			Name:    "Serial Port",
+			Role:    profile.ProfileRoleServer,
+			Channel: channel,
+		},
+		OnConnection: onConnection,
+	})
+}
+
+//NewHandsfreeAudioGatewayProfile1 create a Hands-Free Audio Gateway, the phone
+// side of HFP, see hfp.NewGateway to drive the service level connection.
+// features is the SDP SupportedFeatures bitmask, a zero channel lets bluez pick it
+func NewHandsfreeAudioGatewayProfile1(path dbus.ObjectPath, channel uint16, features uint16, onConnection ProfileConnectionCallback) (*Profile1, error) {
+	return NewProfile1(&Profile1Config{
+		ObjectPath: path,
+		UUID:       profile.HandsfreeAudioGatewayProfileUUID,
+		Options: &profile.ProfileManager1Options{
+			Name:     "Hands-Free Voice gateway",
+			Channel:  channel,
+			Features: features,
+		},
+		OnConnection: onConnection,
+	})
+}
+
+//NewHandsfreeProfile1 create a Hands-Free unit, the headset side of HFP, see
+// hfp.NewHandsfree to drive the service level connection
+func NewHandsfreeProfile1(path dbus.ObjectPath, channel uint16, features uint16, onConnection ProfileConnectionCallback) (*Profile1, error) {
+	return NewProfile1(&Profile1Config{
+		ObjectPath: path,
+		UUID:       profile.HandsfreeProfileUUID,
+		Options: &profile.ProfileManager1Options{
+			Name:     "Hands-Free unit",
+			Channel:  channel,
+			Features: features,
+		},
+		OnConnection: onConnection,
+	})
+}
+
+//NewHeadsetAudioGatewayProfile1 create a Headset Audio Gateway (HSP)
+func NewHeadsetAudioGatewayProfile1(path dbus.ObjectPath, channel uint16, onConnection ProfileConnectionCallback) (*Profile1, error) {
+	return NewProfile1(&Profile1Config{
+		ObjectPath: path,
+		UUID:       profile.HeadsetAudioGatewayProfileUUID,
+		Options: &profile.ProfileManager1Options{
+			Name:    "Headset Voice gateway",
+			Channel: channel,
+		},
+		OnConnection: onConnection,
+	})
+}
+
+//NewHeadsetProfile1 create a Headset (HSP)
+func NewHeadsetProfile1(path dbus.ObjectPath, channel uint16, onConnection ProfileConnectionCallback) (*Profile1, error) {
+	return NewProfile1(&Profile1Config{
+		ObjectPath: path,
+		UUID:       profile.HeadsetProfileUUID,
+		Options: &profile.ProfileManager1Options{
+			Name:    "Headset",
+			Channel: channel,
+		},
+		OnConnection: onConnection,
+	})
+}
+
+//Profile1 an exported org.bluez.Profile1
+type Profile1 struct {
+	config      *Profile1Config
+	manager     *profile.ProfileManager1
+	connections map[dbus.ObjectPath]net.Conn
+	mutex       sync.Mutex
+}
+
+//Interface return the dbus interface name
+func (p *Profile1) Interface() string {
+	return bluez.Profile1Interface
+}
+
+//Path return the object path
+func (p *Profile1) Path() dbus.ObjectPath {
+	return p.config.ObjectPath
+}
+
+//Expose the profile to dbus
+func (p *Profile1) Expose() error {
+
+	conn := p.config.Conn
+
+	err := conn.Export(p, p.Path(), p.Interface())
+	if err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			//Introspect
+			introspect.IntrospectData,
+			//Profile1
+			{
+				Name:    p.Interface(),
+				Methods: introspect.Methods(p),
+			},
+		},
+	}
+
+	return conn.Export(
+		introspect.NewIntrospectable(node),
+		p.Path(),
+		"org.freedesktop.DBus.Introspectable")
+}
+
+//Register expose the profile and register it with the ProfileManager1
+func (p *Profile1) Register() error {
+
+	err := p.Expose()
+	if err != nil {
+		return err
+	}
+
+	p.manager = profile.NewProfileManager1("")
+	p.manager.SetConnection(p.config.Conn)
+	return p.manager.RegisterProfile(string(p.Path()), p.config.UUID, p.config.Options.ToMap())
+}
+
+//Unregister remove the profile from bluez and close the open connections
+func (p *Profile1) Unregister() error {
+
+	var err error
+	if p.manager != nil {
+		err = p.manager.UnregisterProfile(string(p.Path()))
+		p.manager = nil
+	}
+
+	p.closeAll()
+
+	p.config.Conn.Export(nil, p.Path(), p.Interface())
+	p.config.Conn.Export(nil, p.Path(), "org.freedesktop.DBus.Introspectable")
+
+	return err
+}
+
+func (p *Profile1) closeAll() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for path, c := range p.connections {
+		c.Close()
+		delete(p.connections, path)
+	}
+}
+
+//Release called when bluez unregisters the profile
+func (p *Profile1) Release() *dbus.Error {
+	bluez.GetLogger().Debugf("Profile.Release")
+	p.manager = nil
+	p.closeAll()
+	if p.config.OnRelease != nil {
+		p.config.OnRelease()
+	}
+	return nil
+}
+
+//NewConnection called when a new service level connection has been established
+func (p *Profile1) NewConnection(device dbus.ObjectPath, fd dbus.UnixFD, properties map[string]dbus.Variant) *dbus.Error {
+	bluez.GetLogger().Debugf("Profile.NewConnection %s", device)
+
+	local, remote := p.getAddresses(device)
+
+	conn, err := linux.NewSocketConn(int(fd), local, remote)
+	if err != nil {
+		bluez.GetLogger().Errorf("Profile: failed to wrap connection fd: %s", err.Error())
+		return bluez.ErrRejected.WithMessage(err.Error()).DBusError()
+	}
+
+	p.mutex.Lock()
+	if prev, ok := p.connections[device]; ok {
+		prev.Close()
+	}
+	p.connections[device] = conn
+	p.mutex.Unlock()
+
+	if p.config.OnConnection == nil {
+		bluez.GetLogger().Warnf("Profile: no connection callback registered, closing %s", device)
+		p.mutex.Lock()
+		delete(p.connections, device)
+		p.mutex.Unlock()
+		conn.Close()
+		return nil
+	}
+
+	go p.config.OnConnection(&ProfileConnection{device, conn, properties})
+	return nil
+}
+
+//RequestDisconnection called when a profile gets disconnected
+func (p *Profile1) RequestDisconnection(device dbus.ObjectPath) *dbus.Error {
+	bluez.GetLogger().Debugf("Profile.RequestDisconnection %s", device)
+
+	p.mutex.Lock()
+	if c, ok := p.connections[device]; ok {
+		c.Close()
+		delete(p.connections, device)
+	}
+	p.mutex.Unlock()
+
+	if p.config.OnDisconnection != nil {
+		p.config.OnDisconnection(device)
+	}
+	return nil
+}
+
+// getAddresses resolve the adapter and device addresses of a connection
+func (p *Profile1) getAddresses(device dbus.ObjectPath) (*linux.BtAddr, *linux.BtAddr) {
+
+	local := &linux.BtAddr{Proto: "rfcomm"}
+	remote := &linux.BtAddr{Proto: "rfcomm", Channel: p.config.Options.Channel}
+	if p.config.Options.PSM != 0 {
+		local.Proto = "l2cap"
+		remote.Proto = "l2cap"
+		remote.Channel = p.config.Options.PSM
+	}
+
+	// bluez calls the profile on the connection it is exported on
+	dev := profile.NewDevice1(string(device))
+	dev.SetConnection(p.config.Conn)
+	devProps, err := dev.GetProperties()
+	if err != nil {
+		bluez.GetLogger().Warnf("Profile: failed to read the device %s: %s", device, err.Error())
+		return local, remote
+	}
+	remote.Address = devProps.Address
+
+	adapterPath := string(devProps.Adapter)
+	if adapterPath != "" {
+		hostID := adapterPath[strings.LastIndex(adapterPath, "/")+1:]
+		adapter := profile.NewAdapter1(hostID)
+		adapter.SetConnection(p.config.Conn)
+		if adapterProps, err := adapter.GetProperties(); err == nil {
+			local.Address = adapterProps.Address
+		}
+	}
+
+	return local, remote
+}
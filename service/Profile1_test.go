@@ -0,0 +1,167 @@
+package service_test
+
+import (
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestProfileConnection(t *testing.T) {
+
+	// the fake bluez is not installed as system bus, the profile has to use
+	// the configured connection
+	daemon, err := bluetest.StartDaemon()
+	if err != nil {
+		t.Skipf("Cannot start the bus: %s", err.Error())
+	}
+	defer daemon.Close()
+
+	b, err := bluetest.New(daemon.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if _, err = b.AddAdapter("hci0", "00:11:22:33:44:55"); err != nil {
+		t.Fatal(err)
+	}
+	d, err := b.AddDevice("hci0", "00:00:00:00:00:01", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := bluez.DialBus(daemon.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connections := make(chan *service.ProfileConnection, 1)
+	p, err := service.NewProfile1(&service.Profile1Config{
+		ObjectPath: "/bluetest/spp",
+		UUID:       profile.SerialPortProfileUUID,
+		Options: &profile.ProfileManager1Options{
+			Channel: 3,
+		},
+		OnConnection: func(c *service.ProfileConnection) {
+			connections <- c
+		},
+		Conn: conn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[1])
+
+	if dbusErr := p.NewConnection(d.Path, dbus.UnixFD(fds[0]), nil); dbusErr != nil {
+		syscall.Close(fds[0])
+		t.Fatal(dbusErr)
+	}
+
+	select {
+	case c := <-connections:
+		defer c.Conn.Close()
+		if c.Device != d.Path {
+			t.Errorf("Expected device %s, got %s", d.Path, c.Device)
+		}
+		if local := c.Conn.LocalAddr(); local.Network() != "rfcomm" || local.String() != "00:11:22:33:44:55/0" {
+			t.Errorf("Unexpected local address %s %s", local.Network(), local)
+		}
+		if remote := c.Conn.RemoteAddr(); remote.String() != "00:00:00:00:00:01/3" {
+			t.Errorf("Unexpected remote address %s", remote)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the connection")
+	}
+
+	if dbusErr := p.RequestDisconnection(d.Path); dbusErr != nil {
+		t.Fatal(dbusErr)
+	}
+}
+
+func TestProfileRegister(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	d, err := b.AddDevice("hci0", "00:00:00:00:00:01", "printer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.OnProfileConnection = func(uuid string, conn net.Conn) {
+		io.Copy(conn, conn)
+		conn.Close()
+	}
+
+	connections := make(chan *service.ProfileConnection, 1)
+	disconnections := make(chan dbus.ObjectPath, 1)
+	p, err := service.NewProfile1(&service.Profile1Config{
+		ObjectPath: "/bluetest/spp",
+		UUID:       profile.SerialPortProfileUUID,
+		OnConnection: func(c *service.ProfileConnection) {
+			connections <- c
+		},
+		OnDisconnection: func(device dbus.ObjectPath) {
+			disconnections <- device
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.Register(); err != nil {
+		t.Fatal(err)
+	}
+	if profiles := b.Profiles(); profiles["/bluetest/spp"] != profile.SerialPortProfileUUID {
+		t.Fatalf("Expected the profile registered, got %v", profiles)
+	}
+
+	// the device connects to the profile
+	if err = profile.NewDevice1(string(d.Path)).ConnectProfile(profile.SerialPortProfileUUID); err != nil {
+		t.Fatal(err)
+	}
+	var c *service.ProfileConnection
+	select {
+	case c = <-connections:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the connection")
+	}
+	if _, err = c.Conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	c.Conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err = io.ReadFull(c.Conn, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("Unexpected echo %q %v", buf, err)
+	}
+
+	// bluez asks to close the connection
+	if dbusErr := p.RequestDisconnection(d.Path); dbusErr != nil {
+		t.Fatal(dbusErr)
+	}
+	if device := <-disconnections; device != d.Path {
+		t.Fatalf("Unexpected disconnection of %s", device)
+	}
+	if _, err = c.Conn.Write([]byte("x")); err == nil {
+		t.Fatal("Expected the connection closed")
+	}
+
+	if err = p.Unregister(); err != nil {
+		t.Fatal(err)
+	}
+	if profiles := b.Profiles(); len(profiles) != 0 {
+		t.Fatalf("Expected the profile unregistered, got %v", profiles)
+	}
+}
@@ -3,34 +3,60 @@ package service
 import (
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	log "github.com/Sirupsen/logrus"
-	"github.com/fatih/structs"
 	"github.com/godbus/dbus"
 	"github.com/godbus/dbus/introspect"
 	"github.com/godbus/dbus/prop"
 	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/util"
 )
 
 // NewProperties create a new instance
 func NewProperties(conn *dbus.Conn) (*Properties, error) {
-
 	o := &Properties{
-		conn:        conn,
-		props:       make(map[string]bluez.Properties),
-		propsConfig: make(map[string]map[string]*prop.Prop),
+		conn: conn,
 	}
-
-	err := o.parseProperties()
-	return o, err
+	o.ifaces.Store(map[string]*propertiesInterface{})
+	return o, nil
 }
 
-// Properties interface implementation
+//Properties the org.freedesktop.DBus.Properties implementation of an object.
+//
+// It is safe for concurrent use: the interfaces are held in a copy-on-write
+// snapshot, replaced by AddProperties and RemoveProperties and read without
+// locking by the D-Bus handlers. The properties struct of an interface is
+// written by the Set calls under the lock of its interface only, use Get to
+// read a consistent copy. Expose publishes the interfaces added so far,
+// interfaces added later are exported by the next Expose
 type Properties struct {
-	conn        *dbus.Conn
-	props       map[string]bluez.Properties
-	propsConfig map[string]map[string]*prop.Prop
-	instance    *prop.Properties
+	conn *dbus.Conn
+
+	// mutex serialize the writers of ifaces and instance
+	mutex sync.Mutex
+	// ifaces map[string]*propertiesInterface, never modified once stored
+	ifaces   atomic.Value
+	instance *prop.Properties
+
+	// window coalesce the changes emitted within it, see Coalesce
+	window   time.Duration
+	notifier *PropertiesNotifier
+}
+
+// propertiesInterface the properties of an interface with their D-Bus
+// configuration, parsed once when added
+type propertiesInterface struct {
+	mutex  sync.Mutex
+	props  bluez.Properties
+	config map[string]*prop.Prop
+	// emit the signal configured for each property, see Coalesce
+	emit map[string]prop.EmitType
+}
+
+func (p *Properties) snapshot() map[string]*propertiesInterface {
+	return p.ifaces.Load().(map[string]*propertiesInterface)
 }
 
 func (p *Properties) parseTag(conf *prop.Prop, tag string) {
@@ -49,95 +75,203 @@ func (p *Properties) parseTag(conf *prop.Prop, tag string) {
 			conf.Writable = true
 			break
 		default:
-			t := reflect.TypeOf(p)
-			m, ok := t.MethodByName(parts[i])
-			if ok {
+			m := reflect.ValueOf(p).MethodByName(parts[i])
+			if !m.IsValid() {
+				continue
+			}
+			if cb, ok := m.Interface().(func(*prop.Change) *dbus.Error); ok {
 				conf.Writable = true
-				conf.Callback = m.Func.Interface().(func(*prop.Change) *dbus.Error)
+				conf.Callback = cb
 			}
 		}
 	}
 }
 
-func (p *Properties) parseProperties() error {
-	for iface, ifaceVal := range p.props {
-
-		if _, ok := p.propsConfig[iface]; !ok {
-			p.propsConfig[iface] = make(map[string]*prop.Prop)
+// hasTag return true if the dbus tag of a field holds an option
+func hasTag(tag string, option string) bool {
+	for _, part := range strings.Split(tag, ",") {
+		if part == option {
+			return true
 		}
+	}
+	return false
+}
+
+func isZeroValue(value interface{}) bool {
+	return value == nil || reflect.DeepEqual(value, reflect.Zero(reflect.TypeOf(value)).Interface())
+}
 
-		t := structs.New(ifaceVal)
-		for _, field := range t.Fields() {
+func (p *Properties) parseProperties(props bluez.Properties) *propertiesInterface {
 
-			if _, ok := field.Value().(dbus.ObjectPath); ok && field.IsZero() {
-				// log.Debugf("parseProperties: skip empty ObjectPath %s", field.Name())
-				continue
-			}
+	iface := &propertiesInterface{
+		props:  props,
+		config: make(map[string]*prop.Prop),
+		emit:   make(map[string]prop.EmitType),
+	}
 
-			propConf := &prop.Prop{
-				Value:    field.Value(),
-				Emit:     prop.EmitFalse,
-				Writable: false,
-				Callback: p.onChange,
-			}
+	for _, field := range util.StructFields(props) {
 
-			tag := field.Tag("dbus")
-			if tag != "" {
-				p.parseTag(propConf, tag)
-			}
+		if path, ok := field.Value.(dbus.ObjectPath); ok && path == "" {
+			// bluez.GetLogger().Debugf("parseProperties: skip empty ObjectPath %s", field.Name)
+			continue
+		}
+		if hasTag(field.Tag, "omitempty") && isZeroValue(field.Value) {
+			// optional properties bluez reads differently when present
+			continue
+		}
+
+		propConf := &prop.Prop{
+			Value:    field.Value,
+			Emit:     prop.EmitFalse,
+			Writable: false,
+			Callback: p.onChange,
+		}
 
-			// log.Debugf("parseProperties: %s: `%s` %v", field.Name(), tag, propConf)
-			p.propsConfig[iface][field.Name()] = propConf
+		if field.Tag != "" {
+			p.parseTag(propConf, field.Tag)
 		}
+
+		// bluez.GetLogger().Debugf("parseProperties: %s: `%s` %v", field.Name, field.Tag, propConf)
+		iface.config[field.Name] = propConf
+		iface.emit[field.Name] = propConf.Emit
 	}
-	return nil
+	return iface
 }
 
 func (p *Properties) onChange(ev *prop.Change) *dbus.Error {
-	if _, ok := p.propsConfig[ev.Iface]; ok {
-		if conf, ok := p.propsConfig[ev.Iface][ev.Name]; ok {
-			if conf.Writable {
-				log.Debugf("Set %s.%s", ev.Iface, ev.Name)
-				prop := p.props[ev.Iface]
-				s := structs.New(prop)
-				err := s.Field(ev.Name).Set(ev.Value)
-				if err != nil {
-					log.Errorf("Failed to set %s.%s: %s", ev.Iface, ev.Name, err.Error())
-					return DbusErr
-				}
-			}
+
+	iface, ok := p.snapshot()[ev.Iface]
+	if !ok {
+		return nil
+	}
+
+	if conf, ok := iface.config[ev.Name]; ok && conf.Writable {
+		bluez.GetLogger().Debugf("Set %s.%s", ev.Iface, ev.Name)
+		iface.mutex.Lock()
+		err := util.SetStructField(iface.props, ev.Name, ev.Value)
+		iface.mutex.Unlock()
+		if err != nil {
+			bluez.GetLogger().Errorf("Failed to set %s.%s: %s", ev.Iface, ev.Name, err.Error())
+			return DbusErr
+		}
+	}
+
+	p.mutex.Lock()
+	notifier := p.notifier
+	p.mutex.Unlock()
+	if notifier != nil {
+		switch iface.emit[ev.Name] {
+		case prop.EmitTrue:
+			notifier.Changed(ev.Iface, ev.Name, dbus.MakeVariant(ev.Value))
+		case prop.EmitInvalidates:
+			notifier.Invalidated(ev.Iface, ev.Name)
 		}
 	}
 	return nil
 }
 
+//Get return a copy of the properties of an interface, nil if not found
+func (p *Properties) Get(iface string) map[string]interface{} {
+	i, ok := p.snapshot()[iface]
+	if !ok {
+		return nil
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return util.StructToMap(i.props)
+}
+
+//Coalesce emit the changes happening within window as a single
+// PropertiesChanged signal, eg. for a value updated by a sensor loop. It must
+// be called before Expose, zero emits each change
+func (p *Properties) Coalesce(window time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.window = window
+}
+
+//Flush emit the coalesced changes now
+func (p *Properties) Flush() error {
+	p.mutex.Lock()
+	notifier := p.notifier
+	p.mutex.Unlock()
+	if notifier == nil {
+		return nil
+	}
+	return notifier.Flush()
+}
+
 //Instance return the props instance
 func (p *Properties) Instance() *prop.Properties {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 	return p.instance
 }
 
 //Introspection return the props instance
 func (p *Properties) Introspection(iface string) []introspect.Property {
-	return p.instance.Introspection(iface)
+	return p.Instance().Introspection(iface)
 }
 
 //Expose expose the properties interface
 func (p *Properties) Expose(path dbus.ObjectPath) {
-	p.instance = prop.New(p.conn, path, p.propsConfig)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.window > 0 {
+		// the signals are left to the notifier
+		p.notifier = NewPropertiesNotifier(p.conn, path, p.window)
+	}
+
+	// prop.Properties keep the configuration, it gets its own copy
+	config := make(map[string]map[string]*prop.Prop)
+	for name, iface := range p.snapshot() {
+		config[name] = make(map[string]*prop.Prop, len(iface.config))
+		for field, conf := range iface.config {
+			c := *conf
+			if p.notifier != nil {
+				c.Emit = prop.EmitFalse
+			}
+			config[name][field] = &c
+		}
+	}
+	p.instance = prop.New(p.conn, path, config)
 }
 
 //AddProperties add a property set
 func (p *Properties) AddProperties(iface string, props bluez.Properties) error {
-	p.props[iface] = props
-	return p.parseProperties()
+
+	parsed := p.parseProperties(props)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	ifaces := p.snapshot()
+	updated := make(map[string]*propertiesInterface, len(ifaces)+1)
+	for name, i := range ifaces {
+		updated[name] = i
+	}
+	updated[iface] = parsed
+	p.ifaces.Store(updated)
+	return nil
 }
 
 //RemoveProperties remove a property set
 func (p *Properties) RemoveProperties(iface string) {
-	if _, ok := p.props[iface]; ok {
-		delete(p.props, iface)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	ifaces := p.snapshot()
+	if _, ok := ifaces[iface]; !ok {
+		return
 	}
-	if _, ok := p.propsConfig[iface]; ok {
-		delete(p.propsConfig, iface)
+	updated := make(map[string]*propertiesInterface, len(ifaces))
+	for name, i := range ifaces {
+		if name != iface {
+			updated[name] = i
+		}
 	}
+	p.ifaces.Store(updated)
 }
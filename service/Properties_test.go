@@ -0,0 +1,79 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestPropertiesConcurrency(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: true,
+		UUID:    app.GenerateUUID("2233"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.AddService(srv); err != nil {
+		t.Fatal(err)
+	}
+	char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID:  app.GenerateUUID("3344"),
+		Flags: []string{bluez.FlagCharacteristicRead},
+		Value: []byte{0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.AddCharacteristic(char); err != nil {
+		t.Fatal(err)
+	}
+
+	remote := b.Conn().Object("org.bluez.bluetest", char.Path())
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func(i int) {
+			for j := 0; j < 50; j++ {
+				switch i {
+				case 0:
+					char.UpdateValue([]byte{byte(j)})
+				case 1:
+					char.PropertiesInterface.Get(bluez.GattCharacteristic1Interface)
+				case 2:
+					remote.GetProperty(bluez.GattCharacteristic1Interface + ".Value")
+				case 3:
+					char.PropertiesInterface.AddProperties("org.bluez.bluetest.Extra", &profile.GattDescriptor1Properties{})
+					char.PropertiesInterface.RemoveProperties("org.bluez.bluetest.Extra")
+				}
+			}
+			done <- true
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+
+	value, _ := char.PropertiesInterface.Get(bluez.GattCharacteristic1Interface)["Value"].([]byte)
+	if len(value) != 1 || value[0] != 49 {
+		t.Fatalf("Expected the last value, got %v", value)
+	}
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//SetServiceData advertise data for a service, nil removes it. The UUID can be
+// in its 16, 32 or 128 bit form. While advertising the advertisement is
+// updated in place, without interruption, when it already holds service data,
+// else it is registered again
+func (app *Application) SetServiceData(uuid string, data []byte) error {
+
+	u, err := bluetooth.ParseUUID(uuid)
+	if err != nil {
+		return err
+	}
+	app.serviceDataMutex.Lock()
+	if data == nil {
+		delete(app.serviceData, u.String())
+	} else {
+		if app.serviceData == nil {
+			app.serviceData = make(map[string]interface{})
+		}
+		app.serviceData[u.String()] = append([]byte{}, data...)
+	}
+	app.serviceDataMutex.Unlock()
+
+	app.advertisingMutex.Lock()
+	defer app.advertisingMutex.Unlock()
+	adv := app.advertisement
+	if adv == nil {
+		return nil
+	}
+	props := adv.PropertiesInterface.Instance()
+	if _, dbusErr := props.Get(bluez.LEAdvertisement1Interface, "ServiceData"); dbusErr == nil {
+		value := app.advertisedServiceData()
+		if value == nil {
+			value = map[string]interface{}{}
+		}
+		if dbusErr = props.Set(bluez.LEAdvertisement1Interface, "ServiceData", dbus.MakeVariant(value)); dbusErr != nil {
+			return dbusErr
+		}
+		return nil
+	}
+	if data == nil {
+		return nil
+	}
+	// the property is exported with the advertisement only
+	return app.restartAdvertising()
+}
+
+// restartAdvertising register the advertisement again on its adapter, to
+// export the changes of its properties, with advertisingMutex held
+func (app *Application) restartAdvertising() error {
+	adapterID := app.advertisingAdapter
+	if err := app.stopAdvertising(); err != nil {
+		return err
+	}
+	return app.startAdvertising(adapterID)
+}
+
+// advertisedServiceData return a copy of the service data, nil when empty
+func (app *Application) advertisedServiceData() map[string]interface{} {
+	app.serviceDataMutex.Lock()
+	defer app.serviceDataMutex.Unlock()
+	if len(app.serviceData) == 0 {
+		return nil
+	}
+	data := make(map[string]interface{}, len(app.serviceData))
+	for uuid, value := range app.serviceData {
+		data[uuid] = value
+	}
+	return data
+}
+
+//IdentifierRotation the configuration of RotateIdentifier
+type IdentifierRotation struct {
+	// ServiceUUID the service advertising the identifier in its data
+	ServiceUUID string
+	// Interval the lifetime of an identifier, eg. 15 minutes
+	Interval time.Duration
+	// Generate return the payload of a new identifier, called on each
+	// rotation
+	Generate func() ([]byte, error)
+}
+
+//RotateIdentifier advertise an identifier in the service data, generated
+// now then every Interval, for the privacy preserving proximity applications.
+// The advertisement is updated in place on each rotation, it is registered
+// again once if it is running without service data. The errors of the next
+// rotations are logged, the previous identifier is kept. Call the returned
+// function to stop the rotation, the identifier is left in the advertisement
+func (app *Application) RotateIdentifier(config IdentifierRotation) (func(), error) {
+
+	if config.Interval <= 0 {
+		return nil, errors.New("Invalid rotation interval")
+	}
+	if config.Generate == nil {
+		return nil, errors.New("Generate is required")
+	}
+	rotate := func() error {
+		payload, err := config.Generate()
+		if err != nil {
+			return err
+		}
+		return app.SetServiceData(config.ServiceUUID, payload)
+	}
+	if err := rotate(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if err := rotate(); err != nil {
+				app.Logger().Warnf("Cannot rotate the identifier: %s", err.Error())
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}, nil
+}
+
+//MaxDeviceNameSize the longest GAP device name, in bytes
+const MaxDeviceNameSize = 248
+
+//SetIdentity configure the name and the appearance seen by the scanners in
+// the three places they are read from, so that they agree: the LocalName and
+// the Appearance of the advertisement, restarted if running, and the Alias of
+// the adapter, served by bluez as the GAP Device Name characteristic. The GAP
+// Appearance characteristic is derived by bluez from the class of the adapter
+func (app *Application) SetIdentity(adapterID string, name string, appearance uint16) error {
+
+	if name == "" || len(name) > MaxDeviceNameSize {
+		return fmt.Errorf("Invalid device name %q", name)
+	}
+
+	err := profile.NewAdapter1(adapterID).SetProperty("Alias", dbus.MakeVariant(name))
+	if err != nil {
+		return err
+	}
+
+	app.advertisingMutex.Lock()
+	defer app.advertisingMutex.Unlock()
+	app.config.LocalName = name
+	app.config.Appearance = appearance
+	if app.advertisement == nil {
+		return nil
+	}
+	return app.restartAdvertising()
+}
+
+//Advertising return true if the advertisement of the application is
+// registered
+func (app *Application) Advertising() bool {
+	app.advertisingMutex.Lock()
+	defer app.advertisingMutex.Unlock()
+	return app.advertisement != nil
+}
+
+// advertisementReleased handle an advertisement dropped by bluez, see
+// ApplicationConfig.AdvertiseWhileConnected
+func (app *Application) advertisementReleased(adv *LEAdvertisement1) {
+
+	app.advertisingMutex.Lock()
+	defer app.advertisingMutex.Unlock()
+	if app.advertisement != adv {
+		return
+	}
+	if !app.config.AdvertiseWhileConnected {
+		app.Logger().Warnf("Advertisement released by bluez")
+		app.forget(app.advertisementRecovery())
+		app.advertisement = nil
+		app.adMgr = nil
+		return
+	}
+
+	app.Logger().Infof("Advertisement released by bluez, registering it again")
+	if err := app.registerAdvertisement(app.advertisingAdapter); err != nil {
+		app.Logger().Warnf("Cannot register the advertisement again: %s", err.Error())
+	}
+}
+
+// watchAdvertisingConnections register the advertisement again when a
+// central connects to the adapter, the controllers that stop advertising on a
+// connection do not always get the advertisement released. With
+// advertisingMutex held
+func (app *Application) watchAdvertisingConnections(adv *LEAdvertisement1, adapterID string) error {
+
+	events, stop, err := app.WatchConnections()
+	if err != nil {
+		return err
+	}
+	prefix := "/org/bluez/" + adapterID + "/"
+	go func() {
+		for ev := range events {
+			if ev.Connected && strings.HasPrefix(string(ev.Device), prefix) {
+				app.advertisementConnected(adv)
+			}
+		}
+	}()
+	app.advertisingWatch = stop
+	return nil
+}
+
+// advertisementConnected register the advertisement again after a
+// connection, see ApplicationConfig.AdvertiseWhileConnected
+func (app *Application) advertisementConnected(adv *LEAdvertisement1) {
+
+	app.advertisingMutex.Lock()
+	defer app.advertisingMutex.Unlock()
+	if app.advertisement != adv {
+		return
+	}
+
+	app.Logger().Infof("Central connected, registering the advertisement again")
+	err := app.adMgr.UnregisterAdvertisement(string(adv.config.objectPath))
+	if err != nil && !bluez.IsError(err, bluez.ErrDoesNotExist) {
+		app.Logger().Warnf("Cannot unregister the advertisement: %s", err.Error())
+		return
+	}
+	if err = app.registerAdvertisement(app.advertisingAdapter); err != nil {
+		app.Logger().Warnf("Cannot register the advertisement again: %s", err.Error())
+	}
+}
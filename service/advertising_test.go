@@ -0,0 +1,306 @@
+package service_test
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestRotateIdentifier(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+		Logger:     bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	generated := byte(0)
+	stop, err := app.RotateIdentifier(service.IdentifierRotation{
+		ServiceUUID: "FD6F",
+		Interval:    50 * time.Millisecond,
+		Generate: func() ([]byte, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			generated++
+			return []byte{generated}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	if err = app.StartAdvertising("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer app.StopAdvertising()
+
+	adv := b.Adapter("hci0").Advertisements()[0]
+	identifier := func() byte {
+		if err := adv.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := adv.Properties()["ServiceData"].Value().(map[string]dbus.Variant)
+		v, ok := data[bluetooth.CanonicalUUID("FD6F")]
+		if !ok {
+			t.Fatalf("Expected the identifier in the service data, got %v", data)
+		}
+		return v.Value().([]byte)[0]
+	}
+
+	first := identifier()
+	time.Sleep(200 * time.Millisecond)
+	if identifier() == first {
+		t.Fatal("Expected the identifier rotated")
+	}
+	if list := b.Adapter("hci0").Advertisements(); len(list) != 1 || list[0] != adv {
+		t.Fatal("Expected the advertisement updated in place")
+	}
+
+	if _, err = app.RotateIdentifier(service.IdentifierRotation{ServiceUUID: "FD6F"}); err == nil {
+		t.Fatal("Expected an error without interval")
+	}
+}
+
+func TestRotateIdentifierRestart(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+		Logger:     bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = app.StartAdvertising("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer app.StopAdvertising()
+	before := b.Adapter("hci0").Advertisements()[0]
+
+	if _, err = app.RotateIdentifier(service.IdentifierRotation{ServiceUUID: "FD6F", Interval: time.Minute}); err == nil {
+		t.Fatal("Expected an error without Generate")
+	}
+
+	var mutex sync.Mutex
+	generated, failing := byte(0), false
+	stop, err := app.RotateIdentifier(service.IdentifierRotation{
+		ServiceUUID: "FD6F",
+		Interval:    50 * time.Millisecond,
+		Generate: func() ([]byte, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if failing {
+				return nil, errors.New("No entropy")
+			}
+			generated++
+			return []byte{generated}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// the advertisement had no service data, it is registered again once
+	list := b.Adapter("hci0").Advertisements()
+	if len(list) != 1 || list[0] == before {
+		t.Fatal("Expected the advertisement registered again")
+	}
+	adv := list[0]
+	serviceData := func() map[string]dbus.Variant {
+		if err := adv.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := adv.Properties()["ServiceData"].Value().(map[string]dbus.Variant)
+		return data
+	}
+	identifier := func() byte {
+		v, ok := serviceData()[bluetooth.CanonicalUUID("FD6F")]
+		if !ok {
+			t.Fatal("Expected the identifier in the service data")
+		}
+		return v.Value().([]byte)[0]
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if list = b.Adapter("hci0").Advertisements(); len(list) != 1 || list[0] != adv {
+		t.Fatal("Expected the next rotations in place")
+	}
+
+	// a failed rotation keeps the previous identifier
+	mutex.Lock()
+	failing = true
+	mutex.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	kept := identifier()
+	time.Sleep(200 * time.Millisecond)
+	if identifier() != kept {
+		t.Fatal("Expected the identifier kept while the rotation fails")
+	}
+
+	mutex.Lock()
+	failing = false
+	mutex.Unlock()
+	stop()
+	time.Sleep(100 * time.Millisecond)
+	stopped := identifier()
+	time.Sleep(200 * time.Millisecond)
+	if identifier() != stopped {
+		t.Fatal("Expected the rotation stopped")
+	}
+	// stopping twice is safe
+	stop()
+
+	if err = app.SetServiceData("FD6F", nil); err != nil {
+		t.Fatal(err)
+	}
+	if data := serviceData(); len(data) != 0 {
+		t.Fatalf("Expected the identifier removed, got %v", data)
+	}
+}
+
+func TestSetIdentity(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+		LocalName:  "bluetest",
+		Logger:     bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err = app.StartAdvertising("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer app.StopAdvertising()
+
+	a := b.Adapter("hci0")
+	if _, ok := a.Advertisements()[0].Properties()["Appearance"]; ok {
+		t.Fatal("Expected no appearance advertised")
+	}
+
+	if err = app.SetIdentity("hci0", "thermometer", 0x0300); err != nil {
+		t.Fatal(err)
+	}
+	if alias := a.Property("Alias"); alias != "thermometer" {
+		t.Fatalf("Expected the alias set, got %v", alias)
+	}
+	list := a.Advertisements()
+	if len(list) != 1 || list[0].LocalName() != "thermometer" {
+		t.Fatal("Expected the advertisement renamed")
+	}
+	if v, ok := list[0].Properties()["Appearance"]; !ok || v.Value().(uint16) != 0x0300 {
+		t.Fatalf("Expected the appearance advertised, got %v", v)
+	}
+
+	if err = app.SetIdentity("hci0", "", 0); err == nil {
+		t.Fatal("Expected an error for an empty name")
+	}
+}
+
+func TestAdvertiseWhileConnected(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	a := b.Adapter("hci0")
+	wait := func(what string, fn func() bool) {
+		for i := 0; i < 50; i++ {
+			if fn() {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatal("Timeout waiting for " + what)
+	}
+
+	central, err := b.AddDevice("hci0", "00:00:00:00:00:01", "central")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n, restart := range []bool{false, true} {
+		app, err := service.NewApplication(&service.ApplicationConfig{
+			UUIDSuffix:              service.UUIDSuffix,
+			UUID:                    "1234",
+			ObjectName:              "org.bluez.bluetest",
+			ObjectPath:              dbus.ObjectPath("/bluetest" + strconv.Itoa(n)),
+			LocalName:               "bluetest",
+			Logger:                  bluez.NopLogger{},
+			AdvertiseWhileConnected: restart,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = app.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if err = app.StartAdvertising("hci0"); err != nil {
+			t.Fatal(err)
+		}
+		if err = a.ReleaseAdvertisements(); err != nil {
+			t.Fatal(err)
+		}
+
+		if restart {
+			wait("the advertisement registered again", func() bool { return len(a.Advertisements()) == 1 })
+			if !app.Advertising() {
+				t.Fatal("Expected the application advertising")
+			}
+
+			// a controller stopping on a connection without a release
+			before := a.Advertisements()[0]
+			central.SetProperty("Connected", true)
+			wait("the advertisement registered on the connection", func() bool {
+				list := a.Advertisements()
+				return len(list) == 1 && list[0] != before
+			})
+			central.SetProperty("Connected", false)
+		} else {
+			wait("the advertising stopped", func() bool { return !app.Advertising() })
+			if len(a.Advertisements()) != 0 {
+				t.Fatal("Expected no advertisement")
+			}
+		}
+		app.StopAdvertising()
+	}
+}
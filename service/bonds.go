@@ -0,0 +1,53 @@
+package service
+
+import (
+	"path"
+	"sort"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//Bonds return the devices bonded with an adapter, eg. hci0, sorted by path
+func (app *Application) Bonds(adapterID string) ([]dbus.ObjectPath, error) {
+
+	var objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	err := app.config.Conn.Object(bluez.OrgBluez, "/").
+		Call(bluez.ObjectManagerInterface+".GetManagedObjects", 0).
+		Store(&objects)
+	if err != nil {
+		return nil, bluez.ParseError(err)
+	}
+
+	adapter := dbus.ObjectPath("/org/bluez/" + adapterID)
+	bonds := []dbus.ObjectPath{}
+	for p, ifaces := range objects {
+		props, ok := ifaces[bluez.Device1Interface]
+		if !ok {
+			continue
+		}
+		if a, _ := props["Adapter"].Value().(dbus.ObjectPath); a != adapter {
+			continue
+		}
+		if paired, _ := props["Paired"].Value().(bool); paired {
+			bonds = append(bonds, p)
+		}
+	}
+	sort.Slice(bonds, func(i, j int) bool { return bonds[i] < bonds[j] })
+	return bonds, nil
+}
+
+//RemoveBond remove a device and its pairing keys from its adapter, the
+// device is disconnected if connected. bluetoothd removes the keys of both
+// the BR/EDR and the LE transports
+func (app *Application) RemoveBond(device dbus.ObjectPath) error {
+	app.pairedMutex.Lock()
+	delete(app.paired, device)
+	app.pairedMutex.Unlock()
+
+	adapter := dbus.ObjectPath(path.Dir(string(device)))
+	err := app.config.Conn.Object(bluez.OrgBluez, adapter).
+		Call(bluez.Adapter1Interface+".RemoveDevice", 0, device).
+		Store()
+	return bluez.ParseError(err)
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//ConnectionEvent a remote device connected or disconnected
+type ConnectionEvent struct {
+	Device    dbus.ObjectPath
+	Connected bool
+}
+
+//WatchConnections report the connections and disconnections of the remote
+// devices, as seen by bluetoothd. Call the returned function to stop
+func (app *Application) WatchConnections() (<-chan ConnectionEvent, func(), error) {
+
+	dispatcher := bluez.GetSignalDispatcher(app.config.Conn)
+	signals, err := dispatcher.Subscribe(bluez.SignalFilter{
+		Path:          "/org/bluez",
+		PathNamespace: true,
+		Interface:     bluez.PropertiesInterface,
+		Member:        "PropertiesChanged",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := make(chan ConnectionEvent, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(events)
+		for {
+			var sig *dbus.Signal
+			select {
+			case <-done:
+				return
+			case sig = <-signals:
+			}
+			if sig == nil || len(sig.Body) < 2 {
+				continue
+			}
+			if iface, _ := sig.Body[0].(string); iface != bluez.Device1Interface {
+				continue
+			}
+			changed, _ := sig.Body[1].(map[string]dbus.Variant)
+			connected, ok := changed["Connected"].Value().(bool)
+			if !ok {
+				continue
+			}
+			select {
+			case events <- ConnectionEvent{Device: sig.Path, Connected: connected}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			dispatcher.Unsubscribe(signals)
+			close(done)
+		})
+	}
+	return events, stop, nil
+}
+
+// connectionPolicy enforce ApplicationConfig.MaxConnections and
+// AcceptConnection on the devices connecting to an adapter
+type connectionPolicy struct {
+	app       *Application
+	adapterID string
+	stop      func()
+
+	mutex sync.Mutex
+	// connected the devices accepted
+	connected map[dbus.ObjectPath]bool
+	// paused the advertisement was stopped at the maximum of connections
+	paused bool
+}
+
+// watchConnectionPolicy start enforcing the connection policy, if any
+func (app *Application) watchConnectionPolicy(adapterID string) error {
+
+	if app.config.MaxConnections <= 0 && app.config.AcceptConnection == nil {
+		return nil
+	}
+	events, stop, err := app.WatchConnections()
+	if err != nil {
+		return err
+	}
+	p := &connectionPolicy{
+		app:       app,
+		adapterID: adapterID,
+		stop:      stop,
+		connected: make(map[dbus.ObjectPath]bool),
+	}
+	app.connections = p
+
+	prefix := "/org/bluez/" + adapterID + "/"
+	go func() {
+		for ev := range events {
+			if !strings.HasPrefix(string(ev.Device), prefix) {
+				continue
+			}
+			if ev.Connected {
+				p.connect(ev.Device)
+			} else {
+				p.disconnect(ev.Device)
+			}
+		}
+	}()
+	return nil
+}
+
+// unwatchConnectionPolicy stop enforcing the connection policy
+func (app *Application) unwatchConnectionPolicy() {
+	if app.connections == nil {
+		return
+	}
+	app.connections.stop()
+	app.connections = nil
+}
+
+//ConnectedDevices return the devices connected to the application accepted
+// by the connection policy, empty without MaxConnections nor
+// AcceptConnection
+func (app *Application) ConnectedDevices() []dbus.ObjectPath {
+	p := app.connections
+	if p == nil {
+		return []dbus.ObjectPath{}
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	list := make([]dbus.ObjectPath, 0, len(p.connected))
+	for device := range p.connected {
+		list = append(list, device)
+	}
+	return list
+}
+
+// connect accept a device or disconnect it, the advertisement is stopped
+// when the maximum of connections is reached
+func (p *connectionPolicy) connect(device dbus.ObjectPath) {
+
+	p.mutex.Lock()
+	known := p.connected[device]
+	p.mutex.Unlock()
+	if known {
+		return
+	}
+
+	config := p.app.config
+	if config.AcceptConnection != nil && !config.AcceptConnection(device) {
+		p.reject(device, "not accepted")
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if config.MaxConnections > 0 && len(p.connected) >= config.MaxConnections {
+		p.reject(device, "too many connections")
+		return
+	}
+	p.connected[device] = true
+
+	if config.MaxConnections > 0 && len(p.connected) == config.MaxConnections && p.app.Advertising() {
+		if err := p.app.StopAdvertising(); err != nil {
+			p.app.Logger().Warnf("Cannot stop advertising: %s", err.Error())
+			return
+		}
+		p.paused = true
+	}
+}
+
+// disconnect release the slot of a device, restarting the advertisement if
+// it was stopped by the policy
+func (p *connectionPolicy) disconnect(device dbus.ObjectPath) {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.connected[device] {
+		return
+	}
+	delete(p.connected, device)
+
+	if p.paused {
+		if err := p.app.StartAdvertising(p.adapterID); err != nil {
+			p.app.Logger().Warnf("Cannot restart advertising: %s", err.Error())
+			return
+		}
+		p.paused = false
+	}
+}
+
+func (p *connectionPolicy) reject(device dbus.ObjectPath, reason string) {
+	p.app.Logger().Infof("Disconnecting %s: %s", device, reason)
+	err := p.app.config.Conn.Object(bluez.OrgBluez, device).
+		Call(bluez.Device1Interface+".Disconnect", 0).Store()
+	if err != nil {
+		p.app.Logger().Warnf("Cannot disconnect %s: %s", device, bluez.ParseError(err).Error())
+	}
+}
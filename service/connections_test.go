@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestConnectionPolicy(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	var devices []*bluetest.Device
+	for _, address := range []string{"00:00:00:00:00:01", "00:00:00:00:00:02", "00:00:00:00:00:03"} {
+		d, err := b.AddDevice("hci0", address, "central")
+		if err != nil {
+			t.Fatal(err)
+		}
+		devices = append(devices, d)
+	}
+	blocked := devices[2].Path
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix:     service.UUIDSuffix,
+		UUID:           "1234",
+		ObjectName:     "org.bluez.bluetest",
+		ObjectPath:     "/bluetest",
+		LocalName:      "bluetest",
+		Logger:         bluez.NopLogger{},
+		MaxConnections: 1,
+		AcceptConnection: func(device dbus.ObjectPath) bool {
+			return device != blocked
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Register("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Unregister()
+	if err = app.StartAdvertising("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer app.StopAdvertising()
+
+	a := b.Adapter("hci0")
+	wait := func(what string, fn func() bool) {
+		for i := 0; i < 50; i++ {
+			if fn() {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatal("Timeout waiting for " + what)
+	}
+
+	devices[2].SetProperty("Connected", true)
+	wait("the rejection", func() bool { return !devices[2].Property("Connected").(bool) })
+
+	devices[0].SetProperty("Connected", true)
+	wait("the advertisement stop", func() bool { return len(a.Advertisements()) == 0 })
+	if list := app.ConnectedDevices(); len(list) != 1 || list[0] != devices[0].Path {
+		t.Fatalf("Unexpected connected devices %v", list)
+	}
+
+	devices[1].SetProperty("Connected", true)
+	wait("the disconnection of the extra device", func() bool { return !devices[1].Property("Connected").(bool) })
+	if !devices[0].Property("Connected").(bool) {
+		t.Fatal("Expected the first device connected")
+	}
+
+	devices[0].SetProperty("Connected", false)
+	wait("the advertisement restart", func() bool { return len(a.Advertisements()) == 1 })
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//DefaultDialTimeout the timeout of DialProfile when none is given
+const DefaultDialTimeout = 30 * time.Second
+
+var dialCounter uint32
+
+// dialers the client profiles of DialProfileContext, one per UUID
+var dialers = struct {
+	sync.Mutex
+	byUUID map[string]*dialer
+}{byUUID: make(map[string]*dialer)}
+
+// dialer a client profile shared by the dials of an UUID. Bluez hands over
+// the sockets to the profile, they are matched to the pending dial of their
+// device
+type dialer struct {
+	uuid    string
+	profile *Profile1
+	// users the pending dials and the open connections
+	users   int
+	mutex   sync.Mutex
+	pending map[dbus.ObjectPath]chan *ProfileConnection
+}
+
+// acquireDialer return the profile of an UUID, registering it for the first
+// user
+func acquireDialer(uuid string) (*dialer, error) {
+
+	dialers.Lock()
+	defer dialers.Unlock()
+
+	key := strings.ToLower(uuid)
+	if d, ok := dialers.byUUID[key]; ok {
+		d.users++
+		return d, nil
+	}
+
+	d := &dialer{
+		uuid:    uuid,
+		pending: make(map[dbus.ObjectPath]chan *ProfileConnection),
+	}
+
+	id := atomic.AddUint32(&dialCounter, 1)
+	p, err := NewProfile1(&Profile1Config{
+		ObjectPath: dbus.ObjectPath("/org/bluez/gobluetooth/dial" + strconv.Itoa(int(id))),
+		UUID:       uuid,
+		Options: &profile.ProfileManager1Options{
+			Role: profile.ProfileRoleClient,
+		},
+		OnConnection: d.deliver,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.Register()
+	if err != nil {
+		p.Unregister()
+		return nil, err
+	}
+
+	d.profile = p
+	d.users = 1
+	dialers.byUUID[key] = d
+	return d, nil
+}
+
+// release unregister the profile once the last user is gone
+func (d *dialer) release() {
+
+	dialers.Lock()
+	defer dialers.Unlock()
+
+	d.users--
+	if d.users > 0 {
+		return
+	}
+	delete(dialers.byUUID, strings.ToLower(d.uuid))
+	d.profile.Unregister()
+}
+
+// expect add a pending dial to device, a single dial per device is allowed
+func (d *dialer) expect(device dbus.ObjectPath) (chan *ProfileConnection, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if _, ok := d.pending[device]; ok {
+		return nil, bluez.ErrInProgress.WithMessage("Already connecting to profile " + d.uuid + " on " + string(device))
+	}
+	connections := make(chan *ProfileConnection, 1)
+	d.pending[device] = connections
+	return connections, nil
+}
+
+// forget drop a pending dial, closing the socket delivered meanwhile
+func (d *dialer) forget(device dbus.ObjectPath, connections chan *ProfileConnection) {
+	d.mutex.Lock()
+	if d.pending[device] == connections {
+		delete(d.pending, device)
+	}
+	d.mutex.Unlock()
+
+	select {
+	case c := <-connections:
+		c.Conn.Close()
+	default:
+	}
+}
+
+// deliver pass a socket to the pending dial of its device, a socket nobody
+// waits for, eg. arriving after the dial was canceled, is closed
+func (d *dialer) deliver(c *ProfileConnection) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	connections, ok := d.pending[c.Device]
+	if !ok {
+		bluez.GetLogger().Debugf("Dial: closing unexpected connection of %s", c.Device)
+		c.Conn.Close()
+		return
+	}
+	delete(d.pending, c.Device)
+	connections <- c
+}
+
+// profileConn release the client profile once the connection is closed
+type profileConn struct {
+	net.Conn
+	dialer *dialer
+	once   sync.Once
+}
+
+func (c *profileConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.dialer.release)
+	return err
+}
+
+//DialProfile connect to a profile on a remote device, eg. to the Serial Port
+// Profile of an OBD2 dongle or a printer. Bluez resolves the RFCOMM channel via
+// SDP and hands over the socket to a client Profile1, registered while dials
+// to the profile are pending or connected. A zero timeout defaults to
+// DefaultDialTimeout
+func DialProfile(device dbus.ObjectPath, uuid string, timeout time.Duration) (net.Conn, error) {
+	if timeout <= 0 {
+		timeout = DefaultDialTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := DialProfileContext(ctx, device, uuid)
+	if err == context.DeadlineExceeded {
+		return nil, errors.New("Timeout connecting to profile " + uuid + " on " + string(device))
+	}
+	return conn, err
+}
+
+//DialProfileContext connect to a profile on a remote device like DialProfile,
+// until the context is done. The connection attempt is aborted with the
+// context
+func DialProfileContext(ctx context.Context, device dbus.ObjectPath, uuid string) (net.Conn, error) {
+
+	d, err := acquireDialer(uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	connections, err := d.expect(device)
+	if err != nil {
+		d.release()
+		return nil, err
+	}
+
+	dev := profile.NewDevice1(string(device))
+	dev.SetConnection(d.profile.config.Conn)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- dev.ConnectProfileContext(ctx, uuid)
+	}()
+
+	for {
+		select {
+		case c := <-connections:
+			return &profileConn{Conn: c.Conn, dialer: d}, nil
+		case err := <-errs:
+			if err == nil {
+				// connected, the socket may be delivered after the reply
+				errs = nil
+				continue
+			}
+			d.forget(device, connections)
+			d.release()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		case <-ctx.Done():
+			d.forget(device, connections)
+			d.release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+//DialSerialPort connect to the Serial Port Profile of a remote device, a zero
+// timeout defaults to DefaultDialTimeout
+func DialSerialPort(device dbus.ObjectPath, timeout time.Duration) (net.Conn, error) {
+	return DialProfile(device, profile.SerialPortProfileUUID, timeout)
+}
@@ -0,0 +1,131 @@
+package service_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// serveAddress make a fake device write its address on the profile connections
+func serveAddress(d *bluetest.Device) {
+	d.OnProfileConnection = func(uuid string, conn net.Conn) {
+		conn.Write([]byte(d.Address))
+		ioutil.ReadAll(conn)
+		conn.Close()
+	}
+}
+
+func TestDialProfile(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	devices := make([]*bluetest.Device, 2)
+	for i, address := range []string{"00:00:00:00:00:01", "00:00:00:00:00:02"} {
+		d, err := b.AddDevice("hci0", address, "dongle")
+		if err != nil {
+			t.Fatal(err)
+		}
+		serveAddress(d)
+		devices[i] = d
+	}
+
+	// concurrent dials to the same profile get the socket of their device
+	conns := make([]net.Conn, len(devices))
+	errs := make(chan error, len(devices))
+	for i, d := range devices {
+		go func(i int, d *bluetest.Device) {
+			var err error
+			conns[i], err = service.DialSerialPort(d.Path, 5*time.Second)
+			errs <- err
+		}(i, d)
+	}
+	for range devices {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if profiles := b.Profiles(); len(profiles) != 1 {
+		t.Fatalf("Expected a single profile registered, got %v", profiles)
+	}
+
+	for i, conn := range conns {
+		buf := make([]byte, 17)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != devices[i].Address {
+			t.Errorf("Expected the socket of %s, got %s", devices[i].Address, buf)
+		}
+	}
+
+	conns[0].Close()
+	if profiles := b.Profiles(); len(profiles) != 1 {
+		t.Fatalf("Expected the profile registered while a connection is open, got %v", profiles)
+	}
+	conns[1].Close()
+	if profiles := b.Profiles(); len(profiles) != 0 {
+		t.Fatalf("Expected the profile unregistered, got %v", profiles)
+	}
+}
+
+func TestDialProfileCanceled(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	connected, err := b.AddDevice("hci0", "00:00:00:00:00:01", "dongle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveAddress(connected)
+
+	// keeps the profile registered
+	conn, err := service.DialSerialPort(connected.Path, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	slow, err := b.AddDevice("hci0", "00:00:00:00:00:02", "dongle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unblock := make(chan bool)
+	slow.OnConnectProfile = func(uuid string) error {
+		<-unblock
+		return nil
+	}
+	late := make(chan error, 1)
+	slow.OnProfileConnection = func(uuid string, conn net.Conn) {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, err := conn.Read(make([]byte, 1))
+		late <- err
+		conn.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err = service.DialProfileContext(ctx, slow.Path, profile.SerialPortProfileUUID); err != context.DeadlineExceeded {
+		t.Fatalf("Expected %s, got %v", context.DeadlineExceeded, err)
+	}
+
+	// the socket delivered after the dial gave up is closed
+	close(unblock)
+	select {
+	case err := <-late:
+		if err == nil || err.Error() != "EOF" {
+			t.Fatalf("Expected the late socket closed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the late socket")
+	}
+}
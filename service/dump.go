@@ -0,0 +1,138 @@
+package service
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//DumpObject an object of the application, by interface and property name
+type DumpObject struct {
+	Path       dbus.ObjectPath                   `json:"path"`
+	Interfaces map[string]map[string]interface{} `json:"interfaces"`
+	// Name the name of the UUID of a service, characteristic or descriptor,
+	// from the assigned numbers or bluetooth.RegisterUUIDName
+	Name string `json:"name,omitempty"`
+	// Introspection the interfaces listed by Introspect, missing when the
+	// object is not in the introspection tree
+	Introspection []string `json:"introspection,omitempty"`
+	// Children the nodes listed by Introspect
+	Children []string `json:"children,omitempty"`
+}
+
+//Dump a snapshot of the application, to compare what is exported with what
+// bluetoothd sees
+type Dump struct {
+	Name string          `json:"name"`
+	Path dbus.ObjectPath `json:"path"`
+	// Exported the objects held by the ObjectManager of the application
+	Exported []DumpObject `json:"exported"`
+	// Visible the objects returned by GetManagedObjects over the bus, as
+	// bluetoothd reads them on RegisterApplication
+	Visible []DumpObject `json:"visible"`
+	// Error the failure of the GetManagedObjects call, eg. when the
+	// application has not been run
+	Error string `json:"error,omitempty"`
+	// Pending the objects added in a batch not yet committed
+	Pending []dbus.ObjectPath `json:"pending,omitempty"`
+}
+
+//Snapshot return the state of the application, see Dump
+func (app *Application) Snapshot() *Dump {
+
+	d := &Dump{
+		Name: app.Name(),
+		Path: app.Path(),
+	}
+
+	tree := app.tree.snapshot()
+
+	exported, err := app.GetObjectManager().GetManagedObjects()
+	if err != nil {
+		d.Error = err.Error()
+	}
+	d.Exported = dumpObjects(exported, tree)
+
+	visible := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
+	call := app.config.Conn.Object(app.Name(), app.Path()).
+		Call(bluez.ObjectManagerInterface+".GetManagedObjects", 0)
+	if call.Err != nil {
+		d.Error = call.Err.Error()
+	} else if err := call.Store(&visible); err != nil {
+		d.Error = err.Error()
+	}
+	d.Visible = dumpObjects(visible, nil)
+
+	for path := range app.batch {
+		d.Pending = append(d.Pending, path)
+	}
+	sort.Slice(d.Pending, func(i, j int) bool { return d.Pending[i] < d.Pending[j] })
+
+	return d
+}
+
+//Dump return a JSON snapshot of the exported paths, interfaces and
+// properties, with what bluetoothd sees via GetManagedObjects
+func (app *Application) Dump() ([]byte, error) {
+	return json.MarshalIndent(app.Snapshot(), "", "  ")
+}
+
+func dumpObjects(objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant, tree map[dbus.ObjectPath]introspectionSnapshot) []DumpObject {
+
+	list := make([]DumpObject, 0, len(objects))
+	for path, ifaces := range objects {
+		o := DumpObject{
+			Path:       path,
+			Interfaces: make(map[string]map[string]interface{}, len(ifaces)),
+		}
+		for iface, props := range ifaces {
+			o.Interfaces[iface] = make(map[string]interface{}, len(props))
+			for name, value := range props {
+				o.Interfaces[iface][name] = dumpValue(value.Value())
+			}
+			if o.Name == "" {
+				o.Name = dumpName(iface, props)
+			}
+		}
+		if node, ok := tree[path]; ok {
+			o.Introspection = node.interfaces
+			o.Children = node.children
+		}
+		list = append(list, o)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list
+}
+
+// dumpName return the name of the UUID of the GATT objects
+func dumpName(iface string, props map[string]dbus.Variant) string {
+	switch iface {
+	case bluez.GattService1Interface, bluez.GattCharacteristic1Interface, bluez.GattDescriptor1Interface:
+	default:
+		return ""
+	}
+	uuid, ok := props["UUID"].Value().(string)
+	if !ok {
+		return ""
+	}
+	u, err := bluetooth.ParseUUID(uuid)
+	if err != nil {
+		return ""
+	}
+	return u.Name()
+}
+
+// dumpValue format the values not readable in JSON, eg. bytes as hex
+func dumpValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case []byte:
+		return hex.EncodeToString(val)
+	case dbus.Variant:
+		return dumpValue(val.Value())
+	}
+	return v
+}
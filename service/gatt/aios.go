@@ -0,0 +1,510 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Automation IO UUIDs
+var (
+	AutomationIOUUID        = UUID("1815")
+	DigitalUUID             = UUID("2A56")
+	AnalogUUID              = UUID("2A58")
+	AggregateUUID           = UUID("2A5A")
+	UserDescriptionUUID     = UUID("2901")
+	NumberOfDigitalsUUID    = UUID("2909")
+	ValueTriggerSettingUUID = UUID("290A")
+)
+
+//DigitalState the state of a digital signal, two bits of a Digital
+// characteristic
+type DigitalState uint8
+
+// Digital states
+const (
+	DigitalInactive    DigitalState = 0
+	DigitalActive      DigitalState = 1
+	DigitalTristate    DigitalState = 2
+	DigitalOutputState DigitalState = 3
+)
+
+// Conditions of the Value Trigger Setting descriptor
+const (
+	ValueStateChanged    uint8 = 0x00
+	ValueCrossedBoundary uint8 = 0x01
+	ValueOnBoundary      uint8 = 0x02
+	ValueChangedMore     uint8 = 0x03
+	ValueMaskCompare     uint8 = 0x04
+	ValueInsideOutside   uint8 = 0x05
+	ValueOnBoundaries    uint8 = 0x06
+	ValueNoTrigger       uint8 = 0x07
+)
+
+//ErrTriggerNotSupported the value trigger condition is not supported by the
+// signal
+var ErrTriggerNotSupported = bluez.ErrFailed.WithMessage("Trigger condition value not supported")
+
+//ValueTrigger when a signal is notified, exposed as the Value Trigger
+// Setting descriptor
+type ValueTrigger struct {
+	// Condition eg. ValueStateChanged, the analog signals support all the
+	// conditions but ValueMaskCompare, the digital signals ValueStateChanged,
+	// ValueMaskCompare and ValueNoTrigger
+	Condition uint8
+	// Values the boundaries of the analog conditions, Values[0] for the
+	// conditions with a single value
+	Values [2]uint16
+	// Mask the digitals compared by ValueMaskCompare
+	Mask []bool
+}
+
+//Bytes encode the descriptor value
+func (t ValueTrigger) Bytes() []byte {
+	b := []byte{t.Condition}
+	switch t.Condition {
+	case ValueCrossedBoundary, ValueOnBoundary, ValueChangedMore:
+		b = append(b, byte(t.Values[0]), byte(t.Values[0]>>8))
+	case ValueInsideOutside, ValueOnBoundaries:
+		b = append(b, byte(t.Values[0]), byte(t.Values[0]>>8), byte(t.Values[1]), byte(t.Values[1]>>8))
+	case ValueMaskCompare:
+		mask := make([]DigitalState, len(t.Mask))
+		for i, m := range t.Mask {
+			if m {
+				mask[i] = DigitalOutputState
+			}
+		}
+		b = append(b, packDigitals(mask)...)
+	}
+	return b
+}
+
+// parseValueTrigger decode a trigger written to a digital, with count
+// signals, or to an analog if count is zero
+func parseValueTrigger(b []byte, count int) (ValueTrigger, error) {
+
+	t := ValueTrigger{}
+	if len(b) == 0 {
+		return t, bluez.ErrInvalidValueLength
+	}
+	t.Condition = b[0]
+	operand := b[1:]
+
+	size := 0
+	switch t.Condition {
+	case ValueStateChanged, ValueNoTrigger:
+	case ValueCrossedBoundary, ValueOnBoundary, ValueChangedMore, ValueInsideOutside, ValueOnBoundaries:
+		if count > 0 {
+			return t, ErrTriggerNotSupported
+		}
+		size = 2
+		if t.Condition == ValueInsideOutside || t.Condition == ValueOnBoundaries {
+			size = 4
+		}
+	case ValueMaskCompare:
+		if count == 0 {
+			return t, ErrTriggerNotSupported
+		}
+		size = (count + 3) / 4
+	default:
+		return t, ErrTriggerNotSupported
+	}
+	if len(operand) != size {
+		return t, bluez.ErrInvalidValueLength
+	}
+
+	switch size {
+	case 2:
+		t.Values[0] = binary.LittleEndian.Uint16(operand)
+	case 4:
+		t.Values[0] = binary.LittleEndian.Uint16(operand)
+		t.Values[1] = binary.LittleEndian.Uint16(operand[2:])
+	}
+	if t.Condition == ValueMaskCompare {
+		t.Mask = make([]bool, count)
+		for i, s := range unpackDigitals(operand, count) {
+			t.Mask[i] = s != DigitalInactive
+		}
+	}
+	return t, nil
+}
+
+// packDigitals encode the states, four per byte from the least significant
+// bits
+func packDigitals(states []DigitalState) []byte {
+	b := make([]byte, (len(states)+3)/4)
+	for i, s := range states {
+		b[i/4] |= byte(s&0x03) << uint(2*(i%4))
+	}
+	return b
+}
+
+// unpackDigitals decode count states
+func unpackDigitals(b []byte, count int) []DigitalState {
+	states := make([]DigitalState, count)
+	for i := range states {
+		if i/4 < len(b) {
+			states[i] = DigitalState(b[i/4]>>uint(2*(i%4))) & 0x03
+		}
+	}
+	return states
+}
+
+//DigitalConfig configure a Digital characteristic
+type DigitalConfig struct {
+	// Count the digital signals of the characteristic
+	Count int
+	// Writable accept the writes of the outputs, passed to OnWrite
+	Writable bool
+	OnWrite  func(states []DigitalState) error
+	// Description exposed as the User Description, if set
+	Description string
+	// Trigger the initial value trigger, ValueStateChanged if nil
+	Trigger *ValueTrigger
+}
+
+//AnalogConfig configure an Analog characteristic
+type AnalogConfig struct {
+	// Writable accept the writes of the output, passed to OnWrite
+	Writable bool
+	OnWrite  func(value uint16) error
+	// Description exposed as the User Description, if set
+	Description string
+	// Trigger the initial value trigger, ValueStateChanged if nil
+	Trigger *ValueTrigger
+}
+
+//AutomationIOService the Automation IO Service (0x1815), eg. to drive the
+// relays and read the ADCs of a board
+type AutomationIOService struct {
+	srv       *service.GattService1
+	aggregate *service.GattCharacteristic1
+
+	mutex    sync.Mutex
+	digitals []*Digital
+	analogs  []*Analog
+}
+
+//Digital a Digital characteristic, with one or more signals
+type Digital struct {
+	s       *AutomationIOService
+	char    *service.GattCharacteristic1
+	onWrite func(states []DigitalState) error
+	states  []DigitalState
+	trigger ValueTrigger
+}
+
+//Analog an Analog characteristic
+type Analog struct {
+	s       *AutomationIOService
+	char    *service.GattCharacteristic1
+	onWrite func(value uint16) error
+	value   uint16
+	// notified the last value notified, for ValueChangedMore
+	notified uint16
+	trigger  ValueTrigger
+}
+
+//NewAutomationIOService add an Automation IO Service to a running
+// application, aggregate exposes the Aggregate of all the signals
+func NewAutomationIOService(app *service.Application, aggregate bool) (*AutomationIOService, error) {
+
+	s := &AutomationIOService{}
+
+	srv, err := addService(app, AutomationIOUUID, true)
+	if err != nil {
+		return nil, err
+	}
+	s.srv = srv
+
+	if aggregate {
+		s.aggregate, err = addCharacteristic(srv, AggregateUUID,
+			[]string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicNotify},
+			func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+				s.mutex.Lock()
+				defer s.mutex.Unlock()
+				return s.aggregateValue(), nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+//Service return the GATT service
+func (s *AutomationIOService) Service() *service.GattService1 {
+	return s.srv
+}
+
+// aggregateValue the digitals followed by the analogs
+func (s *AutomationIOService) aggregateValue() []byte {
+	b := []byte{}
+	for _, d := range s.digitals {
+		b = append(b, packDigitals(d.states)...)
+	}
+	for _, a := range s.analogs {
+		b = append(b, byte(a.value), byte(a.value>>8))
+	}
+	return b
+}
+
+// notifyAggregate notify the aggregate after a signal triggered
+func (s *AutomationIOService) notifyAggregate() {
+	if s.aggregate != nil && s.aggregate.Notifying() {
+		s.aggregate.UpdateValue(s.aggregateValue())
+	}
+}
+
+// signalFlags return the flags of a signal
+func signalFlags(writable bool) []string {
+	flags := []string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicNotify}
+	if writable {
+		flags = append(flags, bluez.FlagCharacteristicWrite)
+	}
+	return flags
+}
+
+// addSignalDescriptors add the descriptors common to the signals, the
+// presentation format distinguishes the instances
+func (s *AutomationIOService) addSignalDescriptors(char *service.GattCharacteristic1, format uint8, index int, description string, trigger *ValueTrigger, count int) error {
+
+	pf := PresentationFormat{
+		Format:      format,
+		Unit:        UnitUnitless,
+		Namespace:   0x01,
+		Description: uint16(index),
+	}
+	if _, err := addDescriptor(char, PresentationFormatUUID, pf.Bytes()); err != nil {
+		return err
+	}
+	if description != "" {
+		if _, err := addDescriptor(char, UserDescriptionUUID, []byte(description)); err != nil {
+			return err
+		}
+	}
+
+	_, err := addWritableDescriptor(char, ValueTriggerSettingUUID,
+		func(d *service.GattDescriptor1, options map[string]interface{}) ([]byte, error) {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			return trigger.Bytes(), nil
+		},
+		func(d *service.GattDescriptor1, value []byte, options map[string]interface{}) error {
+			t, err := parseValueTrigger(value, count)
+			if err != nil {
+				return err
+			}
+			s.mutex.Lock()
+			*trigger = t
+			s.mutex.Unlock()
+			return nil
+		})
+	return err
+}
+
+//AddDigital add a Digital characteristic
+func (s *AutomationIOService) AddDigital(config DigitalConfig) (*Digital, error) {
+
+	if config.Count < 1 || config.Count > 0xff {
+		return nil, bluez.ErrInvalidArguments.WithMessage("Invalid number of digitals")
+	}
+
+	d := &Digital{
+		s:       s,
+		onWrite: config.OnWrite,
+		states:  make([]DigitalState, config.Count),
+	}
+	if config.Trigger != nil {
+		d.trigger = *config.Trigger
+	}
+
+	var write service.CharacteristicWriteFunc
+	if config.Writable {
+		write = d.write
+	}
+	char, err := addWritable(s.srv, DigitalUUID, signalFlags(config.Writable), d.read, write)
+	if err != nil {
+		return nil, err
+	}
+	d.char = char
+
+	s.mutex.Lock()
+	s.digitals = append(s.digitals, d)
+	index := len(s.digitals)
+	s.mutex.Unlock()
+
+	if err = s.addSignalDescriptors(char, FormatStruct, index, config.Description, &d.trigger, config.Count); err != nil {
+		return nil, err
+	}
+	if _, err = addDescriptor(char, NumberOfDigitalsUUID, []byte{byte(config.Count)}); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Digital) read(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+	d.s.mutex.Lock()
+	defer d.s.mutex.Unlock()
+	return packDigitals(d.states), nil
+}
+
+func (d *Digital) write(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+	if len(value) != (len(d.states)+3)/4 {
+		return bluez.ErrInvalidValueLength
+	}
+	states := unpackDigitals(value, len(d.states))
+	if d.onWrite != nil {
+		if err := d.onWrite(states); err != nil {
+			return err
+		}
+	}
+	d.s.mutex.Lock()
+	d.states = states
+	d.s.mutex.Unlock()
+	return nil
+}
+
+//States return the states of the signals
+func (d *Digital) States() []DigitalState {
+	d.s.mutex.Lock()
+	defer d.s.mutex.Unlock()
+	return append([]DigitalState{}, d.states...)
+}
+
+//Set the states of the signals, notified if the value trigger condition is
+// met
+func (d *Digital) Set(states ...DigitalState) error {
+
+	if len(states) != len(d.states) {
+		return bluez.ErrInvalidArguments.WithMessage("Invalid number of digitals")
+	}
+
+	d.s.mutex.Lock()
+	defer d.s.mutex.Unlock()
+
+	fire := false
+	for i, s := range states {
+		if s == d.states[i] {
+			continue
+		}
+		switch d.trigger.Condition {
+		case ValueStateChanged:
+			fire = true
+		case ValueMaskCompare:
+			fire = fire || (i < len(d.trigger.Mask) && d.trigger.Mask[i])
+		}
+	}
+	d.states = append([]DigitalState{}, states...)
+	if fire {
+		d.char.UpdateValue(packDigitals(d.states))
+		d.s.notifyAggregate()
+	}
+	return nil
+}
+
+//AddAnalog add an Analog characteristic
+func (s *AutomationIOService) AddAnalog(config AnalogConfig) (*Analog, error) {
+
+	a := &Analog{
+		s:       s,
+		onWrite: config.OnWrite,
+	}
+	if config.Trigger != nil {
+		a.trigger = *config.Trigger
+	}
+
+	var write service.CharacteristicWriteFunc
+	if config.Writable {
+		write = a.write
+	}
+	char, err := addWritable(s.srv, AnalogUUID, signalFlags(config.Writable), a.read, write)
+	if err != nil {
+		return nil, err
+	}
+	a.char = char
+
+	s.mutex.Lock()
+	s.analogs = append(s.analogs, a)
+	index := len(s.analogs)
+	s.mutex.Unlock()
+
+	if err = s.addSignalDescriptors(char, FormatUint16, index, config.Description, &a.trigger, 0); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *Analog) read(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+	a.s.mutex.Lock()
+	defer a.s.mutex.Unlock()
+	return []byte{byte(a.value), byte(a.value >> 8)}, nil
+}
+
+func (a *Analog) write(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+	if len(value) != 2 {
+		return bluez.ErrInvalidValueLength
+	}
+	v := binary.LittleEndian.Uint16(value)
+	if a.onWrite != nil {
+		if err := a.onWrite(v); err != nil {
+			return err
+		}
+	}
+	a.s.mutex.Lock()
+	a.value = v
+	a.s.mutex.Unlock()
+	return nil
+}
+
+//Value return the value of the signal
+func (a *Analog) Value() uint16 {
+	a.s.mutex.Lock()
+	defer a.s.mutex.Unlock()
+	return a.value
+}
+
+//Set the value of the signal, notified if the value trigger condition is met
+func (a *Analog) Set(v uint16) {
+
+	a.s.mutex.Lock()
+	defer a.s.mutex.Unlock()
+
+	old := a.value
+	a.value = v
+	if !a.trigger.match(old, v, a.notified) {
+		return
+	}
+	a.notified = v
+	a.char.UpdateValue([]byte{byte(v), byte(v >> 8)})
+	a.s.notifyAggregate()
+}
+
+// match return true if the change of an analog from old to v is notified,
+// last the value notified before
+func (t ValueTrigger) match(old uint16, v uint16, last uint16) bool {
+	if old == v {
+		return false
+	}
+	x, y := t.Values[0], t.Values[1]
+	inside := func(v uint16) bool { return v >= x && v <= y }
+	switch t.Condition {
+	case ValueStateChanged:
+		return true
+	case ValueCrossedBoundary:
+		return (old < x) != (v < x)
+	case ValueOnBoundary:
+		return v == x
+	case ValueChangedMore:
+		d := int(v) - int(last)
+		return d > int(x) || -d > int(x)
+	case ValueInsideOutside:
+		return inside(old) != inside(v)
+	case ValueOnBoundaries:
+		return v == x || v == y
+	}
+	return false
+}
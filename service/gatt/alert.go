@@ -0,0 +1,384 @@
+package gatt
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Alert Notification and Phone Alert Status UUIDs
+var (
+	AlertNotificationUUID         = UUID("1811")
+	SupportedNewAlertCategoryUUID = UUID("2A47")
+	NewAlertUUID                  = UUID("2A46")
+	SupportedUnreadCategoryUUID   = UUID("2A48")
+	UnreadAlertStatusUUID         = UUID("2A45")
+	AlertControlPointUUID         = UUID("2A44")
+	PhoneAlertStatusUUID          = UUID("180E")
+	AlertStatusUUID               = UUID("2A3F")
+	RingerSettingUUID             = UUID("2A41")
+	RingerControlPointUUID        = UUID("2A40")
+)
+
+// Alert categories
+const (
+	CategorySimpleAlert  uint8 = 0
+	CategoryEmail        uint8 = 1
+	CategoryNews         uint8 = 2
+	CategoryCall         uint8 = 3
+	CategoryMissedCall   uint8 = 4
+	CategorySMS          uint8 = 5
+	CategoryVoiceMail    uint8 = 6
+	CategorySchedule     uint8 = 7
+	CategoryHighPriority uint8 = 8
+	CategoryInstantMsg   uint8 = 9
+	// CategoryAll all the categories, in the control point commands
+	CategoryAll uint8 = 0xff
+)
+
+// Alert Notification Control Point commands
+const (
+	AlertEnableNew       uint8 = 0
+	AlertEnableUnread    uint8 = 1
+	AlertDisableNew      uint8 = 2
+	AlertDisableUnread   uint8 = 3
+	AlertNotifyNewNow    uint8 = 4
+	AlertNotifyUnreadNow uint8 = 5
+)
+
+// maxNewAlertText the bytes of text of a New Alert
+const maxNewAlertText = 18
+
+// Alert Status and Ringer Control Point values
+const (
+	AlertStatusRinger  uint8 = 1 << 0
+	AlertStatusVibrate uint8 = 1 << 1
+	AlertStatusDisplay uint8 = 1 << 2
+
+	RingerSilent uint8 = 0
+	RingerNormal uint8 = 1
+
+	RingerSetSilent    uint8 = 1
+	RingerMuteOnce     uint8 = 2
+	RingerCancelSilent uint8 = 3
+)
+
+//ErrCommandNotSupported the error of an unknown Alert Notification Control
+// Point command or category
+var ErrCommandNotSupported = bluez.ErrFailed.WithMessage("Command not supported")
+
+//Categories return the bitmask of the categories, eg. for the supported
+// categories of NewAlertNotificationService
+func Categories(ids ...uint8) uint16 {
+	var mask uint16
+	for _, id := range ids {
+		if id <= CategoryInstantMsg {
+			mask |= 1 << id
+		}
+	}
+	return mask
+}
+
+//NewAlert the value of the New Alert characteristic
+type NewAlert struct {
+	Category uint8
+	Count    uint8
+	// Text eg. the name of the caller, truncated to 18 bytes
+	Text string
+}
+
+//Bytes encode the alert
+func (a NewAlert) Bytes() []byte {
+	text := a.Text
+	for len(text) > maxNewAlertText {
+		_, size := utf8.DecodeLastRuneInString(text)
+		text = text[:len(text)-size]
+	}
+	return append([]byte{a.Category, a.Count}, text...)
+}
+
+//AlertControl a command written to the Alert Notification Control Point
+type AlertControl struct {
+	Command  uint8
+	Category uint8
+}
+
+//ParseAlertControl decode a control point command, supported is the bitmask
+// of the categories it may apply to
+func ParseAlertControl(b []byte, supported uint16) (AlertControl, error) {
+	if len(b) != 2 {
+		return AlertControl{}, bluez.ErrInvalidValueLength
+	}
+	c := AlertControl{Command: b[0], Category: b[1]}
+	if c.Command > AlertNotifyUnreadNow {
+		return c, ErrCommandNotSupported
+	}
+	if c.Category != CategoryAll && (c.Category > CategoryInstantMsg || supported&(1<<c.Category) == 0) {
+		return c, ErrCommandNotSupported
+	}
+	return c, nil
+}
+
+// categories return the categories selected by a command
+func (c AlertControl) categories(supported uint16) uint16 {
+	if c.Category == CategoryAll {
+		return supported
+	}
+	return 1 << c.Category
+}
+
+//AlertNotificationService the Alert Notification Service (0x1811), the
+// client enables the categories it is notified of with the control point
+type AlertNotificationService struct {
+	srv             *service.GattService1
+	newAlert        *service.GattCharacteristic1
+	unread          *service.GattCharacteristic1
+	supportedNew    uint16
+	supportedUnread uint16
+
+	mutex         sync.Mutex
+	enabledNew    uint16
+	enabledUnread uint16
+	lastNew       map[uint8]NewAlert
+	unreadCount   map[uint8]uint8
+}
+
+//NewAlertNotificationService add an Alert Notification Service to a running
+// application, with the bitmasks of the categories supported, see Categories
+func NewAlertNotificationService(app *service.Application, supportedNew uint16, supportedUnread uint16) (*AlertNotificationService, error) {
+
+	s := &AlertNotificationService{
+		supportedNew:    supportedNew,
+		supportedUnread: supportedUnread,
+		lastNew:         make(map[uint8]NewAlert),
+		unreadCount:     make(map[uint8]uint8),
+	}
+
+	srv, err := addService(app, AlertNotificationUUID)
+	if err != nil {
+		return nil, err
+	}
+	s.srv = srv
+
+	if _, err = addReadOnly(srv, SupportedNewAlertCategoryUUID, []byte{byte(supportedNew), byte(supportedNew >> 8)}); err != nil {
+		return nil, err
+	}
+	s.newAlert, err = addCharacteristic(srv, NewAlertUUID, []string{bluez.FlagCharacteristicNotify}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = addReadOnly(srv, SupportedUnreadCategoryUUID, []byte{byte(supportedUnread), byte(supportedUnread >> 8)}); err != nil {
+		return nil, err
+	}
+	s.unread, err = addCharacteristic(srv, UnreadAlertStatusUUID, []string{bluez.FlagCharacteristicNotify}, nil)
+	if err != nil {
+		return nil, err
+	}
+	_, err = addWritable(srv, AlertControlPointUUID, []string{bluez.FlagCharacteristicWrite}, nil,
+		func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+			return s.control(value)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+//Service return the GATT service
+func (s *AlertNotificationService) Service() *service.GattService1 {
+	return s.srv
+}
+
+// control handle a write of the control point
+func (s *AlertNotificationService) control(value []byte) error {
+
+	supported := s.supportedNew
+	if len(value) > 0 && (value[0] == AlertEnableUnread || value[0] == AlertDisableUnread || value[0] == AlertNotifyUnreadNow) {
+		supported = s.supportedUnread
+	}
+	c, err := ParseAlertControl(value, supported)
+	if err != nil {
+		return err
+	}
+	categories := c.categories(supported)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch c.Command {
+	case AlertEnableNew:
+		s.enabledNew |= categories
+	case AlertEnableUnread:
+		s.enabledUnread |= categories
+	case AlertDisableNew:
+		s.enabledNew &^= categories
+	case AlertDisableUnread:
+		s.enabledUnread &^= categories
+	case AlertNotifyNewNow:
+		for id := CategorySimpleAlert; id <= CategoryInstantMsg; id++ {
+			if a, ok := s.lastNew[id]; ok && categories&s.enabledNew&(1<<id) != 0 {
+				s.newAlert.UpdateValue(a.Bytes())
+			}
+		}
+	case AlertNotifyUnreadNow:
+		for id := CategorySimpleAlert; id <= CategoryInstantMsg; id++ {
+			if categories&s.enabledUnread&(1<<id) != 0 {
+				s.unread.UpdateValue([]byte{id, s.unreadCount[id]})
+			}
+		}
+	}
+	return nil
+}
+
+//Enabled return the bitmasks of the categories enabled by the client for the
+// new and the unread alerts
+func (s *AlertNotificationService) Enabled() (uint16, uint16) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.enabledNew, s.enabledUnread
+}
+
+//SendNewAlert notify a new alert if its category is enabled, it is kept for
+// the AlertNotifyNewNow commands
+func (s *AlertNotificationService) SendNewAlert(a NewAlert) error {
+	if a.Category > CategoryInstantMsg || s.supportedNew&(1<<a.Category) == 0 {
+		return ErrCommandNotSupported
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastNew[a.Category] = a
+	if s.enabledNew&(1<<a.Category) != 0 {
+		s.newAlert.UpdateValue(a.Bytes())
+	}
+	return nil
+}
+
+//SetUnread set the number of unread alerts of a category, notified if the
+// category is enabled
+func (s *AlertNotificationService) SetUnread(category uint8, count uint8) error {
+	if category > CategoryInstantMsg || s.supportedUnread&(1<<category) == 0 {
+		return ErrCommandNotSupported
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.unreadCount[category] = count
+	if s.enabledUnread&(1<<category) != 0 {
+		s.unread.UpdateValue([]byte{category, count})
+	}
+	return nil
+}
+
+//PhoneAlertStatusService the Phone Alert Status Service (0x180E), the client
+// reads the alert status and controls the ringer
+type PhoneAlertStatusService struct {
+	srv        *service.GattService1
+	status     *service.GattCharacteristic1
+	ringer     *service.GattCharacteristic1
+	onMuteOnce func()
+
+	mutex       sync.Mutex
+	alertStatus uint8
+	setting     uint8
+}
+
+//NewPhoneAlertStatusService add a Phone Alert Status Service to a running
+// application. onMuteOnce is called when the client mutes the ringing once,
+// the silent mode is handled by the service
+func NewPhoneAlertStatusService(app *service.Application, onMuteOnce func()) (*PhoneAlertStatusService, error) {
+
+	s := &PhoneAlertStatusService{
+		onMuteOnce: onMuteOnce,
+		setting:    RingerNormal,
+	}
+
+	srv, err := addService(app, PhoneAlertStatusUUID)
+	if err != nil {
+		return nil, err
+	}
+	s.srv = srv
+
+	flags := []string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicNotify}
+	s.status, err = addCharacteristic(srv, AlertStatusUUID, flags,
+		func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+			return []byte{s.AlertStatus()}, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	s.ringer, err = addCharacteristic(srv, RingerSettingUUID, flags,
+		func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+			return []byte{s.RingerSetting()}, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	_, err = addWritable(srv, RingerControlPointUUID, []string{bluez.FlagCharacteristicWriteWithoutResponse}, nil,
+		func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+			if len(value) != 1 {
+				return bluez.ErrInvalidValueLength
+			}
+			switch value[0] {
+			case RingerSetSilent:
+				s.SetRingerSetting(RingerSilent)
+			case RingerCancelSilent:
+				s.SetRingerSetting(RingerNormal)
+			case RingerMuteOnce:
+				if s.onMuteOnce != nil {
+					s.onMuteOnce()
+				}
+			}
+			// the other values are reserved and ignored
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+//Service return the GATT service
+func (s *PhoneAlertStatusService) Service() *service.GattService1 {
+	return s.srv
+}
+
+//AlertStatus return the alert status, a bitmask of the AlertStatus* values
+func (s *PhoneAlertStatusService) AlertStatus() uint8 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.alertStatus
+}
+
+//SetAlertStatus set the alert status, a bitmask of the AlertStatus* values,
+// notified on change
+func (s *PhoneAlertStatusService) SetAlertStatus(status uint8) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if status == s.alertStatus {
+		return
+	}
+	s.alertStatus = status
+	s.status.UpdateValue([]byte{status})
+}
+
+//RingerSetting return RingerSilent or RingerNormal
+func (s *PhoneAlertStatusService) RingerSetting() uint8 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.setting
+}
+
+//SetRingerSetting set the ringer setting, eg. when changed on the device,
+// notified on change
+func (s *PhoneAlertStatusService) SetRingerSetting(setting uint8) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if setting == s.setting {
+		return
+	}
+	s.setting = setting
+	s.ringer.UpdateValue([]byte{setting})
+}
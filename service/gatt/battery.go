@@ -0,0 +1,90 @@
+package gatt
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Battery Service UUIDs
+var (
+	BatteryServiceUUID = UUID("180F")
+	BatteryLevelUUID   = UUID("2A19")
+	// PresentationFormatUUID the Characteristic Presentation Format descriptor
+	PresentationFormatUUID = UUID("2904")
+)
+
+//BatteryService the Battery Service (0x180F), exposing the Battery Level of
+// the peripheral. Subscribers are notified when the level changes
+type BatteryService struct {
+	srv   *service.GattService1
+	char  *service.GattCharacteristic1
+	mutex sync.Mutex
+	level uint8
+}
+
+//NewBatteryService add a Battery Service to a running application, the level
+// starts at 100%
+func NewBatteryService(app *service.Application) (*BatteryService, error) {
+
+	b := &BatteryService{level: 100}
+
+	srv, err := addService(app, BatteryServiceUUID)
+	if err != nil {
+		return nil, err
+	}
+	b.srv = srv
+
+	b.char, err = addCharacteristic(srv, BatteryLevelUUID,
+		[]string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicNotify},
+		func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+			return []byte{b.Level()}, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = addDescriptor(b.char, PresentationFormatUUID, PresentationFormat{
+		Format:    FormatUint8,
+		Unit:      UnitPercentage,
+		Namespace: 0x01,
+	}.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+//Service return the GATT service
+func (b *BatteryService) Service() *service.GattService1 {
+	return b.srv
+}
+
+//Level return the battery level in percent
+func (b *BatteryService) Level() uint8 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.level
+}
+
+//SetLevel set the battery level, from 0 to 100 percent. The subscribers are
+// notified if the level changed
+func (b *BatteryService) SetLevel(percent int) error {
+
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("Invalid battery level %d", percent)
+	}
+
+	b.mutex.Lock()
+	changed := b.level != uint8(percent)
+	b.level = uint8(percent)
+	b.mutex.Unlock()
+
+	if changed {
+		b.char.UpdateValue([]byte{uint8(percent)})
+	}
+	return nil
+}
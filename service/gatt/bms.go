@@ -0,0 +1,168 @@
+package gatt
+
+import (
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Bond Management UUIDs
+var (
+	BondManagementUUID             = UUID("181E")
+	BondManagementControlPointUUID = UUID("2AA4")
+	BondManagementFeatureUUID      = UUID("2AA5")
+)
+
+// Bond Management Control Point op codes
+const (
+	BMDeleteBond        uint8 = 0x01
+	BMDeleteBondBREDR   uint8 = 0x02
+	BMDeleteBondLE      uint8 = 0x03
+	BMDeleteAll         uint8 = 0x04
+	BMDeleteAllBREDR    uint8 = 0x05
+	BMDeleteAllLE       uint8 = 0x06
+	BMDeleteOthers      uint8 = 0x07
+	BMDeleteOthersBREDR uint8 = 0x08
+	BMDeleteOthersLE    uint8 = 0x09
+)
+
+//BondRemovalDelay the delay before removing the bonds, for the response to
+// the write to reach the client before it is disconnected
+var BondRemovalDelay = 500 * time.Millisecond
+
+var (
+	//ErrBMOpCodeNotSupported the op code is not supported by the server
+	ErrBMOpCodeNotSupported = bluez.ErrFailed.WithMessage("Op Code not supported")
+	//ErrBMOperationFailed the bonds could not be listed
+	ErrBMOperationFailed = bluez.ErrFailed.WithMessage("Operation failed")
+)
+
+//BondManagementConfig configure a Bond Management Service
+type BondManagementConfig struct {
+	// Adapter the adapter of the bonds, eg. hci0
+	Adapter string
+	// Operations the BM* op codes supported
+	Operations []uint8
+	// AuthorizationCode required after the op codes, if set
+	AuthorizationCode string
+	// OnDelete called with the devices before their bonds are removed
+	OnDelete func(devices []dbus.ObjectPath)
+}
+
+//BondManagementFeatures return the value of the Bond Management Feature
+// characteristic for the op codes supported
+func BondManagementFeatures(operations []uint8, authorization bool) []byte {
+	var features uint32
+	for _, op := range operations {
+		if op < BMDeleteBond || op > BMDeleteOthersLE {
+			continue
+		}
+		bit := 2 * uint(op-1)
+		features |= 1 << bit
+		if authorization {
+			features |= 1 << (bit + 1)
+		}
+	}
+	return []byte{byte(features), byte(features >> 8), byte(features >> 16)}
+}
+
+//BondManagementService the Bond Management Service (0x181E), a peer asks to
+// forget its bond or the others. bluetoothd has a single bond for both the
+// transports of a device, the BR/EDR and LE op codes remove it as a whole
+type BondManagementService struct {
+	srv    *service.GattService1
+	app    *service.Application
+	config BondManagementConfig
+	ops    map[uint8]bool
+}
+
+//NewBondManagementService add a Bond Management Service to a running
+// application
+func NewBondManagementService(app *service.Application, config BondManagementConfig) (*BondManagementService, error) {
+
+	s := &BondManagementService{
+		app:    app,
+		config: config,
+		ops:    make(map[uint8]bool),
+	}
+	for _, op := range config.Operations {
+		s.ops[op] = true
+	}
+
+	srv, err := addService(app, BondManagementUUID)
+	if err != nil {
+		return nil, err
+	}
+	s.srv = srv
+
+	_, err = addWritable(srv, BondManagementControlPointUUID,
+		[]string{bluez.FlagCharacteristicEncryptWrite}, nil,
+		func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+			device, _ := service.OptionDevice(options)
+			return s.control(device, value)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	features := BondManagementFeatures(config.Operations, config.AuthorizationCode != "")
+	if _, err = addReadOnly(srv, BondManagementFeatureUUID, features); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+//Service return the GATT service
+func (s *BondManagementService) Service() *service.GattService1 {
+	return s.srv
+}
+
+// control handle a write of the control point by device
+func (s *BondManagementService) control(device dbus.ObjectPath, value []byte) error {
+
+	if len(value) == 0 {
+		return bluez.ErrInvalidValueLength
+	}
+	op := value[0]
+	if !s.ops[op] {
+		return ErrBMOpCodeNotSupported
+	}
+	if string(value[1:]) != s.config.AuthorizationCode {
+		return bluez.ErrNotAuthorized
+	}
+
+	var devices []dbus.ObjectPath
+	switch op {
+	case BMDeleteBond, BMDeleteBondBREDR, BMDeleteBondLE:
+		if device == "" {
+			return ErrBMOperationFailed
+		}
+		devices = []dbus.ObjectPath{device}
+	default:
+		bonds, err := s.app.Bonds(s.config.Adapter)
+		if err != nil {
+			return ErrBMOperationFailed
+		}
+		for _, bond := range bonds {
+			if op >= BMDeleteOthers && bond == device {
+				continue
+			}
+			devices = append(devices, bond)
+		}
+	}
+
+	if s.config.OnDelete != nil {
+		s.config.OnDelete(devices)
+	}
+	time.AfterFunc(BondRemovalDelay, func() {
+		for _, d := range devices {
+			if err := s.app.RemoveBond(d); err != nil {
+				s.app.Logger().Warnf("Bond management: remove %s: %s", d, err.Error())
+			}
+		}
+	})
+	return nil
+}
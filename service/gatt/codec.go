@@ -0,0 +1,493 @@
+package gatt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// integerFormat the encoding of the integer formats
+type integerFormat struct {
+	size   int
+	bits   uint
+	signed bool
+}
+
+var integerFormats = map[uint8]integerFormat{
+	FormatBoolean: {1, 1, false},
+	Format2Bit:    {1, 2, false},
+	FormatNibble:  {1, 4, false},
+	FormatUint8:   {1, 8, false},
+	FormatUint12:  {2, 12, false},
+	FormatUint16:  {2, 16, false},
+	FormatUint24:  {3, 24, false},
+	FormatUint32:  {4, 32, false},
+	FormatUint48:  {6, 48, false},
+	FormatUint64:  {8, 64, false},
+	FormatSint8:   {1, 8, true},
+	FormatSint12:  {2, 12, true},
+	FormatSint16:  {2, 16, true},
+	FormatSint24:  {3, 24, true},
+	FormatSint32:  {4, 32, true},
+	FormatSint48:  {6, 48, true},
+	FormatSint64:  {8, 64, true},
+}
+
+//EncodeUint encode the low size bytes of v, little endian
+func EncodeUint(v uint64, size int) []byte {
+	b := make([]byte, size)
+	for i := 0; i < size && i < 8; i++ {
+		b[i] = byte(v >> (8 * uint(i)))
+	}
+	return b
+}
+
+//DecodeUint decode an unsigned integer of up to 8 bytes, little endian
+func DecodeUint(b []byte) uint64 {
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
+
+//DecodeSint decode a signed integer of up to 8 bytes, little endian, eg. a
+// sint24
+func DecodeSint(b []byte) int64 {
+	shift := uint(64 - 8*len(b))
+	return int64(DecodeUint(b)<<shift) >> shift
+}
+
+//Size return the size of a value of the format, 0 for the formats of variable
+// length
+func (f PresentationFormat) Size() int {
+	if i, ok := integerFormats[f.Format]; ok {
+		return i.size
+	}
+	switch f.Format {
+	case FormatSFloat:
+		return 2
+	case FormatFloat32, FormatFloat, FormatDUint16:
+		return 4
+	case FormatFloat64:
+		return 8
+	case FormatUint128, FormatSint128:
+		return 16
+	}
+	return 0
+}
+
+//EncodeFloat encode a numeric value. The integer formats carry value /
+// 10^Exponent, rounded, an error is returned if it is out of the range of the
+// format
+func (f PresentationFormat) EncodeFloat(value float64) ([]byte, error) {
+
+	switch f.Format {
+	case FormatFloat32:
+		return EncodeUint(uint64(math.Float32bits(float32(value))), 4), nil
+	case FormatFloat64:
+		return EncodeUint(math.Float64bits(value), 8), nil
+	case FormatSFloat:
+		return NewSFloat(value).Bytes(), nil
+	case FormatFloat:
+		return NewFloat(value).Bytes(), nil
+	}
+
+	i, ok := integerFormats[f.Format]
+	if !ok {
+		return nil, fmt.Errorf("Format 0x%02X is not numeric", f.Format)
+	}
+	v := math.Floor(value/math.Pow10(int(f.Exponent)) + 0.5)
+
+	var min, max float64
+	if i.signed {
+		min = -math.Exp2(float64(i.bits - 1))
+		max = math.Exp2(float64(i.bits-1)) - 1
+	} else {
+		max = math.Exp2(float64(i.bits)) - 1
+	}
+	if math.IsNaN(v) || v < min || v > max {
+		return nil, fmt.Errorf("Value %v out of the range of format 0x%02X", value, f.Format)
+	}
+	if v >= math.Exp2(63) {
+		return EncodeUint(uint64(v), i.size), nil
+	}
+	return EncodeUint(i.mask(uint64(int64(v))), i.size), nil
+}
+
+// mask drop the sign extension of the negative values, eg. in the upper bits
+// of the 2 bytes of a sint12
+func (i integerFormat) mask(v uint64) uint64 {
+	if i.bits < 64 {
+		v &= 1<<i.bits - 1
+	}
+	return v
+}
+
+//DecodeFloat decode a numeric value, the integer formats are multiplied by
+// 10^Exponent
+func (f PresentationFormat) DecodeFloat(b []byte) (float64, error) {
+
+	if size := f.Size(); size == 0 || len(b) != size {
+		return 0, bluez.ErrInvalidValueLength
+	}
+
+	switch f.Format {
+	case FormatFloat32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+	case FormatFloat64:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case FormatSFloat:
+		return SFloat(binary.LittleEndian.Uint16(b)).Float64(), nil
+	case FormatFloat:
+		return Float(binary.LittleEndian.Uint32(b)).Float64(), nil
+	}
+
+	i, ok := integerFormats[f.Format]
+	if !ok {
+		return 0, fmt.Errorf("Format 0x%02X is not numeric", f.Format)
+	}
+	var v float64
+	if i.signed {
+		v = float64(f.signed(b, i))
+	} else {
+		v = float64(f.unsigned(b, i))
+	}
+	return v * math.Pow10(int(f.Exponent)), nil
+}
+
+func (f PresentationFormat) unsigned(b []byte, i integerFormat) uint64 {
+	return i.mask(DecodeUint(b))
+}
+
+func (f PresentationFormat) signed(b []byte, i integerFormat) int64 {
+	shift := 64 - i.bits
+	return int64(DecodeUint(b)<<shift) >> shift
+}
+
+//Encode a value of a Go type matching the format: a bool, an integer, a
+// float or a string for the utf8s and utf16s formats. The other formats take
+// their encoded value as a []byte
+func (f PresentationFormat) Encode(value interface{}) ([]byte, error) {
+
+	switch v := value.(type) {
+	case []byte:
+		if size := f.Size(); size != 0 && len(v) != size {
+			return nil, bluez.ErrInvalidValueLength
+		}
+		return v, nil
+	case string:
+		switch f.Format {
+		case FormatUTF8:
+			return []byte(v), nil
+		case FormatUTF16:
+			units := utf16.Encode([]rune(v))
+			b := make([]byte, 2*len(units))
+			for i, u := range units {
+				binary.LittleEndian.PutUint16(b[2*i:], u)
+			}
+			return b, nil
+		}
+	case bool:
+		if f.Format == FormatBoolean {
+			if v {
+				return []byte{1}, nil
+			}
+			return []byte{0}, nil
+		}
+	case float32:
+		return f.EncodeFloat(float64(v))
+	case float64:
+		return f.EncodeFloat(v)
+	case int:
+		return f.encodeInt(int64(v))
+	case int8:
+		return f.encodeInt(int64(v))
+	case int16:
+		return f.encodeInt(int64(v))
+	case int32:
+		return f.encodeInt(int64(v))
+	case int64:
+		return f.encodeInt(v)
+	case uint:
+		return f.encodeUint(uint64(v))
+	case uint8:
+		return f.encodeUint(uint64(v))
+	case uint16:
+		return f.encodeUint(uint64(v))
+	case uint32:
+		return f.encodeUint(uint64(v))
+	case uint64:
+		return f.encodeUint(v)
+	}
+	return nil, fmt.Errorf("Cannot encode %T in format 0x%02X", value, f.Format)
+}
+
+// encodeInt encode an integer exactly when it is not scaled
+func (f PresentationFormat) encodeInt(v int64) ([]byte, error) {
+	i, ok := integerFormats[f.Format]
+	if !ok || f.Exponent != 0 {
+		return f.EncodeFloat(float64(v))
+	}
+	if v < 0 && !i.signed {
+		return nil, fmt.Errorf("Value %d out of the range of format 0x%02X", v, f.Format)
+	}
+	if v < 0 {
+		if i.bits < 64 && v < -(1<<(i.bits-1)) {
+			return nil, fmt.Errorf("Value %d out of the range of format 0x%02X", v, f.Format)
+		}
+		return EncodeUint(i.mask(uint64(v)), i.size), nil
+	}
+	return f.encodeUint(uint64(v))
+}
+
+// encodeUint encode an unsigned integer exactly when it is not scaled
+func (f PresentationFormat) encodeUint(v uint64) ([]byte, error) {
+	i, ok := integerFormats[f.Format]
+	if !ok || f.Exponent != 0 {
+		return f.EncodeFloat(float64(v))
+	}
+	bits := i.bits
+	if i.signed {
+		bits--
+	}
+	if bits < 64 && v >= 1<<bits {
+		return nil, fmt.Errorf("Value %d out of the range of format 0x%02X", v, f.Format)
+	}
+	return EncodeUint(v, i.size), nil
+}
+
+//Decode a value in its Go type: a bool, a uint64 or an int64 for the integer
+// formats, a float64 for the floats and the integers with an exponent, a
+// string for utf8s and utf16s. The other formats are returned as []byte
+func (f PresentationFormat) Decode(b []byte) (interface{}, error) {
+
+	if size := f.Size(); size != 0 && len(b) != size {
+		return nil, bluez.ErrInvalidValueLength
+	}
+
+	switch f.Format {
+	case FormatBoolean:
+		return b[0]&1 == 1, nil
+	case FormatUTF8:
+		return string(bytes.TrimRight(b, "\x00")), nil
+	case FormatUTF16:
+		if len(b)%2 != 0 {
+			return nil, bluez.ErrInvalidValueLength
+		}
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(b[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	case FormatFloat32, FormatFloat64, FormatSFloat, FormatFloat:
+		return f.DecodeFloat(b)
+	}
+
+	i, ok := integerFormats[f.Format]
+	if !ok {
+		return append([]byte{}, b...), nil
+	}
+	if f.Exponent != 0 {
+		return f.DecodeFloat(b)
+	}
+	if i.signed {
+		return f.signed(b, i), nil
+	}
+	return f.unsigned(b, i), nil
+}
+
+//EncodeDateTime encode a date_time field: the year on 2 bytes, the month,
+// day, hours, minutes and seconds. The zero time is encoded as not known
+func EncodeDateTime(t time.Time) []byte {
+	b := make([]byte, 7)
+	if t.IsZero() {
+		return b
+	}
+	binary.LittleEndian.PutUint16(b, uint16(t.Year()))
+	b[2] = byte(t.Month())
+	b[3] = byte(t.Day())
+	b[4] = byte(t.Hour())
+	b[5] = byte(t.Minute())
+	b[6] = byte(t.Second())
+	return b
+}
+
+//DecodeDateTime decode a date_time field, local to loc. The zero time is
+// returned when the date is not known
+func DecodeDateTime(b []byte, loc *time.Location) (time.Time, error) {
+	if len(b) < 7 {
+		return time.Time{}, bluez.ErrInvalidValueLength
+	}
+	year := int(binary.LittleEndian.Uint16(b))
+	if year == 0 || b[2] == 0 || b[3] == 0 {
+		return time.Time{}, nil
+	}
+	return time.Date(year, time.Month(b[2]), int(b[3]), int(b[4]), int(b[5]), int(b[6]), 0, loc), nil
+}
+
+//EncodeDuration encode a duration as an unsigned integer of size bytes in
+// units of resolution, eg. the Measurement Interval in seconds on 2 bytes
+func EncodeDuration(d time.Duration, resolution time.Duration, size int) ([]byte, error) {
+	n := (d + resolution/2) / resolution
+	if n < 0 || (size < 8 && uint64(n) >= 1<<uint(8*size)) {
+		return nil, fmt.Errorf("Duration %s out of range", d)
+	}
+	return EncodeUint(uint64(n), size), nil
+}
+
+//DecodeDuration decode an unsigned integer in units of resolution
+func DecodeDuration(b []byte, resolution time.Duration) time.Duration {
+	return time.Duration(DecodeUint(b)) * resolution
+}
+
+//ValidateFormat accept the values of the format from min to max, in the unit
+// of DecodeFloat. The values of the wrong length are rejected with
+// bluez.ErrInvalidValueLength, see GattCharacteristic1.SetValidator
+func ValidateFormat(format PresentationFormat, min float64, max float64) service.CharacteristicValidator {
+	return func(value []byte, options map[string]interface{}) error {
+		v, err := format.DecodeFloat(value)
+		if err != nil {
+			return err
+		}
+		if math.IsNaN(v) || v < min || v > max {
+			return service.ErrOutOfRange
+		}
+		return nil
+	}
+}
+
+//FormattedValue a characteristic whose value is encoded in a presentation
+// format, exposed with a Characteristic Presentation Format descriptor. The
+// value is read, written and notified in its Go type, see
+// PresentationFormat.Decode
+type FormattedValue struct {
+	Format PresentationFormat
+	char   *service.GattCharacteristic1
+
+	mutex   sync.Mutex
+	value   []byte
+	onWrite func(value interface{}) error
+}
+
+//NewFormattedValue add a characteristic with a presentation format to srv.
+// It is writable if flags contain a write flag, the writes are decoded and
+// passed to the function of OnWrite. The value starts at zero
+func NewFormattedValue(srv *service.GattService1, uuid string, flags []string, format PresentationFormat) (*FormattedValue, error) {
+
+	v := &FormattedValue{
+		Format: format,
+		value:  make([]byte, format.Size()),
+	}
+
+	read := func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+		v.mutex.Lock()
+		defer v.mutex.Unlock()
+		return readOffset(v.value, options)
+	}
+
+	var err error
+	if hasWriteFlag(flags) {
+		v.char, err = addWritable(srv, uuid, flags, read, v.write)
+	} else {
+		v.char, err = addCharacteristic(srv, uuid, flags, read)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = addDescriptor(v.char, PresentationFormatUUID, format.Bytes()); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func hasWriteFlag(flags []string) bool {
+	for _, f := range flags {
+		switch f {
+		case bluez.FlagCharacteristicWrite, bluez.FlagCharacteristicWriteWithoutResponse,
+			bluez.FlagCharacteristicAuthenticatedSignedWrites, bluez.FlagCharacteristicReliableWrite,
+			bluez.FlagCharacteristicEncryptWrite, bluez.FlagCharacteristicEncryptAuthenticatedWrite,
+			bluez.FlagCharacteristicSecureWrite:
+			return true
+		}
+	}
+	return false
+}
+
+func (v *FormattedValue) write(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+
+	decoded, err := v.Format.Decode(value)
+	if err != nil {
+		return err
+	}
+
+	v.mutex.Lock()
+	onWrite := v.onWrite
+	v.mutex.Unlock()
+
+	if onWrite != nil {
+		if err := onWrite(decoded); err != nil {
+			return err
+		}
+	}
+
+	v.mutex.Lock()
+	v.value = append([]byte{}, value...)
+	v.mutex.Unlock()
+	return nil
+}
+
+//Characteristic return the GATT characteristic
+func (v *FormattedValue) Characteristic() *service.GattCharacteristic1 {
+	return v.char
+}
+
+//OnWrite handle the values written by the clients, an error rejects the
+// write
+func (v *FormattedValue) OnWrite(fn func(value interface{}) error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.onWrite = fn
+}
+
+//Get return the value, see PresentationFormat.Decode
+func (v *FormattedValue) Get() (interface{}, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.Format.Decode(v.value)
+}
+
+//Float return a numeric value, see PresentationFormat.DecodeFloat
+func (v *FormattedValue) Float() (float64, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.Format.DecodeFloat(v.value)
+}
+
+//Set the value, see PresentationFormat.Encode. The subscribers are notified
+// if the value changed
+func (v *FormattedValue) Set(value interface{}) error {
+
+	b, err := v.Format.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	v.mutex.Lock()
+	changed := !bytes.Equal(v.value, b)
+	v.value = b
+	v.mutex.Unlock()
+
+	if changed {
+		v.char.UpdateValue(b)
+	}
+	return nil
+}
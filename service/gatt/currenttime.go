@@ -0,0 +1,208 @@
+package gatt
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Current Time Service UUIDs
+var (
+	CurrentTimeServiceUUID = UUID("1805")
+	CurrentTimeUUID        = UUID("2A2B")
+	LocalTimeInfoUUID      = UUID("2A0F")
+)
+
+// Adjust reasons of the Current Time, a bitmask
+const (
+	AdjustManualUpdate      uint8 = 1 << 0
+	AdjustExternalReference uint8 = 1 << 1
+	AdjustTimeZoneChange    uint8 = 1 << 2
+	AdjustDSTChange         uint8 = 1 << 3
+)
+
+//DSTUnknown the DST offset of the Local Time Information when it is not
+// known
+const DSTUnknown = 0xFF
+
+//CurrentTimeWatchInterval the clock is checked for changes at this interval
+const CurrentTimeWatchInterval = time.Second
+
+//CurrentTime the value of the Current Time characteristic
+type CurrentTime struct {
+	Time time.Time
+	// AdjustReason why the time changed, a bitmask of Adjust*
+	AdjustReason uint8
+}
+
+//Bytes encode the Exact Time 256 and the adjust reason
+func (c CurrentTime) Bytes() []byte {
+	t := c.Time
+	b := make([]byte, 10)
+	copy(b, EncodeDateTime(t))
+	// 1 for monday to 7 for sunday
+	b[7] = byte((int(t.Weekday())+6)%7 + 1)
+	b[8] = byte(t.Nanosecond() * 256 / int(time.Second))
+	b[9] = c.AdjustReason
+	return b
+}
+
+//ParseCurrentTime decode a Current Time value, the date and time are local
+// to loc
+func ParseCurrentTime(b []byte, loc *time.Location) (*CurrentTime, error) {
+	if len(b) < 10 {
+		return nil, errors.New("Invalid Current Time value")
+	}
+	t, err := DecodeDateTime(b, loc)
+	if err != nil {
+		return nil, err
+	}
+	if t.IsZero() {
+		return nil, errors.New("Current Time not known by the server")
+	}
+	nsec := int(b[8]) * int(time.Second) / 256
+	return &CurrentTime{
+		Time:         t.Add(time.Duration(nsec)),
+		AdjustReason: b[9],
+	}, nil
+}
+
+// localTimeInfo encode the time zone of t, in units of 15 minutes. The
+// standard offset is not known apart from DST, the whole offset is sent
+func localTimeInfo(t time.Time) []byte {
+	_, offset := t.Zone()
+	return []byte{byte(int8(offset / (15 * 60))), DSTUnknown}
+}
+
+//CurrentTimeService the Current Time Service (0x1805) serving the local
+// clock. The subscribers are notified when the clock or the time zone
+// changes
+type CurrentTimeService struct {
+	srv     *service.GattService1
+	current *service.GattCharacteristic1
+	mutex   sync.Mutex
+	done    chan struct{}
+}
+
+//NewCurrentTimeService add a Current Time Service to a running application,
+// the clock is watched until Close
+func NewCurrentTimeService(app *service.Application) (*CurrentTimeService, error) {
+
+	c := &CurrentTimeService{done: make(chan struct{})}
+
+	srv, err := addService(app, CurrentTimeServiceUUID)
+	if err != nil {
+		return nil, err
+	}
+	c.srv = srv
+
+	c.current, err = addCharacteristic(srv, CurrentTimeUUID,
+		[]string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicNotify},
+		func(char *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+			return CurrentTime{Time: time.Now()}.Bytes(), nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = addCharacteristic(srv, LocalTimeInfoUUID,
+		[]string{bluez.FlagCharacteristicRead},
+		func(char *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+			return localTimeInfo(time.Now()), nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	go c.watch(CurrentTimeWatchInterval)
+	return c, nil
+}
+
+//Service return the GATT service
+func (c *CurrentTimeService) Service() *service.GattService1 {
+	return c.srv
+}
+
+//TimeChanged notify the current time to the subscribers, eg. after the clock
+// was set from an external reference
+func (c *CurrentTimeService) TimeChanged(reason uint8) {
+	c.current.UpdateValue(CurrentTime{Time: time.Now(), AdjustReason: reason}.Bytes())
+}
+
+//Close stop watching the clock
+func (c *CurrentTimeService) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+}
+
+// watch detect the changes of the wall clock, comparing it with the
+// monotonic clock, and of the time zone
+func (c *CurrentTimeService) watch(interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	_, lastOffset := last.Zone()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		_, offset := now.Zone()
+
+		// Sub uses the monotonic readings, the wall clock is compared
+		// after stripping them
+		drift := now.Round(0).Sub(last.Round(0)) - now.Sub(last)
+		if drift < 0 {
+			drift = -drift
+		}
+
+		var reason uint8
+		if drift > time.Second {
+			reason |= AdjustManualUpdate
+		}
+		if offset != lastOffset {
+			reason |= AdjustTimeZoneChange
+		}
+		if reason != 0 {
+			c.TimeChanged(reason)
+		}
+
+		last = now
+		lastOffset = offset
+	}
+}
+
+//ReadCurrentTime read the Current Time characteristic of a server, eg. a
+// phone, to set the clock of the peripheral
+func ReadCurrentTime(char *profile.GattCharacteristic1) (*CurrentTime, error) {
+	b, err := char.ReadValue(nil)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCurrentTime(b, time.Local)
+}
+
+//SyncCurrentTime read the current time of a connected device exposing the
+// Current Time Service, the time is returned to be applied by the caller
+func SyncCurrentTime(dev *api.Device) (*CurrentTime, error) {
+	char, err := dev.GetCharByUUID(CurrentTimeUUID)
+	if err != nil {
+		return nil, err
+	}
+	return ReadCurrentTime(char)
+}
@@ -0,0 +1,105 @@
+package gatt
+
+import (
+	"encoding/binary"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Device Information Service UUIDs
+var (
+	DeviceInformationUUID = UUID("180A")
+	ManufacturerNameUUID  = UUID("2A29")
+	ModelNumberUUID       = UUID("2A24")
+	SerialNumberUUID      = UUID("2A25")
+	HardwareRevisionUUID  = UUID("2A27")
+	FirmwareRevisionUUID  = UUID("2A26")
+	SoftwareRevisionUUID  = UUID("2A28")
+	PnPIDUUID             = UUID("2A50")
+)
+
+// Sources of the vendor ID of a PnP ID
+const (
+	VendorIDSourceBluetooth uint8 = 0x01
+	VendorIDSourceUSB       uint8 = 0x02
+)
+
+//PnPID the PnP ID characteristic, identifying the product eg. for HID hosts
+type PnPID struct {
+	// VendorIDSource VendorIDSourceBluetooth for a company identifier of the
+	// Bluetooth SIG or VendorIDSourceUSB for a USB vendor ID
+	VendorIDSource uint8
+	VendorID       uint16
+	ProductID      uint16
+	ProductVersion uint16
+}
+
+//Bytes encode the characteristic value
+func (p PnPID) Bytes() []byte {
+	b := make([]byte, 7)
+	b[0] = p.VendorIDSource
+	binary.LittleEndian.PutUint16(b[1:], p.VendorID)
+	binary.LittleEndian.PutUint16(b[3:], p.ProductID)
+	binary.LittleEndian.PutUint16(b[5:], p.ProductVersion)
+	return b
+}
+
+//DeviceInformation the values of the Device Information Service, the empty
+// strings are not exposed
+type DeviceInformation struct {
+	Manufacturer     string
+	Model            string
+	Serial           string
+	HardwareRevision string
+	FirmwareRevision string
+	SoftwareRevision string
+	// PnPID optional
+	PnPID *PnPID
+}
+
+//NewDeviceInformationService add a Device Information Service (0x180A) to a
+// running application, with a read only characteristic for each value
+func NewDeviceInformationService(app *service.Application, info DeviceInformation) (*service.GattService1, error) {
+
+	srv, err := addService(app, DeviceInformationUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []struct {
+		uuid  string
+		value string
+	}{
+		{ManufacturerNameUUID, info.Manufacturer},
+		{ModelNumberUUID, info.Model},
+		{SerialNumberUUID, info.Serial},
+		{HardwareRevisionUUID, info.HardwareRevision},
+		{FirmwareRevisionUUID, info.FirmwareRevision},
+		{SoftwareRevisionUUID, info.SoftwareRevision},
+	}
+	for _, v := range values {
+		if v.value == "" {
+			continue
+		}
+		if _, err = addReadOnly(srv, v.uuid, []byte(v.value)); err != nil {
+			return nil, err
+		}
+	}
+
+	if info.PnPID != nil {
+		if _, err = addReadOnly(srv, PnPIDUUID, info.PnPID.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	return srv, nil
+}
+
+// addReadOnly add a read only characteristic with a fixed value
+func addReadOnly(srv *service.GattService1, uuid string, value []byte) (*service.GattCharacteristic1, error) {
+	return addCharacteristic(srv, uuid, []string{bluez.FlagCharacteristicRead},
+		func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+			return value, nil
+		})
+}
@@ -0,0 +1,341 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Environmental Sensing Service UUIDs
+var (
+	EnvironmentalSensingUUID = UUID("181A")
+	// ESMeasurementUUID the ES Measurement descriptor
+	ESMeasurementUUID = UUID("290C")
+	// ESTriggerSettingUUID the ES Trigger Setting descriptor
+	ESTriggerSettingUUID = UUID("290D")
+)
+
+//SensorKind a characteristic of the Environmental Sensing Service, the value
+// is sent as a fixed point number of Size bytes: value / 10^Exponent
+type SensorKind struct {
+	UUID     string
+	Size     int
+	Signed   bool
+	Exponent int
+}
+
+// Sensor kinds, with the units of the values passed to Set
+var (
+	// Temperature in degrees Celsius
+	Temperature = SensorKind{UUID: UUID("2A6E"), Size: 2, Signed: true, Exponent: -2}
+	// Humidity in percent
+	Humidity = SensorKind{UUID: UUID("2A6F"), Size: 2, Exponent: -2}
+	// Pressure in Pascal
+	Pressure = SensorKind{UUID: UUID("2A6D"), Size: 4, Exponent: -1}
+	// Elevation in meters
+	Elevation = SensorKind{UUID: UUID("2A6C"), Size: 3, Signed: true, Exponent: -2}
+	// TrueWindSpeed in meters per second
+	TrueWindSpeed = SensorKind{UUID: UUID("2A70"), Size: 2, Exponent: -2}
+	// UVIndex unitless
+	UVIndex = SensorKind{UUID: UUID("2A76"), Size: 1}
+	// Irradiance in watts per square meter
+	Irradiance = SensorKind{UUID: UUID("2A77"), Size: 2, Exponent: -1}
+	// Rainfall in meters
+	Rainfall = SensorKind{UUID: UUID("2A78"), Size: 2, Exponent: -3}
+	// DewPoint in degrees Celsius
+	DewPoint = SensorKind{UUID: UUID("2A7B"), Size: 1, Signed: true}
+)
+
+//Encode a value as the fixed point number of the characteristic, the value
+// is clamped to the range of the encoding
+func (k SensorKind) Encode(value float64) []byte {
+
+	bits := uint(k.Size * 8)
+	v := math.Floor(value/math.Pow10(k.Exponent) + 0.5)
+
+	var min, max float64
+	if k.Signed {
+		min = -math.Exp2(float64(bits - 1))
+		max = math.Exp2(float64(bits-1)) - 1
+	} else {
+		max = math.Exp2(float64(bits)) - 1
+	}
+	if v < min {
+		v = min
+	}
+	if v > max {
+		v = max
+	}
+
+	n := uint64(int64(v))
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, n)
+	return buf[:k.Size]
+}
+
+// Sampling functions of the ES Measurement descriptor
+const (
+	SamplingUnspecified    uint8 = 0x00
+	SamplingInstantaneous  uint8 = 0x01
+	SamplingArithmeticMean uint8 = 0x02
+	SamplingRMS            uint8 = 0x03
+	SamplingMaximum        uint8 = 0x04
+	SamplingMinimum        uint8 = 0x05
+)
+
+// Applications of the ES Measurement descriptor
+const (
+	ApplicationUnspecified uint8 = 0x00
+	ApplicationAir         uint8 = 0x01
+	ApplicationWater       uint8 = 0x02
+	ApplicationOutside     uint8 = 0x0D
+	ApplicationInside      uint8 = 0x0E
+)
+
+//ESMeasurement the ES Measurement descriptor, describing how the values are
+// measured
+type ESMeasurement struct {
+	SamplingFunction uint8
+	// MeasurementPeriod and UpdateInterval in seconds, up to 24 bits
+	MeasurementPeriod uint32
+	UpdateInterval    uint32
+	Application       uint8
+	// Uncertainty in units of 0.5%, 0xFF if unknown
+	Uncertainty uint8
+}
+
+//Bytes encode the descriptor value
+func (m ESMeasurement) Bytes() []byte {
+	b := make([]byte, 11)
+	b[2] = m.SamplingFunction
+	put24(b[3:], m.MeasurementPeriod)
+	put24(b[6:], m.UpdateInterval)
+	b[9] = m.Application
+	b[10] = m.Uncertainty
+	return b
+}
+
+func put24(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
+
+// Conditions of the ES Trigger Setting descriptor
+const (
+	TriggerInactive       uint8 = 0x00
+	TriggerFixedInterval  uint8 = 0x01
+	TriggerNoLessThan     uint8 = 0x02
+	TriggerValueChanged   uint8 = 0x03
+	TriggerLessThan       uint8 = 0x04
+	TriggerLessOrEqual    uint8 = 0x05
+	TriggerGreaterThan    uint8 = 0x06
+	TriggerGreaterOrEqual uint8 = 0x07
+	TriggerEqual          uint8 = 0x08
+	TriggerNotEqual       uint8 = 0x09
+)
+
+//Trigger when the value of a sensor is notified, exposed as the ES Trigger
+// Setting descriptor
+type Trigger struct {
+	Condition uint8
+	// Interval of TriggerFixedInterval and TriggerNoLessThan, sent in
+	// seconds
+	Interval time.Duration
+	// Threshold the value the conditions from TriggerLessThan compare to
+	Threshold float64
+}
+
+//Bytes encode the descriptor value for a sensor kind
+func (t Trigger) Bytes(kind SensorKind) []byte {
+	switch t.Condition {
+	case TriggerFixedInterval, TriggerNoLessThan:
+		b := make([]byte, 4)
+		b[0] = t.Condition
+		put24(b[1:], uint32(t.Interval/time.Second))
+		return b
+	case TriggerInactive, TriggerValueChanged:
+		return []byte{t.Condition}
+	}
+	return append([]byte{t.Condition}, kind.Encode(t.Threshold)...)
+}
+
+// match return true if the condition on the value is met
+func (t Trigger) match(value float64) bool {
+	switch t.Condition {
+	case TriggerLessThan:
+		return value < t.Threshold
+	case TriggerLessOrEqual:
+		return value <= t.Threshold
+	case TriggerGreaterThan:
+		return value > t.Threshold
+	case TriggerGreaterOrEqual:
+		return value >= t.Threshold
+	case TriggerEqual:
+		return value == t.Threshold
+	case TriggerNotEqual:
+		return value != t.Threshold
+	}
+	return false
+}
+
+//SensorOptions configure a sensor of the Environmental Sensing Service
+type SensorOptions struct {
+	// Measurement optional ES Measurement descriptor
+	Measurement *ESMeasurement
+	// Trigger optional, each new value is notified without a trigger
+	Trigger *Trigger
+}
+
+//Sensor a characteristic of the Environmental Sensing Service
+type Sensor struct {
+	kind    SensorKind
+	trigger *Trigger
+	char    *service.GattCharacteristic1
+
+	mutex    sync.Mutex
+	value    float64
+	encoded  []byte
+	notified time.Time
+}
+
+//EnvironmentalSensingService the Environmental Sensing Service (0x181A)
+type EnvironmentalSensingService struct {
+	srv     *service.GattService1
+	mutex   sync.Mutex
+	sensors []*Sensor
+	done    chan struct{}
+}
+
+//NewEnvironmentalSensingService add an Environmental Sensing Service to a
+// running application, the sensors are added with AddSensor
+func NewEnvironmentalSensingService(app *service.Application) (*EnvironmentalSensingService, error) {
+	srv, err := addService(app, EnvironmentalSensingUUID)
+	if err != nil {
+		return nil, err
+	}
+	return &EnvironmentalSensingService{
+		srv:  srv,
+		done: make(chan struct{}),
+	}, nil
+}
+
+//Service return the GATT service
+func (e *EnvironmentalSensingService) Service() *service.GattService1 {
+	return e.srv
+}
+
+//AddSensor add a characteristic for a sensor. The value is notified at the
+// interval of a TriggerFixedInterval trigger, until Close
+func (e *EnvironmentalSensingService) AddSensor(kind SensorKind, opts SensorOptions) (*Sensor, error) {
+
+	s := &Sensor{
+		kind:    kind,
+		trigger: opts.Trigger,
+		encoded: kind.Encode(0),
+	}
+
+	char, err := addCharacteristic(e.srv, kind.UUID,
+		[]string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicNotify},
+		func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			return s.encoded, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	s.char = char
+
+	if opts.Measurement != nil {
+		if _, err = addDescriptor(char, ESMeasurementUUID, opts.Measurement.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Trigger != nil {
+		if _, err = addDescriptor(char, ESTriggerSettingUUID, opts.Trigger.Bytes(kind)); err != nil {
+			return nil, err
+		}
+		if opts.Trigger.Condition == TriggerFixedInterval && opts.Trigger.Interval > 0 {
+			go s.schedule(opts.Trigger.Interval, e.done)
+		}
+	}
+
+	e.mutex.Lock()
+	e.sensors = append(e.sensors, s)
+	e.mutex.Unlock()
+
+	return s, nil
+}
+
+//Close stop the periodic notifications
+func (e *EnvironmentalSensingService) Close() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	select {
+	case <-e.done:
+	default:
+		close(e.done)
+	}
+}
+
+// schedule notify the value at each interval
+func (s *Sensor) schedule(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.mutex.Lock()
+			value := s.encoded
+			s.mutex.Unlock()
+			s.char.UpdateValue(value)
+		}
+	}
+}
+
+//Value return the last value set
+func (s *Sensor) Value() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.value
+}
+
+//Set the value of the sensor, in the unit of its kind. It is notified when
+// the trigger condition is met
+func (s *Sensor) Set(value float64) {
+
+	s.mutex.Lock()
+	encoded := s.kind.Encode(value)
+	changed := string(encoded) != string(s.encoded)
+	s.value = value
+	s.encoded = encoded
+
+	notify := true
+	if s.trigger != nil {
+		switch s.trigger.Condition {
+		case TriggerInactive, TriggerFixedInterval:
+			notify = false
+		case TriggerValueChanged:
+			notify = changed
+		case TriggerNoLessThan:
+			notify = changed && time.Since(s.notified) >= s.trigger.Interval
+		default:
+			notify = s.trigger.match(value)
+		}
+	}
+	if notify {
+		s.notified = time.Now()
+	}
+	s.mutex.Unlock()
+
+	if notify {
+		s.char.UpdateValue(encoded)
+	}
+}
@@ -0,0 +1,227 @@
+//Package gatt ready-made standard GATT services for the peripheral, built on
+// the service package
+package gatt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+//UUID return the 128bit form of a 16bit UUID of the Bluetooth SIG, eg. 180F
+func UUID(short string) string {
+	return bluetooth.MustParseUUID(short).String()
+}
+
+// Units of the presentation format descriptor, from the assigned numbers
+const (
+	UnitUnitless          uint16 = 0x2700
+	UnitMetre             uint16 = 0x2701
+	UnitKilogram          uint16 = 0x2702
+	UnitSecond            uint16 = 0x2703
+	UnitAmpere            uint16 = 0x2704
+	UnitKelvin            uint16 = 0x2705
+	UnitHertz             uint16 = 0x2722
+	UnitPascal            uint16 = 0x2724
+	UnitWatt              uint16 = 0x2726
+	UnitVolt              uint16 = 0x2728
+	UnitDegreeCelsius     uint16 = 0x272F
+	UnitPercentage        uint16 = 0x27AD
+	UnitBeatsPerMinute    uint16 = 0x27A7
+	UnitDecibelMilliwatts uint16 = 0x27C3
+)
+
+// Formats of the presentation format descriptor
+const (
+	FormatBoolean uint8 = 0x01
+	Format2Bit    uint8 = 0x02
+	FormatNibble  uint8 = 0x03
+	FormatUint8   uint8 = 0x04
+	FormatUint12  uint8 = 0x05
+	FormatUint16  uint8 = 0x06
+	FormatUint24  uint8 = 0x07
+	FormatUint32  uint8 = 0x08
+	FormatUint48  uint8 = 0x09
+	FormatUint64  uint8 = 0x0A
+	FormatUint128 uint8 = 0x0B
+	FormatSint8   uint8 = 0x0C
+	FormatSint12  uint8 = 0x0D
+	FormatSint16  uint8 = 0x0E
+	FormatSint24  uint8 = 0x0F
+	FormatSint32  uint8 = 0x10
+	FormatSint48  uint8 = 0x11
+	FormatSint64  uint8 = 0x12
+	FormatSint128 uint8 = 0x13
+	FormatFloat32 uint8 = 0x14
+	FormatFloat64 uint8 = 0x15
+	// FormatSFloat and FormatFloat the IEEE 11073-20601 floats, see SFloat
+	// and Float
+	FormatSFloat  uint8 = 0x16
+	FormatFloat   uint8 = 0x17
+	FormatDUint16 uint8 = 0x18
+	FormatUTF8    uint8 = 0x19
+	FormatUTF16   uint8 = 0x1A
+	FormatStruct  uint8 = 0x1B
+)
+
+// formatNames the short names of the formats, from the assigned numbers
+var formatNames = map[uint8]string{
+	FormatBoolean: "boolean", Format2Bit: "2bit", FormatNibble: "nibble",
+	FormatUint8: "uint8", FormatUint12: "uint12", FormatUint16: "uint16", FormatUint24: "uint24",
+	FormatUint32: "uint32", FormatUint48: "uint48", FormatUint64: "uint64", FormatUint128: "uint128",
+	FormatSint8: "sint8", FormatSint12: "sint12", FormatSint16: "sint16", FormatSint24: "sint24",
+	FormatSint32: "sint32", FormatSint48: "sint48", FormatSint64: "sint64", FormatSint128: "sint128",
+	FormatFloat32: "float32", FormatFloat64: "float64", FormatSFloat: "SFLOAT", FormatFloat: "FLOAT",
+	FormatDUint16: "duint16", FormatUTF8: "utf8s", FormatUTF16: "utf16s", FormatStruct: "struct",
+}
+
+//FormatName return the short name of a format, eg. uint16, empty if unknown
+func FormatName(format uint8) string {
+	return formatNames[format]
+}
+
+//ParseFormat return the format of a short name, eg. uint16 or sfloat, the
+// case is ignored
+func ParseFormat(name string) (uint8, error) {
+	for format, n := range formatNames {
+		if strings.EqualFold(n, name) {
+			return format, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown format %s", name)
+}
+
+//PresentationFormat the value of a Characteristic Presentation Format
+// descriptor (0x2904)
+type PresentationFormat struct {
+	Format   uint8
+	Exponent int8
+	Unit     uint16
+	// Namespace 0x01 for the Bluetooth SIG
+	Namespace   uint8
+	Description uint16
+}
+
+//Bytes encode the descriptor value
+func (f PresentationFormat) Bytes() []byte {
+	b := make([]byte, 7)
+	b[0] = f.Format
+	b[1] = byte(f.Exponent)
+	binary.LittleEndian.PutUint16(b[2:], f.Unit)
+	b[4] = f.Namespace
+	binary.LittleEndian.PutUint16(b[5:], f.Description)
+	return b
+}
+
+//ParsePresentationFormat decode the value of a Characteristic Presentation
+// Format descriptor, eg. read from a remote characteristic
+func ParsePresentationFormat(b []byte) (*PresentationFormat, error) {
+	if len(b) != 7 {
+		return nil, bluez.ErrInvalidValueLength
+	}
+	return &PresentationFormat{
+		Format:      b[0],
+		Exponent:    int8(b[1]),
+		Unit:        binary.LittleEndian.Uint16(b[2:]),
+		Namespace:   b[4],
+		Description: binary.LittleEndian.Uint16(b[5:]),
+	}, nil
+}
+
+// addService create and add a primary service, optionally advertised
+func addService(app *service.Application, uuid string, advertise ...bool) (*service.GattService1, error) {
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: true,
+		UUID:    uuid,
+	}, advertise...)
+	if err != nil {
+		return nil, err
+	}
+	err = app.AddService(srv)
+	if err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+// addCharacteristic create and add a characteristic handled by read, if set
+func addCharacteristic(srv *service.GattService1, uuid string, flags []string, read service.CharacteristicReadFunc) (*service.GattCharacteristic1, error) {
+	char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID:  uuid,
+		Flags: flags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if read != nil {
+		char.SetReadFunc(read)
+	}
+	err = srv.AddCharacteristic(char)
+	if err != nil {
+		return nil, err
+	}
+	return char, nil
+}
+
+// addWritable add a characteristic handled by write and read, if set
+func addWritable(srv *service.GattService1, uuid string, flags []string, read service.CharacteristicReadFunc, write service.CharacteristicWriteFunc) (*service.GattCharacteristic1, error) {
+	char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID:  uuid,
+		Flags: flags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if read != nil {
+		char.SetReadFunc(read)
+	}
+	char.SetWriteFunc(write)
+	err = srv.AddCharacteristic(char)
+	if err != nil {
+		return nil, err
+	}
+	return char, nil
+}
+
+// addDescriptor add a read only descriptor with a fixed value
+func addDescriptor(char *service.GattCharacteristic1, uuid string, value []byte) (*service.GattDescriptor1, error) {
+	desc, err := char.CreateDescriptor(&profile.GattDescriptor1Properties{
+		UUID:  uuid,
+		Flags: []string{bluez.FlagDescriptorRead},
+		Value: value,
+	})
+	if err != nil {
+		return nil, err
+	}
+	desc.SetReadFunc(func(d *service.GattDescriptor1, options map[string]interface{}) ([]byte, error) {
+		return value, nil
+	})
+	err = char.AddDescriptor(desc)
+	if err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+// addWritableDescriptor add a descriptor handled by read and write
+func addWritableDescriptor(char *service.GattCharacteristic1, uuid string, read service.DescriptorReadFunc, write service.DescriptorWriteFunc) (*service.GattDescriptor1, error) {
+	desc, err := char.CreateDescriptor(&profile.GattDescriptor1Properties{
+		UUID:  uuid,
+		Flags: []string{bluez.FlagDescriptorRead, bluez.FlagDescriptorWrite},
+	})
+	if err != nil {
+		return nil, err
+	}
+	desc.SetReadFunc(read)
+	desc.SetWriteFunc(write)
+	err = char.AddDescriptor(desc)
+	if err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
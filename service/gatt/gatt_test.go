@@ -0,0 +1,1581 @@
+package gatt
+
+import (
+	"bytes"
+	"hash/crc32"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// serve start the fake bluez and a running application, register is called
+// once the services are added
+func serve(t *testing.T) (*bluetest.Bluez, *service.Application, func() *bluetest.Application) {
+
+	b, err := bluetest.Start()
+	if err != nil {
+		t.Skipf("Cannot start the fake bluez: %s", err.Error())
+	}
+	if _, err = b.AddAdapter("hci0", "00:11:22:33:44:55"); err != nil {
+		b.Close()
+		t.Fatal(err)
+	}
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.gatttest",
+		ObjectPath: "/gatttest",
+		Logger:     bluez.NopLogger{},
+	})
+	if err != nil {
+		b.Close()
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		b.Close()
+		t.Fatal(err)
+	}
+
+	register := func() *bluetest.Application {
+		err := profile.NewGattManager1("hci0").RegisterApplication(app.Path(), map[string]interface{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b.Adapter("hci0").Applications()[0]
+	}
+	return b, app, register
+}
+
+func TestPresentationFormat(t *testing.T) {
+	b := PresentationFormat{Format: FormatSint16, Exponent: -2, Unit: 0x272F, Namespace: 1}.Bytes()
+	if !bytes.Equal(b, []byte{0x0E, 0xFE, 0x2F, 0x27, 0x01, 0x00, 0x00}) {
+		t.Fatalf("Unexpected presentation format %x", b)
+	}
+	if UUID("180f") != "0000180F-0000-1000-8000-00805F9B34FB" {
+		t.Fatalf("Unexpected UUID %s", UUID("180f"))
+	}
+}
+
+func TestCodec(t *testing.T) {
+
+	temperature := PresentationFormat{Format: FormatSint16, Exponent: -2, Unit: UnitDegreeCelsius, Namespace: 1}
+	b, err := temperature.Encode(-12.34)
+	if err != nil || !bytes.Equal(b, []byte{0x2e, 0xfb}) {
+		t.Fatalf("Unexpected encoding %x %v", b, err)
+	}
+	if v, err := temperature.Decode(b); err != nil || math.Abs(v.(float64)+12.34) > 1e-9 {
+		t.Fatalf("Unexpected value %v %v", v, err)
+	}
+	if _, err = temperature.Encode(400.0); err == nil {
+		t.Fatal("Expected a value out of range")
+	}
+	if _, err = temperature.Decode([]byte{0x01}); err != bluez.ErrInvalidValueLength {
+		t.Fatalf("Expected an invalid length, got %v", err)
+	}
+
+	cases := []struct {
+		format  uint8
+		value   interface{}
+		encoded []byte
+		decoded interface{}
+	}{
+		{FormatBoolean, true, []byte{1}, true},
+		{FormatUint8, 200, []byte{200}, uint64(200)},
+		{FormatUint12, uint16(0xabc), []byte{0xbc, 0x0a}, uint64(0xabc)},
+		{FormatUint24, uint32(0x123456), []byte{0x56, 0x34, 0x12}, uint64(0x123456)},
+		{FormatUint48, uint64(0x0102030405), []byte{5, 4, 3, 2, 1, 0}, uint64(0x0102030405)},
+		{FormatSint24, -2, []byte{0xfe, 0xff, 0xff}, int64(-2)},
+		{FormatSint12, int16(-2048), []byte{0x00, 0x08}, int64(-2048)},
+		{FormatFloat32, 1.5, []byte{0, 0, 0xc0, 0x3f}, 1.5},
+		{FormatSFloat, 36.6, []byte{0x6e, 0xf1}, 36.6},
+		{FormatUTF8, "hello", []byte("hello"), "hello"},
+		{FormatUTF16, "hé", []byte{'h', 0, 0xe9, 0}, "hé"},
+	}
+	for _, c := range cases {
+		f := PresentationFormat{Format: c.format}
+		b, err := f.Encode(c.value)
+		if err != nil || !bytes.Equal(b, c.encoded) {
+			t.Fatalf("Unexpected encoding of %v in 0x%02X: %x %v", c.value, c.format, b, err)
+		}
+		v, err := f.Decode(b)
+		if err != nil || v != c.decoded {
+			t.Fatalf("Unexpected decoding of %x in 0x%02X: %v %v", b, c.format, v, err)
+		}
+	}
+	for format, value := range map[uint8]interface{}{FormatUint8: 256, FormatUint12: 4096, FormatSint8: -129, FormatUint16: -1} {
+		if _, err := (PresentationFormat{Format: format}).Encode(value); err == nil {
+			t.Fatalf("Expected %v out of the range of 0x%02X", value, format)
+		}
+	}
+
+	parsed, err := ParsePresentationFormat(temperature.Bytes())
+	if err != nil || *parsed != temperature {
+		t.Fatalf("Unexpected presentation format %+v %v", parsed, err)
+	}
+
+	loc := time.FixedZone("CET", 3600)
+	now := time.Date(2024, time.March, 10, 14, 30, 15, 0, loc)
+	if parsed, err := DecodeDateTime(EncodeDateTime(now), loc); err != nil || !parsed.Equal(now) {
+		t.Fatalf("Unexpected date time %s %v", parsed, err)
+	}
+	if parsed, err := DecodeDateTime(EncodeDateTime(time.Time{}), loc); err != nil || !parsed.IsZero() {
+		t.Fatalf("Unexpected date time %s %v", parsed, err)
+	}
+
+	d, err := EncodeDuration(90*time.Second, time.Second, 2)
+	if err != nil || !bytes.Equal(d, []byte{90, 0}) || DecodeDuration(d, time.Second) != 90*time.Second {
+		t.Fatalf("Unexpected duration %x %v", d, err)
+	}
+	if _, err = EncodeDuration(24*time.Hour, time.Second, 2); err == nil {
+		t.Fatal("Expected a duration out of range")
+	}
+}
+
+func TestFormattedValue(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	srv, err := addService(app, EnvironmentalSensingUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := PresentationFormat{Format: FormatSint16, Exponent: -2, Unit: UnitDegreeCelsius, Namespace: 1}
+	v, err := NewFormattedValue(srv, Temperature.UUID,
+		[]string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicWrite}, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := make(chan interface{}, 1)
+	v.OnWrite(func(value interface{}) error {
+		written <- value
+		return nil
+	})
+	remote := register()
+
+	if err = v.Set(21.5); err != nil {
+		t.Fatal(err)
+	}
+	value, err := remote.ReadValue(Temperature.UUID, nil)
+	if err != nil || !bytes.Equal(value, []byte{0x66, 0x08}) {
+		t.Fatalf("Unexpected value %x %v", value, err)
+	}
+
+	if err = remote.WriteValue(Temperature.UUID, []byte{0xc8, 0x00}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if w := <-written; w.(float64) != 2 {
+		t.Fatalf("Unexpected write %v", w)
+	}
+	if f, err := v.Float(); err != nil || f != 2 {
+		t.Fatalf("Unexpected value %v %v", f, err)
+	}
+	if err = remote.WriteValue(Temperature.UUID, []byte{0xc8}, nil); err == nil {
+		t.Fatal("Expected an invalid length")
+	}
+
+	v.Characteristic().SetValidator(ValidateFormat(format, -40, 85))
+	if err = remote.WriteValue(Temperature.UUID, []byte{0x28, 0x23}, nil); !bluez.IsError(err, service.ErrOutOfRange) {
+		t.Fatalf("Expected %s, got %v", service.ErrOutOfRange, err)
+	}
+	if err = remote.WriteValue(Temperature.UUID, []byte{0x01}, nil); !bluez.IsError(err, bluez.ErrInvalidValueLength) {
+		t.Fatalf("Expected %s, got %v", bluez.ErrInvalidValueLength, err)
+	}
+
+	descriptor, err := remote.ReadDescriptor(PresentationFormatUUID, nil)
+	if err != nil || !bytes.Equal(descriptor, format.Bytes()) {
+		t.Fatalf("Unexpected presentation format %x %v", descriptor, err)
+	}
+}
+
+func TestBatteryService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	battery, err := NewBatteryService(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := register()
+
+	value, err := remote.ReadValue(BatteryLevelUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{100}) {
+		t.Fatalf("Unexpected level %v", value)
+	}
+
+	if err = battery.SetLevel(42); err != nil {
+		t.Fatal(err)
+	}
+	if err = battery.SetLevel(101); err == nil {
+		t.Fatal("Level 101 should be rejected")
+	}
+	value, err = remote.ReadValue(BatteryLevelUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{42}) {
+		t.Fatalf("Unexpected level %v", value)
+	}
+
+	format, err := remote.ReadDescriptor(PresentationFormatUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(format, []byte{0x04, 0x00, 0xAD, 0x27, 0x01, 0x00, 0x00}) {
+		t.Fatalf("Unexpected presentation format %x", format)
+	}
+}
+
+func TestDeviceInformationService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	srv, err := NewDeviceInformationService(app, DeviceInformation{
+		Manufacturer:     "ACME",
+		Model:            "T-1",
+		FirmwareRevision: "1.2.3",
+		PnPID:            &PnPID{VendorIDSource: VendorIDSourceUSB, VendorID: 0x1d6b, ProductID: 0x0246, ProductVersion: 0x0100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.GetCharacteristics()) != 4 {
+		t.Fatalf("Expected 4 characteristics, got %d", len(srv.GetCharacteristics()))
+	}
+	remote := register()
+
+	value, err := remote.ReadValue(ModelNumberUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "T-1" {
+		t.Fatalf("Unexpected model %q", value)
+	}
+	if _, err = remote.ReadValue(SerialNumberUUID, nil); err == nil {
+		t.Fatal("The empty serial number should not be exposed")
+	}
+
+	value, err = remote.ReadValue(PnPIDUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{0x02, 0x6b, 0x1d, 0x46, 0x02, 0x00, 0x01}) {
+		t.Fatalf("Unexpected PnP ID %x", value)
+	}
+}
+
+func TestEncodeHeartRateMeasurement(t *testing.T) {
+
+	b := EncodeHeartRateMeasurement(72, nil, ContactNotSupported, -1)
+	if !bytes.Equal(b, []byte{0x00, 72}) {
+		t.Fatalf("Unexpected measurement %x", b)
+	}
+
+	b = EncodeHeartRateMeasurement(300, []time.Duration{time.Second, 500 * time.Millisecond}, ContactDetected, 10)
+	if !bytes.Equal(b, []byte{0x1f, 0x2c, 0x01, 0x0a, 0x00, 0x00, 0x04, 0x00, 0x02}) {
+		t.Fatalf("Unexpected measurement %x", b)
+	}
+
+	rr := make([]time.Duration, 12)
+	rr[11] = time.Second
+	b = EncodeHeartRateMeasurement(60, rr, ContactNotDetected, -1)
+	if len(b) != HeartRateMeasurementSize || b[0] != 0x14 || !bytes.Equal(b[len(b)-2:], []byte{0x00, 0x04}) {
+		t.Fatalf("Unexpected measurement %x", b)
+	}
+}
+
+func TestHeartRateService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	hrs, err := NewHeartRateService(app, int(LocationWrist), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reset := 0
+	hrs.OnResetEnergy = func() {
+		reset++
+	}
+	remote := register()
+
+	value, err := remote.ReadValue(BodySensorLocationUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{LocationWrist}) {
+		t.Fatalf("Unexpected location %v", value)
+	}
+
+	if err = remote.WriteValue(HeartRateControlPointUUID, []byte{ResetEnergyExpended}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if reset != 1 {
+		t.Fatalf("Expected 1 reset, got %d", reset)
+	}
+	err = remote.WriteValue(HeartRateControlPointUUID, []byte{0x02}, nil)
+	if !bluez.IsError(err, bluez.ErrFailed) {
+		t.Fatalf("Expected %s, got %v", bluez.ErrFailed, err)
+	}
+
+	hrs.SendMeasurement(80, nil, ContactDetected, -1)
+}
+
+func TestSensorKindEncode(t *testing.T) {
+	cases := []struct {
+		kind     SensorKind
+		value    float64
+		expected []byte
+	}{
+		{Temperature, 21.5, []byte{0x66, 0x08}},
+		{Temperature, -10.25, []byte{0xff, 0xfb}},
+		{Humidity, 45.67, []byte{0xd7, 0x11}},
+		{Pressure, 101325, []byte{0x02, 0x76, 0x0f, 0x00}},
+		{Elevation, -1, []byte{0x9c, 0xff, 0xff}},
+		{UVIndex, 300, []byte{0xff}},
+		{DewPoint, -200, []byte{0x80}},
+	}
+	for _, c := range cases {
+		if b := c.kind.Encode(c.value); !bytes.Equal(b, c.expected) {
+			t.Errorf("%s %v: expected %x, got %x", c.kind.UUID, c.value, c.expected, b)
+		}
+	}
+}
+
+func TestEnvironmentalSensingService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	ess, err := NewEnvironmentalSensingService(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ess.Close()
+
+	temp, err := ess.AddSensor(Temperature, SensorOptions{
+		Measurement: &ESMeasurement{SamplingFunction: SamplingInstantaneous, UpdateInterval: 60, Application: ApplicationAir, Uncertainty: 0xff},
+		Trigger:     &Trigger{Condition: TriggerGreaterThan, Threshold: 30},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ess.AddSensor(Humidity, SensorOptions{
+		Trigger: &Trigger{Condition: TriggerFixedInterval, Interval: time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := register()
+
+	temp.Set(21.5)
+	value, err := remote.ReadValue(Temperature.UUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{0x66, 0x08}) {
+		t.Fatalf("Unexpected temperature %x", value)
+	}
+
+	value, err = remote.ReadDescriptor(ESMeasurementUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{0, 0, 0x01, 0, 0, 0, 60, 0, 0, 0x01, 0xff}) {
+		t.Fatalf("Unexpected measurement descriptor %x", value)
+	}
+
+	if b := (Trigger{Condition: TriggerGreaterThan, Threshold: 30}).Bytes(Temperature); !bytes.Equal(b, []byte{0x06, 0xb8, 0x0b}) {
+		t.Fatalf("Unexpected trigger setting %x", b)
+	}
+	if b := (Trigger{Condition: TriggerFixedInterval, Interval: time.Minute}).Bytes(Humidity); !bytes.Equal(b, []byte{0x01, 60, 0, 0}) {
+		t.Fatalf("Unexpected trigger setting %x", b)
+	}
+}
+
+func TestCurrentTime(t *testing.T) {
+
+	loc := time.FixedZone("CET", 3600)
+	now := time.Date(2024, time.March, 10, 14, 30, 15, int(time.Second/2), loc)
+
+	b := CurrentTime{Time: now, AdjustReason: AdjustExternalReference}.Bytes()
+	if !bytes.Equal(b, []byte{0xe8, 0x07, 3, 10, 14, 30, 15, 7, 128, 0x02}) {
+		t.Fatalf("Unexpected current time %x", b)
+	}
+
+	c, err := ParseCurrentTime(b, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Time.Equal(now) || c.AdjustReason != AdjustExternalReference {
+		t.Fatalf("Unexpected current time %+v", c)
+	}
+
+	if _, err = ParseCurrentTime(make([]byte, 10), loc); err == nil {
+		t.Fatal("An unknown time should be rejected")
+	}
+	if b := localTimeInfo(now); !bytes.Equal(b, []byte{4, DSTUnknown}) {
+		t.Fatalf("Unexpected local time information %x", b)
+	}
+}
+
+func TestCurrentTimeService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	cts, err := NewCurrentTimeService(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cts.Close()
+	remote := register()
+
+	value, err := remote.ReadValue(CurrentTimeUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseCurrentTime(value, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(c.Time); d < -time.Second || d > 5*time.Second {
+		t.Fatalf("Unexpected current time %s", c.Time)
+	}
+	cts.TimeChanged(AdjustManualUpdate)
+}
+
+func TestKeyboard(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	kb, err := NewKeyboard(app, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !kb.Service().Advertised() {
+		t.Fatal("The HID service should be advertised")
+	}
+	remote := register()
+
+	value, err := remote.ReadValue(HIDInformationUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{0x11, 0x01, 0x00, 0x03}) {
+		t.Fatalf("Unexpected HID information %x", value)
+	}
+	value, err = remote.ReadValue(ReportMapUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, KeyboardReportMap()) {
+		t.Fatalf("Unexpected report map %x", value)
+	}
+
+	if err = kb.Press(ModLeftShift, 0x04); err != nil {
+		t.Fatal(err)
+	}
+	if err = kb.Press(0, 1, 2, 3, 4, 5, 6, 7); err == nil {
+		t.Fatal("7 keys should be rejected")
+	}
+
+	if err = remote.WriteValue(ProtocolModeUUID, []byte{ProtocolBoot}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if kb.ProtocolMode() != ProtocolBoot {
+		t.Fatal("Expected the boot protocol")
+	}
+	if err = kb.Press(0, 0x05); err != nil {
+		t.Fatal(err)
+	}
+	value, err = remote.ReadValue(BootKeyboardInputUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{0, 0, 0x05, 0, 0, 0, 0, 0}) {
+		t.Fatalf("Unexpected boot report %x", value)
+	}
+
+	if err = remote.WriteValue(HIDControlPointUUID, []byte{0x00}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !kb.Suspended() {
+		t.Fatal("The host should be suspended")
+	}
+}
+
+func TestImmediateAlertService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	alerts := make(chan uint8, 1)
+	if _, err := NewImmediateAlertService(app, func(level uint8) { alerts <- level }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTxPowerService(app, -4); err != nil {
+		t.Fatal(err)
+	}
+	remote := register()
+
+	value, err := remote.ReadValue(TxPowerLevelUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{0xfc}) {
+		t.Fatalf("Unexpected TX power %x", value)
+	}
+
+	err = remote.WriteValue(AlertLevelUUID, []byte{3}, nil)
+	if !bluez.IsError(err, bluez.ErrInvalidArguments) {
+		t.Fatalf("Expected %s, got %v", bluez.ErrInvalidArguments, err)
+	}
+	if err = remote.WriteValue(AlertLevelUUID, []byte{AlertMild}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if level := <-alerts; level != AlertMild {
+		t.Fatalf("Unexpected alert %d", level)
+	}
+}
+
+func TestLinkLossService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	type alert struct {
+		device dbus.ObjectPath
+		level  uint8
+	}
+	alerts := make(chan alert, 2)
+	lls, err := NewLinkLossService(app, func(device dbus.ObjectPath, level uint8) {
+		alerts <- alert{device, level}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lls.Close()
+	remote := register()
+
+	if err = remote.WriteValue(AlertLevelUUID, []byte{AlertHigh}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if lls.Level() != AlertHigh {
+		t.Fatalf("Unexpected level %d", lls.Level())
+	}
+
+	d, err := b.AddDevice("hci0", "aa:bb:cc:dd:ee:ff", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := profile.NewDevice1(string(d.Path))
+	for _, fn := range []func() error{dev.Connect, dev.Disconnect, dev.Connect} {
+		if err = fn(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, expected := range []uint8{AlertHigh, AlertNone} {
+		select {
+		case a := <-alerts:
+			if a.device != d.Path || a.level != expected {
+				t.Fatalf("Unexpected alert %+v", a)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected alert %d", expected)
+		}
+	}
+}
+
+func TestSpeedCadenceMeasurements(t *testing.T) {
+
+	csc := CSCMeasurement{
+		WheelRevolutions: 1000,
+		WheelEventTime:   2 * time.Second,
+		CrankRevolutions: 50,
+		CrankEventTime:   time.Second / 2,
+	}
+	b := csc.Bytes(CSCWheelRevolutions | CSCCrankRevolutions)
+	if !bytes.Equal(b, []byte{0x03, 0xe8, 0x03, 0, 0, 0x00, 0x08, 0x32, 0x00, 0x00, 0x02}) {
+		t.Fatalf("Unexpected CSC measurement %x", b)
+	}
+	if b = csc.Bytes(CSCCrankRevolutions); !bytes.Equal(b, []byte{0x02, 0x32, 0x00, 0x00, 0x02}) {
+		t.Fatalf("Unexpected CSC measurement %x", b)
+	}
+
+	rsc := RSCMeasurement{Speed: 3.5, Cadence: 170, StrideLength: 1.25, TotalDistance: 1234.5, Running: true}
+	b = rsc.Bytes(RSCStrideLength | RSCTotalDistance | RSCRunningStatus)
+	if !bytes.Equal(b, []byte{0x07, 0x80, 0x03, 170, 0x7d, 0x00, 0x39, 0x30, 0x00, 0x00}) {
+		t.Fatalf("Unexpected RSC measurement %x", b)
+	}
+}
+
+func TestSpeedCadenceControlPoint(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	var cumulative uint32
+	cscs, err := NewCyclingSpeedCadenceService(app, SpeedCadenceConfig{
+		Features:  CSCWheelRevolutions | CSCMultipleLocations,
+		Location:  LocationRearWheel,
+		Locations: []uint8{LocationFrontWheel, LocationRearWheel},
+		Handlers: SCHandlers{
+			OnSetCumulativeValue: func(value uint32) error {
+				cumulative = value
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := register()
+
+	if err = remote.WriteValue(SCControlPointUUID, []byte{SCSetCumulativeValue, 0x10, 0, 0, 0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cumulative != 0x10 {
+		t.Fatalf("Unexpected cumulative value %d", cumulative)
+	}
+
+	cases := []struct {
+		request  []byte
+		response []byte
+	}{
+		{[]byte{SCUpdateSensorLocation, LocationFrontWheel}, []byte{SCResponse, SCUpdateSensorLocation, SCSuccess}},
+		{[]byte{SCUpdateSensorLocation, LocationHip}, []byte{SCResponse, SCUpdateSensorLocation, SCInvalidParam}},
+		{[]byte{SCStartCalibration}, []byte{SCResponse, SCStartCalibration, SCNotSupported}},
+		{[]byte{SCRequestSupportedLocations}, []byte{SCResponse, SCRequestSupportedLocations, SCSuccess, LocationFrontWheel, LocationRearWheel}},
+	}
+	for _, c := range cases {
+		if r := cscs.handleControl(c.request[0], c.request[1:]); !bytes.Equal(r, c.response) {
+			t.Errorf("%x: expected %x, got %x", c.request, c.response, r)
+		}
+	}
+
+	value, err := remote.ReadValue(SensorLocationUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{LocationFrontWheel}) {
+		t.Fatalf("Unexpected location %x", value)
+	}
+}
+
+func TestMedfloat(t *testing.T) {
+
+	sfloats := []struct {
+		value    float64
+		expected SFloat
+	}{
+		{120, 0x0078},
+		{0.5, 0xF005},
+		{-1.5, 0xFFF1},
+		{36.6, 0xF16E},
+		{0, 0x0000},
+		{1e12, SFloatPosInfinity},
+		{30000, 0x212C},
+		{math.NaN(), SFloatNaN},
+	}
+	for _, c := range sfloats {
+		if f := NewSFloat(c.value); f != c.expected {
+			t.Errorf("SFLOAT %v: expected %04x, got %04x", c.value, uint16(c.expected), uint16(f))
+		}
+	}
+	if v := SFloat(0xFFF1).Float64(); math.Abs(v+1.5) > 1e-9 {
+		t.Fatalf("Unexpected SFLOAT value %v", v)
+	}
+
+	if f := NewFloat(36.6); !bytes.Equal(f.Bytes(), []byte{0x6e, 0x01, 0x00, 0xff}) {
+		t.Fatalf("Unexpected FLOAT %x", f.Bytes())
+	}
+	if f := NewFloat(-12345.678); math.Abs(f.Float64()+12345.678) > 1e-2 {
+		t.Fatalf("Unexpected FLOAT value %v", f.Float64())
+	}
+	if !math.IsNaN(FloatNRes.Float64()) || !math.IsInf(FloatNegInfinity.Float64(), -1) {
+		t.Fatal("Unexpected special FLOAT values")
+	}
+}
+
+func TestHealthMeasurements(t *testing.T) {
+
+	at := time.Date(2024, time.March, 10, 14, 30, 15, 0, time.UTC)
+
+	b := TemperatureMeasurement{Value: 36.6, Time: at, Type: TemperatureEar}.Bytes()
+	if !bytes.Equal(b, []byte{0x06, 0x6e, 0x01, 0x00, 0xff, 0xe8, 0x07, 3, 10, 14, 30, 15, TemperatureEar}) {
+		t.Fatalf("Unexpected temperature measurement %x", b)
+	}
+
+	b = BloodPressureMeasurement{Systolic: 120, Diastolic: 80, MeanArterial: 93, PulseRate: 60, UserID: 1}.Bytes()
+	if !bytes.Equal(b, []byte{0x0c, 0x78, 0x00, 0x50, 0x00, 0x5d, 0x00, 0x3c, 0x00, 0x01}) {
+		t.Fatalf("Unexpected blood pressure measurement %x", b)
+	}
+}
+
+func TestHealthThermometerIndication(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	hts, err := NewHealthThermometerService(app, HealthThermometerConfig{Type: TemperatureMouth})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hts.measurement.timeout = 2 * time.Second
+	remote := register()
+
+	if err = hts.SendMeasurement(TemperatureMeasurement{Value: 37}); err != ErrNotSubscribed {
+		t.Fatalf("Expected %s, got %v", ErrNotSubscribed, err)
+	}
+
+	if err = remote.StartNotify(TemperatureMeasurementUUID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = hts.SendMeasurement(TemperatureMeasurement{Value: 37}); err != ErrNotConfirmed {
+		t.Fatalf("Expected %s, got %v", ErrNotConfirmed, err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hts.SendMeasurement(TemperatureMeasurement{Value: 37})
+	}()
+	for {
+		select {
+		case err = <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+			if err := remote.Confirm(TemperatureMeasurementUUID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestMemoryRecordStore(t *testing.T) {
+
+	s := NewMemoryRecordStore(4)
+	for i := 0; i < 6; i++ {
+		s.Add(func(seq uint16) []byte { return []byte{byte(seq)} })
+	}
+
+	cases := []struct {
+		filter RecordFilter
+		count  int
+	}{
+		{RecordFilter{Operator: RACPAll}, 4},
+		{RecordFilter{Operator: RACPFirst}, 1},
+		{RecordFilter{Operator: RACPLessOrEqual, Max: 3}, 2},
+		{RecordFilter{Operator: RACPWithinRange, Min: 3, Max: 4}, 2},
+	}
+	for _, c := range cases {
+		if n := s.Count(c.filter); n != c.count {
+			t.Errorf("%+v: expected %d records, got %d", c.filter, c.count, n)
+		}
+	}
+
+	page := s.Records(RecordFilter{Operator: RACPAll}, 1, 2)
+	if len(page) != 2 || page[0].Sequence != 3 || page[1].Sequence != 4 {
+		t.Fatalf("Unexpected page %+v", page)
+	}
+	if last := s.Records(RecordFilter{Operator: RACPLast}, 0, 10); len(last) != 1 || last[0].Sequence != 5 {
+		t.Fatalf("Unexpected last record %+v", last)
+	}
+
+	if n := s.Delete(RecordFilter{Operator: RACPGreaterOrEqual, Min: 4}); n != 2 {
+		t.Fatalf("Expected 2 records deleted, got %d", n)
+	}
+	if n := s.Count(RecordFilter{Operator: RACPAll}); n != 2 {
+		t.Fatalf("Expected 2 records, got %d", n)
+	}
+}
+
+func TestParseRecordFilter(t *testing.T) {
+	cases := []struct {
+		operator uint8
+		operand  []byte
+		code     uint8
+	}{
+		{RACPAll, nil, RACPSuccess},
+		{RACPAll, []byte{1}, RACPInvalidOperand},
+		{RACPNull, nil, RACPInvalidOperator},
+		{0x07, nil, RACPOperatorNotSupported},
+		{RACPLessOrEqual, []byte{racpFilterSequence, 1, 0}, RACPSuccess},
+		{RACPGreaterOrEqual, []byte{0x02, 1, 0}, RACPOperandNotSupported},
+		{RACPWithinRange, []byte{racpFilterSequence, 1, 0}, RACPInvalidOperand},
+		{RACPWithinRange, []byte{racpFilterSequence, 2, 0, 1, 0}, RACPInvalidOperand},
+	}
+	for _, c := range cases {
+		if _, code := parseFilter(c.operator, c.operand); code != c.code {
+			t.Errorf("%d %x: expected %d, got %d", c.operator, c.operand, c.code, code)
+		}
+	}
+}
+
+func TestGlucoseService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	gls, err := NewGlucoseService(app, GlucoseLowBattery, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gls.racp.control.timeout = 2 * time.Second
+	remote := register()
+
+	at := time.Date(2018, 3, 1, 8, 30, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		gls.AddMeasurement(GlucoseMeasurement{
+			Time:          at,
+			Concentration: 0.00095,
+			Type:          GlucoseCapillaryPlasma,
+			Location:      GlucoseFinger,
+		})
+	}
+
+	expected := []byte{0x02, 2, 0, 0xe2, 0x07, 3, 1, 8, 30, 0, 0x5f, 0xb0, 0x12}
+	if last := gls.Store().Records(RecordFilter{Operator: RACPLast}, 0, 1); !bytes.Equal(last[0].Value, expected) {
+		t.Fatalf("Expected %x, got %x", expected, last[0].Value)
+	}
+
+	if err = remote.StartNotify(GlucoseMeasurementUUID); err != nil {
+		t.Fatal(err)
+	}
+	if err = remote.StartNotify(RecordAccessControlPointUUID); err != nil {
+		t.Fatal(err)
+	}
+
+	// send a request and wait for the indication of the response
+	request := func(req []byte, resp []byte) {
+		if err := remote.WriteValue(RecordAccessControlPointUUID, req, nil); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(time.Second)
+		for {
+			value, err := remote.ReadValue(RecordAccessControlPointUUID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(value, resp) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%x: expected %x, got %x", req, resp, value)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err := remote.Confirm(RecordAccessControlPointUUID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request([]byte{RACPReportNumber, RACPAll}, []byte{RACPNumberResponse, RACPNull, 3, 0})
+	request([]byte{RACPDeleteRecords, RACPLessOrEqual, racpFilterSequence, 0, 0}, []byte{RACPResponse, RACPNull, RACPDeleteRecords, RACPSuccess})
+	request([]byte{RACPReportRecords, RACPFirst}, []byte{RACPResponse, RACPNull, RACPReportRecords, RACPSuccess})
+
+	value, err := remote.ReadValue(GlucoseMeasurementUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value[1] != 1 {
+		t.Fatalf("Expected the record 1, got %x", value)
+	}
+
+	request([]byte{RACPReportRecords, RACPGreaterOrEqual, racpFilterSequence, 10, 0}, []byte{RACPResponse, RACPNull, RACPReportRecords, RACPNoRecords})
+	request([]byte{0x09, RACPNull}, []byte{RACPResponse, RACPNull, 0x09, RACPOpCodeNotSupported})
+}
+
+func TestWeightScale(t *testing.T) {
+
+	m := WeightMeasurement{Weight: 70.2, UserID: 2, BMI: 22.9, Height: 1.75}
+	expected := []byte{0x0c, 0xd8, 0x36, 2, 229, 0, 0xd6, 0x06}
+	if v := m.Bytes(WeightMultipleUsers | WeightBMI); !bytes.Equal(v, expected) {
+		t.Fatalf("Expected %x, got %x", expected, v)
+	}
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	wss, err := NewWeightScaleService(app, WeightMultipleUsers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	register()
+
+	wss.SendMeasurement(WeightMeasurement{Weight: 70, UserID: 1})
+	wss.SendMeasurement(WeightMeasurement{Weight: 60, UserID: 2})
+	if wss.Stored() != 2 {
+		t.Fatalf("Expected 2 measurements stored, got %d", wss.Stored())
+	}
+	if n, err := wss.SendStored(1); n != 0 || err != ErrNotSubscribed {
+		t.Fatalf("Unexpected result %d %v", n, err)
+	}
+	if wss.Stored() != 2 {
+		t.Fatalf("Expected 2 measurements stored, got %d", wss.Stored())
+	}
+}
+
+func TestPLXMeasurements(t *testing.T) {
+
+	spot := PLXSpotCheck{PLXReading: PLXReading{SpO2: 98, PulseRate: 72}, PulseAmplitude: 5}
+	expected := []byte{0x08, 98, 0, 72, 0, 5, 0}
+	if v := spot.Bytes(PLXPulseAmplitude | PLXSpotCheckTimestamp); !bytes.Equal(v, expected) {
+		t.Fatalf("Expected %x, got %x", expected, v)
+	}
+
+	cont := PLXContinuous{Normal: PLXReading{SpO2: 97, PulseRate: 70}, Fast: PLXReading{SpO2: 96, PulseRate: 71}, DeviceStatus: 0x010203}
+	expected = []byte{0x09, 97, 0, 70, 0, 96, 0, 71, 0, 3, 2, 1}
+	if v := cont.Bytes(PLXFast | PLXDeviceStatus); !bytes.Equal(v, expected) {
+		t.Fatalf("Expected %x, got %x", expected, v)
+	}
+}
+
+func TestPulseOximeterService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	plx, err := NewPulseOximeterService(app, PulseOximeterConfig{
+		Features:          PLXSpotCheckStorage | PLXMeasurementStatus,
+		MeasurementStatus: 0x0020,
+		SpotCheck:         true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plx.racp.control.timeout = 2 * time.Second
+	remote := register()
+
+	value, err := remote.ReadValue(PLXFeaturesUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{0x05, 0, 0x20, 0}) {
+		t.Fatalf("Unexpected features %x", value)
+	}
+
+	spot := PLXSpotCheck{PLXReading: PLXReading{SpO2: 98, PulseRate: 72}}
+	if err = plx.SendSpotCheck(spot); err != ErrNotSubscribed {
+		t.Fatalf("Expected %s, got %v", ErrNotSubscribed, err)
+	}
+	if n := plx.Store().Count(RecordFilter{Operator: RACPAll}); n != 1 {
+		t.Fatalf("Expected 1 stored spot check, got %d", n)
+	}
+
+	if err = remote.StartNotify(RecordAccessControlPointUUID); err != nil {
+		t.Fatal(err)
+	}
+	req := []byte{RACPReportNumber, RACPLessOrEqual, racpFilterSequence, 0, 0}
+	if err = remote.WriteValue(RecordAccessControlPointUUID, req, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{RACPResponse, RACPNull, RACPReportNumber, RACPOperatorNotSupported}
+	deadline := time.Now().Add(time.Second)
+	for {
+		value, err = remote.ReadValue(RecordAccessControlPointUUID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(value, expected) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %x, got %x", expected, value)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestValueTrigger(t *testing.T) {
+
+	trigger := ValueTrigger{Condition: ValueMaskCompare, Mask: []bool{false, true, false, false, true}}
+	b := trigger.Bytes()
+	if !bytes.Equal(b, []byte{ValueMaskCompare, 0x0c, 0x03}) {
+		t.Fatalf("Unexpected trigger %x", b)
+	}
+	parsed, err := parseValueTrigger(b, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Mask[1] || !parsed.Mask[4] || parsed.Mask[0] {
+		t.Fatalf("Unexpected mask %v", parsed.Mask)
+	}
+	if _, err = parseValueTrigger(b, 0); err != ErrTriggerNotSupported {
+		t.Fatalf("Expected %s, got %v", ErrTriggerNotSupported, err)
+	}
+	if _, err = parseValueTrigger([]byte{ValueInsideOutside, 1, 0}, 0); !bluez.IsError(err, bluez.ErrInvalidValueLength) {
+		t.Fatalf("Expected %s, got %v", bluez.ErrInvalidValueLength, err)
+	}
+
+	cases := []struct {
+		trigger ValueTrigger
+		old, v  uint16
+		match   bool
+	}{
+		{ValueTrigger{Condition: ValueCrossedBoundary, Values: [2]uint16{100}}, 90, 110, true},
+		{ValueTrigger{Condition: ValueCrossedBoundary, Values: [2]uint16{100}}, 110, 120, false},
+		{ValueTrigger{Condition: ValueChangedMore, Values: [2]uint16{10}}, 0, 5, false},
+		{ValueTrigger{Condition: ValueInsideOutside, Values: [2]uint16{10, 20}}, 5, 15, true},
+		{ValueTrigger{Condition: ValueNoTrigger}, 5, 15, false},
+	}
+	for _, c := range cases {
+		if m := c.trigger.match(c.old, c.v, c.old); m != c.match {
+			t.Errorf("%+v %d->%d: expected %v", c.trigger, c.old, c.v, c.match)
+		}
+	}
+}
+
+func TestAutomationIOService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	aios, err := NewAutomationIOService(app, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var relays []DigitalState
+	digital, err := aios.AddDigital(DigitalConfig{
+		Count:    5,
+		Writable: true,
+		OnWrite: func(states []DigitalState) error {
+			relays = states
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := register()
+
+	if err = remote.WriteValue(DigitalUUID, []byte{0x41, 0x01}, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := []DigitalState{DigitalActive, DigitalInactive, DigitalInactive, DigitalActive, DigitalActive}
+	for i, s := range expected {
+		if relays[i] != s || digital.States()[i] != s {
+			t.Fatalf("Unexpected states %v", relays)
+		}
+	}
+
+	value, err := remote.ReadDescriptor(NumberOfDigitalsUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{5}) {
+		t.Fatalf("Unexpected number of digitals %x", value)
+	}
+
+	// notify the changes of the first digital only
+	if err = remote.WriteDescriptor(ValueTriggerSettingUUID, []byte{ValueMaskCompare, 0x03, 0x00}, nil); err != nil {
+		t.Fatal(err)
+	}
+	err = remote.WriteDescriptor(ValueTriggerSettingUUID, []byte{ValueCrossedBoundary, 0x00, 0x02}, nil)
+	if !bluez.IsError(err, bluez.ErrFailed) {
+		t.Fatalf("Expected %s, got %v", ErrTriggerNotSupported, err)
+	}
+
+	notified := func() []byte {
+		v, _ := digital.char.PropertiesInterface.Get(digital.char.Interface())["Value"].([]byte)
+		return v
+	}
+	digital.Set(DigitalActive, DigitalActive, DigitalInactive, DigitalActive, DigitalActive)
+	if v := notified(); v != nil {
+		t.Fatalf("Unexpected notification %x", v)
+	}
+	digital.Set(DigitalInactive, DigitalActive, DigitalInactive, DigitalActive, DigitalActive)
+	if v := notified(); !bytes.Equal(v, []byte{0x44, 0x01}) {
+		t.Fatalf("Unexpected notification %x", v)
+	}
+
+	value, err = remote.ReadValue(AggregateUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{0x44, 0x01}) {
+		t.Fatalf("Unexpected aggregate %x", value)
+	}
+}
+
+func TestNewAlert(t *testing.T) {
+	a := NewAlert{Category: CategoryCall, Count: 1, Text: "Élodie Dupont-Lemaître"}
+	b := a.Bytes()
+	if len(b) > 20 || !bytes.Equal(b[:2], []byte{CategoryCall, 1}) || string(b[2:]) != "Élodie Dupont-Lem" {
+		t.Fatalf("Unexpected alert %x", b)
+	}
+
+	supported := Categories(CategoryEmail, CategoryCall)
+	if supported != 0x0a {
+		t.Fatalf("Unexpected categories %x", supported)
+	}
+	if _, err := ParseAlertControl([]byte{AlertEnableNew, CategorySMS}, supported); err != ErrCommandNotSupported {
+		t.Fatalf("Expected %s, got %v", ErrCommandNotSupported, err)
+	}
+	if _, err := ParseAlertControl([]byte{0x06, CategoryAll}, supported); err != ErrCommandNotSupported {
+		t.Fatalf("Expected %s, got %v", ErrCommandNotSupported, err)
+	}
+}
+
+func TestAlertNotificationService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	ans, err := NewAlertNotificationService(app, Categories(CategoryEmail, CategoryCall), Categories(CategoryEmail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := register()
+
+	if err = remote.WriteValue(AlertControlPointUUID, []byte{AlertEnableNew, CategoryAll}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = remote.WriteValue(AlertControlPointUUID, []byte{AlertEnableUnread, CategoryCall}, nil); !bluez.IsError(err, bluez.ErrFailed) {
+		t.Fatalf("Expected %s, got %v", ErrCommandNotSupported, err)
+	}
+	if err = remote.WriteValue(AlertControlPointUUID, []byte{AlertDisableNew, CategoryEmail}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n, u := ans.Enabled(); n != Categories(CategoryCall) || u != 0 {
+		t.Fatalf("Unexpected categories %x %x", n, u)
+	}
+
+	if err = ans.SendNewAlert(NewAlert{Category: CategoryCall, Count: 2, Text: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	value, _ := ans.newAlert.PropertiesInterface.Get(ans.newAlert.Interface())["Value"].([]byte)
+	if !bytes.Equal(value, []byte{CategoryCall, 2, 'B', 'o', 'b'}) {
+		t.Fatalf("Unexpected new alert %x", value)
+	}
+	if err = ans.SetUnread(CategoryCall, 1); err != ErrCommandNotSupported {
+		t.Fatalf("Expected %s, got %v", ErrCommandNotSupported, err)
+	}
+}
+
+func TestPhoneAlertStatusService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	muted := false
+	pass, err := NewPhoneAlertStatusService(app, func() { muted = true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := register()
+
+	pass.SetAlertStatus(AlertStatusRinger | AlertStatusDisplay)
+	value, err := remote.ReadValue(AlertStatusUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{0x05}) {
+		t.Fatalf("Unexpected alert status %x", value)
+	}
+
+	if err = remote.WriteValue(RingerControlPointUUID, []byte{RingerSetSilent}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if pass.RingerSetting() != RingerSilent {
+		t.Fatal("Expected the ringer silent")
+	}
+	if err = remote.WriteValue(RingerControlPointUUID, []byte{RingerMuteOnce}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !muted {
+		t.Fatal("Expected the ringer muted")
+	}
+	if err = remote.WriteValue(RingerControlPointUUID, []byte{RingerCancelSilent}, nil); err != nil {
+		t.Fatal(err)
+	}
+	value, err = remote.ReadValue(RingerSettingUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(value, []byte{RingerNormal}) {
+		t.Fatalf("Unexpected ringer setting %x", value)
+	}
+}
+
+func TestBondManagementFeatures(t *testing.T) {
+	b := BondManagementFeatures([]uint8{BMDeleteBond, BMDeleteAllLE, BMDeleteOthersLE}, true)
+	if !bytes.Equal(b, []byte{0x03, 0x0c, 0x03}) {
+		t.Fatalf("Unexpected features %x", b)
+	}
+}
+
+func TestBondManagementService(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	devices := []*bluetest.Device{}
+	for _, address := range []string{"AA:00:00:00:00:01", "AA:00:00:00:00:02", "AA:00:00:00:00:03"} {
+		d, err := b.AddDevice("hci0", address, "Device")
+		if err != nil {
+			t.Fatal(err)
+		}
+		devices = append(devices, d)
+	}
+	devices[0].SetProperty("Paired", true)
+	devices[1].SetProperty("Paired", true)
+
+	bonds, err := app.Bonds("hci0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bonds) != 2 || bonds[0] != devices[0].Path || bonds[1] != devices[1].Path {
+		t.Fatalf("Unexpected bonds %v", bonds)
+	}
+
+	BondRemovalDelay = 0
+	var deleted []dbus.ObjectPath
+	_, err = NewBondManagementService(app, BondManagementConfig{
+		Adapter:           "hci0",
+		Operations:        []uint8{BMDeleteOthers},
+		AuthorizationCode: "1234",
+		OnDelete:          func(d []dbus.ObjectPath) { deleted = d },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := register()
+
+	options := map[string]dbus.Variant{"device": dbus.MakeVariant(devices[0].Path)}
+	err = remote.WriteValue(BondManagementControlPointUUID, []byte{BMDeleteAll}, options)
+	if !bluez.IsError(err, bluez.ErrFailed) {
+		t.Fatalf("Expected %s, got %v", ErrBMOpCodeNotSupported, err)
+	}
+	err = remote.WriteValue(BondManagementControlPointUUID, []byte{BMDeleteOthers, '0'}, options)
+	if !bluez.IsError(err, bluez.ErrNotAuthorized) {
+		t.Fatalf("Expected %s, got %v", bluez.ErrNotAuthorized, err)
+	}
+	err = remote.WriteValue(BondManagementControlPointUUID, []byte{BMDeleteOthers, '1', '2', '3', '4'}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0] != devices[1].Path {
+		t.Fatalf("Unexpected bonds deleted %v", deleted)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for b.Device(devices[1].Path) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the bond removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if b.Device(devices[0].Path) == nil {
+		t.Fatal("Expected the bond of the requesting device kept")
+	}
+}
+
+// remoteCharacteristic access a characteristic through the fake bluez, as a
+// device would
+type remoteCharacteristic struct {
+	remote *bluetest.Application
+	conn   *dbus.Conn
+	uuid   string
+	path   dbus.ObjectPath
+	device dbus.ObjectPath
+}
+
+func (r *remoteCharacteristic) options() map[string]dbus.Variant {
+	return map[string]dbus.Variant{"device": dbus.MakeVariant(r.device)}
+}
+
+func (r *remoteCharacteristic) ReadValue(options map[string]dbus.Variant) ([]byte, error) {
+	return r.remote.ReadValue(r.uuid, r.options())
+}
+
+func (r *remoteCharacteristic) WriteValue(b []byte, options map[string]dbus.Variant) error {
+	return r.remote.WriteValue(r.uuid, b, r.options())
+}
+
+// Notifications report the indications, each is confirmed
+func (r *remoteCharacteristic) Notifications() (<-chan []byte, func(), error) {
+	dispatcher := bluez.GetSignalDispatcher(r.conn)
+	signals, err := dispatcher.Subscribe(bluez.SignalFilter{
+		Path:      r.path,
+		Interface: bluez.PropertiesInterface,
+		Member:    "PropertiesChanged",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = r.remote.StartNotify(r.uuid); err != nil {
+		dispatcher.Unsubscribe(signals)
+		return nil, nil, err
+	}
+	values := make(chan []byte, 16)
+	done := make(chan bool)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig == nil || len(sig.Body) < 2 {
+					continue
+				}
+				changed, _ := sig.Body[1].(map[string]dbus.Variant)
+				if value, ok := changed["Value"].Value().([]byte); ok {
+					r.remote.Confirm(r.uuid)
+					values <- value
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return values, func() {
+		once.Do(func() {
+			dispatcher.Unsubscribe(signals)
+			close(done)
+		})
+	}, nil
+}
+
+func TestObjectTransfer(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	written := make(chan uint64, 1)
+	ots, err := NewObjectTransferService(app, ObjectTransferConfig{
+		Timeout: 2 * time.Second,
+		OnWrite: func(device dbus.ObjectPath, id uint64) { written <- id },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ots.Close()
+	ots.oacp.timeout = 2 * time.Second
+	ots.olcp.timeout = 2 * time.Second
+
+	data := bytes.Repeat([]byte("0123456789"), 5)
+	first, err := ots.AddObject(Object{
+		Name:       "first",
+		Allocated:  128,
+		Properties: ObjectPropRead | ObjectPropWrite | ObjectPropAppend,
+	}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ots.AddObject(Object{
+		Name:       "second",
+		Type:       "2ACB",
+		Properties: ObjectPropRead | ObjectPropDelete,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := register()
+
+	device := dbus.ObjectPath("/org/bluez/hci0/dev_AA_00_00_00_00_01")
+	chars := make(map[string]otsCharacteristic)
+	for _, char := range ots.Service().GetCharacteristics() {
+		chars[char.UUID()] = &remoteCharacteristic{
+			remote: remote,
+			conn:   b.ClientConn(),
+			uuid:   char.UUID(),
+			path:   char.Path(),
+			device: device,
+		}
+	}
+
+	server, channel := net.Pipe()
+	ots.attach("AA:00:00:00:00:01", server, 8)
+
+	c, err := newObjectTransferClient(chars, channel, 8, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if actions, lists, err := c.Features(); err != nil || actions != otsActionFeatures || lists != otsListFeatures {
+		t.Fatalf("Unexpected features %x %x %v", actions, lists, err)
+	}
+	if n, err := c.Count(); err != nil || n != 2 {
+		t.Fatalf("Unexpected number of objects %d %v", n, err)
+	}
+	if _, err = c.Current(); !bluez.IsError(err, bluez.ErrFailed) {
+		t.Fatalf("Expected %s, got %v", ErrObjectNotSelected, err)
+	}
+
+	if err = c.First(); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := c.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.ID != first || obj.Name != "first" || obj.Type != ObjectTypeUnspecified || obj.Size != 50 || obj.Allocated != 128 {
+		t.Fatalf("Unexpected object %+v", obj)
+	}
+
+	read, err := c.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(read, data) {
+		t.Fatalf("Unexpected contents %q", read)
+	}
+	if read, err = c.Read(12, 5); err != nil || string(read) != "23456" {
+		t.Fatalf("Unexpected partial contents %q %v", read, err)
+	}
+	if sum, err := c.Checksum(0, 50); err != nil || sum != crc32.ChecksumIEEE(data) {
+		t.Fatalf("Unexpected checksum %x %v", sum, err)
+	}
+
+	if err = c.Write(50, []byte("appended data"), false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id := <-written:
+		if id != first {
+			t.Fatalf("Unexpected object written %d", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Write not completed")
+	}
+	if contents, _ := ots.ObjectData(first); string(contents) != string(data)+"appended data" {
+		t.Fatalf("Unexpected contents %q", contents)
+	}
+
+	// no patching
+	err = c.Write(0, []byte("x"), false)
+	if e, ok := err.(*OTSError); !ok || e.Result != OACPProcedureNotPermitted {
+		t.Fatalf("Expected OACPProcedureNotPermitted, got %v", err)
+	}
+
+	if err = c.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if obj, err = c.Current(); err != nil || obj.Name != "second" || obj.Type != ObjectTypeDirectoryListing {
+		t.Fatalf("Unexpected object %+v %v", obj, err)
+	}
+	err = c.Next()
+	if e, ok := err.(*OTSError); !ok || e.Result != OLCPOutOfBounds {
+		t.Fatalf("Expected OLCPOutOfBounds, got %v", err)
+	}
+	if err = c.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if list := ots.Objects(); len(list) != 1 || list[0].ID != first {
+		t.Fatalf("Unexpected objects %+v", list)
+	}
+
+	err = c.GoTo(0x1234)
+	if e, ok := err.(*OTSError); !ok || e.Result != OLCPObjectIDNotFound {
+		t.Fatalf("Expected OLCPObjectIDNotFound, got %v", err)
+	}
+	if err = c.GoTo(first); err != nil {
+		t.Fatal(err)
+	}
+	if read, err = c.Read(55, 3); err != nil || string(read) != "ded" {
+		t.Fatalf("Unexpected contents %q %v", read, err)
+	}
+}
+
+func TestObjectType(t *testing.T) {
+
+	b, app, register := serve(t)
+	defer b.Close()
+
+	ots, err := NewObjectTransferService(app, ObjectTransferConfig{Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ots.Close()
+	ots.olcp.timeout = 2 * time.Second
+
+	tests := []struct {
+		objectType string
+		expected   string
+		value      []byte
+	}{
+		{"", ObjectTypeUnspecified, []byte{0xca, 0x2a}},
+		{"2ACB", ObjectTypeDirectoryListing, []byte{0xcb, 0x2a}},
+		{"12345678-9abc-def0-1234-56789abcdef0", "12345678-9ABC-DEF0-1234-56789ABCDEF0", []byte{
+			0xf0, 0xde, 0xbc, 0x9a, 0x78, 0x56, 0x34, 0x12, 0xf0, 0xde, 0xbc, 0x9a, 0x78, 0x56, 0x34, 0x12}},
+	}
+	for _, test := range tests {
+		if _, err = ots.AddObject(Object{Name: test.objectType, Type: test.objectType}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = ots.AddObject(Object{Type: "not an UUID"}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid object type")
+	}
+	remote := register()
+
+	device := dbus.ObjectPath("/org/bluez/hci0/dev_AA_00_00_00_00_01")
+	chars := make(map[string]otsCharacteristic)
+	for _, char := range ots.Service().GetCharacteristics() {
+		chars[char.UUID()] = &remoteCharacteristic{
+			remote: remote,
+			conn:   b.ClientConn(),
+			uuid:   char.UUID(),
+			path:   char.Path(),
+			device: device,
+		}
+	}
+
+	server, channel := net.Pipe()
+	ots.attach("AA:00:00:00:00:01", server, 8)
+
+	c, err := newObjectTransferClient(chars, channel, 8, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i, test := range tests {
+		if i == 0 {
+			err = c.First()
+		} else {
+			err = c.Next()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// little endian on the air, 2 bytes for the 16bit types
+		value, err := chars[ObjectTypeUUID].ReadValue(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(value, test.value) {
+			t.Errorf("%q: expected value %x, got %x", test.objectType, test.value, value)
+		}
+
+		obj, err := c.Current()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if obj.Type != test.expected {
+			t.Errorf("%q: expected type %s, got %s", test.objectType, test.expected, obj.Type)
+		}
+	}
+}
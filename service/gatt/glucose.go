@@ -0,0 +1,162 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Glucose UUIDs
+var (
+	GlucoseUUID            = UUID("1808")
+	GlucoseMeasurementUUID = UUID("2A18")
+	GlucoseFeatureUUID     = UUID("2A51")
+)
+
+//DefaultGlucoseRecords the records kept by the store of NewGlucoseService
+const DefaultGlucoseRecords = 500
+
+// Glucose features
+const (
+	GlucoseLowBattery          uint16 = 1 << 0
+	GlucoseMalfunction         uint16 = 1 << 1
+	GlucoseSampleSize          uint16 = 1 << 2
+	GlucoseStripInsertionError uint16 = 1 << 3
+	GlucoseStripTypeError      uint16 = 1 << 4
+	GlucoseResultHighLow       uint16 = 1 << 5
+	GlucoseTemperatureHighLow  uint16 = 1 << 6
+	GlucoseReadInterrupt       uint16 = 1 << 7
+	GlucoseGeneralFault        uint16 = 1 << 8
+	GlucoseTimeFault           uint16 = 1 << 9
+	GlucoseMultipleBond        uint16 = 1 << 10
+)
+
+// Glucose sample types
+const (
+	GlucoseCapillaryWholeBlood    uint8 = 1
+	GlucoseCapillaryPlasma        uint8 = 2
+	GlucoseVenousWholeBlood       uint8 = 3
+	GlucoseVenousPlasma           uint8 = 4
+	GlucoseArterialWholeBlood     uint8 = 5
+	GlucoseArterialPlasma         uint8 = 6
+	GlucoseUndeterminedWholeBlood uint8 = 7
+	GlucoseUndeterminedPlasma     uint8 = 8
+	GlucoseInterstitialFluid      uint8 = 9
+	GlucoseControlSolution        uint8 = 10
+)
+
+// Glucose sample locations
+const (
+	GlucoseFinger          uint8 = 1
+	GlucoseAlternateSite   uint8 = 2
+	GlucoseEarlobe         uint8 = 3
+	GlucoseControlLocation uint8 = 4
+	GlucoseNoLocation      uint8 = 15
+)
+
+//GlucoseMeasurement a measurement of the Glucose Service
+type GlucoseMeasurement struct {
+	// Time the base time of the measurement
+	Time time.Time
+	// TimeOffset from the base time, in minutes, not sent if zero
+	TimeOffset time.Duration
+	// Concentration in kg/L, or mol/L, not sent if zero
+	Concentration float64
+	MolPerL       bool
+	// Type and Location of the sample, sent with the concentration
+	Type     uint8
+	Location uint8
+	// Status the sensor status annunciation, a bitmask of the Glucose*
+	// features, not sent if zero
+	Status uint16
+}
+
+//Bytes encode the measurement with its sequence number
+func (m GlucoseMeasurement) Bytes(sequence uint16) []byte {
+	b := []byte{0, byte(sequence), byte(sequence >> 8)}
+	b = append(b, DateTime(m.Time)...)
+	if m.TimeOffset != 0 {
+		b[0] |= 1 << 0
+		offset := int16(m.TimeOffset / time.Minute)
+		b = append(b, byte(offset), byte(offset>>8))
+	}
+	if m.Concentration != 0 {
+		b[0] |= 1 << 1
+		if m.MolPerL {
+			b[0] |= 1 << 2
+		}
+		b = append(b, NewSFloat(m.Concentration).Bytes()...)
+		b = append(b, m.Type&0x0f|m.Location<<4)
+	}
+	if m.Status != 0 {
+		b[0] |= 1 << 3
+		b = append(b, byte(m.Status), byte(m.Status>>8))
+	}
+	return b
+}
+
+//GlucoseService the Glucose Service (0x1808). The measurements are stored and
+// sent to the collector on request of the Record Access Control Point
+type GlucoseService struct {
+	srv   *service.GattService1
+	store RecordStore
+	racp  *racp
+}
+
+//NewGlucoseService add a Glucose Service to a running application, with the
+// Glucose* features supported by the sensor. The records are kept in store, a
+// MemoryRecordStore of DefaultGlucoseRecords if nil
+func NewGlucoseService(app *service.Application, features uint16, store RecordStore) (*GlucoseService, error) {
+
+	if store == nil {
+		store = NewMemoryRecordStore(DefaultGlucoseRecords)
+	}
+	g := &GlucoseService{store: store}
+
+	srv, err := addService(app, GlucoseUUID, true)
+	if err != nil {
+		return nil, err
+	}
+	g.srv = srv
+
+	measurement, err := addCharacteristic(srv, GlucoseMeasurementUUID, []string{bluez.FlagCharacteristicNotify}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, features)
+	if _, err = addReadOnly(srv, GlucoseFeatureUUID, b); err != nil {
+		return nil, err
+	}
+
+	notify := func(r Record) error {
+		measurement.UpdateValue(r.Value)
+		// leave bluetoothd the time to send each notification
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	if g.racp, err = addRACP(srv, store, notify, true); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+//Service return the GATT service
+func (g *GlucoseService) Service() *service.GattService1 {
+	return g.srv
+}
+
+//Store return the store of the records
+func (g *GlucoseService) Store() RecordStore {
+	return g.store
+}
+
+//AddMeasurement store a measurement, it returns the record with the sequence
+// number assigned
+func (g *GlucoseService) AddMeasurement(m GlucoseMeasurement) Record {
+	return g.store.Add(m.Bytes)
+}
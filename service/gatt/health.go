@@ -0,0 +1,279 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Health Thermometer and Blood Pressure UUIDs
+var (
+	HealthThermometerUUID        = UUID("1809")
+	TemperatureMeasurementUUID   = UUID("2A1C")
+	TemperatureTypeUUID          = UUID("2A1D")
+	IntermediateTemperatureUUID  = UUID("2A1E")
+	MeasurementIntervalUUID      = UUID("2A21")
+	BloodPressureUUID            = UUID("1810")
+	BloodPressureMeasurementUUID = UUID("2A35")
+	IntermediateCuffPressureUUID = UUID("2A36")
+	BloodPressureFeatureUUID     = UUID("2A49")
+)
+
+// Temperature types
+const (
+	TemperatureArmpit   uint8 = 1
+	TemperatureBody     uint8 = 2
+	TemperatureEar      uint8 = 3
+	TemperatureFinger   uint8 = 4
+	TemperatureGastro   uint8 = 5
+	TemperatureMouth    uint8 = 6
+	TemperatureRectum   uint8 = 7
+	TemperatureToe      uint8 = 8
+	TemperatureTympanum uint8 = 9
+)
+
+// Blood Pressure features
+const (
+	BPBodyMovement        uint16 = 1 << 0
+	BPCuffFit             uint16 = 1 << 1
+	BPIrregularPulse      uint16 = 1 << 2
+	BPPulseRateRange      uint16 = 1 << 3
+	BPMeasurementPosition uint16 = 1 << 4
+	BPMultipleBond        uint16 = 1 << 5
+)
+
+//DateTime encode the Date Time characteristic format, the zero time as
+// unknown
+func DateTime(t time.Time) []byte {
+	b := make([]byte, 7)
+	if t.IsZero() {
+		return b
+	}
+	binary.LittleEndian.PutUint16(b, uint16(t.Year()))
+	b[2] = byte(t.Month())
+	b[3] = byte(t.Day())
+	b[4] = byte(t.Hour())
+	b[5] = byte(t.Minute())
+	b[6] = byte(t.Second())
+	return b
+}
+
+//TemperatureMeasurement a measurement of the Health Thermometer Service
+type TemperatureMeasurement struct {
+	// Value in degrees Celsius, or Fahrenheit
+	Value      float64
+	Fahrenheit bool
+	// Time of the measurement, not sent if zero
+	Time time.Time
+	// Type the location of the measurement, not sent if zero
+	Type uint8
+}
+
+//Bytes encode the measurement
+func (m TemperatureMeasurement) Bytes() []byte {
+	b := []byte{0}
+	if m.Fahrenheit {
+		b[0] |= 1 << 0
+	}
+	b = append(b, NewFloat(m.Value).Bytes()...)
+	if !m.Time.IsZero() {
+		b[0] |= 1 << 1
+		b = append(b, DateTime(m.Time)...)
+	}
+	if m.Type != 0 {
+		b[0] |= 1 << 2
+		b = append(b, m.Type)
+	}
+	return b
+}
+
+//HealthThermometerConfig configure a Health Thermometer Service
+type HealthThermometerConfig struct {
+	// Type the fixed location of the measurements, exposed as the Temperature
+	// Type if not zero. Use the Type of the measurements for a thermometer
+	// measuring at several locations
+	Type uint8
+	// Intermediate expose the Intermediate Temperature, notified while
+	// measuring
+	Intermediate bool
+	// Interval the time between the measurements, exposed if not zero
+	Interval time.Duration
+}
+
+//HealthThermometerService the Health Thermometer Service (0x1809)
+type HealthThermometerService struct {
+	srv          *service.GattService1
+	measurement  *indicator
+	intermediate *service.GattCharacteristic1
+}
+
+//NewHealthThermometerService add a Health Thermometer Service to a running
+// application
+func NewHealthThermometerService(app *service.Application, config HealthThermometerConfig) (*HealthThermometerService, error) {
+
+	h := &HealthThermometerService{}
+
+	srv, err := addService(app, HealthThermometerUUID, true)
+	if err != nil {
+		return nil, err
+	}
+	h.srv = srv
+
+	char, err := addCharacteristic(srv, TemperatureMeasurementUUID, []string{bluez.FlagCharacteristicIndicate}, nil)
+	if err != nil {
+		return nil, err
+	}
+	h.measurement = newIndicator(char)
+
+	if config.Type != 0 {
+		if _, err = addReadOnly(srv, TemperatureTypeUUID, []byte{config.Type}); err != nil {
+			return nil, err
+		}
+	}
+	if config.Intermediate {
+		h.intermediate, err = addCharacteristic(srv, IntermediateTemperatureUUID, []string{bluez.FlagCharacteristicNotify}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.Interval > 0 {
+		interval := make([]byte, 2)
+		binary.LittleEndian.PutUint16(interval, clamp16(int(config.Interval/time.Second)))
+		if _, err = addReadOnly(srv, MeasurementIntervalUUID, interval); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+//Service return the GATT service
+func (h *HealthThermometerService) Service() *service.GattService1 {
+	return h.srv
+}
+
+//SendMeasurement indicate a measurement and wait for its confirmation. It
+// returns ErrNotSubscribed if the collector is not subscribed, the
+// measurement should then be stored until it is
+func (h *HealthThermometerService) SendMeasurement(m TemperatureMeasurement) error {
+	return h.measurement.indicate(m.Bytes())
+}
+
+//SendIntermediate notify an intermediate temperature
+func (h *HealthThermometerService) SendIntermediate(m TemperatureMeasurement) {
+	if h.intermediate != nil {
+		h.intermediate.UpdateValue(m.Bytes())
+	}
+}
+
+//BloodPressureMeasurement a measurement of the Blood Pressure Service
+type BloodPressureMeasurement struct {
+	// Systolic, Diastolic and MeanArterial pressures in mmHg, or kPa
+	Systolic     float64
+	Diastolic    float64
+	MeanArterial float64
+	KPa          bool
+	// Time of the measurement, not sent if zero
+	Time time.Time
+	// PulseRate in beats per minute, not sent if zero
+	PulseRate float64
+	// UserID not sent if zero
+	UserID uint8
+	// Status the measurement status, a bitmask of the BP* features, not sent
+	// if zero
+	Status uint16
+}
+
+//Bytes encode the measurement
+func (m BloodPressureMeasurement) Bytes() []byte {
+	b := []byte{0}
+	if m.KPa {
+		b[0] |= 1 << 0
+	}
+	b = append(b, NewSFloat(m.Systolic).Bytes()...)
+	b = append(b, NewSFloat(m.Diastolic).Bytes()...)
+	b = append(b, NewSFloat(m.MeanArterial).Bytes()...)
+	if !m.Time.IsZero() {
+		b[0] |= 1 << 1
+		b = append(b, DateTime(m.Time)...)
+	}
+	if m.PulseRate != 0 {
+		b[0] |= 1 << 2
+		b = append(b, NewSFloat(m.PulseRate).Bytes()...)
+	}
+	if m.UserID != 0 {
+		b[0] |= 1 << 3
+		b = append(b, m.UserID)
+	}
+	if m.Status != 0 {
+		b[0] |= 1 << 4
+		b = append(b, byte(m.Status), byte(m.Status>>8))
+	}
+	return b
+}
+
+//BloodPressureService the Blood Pressure Service (0x1810)
+type BloodPressureService struct {
+	srv          *service.GattService1
+	measurement  *indicator
+	intermediate *service.GattCharacteristic1
+}
+
+//NewBloodPressureService add a Blood Pressure Service to a running
+// application, with the BP* features supported by the sensor. intermediate
+// exposes the Intermediate Cuff Pressure, notified while measuring
+func NewBloodPressureService(app *service.Application, features uint16, intermediate bool) (*BloodPressureService, error) {
+
+	s := &BloodPressureService{}
+
+	srv, err := addService(app, BloodPressureUUID, true)
+	if err != nil {
+		return nil, err
+	}
+	s.srv = srv
+
+	char, err := addCharacteristic(srv, BloodPressureMeasurementUUID, []string{bluez.FlagCharacteristicIndicate}, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.measurement = newIndicator(char)
+
+	if intermediate {
+		s.intermediate, err = addCharacteristic(srv, IntermediateCuffPressureUUID, []string{bluez.FlagCharacteristicNotify}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, features)
+	if _, err = addReadOnly(srv, BloodPressureFeatureUUID, b); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+//Service return the GATT service
+func (s *BloodPressureService) Service() *service.GattService1 {
+	return s.srv
+}
+
+//SendMeasurement indicate a measurement and wait for its confirmation, see
+// HealthThermometerService.SendMeasurement
+func (s *BloodPressureService) SendMeasurement(m BloodPressureMeasurement) error {
+	return s.measurement.indicate(m.Bytes())
+}
+
+//SendIntermediate notify the current cuff pressure, as the Systolic value.
+// The other pressures are sent as NaN
+func (s *BloodPressureService) SendIntermediate(m BloodPressureMeasurement) {
+	if s.intermediate == nil {
+		return
+	}
+	m.Diastolic, m.MeanArterial = math.NaN(), math.NaN()
+	s.intermediate.UpdateValue(m.Bytes())
+}
@@ -0,0 +1,185 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Heart Rate Service UUIDs
+var (
+	HeartRateServiceUUID      = UUID("180D")
+	HeartRateMeasurementUUID  = UUID("2A37")
+	BodySensorLocationUUID    = UUID("2A38")
+	HeartRateControlPointUUID = UUID("2A39")
+)
+
+// Body sensor locations
+const (
+	LocationOther   uint8 = 0
+	LocationChest   uint8 = 1
+	LocationWrist   uint8 = 2
+	LocationFinger  uint8 = 3
+	LocationHand    uint8 = 4
+	LocationEarLobe uint8 = 5
+	LocationFoot    uint8 = 6
+)
+
+//SensorContact the contact status reported with a measurement
+type SensorContact int
+
+// Sensor contact status
+const (
+	ContactNotSupported SensorContact = iota
+	ContactNotDetected
+	ContactDetected
+)
+
+//ResetEnergyExpended the command of the Heart Rate Control Point
+const ResetEnergyExpended = 0x01
+
+//ErrControlPointNotSupported the error of an unknown control point value,
+// bluetoothd maps org.bluez.Error.Failed to the ATT error 0x80
+var ErrControlPointNotSupported = bluez.ErrFailed.WithMessage("Control Point value not supported")
+
+// Heart Rate Measurement flags
+const (
+	hrFlagUint16        = 1 << 0
+	hrFlagContact       = 1 << 1
+	hrFlagContactActive = 1 << 2
+	hrFlagEnergy        = 1 << 3
+	hrFlagRR            = 1 << 4
+)
+
+//HeartRateMeasurementSize the maximum size of a measurement, the value of a
+// notification with the default ATT MTU
+const HeartRateMeasurementSize = 20
+
+//EncodeHeartRateMeasurement encode a Heart Rate Measurement: bpm in beats
+// per minute, the RR intervals since the previous measurement, the energy
+// expended in kJ or a negative value to omit it. The oldest RR intervals are
+// dropped if the value is larger than HeartRateMeasurementSize
+func EncodeHeartRateMeasurement(bpm int, rrIntervals []time.Duration, contact SensorContact, energy int) []byte {
+
+	b := []byte{0}
+	if bpm > 0xff {
+		b[0] |= hrFlagUint16
+		b = append(b, 0, 0)
+		binary.LittleEndian.PutUint16(b[1:], clamp16(bpm))
+	} else {
+		b = append(b, byte(clampInt(bpm, 0, 0xff)))
+	}
+
+	switch contact {
+	case ContactDetected:
+		b[0] |= hrFlagContact | hrFlagContactActive
+	case ContactNotDetected:
+		b[0] |= hrFlagContactActive
+	}
+
+	if energy >= 0 {
+		b[0] |= hrFlagEnergy
+		b = append(b, 0, 0)
+		binary.LittleEndian.PutUint16(b[len(b)-2:], clamp16(energy))
+	}
+
+	if len(rrIntervals) > 0 {
+		b[0] |= hrFlagRR
+		max := (HeartRateMeasurementSize - len(b)) / 2
+		if len(rrIntervals) > max {
+			rrIntervals = rrIntervals[len(rrIntervals)-max:]
+		}
+		for _, rr := range rrIntervals {
+			// in units of 1/1024 second
+			v := clamp16(int(rr * 1024 / time.Second))
+			b = append(b, byte(v), byte(v>>8))
+		}
+	}
+
+	return b
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func clamp16(v int) uint16 {
+	return uint16(clampInt(v, 0, 0xffff))
+}
+
+//HeartRateService the Heart Rate Service (0x180D)
+type HeartRateService struct {
+	// OnResetEnergy called when the collector resets the energy expended with
+	// the control point, the following measurements should restart from zero
+	OnResetEnergy func()
+
+	srv         *service.GattService1
+	measurement *service.GattCharacteristic1
+}
+
+//NewHeartRateService add a Heart Rate Service to a running application. The
+// Body Sensor Location is exposed unless location is negative, the control
+// point is exposed if energy is true, for the sensors reporting the energy
+// expended
+func NewHeartRateService(app *service.Application, location int, energy bool) (*HeartRateService, error) {
+
+	h := &HeartRateService{}
+
+	srv, err := addService(app, HeartRateServiceUUID)
+	if err != nil {
+		return nil, err
+	}
+	h.srv = srv
+
+	h.measurement, err = addCharacteristic(srv, HeartRateMeasurementUUID,
+		[]string{bluez.FlagCharacteristicNotify}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if location >= 0 {
+		if _, err = addReadOnly(srv, BodySensorLocationUUID, []byte{uint8(location)}); err != nil {
+			return nil, err
+		}
+	}
+
+	if energy {
+		_, err = addWritable(srv, HeartRateControlPointUUID,
+			[]string{bluez.FlagCharacteristicWrite}, nil, h.control)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// control handle the writes of the control point
+func (h *HeartRateService) control(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+	if len(value) != 1 || value[0] != ResetEnergyExpended {
+		return ErrControlPointNotSupported
+	}
+	if h.OnResetEnergy != nil {
+		h.OnResetEnergy()
+	}
+	return nil
+}
+
+//Service return the GATT service
+func (h *HeartRateService) Service() *service.GattService1 {
+	return h.srv
+}
+
+//SendMeasurement notify a measurement to the subscribers, see
+// EncodeHeartRateMeasurement
+func (h *HeartRateService) SendMeasurement(bpm int, rrIntervals []time.Duration, contact SensorContact, energy int) {
+	h.measurement.UpdateValue(EncodeHeartRateMeasurement(bpm, rrIntervals, contact, energy))
+}
@@ -0,0 +1,304 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// HID Service UUIDs
+var (
+	HIDServiceUUID         = UUID("1812")
+	HIDInformationUUID     = UUID("2A4A")
+	ReportMapUUID          = UUID("2A4B")
+	HIDControlPointUUID    = UUID("2A4C")
+	ReportUUID             = UUID("2A4D")
+	ProtocolModeUUID       = UUID("2A4E")
+	BootKeyboardInputUUID  = UUID("2A22")
+	BootKeyboardOutputUUID = UUID("2A32")
+	BootMouseInputUUID     = UUID("2A33")
+	// ReportReferenceUUID the Report Reference descriptor of a report
+	ReportReferenceUUID = UUID("2908")
+)
+
+// Report types
+const (
+	ReportInput   uint8 = 0x01
+	ReportOutput  uint8 = 0x02
+	ReportFeature uint8 = 0x03
+)
+
+// Protocol modes
+const (
+	ProtocolBoot   uint8 = 0x00
+	ProtocolReport uint8 = 0x01
+)
+
+// Flags of the HID Information
+const (
+	HIDRemoteWake          uint8 = 1 << 0
+	HIDNormallyConnectable uint8 = 1 << 1
+)
+
+// Appearances of the HID devices, to advertise
+const (
+	AppearanceKeyboard uint16 = 0x03C1
+	AppearanceMouse    uint16 = 0x03C2
+	AppearanceGamepad  uint16 = 0x03C4
+)
+
+//HIDVersion the version of the HID specification, 1.11
+const HIDVersion = 0x0111
+
+// Commands of the HID Control Point
+const (
+	hidSuspend     = 0x00
+	hidExitSuspend = 0x01
+)
+
+//Report a report of the report map
+type Report struct {
+	// ID the report ID, zero if the map has no report IDs
+	ID   uint8
+	Type uint8
+}
+
+//HIDConfig configure a HID Service
+type HIDConfig struct {
+	// ReportMap the report descriptor, see the hid package
+	ReportMap []byte
+	// Reports the reports of the map, each is exposed as a Report
+	// characteristic
+	Reports []Report
+	// CountryCode the localization of the hardware, zero if not localized
+	CountryCode uint8
+	// Flags of the HID Information, eg. HIDNormallyConnectable
+	Flags uint8
+	// BootKeyboard and BootMouse expose the boot protocol reports, for the
+	// hosts with a minimal HID support
+	BootKeyboard bool
+	BootMouse    bool
+	// OnReport called when the host writes an output or feature report. The
+	// boot keyboard output report has ID zero
+	OnReport func(id uint8, kind uint8, value []byte)
+	// OnSuspend called when the host enters or exits the suspend state
+	OnSuspend func(suspended bool)
+}
+
+// hidReport a report characteristic and its last value
+type hidReport struct {
+	Report
+	char  *service.GattCharacteristic1
+	value []byte
+}
+
+//HIDService the HID Service (0x1812) of a HID over GATT device. Hosts require
+// an encrypted link, create the application with a SecurityPolicy requiring
+// encryption
+type HIDService struct {
+	srv    *service.GattService1
+	config HIDConfig
+
+	mutex     sync.Mutex
+	reports   map[Report]*hidReport
+	boot      map[string]*hidReport
+	protocol  uint8
+	suspended bool
+}
+
+//NewHIDService add a HID Service to a running application, the service is
+// advertised
+func NewHIDService(app *service.Application, config HIDConfig) (*HIDService, error) {
+
+	h := &HIDService{
+		config:   config,
+		reports:  make(map[Report]*hidReport),
+		boot:     make(map[string]*hidReport),
+		protocol: ProtocolReport,
+	}
+
+	srv, err := addService(app, HIDServiceUUID, true)
+	if err != nil {
+		return nil, err
+	}
+	h.srv = srv
+
+	info := make([]byte, 4)
+	binary.LittleEndian.PutUint16(info, HIDVersion)
+	info[2] = config.CountryCode
+	info[3] = config.Flags
+	if _, err = addReadOnly(srv, HIDInformationUUID, info); err != nil {
+		return nil, err
+	}
+	if _, err = addReadOnly(srv, ReportMapUUID, append([]byte{}, config.ReportMap...)); err != nil {
+		return nil, err
+	}
+
+	_, err = addWritable(h.srv, HIDControlPointUUID, []string{bluez.FlagCharacteristicWriteWithoutResponse}, nil,
+		func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+			if len(value) != 1 || value[0] > hidExitSuspend {
+				return nil
+			}
+			h.mutex.Lock()
+			h.suspended = value[0] == hidSuspend
+			h.mutex.Unlock()
+			if h.config.OnSuspend != nil {
+				h.config.OnSuspend(value[0] == hidSuspend)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	if config.BootKeyboard || config.BootMouse {
+		_, err = addWritable(h.srv, ProtocolModeUUID,
+			[]string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicWriteWithoutResponse},
+			func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+				return []byte{h.ProtocolMode()}, nil
+			},
+			func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+				// the invalid values are ignored, per specification
+				if len(value) == 1 && value[0] <= ProtocolReport {
+					h.mutex.Lock()
+					h.protocol = value[0]
+					h.mutex.Unlock()
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range config.Reports {
+		report, err := h.addReport(ReportUUID, r)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = addDescriptor(report.char, ReportReferenceUUID, []byte{r.ID, r.Type}); err != nil {
+			return nil, err
+		}
+		h.reports[r] = report
+	}
+
+	if config.BootKeyboard {
+		if h.boot[BootKeyboardInputUUID], err = h.addReport(BootKeyboardInputUUID, Report{Type: ReportInput}); err != nil {
+			return nil, err
+		}
+		if h.boot[BootKeyboardOutputUUID], err = h.addReport(BootKeyboardOutputUUID, Report{Type: ReportOutput}); err != nil {
+			return nil, err
+		}
+	}
+	if config.BootMouse {
+		if h.boot[BootMouseInputUUID], err = h.addReport(BootMouseInputUUID, Report{Type: ReportInput}); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// addReport add the characteristic of a report
+func (h *HIDService) addReport(uuid string, r Report) (*hidReport, error) {
+
+	report := &hidReport{Report: r}
+	read := func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		return report.value, nil
+	}
+
+	var err error
+	switch r.Type {
+	case ReportInput:
+		report.char, err = addCharacteristic(h.srv, uuid,
+			[]string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicNotify}, read)
+	case ReportOutput, ReportFeature:
+		flags := []string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicWrite}
+		if r.Type == ReportOutput {
+			flags = append(flags, bluez.FlagCharacteristicWriteWithoutResponse)
+		}
+		report.char, err = addWritable(h.srv, uuid, flags, read,
+			func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+				h.mutex.Lock()
+				report.value = append([]byte{}, value...)
+				h.mutex.Unlock()
+				if h.config.OnReport != nil {
+					h.config.OnReport(r.ID, r.Type, value)
+				}
+				return nil
+			})
+	default:
+		return nil, fmt.Errorf("Invalid report type %d", r.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+//Service return the GATT service
+func (h *HIDService) Service() *service.GattService1 {
+	return h.srv
+}
+
+//ProtocolMode return the protocol selected by the host, ProtocolReport
+// unless a boot host selected ProtocolBoot
+func (h *HIDService) ProtocolMode() uint8 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.protocol
+}
+
+//Suspended return true if the host entered the suspend state
+func (h *HIDService) Suspended() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.suspended
+}
+
+//SendReport notify an input report, without the report ID
+func (h *HIDService) SendReport(id uint8, value []byte) error {
+	h.mutex.Lock()
+	report, ok := h.reports[Report{ID: id, Type: ReportInput}]
+	h.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("No input report %d", id)
+	}
+	return h.send(report, value)
+}
+
+//SendBootKeyboard notify a boot keyboard input report: the modifiers, a
+// reserved byte and up to 6 key codes
+func (h *HIDService) SendBootKeyboard(value []byte) error {
+	return h.sendBoot(BootKeyboardInputUUID, value)
+}
+
+//SendBootMouse notify a boot mouse input report: the buttons, the X and Y
+// displacements
+func (h *HIDService) SendBootMouse(value []byte) error {
+	return h.sendBoot(BootMouseInputUUID, value)
+}
+
+func (h *HIDService) sendBoot(uuid string, value []byte) error {
+	h.mutex.Lock()
+	report, ok := h.boot[uuid]
+	h.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("Boot report %s not enabled", uuid)
+	}
+	return h.send(report, value)
+}
+
+func (h *HIDService) send(report *hidReport, value []byte) error {
+	value = append([]byte{}, value...)
+	h.mutex.Lock()
+	report.value = value
+	h.mutex.Unlock()
+	report.char.UpdateValue(value)
+	return nil
+}
@@ -0,0 +1,200 @@
+package gatt
+
+import (
+	"errors"
+
+	"github.com/muka/go-bluetooth/hid"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Keyboard modifiers, the first byte of a keyboard report
+const (
+	ModLeftCtrl   uint8 = 1 << 0
+	ModLeftShift  uint8 = 1 << 1
+	ModLeftAlt    uint8 = 1 << 2
+	ModLeftGUI    uint8 = 1 << 3
+	ModRightCtrl  uint8 = 1 << 4
+	ModRightShift uint8 = 1 << 5
+	ModRightAlt   uint8 = 1 << 6
+	ModRightGUI   uint8 = 1 << 7
+)
+
+// Keyboard LEDs, the output report of a keyboard
+const (
+	LEDNumLock    uint8 = 1 << 0
+	LEDCapsLock   uint8 = 1 << 1
+	LEDScrollLock uint8 = 1 << 2
+	LEDCompose    uint8 = 1 << 3
+	LEDKana       uint8 = 1 << 4
+)
+
+//KeyboardReportMap the report map of a boot compatible keyboard: report 1
+// with the modifiers, a reserved byte and 6 key codes, the LEDs as output
+func KeyboardReportMap() []byte {
+	return hid.New().
+		UsagePage(hid.PageGenericDesktop).Usage(hid.UsageKeyboard).
+		Collection(hid.CollectionApplication).
+		ReportID(1).
+		// modifiers
+		UsagePage(hid.PageKeyboard).UsageRange(0xE0, 0xE7).Logical(0, 1).
+		ReportSize(1).ReportCount(8).Input(hid.Variable).
+		// reserved
+		ReportSize(8).ReportCount(1).Input(hid.Constant).
+		// LEDs
+		UsagePage(hid.PageLED).UsageRange(1, 5).ReportSize(1).ReportCount(5).Output(hid.Variable).
+		ReportSize(3).ReportCount(1).Output(hid.Constant).
+		// keys
+		UsagePage(hid.PageKeyboard).UsageRange(0, 0x65).Logical(0, 0x65).
+		ReportSize(8).ReportCount(6).Input(0).
+		EndCollection().
+		MustBuild()
+}
+
+//Keyboard a HID keyboard, with the boot protocol
+type Keyboard struct {
+	*HIDService
+}
+
+//NewKeyboard add the HID Service of a keyboard to a running application,
+// onLEDs is called when the host sets the LEDs, it can be nil
+func NewKeyboard(app *service.Application, onLEDs func(leds uint8)) (*Keyboard, error) {
+	h, err := NewHIDService(app, HIDConfig{
+		ReportMap:    KeyboardReportMap(),
+		Reports:      []Report{{ID: 1, Type: ReportInput}, {ID: 1, Type: ReportOutput}},
+		Flags:        HIDNormallyConnectable | HIDRemoteWake,
+		BootKeyboard: true,
+		OnReport: func(id uint8, kind uint8, value []byte) {
+			if onLEDs != nil && kind == ReportOutput && len(value) > 0 {
+				onLEDs(value[0])
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Keyboard{h}, nil
+}
+
+//Press notify the modifiers and keys held, up to 6 key codes of the keyboard
+// usage page, eg. 0x04 for a
+func (k *Keyboard) Press(modifiers uint8, keys ...uint8) error {
+	if len(keys) > 6 {
+		return errors.New("At most 6 keys can be pressed at once")
+	}
+	report := make([]byte, 8)
+	report[0] = modifiers
+	copy(report[2:], keys)
+	if k.ProtocolMode() == ProtocolBoot {
+		return k.SendBootKeyboard(report)
+	}
+	return k.SendReport(1, report)
+}
+
+//Release notify that all the keys are released
+func (k *Keyboard) Release() error {
+	return k.Press(0)
+}
+
+// Mouse buttons
+const (
+	ButtonLeft   uint8 = 1 << 0
+	ButtonRight  uint8 = 1 << 1
+	ButtonMiddle uint8 = 1 << 2
+)
+
+//MouseReportMap the report map of a boot compatible mouse: report 1 with 3
+// buttons, the X and Y displacements and the wheel
+func MouseReportMap() []byte {
+	return hid.New().
+		UsagePage(hid.PageGenericDesktop).Usage(hid.UsageMouse).
+		Collection(hid.CollectionApplication).
+		ReportID(1).
+		Usage(hid.UsagePointer).
+		Collection(hid.CollectionPhysical).
+		UsagePage(hid.PageButton).UsageRange(1, 3).Logical(0, 1).
+		ReportSize(1).ReportCount(3).Input(hid.Variable).
+		ReportSize(5).ReportCount(1).Input(hid.Constant).
+		UsagePage(hid.PageGenericDesktop).Usage(hid.UsageX).Usage(hid.UsageY).Usage(hid.UsageWheel).
+		Logical(-127, 127).ReportSize(8).ReportCount(3).Input(hid.Variable | hid.Relative).
+		EndCollection().
+		EndCollection().
+		MustBuild()
+}
+
+//Mouse a HID mouse, with the boot protocol
+type Mouse struct {
+	*HIDService
+}
+
+//NewMouse add the HID Service of a mouse to a running application
+func NewMouse(app *service.Application) (*Mouse, error) {
+	h, err := NewHIDService(app, HIDConfig{
+		ReportMap: MouseReportMap(),
+		Reports:   []Report{{ID: 1, Type: ReportInput}},
+		Flags:     HIDNormallyConnectable,
+		BootMouse: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Mouse{h}, nil
+}
+
+//Move notify the buttons held and a relative movement, the wheel is not
+// reported in the boot protocol
+func (m *Mouse) Move(buttons uint8, dx int8, dy int8, wheel int8) error {
+	if m.ProtocolMode() == ProtocolBoot {
+		return m.SendBootMouse([]byte{buttons, byte(dx), byte(dy)})
+	}
+	return m.SendReport(1, []byte{buttons, byte(dx), byte(dy), byte(wheel)})
+}
+
+//GamepadState the state of a gamepad: 16 buttons and 4 axes
+type GamepadState struct {
+	// Buttons a bitmask, bit 0 for button 1
+	Buttons uint16
+	// X, Y the left stick, Z, Rz the right stick
+	X, Y, Z, Rz int8
+}
+
+//GamepadReportMap the report map of a gamepad: report 1 with 16 buttons and
+// 4 axes
+func GamepadReportMap() []byte {
+	return hid.New().
+		UsagePage(hid.PageGenericDesktop).Usage(hid.UsageGamepad).
+		Collection(hid.CollectionApplication).
+		ReportID(1).
+		UsagePage(hid.PageButton).UsageRange(1, 16).Logical(0, 1).
+		ReportSize(1).ReportCount(16).Input(hid.Variable).
+		UsagePage(hid.PageGenericDesktop).
+		Usage(hid.UsageX).Usage(hid.UsageY).Usage(hid.UsageZ).Usage(hid.UsageRz).
+		Logical(-127, 127).ReportSize(8).ReportCount(4).Input(hid.Variable).
+		EndCollection().
+		MustBuild()
+}
+
+//Gamepad a HID gamepad
+type Gamepad struct {
+	*HIDService
+}
+
+//NewGamepad add the HID Service of a gamepad to a running application
+func NewGamepad(app *service.Application) (*Gamepad, error) {
+	h, err := NewHIDService(app, HIDConfig{
+		ReportMap: GamepadReportMap(),
+		Reports:   []Report{{ID: 1, Type: ReportInput}},
+		Flags:     HIDNormallyConnectable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Gamepad{h}, nil
+}
+
+//Send notify the state of the gamepad
+func (g *Gamepad) Send(state GamepadState) error {
+	return g.SendReport(1, []byte{
+		byte(state.Buttons), byte(state.Buttons >> 8),
+		byte(state.X), byte(state.Y), byte(state.Z), byte(state.Rz),
+	})
+}
@@ -0,0 +1,70 @@
+package gatt
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth/service"
+)
+
+//DefaultIndicationTimeout the time allowed to the remote device to confirm
+// an indication, the ATT transaction timeout
+const DefaultIndicationTimeout = 30 * time.Second
+
+var (
+	//ErrNotSubscribed no remote device subscribed to the indications
+	ErrNotSubscribed = errors.New("No subscriber for the indication")
+	//ErrNotConfirmed the indication was not confirmed in time
+	ErrNotConfirmed = errors.New("Indication not confirmed")
+)
+
+// indicator send the indications of a characteristic one at a time, waiting
+// for the confirmation of each
+type indicator struct {
+	char    *service.GattCharacteristic1
+	timeout time.Duration
+	mutex   sync.Mutex
+	confirm chan struct{}
+}
+
+func newIndicator(char *service.GattCharacteristic1) *indicator {
+	i := &indicator{
+		char:    char,
+		timeout: DefaultIndicationTimeout,
+		confirm: make(chan struct{}, 1),
+	}
+	char.SetConfirmFunc(func(c *service.GattCharacteristic1) {
+		select {
+		case i.confirm <- struct{}{}:
+		default:
+		}
+	})
+	return i
+}
+
+// indicate send a value and wait for the confirmation
+func (i *indicator) indicate(value []byte) error {
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if !i.char.Notifying() {
+		return ErrNotSubscribed
+	}
+
+	// a late confirmation of a previous indication
+	select {
+	case <-i.confirm:
+	default:
+	}
+
+	i.char.UpdateValue(value)
+
+	select {
+	case <-i.confirm:
+		return nil
+	case <-time.After(i.timeout):
+		return ErrNotConfirmed
+	}
+}
@@ -0,0 +1,130 @@
+package gatt
+
+import "math"
+
+//SFloat a 16 bit IEEE 11073-20601 float: a 4 bit exponent and a 12 bit
+// mantissa, value = mantissa * 10^exponent
+type SFloat uint16
+
+// Special SFLOAT values
+const (
+	SFloatNaN         SFloat = 0x07FF
+	SFloatNRes        SFloat = 0x0800
+	SFloatPosInfinity SFloat = 0x07FE
+	SFloatNegInfinity SFloat = 0x0802
+)
+
+//Float a 32 bit IEEE 11073-20601 float: an 8 bit exponent and a 24 bit
+// mantissa, value = mantissa * 10^exponent
+type Float uint32
+
+// Special FLOAT values
+const (
+	FloatNaN         Float = 0x007FFFFF
+	FloatNRes        Float = 0x00800000
+	FloatPosInfinity Float = 0x007FFFFE
+	FloatNegInfinity Float = 0x00800002
+)
+
+// medfloat find the most precise mantissa and exponent for v, the special
+// values of the mantissa are avoided. ok is false if v is out of range
+func medfloat(v float64, maxMantissa int64, minExp int, maxExp int) (int64, int, bool) {
+	for exp := minExp; exp <= maxExp; exp++ {
+		m := math.Floor(v/math.Pow10(exp) + 0.5)
+		if m > float64(maxMantissa) || m < -float64(maxMantissa) {
+			continue
+		}
+		mantissa := int64(m)
+		// drop the trailing zeros of the decimals, they carry no precision
+		for mantissa != 0 && mantissa%10 == 0 && exp < 0 {
+			mantissa /= 10
+			exp++
+		}
+		if mantissa == 0 {
+			exp = 0
+		}
+		return mantissa, exp, true
+	}
+	return 0, 0, false
+}
+
+//NewSFloat encode a value, with the most precise exponent
+func NewSFloat(v float64) SFloat {
+	switch {
+	case math.IsNaN(v):
+		return SFloatNaN
+	case math.IsInf(v, 1):
+		return SFloatPosInfinity
+	case math.IsInf(v, -1):
+		return SFloatNegInfinity
+	}
+	m, exp, ok := medfloat(v, 0x07FD, -8, 7)
+	if !ok {
+		if v > 0 {
+			return SFloatPosInfinity
+		}
+		return SFloatNegInfinity
+	}
+	return SFloat(uint16(exp&0x0F)<<12 | uint16(m&0x0FFF))
+}
+
+//Float64 decode the value, NRes is decoded as NaN
+func (f SFloat) Float64() float64 {
+	switch f {
+	case SFloatNaN, SFloatNRes, 0x0801:
+		return math.NaN()
+	case SFloatPosInfinity:
+		return math.Inf(1)
+	case SFloatNegInfinity:
+		return math.Inf(-1)
+	}
+	// sign extend the 12 bit mantissa and the 4 bit exponent
+	m := int64(int16(uint16(f)<<4) >> 4)
+	exp := int(int8(uint8(f>>12)<<4) >> 4)
+	return float64(m) * math.Pow10(exp)
+}
+
+//Bytes encode the value, little endian
+func (f SFloat) Bytes() []byte {
+	return []byte{byte(f), byte(f >> 8)}
+}
+
+//NewFloat encode a value, with the most precise exponent
+func NewFloat(v float64) Float {
+	switch {
+	case math.IsNaN(v):
+		return FloatNaN
+	case math.IsInf(v, 1):
+		return FloatPosInfinity
+	case math.IsInf(v, -1):
+		return FloatNegInfinity
+	}
+	m, exp, ok := medfloat(v, 0x7FFFFD, -128, 127)
+	if !ok {
+		if v > 0 {
+			return FloatPosInfinity
+		}
+		return FloatNegInfinity
+	}
+	return Float(uint32(exp&0xFF)<<24 | uint32(m&0xFFFFFF))
+}
+
+//Float64 decode the value, NRes is decoded as NaN
+func (f Float) Float64() float64 {
+	switch f {
+	case FloatNaN, FloatNRes, 0x00800001:
+		return math.NaN()
+	case FloatPosInfinity:
+		return math.Inf(1)
+	case FloatNegInfinity:
+		return math.Inf(-1)
+	}
+	m := int64(int32(uint32(f)<<8) >> 8)
+	exp := int(int8(uint8(f >> 24)))
+	return float64(m) * math.Pow10(exp)
+}
+
+//Bytes encode the value, little endian
+func (f Float) Bytes() []byte {
+	return []byte{byte(f), byte(f >> 8), byte(f >> 16), byte(f >> 24)}
+}
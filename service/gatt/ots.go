@@ -0,0 +1,873 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/linux"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Object Transfer UUIDs
+var (
+	ObjectTransferUUID           = UUID("1825")
+	OTSFeatureUUID               = UUID("2ABD")
+	ObjectNameUUID               = UUID("2ABE")
+	ObjectTypeUUID               = UUID("2ABF")
+	ObjectSizeUUID               = UUID("2AC0")
+	ObjectIDUUID                 = UUID("2AC3")
+	ObjectPropertiesUUID         = UUID("2AC4")
+	ObjectActionControlPointUUID = UUID("2AC5")
+	ObjectListControlPointUUID   = UUID("2AC6")
+)
+
+// Object types
+var (
+	ObjectTypeUnspecified      = UUID("2ACA")
+	ObjectTypeDirectoryListing = UUID("2ACB")
+)
+
+//OTSPSM the PSM of the Object Transfer Channel on LE
+const OTSPSM uint16 = 0x0025
+
+//DefaultOTSTimeout the time allowed between two packets of a transfer on the
+// Object Transfer Channel
+const DefaultOTSTimeout = 30 * time.Second
+
+//MaxObjectNameSize the longest object name, in bytes
+const MaxObjectNameSize = 120
+
+// otsFirstID the first object ID assigned, the lower IDs are reserved, eg. 0
+// for the directory listing
+const otsFirstID uint64 = 0x100
+
+// Object properties
+const (
+	ObjectPropDelete   uint32 = 1 << 0
+	ObjectPropExecute  uint32 = 1 << 1
+	ObjectPropRead     uint32 = 1 << 2
+	ObjectPropWrite    uint32 = 1 << 3
+	ObjectPropAppend   uint32 = 1 << 4
+	ObjectPropTruncate uint32 = 1 << 5
+	ObjectPropPatch    uint32 = 1 << 6
+	ObjectPropMark     uint32 = 1 << 7
+)
+
+// OACP op codes
+const (
+	OACPCreate            uint8 = 0x01
+	OACPDelete            uint8 = 0x02
+	OACPCalculateChecksum uint8 = 0x03
+	OACPExecute           uint8 = 0x04
+	OACPRead              uint8 = 0x05
+	OACPWrite             uint8 = 0x06
+	OACPAbort             uint8 = 0x07
+	OACPResponse          uint8 = 0x60
+)
+
+// OACP result codes
+const (
+	OACPSuccess               uint8 = 0x01
+	OACPOpCodeNotSupported    uint8 = 0x02
+	OACPInvalidParameter      uint8 = 0x03
+	OACPInsufficientResources uint8 = 0x04
+	OACPInvalidObject         uint8 = 0x05
+	OACPChannelUnavailable    uint8 = 0x06
+	OACPUnsupportedType       uint8 = 0x07
+	OACPProcedureNotPermitted uint8 = 0x08
+	OACPObjectLocked          uint8 = 0x09
+	OACPOperationFailed       uint8 = 0x0A
+)
+
+// OLCP op codes
+const (
+	OLCPFirst         uint8 = 0x01
+	OLCPLast          uint8 = 0x02
+	OLCPPrevious      uint8 = 0x03
+	OLCPNext          uint8 = 0x04
+	OLCPGoTo          uint8 = 0x05
+	OLCPOrder         uint8 = 0x06
+	OLCPRequestNumber uint8 = 0x07
+	OLCPClearMarking  uint8 = 0x08
+	OLCPResponse      uint8 = 0x70
+)
+
+// OLCP result codes
+const (
+	OLCPSuccess            uint8 = 0x01
+	OLCPOpCodeNotSupported uint8 = 0x02
+	OLCPInvalidParameter   uint8 = 0x03
+	OLCPOperationFailed    uint8 = 0x04
+	OLCPOutOfBounds        uint8 = 0x05
+	OLCPTooManyObjects     uint8 = 0x06
+	OLCPNoObject           uint8 = 0x07
+	OLCPObjectIDNotFound   uint8 = 0x08
+)
+
+// OACP features of the OTS Feature characteristic
+const (
+	OACPFeatureCreate            uint32 = 1 << 0
+	OACPFeatureDelete            uint32 = 1 << 1
+	OACPFeatureCalculateChecksum uint32 = 1 << 2
+	OACPFeatureExecute           uint32 = 1 << 3
+	OACPFeatureRead              uint32 = 1 << 4
+	OACPFeatureWrite             uint32 = 1 << 5
+	OACPFeatureAppend            uint32 = 1 << 6
+	OACPFeatureTruncate          uint32 = 1 << 7
+	OACPFeaturePatch             uint32 = 1 << 8
+	OACPFeatureAbort             uint32 = 1 << 9
+)
+
+// OLCP features of the OTS Feature characteristic
+const (
+	OLCPFeatureGoTo          uint32 = 1 << 0
+	OLCPFeatureOrder         uint32 = 1 << 1
+	OLCPFeatureRequestNumber uint32 = 1 << 2
+	OLCPFeatureClearMarking  uint32 = 1 << 3
+)
+
+// the procedures supported by ObjectTransferService
+const (
+	otsActionFeatures = OACPFeatureDelete | OACPFeatureCalculateChecksum | OACPFeatureRead |
+		OACPFeatureWrite | OACPFeatureAppend | OACPFeatureTruncate | OACPFeaturePatch | OACPFeatureAbort
+	otsListFeatures = OLCPFeatureGoTo | OLCPFeatureRequestNumber
+)
+
+// oacpWriteTruncate the mode bit of an OACP Write truncating the object after
+// the data written
+const oacpWriteTruncate = 0x02
+
+//ErrObjectNotSelected the metadata of the current object are read before
+// an object is selected with the OLCP
+var ErrObjectNotSelected = bluez.ErrFailed.WithMessage("Object not selected")
+
+//Object the metadata of an object of an ObjectTransferService
+type Object struct {
+	// ID the 48bit object ID, assigned by AddObject
+	ID uint64
+	// Name up to MaxObjectNameSize bytes
+	Name string
+	// Type the UUID of the object type, ObjectTypeUnspecified if empty
+	Type string
+	// Size the current size of the contents
+	Size uint32
+	// Allocated the size the contents can grow to by appending, at least Size
+	Allocated uint32
+	// Properties a bitmask of ObjectProp*
+	Properties uint32
+}
+
+//ObjectTransferConfig configure an ObjectTransferService
+type ObjectTransferConfig struct {
+	// Timeout DefaultOTSTimeout if zero
+	Timeout time.Duration
+	// OnWrite called once an object has been written by a device
+	OnWrite func(device dbus.ObjectPath, id uint64)
+	// OnDelete called after a device deleted an object
+	OnDelete func(device dbus.ObjectPath, id uint64)
+}
+
+// objectChannel an Object Transfer Channel, an L2CAP CoC: a Read returns a
+// single SDU and a Write sends one
+type objectChannel interface {
+	io.ReadWriteCloser
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+type otsObject struct {
+	Object
+	data []byte
+	// transfers the reads and writes in progress
+	transfers int
+	writing   bool
+}
+
+// info return the metadata with the current size
+func (o *otsObject) info() Object {
+	info := o.Object
+	info.Size = uint32(len(o.data))
+	if info.Allocated < info.Size {
+		info.Allocated = info.Size
+	}
+	return info
+}
+
+// otsWrite the data of an OACP Write, received on the channel
+type otsWrite struct {
+	data chan []byte
+	done chan struct{}
+}
+
+// otsChannel the Object Transfer Channel of a device, one transfer at a time
+type otsChannel struct {
+	conn   objectChannel
+	mtu    int
+	closed chan struct{}
+
+	busy    bool
+	reading bool
+	abort   bool
+	write   *otsWrite
+}
+
+//ObjectTransferService the Object Transfer Service (0x1825): the objects are
+// selected by a device with the Object List Control Point, then read and
+// written with the Object Action Control Point on the Object Transfer
+// Channel, an LE CoC opened by the device, see Listen.
+//
+// Each device has its own current object. The responses of the control points
+// are indicated to all the subscribed devices, the clients match them by op
+// code
+type ObjectTransferService struct {
+	srv    *service.GattService1
+	app    *service.Application
+	config ObjectTransferConfig
+	oacp   *indicator
+	olcp   *indicator
+
+	mutex    sync.Mutex
+	objects  []*otsObject
+	nextID   uint64
+	current  map[dbus.ObjectPath]uint64
+	channels map[string]*otsChannel
+	listener *linux.L2CAPListener
+}
+
+//NewObjectTransferService add an Object Transfer Service to a running
+// application
+func NewObjectTransferService(app *service.Application, config ObjectTransferConfig) (*ObjectTransferService, error) {
+
+	if config.Timeout == 0 {
+		config.Timeout = DefaultOTSTimeout
+	}
+
+	s := &ObjectTransferService{
+		app:      app,
+		config:   config,
+		nextID:   otsFirstID,
+		current:  make(map[dbus.ObjectPath]uint64),
+		channels: make(map[string]*otsChannel),
+	}
+
+	srv, err := addService(app, ObjectTransferUUID)
+	if err != nil {
+		return nil, err
+	}
+	s.srv = srv
+
+	features := make([]byte, 8)
+	binary.LittleEndian.PutUint32(features[0:], otsActionFeatures)
+	binary.LittleEndian.PutUint32(features[4:], otsListFeatures)
+	if _, err = addReadOnly(srv, OTSFeatureUUID, features); err != nil {
+		return nil, err
+	}
+
+	metadata := []struct {
+		uuid   string
+		encode func(o Object) []byte
+	}{
+		{ObjectNameUUID, func(o Object) []byte { return []byte(o.Name) }},
+		{ObjectTypeUUID, func(o Object) []byte {
+			return bluetooth.MustParseUUID(o.Type).Bytes()
+		}},
+		{ObjectSizeUUID, func(o Object) []byte {
+			b := make([]byte, 8)
+			binary.LittleEndian.PutUint32(b[0:], o.Size)
+			binary.LittleEndian.PutUint32(b[4:], o.Allocated)
+			return b
+		}},
+		{ObjectIDUUID, func(o Object) []byte {
+			b := make([]byte, 6)
+			put48(b, o.ID)
+			return b
+		}},
+		{ObjectPropertiesUUID, func(o Object) []byte {
+			b := make([]byte, 4)
+			binary.LittleEndian.PutUint32(b, o.Properties)
+			return b
+		}},
+	}
+	for _, m := range metadata {
+		if _, err = addCharacteristic(srv, m.uuid, []string{bluez.FlagCharacteristicRead}, s.readMetadata(m.encode)); err != nil {
+			return nil, err
+		}
+	}
+
+	flags := []string{bluez.FlagCharacteristicWrite, bluez.FlagCharacteristicIndicate}
+	char, err := addWritable(srv, ObjectActionControlPointUUID, flags, nil, s.actionControl)
+	if err != nil {
+		return nil, err
+	}
+	s.oacp = newIndicator(char)
+
+	char, err = addWritable(srv, ObjectListControlPointUUID, flags, nil, s.listControl)
+	if err != nil {
+		return nil, err
+	}
+	s.olcp = newIndicator(char)
+
+	return s, nil
+}
+
+//Service return the GATT service
+func (s *ObjectTransferService) Service() *service.GattService1 {
+	return s.srv
+}
+
+//Listen accept the Object Transfer Channels of the devices on psm, OTSPSM if
+// zero. OTSPSM is a fixed PSM, binding it requires CAP_NET_BIND_SERVICE
+func (s *ObjectTransferService) Listen(psm uint16, opts linux.L2CAPOptions) error {
+
+	if psm == 0 {
+		psm = OTSPSM
+	}
+	l, err := linux.ListenL2CAP(psm, opts)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	if s.listener != nil {
+		s.mutex.Unlock()
+		l.Close()
+		return errors.New("Object Transfer Service already listening")
+	}
+	s.listener = l
+	s.mutex.Unlock()
+
+	go s.accept(l)
+	return nil
+}
+
+func (s *ObjectTransferService) accept(l *linux.L2CAPListener) {
+	for {
+		conn, err := l.AcceptL2CAP()
+		if err != nil {
+			s.app.Logger().Debugf("Object Transfer: accept: %s", err.Error())
+			return
+		}
+		addr, ok := conn.RemoteAddr().(*linux.BtAddr)
+		if !ok {
+			conn.Close()
+			continue
+		}
+		s.attach(addr.Address, conn, conn.SendMTU)
+	}
+}
+
+//Close stop listening and close the Object Transfer Channels
+func (s *ObjectTransferService) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+	for _, ch := range s.channels {
+		ch.conn.Close()
+	}
+}
+
+// attach the Object Transfer Channel of a device, replacing the previous one
+func (s *ObjectTransferService) attach(address string, conn objectChannel, mtu int) {
+
+	ch := &otsChannel{
+		conn:   conn,
+		mtu:    mtu,
+		closed: make(chan struct{}),
+	}
+
+	address = strings.ToUpper(address)
+	s.mutex.Lock()
+	old := s.channels[address]
+	s.channels[address] = ch
+	s.mutex.Unlock()
+
+	if old != nil {
+		old.conn.Close()
+	}
+	go s.receive(address, ch)
+}
+
+// receive the SDUs of a channel until it is closed, they are dropped when no
+// OACP Write is in progress
+func (s *ObjectTransferService) receive(address string, ch *otsChannel) {
+
+	defer func() {
+		s.mutex.Lock()
+		if s.channels[address] == ch {
+			delete(s.channels, address)
+		}
+		s.mutex.Unlock()
+		ch.conn.Close()
+		close(ch.closed)
+	}()
+
+	buf := make([]byte, 0xffff)
+	for {
+		n, err := ch.conn.Read(buf)
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		w := ch.write
+		s.mutex.Unlock()
+		if w == nil {
+			continue
+		}
+
+		sdu := make([]byte, n)
+		copy(sdu, buf[:n])
+		select {
+		case w.data <- sdu:
+		case <-w.done:
+		}
+	}
+}
+
+//AddObject add an object with its contents, the ID is assigned
+func (s *ObjectTransferService) AddObject(obj Object, data []byte) (uint64, error) {
+
+	if len(obj.Name) > MaxObjectNameSize {
+		return 0, fmt.Errorf("Object name longer than %d bytes", MaxObjectNameSize)
+	}
+	if obj.Type == "" {
+		obj.Type = ObjectTypeUnspecified
+	}
+	objectType, err := bluetooth.ParseUUID(obj.Type)
+	if err != nil {
+		return 0, err
+	}
+	obj.Type = objectType.String()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	obj.ID = s.nextID
+	s.nextID++
+	s.objects = append(s.objects, &otsObject{
+		Object: obj,
+		data:   append([]byte{}, data...),
+	})
+	return obj.ID, nil
+}
+
+//RemoveObject remove an object, return false if it does not exist
+func (s *ObjectTransferService) RemoveObject(id uint64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.remove(id)
+}
+
+func (s *ObjectTransferService) remove(id uint64) bool {
+	i := s.find(id)
+	if i < 0 {
+		return false
+	}
+	s.objects = append(s.objects[:i], s.objects[i+1:]...)
+	for device, current := range s.current {
+		if current == id {
+			delete(s.current, device)
+		}
+	}
+	return true
+}
+
+//Objects return the metadata of the objects, in the order of their IDs
+func (s *ObjectTransferService) Objects() []Object {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	list := make([]Object, 0, len(s.objects))
+	for _, o := range s.objects {
+		list = append(list, o.info())
+	}
+	return list
+}
+
+//ObjectData return a copy of the contents of an object
+func (s *ObjectTransferService) ObjectData(id uint64) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	i := s.find(id)
+	if i < 0 {
+		return nil, false
+	}
+	return append([]byte{}, s.objects[i].data...), true
+}
+
+// find return the index of an object, -1 if it does not exist
+func (s *ObjectTransferService) find(id uint64) int {
+	for i, o := range s.objects {
+		if o.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// selected return the index of the current object of a device, -1 if none
+func (s *ObjectTransferService) selected(device dbus.ObjectPath) int {
+	id, ok := s.current[device]
+	if !ok {
+		return -1
+	}
+	return s.find(id)
+}
+
+func (s *ObjectTransferService) readMetadata(encode func(o Object) []byte) service.CharacteristicReadFunc {
+	return func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+		device, _ := service.OptionDevice(options)
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		i := s.selected(device)
+		if i < 0 {
+			return nil, ErrObjectNotSelected
+		}
+		return readOffset(encode(s.objects[i].info()), options)
+	}
+}
+
+func (s *ObjectTransferService) listControl(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+
+	if len(value) == 0 {
+		return bluez.ErrInvalidValueLength
+	}
+	device, _ := service.OptionDevice(options)
+
+	s.mutex.Lock()
+	result, params := s.list(device, value[0], value[1:])
+	s.mutex.Unlock()
+
+	resp := append([]byte{OLCPResponse, value[0], result}, params...)
+	go s.olcp.indicate(resp)
+	return nil
+}
+
+// list handle an OLCP procedure, return the result code and the response
+// parameters
+func (s *ObjectTransferService) list(device dbus.ObjectPath, op uint8, params []byte) (uint8, []byte) {
+
+	switch op {
+	case OLCPFirst, OLCPLast, OLCPPrevious, OLCPNext, OLCPRequestNumber:
+		if len(params) != 0 {
+			return OLCPInvalidParameter, nil
+		}
+	case OLCPGoTo:
+		if len(params) != 6 {
+			return OLCPInvalidParameter, nil
+		}
+	default:
+		return OLCPOpCodeNotSupported, nil
+	}
+
+	if op == OLCPRequestNumber {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, uint32(len(s.objects)))
+		return OLCPSuccess, b
+	}
+	if len(s.objects) == 0 {
+		return OLCPNoObject, nil
+	}
+
+	switch op {
+	case OLCPFirst:
+		s.current[device] = s.objects[0].ID
+	case OLCPLast:
+		s.current[device] = s.objects[len(s.objects)-1].ID
+	case OLCPPrevious, OLCPNext:
+		i := s.selected(device)
+		if i < 0 {
+			return OLCPOperationFailed, nil
+		}
+		if op == OLCPPrevious {
+			i--
+		} else {
+			i++
+		}
+		if i < 0 || i >= len(s.objects) {
+			return OLCPOutOfBounds, nil
+		}
+		s.current[device] = s.objects[i].ID
+	case OLCPGoTo:
+		id := get48(params)
+		if s.find(id) < 0 {
+			return OLCPObjectIDNotFound, nil
+		}
+		s.current[device] = id
+	}
+	return OLCPSuccess, nil
+}
+
+func (s *ObjectTransferService) actionControl(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+
+	if len(value) == 0 {
+		return bluez.ErrInvalidValueLength
+	}
+	device, _ := service.OptionDevice(options)
+
+	s.mutex.Lock()
+	result, params, next := s.action(device, value[0], value[1:])
+	s.mutex.Unlock()
+
+	// the transfer starts once the response is indicated
+	resp := append([]byte{OACPResponse, value[0], result}, params...)
+	go func() {
+		s.oacp.indicate(resp)
+		if next != nil {
+			next()
+		}
+	}()
+	return nil
+}
+
+// action handle an OACP procedure, return the result code, the response
+// parameters and the function completing the procedure, if any
+func (s *ObjectTransferService) action(device dbus.ObjectPath, op uint8, params []byte) (uint8, []byte, func()) {
+
+	ch := s.channels[deviceAddress(device)]
+
+	switch op {
+	case OACPAbort:
+		if len(params) != 0 {
+			return OACPInvalidParameter, nil, nil
+		}
+		if ch == nil || !ch.reading {
+			return OACPOperationFailed, nil, nil
+		}
+		ch.abort = true
+		return OACPSuccess, nil, nil
+	case OACPDelete, OACPCalculateChecksum, OACPRead, OACPWrite:
+	default:
+		return OACPOpCodeNotSupported, nil, nil
+	}
+
+	i := s.selected(device)
+	if i < 0 {
+		return OACPInvalidObject, nil, nil
+	}
+	obj := s.objects[i]
+
+	if op == OACPDelete {
+		if len(params) != 0 {
+			return OACPInvalidParameter, nil, nil
+		}
+		if obj.Properties&ObjectPropDelete == 0 {
+			return OACPProcedureNotPermitted, nil, nil
+		}
+		if obj.transfers > 0 {
+			return OACPObjectLocked, nil, nil
+		}
+		s.remove(obj.ID)
+		return OACPSuccess, nil, func() {
+			if s.config.OnDelete != nil {
+				s.config.OnDelete(device, obj.ID)
+			}
+		}
+	}
+
+	size := 8
+	if op == OACPWrite {
+		size = 9
+	}
+	if len(params) != size {
+		return OACPInvalidParameter, nil, nil
+	}
+	offset := binary.LittleEndian.Uint32(params[0:])
+	length := binary.LittleEndian.Uint32(params[4:])
+	end := uint64(offset) + uint64(length)
+
+	switch op {
+	case OACPCalculateChecksum:
+		if obj.writing {
+			return OACPObjectLocked, nil, nil
+		}
+		if end > uint64(len(obj.data)) {
+			return OACPInvalidParameter, nil, nil
+		}
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, crc32.ChecksumIEEE(obj.data[offset:end]))
+		return OACPSuccess, b, nil
+
+	case OACPRead:
+		if obj.Properties&ObjectPropRead == 0 {
+			return OACPProcedureNotPermitted, nil, nil
+		}
+		if end > uint64(len(obj.data)) {
+			return OACPInvalidParameter, nil, nil
+		}
+		if ch == nil || ch.busy {
+			return OACPChannelUnavailable, nil, nil
+		}
+		if obj.writing {
+			return OACPObjectLocked, nil, nil
+		}
+		data := append([]byte{}, obj.data[offset:end]...)
+		obj.transfers++
+		ch.busy, ch.reading, ch.abort = true, true, false
+		return OACPSuccess, nil, func() {
+			s.sendObject(ch, obj, data)
+		}
+	}
+
+	// OACPWrite
+	truncate := params[8]&oacpWriteTruncate != 0
+	current := uint64(len(obj.data))
+	switch {
+	case obj.Properties&ObjectPropWrite == 0,
+		truncate && obj.Properties&ObjectPropTruncate == 0,
+		end > current && obj.Properties&ObjectPropAppend == 0,
+		// replacing the whole contents is not a patch
+		uint64(offset) < current && length > 0 && obj.Properties&ObjectPropPatch == 0 && !(offset == 0 && truncate):
+		return OACPProcedureNotPermitted, nil, nil
+	case uint64(offset) > current:
+		return OACPInvalidParameter, nil, nil
+	case end > uint64(obj.info().Allocated) && end > current:
+		return OACPInsufficientResources, nil, nil
+	}
+	if ch == nil || ch.busy {
+		return OACPChannelUnavailable, nil, nil
+	}
+	if obj.transfers > 0 {
+		return OACPObjectLocked, nil, nil
+	}
+
+	w := &otsWrite{
+		data: make(chan []byte, 16),
+		done: make(chan struct{}),
+	}
+	obj.transfers++
+	obj.writing = true
+	ch.busy = true
+	ch.write = w
+	return OACPSuccess, nil, func() {
+		s.receiveObject(device, ch, w, obj, offset, length, truncate)
+	}
+}
+
+// sendObject send the data of an OACP Read, until aborted
+func (s *ObjectTransferService) sendObject(ch *otsChannel, obj *otsObject, data []byte) {
+
+	for len(data) > 0 {
+		s.mutex.Lock()
+		abort := ch.abort
+		s.mutex.Unlock()
+		if abort {
+			break
+		}
+
+		n := ch.mtu
+		if n > len(data) {
+			n = len(data)
+		}
+		ch.conn.SetWriteDeadline(time.Now().Add(s.config.Timeout))
+		if _, err := ch.conn.Write(data[:n]); err != nil {
+			s.app.Logger().Debugf("Object Transfer: send object %d: %s", obj.ID, err.Error())
+			break
+		}
+		data = data[n:]
+	}
+
+	s.mutex.Lock()
+	obj.transfers--
+	ch.busy, ch.reading, ch.abort = false, false, false
+	s.mutex.Unlock()
+}
+
+// receiveObject receive the data of an OACP Write, the object is updated once
+// all the data has been received
+func (s *ObjectTransferService) receiveObject(device dbus.ObjectPath, ch *otsChannel, w *otsWrite, obj *otsObject, offset uint32, length uint32, truncate bool) {
+
+	buf := make([]byte, 0, length)
+
+receive:
+	for uint32(len(buf)) < length {
+		select {
+		case b := <-w.data:
+			buf = append(buf, b...)
+		case <-ch.closed:
+			break receive
+		case <-time.After(s.config.Timeout):
+			break receive
+		}
+	}
+
+	s.mutex.Lock()
+	ch.write = nil
+	close(w.done)
+	ch.busy = false
+	obj.transfers--
+	obj.writing = false
+
+	complete := uint32(len(buf)) >= length
+	if complete {
+		end := offset + length
+		var tail []byte
+		if !truncate && int(end) < len(obj.data) {
+			tail = obj.data[end:]
+		}
+		data := make([]byte, 0, int(end)+len(tail))
+		data = append(data, obj.data[:offset]...)
+		data = append(data, buf[:length]...)
+		obj.data = append(data, tail...)
+	}
+	s.mutex.Unlock()
+
+	if !complete {
+		s.app.Logger().Debugf("Object Transfer: write of object %d incomplete, %d of %d bytes", obj.ID, len(buf), length)
+		return
+	}
+	if s.config.OnWrite != nil {
+		s.config.OnWrite(device, obj.ID)
+	}
+}
+
+// deviceAddress return the address of a device from its object path
+func deviceAddress(device dbus.ObjectPath) string {
+	path := string(device)
+	i := strings.LastIndex(path, "/dev_")
+	if i < 0 {
+		return ""
+	}
+	return strings.Replace(path[i+5:], "_", ":", -1)
+}
+
+// readOffset return the part of a long value requested by a read
+func readOffset(value []byte, options map[string]interface{}) ([]byte, error) {
+	v, ok := options["offset"]
+	if !ok {
+		return value, nil
+	}
+	if variant, ok := v.(dbus.Variant); ok {
+		v = variant.Value()
+	}
+	offset, _ := v.(uint16)
+	if int(offset) > len(value) {
+		return nil, bluez.ErrInvalidOffset
+	}
+	return value[offset:], nil
+}
+
+func put48(b []byte, v uint64) {
+	for i := 0; i < 6; i++ {
+		b[i] = byte(v >> (8 * uint(i)))
+	}
+}
+
+func get48(b []byte) uint64 {
+	var v uint64
+	for i := 0; i < 6; i++ {
+		v |= uint64(b[i]) << (8 * uint(i))
+	}
+	return v
+}
@@ -0,0 +1,392 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/linux"
+)
+
+//OTSError an OACP or OLCP procedure failed with a result code, eg.
+// OACPInvalidObject
+type OTSError struct {
+	// ControlPoint the UUID of the control point, eg.
+	// ObjectActionControlPointUUID
+	ControlPoint string
+	OpCode       uint8
+	Result       uint8
+}
+
+func (e *OTSError) Error() string {
+	name := "OACP"
+	if e.ControlPoint == ObjectListControlPointUUID {
+		name = "OLCP"
+	}
+	return fmt.Sprintf("%s op code %d failed with result %d", name, e.OpCode, e.Result)
+}
+
+// otsCharacteristic the client operations used on the characteristics of the
+// service, implemented by *profile.GattCharacteristic1
+type otsCharacteristic interface {
+	ReadValue(options map[string]dbus.Variant) ([]byte, error)
+	WriteValue(b []byte, options map[string]dbus.Variant) error
+	Notifications() (<-chan []byte, func(), error)
+}
+
+var _ otsCharacteristic = (*profile.GattCharacteristic1)(nil)
+
+//ObjectTransferClientOptions configure an ObjectTransferClient
+type ObjectTransferClientOptions struct {
+	// PSM of the Object Transfer Channel, OTSPSM if zero
+	PSM uint16
+	// L2CAP the options of the channel, the address type is the one of the
+	// device if not set
+	L2CAP linux.L2CAPOptions
+	// Timeout of the responses and between two packets of a transfer,
+	// DefaultOTSTimeout if zero
+	Timeout time.Duration
+}
+
+//ObjectTransferClient the client of the Object Transfer Service of a device:
+// an object is selected with the OLCP procedures, eg. First or GoTo, then
+// read or written
+type ObjectTransferClient struct {
+	chars   map[string]otsCharacteristic
+	channel objectChannel
+	mtu     int
+	timeout time.Duration
+
+	actions <-chan []byte
+	lists   <-chan []byte
+	stops   []func()
+
+	// mutex serialize the procedures, a response is matched by its op code
+	mutex sync.Mutex
+	buf   []byte
+}
+
+//NewObjectTransferClient connect to the Object Transfer Service of a device
+// and open the Object Transfer Channel
+func NewObjectTransferClient(dev *api.Device, opts ObjectTransferClientOptions) (*ObjectTransferClient, error) {
+
+	chars := make(map[string]otsCharacteristic)
+	for _, uuid := range []string{
+		OTSFeatureUUID, ObjectNameUUID, ObjectTypeUUID, ObjectSizeUUID, ObjectIDUUID,
+		ObjectPropertiesUUID, ObjectActionControlPointUUID, ObjectListControlPointUUID,
+	} {
+		char, err := dev.GetCharByUUID(uuid)
+		if err != nil {
+			return nil, err
+		}
+		if char == nil {
+			return nil, fmt.Errorf("Characteristic %s not found", uuid)
+		}
+		chars[uuid] = char
+	}
+
+	props, err := dev.GetProperties()
+	if err != nil {
+		return nil, err
+	}
+	l2cap := opts.L2CAP
+	if l2cap.AddressType == 0 && props.AddressType == "random" {
+		l2cap.AddressType = linux.L2CAPAddressLERandom
+	}
+	psm := opts.PSM
+	if psm == 0 {
+		psm = OTSPSM
+	}
+	conn, err := linux.DialL2CAP(props.Address, psm, l2cap)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newObjectTransferClient(chars, conn, conn.SendMTU, opts.Timeout)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func newObjectTransferClient(chars map[string]otsCharacteristic, channel objectChannel, mtu int, timeout time.Duration) (*ObjectTransferClient, error) {
+
+	if timeout == 0 {
+		timeout = DefaultOTSTimeout
+	}
+	c := &ObjectTransferClient{
+		chars:   chars,
+		channel: channel,
+		mtu:     mtu,
+		timeout: timeout,
+		buf:     make([]byte, 0xffff),
+	}
+
+	var err error
+	var stop func()
+	c.actions, stop, err = chars[ObjectActionControlPointUUID].Notifications()
+	if err != nil {
+		return nil, err
+	}
+	c.stops = append(c.stops, stop)
+	c.lists, stop, err = chars[ObjectListControlPointUUID].Notifications()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.stops = append(c.stops, stop)
+	return c, nil
+}
+
+//Close stop the indications and close the Object Transfer Channel
+func (c *ObjectTransferClient) Close() {
+	for _, stop := range c.stops {
+		stop()
+	}
+	c.stops = nil
+	c.channel.Close()
+}
+
+// request run a procedure of a control point, return the response
+// parameters. A result other than success is returned as an *OTSError
+func (c *ObjectTransferClient) request(uuid string, value []byte) ([]byte, error) {
+
+	responses, code := c.actions, OACPResponse
+	if uuid == ObjectListControlPointUUID {
+		responses, code = c.lists, OLCPResponse
+	}
+
+	// drop the responses left by a previous timeout
+	for drained := false; !drained; {
+		select {
+		case <-responses:
+		default:
+			drained = true
+		}
+	}
+
+	if err := c.chars[uuid].WriteValue(value, nil); err != nil {
+		return nil, err
+	}
+
+	timeout := time.After(c.timeout)
+	for {
+		select {
+		case b, ok := <-responses:
+			if !ok {
+				return nil, errors.New("Object Transfer: indications stopped")
+			}
+			if len(b) < 3 || b[0] != code || b[1] != value[0] {
+				continue
+			}
+			if b[2] != OACPSuccess {
+				return nil, &OTSError{ControlPoint: uuid, OpCode: value[0], Result: b[2]}
+			}
+			return b[3:], nil
+		case <-timeout:
+			return nil, fmt.Errorf("Object Transfer: no response to op code %d", value[0])
+		}
+	}
+}
+
+func (c *ObjectTransferClient) list(op uint8, params ...byte) ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.request(ObjectListControlPointUUID, append([]byte{op}, params...))
+}
+
+//First select the first object
+func (c *ObjectTransferClient) First() error {
+	_, err := c.list(OLCPFirst)
+	return err
+}
+
+//Last select the last object
+func (c *ObjectTransferClient) Last() error {
+	_, err := c.list(OLCPLast)
+	return err
+}
+
+//Previous select the previous object, an *OTSError with OLCPOutOfBounds past
+// the first one
+func (c *ObjectTransferClient) Previous() error {
+	_, err := c.list(OLCPPrevious)
+	return err
+}
+
+//Next select the next object, an *OTSError with OLCPOutOfBounds past the last
+// one
+func (c *ObjectTransferClient) Next() error {
+	_, err := c.list(OLCPNext)
+	return err
+}
+
+//GoTo select an object by ID
+func (c *ObjectTransferClient) GoTo(id uint64) error {
+	b := make([]byte, 6)
+	put48(b, id)
+	_, err := c.list(OLCPGoTo, b...)
+	return err
+}
+
+//Count return the number of objects
+func (c *ObjectTransferClient) Count() (int, error) {
+	b, err := c.list(OLCPRequestNumber)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) < 4 {
+		return 0, errors.New("Invalid OLCP number of objects")
+	}
+	return int(binary.LittleEndian.Uint32(b)), nil
+}
+
+//Features return the OACP and OLCP features of the server, eg.
+// OACPFeatureWrite
+func (c *ObjectTransferClient) Features() (uint32, uint32, error) {
+	b, err := c.chars[OTSFeatureUUID].ReadValue(nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(b) < 8 {
+		return 0, 0, errors.New("Invalid OTS Feature value")
+	}
+	return binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[4:]), nil
+}
+
+//Current read the metadata of the current object
+func (c *ObjectTransferClient) Current() (*Object, error) {
+
+	values := make(map[string][]byte)
+	for uuid, size := range map[string]int{
+		ObjectNameUUID:       0,
+		ObjectTypeUUID:       2,
+		ObjectSizeUUID:       8,
+		ObjectIDUUID:         6,
+		ObjectPropertiesUUID: 4,
+	} {
+		b, err := c.chars[uuid].ReadValue(nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) < size {
+			return nil, fmt.Errorf("Invalid value of %s", uuid)
+		}
+		values[uuid] = b
+	}
+
+	objectType, err := bluetooth.UUIDFromBytes(values[ObjectTypeUUID])
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		ID:         get48(values[ObjectIDUUID]),
+		Name:       string(values[ObjectNameUUID]),
+		Type:       objectType.String(),
+		Size:       binary.LittleEndian.Uint32(values[ObjectSizeUUID]),
+		Allocated:  binary.LittleEndian.Uint32(values[ObjectSizeUUID][4:]),
+		Properties: binary.LittleEndian.Uint32(values[ObjectPropertiesUUID]),
+	}, nil
+}
+
+// rangeParams encode the offset and length of an OACP procedure
+func rangeParams(op uint8, offset uint32, length uint32) []byte {
+	b := make([]byte, 9)
+	b[0] = op
+	binary.LittleEndian.PutUint32(b[1:], offset)
+	binary.LittleEndian.PutUint32(b[5:], length)
+	return b
+}
+
+//Read length bytes of the current object from offset
+func (c *ObjectTransferClient) Read(offset uint32, length uint32) ([]byte, error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, err := c.request(ObjectActionControlPointUUID, rangeParams(OACPRead, offset, length)); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, length)
+	for uint32(len(data)) < length {
+		c.channel.SetReadDeadline(time.Now().Add(c.timeout))
+		n, err := c.channel.Read(c.buf)
+		if err != nil {
+			return nil, fmt.Errorf("Object Transfer: read %d of %d bytes: %s", len(data), length, err.Error())
+		}
+		data = append(data, c.buf[:n]...)
+	}
+	if uint32(len(data)) > length {
+		return nil, errors.New("Object Transfer: more data than requested")
+	}
+	return data, nil
+}
+
+//ReadAll read the contents of the current object
+func (c *ObjectTransferClient) ReadAll() ([]byte, error) {
+	obj, err := c.Current()
+	if err != nil {
+		return nil, err
+	}
+	return c.Read(0, obj.Size)
+}
+
+//Write data to the current object from offset, truncate drops the contents
+// after the data written
+func (c *ObjectTransferClient) Write(offset uint32, data []byte, truncate bool) error {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	req := rangeParams(OACPWrite, offset, uint32(len(data)))
+	mode := byte(0)
+	if truncate {
+		mode = oacpWriteTruncate
+	}
+	if _, err := c.request(ObjectActionControlPointUUID, append(req, mode)); err != nil {
+		return err
+	}
+
+	for len(data) > 0 {
+		n := c.mtu
+		if n > len(data) {
+			n = len(data)
+		}
+		c.channel.SetWriteDeadline(time.Now().Add(c.timeout))
+		if _, err := c.channel.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+//Checksum return the CRC-32 of length bytes of the current object from offset
+func (c *ObjectTransferClient) Checksum(offset uint32, length uint32) (uint32, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	b, err := c.request(ObjectActionControlPointUUID, rangeParams(OACPCalculateChecksum, offset, length))
+	if err != nil {
+		return 0, err
+	}
+	if len(b) < 4 {
+		return 0, errors.New("Invalid OACP checksum")
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+//Delete the current object
+func (c *ObjectTransferClient) Delete() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, err := c.request(ObjectActionControlPointUUID, []byte{OACPDelete})
+	return err
+}
@@ -0,0 +1,240 @@
+package gatt
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Pulse Oximeter UUIDs
+var (
+	PulseOximeterUUID           = UUID("1822")
+	PLXSpotCheckMeasurementUUID = UUID("2A5E")
+	PLXContinuousUUID           = UUID("2A5F")
+	PLXFeaturesUUID             = UUID("2A60")
+)
+
+//DefaultPLXRecords the spot checks kept by the store of
+// NewPulseOximeterService
+const DefaultPLXRecords = 100
+
+// Pulse Oximeter supported features
+const (
+	PLXMeasurementStatus  uint16 = 1 << 0
+	PLXDeviceStatus       uint16 = 1 << 1
+	PLXSpotCheckStorage   uint16 = 1 << 2
+	PLXSpotCheckTimestamp uint16 = 1 << 3
+	PLXFast               uint16 = 1 << 4
+	PLXSlow               uint16 = 1 << 5
+	PLXPulseAmplitude     uint16 = 1 << 6
+	PLXMultipleBonds      uint16 = 1 << 7
+)
+
+//PLXReading an oxygen saturation in percent and a pulse rate in beats per
+// minute
+type PLXReading struct {
+	SpO2      float64
+	PulseRate float64
+}
+
+func (r PLXReading) bytes() []byte {
+	return append(NewSFloat(r.SpO2).Bytes(), NewSFloat(r.PulseRate).Bytes()...)
+}
+
+//PLXSpotCheck a spot-check measurement of the Pulse Oximeter Service
+type PLXSpotCheck struct {
+	PLXReading
+	// Time of the measurement, sent with PLXSpotCheckTimestamp if not zero
+	Time time.Time
+	// ClockNotSet the device clock is not set, the time is not reliable
+	ClockNotSet bool
+	// MeasurementStatus sent with PLXMeasurementStatus
+	MeasurementStatus uint16
+	// DeviceStatus the device and sensor status, 24 bits, sent with
+	// PLXDeviceStatus
+	DeviceStatus uint32
+	// PulseAmplitude the pulse amplitude index in percent, sent with
+	// PLXPulseAmplitude
+	PulseAmplitude float64
+}
+
+//Bytes encode the measurement, with the fields of the supported features
+func (m PLXSpotCheck) Bytes(features uint16) []byte {
+	b := append([]byte{0}, m.bytes()...)
+	if !m.Time.IsZero() && features&PLXSpotCheckTimestamp != 0 {
+		b[0] |= 1 << 0
+		b = append(b, DateTime(m.Time)...)
+	}
+	if features&PLXMeasurementStatus != 0 {
+		b[0] |= 1 << 1
+		b = append(b, byte(m.MeasurementStatus), byte(m.MeasurementStatus>>8))
+	}
+	if features&PLXDeviceStatus != 0 {
+		b[0] |= 1 << 2
+		b = append(b, byte(m.DeviceStatus), byte(m.DeviceStatus>>8), byte(m.DeviceStatus>>16))
+	}
+	if features&PLXPulseAmplitude != 0 {
+		b[0] |= 1 << 3
+		b = append(b, NewSFloat(m.PulseAmplitude).Bytes()...)
+	}
+	if m.ClockNotSet {
+		b[0] |= 1 << 4
+	}
+	return b
+}
+
+//PLXContinuous a continuous measurement of the Pulse Oximeter Service
+type PLXContinuous struct {
+	Normal PLXReading
+	// Fast and Slow the readings of the fast and slow averaging, sent with
+	// PLXFast and PLXSlow
+	Fast PLXReading
+	Slow PLXReading
+	// MeasurementStatus, DeviceStatus and PulseAmplitude, see PLXSpotCheck
+	MeasurementStatus uint16
+	DeviceStatus      uint32
+	PulseAmplitude    float64
+}
+
+//Bytes encode the measurement, with the fields of the supported features
+func (m PLXContinuous) Bytes(features uint16) []byte {
+	b := append([]byte{0}, m.Normal.bytes()...)
+	if features&PLXFast != 0 {
+		b[0] |= 1 << 0
+		b = append(b, m.Fast.bytes()...)
+	}
+	if features&PLXSlow != 0 {
+		b[0] |= 1 << 1
+		b = append(b, m.Slow.bytes()...)
+	}
+	if features&PLXMeasurementStatus != 0 {
+		b[0] |= 1 << 2
+		b = append(b, byte(m.MeasurementStatus), byte(m.MeasurementStatus>>8))
+	}
+	if features&PLXDeviceStatus != 0 {
+		b[0] |= 1 << 3
+		b = append(b, byte(m.DeviceStatus), byte(m.DeviceStatus>>8), byte(m.DeviceStatus>>16))
+	}
+	if features&PLXPulseAmplitude != 0 {
+		b[0] |= 1 << 4
+		b = append(b, NewSFloat(m.PulseAmplitude).Bytes()...)
+	}
+	return b
+}
+
+//PulseOximeterConfig configure a Pulse Oximeter Service
+type PulseOximeterConfig struct {
+	// Features the PLX* features supported by the sensor
+	Features uint16
+	// MeasurementStatus and DeviceStatus the status bits supported, exposed
+	// with PLXMeasurementStatus and PLXDeviceStatus
+	MeasurementStatus uint16
+	DeviceStatus      uint32
+	// SpotCheck expose the spot-check measurements
+	SpotCheck bool
+	// Continuous expose the continuous measurements
+	Continuous bool
+	// Store the stored spot checks with PLXSpotCheckStorage, a
+	// MemoryRecordStore of DefaultPLXRecords if nil
+	Store RecordStore
+}
+
+//PulseOximeterService the Pulse Oximeter Service (0x1822)
+type PulseOximeterService struct {
+	srv        *service.GattService1
+	features   uint16
+	spotCheck  *indicator
+	continuous *service.GattCharacteristic1
+	store      RecordStore
+	racp       *racp
+}
+
+//NewPulseOximeterService add a Pulse Oximeter Service to a running
+// application. With PLXSpotCheckStorage the spot checks not confirmed are
+// stored, the collector reads them with the Record Access Control Point
+func NewPulseOximeterService(app *service.Application, config PulseOximeterConfig) (*PulseOximeterService, error) {
+
+	p := &PulseOximeterService{features: config.Features}
+
+	srv, err := addService(app, PulseOximeterUUID, true)
+	if err != nil {
+		return nil, err
+	}
+	p.srv = srv
+
+	if config.SpotCheck {
+		char, err := addCharacteristic(srv, PLXSpotCheckMeasurementUUID, []string{bluez.FlagCharacteristicIndicate}, nil)
+		if err != nil {
+			return nil, err
+		}
+		p.spotCheck = newIndicator(char)
+	}
+	if config.Continuous {
+		p.continuous, err = addCharacteristic(srv, PLXContinuousUUID, []string{bluez.FlagCharacteristicNotify}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b := []byte{byte(config.Features), byte(config.Features >> 8)}
+	if config.Features&PLXMeasurementStatus != 0 {
+		b = append(b, byte(config.MeasurementStatus), byte(config.MeasurementStatus>>8))
+	}
+	if config.Features&PLXDeviceStatus != 0 {
+		v := config.DeviceStatus
+		b = append(b, byte(v), byte(v>>8), byte(v>>16))
+	}
+	if _, err = addReadOnly(srv, PLXFeaturesUUID, b); err != nil {
+		return nil, err
+	}
+
+	if p.spotCheck != nil && config.Features&PLXSpotCheckStorage != 0 {
+		p.store = config.Store
+		if p.store == nil {
+			p.store = NewMemoryRecordStore(DefaultPLXRecords)
+		}
+		indicate := func(r Record) error {
+			return p.spotCheck.indicate(r.Value)
+		}
+		// the stored spot checks are selected as a whole, without filter
+		if p.racp, err = addRACP(srv, p.store, indicate, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+//Service return the GATT service
+func (p *PulseOximeterService) Service() *service.GattService1 {
+	return p.srv
+}
+
+//Store return the store of the spot checks, nil without PLXSpotCheckStorage
+func (p *PulseOximeterService) Store() RecordStore {
+	return p.store
+}
+
+//SendSpotCheck indicate a spot-check measurement and wait for its
+// confirmation. With PLXSpotCheckStorage a measurement not confirmed is
+// stored, the error is returned anyway
+func (p *PulseOximeterService) SendSpotCheck(m PLXSpotCheck) error {
+	if p.spotCheck == nil {
+		return errors.New("Spot-check measurements not enabled")
+	}
+	value := m.Bytes(p.features)
+	err := p.spotCheck.indicate(value)
+	if err != nil && p.store != nil {
+		p.store.Add(func(uint16) []byte { return value })
+	}
+	return err
+}
+
+//SendContinuous notify a continuous measurement
+func (p *PulseOximeterService) SendContinuous(m PLXContinuous) {
+	if p.continuous != nil {
+		p.continuous.UpdateValue(m.Bytes(p.features))
+	}
+}
@@ -0,0 +1,181 @@
+package gatt
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Proximity profile UUIDs
+var (
+	LinkLossServiceUUID       = UUID("1803")
+	ImmediateAlertServiceUUID = UUID("1802")
+	TxPowerServiceUUID        = UUID("1804")
+	AlertLevelUUID            = UUID("2A06")
+	TxPowerLevelUUID          = UUID("2A07")
+)
+
+// Alert levels
+const (
+	AlertNone uint8 = 0x00
+	AlertMild uint8 = 0x01
+	AlertHigh uint8 = 0x02
+)
+
+//ErrInvalidAlertLevel the error of a write of an unknown alert level
+var ErrInvalidAlertLevel = bluez.ErrInvalidArguments.WithMessage("Invalid alert level")
+
+// parseAlertLevel decode a written alert level
+func parseAlertLevel(value []byte) (uint8, error) {
+	if len(value) != 1 {
+		return 0, bluez.ErrInvalidValueLength
+	}
+	if value[0] > AlertHigh {
+		return 0, ErrInvalidAlertLevel
+	}
+	return value[0], nil
+}
+
+//LinkLossService the Link Loss Service (0x1803): the central sets the alert
+// level raised when the link is lost
+type LinkLossService struct {
+	srv     *service.GattService1
+	onAlert func(device dbus.ObjectPath, level uint8)
+	stop    func()
+
+	mutex sync.Mutex
+	level uint8
+	lost  map[dbus.ObjectPath]bool
+}
+
+//NewLinkLossService add a Link Loss Service to a running application. onAlert
+// is called with the alert level when a device disconnects, and with
+// AlertNone when it reconnects. bluetoothd does not report the reason of a
+// disconnection, the alert is raised for the disconnections requested by the
+// central as well. The connections are watched until Close
+func NewLinkLossService(app *service.Application, onAlert func(device dbus.ObjectPath, level uint8)) (*LinkLossService, error) {
+
+	l := &LinkLossService{
+		onAlert: onAlert,
+		lost:    make(map[dbus.ObjectPath]bool),
+	}
+
+	srv, err := addService(app, LinkLossServiceUUID)
+	if err != nil {
+		return nil, err
+	}
+	l.srv = srv
+
+	_, err = addWritable(srv, AlertLevelUUID,
+		[]string{bluez.FlagCharacteristicRead, bluez.FlagCharacteristicWrite},
+		func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+			return []byte{l.Level()}, nil
+		},
+		func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+			level, err := parseAlertLevel(value)
+			if err != nil {
+				return err
+			}
+			l.mutex.Lock()
+			l.level = level
+			l.mutex.Unlock()
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	events, stop, err := app.WatchConnections()
+	if err != nil {
+		return nil, err
+	}
+	l.stop = stop
+	go l.watch(events)
+
+	return l, nil
+}
+
+func (l *LinkLossService) watch(events <-chan service.ConnectionEvent) {
+	for ev := range events {
+		l.mutex.Lock()
+		level := l.level
+		lost := l.lost[ev.Device]
+		if ev.Connected {
+			delete(l.lost, ev.Device)
+		} else if level != AlertNone {
+			l.lost[ev.Device] = true
+		}
+		l.mutex.Unlock()
+
+		if l.onAlert == nil {
+			continue
+		}
+		if !ev.Connected && level != AlertNone {
+			l.onAlert(ev.Device, level)
+		}
+		if ev.Connected && lost {
+			l.onAlert(ev.Device, AlertNone)
+		}
+	}
+}
+
+//Service return the GATT service
+func (l *LinkLossService) Service() *service.GattService1 {
+	return l.srv
+}
+
+//Level return the alert level set by the central
+func (l *LinkLossService) Level() uint8 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.level
+}
+
+//Close stop watching the connections
+func (l *LinkLossService) Close() {
+	l.stop()
+}
+
+//NewImmediateAlertService add an Immediate Alert Service (0x1802) to a
+// running application, onAlert is called when the central writes the alert
+// level, eg. to find the device
+func NewImmediateAlertService(app *service.Application, onAlert func(level uint8)) (*service.GattService1, error) {
+
+	srv, err := addService(app, ImmediateAlertServiceUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = addWritable(srv, AlertLevelUUID,
+		[]string{bluez.FlagCharacteristicWriteWithoutResponse}, nil,
+		func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+			level, err := parseAlertLevel(value)
+			if err != nil {
+				return err
+			}
+			if onAlert != nil {
+				onAlert(level)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+//NewTxPowerService add a Tx Power Service (0x1804) to a running application,
+// exposing the TX power of the peripheral in dBm, eg. from
+// hci.Socket.AdvertisingTxPower, for the central to estimate the path loss
+func NewTxPowerService(app *service.Application, level int8) (*service.GattService1, error) {
+	srv, err := addService(app, TxPowerServiceUUID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = addReadOnly(srv, TxPowerLevelUUID, []byte{byte(level)}); err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
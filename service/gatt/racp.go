@@ -0,0 +1,375 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+//RecordAccessControlPointUUID the Record Access Control Point of the record
+// based health profiles
+var RecordAccessControlPointUUID = UUID("2A52")
+
+// RACP op codes
+const (
+	RACPReportRecords  uint8 = 0x01
+	RACPDeleteRecords  uint8 = 0x02
+	RACPAbort          uint8 = 0x03
+	RACPReportNumber   uint8 = 0x04
+	RACPNumberResponse uint8 = 0x05
+	RACPResponse       uint8 = 0x06
+)
+
+// RACP operators
+const (
+	RACPNull           uint8 = 0x00
+	RACPAll            uint8 = 0x01
+	RACPLessOrEqual    uint8 = 0x02
+	RACPGreaterOrEqual uint8 = 0x03
+	RACPWithinRange    uint8 = 0x04
+	RACPFirst          uint8 = 0x05
+	RACPLast           uint8 = 0x06
+)
+
+// RACP response codes
+const (
+	RACPSuccess              uint8 = 0x01
+	RACPOpCodeNotSupported   uint8 = 0x02
+	RACPInvalidOperator      uint8 = 0x03
+	RACPOperatorNotSupported uint8 = 0x04
+	RACPInvalidOperand       uint8 = 0x05
+	RACPNoRecords            uint8 = 0x06
+	RACPAbortUnsuccessful    uint8 = 0x07
+	RACPNotCompleted         uint8 = 0x08
+	RACPOperandNotSupported  uint8 = 0x09
+)
+
+// racpFilterSequence the filter type of the sequence numbers
+const racpFilterSequence = 0x01
+
+//RACPPageSize the records read at once from the store while reporting
+const RACPPageSize = 16
+
+//Record a stored measurement
+type Record struct {
+	Sequence uint16
+	// Value the encoded measurement, sent as is
+	Value []byte
+}
+
+//RecordFilter select the records of a RACP request
+type RecordFilter struct {
+	// Operator eg. RACPAll or RACPWithinRange
+	Operator uint8
+	// Min and Max the sequence numbers of the range operators
+	Min uint16
+	Max uint16
+}
+
+//Match return true if the filter selects the sequence number, RACPFirst and
+// RACPLast are handled by the store
+func (f RecordFilter) Match(sequence uint16) bool {
+	switch f.Operator {
+	case RACPAll, RACPFirst, RACPLast:
+		return true
+	case RACPLessOrEqual:
+		return sequence <= f.Max
+	case RACPGreaterOrEqual:
+		return sequence >= f.Min
+	case RACPWithinRange:
+		return sequence >= f.Min && sequence <= f.Max
+	}
+	return false
+}
+
+//RecordStore the stored records of a RACP, eg. backed by a file for the
+// records to survive a restart
+type RecordStore interface {
+	// Add a record, encode is called with the sequence number assigned
+	Add(encode func(sequence uint16) []byte) Record
+	// Records return at most limit records selected by the filter, after
+	// skipping offset, in the order of the sequence numbers
+	Records(filter RecordFilter, offset int, limit int) []Record
+	// Count the records selected by the filter
+	Count(filter RecordFilter) int
+	// Delete the records selected by the filter, return their number
+	Delete(filter RecordFilter) int
+}
+
+//MemoryRecordStore a RecordStore keeping the last records in memory
+type MemoryRecordStore struct {
+	mutex    sync.Mutex
+	records  []Record
+	capacity int
+	next     uint16
+}
+
+//NewMemoryRecordStore create a store, the oldest records are dropped beyond
+// capacity
+func NewMemoryRecordStore(capacity int) *MemoryRecordStore {
+	return &MemoryRecordStore{capacity: capacity}
+}
+
+//Add a record
+func (s *MemoryRecordStore) Add(encode func(sequence uint16) []byte) Record {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	r := Record{Sequence: s.next, Value: encode(s.next)}
+	s.next++
+	s.records = append(s.records, r)
+	if s.capacity > 0 && len(s.records) > s.capacity {
+		s.records = s.records[len(s.records)-s.capacity:]
+	}
+	return r
+}
+
+// selected return the records selected by the filter
+func (s *MemoryRecordStore) selected(filter RecordFilter) []Record {
+	if len(s.records) == 0 {
+		return nil
+	}
+	switch filter.Operator {
+	case RACPFirst:
+		return s.records[:1]
+	case RACPLast:
+		return s.records[len(s.records)-1:]
+	}
+	list := []Record{}
+	for _, r := range s.records {
+		if filter.Match(r.Sequence) {
+			list = append(list, r)
+		}
+	}
+	return list
+}
+
+//Records return a page of the records selected by the filter
+func (s *MemoryRecordStore) Records(filter RecordFilter, offset int, limit int) []Record {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	list := s.selected(filter)
+	if offset >= len(list) {
+		return nil
+	}
+	list = list[offset:]
+	if limit < len(list) {
+		list = list[:limit]
+	}
+	return append([]Record{}, list...)
+}
+
+//Count the records selected by the filter
+func (s *MemoryRecordStore) Count(filter RecordFilter) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.selected(filter))
+}
+
+//Delete the records selected by the filter
+func (s *MemoryRecordStore) Delete(filter RecordFilter) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	selected := s.selected(filter)
+	if len(selected) == 0 {
+		return 0
+	}
+	deleted := make(map[uint16]bool, len(selected))
+	for _, r := range selected {
+		deleted[r.Sequence] = true
+	}
+	kept := s.records[:0]
+	for _, r := range s.records {
+		if !deleted[r.Sequence] {
+			kept = append(kept, r)
+		}
+	}
+	s.records = kept
+	return len(deleted)
+}
+
+// racp the Record Access Control Point: the records are sent on the
+// measurement characteristic, the results indicated on the control point
+type racp struct {
+	store RecordStore
+	// send a record, an error stops the report
+	send func(Record) error
+	// sequence true if the records can be filtered by sequence number
+	sequence bool
+	control  *indicator
+
+	mutex   sync.Mutex
+	running bool
+	abort   bool
+}
+
+// addRACP add the control point of the records of the store, sequence
+// enables the filters by sequence number
+func addRACP(srv *service.GattService1, store RecordStore, send func(Record) error, sequence bool) (*racp, error) {
+	r := &racp{
+		store:    store,
+		send:     send,
+		sequence: sequence,
+	}
+	char, err := addWritable(srv, RecordAccessControlPointUUID,
+		[]string{bluez.FlagCharacteristicWrite, bluez.FlagCharacteristicIndicate}, nil, r.write)
+	if err != nil {
+		return nil, err
+	}
+	r.control = newIndicator(char)
+	return r, nil
+}
+
+// parseFilter decode the operator and operand of a request
+func parseFilter(operator uint8, operand []byte) (RecordFilter, uint8) {
+	f := RecordFilter{Operator: operator}
+	switch operator {
+	case RACPAll, RACPFirst, RACPLast:
+		if len(operand) != 0 {
+			return f, RACPInvalidOperand
+		}
+		return f, RACPSuccess
+	case RACPLessOrEqual, RACPGreaterOrEqual, RACPWithinRange:
+	case RACPNull:
+		return f, RACPInvalidOperator
+	default:
+		return f, RACPOperatorNotSupported
+	}
+
+	size := 3
+	if operator == RACPWithinRange {
+		size = 5
+	}
+	if len(operand) == 0 {
+		return f, RACPInvalidOperand
+	}
+	if operand[0] != racpFilterSequence {
+		return f, RACPOperandNotSupported
+	}
+	if len(operand) != size {
+		return f, RACPInvalidOperand
+	}
+	v := binary.LittleEndian.Uint16(operand[1:])
+	switch operator {
+	case RACPLessOrEqual:
+		f.Max = v
+	case RACPGreaterOrEqual:
+		f.Min = v
+	case RACPWithinRange:
+		f.Min, f.Max = v, binary.LittleEndian.Uint16(operand[3:])
+		if f.Min > f.Max {
+			return f, RACPInvalidOperand
+		}
+	}
+	return f, RACPSuccess
+}
+
+func (r *racp) write(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+
+	if len(value) < 2 {
+		return bluez.ErrInvalidValueLength
+	}
+	op, operator, operand := value[0], value[1], value[2:]
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if op == RACPAbort {
+		if operator != RACPNull || len(operand) != 0 {
+			r.respond(op, RACPInvalidOperator)
+			return nil
+		}
+		r.abort = r.running
+		r.respond(op, RACPSuccess)
+		return nil
+	}
+	if r.running {
+		return bluez.ErrInProgress
+	}
+
+	switch op {
+	case RACPReportRecords, RACPDeleteRecords, RACPReportNumber:
+	default:
+		r.respond(op, RACPOpCodeNotSupported)
+		return nil
+	}
+
+	filter, code := parseFilter(operator, operand)
+	// only the range operators have an operand
+	if code == RACPSuccess && !r.sequence && len(operand) > 0 {
+		code = RACPOperatorNotSupported
+	}
+	if code != RACPSuccess {
+		r.respond(op, code)
+		return nil
+	}
+
+	switch op {
+	case RACPReportNumber:
+		b := []byte{RACPNumberResponse, RACPNull, 0, 0}
+		binary.LittleEndian.PutUint16(b[2:], clamp16(r.store.Count(filter)))
+		r.indicate(b)
+	case RACPDeleteRecords:
+		if r.store.Delete(filter) == 0 {
+			r.respond(op, RACPNoRecords)
+		} else {
+			r.respond(op, RACPSuccess)
+		}
+	case RACPReportRecords:
+		r.running = true
+		r.abort = false
+		go r.report(filter)
+	}
+	return nil
+}
+
+// respond indicate a response code
+func (r *racp) respond(op uint8, code uint8) {
+	r.indicate([]byte{RACPResponse, RACPNull, op, code})
+}
+
+// indicate a response once the write is acknowledged, it is dropped if the
+// client did not subscribe to the control point
+func (r *racp) indicate(b []byte) {
+	go r.control.indicate(b)
+}
+
+// report send the records selected by the filter, a page at a time
+func (r *racp) report(filter RecordFilter) {
+
+	sent := 0
+	code := RACPSuccess
+	for offset := 0; ; offset += RACPPageSize {
+		page := r.store.Records(filter, offset, RACPPageSize)
+		if len(page) == 0 {
+			break
+		}
+		for _, record := range page {
+			r.mutex.Lock()
+			abort := r.abort
+			r.mutex.Unlock()
+			if abort {
+				code = RACPNotCompleted
+				break
+			}
+			if err := r.send(record); err != nil {
+				code = RACPNotCompleted
+				break
+			}
+			sent++
+		}
+		if code != RACPSuccess {
+			break
+		}
+	}
+	if sent == 0 && code == RACPSuccess {
+		code = RACPNoRecords
+	}
+
+	r.mutex.Lock()
+	r.running = false
+	r.abort = false
+	r.respond(RACPReportRecords, code)
+	r.mutex.Unlock()
+}
@@ -0,0 +1,357 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Cycling and Running Speed and Cadence UUIDs
+var (
+	CyclingSpeedCadenceUUID = UUID("1816")
+	CSCMeasurementUUID      = UUID("2A5B")
+	CSCFeatureUUID          = UUID("2A5C")
+	RunningSpeedCadenceUUID = UUID("1814")
+	RSCMeasurementUUID      = UUID("2A53")
+	RSCFeatureUUID          = UUID("2A54")
+	SensorLocationUUID      = UUID("2A5D")
+	SCControlPointUUID      = UUID("2A55")
+)
+
+// CSC features
+const (
+	CSCWheelRevolutions  uint16 = 1 << 0
+	CSCCrankRevolutions  uint16 = 1 << 1
+	CSCMultipleLocations uint16 = 1 << 2
+)
+
+// RSC features
+const (
+	RSCStrideLength      uint16 = 1 << 0
+	RSCTotalDistance     uint16 = 1 << 1
+	RSCRunningStatus     uint16 = 1 << 2
+	RSCCalibration       uint16 = 1 << 3
+	RSCMultipleLocations uint16 = 1 << 4
+)
+
+// Sensor locations
+const (
+	LocationTopOfShoe  uint8 = 1
+	LocationInShoe     uint8 = 2
+	LocationHip        uint8 = 3
+	LocationFrontWheel uint8 = 4
+	LocationLeftCrank  uint8 = 5
+	LocationRightCrank uint8 = 6
+	LocationLeftPedal  uint8 = 7
+	LocationRightPedal uint8 = 8
+	LocationFrontHub   uint8 = 9
+	LocationRearWheel  uint8 = 12
+	LocationRearHub    uint8 = 13
+)
+
+// SC Control Point op codes
+const (
+	SCSetCumulativeValue        uint8 = 0x01
+	SCStartCalibration          uint8 = 0x02
+	SCUpdateSensorLocation      uint8 = 0x03
+	SCRequestSupportedLocations uint8 = 0x04
+	SCResponse                  uint8 = 0x10
+)
+
+// SC Control Point results
+const (
+	SCSuccess         uint8 = 0x01
+	SCNotSupported    uint8 = 0x02
+	SCInvalidParam    uint8 = 0x03
+	SCOperationFailed uint8 = 0x04
+)
+
+// eventTime encode a time in units of 1/1024 second, rolling over every 64s
+func eventTime(d time.Duration) uint16 {
+	return uint16(d * 1024 / time.Second)
+}
+
+//CSCMeasurement a CSC Measurement, the fields of the features not supported
+// by the service are not sent
+type CSCMeasurement struct {
+	// WheelRevolutions the cumulative revolutions
+	WheelRevolutions uint32
+	// WheelEventTime the time of the last wheel event, since any reference
+	WheelEventTime   time.Duration
+	CrankRevolutions uint16
+	CrankEventTime   time.Duration
+}
+
+//Bytes encode the measurement with the fields of the features
+func (m CSCMeasurement) Bytes(features uint16) []byte {
+	b := []byte{0}
+	if features&CSCWheelRevolutions != 0 {
+		b[0] |= 1 << 0
+		b = append(b, 0, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(b[1:], m.WheelRevolutions)
+		binary.LittleEndian.PutUint16(b[5:], eventTime(m.WheelEventTime))
+	}
+	if features&CSCCrankRevolutions != 0 {
+		b[0] |= 1 << 1
+		n := len(b)
+		b = append(b, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint16(b[n:], m.CrankRevolutions)
+		binary.LittleEndian.PutUint16(b[n+2:], eventTime(m.CrankEventTime))
+	}
+	return b
+}
+
+//RSCMeasurement a RSC Measurement, the fields of the features not supported
+// by the service are not sent
+type RSCMeasurement struct {
+	// Speed in m/s
+	Speed float64
+	// Cadence in steps per minute
+	Cadence uint8
+	// StrideLength in m
+	StrideLength float64
+	// TotalDistance in m
+	TotalDistance float64
+	Running       bool
+}
+
+//Bytes encode the measurement with the fields of the features
+func (m RSCMeasurement) Bytes(features uint16) []byte {
+	b := []byte{0, 0, 0, m.Cadence}
+	binary.LittleEndian.PutUint16(b[1:], clamp16(int(m.Speed*256+0.5)))
+	if features&RSCStrideLength != 0 {
+		b[0] |= 1 << 0
+		v := clamp16(int(m.StrideLength*100 + 0.5))
+		b = append(b, byte(v), byte(v>>8))
+	}
+	if features&RSCTotalDistance != 0 {
+		b[0] |= 1 << 1
+		n := len(b)
+		b = append(b, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(b[n:], uint32(m.TotalDistance*10+0.5))
+	}
+	if features&RSCRunningStatus != 0 && m.Running {
+		b[0] |= 1 << 2
+	}
+	return b
+}
+
+//SCHandlers the procedures of the SC Control Point, a nil handler is
+// reported as not supported
+type SCHandlers struct {
+	// OnSetCumulativeValue set the wheel revolutions of a CSC sensor or the
+	// total distance of a RSC sensor, in 1/10 m
+	OnSetCumulativeValue func(value uint32) error
+	// OnCalibrate start the calibration of a RSC sensor
+	OnCalibrate func() error
+	// OnLocation called when the collector updates the sensor location
+	OnLocation func(location uint8)
+}
+
+//SpeedCadenceConfig configure a Cycling or Running Speed and Cadence Service
+type SpeedCadenceConfig struct {
+	// Features the CSC* or RSC* features
+	Features uint16
+	// Location the sensor location, exposed if not zero
+	Location uint8
+	// Locations the supported locations the collector can select, with the
+	// multiple locations feature
+	Locations []uint8
+	Handlers  SCHandlers
+}
+
+//SpeedCadenceService a Cycling (0x1816) or Running (0x1814) Speed and Cadence
+// Service
+type SpeedCadenceService struct {
+	srv         *service.GattService1
+	measurement *service.GattCharacteristic1
+	control     *service.GattCharacteristic1
+	config      SpeedCadenceConfig
+	running     bool
+
+	mutex    sync.Mutex
+	location uint8
+}
+
+//NewCyclingSpeedCadenceService add a Cycling Speed and Cadence Service to a
+// running application
+func NewCyclingSpeedCadenceService(app *service.Application, config SpeedCadenceConfig) (*SpeedCadenceService, error) {
+	return newSpeedCadenceService(app, config, false)
+}
+
+//NewRunningSpeedCadenceService add a Running Speed and Cadence Service to a
+// running application
+func NewRunningSpeedCadenceService(app *service.Application, config SpeedCadenceConfig) (*SpeedCadenceService, error) {
+	return newSpeedCadenceService(app, config, true)
+}
+
+func newSpeedCadenceService(app *service.Application, config SpeedCadenceConfig, running bool) (*SpeedCadenceService, error) {
+
+	s := &SpeedCadenceService{
+		config:   config,
+		running:  running,
+		location: config.Location,
+	}
+
+	serviceUUID, measurementUUID, featureUUID := CyclingSpeedCadenceUUID, CSCMeasurementUUID, CSCFeatureUUID
+	if running {
+		serviceUUID, measurementUUID, featureUUID = RunningSpeedCadenceUUID, RSCMeasurementUUID, RSCFeatureUUID
+	}
+
+	srv, err := addService(app, serviceUUID, true)
+	if err != nil {
+		return nil, err
+	}
+	s.srv = srv
+
+	s.measurement, err = addCharacteristic(srv, measurementUUID, []string{bluez.FlagCharacteristicNotify}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]byte, 2)
+	binary.LittleEndian.PutUint16(features, config.Features)
+	if _, err = addReadOnly(srv, featureUUID, features); err != nil {
+		return nil, err
+	}
+
+	if config.Location != 0 {
+		_, err = addCharacteristic(srv, SensorLocationUUID, []string{bluez.FlagCharacteristicRead},
+			func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+				return []byte{s.Location()}, nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.hasControlPoint() {
+		s.control, err = addWritable(srv, SCControlPointUUID,
+			[]string{bluez.FlagCharacteristicWrite, bluez.FlagCharacteristicIndicate}, nil,
+			func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+				if len(value) == 0 {
+					return bluez.ErrInvalidValueLength
+				}
+				// the response is indicated once the write is acknowledged
+				response := s.handleControl(value[0], value[1:])
+				go c.UpdateValue(response)
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// multipleLocations return true if the collector can select the location
+func (s *SpeedCadenceService) multipleLocations() bool {
+	if s.running {
+		return s.config.Features&RSCMultipleLocations != 0
+	}
+	return s.config.Features&CSCMultipleLocations != 0
+}
+
+// hasControlPoint return true if a feature requires the SC Control Point
+func (s *SpeedCadenceService) hasControlPoint() bool {
+	if s.multipleLocations() {
+		return true
+	}
+	if s.running {
+		return s.config.Features&(RSCTotalDistance|RSCCalibration) != 0
+	}
+	return s.config.Features&CSCWheelRevolutions != 0
+}
+
+// handleControl run a procedure and return the response
+func (s *SpeedCadenceService) handleControl(op uint8, params []byte) []byte {
+
+	result := SCNotSupported
+	var data []byte
+	h := s.config.Handlers
+
+	switch op {
+	case SCSetCumulativeValue:
+		if h.OnSetCumulativeValue == nil {
+			break
+		}
+		if len(params) != 4 {
+			result = SCInvalidParam
+			break
+		}
+		result = scResult(h.OnSetCumulativeValue(binary.LittleEndian.Uint32(params)))
+
+	case SCStartCalibration:
+		if !s.running || h.OnCalibrate == nil {
+			break
+		}
+		result = scResult(h.OnCalibrate())
+
+	case SCUpdateSensorLocation:
+		if !s.multipleLocations() {
+			break
+		}
+		if len(params) != 1 || !s.supportsLocation(params[0]) {
+			result = SCInvalidParam
+			break
+		}
+		s.mutex.Lock()
+		s.location = params[0]
+		s.mutex.Unlock()
+		if h.OnLocation != nil {
+			h.OnLocation(params[0])
+		}
+		result = SCSuccess
+
+	case SCRequestSupportedLocations:
+		if !s.multipleLocations() {
+			break
+		}
+		result = SCSuccess
+		data = s.config.Locations
+	}
+
+	return append([]byte{SCResponse, op, result}, data...)
+}
+
+func scResult(err error) uint8 {
+	if err != nil {
+		return SCOperationFailed
+	}
+	return SCSuccess
+}
+
+func (s *SpeedCadenceService) supportsLocation(location uint8) bool {
+	for _, l := range s.config.Locations {
+		if l == location {
+			return true
+		}
+	}
+	return false
+}
+
+//Service return the GATT service
+func (s *SpeedCadenceService) Service() *service.GattService1 {
+	return s.srv
+}
+
+//Location return the sensor location
+func (s *SpeedCadenceService) Location() uint8 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.location
+}
+
+//SendCSC notify a CSC Measurement
+func (s *SpeedCadenceService) SendCSC(m CSCMeasurement) {
+	s.measurement.UpdateValue(m.Bytes(s.config.Features))
+}
+
+//SendRSC notify a RSC Measurement
+func (s *SpeedCadenceService) SendRSC(m RSCMeasurement) {
+	s.measurement.UpdateValue(m.Bytes(s.config.Features))
+}
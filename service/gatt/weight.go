@@ -0,0 +1,163 @@
+package gatt
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// Weight Scale UUIDs
+var (
+	WeightScaleUUID        = UUID("181D")
+	WeightMeasurementUUID  = UUID("2A9D")
+	WeightScaleFeatureUUID = UUID("2A9E")
+)
+
+//DefaultWeightStored the measurements kept while no collector is connected
+const DefaultWeightStored = 25
+
+//WeightUserUnknown the user index of a measurement of an unknown user
+const WeightUserUnknown uint8 = 0xff
+
+// Weight Scale features, the weight resolution is set in bits 3 to 6 and the
+// height resolution in bits 7 to 9, zero if not specified
+const (
+	WeightTimeStamp     uint32 = 1 << 0
+	WeightMultipleUsers uint32 = 1 << 1
+	WeightBMI           uint32 = 1 << 2
+)
+
+//WeightMeasurement a measurement of the Weight Scale Service
+type WeightMeasurement struct {
+	// Weight in kg, or lb
+	Weight   float64
+	Imperial bool
+	// Time of the measurement, not sent if zero
+	Time time.Time
+	// UserID the index of the user, sent with WeightMultipleUsers. Use
+	// WeightUserUnknown for an unknown user
+	UserID uint8
+	// BMI and Height in m, or in, sent with WeightBMI
+	BMI    float64
+	Height float64
+}
+
+//Bytes encode the measurement, with the fields of the Weight Scale features
+func (m WeightMeasurement) Bytes(features uint32) []byte {
+	b := []byte{0, 0, 0}
+	weight, height := m.Weight/0.005, m.Height/0.001
+	if m.Imperial {
+		b[0] |= 1 << 0
+		weight, height = m.Weight/0.01, m.Height/0.1
+	}
+	binary.LittleEndian.PutUint16(b[1:], clamp16(int(math.Floor(weight+0.5))))
+	if !m.Time.IsZero() && features&WeightTimeStamp != 0 {
+		b[0] |= 1 << 1
+		b = append(b, DateTime(m.Time)...)
+	}
+	if features&WeightMultipleUsers != 0 {
+		b[0] |= 1 << 2
+		b = append(b, m.UserID)
+	}
+	if features&WeightBMI != 0 {
+		b[0] |= 1 << 3
+		bmi := clamp16(int(math.Floor(m.BMI*10 + 0.5)))
+		h := clamp16(int(math.Floor(height + 0.5)))
+		b = append(b, byte(bmi), byte(bmi>>8), byte(h), byte(h>>8))
+	}
+	return b
+}
+
+//WeightScaleService the Weight Scale Service (0x181D)
+type WeightScaleService struct {
+	srv         *service.GattService1
+	features    uint32
+	measurement *indicator
+
+	mutex  sync.Mutex
+	stored []WeightMeasurement
+}
+
+//NewWeightScaleService add a Weight Scale Service to a running application,
+// with the Weight* features supported by the scale
+func NewWeightScaleService(app *service.Application, features uint32) (*WeightScaleService, error) {
+
+	s := &WeightScaleService{features: features}
+
+	srv, err := addService(app, WeightScaleUUID, true)
+	if err != nil {
+		return nil, err
+	}
+	s.srv = srv
+
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, features)
+	if _, err = addReadOnly(srv, WeightScaleFeatureUUID, b); err != nil {
+		return nil, err
+	}
+
+	char, err := addCharacteristic(srv, WeightMeasurementUUID, []string{bluez.FlagCharacteristicIndicate}, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.measurement = newIndicator(char)
+
+	return s, nil
+}
+
+//Service return the GATT service
+func (s *WeightScaleService) Service() *service.GattService1 {
+	return s.srv
+}
+
+//SendMeasurement indicate a measurement and wait for its confirmation. A
+// measurement not confirmed is stored, up to DefaultWeightStored, to be sent
+// with SendStored once the collector is back
+func (s *WeightScaleService) SendMeasurement(m WeightMeasurement) error {
+	err := s.measurement.indicate(m.Bytes(s.features))
+	if err != nil {
+		s.mutex.Lock()
+		s.stored = append(s.stored, m)
+		if len(s.stored) > DefaultWeightStored {
+			s.stored = s.stored[len(s.stored)-DefaultWeightStored:]
+		}
+		s.mutex.Unlock()
+	}
+	return err
+}
+
+//Stored return the number of measurements stored
+func (s *WeightScaleService) Stored() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.stored)
+}
+
+//SendStored indicate the stored measurements of a user, oldest first, eg.
+// once the collector subscribed to the measurements and the user consented.
+// The user is ignored without WeightMultipleUsers. It returns the number of
+// measurements sent, the others are kept
+func (s *WeightScaleService) SendStored(userID uint8) (int, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sent := 0
+	kept := []WeightMeasurement{}
+	var err error
+	for _, m := range s.stored {
+		if err == nil && (s.features&WeightMultipleUsers == 0 || m.UserID == userID) {
+			if err = s.measurement.indicate(m.Bytes(s.features)); err == nil {
+				sent++
+				continue
+			}
+		}
+		kept = append(kept, m)
+	}
+	s.stored = kept
+	return sent, err
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+)
+
+// introspectionTree the introspection data of the objects of an
+// application. Each node lists its children, adding or removing an object
+// only exports it again with its parent
+type introspectionTree struct {
+	conn *dbus.Conn
+
+	mutex sync.Mutex
+	nodes map[dbus.ObjectPath]*introspectionNode
+	// roots the nodes without a parent in the tree
+	roots map[dbus.ObjectPath]bool
+	// dirty the nodes to export on flush, nil when exporting immediately
+	dirty map[dbus.ObjectPath]bool
+}
+
+type introspectionNode struct {
+	interfaces []introspect.Interface
+	children   map[dbus.ObjectPath]bool
+}
+
+func newIntrospectionTree(conn *dbus.Conn) *introspectionTree {
+	return &introspectionTree{
+		conn:  conn,
+		nodes: make(map[dbus.ObjectPath]*introspectionNode),
+		roots: make(map[dbus.ObjectPath]bool),
+	}
+}
+
+// isBelow return true if path is a descendant of parent
+func isBelow(path dbus.ObjectPath, parent dbus.ObjectPath) bool {
+	if parent == "/" {
+		return path != "/"
+	}
+	return strings.HasPrefix(string(path), string(parent)+"/")
+}
+
+// parent return the closest ancestor in the tree, empty if none
+func (t *introspectionTree) parent(path dbus.ObjectPath) dbus.ObjectPath {
+	p := string(path)
+	for p != "/" {
+		i := strings.LastIndex(p, "/")
+		if i <= 0 {
+			p = "/"
+		} else {
+			p = p[:i]
+		}
+		if _, ok := t.nodes[dbus.ObjectPath(p)]; ok {
+			return dbus.ObjectPath(p)
+		}
+	}
+	return ""
+}
+
+func (t *introspectionTree) siblings(parent dbus.ObjectPath) map[dbus.ObjectPath]bool {
+	if parent == "" {
+		return t.roots
+	}
+	return t.nodes[parent].children
+}
+
+// add or update an object and export its introspection, with its parent
+func (t *introspectionTree) add(path dbus.ObjectPath, interfaces []introspect.Interface) error {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	n, ok := t.nodes[path]
+	if ok {
+		n.interfaces = interfaces
+		return t.update(path)
+	}
+
+	n = &introspectionNode{
+		interfaces: interfaces,
+		children:   make(map[dbus.ObjectPath]bool),
+	}
+	t.nodes[path] = n
+
+	parent := t.parent(path)
+	siblings := t.siblings(parent)
+	// adopt the objects added before this one
+	for child := range siblings {
+		if isBelow(child, path) {
+			delete(siblings, child)
+			n.children[child] = true
+		}
+	}
+	siblings[path] = true
+
+	err := t.update(path)
+	if err != nil {
+		return err
+	}
+	if parent != "" {
+		return t.update(parent)
+	}
+	return nil
+}
+
+// remove an object and its descendants, exporting again its parent
+func (t *introspectionTree) remove(path dbus.ObjectPath) error {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, ok := t.nodes[path]; !ok {
+		return nil
+	}
+
+	parent := t.parent(path)
+	delete(t.siblings(parent), path)
+	t.drop(path)
+
+	if parent != "" {
+		return t.update(parent)
+	}
+	return nil
+}
+
+// defer the exports until flush, each node is then exported once
+func (t *introspectionTree) begin() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.dirty == nil {
+		t.dirty = make(map[dbus.ObjectPath]bool)
+	}
+}
+
+// flush export the nodes changed since begin
+func (t *introspectionTree) flush() error {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	dirty := t.dirty
+	t.dirty = nil
+	for path := range dirty {
+		err := t.export(path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update export a node, or mark it for flush
+func (t *introspectionTree) update(path dbus.ObjectPath) error {
+	if t.dirty != nil {
+		t.dirty[path] = true
+		return nil
+	}
+	return t.export(path)
+}
+
+func (t *introspectionTree) drop(path dbus.ObjectPath) {
+	for child := range t.nodes[path].children {
+		t.drop(child)
+	}
+	delete(t.nodes, path)
+	delete(t.dirty, path)
+	t.conn.Export(nil, path, "org.freedesktop.DBus.Introspectable")
+}
+
+// export the introspection of a node with its current children
+func (t *introspectionTree) export(path dbus.ObjectPath) error {
+
+	n := t.nodes[path]
+
+	prefix := string(path) + "/"
+	if path == "/" {
+		prefix = "/"
+	}
+	names := make([]string, 0, len(n.children))
+	for child := range n.children {
+		names = append(names, strings.TrimPrefix(string(child), prefix))
+	}
+	sort.Strings(names)
+
+	children := make([]introspect.Node, len(names))
+	for i, name := range names {
+		children[i] = introspect.Node{Name: name}
+	}
+
+	node := &introspect.Node{
+		Interfaces: n.interfaces,
+		Children:   children,
+	}
+	return t.conn.Export(
+		introspect.NewIntrospectable(node),
+		path,
+		"org.freedesktop.DBus.Introspectable")
+}
+
+type introspectionSnapshot struct {
+	interfaces []string
+	children   []string
+}
+
+// snapshot return the interfaces and children names of each node
+func (t *introspectionTree) snapshot() map[dbus.ObjectPath]introspectionSnapshot {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	nodes := make(map[dbus.ObjectPath]introspectionSnapshot, len(t.nodes))
+	for path, n := range t.nodes {
+		s := introspectionSnapshot{}
+		for _, iface := range n.interfaces {
+			s.interfaces = append(s.interfaces, iface.Name)
+		}
+		for child := range n.children {
+			s.children = append(s.children, string(child))
+		}
+		sort.Strings(s.children)
+		nodes[path] = s
+	}
+	return nodes
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//PropertiesNotifier coalesce the property updates of an object: the changes
+// happening within a window are emitted as a single PropertiesChanged signal
+// per interface, with the last value of each property
+type PropertiesNotifier struct {
+	conn   *dbus.Conn
+	path   dbus.ObjectPath
+	window time.Duration
+
+	mutex       sync.Mutex
+	changed     map[string]map[string]dbus.Variant
+	invalidated map[string]map[string]bool
+	timer       *time.Timer
+}
+
+//NewPropertiesNotifier create a notifier for the object at path, signals are
+// delayed by at most window
+func NewPropertiesNotifier(conn *dbus.Conn, path dbus.ObjectPath, window time.Duration) *PropertiesNotifier {
+	return &PropertiesNotifier{
+		conn:        conn,
+		path:        path,
+		window:      window,
+		changed:     make(map[string]map[string]dbus.Variant),
+		invalidated: make(map[string]map[string]bool),
+	}
+}
+
+//Changed record the new value of a property
+func (n *PropertiesNotifier) Changed(iface string, name string, value dbus.Variant) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if _, ok := n.changed[iface]; !ok {
+		n.changed[iface] = make(map[string]dbus.Variant)
+	}
+	n.changed[iface][name] = value
+	delete(n.invalidated[iface], name)
+	n.schedule()
+}
+
+//Invalidated record a property as invalidated, its value is not sent
+func (n *PropertiesNotifier) Invalidated(iface string, name string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if _, ok := n.invalidated[iface]; !ok {
+		n.invalidated[iface] = make(map[string]bool)
+	}
+	n.invalidated[iface][name] = true
+	delete(n.changed[iface], name)
+	n.schedule()
+}
+
+// schedule a flush at the end of the window, n.mutex must be locked
+func (n *PropertiesNotifier) schedule() {
+	if n.timer != nil {
+		return
+	}
+	n.timer = time.AfterFunc(n.window, func() {
+		err := n.Flush()
+		if err != nil {
+			bluez.GetLogger().Errorf("Failed to emit PropertiesChanged on %s: %s", n.path, err.Error())
+		}
+	})
+}
+
+//Flush emit the pending changes now
+func (n *PropertiesNotifier) Flush() error {
+
+	n.mutex.Lock()
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+	changed := n.changed
+	invalidated := n.invalidated
+	n.changed = make(map[string]map[string]dbus.Variant)
+	n.invalidated = make(map[string]map[string]bool)
+	n.mutex.Unlock()
+
+	ifaces := make(map[string]bool)
+	for iface := range changed {
+		ifaces[iface] = true
+	}
+	for iface := range invalidated {
+		ifaces[iface] = true
+	}
+
+	for iface := range ifaces {
+		props := changed[iface]
+		if props == nil {
+			props = make(map[string]dbus.Variant)
+		}
+		names := make([]string, 0, len(invalidated[iface]))
+		for name := range invalidated[iface] {
+			names = append(names, name)
+		}
+		if len(props) == 0 && len(names) == 0 {
+			continue
+		}
+		err := n.conn.Emit(n.path, bluez.PropertiesChanged, iface, props, names)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Close emit the pending changes and stop the notifier
+func (n *PropertiesNotifier) Close() error {
+	return n.Flush()
+}
@@ -0,0 +1,85 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestPropertiesCoalescing(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix:   service.UUIDSuffix,
+		UUID:         "1234",
+		ObjectName:   "org.bluez.bluetest",
+		ObjectPath:   "/bluetest",
+		NotifyWindow: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: true,
+		UUID:    app.GenerateUUID("2233"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.AddService(srv); err != nil {
+		t.Fatal(err)
+	}
+	char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID:  app.GenerateUUID("3344"),
+		Flags: []string{bluez.FlagCharacteristicNotify},
+		Value: []byte{0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.AddCharacteristic(char); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := bluez.GetSignalDispatcher(b.Conn())
+	signals, err := dispatcher.Subscribe(bluez.SignalFilter{
+		Path:      char.Path(),
+		Interface: bluez.PropertiesInterface,
+		Member:    "PropertiesChanged",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dispatcher.Unsubscribe(signals)
+
+	for i := 1; i <= 50; i++ {
+		char.UpdateValue([]byte{byte(i)})
+	}
+
+	select {
+	case sig := <-signals:
+		changed := sig.Body[1].(map[string]dbus.Variant)
+		value, _ := changed["Value"].Value().([]byte)
+		if len(value) != 1 || value[0] != 50 {
+			t.Fatalf("Expected the last value, got %v", value)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("PropertiesChanged not received")
+	}
+
+	select {
+	case sig := <-signals:
+		t.Fatalf("Unexpected signal %v", sig.Body)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
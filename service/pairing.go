@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//ErrPairingTimeout no device paired before the end of the pairing mode
+var ErrPairingTimeout = errors.New("Pairing mode timed out")
+
+//EnterPairingMode make the device pairable until a central pairs, the
+// timeout expires or ctx is done. It registers a default pairing agent,
+// opens a discoverable window on the adapter, advertises as general
+// discoverable and accepts the pairing of the first device only, see
+// ApplicationConfig.OnPairing. The previous state is restored on return.
+//
+// The application must be registered, it is advertised during the pairing
+// mode if it is not yet. It returns the path of the paired device
+func (app *Application) EnterPairingMode(ctx context.Context, timeout time.Duration) (dbus.ObjectPath, error) {
+
+	adapterID := app.adapterID
+	if adapterID == "" {
+		return "", errors.New("Application not registered")
+	}
+
+	// subscribe first, not to miss a fast pairing
+	dispatcher := bluez.GetSignalDispatcher(app.config.Conn)
+	signals, err := dispatcher.Subscribe(bluez.SignalFilter{
+		Path:          dbus.ObjectPath("/org/bluez/" + adapterID),
+		PathNamespace: true,
+		Interface:     bluez.PropertiesInterface,
+		Member:        "PropertiesChanged",
+	})
+	if err != nil {
+		return "", err
+	}
+	defer dispatcher.Unsubscribe(signals)
+
+	capability := app.config.PairingCapability
+	if capability == "" {
+		capability = profile.AgentCapabilityNoInputNoOutput
+	}
+	agent, err := api.NewAgent(string(app.Path())+"/agent", capability)
+	if err != nil {
+		return "", err
+	}
+	if err = agent.Register(true); err != nil {
+		agent.Unregister()
+		return "", err
+	}
+	defer agent.Unregister()
+
+	window, err := api.OpenDiscoverableWindow(adapterID, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer window.Close()
+
+	advertising := app.Advertising()
+	if err = app.advertiseDiscoverable(adapterID, true); err != nil {
+		return "", err
+	}
+	defer func() {
+		if advertising {
+			app.advertiseDiscoverable(adapterID, false)
+			return
+		}
+		app.advertisingMutex.Lock()
+		defer app.advertisingMutex.Unlock()
+		app.discoverable = false
+		app.stopAdvertising()
+	}()
+
+	// accepted the device allowed to pair, the first one sending a request
+	var accepted dbus.ObjectPath
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-window.Done():
+			return "", ErrPairingTimeout
+		case ev := <-agent.Events():
+			switch ev.(type) {
+			case api.PairingCompleteEvent, api.PairingFailedEvent:
+				// the outcome comes with Paired
+				continue
+			}
+			device := dbus.ObjectPath(ev.GetDevice().Path)
+			if accepted == "" {
+				accepted = device
+			}
+			app.answerPairing(ev, device == accepted)
+		case sig := <-signals:
+			device, ok := pairedDevice(sig)
+			if ok && (accepted == "" || device == accepted) {
+				return device, nil
+			}
+		}
+	}
+}
+
+// advertiseDiscoverable restart the advertisement with the general
+// discoverable flag set or left to the adapter
+func (app *Application) advertiseDiscoverable(adapterID string, discoverable bool) error {
+	app.advertisingMutex.Lock()
+	defer app.advertisingMutex.Unlock()
+	if err := app.stopAdvertising(); err != nil {
+		return err
+	}
+	app.discoverable = discoverable
+	return app.startAdvertising(adapterID)
+}
+
+// answerPairing apply the one-shot accept policy of the pairing mode: the
+// requests of the other devices are rejected
+func (app *Application) answerPairing(ev api.PairingEvent, allowed bool) {
+
+	if allowed && app.config.OnPairing != nil {
+		app.config.OnPairing(ev)
+		return
+	}
+	if !allowed {
+		app.Logger().Infof("Pairing mode: rejecting %s", ev.GetDevice().Path)
+	}
+
+	switch e := ev.(type) {
+	case api.ConfirmationRequestEvent:
+		e.Accept(allowed)
+	case api.AuthorizationRequestEvent:
+		e.Accept(allowed)
+	case api.PinCodeRequestEvent:
+		e.Reply("", false)
+	case api.PasskeyRequestEvent:
+		e.Reply(0, false)
+	}
+}
+
+// pairedDevice return the device of a PropertiesChanged setting Paired
+func pairedDevice(sig *dbus.Signal) (dbus.ObjectPath, bool) {
+	if sig == nil || len(sig.Body) < 2 {
+		return "", false
+	}
+	if iface, _ := sig.Body[0].(string); iface != bluez.Device1Interface {
+		return "", false
+	}
+	changed, _ := sig.Body[1].(map[string]dbus.Variant)
+	paired, _ := changed["Paired"].Value().(bool)
+	return sig.Path, paired
+}
@@ -0,0 +1,85 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestPairingMode(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	d, err := b.AddDevice("hci0", "00:00:00:00:00:01", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+		LocalName:  "bluetest",
+		Logger:     bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = app.EnterPairingMode(context.Background(), time.Second); err == nil {
+		t.Fatal("Expected an error for an application not registered")
+	}
+	if err = app.Register("hci0"); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Unregister()
+
+	type result struct {
+		device dbus.ObjectPath
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		device, err := app.EnterPairingMode(context.Background(), 5*time.Second)
+		done <- result{device, err}
+	}()
+
+	a := b.Adapter("hci0")
+	for i := 0; len(b.Agents()) == 0 || len(a.Advertisements()) == 0; i++ {
+		if i == 50 {
+			t.Fatal("Timeout waiting for the pairing mode")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !a.Property("Discoverable").(bool) || !a.Property("Pairable").(bool) {
+		t.Fatal("Expected the adapter discoverable and pairable")
+	}
+	if v, ok := a.Advertisements()[0].Properties()["Discoverable"]; !ok || !v.Value().(bool) {
+		t.Fatal("Expected a general discoverable advertisement")
+	}
+
+	if err = d.PairFromRemote(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-done:
+		if r.err != nil || r.device != d.Path {
+			t.Fatalf("Expected %s paired, got %s %v", d.Path, r.device, r.err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the pairing")
+	}
+
+	if len(b.Agents()) != 0 || len(a.Advertisements()) != 0 || a.Property("Discoverable").(bool) {
+		t.Fatal("Expected the state restored")
+	}
+}
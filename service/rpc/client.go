@@ -0,0 +1,173 @@
+package rpc
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+//DefaultTimeout the time allowed to the peripheral to answer a request
+const DefaultTimeout = 10 * time.Second
+
+// characteristic the client operations used on the characteristics,
+// implemented by *profile.GattCharacteristic1
+type characteristic interface {
+	WriteValue(b []byte, options map[string]dbus.Variant) error
+	Notifications() (<-chan []byte, func(), error)
+}
+
+var _ characteristic = (*profile.GattCharacteristic1)(nil)
+
+//ClientOptions configure a Client
+type ClientOptions struct {
+	RequestUUID  string
+	ResponseUUID string
+	// MTU the ATT MTU of the connection, DefaultMTU if zero
+	MTU int
+	// MaxMessageSize the largest response, DefaultMaxMessageSize if zero
+	MaxMessageSize int
+	// Timeout DefaultTimeout if zero
+	Timeout time.Duration
+}
+
+// result a response, or the error of its reassembly
+type result struct {
+	msg []byte
+	err error
+}
+
+//Client the central side of the protocol, safe for concurrent calls
+type Client struct {
+	request characteristic
+	stop    func()
+	opts    ClientOptions
+
+	mutex   sync.Mutex
+	nextID  uint16
+	pending map[uint16]chan result
+	closed  bool
+}
+
+//NewClient connect to the characteristics of a Server on a device
+func NewClient(dev *api.Device, opts ClientOptions) (*Client, error) {
+	request, err := dev.GetCharByUUID(opts.RequestUUID)
+	if err != nil {
+		return nil, err
+	}
+	response, err := dev.GetCharByUUID(opts.ResponseUUID)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(request, response, opts)
+}
+
+func newClient(request, response characteristic, opts ClientOptions) (*Client, error) {
+	if opts.MTU == 0 {
+		opts.MTU = DefaultMTU
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	notifications, stop, err := response.Notifications()
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		request: request,
+		stop:    stop,
+		opts:    opts,
+		// the responses are notified to all the centrals, start at a
+		// random ID to not collect the fragments of the others
+		nextID:  uint16(rand.Intn(0x10000)),
+		pending: make(map[uint16]chan result),
+	}
+	go c.receive(notifications)
+	return c, nil
+}
+
+//Close stop the notifications, the pending calls fail
+func (c *Client) Close() {
+	c.stop()
+}
+
+// receive dispatch the responses to the pending calls
+func (c *Client) receive(notifications <-chan []byte) {
+
+	r := newReassembler(c.opts.MaxMessageSize)
+	for b := range notifications {
+		id, msg, err := r.add(b)
+		if err == nil && msg == nil {
+			continue
+		}
+		c.mutex.Lock()
+		ch, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		if !ok {
+			// the response to another central
+			continue
+		}
+		ch <- result{msg, err}
+	}
+
+	c.mutex.Lock()
+	c.closed = true
+	for id, ch := range c.pending {
+		ch <- result{err: fmt.Errorf("RPC notifications stopped")}
+		delete(c.pending, id)
+	}
+	c.mutex.Unlock()
+}
+
+//Call send a request and wait for its response body. A status other than
+// StatusOK is returned as an *Error
+func (c *Client) Call(method uint8, body []byte) ([]byte, error) {
+
+	ch := make(chan result, 1)
+
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil, fmt.Errorf("RPC client closed")
+	}
+	id := c.nextID
+	c.nextID++
+	c.pending[id] = ch
+	c.mutex.Unlock()
+
+	cancel := func() {
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+	}
+
+	msg := append([]byte{method}, body...)
+	for _, b := range fragment(id, msg, fragmentSize(c.opts.MTU)) {
+		if err := c.request.WriteValue(b, nil); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	select {
+	case res := <-ch:
+		if res.err != nil {
+			return nil, res.err
+		}
+		if len(res.msg) == 0 {
+			return nil, fmt.Errorf("RPC message %d: empty response", id)
+		}
+		if res.msg[0] != StatusOK {
+			return nil, &Error{Status: res.msg[0], Message: string(res.msg[1:])}
+		}
+		return res.msg[1:], nil
+	case <-time.After(c.opts.Timeout):
+		cancel()
+		return nil, fmt.Errorf("RPC message %d: no response", id)
+	}
+}
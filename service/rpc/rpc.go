@@ -0,0 +1,163 @@
+//Package rpc a request / response protocol over a pair of characteristics:
+// the central writes the requests to a request characteristic and the
+// peripheral notifies the responses on a response characteristic.
+//
+// Each message is split in fragments fitting the ATT MTU, prefixed by a
+// 3 bytes header: the flags, with the index of the fragment in the upper 6
+// bits, and the correlation ID of the message, little endian. The fragments
+// are reordered by their index. A request starts with its method, a
+// response with its status
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Fragment flags
+const (
+	FlagFirst uint8 = 0x01
+	FlagLast  uint8 = 0x02
+)
+
+// Response statuses
+const (
+	StatusOK            uint8 = 0x00
+	StatusFailed        uint8 = 0x01
+	StatusUnknownMethod uint8 = 0x02
+	StatusInvalid       uint8 = 0x03
+)
+
+// headerSize the size of the fragment header
+const headerSize = 3
+
+//DefaultMTU the ATT MTU used when the peer does not report one
+const DefaultMTU = 23
+
+//DefaultMaxMessageSize the largest message reassembled
+const DefaultMaxMessageSize = 4096
+
+//ErrUnknownMethod return it from a Handler for a method not implemented
+var ErrUnknownMethod = &Error{Status: StatusUnknownMethod, Message: "Unknown method"}
+
+//Error a response with a status other than StatusOK, its body is the message
+type Error struct {
+	Status  uint8
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("RPC failed with status 0x%02x: %s", e.Status, e.Message)
+}
+
+// fragmentSize return the payload of a fragment for an ATT MTU
+func fragmentSize(mtu int) int {
+	if mtu < DefaultMTU {
+		mtu = DefaultMTU
+	}
+	// the ATT header of a write or a notification
+	return mtu - 3 - headerSize
+}
+
+// fragment split a message in fragments of at most size bytes of payload
+func fragment(id uint16, msg []byte, size int) [][]byte {
+	fragments := make([][]byte, 0, len(msg)/size+1)
+	for i, offset := 0, 0; offset < len(msg) || i == 0; i++ {
+		end := offset + size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		flags := uint8(i%64) << 2
+		if i == 0 {
+			flags |= FlagFirst
+		}
+		if end == len(msg) {
+			flags |= FlagLast
+		}
+		b := make([]byte, headerSize, headerSize+end-offset)
+		b[0] = flags
+		binary.LittleEndian.PutUint16(b[1:], id)
+		fragments = append(fragments, append(b, msg[offset:end]...))
+		offset = end
+	}
+	return fragments
+}
+
+// maxEarly the fragments buffered ahead of a missing one
+const maxEarly = 32
+
+// partial a message being reassembled
+type partial struct {
+	next uint8
+	data []byte
+	// early the fragments received ahead of next, the signals of the
+	// notifications are not delivered in order by godbus
+	early map[uint8][]byte
+}
+
+// reassembler collect the fragments of the messages, by correlation ID
+type reassembler struct {
+	max     int
+	pending map[uint16]*partial
+}
+
+func newReassembler(max int) *reassembler {
+	if max == 0 {
+		max = DefaultMaxMessageSize
+	}
+	return &reassembler{
+		max:     max,
+		pending: make(map[uint16]*partial),
+	}
+}
+
+// add a fragment, it returns the message once complete. A fragment lost
+// drops its message
+func (r *reassembler) add(b []byte) (uint16, []byte, error) {
+
+	if len(b) < headerSize {
+		return 0, nil, errors.New("RPC fragment too short")
+	}
+	id := binary.LittleEndian.Uint16(b[1:])
+	index := b[0] >> 2
+
+	p, ok := r.pending[id]
+	if !ok {
+		p = &partial{early: make(map[uint8][]byte)}
+		r.pending[id] = p
+	}
+
+	if index != p.next {
+		if _, ok := p.early[index]; ok || len(p.early) >= maxEarly {
+			delete(r.pending, id)
+			return id, nil, fmt.Errorf("RPC message %d: fragment %d lost", id, p.next)
+		}
+		p.early[index] = b
+		return id, nil, nil
+	}
+
+	for {
+		if len(p.data)+len(b)-headerSize > r.max {
+			delete(r.pending, id)
+			return id, nil, fmt.Errorf("RPC message %d larger than %d bytes", id, r.max)
+		}
+		p.data = append(p.data, b[headerSize:]...)
+		p.next = (p.next + 1) % 64
+
+		if b[0]&FlagLast != 0 {
+			delete(r.pending, id)
+			if p.data == nil {
+				p.data = []byte{}
+			}
+			return id, p.data, nil
+		}
+
+		next, ok := p.early[p.next]
+		if !ok {
+			return id, nil, nil
+		}
+		delete(p.early, p.next)
+		b = next
+	}
+}
@@ -0,0 +1,279 @@
+package rpc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+const (
+	testService  = "4A980001-0000-1000-8000-00805F9B34FB"
+	testRequest  = "4A980002-0000-1000-8000-00805F9B34FB"
+	testResponse = "4A980003-0000-1000-8000-00805F9B34FB"
+)
+
+func TestFragment(t *testing.T) {
+
+	msg := make([]byte, 100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	fragments := fragment(7, msg, fragmentSize(DefaultMTU))
+	if len(fragments) != 6 {
+		t.Fatalf("Expected 6 fragments, got %d", len(fragments))
+	}
+	if fragments[0][0] != FlagFirst || fragments[5][0] != FlagLast|5<<2 {
+		t.Fatalf("Unexpected flags %x %x", fragments[0][0], fragments[5][0])
+	}
+
+	r := newReassembler(0)
+	for i, b := range fragments {
+		id, out, err := r.add(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != 7 {
+			t.Fatalf("Unexpected id %d", id)
+		}
+		if i < len(fragments)-1 && out != nil {
+			t.Fatalf("Message complete at fragment %d", i)
+		}
+		if i == len(fragments)-1 && !bytes.Equal(out, msg) {
+			t.Fatalf("Unexpected message %x", out)
+		}
+	}
+
+	// an empty message is a single fragment
+	empty := fragment(1, nil, 20)
+	if len(empty) != 1 || empty[0][0] != FlagFirst|FlagLast {
+		t.Fatalf("Unexpected empty message %x", empty)
+	}
+	if _, out, err := r.add(empty[0]); err != nil || out == nil || len(out) != 0 {
+		t.Fatalf("Unexpected empty message %x %v", out, err)
+	}
+
+	// the fragments are reordered
+	for i, j := range []int{1, 0, 3, 2, 5, 4} {
+		_, out, err := r.add(fragments[j])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < len(fragments)-1 && out != nil {
+			t.Fatalf("Message complete at fragment %d", i)
+		}
+		if i == len(fragments)-1 && !bytes.Equal(out, msg) {
+			t.Fatalf("Unexpected reordered message %x", out)
+		}
+	}
+
+	// a lost fragment drops the message
+	if _, _, err := r.add(fragments[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.add(fragments[2]); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.add(fragments[2]); err == nil {
+		t.Fatal("Lost fragment not detected")
+	}
+
+	r = newReassembler(50)
+	for _, b := range fragments[:3] {
+		_, _, err := r.add(b)
+		if err != nil {
+			if !strings.Contains(err.Error(), "larger") {
+				t.Fatal(err)
+			}
+			return
+		}
+	}
+	t.Fatal("Message larger than the limit accepted")
+}
+
+// remoteRequest write the requests through the fake bluez
+type remoteRequest struct {
+	remote *bluetest.Application
+	mtu    uint16
+}
+
+func (r *remoteRequest) WriteValue(b []byte, options map[string]dbus.Variant) error {
+	return r.remote.WriteValue(testRequest, b, map[string]dbus.Variant{
+		"device": dbus.MakeVariant(dbus.ObjectPath("/org/bluez/hci0/dev_00_11_22_33_44_66")),
+		"mtu":    dbus.MakeVariant(r.mtu),
+	})
+}
+
+func (r *remoteRequest) Notifications() (<-chan []byte, func(), error) {
+	return nil, nil, errors.New("Not supported")
+}
+
+// remoteResponse receive the notifications of the response characteristic
+type remoteResponse struct {
+	conn *dbus.Conn
+	path dbus.ObjectPath
+}
+
+func (r *remoteResponse) WriteValue(b []byte, options map[string]dbus.Variant) error {
+	return errors.New("Not supported")
+}
+
+func (r *remoteResponse) Notifications() (<-chan []byte, func(), error) {
+	dispatcher := bluez.GetSignalDispatcher(r.conn)
+	signals, err := dispatcher.Subscribe(bluez.SignalFilter{
+		Path:      r.path,
+		Interface: bluez.PropertiesInterface,
+		Member:    "PropertiesChanged",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	values := make(chan []byte, 64)
+	done := make(chan bool)
+	go func() {
+		defer close(values)
+		for {
+			select {
+			case sig := <-signals:
+				if sig == nil || len(sig.Body) < 2 {
+					continue
+				}
+				changed, _ := sig.Body[1].(map[string]dbus.Variant)
+				if value, ok := changed["Value"].Value().([]byte); ok {
+					values <- value
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return values, func() {
+		once.Do(func() {
+			dispatcher.Unsubscribe(signals)
+			close(done)
+		})
+	}, nil
+}
+
+func TestCall(t *testing.T) {
+
+	b, err := bluetest.Start()
+	if err != nil {
+		t.Skipf("Cannot start the fake bluez: %s", err.Error())
+	}
+	defer b.Close()
+	if _, err = b.AddAdapter("hci0", "00:11:22:33:44:55"); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.rpctest",
+		ObjectPath: "/rpctest",
+		Logger:     bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	devices := map[dbus.ObjectPath]bool{}
+	server, err := NewServer(app, ServerConfig{
+		ServiceUUID:  testService,
+		RequestUUID:  testRequest,
+		ResponseUUID: testResponse,
+		Handler: func(req *Request) ([]byte, error) {
+			mutex.Lock()
+			devices[req.Device] = true
+			mutex.Unlock()
+			switch req.Method {
+			case 1:
+				// echo, reversed
+				out := make([]byte, len(req.Body))
+				for i, c := range req.Body {
+					out[len(out)-1-i] = c
+				}
+				return out, nil
+			case 2:
+				return nil, errors.New("Failing method")
+			}
+			return nil, ErrUnknownMethod
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = profile.NewGattManager1("hci0").RegisterApplication(app.Path(), map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := b.Adapter("hci0").Applications()[0]
+
+	var responsePath dbus.ObjectPath
+	for _, char := range server.Service().GetCharacteristics() {
+		if char.UUID() == testResponse {
+			responsePath = char.Path()
+		}
+	}
+
+	c, err := newClient(
+		&remoteRequest{remote: remote, mtu: 64},
+		&remoteResponse{conn: b.ClientConn(), path: responsePath},
+		ClientOptions{Timeout: 2 * time.Second},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	body := bytes.Repeat([]byte("0123456789"), 30)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			in := append([]byte{byte(i)}, body...)
+			out, err := c.Call(1, in)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for j := range in {
+				if out[len(out)-1-j] != in[j] {
+					t.Errorf("Unexpected response %x", out)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	_, err = c.Call(2, nil)
+	if e, ok := err.(*Error); !ok || e.Status != StatusFailed || e.Message != "Failing method" {
+		t.Fatalf("Expected StatusFailed, got %v", err)
+	}
+	_, err = c.Call(9, nil)
+	if e, ok := err.(*Error); !ok || e.Status != StatusUnknownMethod {
+		t.Fatalf("Expected StatusUnknownMethod, got %v", err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !devices["/org/bluez/hci0/dev_00_11_22_33_44_66"] {
+		t.Fatalf("Unexpected devices %v", devices)
+	}
+}
@@ -0,0 +1,206 @@
+package rpc
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+//Request a request received by a Server
+type Request struct {
+	// Device the central sending the request, empty with the older bluez
+	// versions
+	Device dbus.ObjectPath
+	ID     uint16
+	Method uint8
+	Body   []byte
+}
+
+//Handler handle a request and return the body of its response. An *Error
+// is returned with its status, the other errors with StatusFailed
+type Handler func(req *Request) ([]byte, error)
+
+//ServerConfig configure a Server
+type ServerConfig struct {
+	ServiceUUID  string
+	RequestUUID  string
+	ResponseUUID string
+	// Advertise the service UUID
+	Advertise bool
+	// MTU the ATT MTU of the responses when the central does not report
+	// one, DefaultMTU if zero
+	MTU int
+	// MaxMessageSize the largest request, DefaultMaxMessageSize if zero
+	MaxMessageSize int
+	// Handler called for each request, in its own goroutine
+	Handler Handler
+}
+
+//Server the peripheral side of the protocol. The responses are notified to
+// all the subscribed centrals, each discards the IDs it did not send
+type Server struct {
+	srv      *service.GattService1
+	response *service.GattCharacteristic1
+	config   ServerConfig
+
+	mutex     sync.Mutex
+	requests  map[dbus.ObjectPath]*reassembler
+	mtu       map[dbus.ObjectPath]int
+	responses sync.Mutex
+}
+
+//NewServer add the service of the protocol to an application
+func NewServer(app *service.Application, config ServerConfig) (*Server, error) {
+
+	if config.ServiceUUID == "" || config.RequestUUID == "" || config.ResponseUUID == "" {
+		return nil, errors.New("The service, request and response UUIDs are required")
+	}
+	if config.Handler == nil {
+		return nil, errors.New("Handler is required")
+	}
+	if config.MTU == 0 {
+		config.MTU = DefaultMTU
+	}
+
+	s := &Server{
+		config:   config,
+		requests: make(map[dbus.ObjectPath]*reassembler),
+		mtu:      make(map[dbus.ObjectPath]int),
+	}
+
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: true,
+		UUID:    config.ServiceUUID,
+	}, config.Advertise)
+	if err != nil {
+		return nil, err
+	}
+	if err = app.AddService(srv); err != nil {
+		return nil, err
+	}
+	s.srv = srv
+
+	request, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID: config.RequestUUID,
+		Flags: []string{
+			bluez.FlagCharacteristicWrite,
+			bluez.FlagCharacteristicWriteWithoutResponse,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	request.SetWriteFunc(func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+		return s.receive(value, options)
+	})
+	if err = srv.AddCharacteristic(request); err != nil {
+		return nil, err
+	}
+
+	s.response, err = srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID:  config.ResponseUUID,
+		Flags: []string{bluez.FlagCharacteristicNotify},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = srv.AddCharacteristic(s.response); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+//Service return the GATT service
+func (s *Server) Service() *service.GattService1 {
+	return s.srv
+}
+
+// optionMTU extract the ATT MTU from the WriteValue options, reported by
+// bluez 5.62 and later
+func optionMTU(options map[string]interface{}) (int, bool) {
+	val, ok := options["mtu"]
+	if !ok {
+		return 0, false
+	}
+	if v, ok := val.(dbus.Variant); ok {
+		val = v.Value()
+	}
+	mtu, ok := val.(uint16)
+	return int(mtu), ok
+}
+
+// receive reassemble a request fragment, the complete requests are handled
+// in their own goroutine
+func (s *Server) receive(value []byte, options map[string]interface{}) error {
+
+	device, _ := service.OptionDevice(options)
+
+	s.mutex.Lock()
+	if mtu, ok := optionMTU(options); ok {
+		s.mtu[device] = mtu
+	}
+	r, ok := s.requests[device]
+	if !ok {
+		r = newReassembler(s.config.MaxMessageSize)
+		s.requests[device] = r
+	}
+	id, msg, err := r.add(value)
+	mtu, ok := s.mtu[device]
+	if !ok {
+		mtu = s.config.MTU
+	}
+	s.mutex.Unlock()
+
+	if err != nil {
+		return bluez.ErrInvalidArguments.WithMessage(err.Error())
+	}
+	if msg == nil {
+		return nil
+	}
+	if len(msg) == 0 {
+		go s.respond(id, mtu, StatusInvalid, []byte("Empty request"))
+		return nil
+	}
+
+	req := &Request{
+		Device: device,
+		ID:     id,
+		Method: msg[0],
+		Body:   msg[1:],
+	}
+	go s.handle(req, mtu)
+	return nil
+}
+
+// handle call the handler and send its response
+func (s *Server) handle(req *Request, mtu int) {
+	var body []byte
+	err := bluez.SafeCall(bluez.GetLogger(), "rpc.Handler", func() (err error) {
+		body, err = s.config.Handler(req)
+		return err
+	})
+	if err != nil {
+		if e, ok := err.(*Error); ok {
+			s.respond(req.ID, mtu, e.Status, []byte(e.Message))
+			return
+		}
+		s.respond(req.ID, mtu, StatusFailed, []byte(err.Error()))
+		return
+	}
+	s.respond(req.ID, mtu, StatusOK, body)
+}
+
+// respond notify the fragments of a response, the fragments of the
+// responses are not interleaved
+func (s *Server) respond(id uint16, mtu int, status uint8, body []byte) {
+	s.responses.Lock()
+	defer s.responses.Unlock()
+	for _, b := range fragment(id, append([]byte{status}, body...), fragmentSize(mtu)) {
+		s.response.UpdateValue(b)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//SecurityPolicy security requirements applied to every characteristic and
+// descriptor of an Application.
+// Flags are upgraded to the matching encrypt-*, encrypt-authenticated-* or
+// secure-* variant, so bluez enforces the requirement at the ATT level. With
+// RequireAuthentication or RequireSecureConnections the read and write
+// requests coming from a device that is not bonded are rejected as well
+type SecurityPolicy struct {
+	// RequireEncryption the link must be encrypted
+	RequireEncryption bool
+	// RequireAuthentication the link must be encrypted with an authenticated (MITM protected) key
+	RequireAuthentication bool
+	// RequireSecureConnections the link must use LE Secure Connections
+	RequireSecureConnections bool
+}
+
+//IsEmpty return true if the policy does not require anything
+func (p SecurityPolicy) IsEmpty() bool {
+	return !p.RequireEncryption && !p.RequireAuthentication && !p.RequireSecureConnections
+}
+
+// ApplyFlags return a list of flags satisfying the policy. bluez has no
+// secure variant of write-without-response, the matching write flag is added
+// so that the attribute requires the security level for the commands too
+func (p SecurityPolicy) ApplyFlags(flags []string) []string {
+
+	if p.IsEmpty() {
+		return flags
+	}
+
+	read := bluez.FlagCharacteristicEncryptRead
+	write := bluez.FlagCharacteristicEncryptWrite
+	if p.RequireAuthentication {
+		read = bluez.FlagCharacteristicEncryptAuthenticatedRead
+		write = bluez.FlagCharacteristicEncryptAuthenticatedWrite
+	}
+	if p.RequireSecureConnections {
+		read = bluez.FlagCharacteristicSecureRead
+		write = bluez.FlagCharacteristicSecureWrite
+	}
+
+	result := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		switch flag {
+		case bluez.FlagCharacteristicRead,
+			bluez.FlagCharacteristicEncryptRead,
+			bluez.FlagCharacteristicEncryptAuthenticatedRead,
+			bluez.FlagCharacteristicSecureRead:
+			flag = read
+		case bluez.FlagCharacteristicWrite,
+			bluez.FlagCharacteristicEncryptWrite,
+			bluez.FlagCharacteristicEncryptAuthenticatedWrite,
+			bluez.FlagCharacteristicSecureWrite:
+			flag = write
+		case bluez.FlagCharacteristicWriteWithoutResponse:
+			if !hasFlag(result, write) {
+				result = append(result, write)
+			}
+		}
+		if !hasFlag(result, flag) {
+			result = append(result, flag)
+		}
+	}
+	return result
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+//SecurityRequirement identify the requirement that was not met
+type SecurityRequirement string
+
+// Requirements reported by SecurityError
+const (
+	SecurityRequirementBonding SecurityRequirement = "bonding"
+)
+
+//SecurityError the link to a device does not satisfy the SecurityPolicy
+type SecurityError struct {
+	Device      dbus.ObjectPath
+	Requirement SecurityRequirement
+}
+
+func (e *SecurityError) Error() string {
+	return "Device " + string(e.Device) + " does not satisfy security requirement: " + string(e.Requirement)
+}
+
+//DBusError return the error reported to bluez, mapped to ATT Insufficient Authorization
+func (e *SecurityError) DBusError() *dbus.Error {
+	return bluez.ErrNotAuthorized.WithMessage(e.Error()).DBusError()
+}
+
+//OptionDevice extract the remote device from ReadValue / WriteValue options,
+// not reported by the older bluez versions
+func OptionDevice(options map[string]interface{}) (dbus.ObjectPath, bool) {
+	val, ok := options["device"]
+	if !ok {
+		return "", false
+	}
+	if v, ok := val.(dbus.Variant); ok {
+		val = v.Value()
+	}
+	path, ok := val.(dbus.ObjectPath)
+	return path, ok
+}
+
+// requireBonding return true if the policy needs a bonded device: an
+// authenticated key or a Secure Connections one comes from a pairing, an
+// encrypted link is left to the encrypt-* flags
+func (p SecurityPolicy) requireBonding() bool {
+	return p.RequireAuthentication || p.RequireSecureConnections
+}
+
+//CheckSecurity verify the device issuing a request satisfies the
+// application SecurityPolicy. The level of the link is enforced by bluez with
+// the flags, see ApplyFlags, the check rejects the devices not bonded when
+// the policy requires it
+func (app *Application) CheckSecurity(options map[string]interface{}) *SecurityError {
+
+	if !app.config.Security.requireBonding() {
+		return nil
+	}
+
+	path, ok := OptionDevice(options)
+	if !ok {
+		// older bluez versions do not report the device, rely on flags
+		return nil
+	}
+
+	paired, err := app.devicePaired(path)
+	if err != nil {
+		app.Logger().Warnf("Cannot read the pairing of %s: %s", path, err.Error())
+	}
+	if !paired {
+		return &SecurityError{path, SecurityRequirementBonding}
+	}
+	return nil
+}
+
+// devicePaired return the Paired property of a device, read on the
+// application connection. A paired device is cached until it disconnects, see
+// watchPairedDevices
+func (app *Application) devicePaired(path dbus.ObjectPath) (bool, error) {
+
+	app.pairedMutex.Lock()
+	paired := app.paired[path]
+	app.pairedMutex.Unlock()
+	if paired {
+		return true, nil
+	}
+
+	v, err := app.config.Conn.Object(bluez.OrgBluez, path).GetProperty(bluez.Device1Interface + ".Paired")
+	if err != nil {
+		return false, bluez.ParseError(err)
+	}
+	paired, _ = v.Value().(bool)
+	if paired {
+		app.pairedMutex.Lock()
+		if app.paired != nil {
+			app.paired[path] = true
+		}
+		app.pairedMutex.Unlock()
+	}
+	return paired, nil
+}
+
+// watchPairedDevices cache the paired devices while they are connected, when
+// the policy requires bonding. The bonds are removed by bluez with the
+// device, disconnected first
+func (app *Application) watchPairedDevices() error {
+
+	if !app.config.Security.requireBonding() {
+		return nil
+	}
+	events, stop, err := app.WatchConnections()
+	if err != nil {
+		return err
+	}
+
+	app.pairedMutex.Lock()
+	app.paired = make(map[dbus.ObjectPath]bool)
+	app.pairedWatch = stop
+	app.pairedMutex.Unlock()
+
+	go func() {
+		for ev := range events {
+			if ev.Connected {
+				continue
+			}
+			app.pairedMutex.Lock()
+			delete(app.paired, ev.Device)
+			app.pairedMutex.Unlock()
+		}
+	}()
+	return nil
+}
+
+// unwatchPairedDevices stop caching the paired devices
+func (app *Application) unwatchPairedDevices() {
+	app.pairedMutex.Lock()
+	stop := app.pairedWatch
+	app.paired = nil
+	app.pairedWatch = nil
+	app.pairedMutex.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
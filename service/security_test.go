@@ -0,0 +1,142 @@
+package service_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestApplyFlags(t *testing.T) {
+
+	flags := []string{
+		bluez.FlagCharacteristicRead,
+		bluez.FlagCharacteristicWriteWithoutResponse,
+		bluez.FlagCharacteristicNotify,
+	}
+	tests := []struct {
+		policy   service.SecurityPolicy
+		expected []string
+	}{
+		{service.SecurityPolicy{}, flags},
+		{service.SecurityPolicy{RequireEncryption: true}, []string{
+			bluez.FlagCharacteristicEncryptRead,
+			bluez.FlagCharacteristicEncryptWrite,
+			bluez.FlagCharacteristicWriteWithoutResponse,
+			bluez.FlagCharacteristicNotify,
+		}},
+		{service.SecurityPolicy{RequireAuthentication: true}, []string{
+			bluez.FlagCharacteristicEncryptAuthenticatedRead,
+			bluez.FlagCharacteristicEncryptAuthenticatedWrite,
+			bluez.FlagCharacteristicWriteWithoutResponse,
+			bluez.FlagCharacteristicNotify,
+		}},
+		{service.SecurityPolicy{RequireAuthentication: true, RequireSecureConnections: true}, []string{
+			bluez.FlagCharacteristicSecureRead,
+			bluez.FlagCharacteristicSecureWrite,
+			bluez.FlagCharacteristicWriteWithoutResponse,
+			bluez.FlagCharacteristicNotify,
+		}},
+	}
+	for _, test := range tests {
+		result := test.policy.ApplyFlags(flags)
+		if strings.Join(result, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%+v: expected %v, got %v", test.policy, test.expected, result)
+		}
+	}
+
+	// a write flag already there is not duplicated
+	result := service.SecurityPolicy{RequireEncryption: true}.ApplyFlags([]string{
+		bluez.FlagCharacteristicWrite,
+		bluez.FlagCharacteristicWriteWithoutResponse,
+	})
+	if strings.Join(result, ",") != bluez.FlagCharacteristicEncryptWrite+","+bluez.FlagCharacteristicWriteWithoutResponse {
+		t.Errorf("Unexpected flags %v", result)
+	}
+}
+
+func TestCheckSecurity(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	dev, err := b.AddDevice("hci0", "00:00:00:00:00:01", "central")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := map[string]interface{}{"device": dbus.MakeVariant(dev.Path)}
+
+	for n, policy := range []service.SecurityPolicy{
+		{RequireEncryption: true},
+		{RequireAuthentication: true},
+		{RequireSecureConnections: true},
+	} {
+		app, err := service.NewApplication(&service.ApplicationConfig{
+			UUIDSuffix: service.UUIDSuffix,
+			UUID:       "1234",
+			ObjectName: "org.bluez.bluetest",
+			ObjectPath: dbus.ObjectPath("/bluetest" + strconv.Itoa(n)),
+			Logger:     bluez.NopLogger{},
+			Security:   policy,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = app.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if err = app.Register("hci0"); err != nil {
+			t.Fatal(err)
+		}
+
+		dev.SetProperty("Paired", false)
+		dev.SetProperty("Connected", true)
+
+		secErr := app.CheckSecurity(options)
+		if policy.RequireEncryption {
+			// left to the encrypt-* flags
+			if secErr != nil {
+				t.Fatalf("%+v: unexpected error %s", policy, secErr)
+			}
+		} else if secErr == nil || secErr.Requirement != service.SecurityRequirementBonding {
+			t.Fatalf("%+v: expected a bonding error, got %v", policy, secErr)
+		}
+
+		dev.SetProperty("Paired", true)
+		if secErr = app.CheckSecurity(options); secErr != nil {
+			t.Fatalf("%+v: unexpected error for a paired device %s", policy, secErr)
+		}
+
+		// the older bluez versions do not report the device
+		if secErr = app.CheckSecurity(map[string]interface{}{}); secErr != nil {
+			t.Fatalf("%+v: unexpected error without device %s", policy, secErr)
+		}
+
+		if !policy.RequireEncryption {
+			// the pairing is read again after a disconnection
+			dev.SetProperty("Paired", false)
+			dev.SetProperty("Connected", false)
+			rejected := false
+			for i := 0; i < 50 && !rejected; i++ {
+				rejected = app.CheckSecurity(options) != nil
+				time.Sleep(20 * time.Millisecond)
+			}
+			if !rejected {
+				t.Fatalf("%+v: the device is still considered paired", policy)
+			}
+
+			// an unknown device is rejected
+			unknown := map[string]interface{}{"device": dbus.MakeVariant(dbus.ObjectPath("/org/bluez/hci0/dev_00_00_00_00_00_99"))}
+			if secErr = app.CheckSecurity(unknown); secErr == nil {
+				t.Fatalf("%+v: expected an error for an unknown device", policy)
+			}
+		}
+
+		app.Unregister()
+	}
+}
@@ -0,0 +1,109 @@
+package transfer
+
+import (
+	"io"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez/profile"
+)
+
+// characteristic the client operations used on the characteristics,
+// implemented by *profile.GattCharacteristic1
+type characteristic interface {
+	WriteValue(b []byte, options map[string]dbus.Variant) error
+	Notifications() (<-chan []byte, func(), error)
+}
+
+var _ characteristic = (*profile.GattCharacteristic1)(nil)
+
+//CharacteristicLink a Link of a central: the packets are written without
+// response to a characteristic and received as the notifications of another
+type CharacteristicLink struct {
+	write   characteristic
+	packets <-chan []byte
+	stop    func()
+}
+
+//Dial open a link to the characteristics of a device, eg. those of a Server
+func Dial(dev *api.Device, writeUUID string, notifyUUID string) (*CharacteristicLink, error) {
+	write, err := dev.GetCharByUUID(writeUUID)
+	if err != nil {
+		return nil, err
+	}
+	notify, err := dev.GetCharByUUID(notifyUUID)
+	if err != nil {
+		return nil, err
+	}
+	return newCharacteristicLink(write, notify)
+}
+
+func newCharacteristicLink(write, notify characteristic) (*CharacteristicLink, error) {
+	packets, stop, err := notify.Notifications()
+	if err != nil {
+		return nil, err
+	}
+	return &CharacteristicLink{
+		write:   write,
+		packets: packets,
+		stop:    stop,
+	}, nil
+}
+
+//Write a packet without response
+func (l *CharacteristicLink) Write(b []byte) error {
+	return l.write.WriteValue(b, map[string]dbus.Variant{"type": dbus.MakeVariant("command")})
+}
+
+//Packets return the notifications received
+func (l *CharacteristicLink) Packets() <-chan []byte {
+	return l.packets
+}
+
+//Close stop the notifications
+func (l *CharacteristicLink) Close() {
+	l.stop()
+}
+
+//StreamLink a Link over packet oriented streams, eg. the file descriptors
+// of AcquireWrite and AcquireNotify: each write sends a packet, each read
+// returns one. It avoids a D-Bus call per packet
+type StreamLink struct {
+	w       io.Writer
+	packets chan []byte
+	// Err the error stopping the reads, set once Packets is closed
+	Err error
+}
+
+//NewStreamLink create a link writing to w and reading from r the packets of
+// up to mtu bytes
+func NewStreamLink(w io.Writer, r io.Reader, mtu int) *StreamLink {
+	l := &StreamLink{
+		w:       w,
+		packets: make(chan []byte, DefaultWindow),
+	}
+	go func() {
+		defer close(l.packets)
+		buf := make([]byte, mtu)
+		for {
+			n, err := r.Read(buf)
+			if err != nil {
+				l.Err = err
+				return
+			}
+			l.packets <- append([]byte{}, buf[:n]...)
+		}
+	}()
+	return l
+}
+
+//Write a packet
+func (l *StreamLink) Write(b []byte) error {
+	_, err := l.w.Write(b)
+	return err
+}
+
+//Packets return the packets read
+func (l *StreamLink) Packets() <-chan []byte {
+	return l.packets
+}
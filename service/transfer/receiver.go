@@ -0,0 +1,212 @@
+package transfer
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"sync"
+)
+
+//ReceiverOptions configure a Receiver
+type ReceiverOptions struct {
+	// Window the window of the senders, DefaultWindow if zero. The data is
+	// acknowledged every half window
+	Window int
+	// MaxSize the largest blob accepted, DefaultMaxSize if zero
+	MaxSize int
+	// OnComplete called with each blob received and checked
+	OnComplete func(id uint32, data []byte)
+}
+
+// incoming a blob being received
+type incoming struct {
+	id   uint32
+	size int
+	crc  uint32
+	data []byte
+	// early the packets received ahead of the data, the writes are not
+	// delivered in order by godbus
+	early  map[int][]byte
+	nacked bool
+	// received the packets since the last acknowledgement
+	received int
+}
+
+//Receiver receive the blobs of a sender, keeping the interrupted transfers
+// to resume them
+type Receiver struct {
+	write func(b []byte) error
+	opts  ReceiverOptions
+
+	mutex     sync.Mutex
+	current   *incoming
+	finished  *incoming
+	transfers map[uint32]*incoming
+	// completed the CRC of the blobs received, by ID
+	completed map[uint32]uint32
+}
+
+//NewReceiver create a receiver replying with write, eg. the Write of a Link
+func NewReceiver(write func(b []byte) error, opts ReceiverOptions) *Receiver {
+	if opts.Window == 0 {
+		opts.Window = DefaultWindow
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	return &Receiver{
+		write:     write,
+		opts:      opts,
+		transfers: make(map[uint32]*incoming),
+		completed: make(map[uint32]uint32),
+	}
+}
+
+//Serve handle the packets until the channel is closed, eg. the Packets of a
+// Link
+func (r *Receiver) Serve(packets <-chan []byte) {
+	for b := range packets {
+		r.Handle(b)
+	}
+}
+
+//Handle a packet of the sender, OnComplete is called once a blob is
+// complete
+func (r *Receiver) Handle(b []byte) error {
+
+	r.mutex.Lock()
+	err := r.handle(b)
+	finished := r.finished
+	r.finished = nil
+	r.mutex.Unlock()
+
+	if finished != nil && r.opts.OnComplete != nil {
+		r.opts.OnComplete(finished.id, finished.data)
+	}
+	return err
+}
+
+func (r *Receiver) handle(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	switch b[0] {
+	case OpStart:
+		if len(b) < 13 {
+			return nil
+		}
+		return r.start(binary.LittleEndian.Uint32(b[1:]), int(binary.LittleEndian.Uint32(b[5:])), binary.LittleEndian.Uint32(b[9:]))
+	case OpData:
+		if len(b) < dataHeader || r.current == nil {
+			return nil
+		}
+		return r.data(r.current, int(binary.LittleEndian.Uint32(b[1:])), b[dataHeader:])
+	}
+	return nil
+}
+
+func (r *Receiver) done(id uint32, status uint8) error {
+	return r.write([]byte{OpDone, status})
+}
+
+// start accept a transfer, resuming it if the blob did not change
+func (r *Receiver) start(id uint32, size int, crc uint32) error {
+
+	if c, ok := r.completed[id]; ok && c == crc {
+		return r.done(id, StatusOK)
+	}
+	if size > r.opts.MaxSize {
+		return r.done(id, StatusTooLarge)
+	}
+
+	t, ok := r.transfers[id]
+	if !ok || t.size != size || t.crc != crc {
+		t = &incoming{
+			id:   id,
+			size: size,
+			crc:  crc,
+			data: make([]byte, 0, size),
+		}
+		r.transfers[id] = t
+	}
+	t.early = make(map[int][]byte)
+	t.nacked = false
+	t.received = 0
+	r.current = t
+	return r.write(offsetPacket(OpAccept, len(t.data)))
+}
+
+// data store a data packet, acknowledging it every half window
+func (r *Receiver) data(t *incoming, offset int, payload []byte) error {
+
+	switch {
+	case offset > len(t.data):
+		if offset+len(payload) > t.size {
+			return nil
+		}
+		t.early[offset] = append([]byte{}, payload...)
+		// a lost packet, not only reordered
+		if len(t.early) > r.opts.Window/2 && !t.nacked {
+			t.nacked = true
+			return r.write(offsetPacket(OpNack, len(t.data)))
+		}
+		return nil
+	case offset < len(t.data):
+		// sent again after a timeout, acknowledge what was received
+		t.received++
+		if t.received >= r.opts.Window/2 {
+			t.received = 0
+			return r.write(offsetPacket(OpAck, len(t.data)))
+		}
+		return nil
+	}
+
+	if len(t.data)+len(payload) > t.size {
+		return nil
+	}
+	t.data = append(t.data, payload...)
+	t.received++
+	for {
+		next, ok := t.early[len(t.data)]
+		if !ok {
+			break
+		}
+		delete(t.early, len(t.data))
+		t.data = append(t.data, next...)
+		t.received++
+	}
+	for off := range t.early {
+		if off < len(t.data) {
+			delete(t.early, off)
+		}
+	}
+	t.nacked = false
+
+	if len(t.data) == t.size {
+		delete(r.transfers, t.id)
+		r.current = nil
+		if crc32.ChecksumIEEE(t.data) != t.crc {
+			return r.done(t.id, StatusCRC)
+		}
+		r.completed[t.id] = t.crc
+		r.finished = t
+		return r.done(t.id, StatusOK)
+	}
+
+	if t.received >= r.opts.Window/2 {
+		t.received = 0
+		return r.write(offsetPacket(OpAck, len(t.data)))
+	}
+	return nil
+}
+
+//Abort drop the interrupted transfer of an ID
+func (r *Receiver) Abort(id uint32) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.transfers, id)
+	if r.current != nil && r.current.id == id {
+		r.current = nil
+		return r.done(id, StatusAborted)
+	}
+	return nil
+}
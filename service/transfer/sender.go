@@ -0,0 +1,189 @@
+package transfer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"time"
+)
+
+//SenderOptions configure a Sender
+type SenderOptions struct {
+	// PacketSize DefaultPacketSize if zero, up to the ATT MTU minus 3
+	PacketSize int
+	// Window DefaultWindow if zero
+	Window int
+	// Timeout DefaultTimeout if zero
+	Timeout time.Duration
+	// Retries DefaultRetries if zero
+	Retries int
+	// Progress called with the bytes acknowledged by the receiver
+	Progress func(sent int, total int)
+}
+
+//Sender send blobs over a link, one at a time
+type Sender struct {
+	link  Link
+	opts  SenderOptions
+	mutex sync.Mutex
+}
+
+//NewSender create a sender over a link
+func NewSender(link Link, opts SenderOptions) *Sender {
+	if opts.PacketSize == 0 {
+		opts.PacketSize = DefaultPacketSize
+	}
+	if opts.Window == 0 {
+		opts.Window = DefaultWindow
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Retries == 0 {
+		opts.Retries = DefaultRetries
+	}
+	return &Sender{link: link, opts: opts}
+}
+
+// reply a packet of the receiver
+type reply struct {
+	op     uint8
+	offset int
+	status uint8
+}
+
+// next wait for the next reply of the receiver, false on timeout
+func (s *Sender) next(timeout <-chan time.Time) (*reply, bool, error) {
+	for {
+		select {
+		case b, ok := <-s.link.Packets():
+			if !ok {
+				return nil, false, fmt.Errorf("Transfer link closed")
+			}
+			switch {
+			case len(b) >= 5 && (b[0] == OpAccept || b[0] == OpAck || b[0] == OpNack):
+				return &reply{op: b[0], offset: int(binary.LittleEndian.Uint32(b[1:]))}, true, nil
+			case len(b) >= 2 && b[0] == OpDone:
+				return &reply{op: OpDone, status: b[1]}, true, nil
+			}
+		case <-timeout:
+			return nil, false, nil
+		}
+	}
+}
+
+// start send OpStart until accepted, it returns the offset to resume from
+// or -1 if the receiver already has the blob
+func (s *Sender) start(id uint32, data []byte, crc uint32) (int, error) {
+	for retries := 0; retries <= s.opts.Retries; retries++ {
+		if err := s.link.Write(startPacket(id, len(data), crc)); err != nil {
+			return 0, err
+		}
+		timeout := time.After(s.opts.Timeout)
+		for {
+			r, ok, err := s.next(timeout)
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				break
+			}
+			switch r.op {
+			case OpAccept:
+				if r.offset > len(data) {
+					return 0, fmt.Errorf("Transfer %d: invalid offset %d", id, r.offset)
+				}
+				return r.offset, nil
+			case OpDone:
+				if r.status != StatusOK {
+					return 0, &StatusError{ID: id, Status: r.status}
+				}
+				return -1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("Transfer %d: not accepted", id)
+}
+
+//Send a blob, resuming a previous transfer with the same ID. A blob
+// already received is not sent again
+func (s *Sender) Send(id uint32, data []byte) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	crc := crc32.ChecksumIEEE(data)
+	acked, err := s.start(id, data, crc)
+	if err != nil {
+		return err
+	}
+	if acked < 0 {
+		if s.opts.Progress != nil {
+			s.opts.Progress(len(data), len(data))
+		}
+		return nil
+	}
+
+	chunk := s.opts.PacketSize - dataHeader
+	sent := acked
+	retries := 0
+	timeout := time.After(s.opts.Timeout)
+	for {
+		// fill the window
+		for sent < len(data) && sent-acked < s.opts.Window*chunk {
+			end := sent + chunk
+			if end > len(data) {
+				end = len(data)
+			}
+			if err := s.link.Write(append(offsetPacket(OpData, sent), data[sent:end]...)); err != nil {
+				return err
+			}
+			sent = end
+		}
+
+		r, ok, err := s.next(timeout)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			retries++
+			if retries > s.opts.Retries {
+				return fmt.Errorf("Transfer %d: no acknowledgement at offset %d", id, acked)
+			}
+			// go back to the last acknowledged data
+			sent = acked
+			timeout = time.After(s.opts.Timeout)
+			continue
+		}
+
+		switch r.op {
+		case OpAck, OpNack:
+			if r.offset > len(data) {
+				return fmt.Errorf("Transfer %d: invalid offset %d", id, r.offset)
+			}
+			if r.offset > acked {
+				acked = r.offset
+				retries = 0
+				timeout = time.After(s.opts.Timeout)
+				if s.opts.Progress != nil {
+					s.opts.Progress(acked, len(data))
+				}
+			}
+			if sent < acked {
+				sent = acked
+			}
+			if r.op == OpNack {
+				sent = r.offset
+			}
+		case OpDone:
+			if r.status != StatusOK {
+				return &StatusError{ID: id, Status: r.status}
+			}
+			if s.opts.Progress != nil && acked < len(data) {
+				s.opts.Progress(len(data), len(data))
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package transfer
+
+import (
+	"errors"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+//ServerConfig configure a ServerLink
+type ServerConfig struct {
+	ServiceUUID string
+	// WriteUUID the characteristic written by the central
+	WriteUUID string
+	// NotifyUUID the characteristic notified to the central
+	NotifyUUID string
+	// Advertise the service UUID
+	Advertise bool
+}
+
+//ServerLink the Link of a peripheral: the packets are received as the
+// writes of a characteristic and sent as the notifications of another.
+// Serve a Receiver on it to receive the blobs of a central, or Send them
+// to a central running a Receiver
+type ServerLink struct {
+	srv     *service.GattService1
+	notify  *service.GattCharacteristic1
+	packets chan []byte
+}
+
+//NewServerLink add the service of the link to an application
+func NewServerLink(app *service.Application, config ServerConfig) (*ServerLink, error) {
+
+	if config.ServiceUUID == "" || config.WriteUUID == "" || config.NotifyUUID == "" {
+		return nil, errors.New("The service, write and notify UUIDs are required")
+	}
+
+	l := &ServerLink{
+		packets: make(chan []byte, 4*DefaultWindow),
+	}
+
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: true,
+		UUID:    config.ServiceUUID,
+	}, config.Advertise)
+	if err != nil {
+		return nil, err
+	}
+	if err = app.AddService(srv); err != nil {
+		return nil, err
+	}
+	l.srv = srv
+
+	write, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID: config.WriteUUID,
+		Flags: []string{
+			bluez.FlagCharacteristicWrite,
+			bluez.FlagCharacteristicWriteWithoutResponse,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	write.SetWriteFunc(func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+		// a full channel slows down the central
+		l.packets <- append([]byte{}, value...)
+		return nil
+	})
+	if err = srv.AddCharacteristic(write); err != nil {
+		return nil, err
+	}
+
+	l.notify, err = srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID:  config.NotifyUUID,
+		Flags: []string{bluez.FlagCharacteristicNotify},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = srv.AddCharacteristic(l.notify); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+//Service return the GATT service
+func (l *ServerLink) Service() *service.GattService1 {
+	return l.srv
+}
+
+//Write notify a packet
+func (l *ServerLink) Write(b []byte) error {
+	l.notify.UpdateValue(b)
+	return nil
+}
+
+//Packets return the packets written by the central
+func (l *ServerLink) Packets() <-chan []byte {
+	return l.packets
+}
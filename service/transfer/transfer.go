@@ -0,0 +1,93 @@
+//Package transfer send large blobs, eg. logs or firmware images, over a
+// pair of GATT characteristics: the data is written without response and the
+// receiver acknowledges it with notifications, or the other way around.
+//
+// The data packets carry their offset, which sequences them and lets the
+// receiver ask the sender to go back after a loss. The blob is checked with
+// its CRC32 once complete, and a transfer interrupted, eg. by a disconnection,
+// resumes where it stopped when started again with the same ID
+package transfer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Op codes, from the sender
+const (
+	OpStart uint8 = 0x01
+	OpData  uint8 = 0x02
+)
+
+// Op codes, from the receiver
+const (
+	OpAccept uint8 = 0x81
+	OpAck    uint8 = 0x82
+	OpNack   uint8 = 0x83
+	OpDone   uint8 = 0x84
+)
+
+// Statuses of OpDone
+const (
+	StatusOK       uint8 = 0x00
+	StatusCRC      uint8 = 0x01
+	StatusTooLarge uint8 = 0x02
+	StatusAborted  uint8 = 0x03
+)
+
+// dataHeader the op code and the offset of a data packet
+const dataHeader = 5
+
+//DefaultPacketSize the size of the packets, the payload of the default ATT
+// MTU
+const DefaultPacketSize = 20
+
+//DefaultWindow the data packets sent ahead of the acknowledgements
+const DefaultWindow = 16
+
+//DefaultTimeout the time allowed to the receiver to acknowledge the data
+const DefaultTimeout = 5 * time.Second
+
+//DefaultRetries the timeouts tolerated before failing
+const DefaultRetries = 3
+
+//DefaultMaxSize the largest blob accepted by a Receiver
+const DefaultMaxSize = 16 << 20
+
+//StatusError the receiver ended a transfer with a status other than StatusOK
+type StatusError struct {
+	ID     uint32
+	Status uint8
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Transfer %d failed with status 0x%02x", e.ID, e.Status)
+}
+
+//Link carry the packets between a sender and a receiver, eg. a
+// CharacteristicLink or a StreamLink
+type Link interface {
+	// Write send a packet
+	Write(b []byte) error
+	// Packets receive the packets of the other side, closed with the link
+	Packets() <-chan []byte
+}
+
+// startPacket encode OpStart
+func startPacket(id uint32, size int, crc uint32) []byte {
+	b := make([]byte, 13)
+	b[0] = OpStart
+	binary.LittleEndian.PutUint32(b[1:], id)
+	binary.LittleEndian.PutUint32(b[5:], uint32(size))
+	binary.LittleEndian.PutUint32(b[9:], crc)
+	return b
+}
+
+// offsetPacket encode an op code followed by an offset, eg. OpAck
+func offsetPacket(op uint8, offset int) []byte {
+	b := make([]byte, 5)
+	b[0] = op
+	binary.LittleEndian.PutUint32(b[1:], uint32(offset))
+	return b
+}
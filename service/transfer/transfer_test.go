@@ -0,0 +1,324 @@
+package transfer
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+// pipe one end of an in memory link, filter alters or drops (nil) the
+// packets written
+type pipe struct {
+	packets chan []byte
+	peer    *pipe
+	mutex   sync.Mutex
+	filter  func(b []byte) []byte
+}
+
+func newPipe() (*pipe, *pipe) {
+	a := &pipe{packets: make(chan []byte, 1024)}
+	b := &pipe{packets: make(chan []byte, 1024), peer: a}
+	a.peer = b
+	return a, b
+}
+
+func (p *pipe) setFilter(filter func(b []byte) []byte) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.filter = filter
+}
+
+func (p *pipe) Write(b []byte) error {
+	b = append([]byte{}, b...)
+	p.mutex.Lock()
+	filter := p.filter
+	p.mutex.Unlock()
+	if filter != nil {
+		if b = filter(b); b == nil {
+			return nil
+		}
+	}
+	p.peer.packets <- b
+	return nil
+}
+
+func (p *pipe) Packets() <-chan []byte {
+	return p.packets
+}
+
+func blob(size int) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	return b
+}
+
+// receive serve a receiver on a pipe, it returns the blobs completed
+func receive(p *pipe) (*Receiver, <-chan []byte) {
+	blobs := make(chan []byte, 4)
+	r := NewReceiver(p.Write, ReceiverOptions{
+		OnComplete: func(id uint32, data []byte) {
+			blobs <- data
+		},
+	})
+	go r.Serve(p.Packets())
+	return r, blobs
+}
+
+func expectBlob(t *testing.T, blobs <-chan []byte, data []byte) {
+	select {
+	case b := <-blobs:
+		if !bytes.Equal(b, data) {
+			t.Fatal("Blob mismatch")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Blob not received")
+	}
+}
+
+func TestSend(t *testing.T) {
+
+	a, b := newPipe()
+	_, blobs := receive(b)
+
+	data := blob(100000)
+	progress := 0
+	s := NewSender(a, SenderOptions{
+		PacketSize: 244,
+		Progress: func(sent, total int) {
+			if sent < progress || total != len(data) {
+				t.Fatalf("Unexpected progress %d/%d", sent, total)
+			}
+			progress = sent
+		},
+	})
+	if err := s.Send(1, data); err != nil {
+		t.Fatal(err)
+	}
+	if progress != len(data) {
+		t.Fatalf("Unexpected progress %d", progress)
+	}
+	expectBlob(t, blobs, data)
+
+	// already received
+	written := 0
+	a.setFilter(func(b []byte) []byte {
+		if b[0] == OpData {
+			written++
+		}
+		return b
+	})
+	if err := s.Send(1, data); err != nil {
+		t.Fatal(err)
+	}
+	if written != 0 {
+		t.Fatalf("Blob sent again, %d packets", written)
+	}
+}
+
+func TestSendLoss(t *testing.T) {
+
+	a, b := newPipe()
+	_, blobs := receive(b)
+
+	// drop some data packets and swap others
+	count := 0
+	var held []byte
+	a.setFilter(func(b []byte) []byte {
+		if b[0] != OpData {
+			return b
+		}
+		count++
+		switch {
+		case count%37 == 0:
+			return nil
+		case count%11 == 0:
+			held = b
+			return nil
+		case held != nil:
+			a.peer.packets <- b
+			b, held = held, nil
+		}
+		return b
+	})
+
+	data := blob(20000)
+	s := NewSender(a, SenderOptions{Timeout: 100 * time.Millisecond})
+	if err := s.Send(2, data); err != nil {
+		t.Fatal(err)
+	}
+	expectBlob(t, blobs, data)
+}
+
+func TestResume(t *testing.T) {
+
+	a, b := newPipe()
+	_, blobs := receive(b)
+
+	// the link goes down after a part of the blob
+	count := 0
+	a.setFilter(func(b []byte) []byte {
+		if b[0] == OpData {
+			count++
+			if count > 100 {
+				return nil
+			}
+		}
+		return b
+	})
+
+	data := blob(10000)
+	s := NewSender(a, SenderOptions{Timeout: 50 * time.Millisecond, Retries: 1})
+	if err := s.Send(3, data); err == nil {
+		t.Fatal("Expected a timeout")
+	}
+
+	sent := 0
+	a.setFilter(func(b []byte) []byte {
+		if b[0] == OpData {
+			sent += len(b) - dataHeader
+		}
+		return b
+	})
+	if err := s.Send(3, data); err != nil {
+		t.Fatal(err)
+	}
+	if sent >= len(data)-1000 {
+		t.Fatalf("Transfer not resumed, %d bytes sent again", sent)
+	}
+	expectBlob(t, blobs, data)
+}
+
+func TestSendCRC(t *testing.T) {
+
+	a, b := newPipe()
+	receive(b)
+
+	a.setFilter(func(b []byte) []byte {
+		if b[0] == OpData && len(b) > dataHeader {
+			b[dataHeader] ^= 0xff
+		}
+		return b
+	})
+	err := NewSender(a, SenderOptions{}).Send(4, blob(1000))
+	if e, ok := err.(*StatusError); !ok || e.Status != StatusCRC {
+		t.Fatalf("Expected StatusCRC, got %v", err)
+	}
+
+	var reply []byte
+	r := NewReceiver(func(b []byte) error {
+		reply = b
+		return nil
+	}, ReceiverOptions{MaxSize: 10})
+	r.Handle(startPacket(5, 11, 0))
+	if !bytes.Equal(reply, []byte{OpDone, StatusTooLarge}) {
+		t.Fatalf("Unexpected reply %x", reply)
+	}
+}
+
+// remoteLink the central side of a ServerLink, through the fake bluez
+type remoteLink struct {
+	remote  *bluetest.Application
+	packets chan []byte
+}
+
+func (l *remoteLink) Write(b []byte) error {
+	return l.remote.WriteValue(testWrite, b, nil)
+}
+
+func (l *remoteLink) Packets() <-chan []byte {
+	return l.packets
+}
+
+const (
+	testService = "5B940001-0000-1000-8000-00805F9B34FB"
+	testWrite   = "5B940002-0000-1000-8000-00805F9B34FB"
+	testNotify  = "5B940003-0000-1000-8000-00805F9B34FB"
+)
+
+func TestServerLink(t *testing.T) {
+
+	b, err := bluetest.Start()
+	if err != nil {
+		t.Skipf("Cannot start the fake bluez: %s", err.Error())
+	}
+	defer b.Close()
+	if _, err = b.AddAdapter("hci0", "00:11:22:33:44:55"); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.transfertest",
+		ObjectPath: "/transfertest",
+		Logger:     bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	link, err := NewServerLink(app, ServerConfig{
+		ServiceUUID: testService,
+		WriteUUID:   testWrite,
+		NotifyUUID:  testNotify,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs := make(chan []byte, 1)
+	go NewReceiver(link.Write, ReceiverOptions{
+		OnComplete: func(id uint32, data []byte) {
+			blobs <- data
+		},
+	}).Serve(link.Packets())
+
+	err = profile.NewGattManager1("hci0").RegisterApplication(app.Path(), map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var notifyPath dbus.ObjectPath
+	for _, char := range link.Service().GetCharacteristics() {
+		if char.UUID() == testNotify {
+			notifyPath = char.Path()
+		}
+	}
+	signals, err := bluez.GetSignalDispatcher(b.ClientConn()).Subscribe(bluez.SignalFilter{
+		Path:      notifyPath,
+		Interface: bluez.PropertiesInterface,
+		Member:    "PropertiesChanged",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := &remoteLink{
+		remote:  b.Adapter("hci0").Applications()[0],
+		packets: make(chan []byte, 64),
+	}
+	go func() {
+		for sig := range signals {
+			changed, _ := sig.Body[1].(map[string]dbus.Variant)
+			if value, ok := changed["Value"].Value().([]byte); ok {
+				remote.packets <- value
+			}
+		}
+	}()
+
+	data := blob(3000)
+	if err = NewSender(remote, SenderOptions{PacketSize: 100, Timeout: time.Second}).Send(1, data); err != nil {
+		t.Fatal(err)
+	}
+	expectBlob(t, blobs, data)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/muka/go-bluetooth/bluez"
+)
+
+//ErrOutOfRange the ATT error Out of Range (0xFF) of the common profile error
+// codes. bluetoothd replies the code sent as the message of
+// org.bluez.Error.Failed
+var ErrOutOfRange = bluez.ErrFailed.WithMessage("0xff")
+
+//CharacteristicValidator check a value written to a characteristic, see
+// SetValidator
+type CharacteristicValidator func(value []byte, options map[string]interface{}) error
+
+//SetValidator check the values written to the characteristic before the
+// write callback, the function of SetWriteFunc or the WriteFunc of the
+// application. A bluez error, eg. bluez.ErrInvalidValueLength, is replied as
+// is, the other errors are replied as ErrOutOfRange.
+//
+// The options carry the offset of the prepared writes, the validator
+// receives each part of a long value
+func (s *GattCharacteristic1) SetValidator(fn CharacteristicValidator) {
+	s.validator = fn
+}
+
+// validate run the validator, the error is converted to an ATT error
+func (s *GattCharacteristic1) validate(value []byte, options map[string]interface{}) *bluez.Error {
+	if s.validator == nil {
+		return nil
+	}
+	err := bluez.SafeCall(s.logger(), "Validator", func() error {
+		return s.validator(value, options)
+	})
+	if err == nil {
+		return nil
+	}
+	if bzErr, ok := err.(*bluez.Error); ok {
+		return bzErr
+	}
+	s.logger().Debugf("Characteristic %s invalid value %x: %s", s.properties.UUID, value, err.Error())
+	return ErrOutOfRange
+}
+
+//ValidateLength accept the values of min to max bytes, max 0 for no maximum.
+// The other values are rejected with bluez.ErrInvalidValueLength
+func ValidateLength(min int, max int) CharacteristicValidator {
+	return func(value []byte, options map[string]interface{}) error {
+		if len(value) < min || (max > 0 && len(value) > max) {
+			return bluez.ErrInvalidValueLength
+		}
+		return nil
+	}
+}
+
+//ValidateRange accept the values of the unsigned integer of the first size
+// bytes, little endian, from min to max. The values too short are rejected
+// with bluez.ErrInvalidValueLength, the values out of range with ErrOutOfRange
+func ValidateRange(size int, min uint64, max uint64) CharacteristicValidator {
+	return func(value []byte, options map[string]interface{}) error {
+		if len(value) < size {
+			return bluez.ErrInvalidValueLength
+		}
+		var v uint64
+		for i := size - 1; i >= 0; i-- {
+			v = v<<8 | uint64(value[i])
+		}
+		if v < min || v > max {
+			return ErrOutOfRange
+		}
+		return nil
+	}
+}
+
+//ValidateAll run the validators in turn, until one rejects the value
+func ValidateAll(validators ...CharacteristicValidator) CharacteristicValidator {
+	return func(value []byte, options map[string]interface{}) error {
+		for _, fn := range validators {
+			if err := fn(value, options); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+//ValidateFunc accept the values for which fn returns true, the others are
+// rejected with ErrOutOfRange. The description is logged
+func ValidateFunc(description string, fn func(value []byte) bool) CharacteristicValidator {
+	return func(value []byte, options map[string]interface{}) error {
+		if !fn(value) {
+			return fmt.Errorf("Value not %s", description)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,87 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+func TestCharacteristicValidator(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		UUIDSuffix: service.UUIDSuffix,
+		UUID:       "1234",
+		ObjectName: "org.bluez.bluetest",
+		ObjectPath: "/bluetest",
+		Logger:     bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := app.CreateService(&profile.GattService1Properties{
+		Primary: true,
+		UUID:    app.GenerateUUID("2233"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = app.AddService(srv); err != nil {
+		t.Fatal(err)
+	}
+	char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+		UUID:  app.GenerateUUID("3344"),
+		Flags: []string{bluez.FlagCharacteristicWrite},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := [][]byte{}
+	char.SetWriteFunc(func(c *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+		written = append(written, value)
+		return nil
+	})
+	char.SetValidator(service.ValidateAll(
+		service.ValidateLength(2, 2),
+		service.ValidateRange(2, 10, 1000),
+		service.ValidateFunc("even", func(value []byte) bool { return value[0]%2 == 0 }),
+	))
+	if err = srv.AddCharacteristic(char); err != nil {
+		t.Fatal(err)
+	}
+
+	err = profile.NewGattManager1("hci0").RegisterApplication(app.Path(), map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := b.Adapter("hci0").Applications()[0]
+
+	uuid := app.GenerateUUID("3344")
+	if err = remote.WriteValue(uuid, []byte{1}, nil); !bluez.IsError(err, bluez.ErrInvalidValueLength) {
+		t.Fatalf("Expected %s, got %v", bluez.ErrInvalidValueLength, err)
+	}
+	for _, value := range [][]byte{{0x02, 0x00}, {0xe9, 0x03}, {0x0b, 0x00}} {
+		err = remote.WriteValue(uuid, value, nil)
+		if e, ok := err.(*bluez.Error); !ok || e.Name != service.ErrOutOfRange.Name || e.Message != service.ErrOutOfRange.Message {
+			t.Fatalf("Expected %s for %x, got %v", service.ErrOutOfRange, value, err)
+		}
+	}
+	if len(written) != 0 {
+		t.Fatalf("Unexpected writes %v", written)
+	}
+	if err = remote.WriteValue(uuid, []byte{0x0c, 0x00}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 1 {
+		t.Fatalf("Expected 1 write, got %v", written)
+	}
+}
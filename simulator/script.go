@@ -0,0 +1,139 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/service"
+)
+
+//Script the values played on the characteristics of a simulator, in JSON:
+//
+//	{"characteristics": [
+//	  {"characteristic": "2a19", "values": ["64", "63", "62"], "interval": "5s", "loop": true},
+//	  {"characteristic": "2a37", "values": ["0048", "0049"], "mode": "read"}
+//	]}
+type Script struct {
+	Characteristics []CharScript `json:"characteristics"`
+}
+
+// Modes of a CharScript
+const (
+	// ModeInterval update the value every interval, notified to the
+	// subscribed devices
+	ModeInterval = "interval"
+	// ModeRead return the next value on each read
+	ModeRead = "read"
+)
+
+//CharScript the values of a characteristic
+type CharScript struct {
+	// Service the UUID of the service, optional if the characteristic UUID is
+	// unique
+	Service        string         `json:"service,omitempty"`
+	Characteristic string         `json:"characteristic"`
+	Values         []api.HexValue `json:"values"`
+	// Mode ModeInterval, the default, or ModeRead
+	Mode string `json:"mode,omitempty"`
+	// Interval the time between the values, eg. 1s, ModeInterval
+	Interval string `json:"interval,omitempty"`
+	// Loop restart from the first value after the last one, else the last
+	// value is kept
+	Loop bool `json:"loop,omitempty"`
+}
+
+//LoadScript read a script in JSON
+func LoadScript(file string) (*Script, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	script := new(Script)
+	if err = json.Unmarshal(b, script); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	return script, nil
+}
+
+// player play a CharScript on a characteristic
+type player struct {
+	*CharScript
+	char     *service.GattCharacteristic1
+	interval time.Duration
+
+	mutex sync.Mutex
+	next  int
+	done  chan struct{}
+}
+
+func newPlayer(cs *CharScript, char *service.GattCharacteristic1) (*player, error) {
+	if len(cs.Values) == 0 {
+		return nil, fmt.Errorf("%s: no values", cs.Characteristic)
+	}
+	p := &player{CharScript: cs, char: char}
+	switch cs.Mode {
+	case "", ModeInterval:
+		interval, err := time.ParseDuration(cs.Interval)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("%s: invalid interval %q", cs.Characteristic, cs.Interval)
+		}
+		p.interval = interval
+	case ModeRead:
+	default:
+		return nil, fmt.Errorf("%s: invalid mode %s", cs.Characteristic, cs.Mode)
+	}
+	return p, nil
+}
+
+// value return the next value of the script
+func (p *player) value() []byte {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	v := p.Values[p.next]
+	if p.next < len(p.Values)-1 {
+		p.next++
+	} else if p.Loop {
+		p.next = 0
+	}
+	return v
+}
+
+func (p *player) start() {
+	p.next = 0
+	if p.interval == 0 {
+		p.char.SetReadFunc(func(c *service.GattCharacteristic1, options map[string]interface{}) ([]byte, error) {
+			v := p.value()
+			c.UpdateValue(v)
+			return v, nil
+		})
+		return
+	}
+
+	p.done = make(chan struct{})
+	go func(done chan struct{}) {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			p.char.UpdateValue(p.value())
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}(p.done)
+}
+
+func (p *player) stop() {
+	if p.done != nil {
+		close(p.done)
+		p.done = nil
+	}
+	if p.interval == 0 {
+		p.char.SetReadFunc(nil)
+	}
+}
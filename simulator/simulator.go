@@ -0,0 +1,313 @@
+//Package simulator serve the GATT database exported from a real device, see
+// api.Device.ExportGATT, as a local peripheral, to test the centrals and the
+// mobile applications against virtual hardware. The values are the exported
+// ones, updated by the writes of the remote devices, or played by a Script.
+package simulator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/profile"
+	"github.com/muka/go-bluetooth/service"
+)
+
+//Config the options of a Simulator
+type Config struct {
+	// AdapterID the adapter serving the peripheral, defaults to hci0
+	AdapterID string
+	// ObjectPath the path of the application, defaults to
+	// /org/bluez/simulator
+	ObjectPath dbus.ObjectPath
+	// Name the local name advertised, defaults to the name of the database
+	Name string
+	// Advertise the peripheral once started, with the UUIDs of
+	// AdvertisedServices or of the primary services
+	Advertise          bool
+	AdvertisedServices []string
+	// Script play the values of some characteristics, optional
+	Script *Script
+	// OnWrite is called with the writes of the remote devices, after the
+	// value is updated
+	OnWrite func(serviceUUID, charUUID string, value []byte)
+	Logger  bluez.Logger
+}
+
+//Simulator an application serving a GATT database
+type Simulator struct {
+	db     *api.GattDatabase
+	config *Config
+	app    *service.Application
+	script []*player
+
+	mutex   sync.Mutex
+	started bool
+}
+
+//Load read a GATT database exported in JSON
+func Load(file string) (*api.GattDatabase, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	db, err := api.ImportGATT(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	return db, nil
+}
+
+// gapServices the services of the database provided by bluez to every
+// application, not simulated
+var gapServices = []string{"1800", "1801"}
+
+//IsGAPService return true for the GAP and GATT services, served by bluez and
+// not simulated
+func IsGAPService(uuid string) bool {
+	for _, gap := range gapServices {
+		if bluetooth.EqualUUID(uuid, gap) {
+			return true
+		}
+	}
+	return false
+}
+
+//Validate check the UUIDs of a database
+func Validate(db *api.GattDatabase) error {
+	if len(db.Services) == 0 {
+		return fmt.Errorf("No services")
+	}
+	for _, s := range db.Services {
+		if _, err := bluetooth.ParseUUID(s.UUID); err != nil {
+			return err
+		}
+		for _, c := range s.Characteristics {
+			if _, err := bluetooth.ParseUUID(c.UUID); err != nil {
+				return err
+			}
+			for _, d := range c.Descriptors {
+				if _, err := bluetooth.ParseUUID(d.UUID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//New expose the services of a database, except the GAP and GATT services
+// served by bluez, in an application. The application is registered by Start
+func New(db *api.GattDatabase, config *Config) (*Simulator, error) {
+
+	if err := Validate(db); err != nil {
+		return nil, err
+	}
+	if config.AdapterID == "" {
+		config.AdapterID = "hci0"
+	}
+	if config.ObjectPath == "" {
+		config.ObjectPath = "/org/bluez/simulator"
+	}
+	if config.Name == "" {
+		config.Name = db.Name
+	}
+
+	app, err := service.NewApplication(&service.ApplicationConfig{
+		ObjectName: "org.bluez",
+		ObjectPath: config.ObjectPath,
+		LocalName:  config.Name,
+		Logger:     config.Logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = app.Run(); err != nil {
+		return nil, err
+	}
+
+	s := &Simulator{db: db, config: config, app: app}
+	for _, srv := range db.Services {
+		if IsGAPService(srv.UUID) {
+			continue
+		}
+		if err = s.addService(srv); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Script != nil {
+		for i := range config.Script.Characteristics {
+			cs := &config.Script.Characteristics[i]
+			char, err := s.Char(cs.Service, cs.Characteristic)
+			if err != nil {
+				return nil, fmt.Errorf("Script: %s", err)
+			}
+			p, err := newPlayer(cs, char)
+			if err != nil {
+				return nil, fmt.Errorf("Script: %s", err)
+			}
+			s.script = append(s.script, p)
+		}
+	}
+	return s, nil
+}
+
+// advertised return true if the service is in the advertisement
+func (s *Simulator) advertised(srv api.GattServiceInfo) bool {
+	if !s.config.Advertise {
+		return false
+	}
+	if len(s.config.AdvertisedServices) == 0 {
+		return srv.Primary
+	}
+	for _, uuid := range s.config.AdvertisedServices {
+		if bluetooth.EqualUUID(uuid, srv.UUID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Simulator) addService(info api.GattServiceInfo) error {
+
+	srv, err := s.app.CreateService(&profile.GattService1Properties{
+		Primary: info.Primary,
+		UUID:    bluetooth.CanonicalUUID(info.UUID),
+	}, s.advertised(info))
+	if err != nil {
+		return err
+	}
+	if err = s.app.AddService(srv); err != nil {
+		return err
+	}
+
+	for _, c := range info.Characteristics {
+		char, err := srv.CreateCharacteristic(&profile.GattCharacteristic1Properties{
+			UUID:  bluetooth.CanonicalUUID(c.UUID),
+			Flags: c.Flags,
+			Value: c.Value,
+		})
+		if err != nil {
+			return err
+		}
+		serviceUUID, charUUID := info.UUID, c.UUID
+		char.SetWriteFunc(func(char *service.GattCharacteristic1, value []byte, options map[string]interface{}) error {
+			char.UpdateValue(value)
+			if s.config.OnWrite != nil {
+				s.config.OnWrite(serviceUUID, charUUID, value)
+			}
+			return nil
+		})
+		if err = srv.AddCharacteristic(char); err != nil {
+			return err
+		}
+
+		for _, d := range c.Descriptors {
+			// the client configuration is managed by bluez
+			if bluetooth.EqualUUID(d.UUID, "2902") {
+				continue
+			}
+			desc, err := char.CreateDescriptor(&profile.GattDescriptor1Properties{
+				UUID:  bluetooth.CanonicalUUID(d.UUID),
+				Flags: []string{bluez.FlagDescriptorRead},
+				Value: d.Value,
+			})
+			if err != nil {
+				return err
+			}
+			if err = char.AddDescriptor(desc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//Application return the application of the simulator
+func (s *Simulator) Application() *service.Application {
+	return s.app
+}
+
+//Char find a characteristic by UUID, in the service if not empty
+func (s *Simulator) Char(serviceUUID, charUUID string) (*service.GattCharacteristic1, error) {
+	var found *service.GattCharacteristic1
+	for _, srv := range s.app.GetServices() {
+		if serviceUUID != "" && !bluetooth.EqualUUID(srv.UUID(), serviceUUID) {
+			continue
+		}
+		for _, char := range srv.GetCharacteristics() {
+			if !bluetooth.EqualUUID(char.UUID(), charUUID) {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("Characteristic %s found in several services", charUUID)
+			}
+			found = char
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("Characteristic %s not found", charUUID)
+	}
+	return found, nil
+}
+
+//Start register the application, advertise it if configured and play the
+// script
+func (s *Simulator) Start() error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.started {
+		return fmt.Errorf("Simulator already started")
+	}
+
+	if err := s.app.Register(s.config.AdapterID); err != nil {
+		return err
+	}
+	if s.config.Advertise {
+		if err := s.app.StartAdvertising(s.config.AdapterID); err != nil {
+			s.app.Unregister()
+			return err
+		}
+	}
+	for _, p := range s.script {
+		p.start()
+	}
+	s.started = true
+	return nil
+}
+
+//Stop the script, the advertising and unregister the application
+func (s *Simulator) Stop() error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.started {
+		return nil
+	}
+	s.started = false
+
+	for _, p := range s.script {
+		p.stop()
+	}
+	if s.config.Advertise {
+		if err := s.app.StopAdvertising(); err != nil {
+			s.app.Unregister()
+			return err
+		}
+	}
+	return s.app.Unregister()
+}
+
+//Close stop the simulator and remove its services
+func (s *Simulator) Close() {
+	s.Stop()
+	for _, srv := range s.app.GetServices() {
+		s.app.RemoveService(srv)
+	}
+}
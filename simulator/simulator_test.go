@@ -0,0 +1,140 @@
+package simulator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/muka/go-bluetooth"
+	"github.com/muka/go-bluetooth/api"
+	"github.com/muka/go-bluetooth/bluez"
+	"github.com/muka/go-bluetooth/bluez/bluetest"
+)
+
+func TestValidate(t *testing.T) {
+	if err := Validate(&api.GattDatabase{}); err == nil {
+		t.Fatal("Expected an error without services")
+	}
+	db := &api.GattDatabase{Services: []api.GattServiceInfo{{
+		UUID:            "180f",
+		Characteristics: []api.GattCharacteristicInfo{{UUID: "2a19"}},
+	}}}
+	if err := Validate(db); err != nil {
+		t.Fatal(err)
+	}
+	db.Services[0].Characteristics[0].Descriptors = []api.GattDescriptorInfo{{UUID: "nope"}}
+	if err := Validate(db); err == nil {
+		t.Fatal("Expected an error on the descriptor UUID")
+	}
+}
+
+func TestScript(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "simulator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "script.json")
+	err = ioutil.WriteFile(file, []byte(`{"characteristics": [
+		{"characteristic": "2a19", "values": ["64", "63"], "interval": "1s", "loop": true},
+		{"characteristic": "2a37", "values": ["0048", "0049"], "mode": "read"},
+		{"characteristic": "2a38", "values": ["01"]},
+		{"characteristic": "2a39", "values": [], "mode": "read"},
+		{"characteristic": "2a3a", "values": ["01"], "mode": "write"}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := LoadScript(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(script.Characteristics) != 5 {
+		t.Fatalf("Expected 5 characteristics, got %d", len(script.Characteristics))
+	}
+
+	loop, err := newPlayer(&script.Characteristics[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []byte{0x64, 0x63, 0x64} {
+		if v := loop.value(); len(v) != 1 || v[0] != expected {
+			t.Fatalf("Value %d: expected %x, got %x", i, expected, v)
+		}
+	}
+
+	read, err := newPlayer(&script.Characteristics[1], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.interval != 0 {
+		t.Fatalf("Expected no interval in read mode, got %s", read.interval)
+	}
+	for i, expected := range []byte{0x48, 0x49, 0x49} {
+		if v := read.value(); len(v) != 2 || v[1] != expected {
+			t.Fatalf("Value %d: expected 00%x, got %x", i, expected, v)
+		}
+	}
+
+	for _, cs := range script.Characteristics[2:] {
+		if _, err = newPlayer(&cs, nil); err == nil {
+			t.Fatalf("%s: expected an error", cs.Characteristic)
+		}
+	}
+}
+
+func TestSimulator(t *testing.T) {
+
+	b := bluetest.StartTest(t)
+	defer b.Close()
+
+	db, err := api.ImportGATT([]byte(`{"name": "sensor", "services": [
+		{"uuid": "1800", "primary": true, "characteristics": [{"uuid": "2a00", "flags": ["read"], "value": "73656e736f72"}]},
+		{"uuid": "180d", "primary": true, "characteristics": [
+			{"uuid": "2a37", "flags": ["read", "notify"], "value": "0040",
+				"descriptors": [{"uuid": "2902", "value": "0000"}]},
+			{"uuid": "2a39", "flags": ["write"]}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written []byte
+	sim, err := New(db, &Config{
+		Script: &Script{Characteristics: []CharScript{{
+			Characteristic: "2a37",
+			Values:         []api.HexValue{{0, 0x48}, {0, 0x49}},
+			Mode:           ModeRead,
+		}}},
+		OnWrite: func(serviceUUID, charUUID string, value []byte) { written = value },
+		Logger:  bluez.NopLogger{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+	if err = sim.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	central := b.Adapter("hci0").Applications()[0]
+	if services := central.Services(); len(services) != 1 || !bluetooth.EqualUUID(services[0], "180d") {
+		t.Fatalf("Expected the heart rate service only, got %v", services)
+	}
+	uuid := bluetooth.CanonicalUUID("2a37")
+	for _, expected := range []byte{0x48, 0x49, 0x49} {
+		value, err := central.ReadValue(uuid, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(value) != 2 || value[1] != expected {
+			t.Fatalf("Expected 00%x, got %x", expected, value)
+		}
+	}
+	if err = central.WriteValue(bluetooth.CanonicalUUID("2a39"), []byte{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 1 || written[0] != 1 {
+		t.Fatalf("Expected the write of 01, got %x", written)
+	}
+}
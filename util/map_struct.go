@@ -8,11 +8,12 @@ import (
 
 func mapStructField(obj interface{}, name string, value dbus.Variant) error {
 	structValue := reflect.ValueOf(obj).Elem()
-	structFieldValue := structValue.FieldByName(name)
-
-	if !structFieldValue.IsValid() {
+	table := getStructTable(structValue.Type())
+	i, ok := table.byName[name]
+	if !ok {
 		return errors.New("No such field: " + name + " in obj")
 	}
+	structFieldValue := structValue.Field(table.fields[i].index)
 
 	if !structFieldValue.CanSet() {
 		return errors.New("Cannot set " + name + " field value")
@@ -0,0 +1,187 @@
+package util
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+)
+
+// structField a field of a properties struct, resolved once per type
+type structField struct {
+	index     int
+	name      string
+	omitEmpty bool
+	nested    bool
+	dbusTag   string
+}
+
+// structTable the exported fields of a type, by declaration order and name
+type structTable struct {
+	fields []structField
+	byName map[string]int
+}
+
+var variantType = reflect.TypeOf(dbus.Variant{})
+
+var structTables = struct {
+	sync.RWMutex
+	types map[reflect.Type]*structTable
+}{types: make(map[reflect.Type]*structTable)}
+
+// getStructTable return the fields of t, a struct type, computing them on
+// first use
+func getStructTable(t reflect.Type) *structTable {
+
+	structTables.RLock()
+	table, ok := structTables.types[t]
+	structTables.RUnlock()
+	if ok {
+		return table
+	}
+
+	table = &structTable{
+		fields: make([]structField, 0, t.NumField()),
+		byName: make(map[string]int),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// unexported
+		if f.PkgPath != "" {
+			continue
+		}
+
+		field := structField{
+			index:   i,
+			name:    f.Name,
+			dbusTag: f.Tag.Get("dbus"),
+		}
+
+		// honor the structs tag, eg. `structs:"name,omitempty"`
+		tag := strings.Split(f.Tag.Get("structs"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if tag[0] != "" {
+			field.name = tag[0]
+		}
+		for _, opt := range tag[1:] {
+			if opt == "omitempty" {
+				field.omitEmpty = true
+			}
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		// variants are values, not nested properties
+		field.nested = ft.Kind() == reflect.Struct && ft != variantType
+
+		table.byName[f.Name] = len(table.fields)
+		table.fields = append(table.fields, field)
+	}
+
+	structTables.Lock()
+	structTables.types[t] = table
+	structTables.Unlock()
+
+	return table
+}
+
+// structValue dereference s, a struct or a pointer to a struct
+func structValue(s interface{}) (reflect.Value, bool) {
+	v := reflect.ValueOf(s)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.Kind() == reflect.Struct
+}
+
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+//StructToMap convert a struct to a map by field name, as structs.Map does.
+// The fields are resolved once per type, use it in the frequent paths like
+// property updates
+func StructToMap(s interface{}) map[string]interface{} {
+
+	v, ok := structValue(s)
+	if !ok {
+		return map[string]interface{}{}
+	}
+
+	table := getStructTable(v.Type())
+	m := make(map[string]interface{}, len(table.fields))
+	for _, f := range table.fields {
+		fv := v.Field(f.index)
+		if f.omitEmpty && isZero(fv) {
+			continue
+		}
+		if f.nested {
+			if nested, ok := structValue(fv.Interface()); ok {
+				m[f.name] = StructToMap(nested.Interface())
+				continue
+			}
+		}
+		m[f.name] = fv.Interface()
+	}
+	return m
+}
+
+//StructField a field of a struct with its dbus tag
+type StructField struct {
+	Name  string
+	Tag   string
+	Value interface{}
+}
+
+//StructFields list the exported fields of a struct, in declaration order
+func StructFields(s interface{}) []StructField {
+
+	v, ok := structValue(s)
+	if !ok {
+		return nil
+	}
+
+	table := getStructTable(v.Type())
+	fields := make([]StructField, 0, len(table.fields))
+	for _, f := range table.fields {
+		fields = append(fields, StructField{
+			Name:  v.Type().Field(f.index).Name,
+			Tag:   f.dbusTag,
+			Value: v.Field(f.index).Interface(),
+		})
+	}
+	return fields
+}
+
+//SetStructField set a field of a struct pointer by name
+func SetStructField(s interface{}, name string, value interface{}) error {
+
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("A pointer to a struct is required")
+	}
+	v = v.Elem()
+
+	table := getStructTable(v.Type())
+	i, ok := table.byName[name]
+	if !ok {
+		return errors.New("No such field: " + name + " in obj")
+	}
+	fv := v.Field(table.fields[i].index)
+
+	val := reflect.ValueOf(value)
+	if !val.IsValid() || !val.Type().AssignableTo(fv.Type()) {
+		return errors.New("Provided value type didn't match obj field type")
+	}
+	fv.Set(val)
+	return nil
+}
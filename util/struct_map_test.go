@@ -0,0 +1,82 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+type testNested struct {
+	Value int
+}
+
+type testProperties struct {
+	Name     string `dbus:"emit"`
+	Alias    string `structs:"Nickname"`
+	Optional uint16 `structs:",omitempty"`
+	Skipped  bool   `structs:"-"`
+	Path     dbus.ObjectPath
+	Data     dbus.Variant
+	Nested   testNested
+	hidden   bool
+}
+
+func TestStructToMap(t *testing.T) {
+
+	p := &testProperties{
+		Name:   "name",
+		Alias:  "alias",
+		Data:   dbus.MakeVariant(uint8(1)),
+		Nested: testNested{2},
+	}
+	m := StructToMap(p)
+
+	if len(m) != 5 {
+		t.Fatalf("Unexpected fields %v", m)
+	}
+	if m["Name"] != "name" || m["Nickname"] != "alias" {
+		t.Fatalf("Unexpected values %v", m)
+	}
+	if _, ok := m["Optional"]; ok {
+		t.Fatal("Optional should be omitted when empty")
+	}
+	if v, ok := m["Data"].(dbus.Variant); !ok || v.Value() != uint8(1) {
+		t.Fatalf("Variant should be kept, got %v", m["Data"])
+	}
+	if nested, ok := m["Nested"].(map[string]interface{}); !ok || nested["Value"] != 2 {
+		t.Fatalf("Nested struct should be converted, got %v", m["Nested"])
+	}
+
+	p.Optional = 3
+	if StructToMap(p)["Optional"] != uint16(3) {
+		t.Fatal("Optional should be set")
+	}
+}
+
+func TestStructFields(t *testing.T) {
+
+	p := &testProperties{Name: "name"}
+	fields := StructFields(p)
+	if len(fields) != 6 || fields[0].Name != "Name" || fields[0].Tag != "emit" {
+		t.Fatalf("Unexpected fields %v", fields)
+	}
+
+	err := SetStructField(p, "Alias", "alias")
+	if err != nil || p.Alias != "alias" {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if SetStructField(p, "Alias", 1) == nil {
+		t.Fatal("Expected a type error")
+	}
+	if SetStructField(p, "Missing", 1) == nil {
+		t.Fatal("Expected a missing field error")
+	}
+}
+
+func BenchmarkStructToMap(b *testing.B) {
+	p := &testProperties{Name: "name", Alias: "alias"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		StructToMap(p)
+	}
+}
@@ -0,0 +1,223 @@
+//Package bluetooth the types shared by the bluez, service and linux
+// packages, eg. UUID
+package bluetooth
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//UUID a Bluetooth UUID in its 128bit form, big endian as written. The 16 and
+// 32bit UUIDs of the Bluetooth SIG are expanded with the BaseUUID
+type UUID [16]byte
+
+//BaseUUID the Bluetooth base UUID, 00000000-0000-1000-8000-00805F9B34FB
+var BaseUUID = UUID{0, 0, 0, 0, 0, 0, 0x10, 0, 0x80, 0, 0, 0x80, 0x5F, 0x9B, 0x34, 0xFB}
+
+//UUID16 expand a 16bit UUID, eg. 0x180F
+func UUID16(v uint16) UUID {
+	return UUID32(uint32(v))
+}
+
+//UUID32 expand a 32bit UUID
+func UUID32(v uint32) UUID {
+	u := BaseUUID
+	u[0], u[1], u[2], u[3] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+	return u
+}
+
+//ParseUUID parse a UUID in the 16bit (180F, 0x180F), 32bit (0000180F) or
+// 128bit form, with or without dashes. The case is ignored
+func ParseUUID(s string) (UUID, error) {
+
+	var u UUID
+	h := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+
+	switch len(h) {
+	case 4, 8:
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return u, fmt.Errorf("Invalid UUID %q", s)
+		}
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return UUID32(v), nil
+	case 36:
+		if h[8] != '-' || h[13] != '-' || h[18] != '-' || h[23] != '-' {
+			return u, fmt.Errorf("Invalid UUID %q", s)
+		}
+		h = h[0:8] + h[9:13] + h[14:18] + h[19:23] + h[24:]
+	case 32:
+	default:
+		return u, fmt.Errorf("Invalid UUID %q", s)
+	}
+
+	if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+		return u, fmt.Errorf("Invalid UUID %q", s)
+	}
+	return u, nil
+}
+
+//MustParseUUID parse a UUID, it panics if invalid. For the UUIDs known at
+// compile time
+func MustParseUUID(s string) UUID {
+	u, err := ParseUUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+//CanonicalUUID return the canonical 128bit form of a UUID string, as
+// exchanged with bluez, or the string unchanged if it is not a valid UUID
+func CanonicalUUID(s string) string {
+	u, err := ParseUUID(s)
+	if err != nil {
+		return s
+	}
+	return u.String()
+}
+
+//EqualUUID compare two UUID strings in any of their forms
+func EqualUUID(a string, b string) bool {
+	ua, err := ParseUUID(a)
+	if err != nil {
+		return strings.EqualFold(a, b)
+	}
+	ub, err := ParseUUID(b)
+	if err != nil {
+		return false
+	}
+	return ua == ub
+}
+
+//String return the 128bit form, upper case with dashes
+func (u UUID) String() string {
+	h := strings.ToUpper(hex.EncodeToString(u[:]))
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+//ShortString return the shortest form: 4 hex digits for a 16bit UUID, 8 for
+// a 32bit one, the 128bit form otherwise
+func (u UUID) ShortString() string {
+	if v, ok := u.Uint16(); ok {
+		return fmt.Sprintf("%04X", v)
+	}
+	if v, ok := u.Uint32(); ok {
+		return fmt.Sprintf("%08X", v)
+	}
+	return u.String()
+}
+
+//IsSIG return true if the UUID is derived from the BaseUUID, a 16 or 32bit
+// UUID of the Bluetooth SIG
+func (u UUID) IsSIG() bool {
+	for i := 4; i < 16; i++ {
+		if u[i] != BaseUUID[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//Uint16 return the 16bit value, false if the UUID has no 16bit form
+func (u UUID) Uint16() (uint16, bool) {
+	v, ok := u.Uint32()
+	if !ok || v > 0xffff {
+		return 0, false
+	}
+	return uint16(v), true
+}
+
+//Uint32 return the 32bit value, false if the UUID has no 32bit form
+func (u UUID) Uint32() (uint32, bool) {
+	if !u.IsSIG() {
+		return 0, false
+	}
+	return uint32(u[0])<<24 | uint32(u[1])<<16 | uint32(u[2])<<8 | uint32(u[3]), true
+}
+
+//Bytes return the little endian form of the attribute protocol and the
+// advertising data: 2 bytes for a 16bit UUID, 16 otherwise
+func (u UUID) Bytes() []byte {
+	if v, ok := u.Uint16(); ok {
+		return []byte{byte(v), byte(v >> 8)}
+	}
+	b := make([]byte, 16)
+	for i := range u {
+		b[15-i] = u[i]
+	}
+	return b
+}
+
+//UUIDFromBytes decode a little endian UUID of 2, 4 or 16 bytes
+func UUIDFromBytes(b []byte) (UUID, error) {
+	var u UUID
+	switch len(b) {
+	case 2, 4:
+		var v uint32
+		for i := len(b) - 1; i >= 0; i-- {
+			v = v<<8 | uint32(b[i])
+		}
+		return UUID32(v), nil
+	case 16:
+		for i := range b {
+			u[15-i] = b[i]
+		}
+		return u, nil
+	}
+	return u, fmt.Errorf("Invalid UUID length %d", len(b))
+}
+
+//MarshalText encode the 128bit form, eg. for JSON
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+//UnmarshalText decode any of the forms of ParseUUID
+func (u *UUID) UnmarshalText(b []byte) error {
+	v, err := ParseUUID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+var (
+	namesMutex sync.RWMutex
+	names      = map[UUID]string{}
+)
+
+//RegisterUUIDName name a UUID for the logs, eg. a vendor service. The name
+// takes precedence over the assigned numbers
+func RegisterUUIDName(u UUID, name string) {
+	namesMutex.Lock()
+	defer namesMutex.Unlock()
+	names[u] = name
+}
+
+//Name return the name registered for the UUID or its name in the assigned
+// numbers, empty if unknown
+func (u UUID) Name() string {
+	namesMutex.RLock()
+	name, ok := names[u]
+	namesMutex.RUnlock()
+	if ok {
+		return name
+	}
+	return assignedName(u)
+}
+
+//Describe return the short form followed by the name, if known, eg.
+// "180F (Battery Service)", for the logs
+func (u UUID) Describe() string {
+	if name := u.Name(); name != "" {
+		return u.ShortString() + " (" + name + ")"
+	}
+	return u.ShortString()
+}
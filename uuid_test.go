@@ -0,0 +1,85 @@
+package bluetooth
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestParseUUID(t *testing.T) {
+	cases := map[string]string{
+		"180f":                                 "0000180F-0000-1000-8000-00805F9B34FB",
+		"0x2A19":                               "00002A19-0000-1000-8000-00805F9B34FB",
+		"00011101":                             "00011101-0000-1000-8000-00805F9B34FB",
+		"f000aa01-0451-4000-b000-000000000000": "F000AA01-0451-4000-B000-000000000000",
+		"F000AA0104514000B000000000000000":     "F000AA01-0451-4000-B000-000000000000",
+	}
+	for in, out := range cases {
+		u, err := ParseUUID(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() != out {
+			t.Fatalf("ParseUUID(%s) = %s, expected %s", in, u, out)
+		}
+	}
+	for _, in := range []string{"", "18F", "180G", "F000AA01-0451-4000-B000+000000000000", "F000AA01"[:6]} {
+		if _, err := ParseUUID(in); err == nil {
+			t.Fatalf("Invalid UUID %q accepted", in)
+		}
+	}
+}
+
+func TestShortUUID(t *testing.T) {
+	u := UUID16(0x180F)
+	if v, ok := u.Uint16(); !ok || v != 0x180F || u.ShortString() != "180F" {
+		t.Fatalf("Unexpected 16bit form of %s", u)
+	}
+	if !bytes.Equal(u.Bytes(), []byte{0x0F, 0x18}) {
+		t.Fatalf("Unexpected bytes %x", u.Bytes())
+	}
+
+	u = UUID32(0x00011101)
+	if _, ok := u.Uint16(); ok || u.ShortString() != "00011101" {
+		t.Fatalf("Unexpected 32bit form of %s", u)
+	}
+
+	u = MustParseUUID("F000AA01-0451-4000-B000-000000000000")
+	if u.IsSIG() || u.ShortString() != u.String() {
+		t.Fatalf("Unexpected short form of %s", u)
+	}
+	parsed, err := UUIDFromBytes(u.Bytes())
+	if err != nil || parsed != u {
+		t.Fatalf("Unexpected UUID %s %v", parsed, err)
+	}
+	if parsed, err = UUIDFromBytes([]byte{0x0F, 0x18}); err != nil || parsed != UUID16(0x180F) {
+		t.Fatalf("Unexpected UUID %s %v", parsed, err)
+	}
+
+	if !EqualUUID("2a19", "00002A19-0000-1000-8000-00805f9b34fb") || EqualUUID("2a19", "2a1a") {
+		t.Fatal("Unexpected UUID comparison")
+	}
+}
+
+func TestUUIDName(t *testing.T) {
+	u := MustParseUUID("F000AA01-0451-4000-B000-000000000000")
+	if u.Name() != "" || u.Describe() != u.String() {
+		t.Fatalf("Unexpected description %s", u.Describe())
+	}
+	RegisterUUIDName(u, "Temperature Data")
+	if u.Describe() != "F000AA01-0451-4000-B000-000000000000 (Temperature Data)" {
+		t.Fatalf("Unexpected description %s", u.Describe())
+	}
+	if d := UUID16(0x2902).Describe(); d != "2902 (Client Characteristic Configuration)" {
+		t.Fatalf("Unexpected description %s", d)
+	}
+
+	b, err := json.Marshal(map[string]UUID{"uuid": UUID16(0x180F)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]UUID
+	if err = json.Unmarshal(b, &decoded); err != nil || decoded["uuid"] != UUID16(0x180F) {
+		t.Fatalf("Unexpected JSON %s %v", b, err)
+	}
+}